@@ -0,0 +1,293 @@
+// Command loadtest replays the recorded query models against a mock upstream and reports the latency percentiles of the
+// stages of the query pipeline along with the allocations per query.
+//
+//	go run ./cmd/loadtest -n 500 -c 8
+//	go run ./cmd/loadtest -queries recorded.json -json > report.json
+//
+// Recorded queries are a json array of {"name", "query", "response": {"status", "headers", "body"}} items. The url of each query is
+// replaced with the url of the mock upstream, which serves the recorded response. Without the recorded queries, json, csv and xml
+// queries with generated responses are replayed. Allocations include the allocations of the mock upstream
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/pluginhost"
+)
+
+// recordedQuery is the query model replayed against the recorded response
+type recordedQuery struct {
+	Name     string           `json:"name"`
+	Query    models.Query     `json:"query"`
+	Response recordedResponse `json:"response"`
+}
+
+type recordedResponse struct {
+	Status  int               `json:"status,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body"`
+}
+
+// stageReport is the latency percentiles of a stage of the query pipeline
+type stageReport struct {
+	P50 time.Duration `json:"p50"`
+	P99 time.Duration `json:"p99"`
+}
+
+type caseReport struct {
+	Name           string                 `json:"name"`
+	Queries        int                    `json:"queries"`
+	Rows           int                    `json:"rows"`
+	Errors         int                    `json:"errors"`
+	FirstError     string                 `json:"firstError,omitempty"`
+	Stages         map[string]stageReport `json:"stages"`
+	AllocsPerQuery uint64                 `json:"allocsPerQuery"`
+	BytesPerQuery  uint64                 `json:"bytesPerQuery"`
+}
+
+var stages = []string{"ttfb", "download", "parse", "transform", "cache", "total"}
+
+func main() {
+	queriesFile := flag.String("queries", "", "json file of the recorded queries. defaults to the generated json, csv and xml queries")
+	requests := flag.Int("n", 200, "number of queries per recorded query")
+	concurrency := flag.Int("c", 4, "number of concurrent queries")
+	rows := flag.Int("rows", 1000, "number of rows of the generated responses")
+	jsonOutput := flag.Bool("json", false, "print the report as json")
+	maxP99 := flag.Duration("max-p99", 0, "exit with error when the p99 of the total latency of any query exceeds the duration")
+	flag.Parse()
+	backend.Logger = log.NewWithLevel(log.Error)
+
+	recorded, err := loadRecordedQueries(*queriesFile, *rows)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/recorded/"))
+		if err != nil || i < 0 || i >= len(recorded) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		res := recorded[i].Response
+		for k, v := range res.Headers {
+			w.Header().Set(k, v)
+		}
+		if res.Status != 0 {
+			w.WriteHeader(res.Status)
+		}
+		_, _ = w.Write([]byte(res.Body))
+	}))
+	defer server.Close()
+	client, err := infinity.NewClient(context.Background(), models.InfinitySettings{})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	reports := make([]caseReport, 0, len(recorded))
+	failed := false
+	for i, rq := range recorded {
+		rq.Query.URL = fmt.Sprintf("%s/recorded/%d", server.URL, i)
+		if rq.Query.Source == "" {
+			rq.Query.Source = "url"
+		}
+		report := run(rq, *client, *requests, *concurrency)
+		if *maxP99 > 0 && report.Stages["total"].P99 > *maxP99 {
+			failed = true
+		}
+		reports = append(reports, report)
+	}
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(reports)
+	} else {
+		printReports(reports)
+	}
+	if failed {
+		fmt.Fprintf(os.Stderr, "p99 latency exceeds %s\n", *maxP99)
+		os.Exit(1)
+	}
+}
+
+// run replays the query n times with the given concurrency
+func run(rq recordedQuery, client infinity.Client, n int, concurrency int) caseReport {
+	report := caseReport{Name: rq.Name, Queries: n, Stages: map[string]stageReport{}}
+	durations := map[string][]time.Duration{}
+	var mu sync.Mutex
+	jobs := make(chan struct{})
+	var wg sync.WaitGroup
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	for w := 0; w < max(concurrency, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range jobs {
+				start := time.Now()
+				res := pluginhost.QueryDataQuery(context.Background(), rq.Query, client, map[string]string{}, backend.PluginContext{})
+				total := time.Since(start)
+				mu.Lock()
+				if res.Error != nil {
+					report.Errors++
+					if report.FirstError == "" {
+						report.FirstError = res.Error.Error()
+					}
+				}
+				if len(res.Frames) > 0 {
+					report.Rows = res.Frames[0].Rows()
+				}
+				durations["total"] = append(durations["total"], total)
+				if timings := getTimings(res); timings != nil {
+					durations["ttfb"] = append(durations["ttfb"], timings.TTFB)
+					durations["download"] = append(durations["download"], timings.Download)
+					durations["parse"] = append(durations["parse"], timings.Parse)
+					durations["transform"] = append(durations["transform"], timings.Transform)
+					durations["cache"] = append(durations["cache"], timings.Cache)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- struct{}{}
+	}
+	close(jobs)
+	wg.Wait()
+	runtime.ReadMemStats(&after)
+	if n > 0 {
+		report.AllocsPerQuery = (after.Mallocs - before.Mallocs) / uint64(n)
+		report.BytesPerQuery = (after.TotalAlloc - before.TotalAlloc) / uint64(n)
+	}
+	for stage, values := range durations {
+		report.Stages[stage] = stageReport{P50: percentile(values, 50), P99: percentile(values, 99)}
+	}
+	return report
+}
+
+func getTimings(res backend.DataResponse) *infinity.Timings {
+	if len(res.Frames) == 0 || res.Frames[0].Meta == nil {
+		return nil
+	}
+	if customMeta, ok := res.Frames[0].Meta.Custom.(*infinity.CustomMeta); ok && customMeta != nil {
+		return customMeta.Timings
+	}
+	return nil
+}
+
+func percentile(values []time.Duration, p int) time.Duration {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration{}, values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[(len(sorted)-1)*p/100]
+}
+
+func printReports(reports []caseReport) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := []string{"query", "queries", "rows", "errors"}
+	for _, stage := range stages {
+		header = append(header, stage+" p50", stage+" p99")
+	}
+	header = append(header, "allocs/query", "bytes/query")
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, report := range reports {
+		row := []string{report.Name, strconv.Itoa(report.Queries), strconv.Itoa(report.Rows), strconv.Itoa(report.Errors)}
+		for _, stage := range stages {
+			row = append(row, report.Stages[stage].P50.String(), report.Stages[stage].P99.String())
+		}
+		row = append(row, strconv.FormatUint(report.AllocsPerQuery, 10), strconv.FormatUint(report.BytesPerQuery, 10))
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+	for _, report := range reports {
+		if report.FirstError != "" {
+			fmt.Fprintf(os.Stderr, "%s: %d errors. first error: %s\n", report.Name, report.Errors, report.FirstError)
+		}
+	}
+}
+
+func loadRecordedQueries(file string, rows int) ([]recordedQuery, error) {
+	if file == "" {
+		return generatedQueries(rows), nil
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the recorded queries. %w", err)
+	}
+	var recorded []recordedQuery
+	if err := json.Unmarshal(b, &recorded); err != nil {
+		return nil, fmt.Errorf("error reading the recorded queries. %w", err)
+	}
+	for i := range recorded {
+		if recorded[i].Name == "" {
+			recorded[i].Name = fmt.Sprintf("query %d", i+1)
+		}
+	}
+	return recorded, nil
+}
+
+// generatedQueries returns the json, csv and xml queries of the users with the given number of rows
+func generatedQueries(rows int) []recordedQuery {
+	var jsonBody, csvBody, xmlBody strings.Builder
+	jsonBody.WriteString("[")
+	csvBody.WriteString("name,age,country,joined\n")
+	xmlBody.WriteString("<users>")
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			jsonBody.WriteString(",")
+		}
+		name, age, country, joined := fmt.Sprintf("user%d", i), 20+i%50, []string{"uk", "us", "in"}[i%3], time.Unix(int64(1600000000+i*3600), 0).UTC().Format(time.RFC3339)
+		fmt.Fprintf(&jsonBody, `{"name":%q,"age":%d,"country":%q,"joined":%q}`, name, age, country, joined)
+		fmt.Fprintf(&csvBody, "%s,%d,%s,%s\n", name, age, country, joined)
+		fmt.Fprintf(&xmlBody, `<user name="%s" age="%d" country="%s" joined="%s" />`, name, age, country, joined)
+	}
+	jsonBody.WriteString("]")
+	xmlBody.WriteString("</users>")
+	columns := []models.InfinityColumn{
+		{Selector: "name", Text: "Name", Type: "string"},
+		{Selector: "age", Text: "Age", Type: "number"},
+		{Selector: "country", Text: "Country", Type: "string"},
+		{Selector: "joined", Text: "Joined", Type: "timestamp"},
+	}
+	xmlColumns := []models.InfinityColumn{
+		{Selector: "name", Text: "Name", Type: "string"},
+		{Selector: "age", Text: "Age", Type: "number"},
+		{Selector: "country", Text: "Country", Type: "string"},
+	}
+	return []recordedQuery{
+		{
+			Name:     "json backend",
+			Query:    models.Query{RefID: "A", Type: models.QueryTypeJSON, Parser: models.InfinityParserBackend, Columns: columns, FilterExpression: "age > 30"},
+			Response: recordedResponse{Headers: map[string]string{"Content-Type": "application/json"}, Body: jsonBody.String()},
+		},
+		{
+			Name:     "csv backend",
+			Query:    models.Query{RefID: "A", Type: models.QueryTypeCSV, Parser: models.InfinityParserBackend, Columns: columns},
+			Response: recordedResponse{Headers: map[string]string{"Content-Type": "text/csv"}, Body: csvBody.String()},
+		},
+		{
+			Name:     "xml backend",
+			Query:    models.Query{RefID: "A", Type: models.QueryTypeXML, Parser: models.InfinityParserBackend, RootSelector: "users.user", Columns: xmlColumns},
+			Response: recordedResponse{Headers: map[string]string{"Content-Type": "application/xml"}, Body: xmlBody.String()},
+		},
+	}
+}
@@ -0,0 +1,130 @@
+package infinity
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+// IsAlertingRequest returns true when the request is made by the grafana alerting
+func IsAlertingRequest(requestHeaders map[string]string) bool {
+	return strings.EqualFold(requestHeaders["FromAlert"], "true")
+}
+
+// IsHeadlessRequest returns true when the results of the request are consumed without the grafana frontend,
+// such as alerting, recorded queries and server side expressions
+func IsHeadlessRequest(requestHeaders map[string]string) bool {
+	return IsAlertingRequest(requestHeaders) || strings.EqualFold(requestHeaders["FromExpression"], "true")
+}
+
+// ShapeFrameForFormat reshapes the frame into the frame type expected by the query format.
+// Numeric and trace formats are always enforced. Time series shape is enforced only when strict is set (headless requests),
+// so the dashboards can keep rendering the tables which can't be converted to time series
+func ShapeFrameForFormat(ctx context.Context, frame *data.Frame, query models.Query, strict bool) (*data.Frame, error) {
+	_, span := tracing.DefaultTracer().Start(ctx, "ShapeFrameForFormat")
+	defer span.End()
+	if frame == nil {
+		return frame, nil
+	}
+	switch query.Format {
+	case "numeric":
+		return toNumericWideFrame(frame)
+	case "timeseries":
+		if strict {
+			return toTimeSeriesWideFrame(frame)
+		}
+	case "trace":
+		return toTraceFrame(frame, query.TraceOptions)
+	}
+	return frame, nil
+}
+
+func toTimeSeriesWideFrame(frame *data.Frame) (*data.Frame, error) {
+	if frame.Rows() == 0 {
+		return frame, nil
+	}
+	switch frame.TimeSeriesSchema().Type {
+	case data.TimeSeriesTypeWide:
+	case data.TimeSeriesTypeLong:
+		wFrame, err := data.LongToWide(frame, &data.FillMissing{Mode: data.FillModeNull})
+		if err != nil {
+			return frame, fmt.Errorf("unable to convert the results into time series. %w", err)
+		}
+		frame = wFrame
+	default:
+		return frame, fmt.Errorf("time series format requires a time field and at least one numeric field. found %s", describeFields(frame))
+	}
+	setFrameType(frame, data.FrameTypeTimeSeriesWide)
+	return frame, nil
+}
+
+// toNumericWideFrame converts the table into a single row frame where every numeric value becomes its own field.
+// String fields of the row become the labels of the values. Time and other fields are ignored
+func toNumericWideFrame(frame *data.Frame) (*data.Frame, error) {
+	valueFields := []*data.Field{}
+	labelFields := []*data.Field{}
+	for _, field := range frame.Fields {
+		switch {
+		case field.Type().Numeric():
+			valueFields = append(valueFields, field)
+		case field.Type() == data.FieldTypeString || field.Type() == data.FieldTypeNullableString:
+			labelFields = append(labelFields, field)
+		}
+	}
+	if len(valueFields) == 0 {
+		return frame, fmt.Errorf("numeric format requires at least one numeric field. found %s", describeFields(frame))
+	}
+	out := data.NewFrame(frame.Name)
+	out.Meta = frame.Meta
+	seen := map[string]bool{}
+	for row := 0; row < frame.Rows(); row++ {
+		labels := data.Labels{}
+		for _, field := range labelFields {
+			if v, ok := field.ConcreteAt(row); ok {
+				labels[field.Name] = v.(string)
+			}
+		}
+		for _, field := range valueFields {
+			fieldLabels := labels.Copy()
+			for k, v := range field.Labels {
+				fieldLabels[k] = v
+			}
+			key := field.Name + fieldLabels.String()
+			if seen[key] {
+				return frame, fmt.Errorf("numeric format requires unique label sets. %s%s is returned more than once. use summarize to aggregate the rows", field.Name, fieldLabels.String())
+			}
+			seen[key] = true
+			value, err := field.NullableFloatAt(row)
+			if err != nil {
+				return frame, fmt.Errorf("unable to read the numeric value of %s. %w", field.Name, err)
+			}
+			newField := data.NewField(field.Name, fieldLabels, []*float64{value})
+			newField.Config = field.Config
+			out.Fields = append(out.Fields, newField)
+		}
+	}
+	setFrameType(out, data.FrameTypeNumericWide)
+	return out, nil
+}
+
+func setFrameType(frame *data.Frame, frameType data.FrameType) {
+	if frame.Meta == nil {
+		frame.Meta = &data.FrameMeta{}
+	}
+	frame.Meta.Type = frameType
+}
+
+func describeFields(frame *data.Frame) string {
+	if len(frame.Fields) == 0 {
+		return "no fields"
+	}
+	fields := []string{}
+	for _, field := range frame.Fields {
+		fields = append(fields, fmt.Sprintf("%s (%s)", field.Name, field.Type().ItemTypeString()))
+	}
+	return "fields " + strings.Join(fields, ", ")
+}
@@ -0,0 +1,70 @@
+package infinity_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestShapeFrameForFormat(t *testing.T) {
+	ctx := context.Background()
+	t.Run("numeric", func(t *testing.T) {
+		frame := data.NewFrame("A",
+			data.NewField("host", nil, []*string{toSP("a"), toSP("b")}),
+			data.NewField("cpu", nil, []int64{10, 20}),
+			data.NewField("mem", nil, []*float64{nil, toFP(0.5)}),
+			data.NewField("time", nil, []time.Time{time.Unix(0, 0), time.Unix(1, 0)}),
+		)
+		got, err := infinity.ShapeFrameForFormat(ctx, frame, models.Query{Format: "numeric"}, false)
+		require.Nil(t, err)
+		require.Equal(t, data.FrameTypeNumericWide, got.Meta.Type)
+		require.Equal(t, 1, got.Rows())
+		require.Equal(t, 4, len(got.Fields))
+		require.Equal(t, "cpu", got.Fields[2].Name)
+		require.Equal(t, data.Labels{"host": "b"}, got.Fields[2].Labels)
+		require.Equal(t, 20.0, *got.Fields[2].At(0).(*float64))
+		require.Nil(t, got.Fields[1].At(0).(*float64))
+	})
+	t.Run("numeric with duplicate labels", func(t *testing.T) {
+		frame := data.NewFrame("A",
+			data.NewField("host", nil, []string{"a", "a"}),
+			data.NewField("cpu", nil, []float64{10, 20}),
+		)
+		_, err := infinity.ShapeFrameForFormat(ctx, frame, models.Query{Format: "numeric"}, false)
+		require.ErrorContains(t, err, "unique label sets")
+	})
+	t.Run("numeric without numeric fields", func(t *testing.T) {
+		frame := data.NewFrame("A", data.NewField("host", nil, []string{"a"}))
+		_, err := infinity.ShapeFrameForFormat(ctx, frame, models.Query{Format: "numeric"}, false)
+		require.ErrorContains(t, err, "host (string)")
+	})
+	t.Run("timeseries", func(t *testing.T) {
+		frame := data.NewFrame("A",
+			data.NewField("time", nil, []time.Time{time.Unix(0, 0), time.Unix(0, 0)}),
+			data.NewField("host", nil, []string{"a", "b"}),
+			data.NewField("cpu", nil, []float64{10, 20}),
+		)
+		got, err := infinity.ShapeFrameForFormat(ctx, frame, models.Query{Format: "timeseries"}, true)
+		require.Nil(t, err)
+		require.Equal(t, data.FrameTypeTimeSeriesWide, got.Meta.Type)
+		require.Equal(t, 3, len(got.Fields))
+		require.Equal(t, data.Labels{"host": "a"}, got.Fields[1].Labels)
+	})
+	t.Run("timeseries without time field", func(t *testing.T) {
+		frame := data.NewFrame("A", data.NewField("cpu", nil, []float64{10}))
+		got, err := infinity.ShapeFrameForFormat(ctx, frame, models.Query{Format: "timeseries"}, false)
+		require.Nil(t, err)
+		require.Equal(t, frame, got)
+		_, err = infinity.ShapeFrameForFormat(ctx, frame, models.Query{Format: "timeseries"}, true)
+		require.ErrorContains(t, err, "requires a time field")
+	})
+	t.Run("alerting request", func(t *testing.T) {
+		require.True(t, infinity.IsAlertingRequest(map[string]string{"FromAlert": "true"}))
+		require.False(t, infinity.IsAlertingRequest(map[string]string{}))
+	})
+}
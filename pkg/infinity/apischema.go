@@ -0,0 +1,510 @@
+package infinity
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+// maxAPISchemaBytes limits the size of the WSDL and OpenAPI documents
+const maxAPISchemaBytes = 20 * 1024 * 1024
+
+// APIOperation is the operation of the SOAP service or the REST API. Operations are used by the query editor for autocomplete
+type APIOperation struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Method      string         `json:"method,omitempty"`
+	Path        string         `json:"path,omitempty"`
+	Action      string         `json:"action,omitempty"` // SOAPAction of the SOAP operations
+	Endpoint    string         `json:"endpoint,omitempty"`
+	Parameters  []APIParameter `json:"parameters,omitempty"`
+	Example     any            `json:"example,omitempty"` // example of the successful response, if documented
+	Query       *APIQuery      `json:"query,omitempty"`   // query pre-filled for the operation
+}
+
+// APIQuery is the partial query model built from the operation. The query editor merges it into the query when the operation is selected
+type APIQuery struct {
+	URL     string                         `json:"url"`
+	Method  string                         `json:"method"`
+	Params  []models.URLOptionKeyValuePair `json:"params,omitempty"`
+	Headers []models.URLOptionKeyValuePair `json:"headers,omitempty"`
+}
+
+type APIParameter struct {
+	Name        string `json:"name"`
+	In          string `json:"in,omitempty"` // 'query' | 'path' | 'header' | 'body' | 'element'
+	Type        string `json:"type,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Description string `json:"description,omitempty"`
+	Default     string `json:"default,omitempty"`
+}
+
+// GetAPIOperations fetches the WSDL or OpenAPI document using the client of the datasource and returns the operations
+func GetAPIOperations(ctx context.Context, client Client, documentURL string) ([]APIOperation, error) {
+	ctx, span := tracing.DefaultTracer().Start(ctx, "GetAPIOperations")
+	defer span.End()
+	body, err := getAPISchemaDocument(ctx, client, documentURL)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAPIOperations(body)
+}
+
+// GetOpenAPIOperations returns the operations of the OpenAPI document configured in the datasource settings,
+// along with the queries pre-filled for each operation
+func GetOpenAPIOperations(ctx context.Context, client Client) ([]APIOperation, error) {
+	ctx, span := tracing.DefaultTracer().Start(ctx, "GetOpenAPIOperations")
+	defer span.End()
+	if !client.Settings.EnableOpenAPI || strings.TrimSpace(client.Settings.OpenAPIUrl) == "" {
+		return nil, UserError(errors.New("open api is not enabled in the datasource settings"))
+	}
+	body, err := getAPISchemaDocument(ctx, client, client.Settings.OpenAPIUrl)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseOpenAPIDocument(body)
+	if err != nil {
+		return nil, err
+	}
+	operations := getOpenAPIOperations(doc)
+	for i, op := range operations {
+		endpoint := op.Endpoint
+		if client.Settings.OpenAPIBaseUrl != "" {
+			endpoint = strings.TrimSuffix(client.Settings.OpenAPIBaseUrl, "/")
+		}
+		operations[i].Endpoint = resolveOpenAPIEndpoint(client.Settings.OpenAPIUrl, endpoint)
+		operations[i].Query = getOpenAPIQuery(operations[i])
+	}
+	return operations, nil
+}
+
+// resolveOpenAPIEndpoint resolves the relative server urls (ex: /api/v1) against the url of the document
+func resolveOpenAPIEndpoint(documentURL string, endpoint string) string {
+	base, err := url.Parse(documentURL)
+	if err != nil {
+		return endpoint
+	}
+	ref, err := url.Parse(endpoint)
+	if err != nil || ref.IsAbs() {
+		return endpoint
+	}
+	return strings.TrimSuffix(base.ResolveReference(ref).String(), "/")
+}
+
+func getOpenAPIQuery(op APIOperation) *APIQuery {
+	query := &APIQuery{URL: op.Endpoint + op.Path, Method: op.Method}
+	for _, p := range op.Parameters {
+		switch p.In {
+		case "query":
+			query.Params = append(query.Params, models.URLOptionKeyValuePair{Key: p.Name, Value: p.Default})
+		case "header":
+			query.Headers = append(query.Headers, models.URLOptionKeyValuePair{Key: p.Name, Value: p.Default})
+		}
+	}
+	return query
+}
+
+func getAPISchemaDocument(ctx context.Context, client Client, documentURL string) ([]byte, error) {
+	if strings.TrimSpace(documentURL) == "" {
+		return nil, UserError(errors.New("document url is required"))
+	}
+	if !CanAllowURL(documentURL, client.Settings.AllowedHosts) {
+		return nil, UserError(errors.New("requested URL is not allowed. To allow this URL, update the datasource config Security -> Allowed Hosts section"))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, documentURL, nil)
+	if err != nil {
+		return nil, UserError(fmt.Errorf("invalid document url. %w", err))
+	}
+	res, err := client.HttpClient.Do(req)
+	if err != nil {
+		return nil, DownstreamError(fmt.Errorf("error fetching the document. %w", err), 0)
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= http.StatusBadRequest {
+		return nil, DownstreamError(fmt.Errorf("error fetching the document. %s", res.Status), res.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxAPISchemaBytes+1))
+	if err != nil {
+		return nil, DownstreamError(fmt.Errorf("error reading the document. %w", err), 0)
+	}
+	if len(body) > maxAPISchemaBytes {
+		return nil, DownstreamError(errors.New("document is too large"), 0)
+	}
+	return body, nil
+}
+
+// ParseAPIOperations returns the operations of the WSDL (1.1) or OpenAPI (swagger 2.0 or openapi 3.x, json or yaml) document
+func ParseAPIOperations(body []byte) ([]APIOperation, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("<")) {
+		return ParseWSDL(body)
+	}
+	doc, err := parseOpenAPIDocument(body)
+	if err != nil {
+		return nil, err
+	}
+	return getOpenAPIOperations(doc), nil
+}
+
+type wsdlDefinitions struct {
+	Types struct {
+		Schemas []struct {
+			Elements []wsdlSchemaElement `xml:"element"`
+		} `xml:"schema"`
+	} `xml:"types"`
+	Messages []struct {
+		Name  string `xml:"name,attr"`
+		Parts []struct {
+			Name    string `xml:"name,attr"`
+			Element string `xml:"element,attr"`
+			Type    string `xml:"type,attr"`
+		} `xml:"part"`
+	} `xml:"message"`
+	PortTypes []struct {
+		Operations []struct {
+			Name          string `xml:"name,attr"`
+			Documentation string `xml:"documentation"`
+			Input         struct {
+				Message string `xml:"message,attr"`
+			} `xml:"input"`
+		} `xml:"operation"`
+	} `xml:"portType"`
+	Bindings []struct {
+		Operations []struct {
+			Name     string `xml:"name,attr"`
+			Protocol struct {
+				SOAPAction string `xml:"soapAction,attr"`
+			} `xml:"operation"`
+		} `xml:"operation"`
+	} `xml:"binding"`
+	Services []struct {
+		Ports []struct {
+			Address struct {
+				Location string `xml:"location,attr"`
+			} `xml:"address"`
+		} `xml:"port"`
+	} `xml:"service"`
+}
+
+type wsdlSchemaElement struct {
+	Name        string `xml:"name,attr"`
+	Type        string `xml:"type,attr"`
+	MinOccurs   string `xml:"minOccurs,attr"`
+	ComplexType struct {
+		Sequence struct {
+			Elements []wsdlSchemaElement `xml:"element"`
+		} `xml:"sequence"`
+		All struct {
+			Elements []wsdlSchemaElement `xml:"element"`
+		} `xml:"all"`
+	} `xml:"complexType"`
+}
+
+// ParseWSDL returns the operations of the WSDL 1.1 document along with the fields of the input elements
+func ParseWSDL(body []byte) ([]APIOperation, error) {
+	var defs wsdlDefinitions
+	if err := xml.Unmarshal(body, &defs); err != nil {
+		return nil, UserError(fmt.Errorf("invalid WSDL document. %w", err))
+	}
+	elements := map[string]wsdlSchemaElement{}
+	for _, schema := range defs.Types.Schemas {
+		for _, e := range schema.Elements {
+			elements[e.Name] = e
+		}
+	}
+	actions := map[string]string{}
+	for _, binding := range defs.Bindings {
+		for _, op := range binding.Operations {
+			if op.Protocol.SOAPAction != "" {
+				actions[op.Name] = op.Protocol.SOAPAction
+			}
+		}
+	}
+	endpoint := ""
+	for _, service := range defs.Services {
+		for _, port := range service.Ports {
+			if endpoint == "" {
+				endpoint = port.Address.Location
+			}
+		}
+	}
+	operations := []APIOperation{}
+	seen := map[string]bool{}
+	for _, portType := range defs.PortTypes {
+		for _, op := range portType.Operations {
+			if seen[op.Name] {
+				continue
+			}
+			seen[op.Name] = true
+			operation := APIOperation{Name: op.Name, Description: strings.TrimSpace(op.Documentation), Method: http.MethodPost, Action: actions[op.Name], Endpoint: endpoint}
+			for _, message := range defs.Messages {
+				if message.Name != localXMLName(op.Input.Message) {
+					continue
+				}
+				for _, part := range message.Parts {
+					element, ok := elements[localXMLName(part.Element)]
+					if !ok {
+						operation.Parameters = append(operation.Parameters, APIParameter{Name: part.Name, In: "body", Type: localXMLName(part.Type)})
+						continue
+					}
+					fields := append([]wsdlSchemaElement{}, element.ComplexType.Sequence.Elements...)
+					fields = append(fields, element.ComplexType.All.Elements...)
+					for _, field := range fields {
+						operation.Parameters = append(operation.Parameters, APIParameter{Name: field.Name, In: "element", Type: localXMLName(field.Type), Required: field.MinOccurs != "0"})
+					}
+				}
+			}
+			operations = append(operations, operation)
+		}
+	}
+	sort.SliceStable(operations, func(i, j int) bool { return operations[i].Name < operations[j].Name })
+	return operations, nil
+}
+
+func localXMLName(name string) string {
+	if idx := strings.LastIndex(name, ":"); idx != -1 {
+		return name[idx+1:]
+	}
+	return name
+}
+
+func parseOpenAPIDocument(body []byte) (map[string]any, error) {
+	var doc any
+	if err := yaml.Unmarshal(body, &doc); err != nil {
+		return nil, UserError(fmt.Errorf("invalid OpenAPI document. %w", err))
+	}
+	out, ok := normalizeYAML(doc).(map[string]any)
+	if !ok || (out["openapi"] == nil && out["swagger"] == nil) {
+		return nil, UserError(errors.New("invalid OpenAPI document. openapi or swagger version not found"))
+	}
+	return out, nil
+}
+
+// normalizeYAML converts the maps with non string keys (ex: response status codes) into the maps with string keys
+func normalizeYAML(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, item := range t {
+			t[k] = normalizeYAML(item)
+		}
+		return t
+	case map[any]any:
+		out := map[string]any{}
+		for k, item := range t {
+			out[fmt.Sprintf("%v", k)] = normalizeYAML(item)
+		}
+		return out
+	case []any:
+		for i, item := range t {
+			t[i] = normalizeYAML(item)
+		}
+		return t
+	}
+	return v
+}
+
+var openAPIMethods = []string{"get", "post", "put", "patch", "delete", "head", "options"}
+
+func getOpenAPIOperations(doc map[string]any) []APIOperation {
+	endpoint := getOpenAPIBaseURL(doc)
+	paths, _ := doc["paths"].(map[string]any)
+	keys := []string{}
+	for k := range paths {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	operations := []APIOperation{}
+	for _, path := range keys {
+		item, _ := resolveOpenAPIRef(doc, paths[path]).(map[string]any)
+		for _, method := range openAPIMethods {
+			op, ok := item[method].(map[string]any)
+			if !ok {
+				continue
+			}
+			name, _ := op["operationId"].(string)
+			if name == "" {
+				name = strings.ToUpper(method) + " " + path
+			}
+			description, _ := op["summary"].(string)
+			if description == "" {
+				description, _ = op["description"].(string)
+			}
+			operation := APIOperation{Name: name, Description: description, Method: strings.ToUpper(method), Path: path, Endpoint: endpoint}
+			params := map[string]int{}
+			for _, list := range []any{item["parameters"], op["parameters"]} {
+				items, _ := list.([]any)
+				for _, p := range items {
+					param, ok := resolveOpenAPIRef(doc, p).(map[string]any)
+					if !ok {
+						continue
+					}
+					parameter := getOpenAPIParameter(doc, param)
+					// operation parameters override the path item parameters
+					if idx, ok := params[parameter.In+":"+parameter.Name]; ok {
+						operation.Parameters[idx] = parameter
+						continue
+					}
+					params[parameter.In+":"+parameter.Name] = len(operation.Parameters)
+					operation.Parameters = append(operation.Parameters, parameter)
+				}
+			}
+			operation.Example = getOpenAPIExample(doc, op)
+			if body, ok := resolveOpenAPIRef(doc, op["requestBody"]).(map[string]any); ok {
+				required, _ := body["required"].(bool)
+				operation.Parameters = append(operation.Parameters, APIParameter{Name: "body", In: "body", Type: "object", Required: required})
+			}
+			operations = append(operations, operation)
+		}
+	}
+	return operations
+}
+
+func getOpenAPIParameter(doc map[string]any, param map[string]any) APIParameter {
+	parameter := APIParameter{}
+	parameter.Name, _ = param["name"].(string)
+	parameter.In, _ = param["in"].(string)
+	parameter.Required, _ = param["required"].(bool)
+	parameter.Description, _ = param["description"].(string)
+	parameter.Type, _ = param["type"].(string)
+	defaultValue := param["default"]
+	if defaultValue == nil {
+		defaultValue = param["example"]
+	}
+	if schema, ok := resolveOpenAPIRef(doc, param["schema"]).(map[string]any); ok {
+		if parameter.Type == "" {
+			parameter.Type, _ = schema["type"].(string)
+		}
+		if defaultValue == nil {
+			defaultValue = schema["default"]
+		}
+	}
+	if defaultValue != nil {
+		parameter.Default = fmt.Sprintf("%v", defaultValue)
+	}
+	return parameter
+}
+
+// getOpenAPIExample returns the example of the first successful response. Both the openapi 3 (content.<media type>.example(s))
+// and swagger 2 (examples.<media type>) examples are supported. Schema level examples are used as the fallback
+func getOpenAPIExample(doc map[string]any, op map[string]any) any {
+	responses, _ := op["responses"].(map[string]any)
+	codes := []string{}
+	for code := range responses {
+		if strings.HasPrefix(code, "2") {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		response, _ := resolveOpenAPIRef(doc, responses[code]).(map[string]any)
+		if examples, ok := response["examples"].(map[string]any); ok {
+			if example := getOpenAPIMediaTypeValue(examples); example != nil {
+				return example
+			}
+		}
+		content, _ := response["content"].(map[string]any)
+		for _, mediaType := range getOpenAPIMediaTypes(content) {
+			media, _ := content[mediaType].(map[string]any)
+			if example, ok := media["example"]; ok {
+				return example
+			}
+			if examples, ok := media["examples"].(map[string]any); ok {
+				keys := getOpenAPIMediaTypes(examples)
+				if len(keys) > 0 {
+					if example, ok := resolveOpenAPIRef(doc, examples[keys[0]]).(map[string]any); ok && example["value"] != nil {
+						return example["value"]
+					}
+				}
+			}
+			if schema, ok := resolveOpenAPIRef(doc, media["schema"]).(map[string]any); ok && schema["example"] != nil {
+				return schema["example"]
+			}
+		}
+		if schema, ok := resolveOpenAPIRef(doc, response["schema"]).(map[string]any); ok && schema["example"] != nil {
+			return schema["example"]
+		}
+	}
+	return nil
+}
+
+// getOpenAPIMediaTypeValue returns the value of the json media type, or the first media type if json is not documented
+func getOpenAPIMediaTypeValue(values map[string]any) any {
+	keys := getOpenAPIMediaTypes(values)
+	if len(keys) == 0 {
+		return nil
+	}
+	return values[keys[0]]
+}
+
+// getOpenAPIMediaTypes returns the keys sorted with the json media types first
+func getOpenAPIMediaTypes(values map[string]any) []string {
+	keys := []string{}
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		ji, jj := strings.Contains(keys[i], "json"), strings.Contains(keys[j], "json")
+		if ji != jj {
+			return ji
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// getOpenAPIBaseURL returns the first server url (openapi 3) or the url built from the host and base path (swagger 2)
+func getOpenAPIBaseURL(doc map[string]any) string {
+	if servers, ok := doc["servers"].([]any); ok && len(servers) > 0 {
+		if server, ok := servers[0].(map[string]any); ok {
+			u, _ := server["url"].(string)
+			return strings.TrimSuffix(u, "/")
+		}
+	}
+	host, _ := doc["host"].(string)
+	if host == "" {
+		return ""
+	}
+	scheme := "https"
+	if schemes, ok := doc["schemes"].([]any); ok && len(schemes) > 0 {
+		if s, ok := schemes[0].(string); ok {
+			scheme = s
+		}
+	}
+	basePath, _ := doc["basePath"].(string)
+	return strings.TrimSuffix(scheme+"://"+host+basePath, "/")
+}
+
+// resolveOpenAPIRef resolves the local references such as #/components/parameters/limit. Other values are returned as is
+func resolveOpenAPIRef(doc map[string]any, v any) any {
+	for depth := 0; depth < 10; depth++ {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return v
+		}
+		ref, ok := m["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#/") {
+			return v
+		}
+		var current any = doc
+		for _, key := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			key = strings.NewReplacer("~1", "/", "~0", "~").Replace(key)
+			obj, ok := current.(map[string]any)
+			if !ok {
+				return nil
+			}
+			current = obj[key]
+		}
+		v = current
+	}
+	return v
+}
@@ -0,0 +1,245 @@
+package infinity_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+const testWSDL = `<?xml version="1.0" encoding="utf-8"?>
+<wsdl:definitions xmlns:wsdl="http://schemas.xmlsoap.org/wsdl/" xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/" xmlns:s="http://www.w3.org/2001/XMLSchema" xmlns:tns="http://example.com/">
+  <wsdl:types>
+    <s:schema targetNamespace="http://example.com/">
+      <s:element name="Add">
+        <s:complexType>
+          <s:sequence>
+            <s:element minOccurs="1" name="intA" type="s:int" />
+            <s:element minOccurs="0" name="intB" type="s:int" />
+          </s:sequence>
+        </s:complexType>
+      </s:element>
+    </s:schema>
+  </wsdl:types>
+  <wsdl:message name="AddSoapIn"><wsdl:part name="parameters" element="tns:Add" /></wsdl:message>
+  <wsdl:message name="PingSoapIn"><wsdl:part name="host" type="s:string" /></wsdl:message>
+  <wsdl:portType name="CalculatorSoap">
+    <wsdl:operation name="Ping"><wsdl:input message="tns:PingSoapIn" /></wsdl:operation>
+    <wsdl:operation name="Add"><wsdl:documentation>Adds two integers</wsdl:documentation><wsdl:input message="tns:AddSoapIn" /></wsdl:operation>
+  </wsdl:portType>
+  <wsdl:binding name="CalculatorSoap" type="tns:CalculatorSoap">
+    <wsdl:operation name="Add"><soap:operation soapAction="http://example.com/Add" /></wsdl:operation>
+  </wsdl:binding>
+  <wsdl:service name="Calculator">
+    <wsdl:port name="CalculatorSoap" binding="tns:CalculatorSoap"><soap:address location="http://example.com/calculator.asmx" /></wsdl:port>
+  </wsdl:service>
+</wsdl:definitions>`
+
+const testOpenAPI3 = `openapi: 3.0.0
+servers:
+  - url: https://api.example.com/v1/
+paths:
+  /users/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema:
+          type: string
+    get:
+      operationId: getUser
+      summary: Get the user
+      parameters:
+        - $ref: '#/components/parameters/fields'
+      responses:
+        200:
+          description: ok
+    put:
+      requestBody:
+        required: true
+        content:
+          application/json: {}
+components:
+  parameters:
+    fields:
+      name: fields
+      in: query
+      schema:
+        type: array
+`
+
+const testSwagger2 = `{
+  "swagger": "2.0",
+  "host": "api.example.com",
+  "basePath": "/v2",
+  "schemes": ["http"],
+  "paths": { "/pets": { "get": { "description": "List pets", "parameters": [{ "name": "limit", "in": "query", "type": "integer" }] } } }
+}`
+
+func TestParseAPIOperations(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []infinity.APIOperation
+		wantErr error
+	}{
+		{
+			name: "wsdl",
+			body: testWSDL,
+			want: []infinity.APIOperation{
+				{Name: "Add", Description: "Adds two integers", Method: "POST", Action: "http://example.com/Add", Endpoint: "http://example.com/calculator.asmx", Parameters: []infinity.APIParameter{
+					{Name: "intA", In: "element", Type: "int", Required: true},
+					{Name: "intB", In: "element", Type: "int"},
+				}},
+				{Name: "Ping", Method: "POST", Endpoint: "http://example.com/calculator.asmx", Parameters: []infinity.APIParameter{{Name: "host", In: "body", Type: "string"}}},
+			},
+		},
+		{
+			name: "openapi 3 yaml",
+			body: testOpenAPI3,
+			want: []infinity.APIOperation{
+				{Name: "getUser", Description: "Get the user", Method: "GET", Path: "/users/{id}", Endpoint: "https://api.example.com/v1", Parameters: []infinity.APIParameter{
+					{Name: "id", In: "path", Type: "string", Required: true},
+					{Name: "fields", In: "query", Type: "array"},
+				}},
+				{Name: "PUT /users/{id}", Method: "PUT", Path: "/users/{id}", Endpoint: "https://api.example.com/v1", Parameters: []infinity.APIParameter{
+					{Name: "id", In: "path", Type: "string", Required: true},
+					{Name: "body", In: "body", Type: "object", Required: true},
+				}},
+			},
+		},
+		{
+			name: "swagger 2 json",
+			body: testSwagger2,
+			want: []infinity.APIOperation{
+				{Name: "GET /pets", Description: "List pets", Method: "GET", Path: "/pets", Endpoint: "http://api.example.com/v2", Parameters: []infinity.APIParameter{{Name: "limit", In: "query", Type: "integer"}}},
+			},
+		},
+		{
+			name:    "unknown document",
+			body:    `{ "foo": "bar" }`,
+			wantErr: fmt.Errorf("invalid OpenAPI document. openapi or swagger version not found"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := infinity.ParseAPIOperations([]byte(tt.body))
+			if tt.wantErr != nil {
+				require.NotNil(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				require.Equal(t, infinity.ErrorKindUser, infinity.GetErrorKind(err))
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGetAPIOperations(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/service.wsdl" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(testWSDL))
+	}))
+	defer server.Close()
+	client := infinity.Client{HttpClient: server.Client()}
+	t.Run("should return the operations", func(t *testing.T) {
+		got, err := infinity.GetAPIOperations(context.Background(), client, server.URL+"/service.wsdl")
+		require.Nil(t, err)
+		require.Equal(t, 2, len(got))
+		require.Equal(t, "Add", got[0].Name)
+	})
+	t.Run("should return downstream error when the document not found", func(t *testing.T) {
+		_, err := infinity.GetAPIOperations(context.Background(), client, server.URL+"/missing.wsdl")
+		require.NotNil(t, err)
+		require.Equal(t, infinity.ErrorKindDownstream, infinity.GetErrorKind(err))
+	})
+	t.Run("should not allow the hosts not in the allowed list", func(t *testing.T) {
+		client := infinity.Client{HttpClient: server.Client(), Settings: models.InfinitySettings{AllowedHosts: []string{"https://example.com"}}}
+		_, err := infinity.GetAPIOperations(context.Background(), client, server.URL+"/service.wsdl")
+		require.NotNil(t, err)
+		require.Equal(t, infinity.ErrorKindUser, infinity.GetErrorKind(err))
+	})
+}
+
+func TestGetOpenAPIOperations(t *testing.T) {
+	spec := `openapi: 3.0.0
+servers:
+  - url: /api
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+            default: 10
+        - name: X-Tenant
+          in: header
+      responses:
+        '200':
+          description: ok
+          content:
+            text/plain:
+              example: plain
+            application/json:
+              examples:
+                pets:
+                  value: [{ "name": "tom" }]
+  /pets/{id}:
+    get:
+      operationId: getPet
+      responses:
+        '200':
+          $ref: '#/components/responses/pet'
+components:
+  responses:
+    pet:
+      description: ok
+      content:
+        application/json:
+          schema:
+            example: { "name": "tom" }
+`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(spec))
+	}))
+	defer server.Close()
+	t.Run("should return error when open api not enabled", func(t *testing.T) {
+		_, err := infinity.GetOpenAPIOperations(context.Background(), infinity.Client{HttpClient: server.Client()})
+		require.NotNil(t, err)
+		require.Equal(t, infinity.ErrorKindUser, infinity.GetErrorKind(err))
+	})
+	t.Run("should return the operations with examples and pre-filled queries", func(t *testing.T) {
+		client := infinity.Client{HttpClient: server.Client(), Settings: models.InfinitySettings{EnableOpenAPI: true, OpenAPIUrl: server.URL + "/spec/openapi.yaml"}}
+		got, err := infinity.GetOpenAPIOperations(context.Background(), client)
+		require.Nil(t, err)
+		require.Equal(t, 2, len(got))
+		require.Equal(t, "listPets", got[0].Name)
+		require.Equal(t, []any{map[string]any{"name": "tom"}}, got[0].Example)
+		require.Equal(t, &infinity.APIQuery{
+			URL:     server.URL + "/api/pets",
+			Method:  "GET",
+			Params:  []models.URLOptionKeyValuePair{{Key: "limit", Value: "10"}},
+			Headers: []models.URLOptionKeyValuePair{{Key: "X-Tenant"}},
+		}, got[0].Query)
+		require.Equal(t, map[string]any{"name": "tom"}, got[1].Example)
+		require.Equal(t, server.URL+"/api/pets/{id}", got[1].Query.URL)
+	})
+	t.Run("should use the base url of the settings", func(t *testing.T) {
+		client := infinity.Client{HttpClient: server.Client(), Settings: models.InfinitySettings{EnableOpenAPI: true, OpenAPIUrl: server.URL + "/openapi.yaml", OpenAPIBaseUrl: "https://example.com/v2/"}}
+		got, err := infinity.GetOpenAPIOperations(context.Background(), client)
+		require.Nil(t, err)
+		require.Equal(t, "https://example.com/v2/pets", got[0].Query.URL)
+	})
+}
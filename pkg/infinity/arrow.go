@@ -0,0 +1,116 @@
+package infinity
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/apache/arrow/go/v13/arrow"
+	"github.com/apache/arrow/go/v13/arrow/array"
+	"github.com/apache/arrow/go/v13/arrow/ipc"
+	"github.com/apache/arrow/go/v13/arrow/memory"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+// arrowFileMagic is the leading bytes of the arrow IPC file format. Responses without it are read as IPC stream
+var arrowFileMagic = []byte("ARROW1")
+
+// GetArrowBackendResponse converts the arrow IPC stream (application/vnd.apache.arrow.stream) or file response into a frame.
+// Record batches of the stream are concatenated into a single frame
+func GetArrowBackendResponse(ctx context.Context, body []byte, query models.Query) (*data.Frame, error) {
+	_, span := tracing.DefaultTracer().Start(ctx, "GetArrowBackendResponse")
+	defer span.End()
+	defer trackTiming(ctx, parseTiming)()
+	dummyFrame := GetDummyFrame(query)
+	if len(bytes.TrimSpace(body)) == 0 {
+		return dummyFrame, nil
+	}
+	var frame *data.Frame
+	var err error
+	if bytes.HasPrefix(body, arrowFileMagic) {
+		frame, err = data.UnmarshalArrowFrame(body)
+	} else {
+		frame, err = readArrowStream(body)
+	}
+	if err != nil {
+		return dummyFrame, DownstreamError(fmt.Errorf("error reading arrow response. %w", err), 0)
+	}
+	if frame.Name == "" {
+		frame.Name = dummyFrame.Name
+	}
+	frame.RefID = query.RefID
+	if frame.Meta == nil {
+		frame.Meta = dummyFrame.Meta
+	} else if frame.Meta.Custom == nil {
+		frame.Meta.Custom = dummyFrame.Meta.Custom
+	}
+	return frame, nil
+}
+
+func readArrowStream(body []byte) (*data.Frame, error) {
+	reader, err := ipc.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Release()
+	records := []arrow.Record{}
+	defer func() {
+		for _, r := range records {
+			r.Release()
+		}
+	}()
+	for reader.Next() {
+		record := reader.Record()
+		record.Retain()
+		records = append(records, record)
+	}
+	if err := reader.Err(); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	record, err := concatArrowRecords(reader.Schema(), records)
+	if err != nil {
+		return nil, err
+	}
+	defer record.Release()
+	return data.FromArrowRecord(record)
+}
+
+// concatArrowRecords merges the record batches column by column so that the frame is built in a single pass
+func concatArrowRecords(schema *arrow.Schema, records []arrow.Record) (arrow.Record, error) {
+	if len(records) == 1 {
+		records[0].Retain()
+		return records[0], nil
+	}
+	columns := make([]arrow.Array, schema.NumFields())
+	defer func() {
+		for _, c := range columns {
+			if c != nil {
+				c.Release()
+			}
+		}
+	}()
+	rows := int64(0)
+	for _, r := range records {
+		rows += r.NumRows()
+	}
+	for i := range columns {
+		if len(records) == 0 {
+			columns[i] = array.MakeArrayOfNull(memory.DefaultAllocator, schema.Field(i).Type, 0)
+			continue
+		}
+		chunks := make([]arrow.Array, len(records))
+		for j, r := range records {
+			chunks[j] = r.Column(i)
+		}
+		column, err := array.Concatenate(chunks, memory.DefaultAllocator)
+		if err != nil {
+			return nil, err
+		}
+		columns[i] = column
+	}
+	return array.NewRecord(schema, columns, rows), nil
+}
@@ -0,0 +1,81 @@
+package infinity_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/apache/arrow/go/v13/arrow"
+	"github.com/apache/arrow/go/v13/arrow/array"
+	"github.com/apache/arrow/go/v13/arrow/ipc"
+	"github.com/apache/arrow/go/v13/arrow/memory"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func arrowStream(t *testing.T, batches ...[]int64) []byte {
+	t.Helper()
+	schema := arrow.NewSchema([]arrow.Field{{Name: "name", Type: arrow.BinaryTypes.String}, {Name: "value", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	var buf bytes.Buffer
+	w := ipc.NewWriter(&buf, ipc.WithSchema(schema))
+	for _, values := range batches {
+		b := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+		for _, v := range values {
+			b.Field(0).(*array.StringBuilder).Append("row")
+			b.Field(1).(*array.Int64Builder).Append(v)
+		}
+		record := b.NewRecord()
+		require.Nil(t, w.Write(record))
+		record.Release()
+		b.Release()
+	}
+	require.Nil(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestGetArrowBackendResponse(t *testing.T) {
+	t.Run("should concatenate the record batches of the stream", func(t *testing.T) {
+		frame, err := infinity.GetArrowBackendResponse(context.Background(), arrowStream(t, []int64{1, 2}, []int64{3}), models.Query{RefID: "A", Type: models.QueryTypeArrow})
+		require.Nil(t, err)
+		require.Equal(t, "A", frame.RefID)
+		require.Equal(t, 3, frame.Rows())
+		require.Equal(t, int64(3), frame.Fields[1].At(2))
+	})
+	t.Run("should read the arrow file format", func(t *testing.T) {
+		body, err := data.NewFrame("test", data.NewField("value", nil, []float64{1.5})).MarshalArrow()
+		require.Nil(t, err)
+		frame, err := infinity.GetArrowBackendResponse(context.Background(), body, models.Query{RefID: "A", Type: models.QueryTypeArrow})
+		require.Nil(t, err)
+		require.Equal(t, "test", frame.Name)
+		require.Equal(t, 1.5, frame.Fields[0].At(0))
+	})
+	t.Run("should fail on invalid response", func(t *testing.T) {
+		_, err := infinity.GetArrowBackendResponse(context.Background(), []byte("not arrow"), models.Query{RefID: "A", Type: models.QueryTypeArrow})
+		require.ErrorContains(t, err, "error reading arrow response")
+	})
+	t.Run("should read the stream from url", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Contains(t, r.Header.Get("Accept"), "application/vnd.apache.arrow.stream")
+			w.Header().Set("Content-Type", "application/vnd.apache.arrow.stream")
+			_, _ = w.Write(arrowStream(t, []int64{1, 2}))
+		}))
+		defer server.Close()
+		client, err := infinity.NewClient(context.Background(), models.InfinitySettings{})
+		require.Nil(t, err)
+		query := models.ApplyDefaultsToQuery(context.Background(), models.Query{RefID: "A", Type: models.QueryTypeArrow, Source: "url", URL: server.URL})
+		frame, err := infinity.GetFrameForURLSources(context.Background(), query, *client, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, 2, frame.Rows())
+	})
+	t.Run("should read base64 encoded inline data", func(t *testing.T) {
+		query := models.Query{RefID: "A", Type: models.QueryTypeArrow, Source: "inline", Parser: models.InfinityParserBackend, Data: base64.StdEncoding.EncodeToString(arrowStream(t, []int64{7}))}
+		frame, err := infinity.GetFrameForInlineSources(context.Background(), query)
+		require.Nil(t, err)
+		require.Equal(t, int64(7), frame.Fields[1].At(0))
+	})
+}
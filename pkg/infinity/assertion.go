@@ -0,0 +1,156 @@
+package infinity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+	"github.com/yesoreyeram/grafana-plugins/lib/go/jsonframer"
+)
+
+// assertionResponse is the response of the url query the assertions are evaluated against
+type assertionResponse struct {
+	body       any
+	statusCode int
+	duration   time.Duration
+	err        error
+}
+
+// GetAssertionsFrame sends the request of the url query and evaluates the assertions of the query against the response.
+// Returns a row per assertion, with passed as 1 or 0 so the frame can be used in the alert rules. Failed requests fail the
+// assertions instead of failing the query, so the unreachable services are reported the same way as the failed assertions
+func GetAssertionsFrame(ctx context.Context, query models.Query, infClient Client, requestHeaders map[string]string) (*data.Frame, error) {
+	ctx, span := tracing.DefaultTracer().Start(ctx, "GetAssertionsFrame")
+	defer span.End()
+	checks := make([]func(res assertionResponse) (string, bool, error), 0, len(query.Assertions))
+	descriptions := make([]string, 0, len(query.Assertions))
+	for _, assertion := range query.Assertions {
+		check, description, err := compileAssertion(assertion)
+		if err != nil {
+			return nil, UserError(err)
+		}
+		checks = append(checks, check)
+		descriptions = append(descriptions, description)
+	}
+	// assertions always check the live response
+	body, statusCode, duration, err := infClient.GetResults(withoutResponseCache(ctx), query, requestHeaders)
+	if ctxErr := ContextError(ctx); ctxErr != nil {
+		return nil, ctxErr
+	}
+	if infClient.IsMock {
+		duration = 123
+	}
+	res := assertionResponse{body: body, statusCode: statusCode, duration: duration, err: err}
+	frame := data.NewFrame(query.RefID,
+		data.NewField("assertion", nil, []string{}),
+		data.NewField("type", nil, []string{}),
+		data.NewField("expected", nil, []string{}),
+		data.NewField("actual", nil, []string{}),
+		data.NewField("passed", nil, []float64{}),
+		data.NewField("status_code", nil, []float64{}),
+		data.NewField("latency_ms", nil, []float64{}),
+		data.NewField("error", nil, []string{}),
+	)
+	failed := 0
+	for i, check := range checks {
+		actual, passed, checkErr := check(res)
+		errMessage := ""
+		if checkErr != nil {
+			errMessage = checkErr.Error()
+		}
+		result := 0.0
+		if passed {
+			result = 1
+		} else {
+			failed++
+		}
+		assertion := query.Assertions[i]
+		frame.AppendRow(descriptions[i], string(assertion.Type), assertion.Value, actual, result, float64(statusCode), float64(duration.Milliseconds()), errMessage)
+	}
+	frame.Meta = &data.FrameMeta{ExecutedQueryString: infClient.GetExecutedURL(ctx, query)}
+	if failed > 0 {
+		frame.AppendNotices(data.Notice{Severity: data.NoticeSeverityWarning, Text: fmt.Sprintf("%d of %d assertions failed", failed, len(checks))})
+	}
+	return frame, nil
+}
+
+// compileAssertion validates the assertion and returns the check of the assertion along with its description
+func compileAssertion(assertion models.Assertion) (func(res assertionResponse) (string, bool, error), string, error) {
+	value := strings.TrimSpace(assertion.Value)
+	switch assertion.Type {
+	case models.AssertionTypeStatusCode:
+		expected, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid status code assertion %q. value should be the status code", assertion.Value)
+		}
+		return func(res assertionResponse) (string, bool, error) {
+			if res.statusCode == 0 {
+				return "", false, res.err
+			}
+			return strconv.Itoa(res.statusCode), res.statusCode == expected, nil
+		}, fmt.Sprintf("status code equals %d", expected), nil
+	case models.AssertionTypeLatency:
+		maxLatency, err := strconv.ParseFloat(value, 64)
+		if err != nil || maxLatency <= 0 {
+			return nil, "", fmt.Errorf("invalid latency assertion %q. value should be the max latency in milliseconds", assertion.Value)
+		}
+		return func(res assertionResponse) (string, bool, error) {
+			if res.statusCode == 0 {
+				return "", false, res.err
+			}
+			latency := float64(res.duration.Milliseconds())
+			return strconv.FormatFloat(latency, 'f', -1, 64), latency < maxLatency, nil
+		}, fmt.Sprintf("latency under %sms", value), nil
+	case models.AssertionTypeBody:
+		re, err := regexp.Compile(assertion.Value)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid body assertion regex %q. %w", assertion.Value, err)
+		}
+		description := fmt.Sprintf("body matches %s", assertion.Value)
+		if assertion.Path != "" {
+			description = fmt.Sprintf("body %s matches %s", assertion.Path, assertion.Value)
+		}
+		return func(res assertionResponse) (string, bool, error) {
+			if res.err != nil {
+				return "", false, res.err
+			}
+			actual, err := getResponseValue(res.body, assertion.Path)
+			if err != nil {
+				return "", false, err
+			}
+			return actual, re.MatchString(actual), nil
+		}, description, nil
+	}
+	return nil, "", fmt.Errorf("invalid assertion type %q. type should be status_code, body or latency", assertion.Type)
+}
+
+// getResponseValue returns the value at the path of the response. string values are returned without the quotes
+func getResponseValue(body any, path string) (string, error) {
+	text, ok := body.(string)
+	if !ok {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return "", err
+		}
+		text = string(b)
+	}
+	if path == "" {
+		return text, nil
+	}
+	value, err := jsonframer.GetRootData(text, path)
+	if err != nil {
+		return "", fmt.Errorf("path %s not found in the response body", path)
+	}
+	var s string
+	if json.Unmarshal([]byte(value), &s) == nil {
+		return s, nil
+	}
+	return value, nil
+}
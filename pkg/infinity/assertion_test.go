@@ -0,0 +1,94 @@
+package infinity_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestGetAssertionsFrame(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{ "status": "ok", "checks": { "db": { "latency": 12 } } }`)
+		case "/slow":
+			time.Sleep(60 * time.Millisecond)
+			fmt.Fprint(w, "pong")
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	client := infinity.Client{Settings: models.InfinitySettings{}, HttpClient: server.Client()}
+	value := func(frame *data.Frame, name string, row int) any {
+		field, _ := frame.FieldByName(name)
+		require.NotNil(t, field, name)
+		v, _ := field.ConcreteAt(row)
+		return v
+	}
+	t.Run("should evaluate the assertions against the response", func(t *testing.T) {
+		query := models.Query{RefID: "A", Source: "url", Type: models.QueryTypeJSON, Parser: models.InfinityParserBackend, URL: server.URL + "/health", Assertions: []models.Assertion{
+			{Type: models.AssertionTypeStatusCode, Value: "200"},
+			{Type: models.AssertionTypeBody, Path: "status", Value: "^ok$"},
+			{Type: models.AssertionTypeBody, Path: "checks.db.latency", Value: "^[0-9]$"},
+			{Type: models.AssertionTypeLatency, Value: "5000"},
+		}}
+		frame, err := infinity.GetFrameForURLSources(context.Background(), query, client, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, 4, frame.Rows())
+		require.Equal(t, "status code equals 200", value(frame, "assertion", 0))
+		require.Equal(t, 1.0, value(frame, "passed", 0))
+		require.Equal(t, "ok", value(frame, "actual", 1))
+		require.Equal(t, 1.0, value(frame, "passed", 1))
+		require.Equal(t, "12", value(frame, "actual", 2))
+		require.Equal(t, 0.0, value(frame, "passed", 2))
+		require.Equal(t, 1.0, value(frame, "passed", 3))
+		require.Equal(t, 200.0, value(frame, "status_code", 3))
+		require.Equal(t, "1 of 4 assertions failed", frame.Meta.Notices[0].Text)
+	})
+	t.Run("should fail the assertions of the failed requests", func(t *testing.T) {
+		query := models.Query{RefID: "A", Source: "url", Type: models.QueryTypeJSON, URL: server.URL + "/down", Assertions: []models.Assertion{
+			{Type: models.AssertionTypeStatusCode, Value: "200"},
+			{Type: models.AssertionTypeBody, Value: "ok"},
+		}}
+		frame, err := infinity.GetFrameForURLSources(context.Background(), query, client, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, "503", value(frame, "actual", 0))
+		require.Equal(t, 0.0, value(frame, "passed", 0))
+		require.Equal(t, 0.0, value(frame, "passed", 1))
+		require.Equal(t, "503 Service Unavailable", value(frame, "error", 1))
+	})
+	t.Run("should fail the latency assertion of the slow responses", func(t *testing.T) {
+		query := models.Query{RefID: "A", Source: "url", Type: models.QueryTypeCSV, URL: server.URL + "/slow", Assertions: []models.Assertion{
+			{Type: models.AssertionTypeLatency, Value: "50"},
+			{Type: models.AssertionTypeBody, Value: "^pong$"},
+		}}
+		frame, err := infinity.GetFrameForURLSources(context.Background(), query, client, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, 0.0, value(frame, "passed", 0))
+		require.GreaterOrEqual(t, value(frame, "latency_ms", 0), 50.0)
+		require.Equal(t, 1.0, value(frame, "passed", 1))
+	})
+	t.Run("should validate the assertions", func(t *testing.T) {
+		for _, assertion := range []models.Assertion{
+			{Type: models.AssertionTypeStatusCode, Value: "ok"},
+			{Type: models.AssertionTypeLatency, Value: "-1"},
+			{Type: models.AssertionTypeBody, Value: "("},
+			{Type: "header", Value: "x"},
+		} {
+			query := models.Query{RefID: "A", Source: "url", URL: server.URL + "/health", Assertions: []models.Assertion{assertion}}
+			_, err := infinity.GetFrameForURLSources(context.Background(), query, client, map[string]string{})
+			require.NotNil(t, err)
+			require.Equal(t, infinity.ErrorKindUser, infinity.GetErrorKind(err))
+		}
+	})
+}
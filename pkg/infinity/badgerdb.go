@@ -1,20 +1,31 @@
 package infinity
 
 import (
+	"bufio"
 	"bytes"
-	"encoding/gob"
+	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	badger "github.com/dgraph-io/badger/v3"
+	"github.com/dgraph-io/badger/v3/pb"
 )
 
 // https://github.com/prasanthmj/sett.git
 const (
-	STRUCT_TYPE = 1
-	STRING_TYPE = 2
+	STRUCT_TYPE  = 1
+	STRING_TYPE  = 2
+	COUNTER_TYPE = 3
 )
 
 type SettItem struct {
@@ -24,8 +35,9 @@ type SettItem struct {
 	unlock  bool
 }
 type SettValueItem struct {
-	V      interface{}
-	Locked bool
+	V       interface{}
+	Locked  bool
+	migrate bool
 }
 
 func NewSettItem(s *Sett, txn *badger.Txn, key string) *SettItem {
@@ -50,8 +62,7 @@ func (si *SettItem) GetStructValue() (*SettValueItem, error) {
 	if err != nil {
 		return nil, err
 	}
-	var container genericContainer
-	err = gob.NewDecoder(bytes.NewBuffer(val)).Decode(&container)
+	v, migrate, err := si.s.decodeValue(meta, val)
 	if err != nil {
 		return nil, err
 	}
@@ -59,7 +70,8 @@ func (si *SettItem) GetStructValue() (*SettValueItem, error) {
 	if (meta & 0x80) != 0 {
 		locked = true
 	}
-	ret := &SettValueItem{V: container.V, Locked: locked}
+	si.s.touch(si.fullKey)
+	ret := &SettValueItem{V: v, Locked: locked, migrate: migrate}
 	return ret, nil
 }
 func (si *SettItem) IsLocked() bool {
@@ -68,28 +80,102 @@ func (si *SettItem) IsLocked() bool {
 		return false
 	}
 	if (item.UserMeta() & 0x80) != 0 {
+		if _, deadline, err := si.getLockInfo(); err == nil && !deadline.IsZero() && time.Now().After(deadline) {
+			return false
+		}
 		return true
 	}
 	return false
 }
 
 func (si *SettItem) Lock() error {
+	if si.IsLocked() {
+		return fmt.Errorf("the item was already locked")
+	}
+	if err := si.setLocked(true); err != nil {
+		return err
+	}
+	return si.deleteLockInfo()
+}
+
+// TryLock locks the item on behalf of the owner until the ttl elapses.
+// The owner can extend its own lock by calling TryLock again
+func (si *SettItem) TryLock(owner string, ttl time.Duration) error {
+	if si.IsLocked() {
+		currentOwner, _, err := si.getLockInfo()
+		if err != nil || currentOwner != owner {
+			return fmt.Errorf("the item was already locked")
+		}
+	}
+	if err := si.setLocked(true); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(ttl)
+	return si.txn.Set([]byte(si.lockInfoKey()), []byte(owner+"\x00"+strconv.FormatInt(deadline.UnixNano(), 10)))
+}
+
+// ForceUnlock removes the lock of the item irrespective of the owner
+func (si *SettItem) ForceUnlock() error {
+	if err := si.setLocked(false); err != nil {
+		return err
+	}
+	return si.deleteLockInfo()
+}
+
+func (si *SettItem) setLocked(locked bool) error {
 	item, err := si.txn.Get([]byte(si.fullKey))
 	if err != nil {
 		return err
 	}
-	meta := item.UserMeta()
-	if (meta & 0x80) != 0 {
-		return fmt.Errorf("the item was already locked")
-	}
-	var val []byte
-	val, err = item.ValueCopy(nil)
+	val, err := item.ValueCopy(nil)
 	if err != nil {
 		return err
 	}
+	meta := item.UserMeta() &^ 0x80
+	if locked {
+		meta = meta | 0x80
+	}
 	e := badger.NewEntry([]byte(si.fullKey), val)
-	meta = meta | 0x80
-	err = si.setEntry(e, meta)
+	if item.ExpiresAt() > 0 {
+		e.ExpiresAt = item.ExpiresAt()
+	}
+	e.WithMeta(meta)
+	return si.txn.SetEntry(e)
+}
+
+func (si *SettItem) lockInfoKey() string {
+	return "__lock:" + si.fullKey
+}
+
+// getLockInfo returns the owner and deadline of the lock. Locks acquired without owner have zero deadline
+func (si *SettItem) getLockInfo() (string, time.Time, error) {
+	item, err := si.txn.Get([]byte(si.lockInfoKey()))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return "", time.Time{}, nil
+	}
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	val, err := item.ValueCopy(nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	owner, deadline, found := strings.Cut(string(val), "\x00")
+	if !found {
+		return "", time.Time{}, errors.New("invalid lock info")
+	}
+	nanos, err := strconv.ParseInt(deadline, 10, 64)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return owner, time.Unix(0, nanos), nil
+}
+
+func (si *SettItem) deleteLockInfo() error {
+	err := si.txn.Delete([]byte(si.lockInfoKey()))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil
+	}
 	return err
 }
 
@@ -97,28 +183,52 @@ func (si *SettItem) SetStructValue(val interface{}) error {
 	if !si.unlock && si.IsLocked() {
 		return fmt.Errorf("the item with key %s is locked. Can't update now", si.fullKey)
 	}
-	var bValue bytes.Buffer
-	container := genericContainer{V: val}
-	err := gob.NewEncoder(&bValue).Encode(&container)
+	bValue, err := si.s.encodeValue(val)
 	if err != nil {
 		return err
 	}
-	e := badger.NewEntry([]byte(si.fullKey), bValue.Bytes())
+	if err := si.updateIndexes(val, false); err != nil {
+		return err
+	}
+	e := badger.NewEntry([]byte(si.fullKey), bValue)
 
-	err = si.setEntry(e, STRUCT_TYPE)
+	err = si.setEntry(e, STRUCT_TYPE|ENVELOPE_FLAG)
 	return err
 }
+
+// migrateValue rewrites the value with the current codec of the table,
+// retaining the lock, expiry and version independent metadata of the item
+func (si *SettItem) migrateValue(val interface{}) error {
+	item, err := si.txn.Get([]byte(si.fullKey))
+	if err != nil {
+		return err
+	}
+	bValue, err := si.s.encodeValue(val)
+	if err != nil {
+		return err
+	}
+	e := badger.NewEntry([]byte(si.fullKey), bValue)
+	if item.ExpiresAt() > 0 {
+		e.ExpiresAt = item.ExpiresAt()
+	}
+	e.WithMeta(item.UserMeta() | ENVELOPE_FLAG)
+	return si.txn.SetEntry(e)
+}
 func (si *SettItem) setEntry(e *badger.Entry, vtype byte) error {
 	if si.s.ttl > 0 {
 		e.WithTTL(si.s.ttl)
 	}
 	e.WithMeta(vtype)
+	si.s.touch(si.fullKey)
 	return si.txn.SetEntry(e)
 }
 func (si *SettItem) SetStringValue(val string) error {
 	if !si.unlock && si.IsLocked() {
 		return fmt.Errorf("the item with key %s is locked. Can't update now", si.fullKey)
 	}
+	if err := si.updateIndexes(val, false); err != nil {
+		return err
+	}
 	e := badger.NewEntry([]byte(si.fullKey), []byte(val))
 
 	err := si.setEntry(e, STRING_TYPE)
@@ -138,17 +248,91 @@ func (si *SettItem) GetStringValue() (string, error) {
 	if err != nil {
 		return "", err
 	}
+	si.s.touch(si.fullKey)
 	return string(val), nil
 }
 
+func (si *SettItem) SetCounterValue(val int64) error {
+	if !si.unlock && si.IsLocked() {
+		return fmt.Errorf("the item with key %s is locked. Can't update now", si.fullKey)
+	}
+	if err := si.updateIndexes(val, false); err != nil {
+		return err
+	}
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(val))
+	e := badger.NewEntry([]byte(si.fullKey), b)
+	return si.setEntry(e, COUNTER_TYPE)
+}
+func (si *SettItem) GetCounterValue() (int64, error) {
+	item, err := si.txn.Get([]byte(si.fullKey))
+	if err != nil {
+		return 0, err
+	}
+	if (item.UserMeta() & 0x0F) != COUNTER_TYPE {
+		return 0, errors.New("attempt to fetch counter where item was not counter type")
+	}
+	val, err := item.ValueCopy(nil)
+	if err != nil {
+		return 0, err
+	}
+	if len(val) != 8 {
+		return 0, errors.New("invalid counter value")
+	}
+	return int64(binary.BigEndian.Uint64(val)), nil
+}
+
 func (si *SettItem) Delete() error {
 	if !si.unlock && si.IsLocked() {
 		return fmt.Errorf("the item with key %s is locked. Can't delete now", si.fullKey)
 	}
-
+	if err := si.updateIndexes(nil, true); err != nil {
+		return err
+	}
+	if err := si.deleteLockInfo(); err != nil {
+		return err
+	}
 	return si.txn.Delete([]byte(si.fullKey))
 }
 
+// updateIndexes removes the index entries of the current value and adds the index entries of the new value
+func (si *SettItem) updateIndexes(val interface{}, deleted bool) error {
+	indexes := si.s.getIndexes()
+	if len(indexes) == 0 {
+		return nil
+	}
+	key := si.fullKey[len(si.s.makeKey("")):]
+	var current interface{}
+	hasCurrent := false
+	if item, err := si.txn.Get([]byte(si.fullKey)); err == nil {
+		if v, err := si.s.getItemValue(item); err == nil {
+			current, hasCurrent = v, true
+		}
+	}
+	for name, extractor := range indexes {
+		if hasCurrent {
+			if attr, ok := extractor(current); ok {
+				if err := si.txn.Delete([]byte(si.s.indexKey(name, attr, key))); err != nil {
+					return err
+				}
+			}
+		}
+		if deleted {
+			continue
+		}
+		if attr, ok := extractor(val); ok {
+			e := badger.NewEntry([]byte(si.s.indexKey(name, attr, key)), nil)
+			if si.s.ttl > 0 {
+				e.WithTTL(si.s.ttl)
+			}
+			if err := si.txn.SetEntry(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 var (
 	DefaultOptions         = badger.DefaultOptions
 	DefaultIteratorOptions = badger.DefaultIteratorOptions
@@ -159,12 +343,29 @@ type Sett struct {
 	table     string
 	ttl       time.Duration
 	keyLength int
+	registry  *settRegistry
+	ctx       context.Context
+	codec     Codec
+	namespace string
+}
+
+// IndexFunc extracts the indexed attribute from the value.
+// Return false when the value doesn't have the attribute
+type IndexFunc func(v interface{}) (string, bool)
+
+// settRegistry holds the indexes, size limits and access timestamps of all the tables of a badger instance
+type settRegistry struct {
+	mu       sync.RWMutex
+	indexes  map[string]map[string]IndexFunc
+	limits   map[string]SettLimits
+	accessMu sync.Mutex
+	access   map[string]int64
 }
 
 // Open is constructor function to create badger instance,
 // configure defaults and return struct instance
 func Open() *Sett {
-	s := Sett{}
+	s := Sett{registry: &settRegistry{indexes: map[string]map[string]IndexFunc{}}}
 	opt := badger.DefaultOptions("").WithInMemory(true)
 	db, err := badger.Open(opt)
 	if err != nil {
@@ -174,10 +375,108 @@ func Open() *Sett {
 	return &s
 }
 
+// SettOptions configures the badger instance opened by OpenWithOptions
+type SettOptions struct {
+	// Dir is the directory where the data is persisted. Data is kept in memory when empty
+	Dir string
+	// EncryptionKey enables the encryption at rest with AES. Key must be 16, 24 or 32 bytes long
+	EncryptionKey []byte
+	// PreviousEncryptionKey is the key the data was encrypted with, before rotating to EncryptionKey
+	PreviousEncryptionKey []byte
+	// Limits caps the size of the whole cache
+	Limits SettLimits
+	// GC configures the value log garbage collection, started by BadgerInitWithOptions for the persistent cache
+	GC SettGCOptions
+}
+
+func (o SettOptions) equal(other SettOptions) bool {
+	return o.Dir == other.Dir &&
+		bytes.Equal(o.EncryptionKey, other.EncryptionKey) &&
+		bytes.Equal(o.PreviousEncryptionKey, other.PreviousEncryptionKey) &&
+		o.Limits == other.Limits &&
+		o.GC == other.GC
+}
+
+// OpenWithOptions creates the badger instance with the given options.
+// When the previous encryption key is given, the key registry is re-encrypted with the new key before opening
+func OpenWithOptions(options SettOptions) (*Sett, error) {
+	if options.Dir == "" {
+		return Open().WithLimits(options.Limits), nil
+	}
+	if len(options.PreviousEncryptionKey) > 0 {
+		if err := rotateEncryptionKey(options.Dir, options.PreviousEncryptionKey, options.EncryptionKey); err != nil {
+			return nil, fmt.Errorf("error rotating the encryption key. %w", err)
+		}
+	}
+	opt := badger.DefaultOptions(options.Dir)
+	if len(options.EncryptionKey) > 0 {
+		opt = opt.WithEncryptionKey(options.EncryptionKey).WithIndexCacheSize(100 << 20)
+	}
+	db, err := badger.Open(opt)
+	if err != nil {
+		return nil, err
+	}
+	s := &Sett{db: db, registry: &settRegistry{indexes: map[string]map[string]IndexFunc{}}}
+	return s.WithLimits(options.Limits), nil
+}
+
+// rotateEncryptionKey re-encrypts the key registry of the directory with the new key.
+// Registries already encrypted with the new key are left as is
+func rotateEncryptionKey(dir string, previousKey []byte, key []byte) error {
+	if _, err := os.Stat(filepath.Join(dir, badger.KeyRegistryFileName)); os.IsNotExist(err) {
+		return nil
+	}
+	registry, err := badger.OpenKeyRegistry(badger.KeyRegistryOptions{Dir: dir, ReadOnly: true, EncryptionKey: previousKey})
+	if errors.Is(err, badger.ErrEncryptionKeyMismatch) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer registry.Close()
+	return badger.WriteKeyRegistry(registry, badger.KeyRegistryOptions{Dir: dir, EncryptionKey: key})
+}
+
 // Table selects the table, operations are to be performed
 // on. Used as a prefix on the keys passed to badger
 func (s *Sett) Table(table string) *Sett {
-	return &Sett{db: s.db, table: table}
+	return &Sett{db: s.db, table: s.namespace + table, registry: s.registry, ctx: s.ctx, codec: s.codec, namespace: s.namespace}
+}
+
+// Namespace returns the instance whose tables are isolated from the tables of other namespaces.
+// Namespaces can be nested
+func (s *Sett) Namespace(namespace string) *Sett {
+	if namespace == "" {
+		return s
+	}
+	return &Sett{db: s.db, registry: s.registry, ctx: s.ctx, codec: s.codec, namespace: s.namespace + strings.TrimSuffix(namespace, "/") + "/"}
+}
+
+// WithContext returns the copy of the table whose operations honor the
+// cancellation and deadline of the context
+func (s *Sett) WithContext(ctx context.Context) *Sett {
+	return &Sett{db: s.db, table: s.table, ttl: s.ttl, keyLength: s.keyLength, registry: s.registry, ctx: ctx, codec: s.codec, namespace: s.namespace}
+}
+
+func (s *Sett) ctxErr() error {
+	if s.ctx == nil {
+		return nil
+	}
+	return s.ctx.Err()
+}
+
+func (s *Sett) update(fn func(txn *badger.Txn) error) error {
+	if err := s.ctxErr(); err != nil {
+		return err
+	}
+	return s.db.Update(fn)
+}
+
+func (s *Sett) view(fn func(txn *badger.Txn) error) error {
+	if err := s.ctxErr(); err != nil {
+		return err
+	}
+	return s.db.View(fn)
 }
 
 // WithTTL sets a (TTL) Time To Live value for values in this table
@@ -188,6 +487,13 @@ func (s *Sett) WithTTL(d time.Duration) *Sett {
 	return s
 }
 
+// WithCodec sets the codec used to write the struct values of this table. Defaults to MsgpackCodec.
+// Values written with other codecs are still readable and migrated on read
+func (s *Sett) WithCodec(codec Codec) *Sett {
+	s.codec = codec
+	return s
+}
+
 // WithKeyLength sets the key length for generated string keys
 // for example with Insert() call where the key is generated
 func (s *Sett) WithKeyLength(len int) *Sett {
@@ -201,10 +507,13 @@ type genericContainer struct {
 
 // SetStruct can be used to set the value as any struct type
 func (s *Sett) SetStruct(key string, val interface{}) error {
-	err := s.db.Update(func(txn *badger.Txn) error {
+	err := s.update(func(txn *badger.Txn) error {
 		sit := NewSettItem(s, txn, key)
 		return sit.SetStructValue(val)
 	})
+	if err == nil {
+		s.enforceLimits()
+	}
 	return err
 }
 
@@ -215,19 +524,17 @@ func (s *Sett) SetStruct(key string, val interface{}) error {
 func (s *Sett) Cut(key string) (interface{}, error) {
 	var err error
 	var container genericContainer
-	err = s.db.Update(func(txn *badger.Txn) error {
+	err = s.update(func(txn *badger.Txn) error {
 		bkey := []byte(s.makeKey(key))
 		item, err := txn.Get(bkey)
 		if err != nil {
 			return err
 		}
-		var val []byte
-		val, err = item.ValueCopy(nil)
+		container.V, err = s.getItemValue(item)
 		if err != nil {
 			return err
 		}
-		err = gob.NewDecoder(bytes.NewBuffer(val)).Decode(&container)
-		if err != nil {
+		if err = NewSettItem(s, txn, key).updateIndexes(nil, true); err != nil {
 			return err
 		}
 		err = txn.Delete(bkey)
@@ -246,28 +553,106 @@ func (s *Sett) GetStruct(key string) (interface{}, error) {
 
 	var err error
 	var iv interface{}
-	err = s.db.View(func(txn *badger.Txn) error {
+	migrate := false
+	err = s.view(func(txn *badger.Txn) error {
 		si := NewSettItem(s, txn, key)
 		sv, err := si.GetStructValue()
 		if err != nil {
 			return err
 		}
 		iv = sv.V
+		migrate = sv.migrate
 		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
+	if migrate {
+		// rewriting the value with the current codec is best effort. A concurrent writer wins the conflict
+		_ = s.update(func(txn *badger.Txn) error {
+			return NewSettItem(s, txn, key).migrateValue(iv)
+		})
+	}
 	return iv, nil
 }
 
+// Migrate rewrites all the struct values of the table, which are not written with the current codec of the table.
+// Returns the number of migrated values
+func (s *Sett) Migrate() (int, error) {
+	count := 0
+	err := s.update(func(txn *badger.Txn) error {
+		opt := DefaultIteratorOptions
+		prefix := []byte(s.makeKey(""))
+		opt.Prefix = prefix
+		it := txn.NewIterator(opt)
+		var keys []string
+		var values []interface{}
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			if (item.UserMeta() & 0x0F) != STRUCT_TYPE {
+				continue
+			}
+			val, err := item.ValueCopy(nil)
+			if err != nil {
+				it.Close()
+				return err
+			}
+			v, migrate, err := s.decodeValue(item.UserMeta(), val)
+			if err != nil || !migrate {
+				continue
+			}
+			keys = append(keys, string(item.Key())[len(prefix):])
+			values = append(values, v)
+		}
+		it.Close()
+		for i, key := range keys {
+			if err := NewSettItem(s, txn, key).migrateValue(values[i]); err != nil {
+				return err
+			}
+		}
+		count = len(keys)
+		return nil
+	})
+	return count, err
+}
+
+// SetWithTTL sets the value with a Time To Live specific to the key,
+// irrespective of the TTL configured for the table
+func (s *Sett) SetWithTTL(key string, val interface{}, ttl time.Duration) error {
+	t := &Sett{db: s.db, table: s.table, ttl: ttl, keyLength: s.keyLength, registry: s.registry, ctx: s.ctx, codec: s.codec, namespace: s.namespace}
+	return t.Set(key, val)
+}
+
+// TTL returns the remaining Time To Live of the key.
+// Zero duration is returned for the keys without expiry
+func (s *Sett) TTL(key string) (time.Duration, error) {
+	var ttl time.Duration
+	err := s.view(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(s.makeKey(key)))
+		if err != nil {
+			return err
+		}
+		if expiresAt := item.ExpiresAt(); expiresAt > 0 {
+			ttl = time.Until(time.Unix(int64(expiresAt), 0))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return ttl, nil
+}
+
 // Set passes a key & value to badger. Expects string for both
 // key and value for convenience, unlike badger itself
 func (s *Sett) SetStr(key string, val string) error {
-	err := s.db.Update(func(txn *badger.Txn) error {
+	err := s.update(func(txn *badger.Txn) error {
 		si := NewSettItem(s, txn, key)
 		return si.SetStringValue(val)
 	})
+	if err == nil {
+		s.enforceLimits()
+	}
 	return err
 }
 
@@ -275,7 +660,7 @@ func (s *Sett) SetStr(key string, val string) error {
 func (s *Sett) GetStr(key string) (string, error) {
 	var val string
 	var err error
-	err = s.db.View(func(txn *badger.Txn) error {
+	err = s.view(func(txn *badger.Txn) error {
 		si := NewSettItem(s, txn, key)
 		val, err = si.GetStringValue()
 		return err
@@ -315,7 +700,7 @@ func (s *Sett) HasKey(key string) bool {
 func (s *Sett) Keys(filter ...string) ([]string, error) {
 	var result []string
 	var err error
-	err = s.db.View(func(txn *badger.Txn) error {
+	err = s.view(func(txn *badger.Txn) error {
 		var fullFilter string
 		it := txn.NewIterator(DefaultIteratorOptions)
 		defer it.Close()
@@ -333,6 +718,9 @@ func (s *Sett) Keys(filter ...string) ([]string, error) {
 		tn := len(s.table + ":")
 
 		for it.Seek([]byte(fullFilter)); it.ValidForPrefix([]byte(fullFilter)); it.Next() {
+			if err := s.ctxErr(); err != nil {
+				return err
+			}
 			item := it.Item()
 			k := string(item.Key())
 			k = k[tn:]
@@ -344,38 +732,435 @@ func (s *Sett) Keys(filter ...string) ([]string, error) {
 	return result, err
 }
 
+// counterLocks serializes the increments of the same counter to avoid the transaction conflicts between them.
+// Increments of the other counters run concurrently
+var counterLocks = &keyLocks{locks: map[string]*keyLock{}}
+
+type keyLocks struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// lock locks the key and returns the unlock func. Locks are removed once no longer held or waited for
+func (k *keyLocks) lock(key string) func() {
+	k.mu.Lock()
+	l, ok := k.locks[key]
+	if !ok {
+		l = &keyLock{}
+		k.locks[key] = l
+	}
+	l.refs++
+	k.mu.Unlock()
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		if l.refs--; l.refs == 0 {
+			delete(k.locks, key)
+		}
+	}
+}
+
+// Incr increments the counter stored in the key by delta and returns the new value.
+// Counters are stored as raw int64 values, so no gob encoding is involved.
+// Missing counters start from zero. The merge operator of badger is not used, as it doesn't return the value after
+// the own increment, which the callers such as the list sequences rely on to get unique values. Increments of the
+// same counter are serialized instead and retried on the conflicts with the other writers of the key
+func (s *Sett) Incr(key string, delta int64) (int64, error) {
+	var value int64
+	var err error
+	defer counterLocks.lock(s.makeKey(key))()
+	for attempt := 0; attempt < 10; attempt++ {
+		err = s.update(func(txn *badger.Txn) error {
+			value = 0
+			si := NewSettItem(s, txn, key)
+			current, err := si.GetCounterValue()
+			if err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+				return err
+			}
+			value = current + delta
+			return si.SetCounterValue(value)
+		})
+		if !errors.Is(err, badger.ErrConflict) {
+			break
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return value, nil
+}
+
+// Counter returns the current value of the counter
+func (s *Sett) Counter(key string) (int64, error) {
+	var value int64
+	err := s.view(func(txn *badger.Txn) error {
+		var err error
+		value, err = NewSettItem(s, txn, key).GetCounterValue()
+		return err
+	})
+	return value, err
+}
+
+const (
+	listItemsTable = "__list:"
+	listSeqTable   = "__listseq:"
+)
+
+// listTable returns the table holding the list entries of the table. It is
+// kept outside of the table keyspace, like the index entries, so that Keys
+// and Filter don't return the list items
+func (s *Sett) listTable(kind string) *Sett {
+	return &Sett{db: s.db, table: kind + s.table, ttl: s.ttl, registry: s.registry, ctx: s.ctx, codec: s.codec}
+}
+
+func listItemPrefix(list string) string {
+	return list + ":"
+}
+
+// Push appends the value to the end of the list
+func (s *Sett) Push(list string, val interface{}) error {
+	seq, err := s.listTable(listSeqTable).Incr(list, 1)
+	if err != nil {
+		return err
+	}
+	return s.listTable(listItemsTable).Set(fmt.Sprintf("%s%020d", listItemPrefix(list), seq), val)
+}
+
+// PopN removes and returns up to n values from the head of the list
+func (s *Sett) PopN(list string, n int) ([]interface{}, error) {
+	var result []interface{}
+	err := s.update(func(txn *badger.Txn) error {
+		result = nil
+		opt := DefaultIteratorOptions
+		prefix := []byte(s.listTable(listItemsTable).makeKey(listItemPrefix(list)))
+		opt.Prefix = prefix
+		it := txn.NewIterator(opt)
+		var keys [][]byte
+		for it.Seek(prefix); it.ValidForPrefix(prefix) && len(result) < n; it.Next() {
+			item := it.Item()
+			v, err := s.getItemValue(item)
+			if err != nil {
+				it.Close()
+				return err
+			}
+			result = append(result, v)
+			keys = append(keys, item.KeyCopy(nil))
+		}
+		it.Close()
+		for _, k := range keys {
+			if err := txn.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Trim removes the values of the list pushed before the last keep values and returns the number of removed values.
+// The removed entries are found by the sequence of the list, so the values kept are never read
+func (s *Sett) Trim(list string, keep int) (int, error) {
+	seq, err := s.listTable(listSeqTable).Counter(list)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	last := seq - int64(keep)
+	if last <= 0 {
+		return 0, nil
+	}
+	items := s.listTable(listItemsTable)
+	prefix := []byte(items.makeKey(listItemPrefix(list)))
+	end := []byte(items.makeKey(fmt.Sprintf("%s%020d", listItemPrefix(list), last)))
+	removed := 0
+	err = s.update(func(txn *badger.Txn) error {
+		opt := DefaultIteratorOptions
+		opt.Prefix = prefix
+		opt.PrefetchValues = false
+		it := txn.NewIterator(opt)
+		var keys [][]byte
+		for it.Seek(prefix); it.ValidForPrefix(prefix) && bytes.Compare(it.Item().Key(), end) <= 0; it.Next() {
+			keys = append(keys, it.Item().KeyCopy(nil))
+		}
+		it.Close()
+		for _, k := range keys {
+			if err := txn.Delete(k); err != nil {
+				return err
+			}
+		}
+		removed = len(keys)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+// Range returns up to limit values of the list starting from the offset without removing them
+func (s *Sett) Range(list string, offset int, limit int) ([]interface{}, error) {
+	var result []interface{}
+	err := s.view(func(txn *badger.Txn) error {
+		opt := DefaultIteratorOptions
+		prefix := []byte(s.listTable(listItemsTable).makeKey(listItemPrefix(list)))
+		opt.Prefix = prefix
+		it := txn.NewIterator(opt)
+		defer it.Close()
+		idx := 0
+		for it.Seek(prefix); it.ValidForPrefix(prefix) && len(result) < limit; it.Next() {
+			if err := s.ctxErr(); err != nil {
+				return err
+			}
+			if idx < offset {
+				idx++
+				continue
+			}
+			v, err := s.getItemValue(it.Item())
+			if err != nil {
+				return err
+			}
+			result = append(result, v)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// ErrVersionMismatch is returned by SetIfVersion when the item was modified after it was read
+var ErrVersionMismatch = errors.New("the item was modified by another writer")
+
+// GetWithVersion returns the value along with the version of the item.
+// The version is to be passed to SetIfVersion
+func (s *Sett) GetWithVersion(key string) (interface{}, uint64, error) {
+	var val interface{}
+	var version uint64
+	err := s.view(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(s.makeKey(key)))
+		if err != nil {
+			return err
+		}
+		version = item.Version()
+		val, err = s.getItemValue(item)
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return val, version, nil
+}
+
+// SetIfVersion sets the value only when the item was not modified since the given version was read.
+// Use version 0 to create an item only if it doesn't exist yet
+func (s *Sett) SetIfVersion(key string, val interface{}, version uint64) error {
+	err := s.update(func(txn *badger.Txn) error {
+		var current uint64
+		item, err := txn.Get([]byte(s.makeKey(key)))
+		if err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+			return err
+		}
+		if err == nil {
+			current = item.Version()
+		}
+		if current != version {
+			return ErrVersionMismatch
+		}
+		si := NewSettItem(s, txn, key)
+		if v, ok := val.(string); ok {
+			return si.SetStringValue(v)
+		}
+		return si.SetStructValue(val)
+	})
+	if errors.Is(err, badger.ErrConflict) {
+		return ErrVersionMismatch
+	}
+	return err
+}
+
+// SettKV is a key and value pair returned by Scan
+type SettKV struct {
+	Key   string
+	Value interface{}
+}
+
+// Scan returns up to limit items of the table whose keys start with the prefix,
+// starting from the cursor. The returned cursor is to be passed to the next Scan call
+// and is empty when there are no more items
+func (s *Sett) Scan(prefix string, cursor string, limit int) ([]SettKV, string, error) {
+	var result []SettKV
+	var nextCursor string
+	if limit <= 0 {
+		return result, nextCursor, errors.New("limit should be greater than zero")
+	}
+	err := s.view(func(txn *badger.Txn) error {
+		opt := DefaultIteratorOptions
+		opt.PrefetchSize = limit
+		fullPrefix := []byte(s.makeKey(prefix))
+		opt.Prefix = fullPrefix
+		it := txn.NewIterator(opt)
+		defer it.Close()
+		start := fullPrefix
+		if cursor != "" {
+			start = []byte(s.makeKey(cursor))
+		}
+		tn := len(s.makeKey(""))
+		for it.Seek(start); it.ValidForPrefix(fullPrefix); it.Next() {
+			if err := s.ctxErr(); err != nil {
+				return err
+			}
+			item := it.Item()
+			k := string(item.Key())[tn:]
+			if len(result) == limit {
+				nextCursor = k
+				break
+			}
+			v, err := s.getItemValue(item)
+			if err != nil {
+				return err
+			}
+			result = append(result, SettKV{Key: k, Value: v})
+		}
+		return nil
+	})
+	return result, nextCursor, err
+}
+
+func (s *Sett) getItemValue(item *badger.Item) (interface{}, error) {
+	val, err := item.ValueCopy(nil)
+	if err != nil {
+		return nil, err
+	}
+	if (item.UserMeta() & 0x0F) == STRING_TYPE {
+		return string(val), nil
+	}
+	if (item.UserMeta()&0x0F) == COUNTER_TYPE && len(val) == 8 {
+		return int64(binary.BigEndian.Uint64(val)), nil
+	}
+	v, _, err := s.decodeValue(item.UserMeta(), val)
+	return v, err
+}
+
+// AddIndex registers the index for the table and indexes the existing items of the table.
+// Index entries are maintained on every write to the table afterwards
+func (s *Sett) AddIndex(name string, extractor IndexFunc) error {
+	if s.registry == nil {
+		return errors.New("indexes are not supported for this instance")
+	}
+	s.registry.mu.Lock()
+	if s.registry.indexes[s.table] == nil {
+		s.registry.indexes[s.table] = map[string]IndexFunc{}
+	}
+	s.registry.indexes[s.table][name] = extractor
+	s.registry.mu.Unlock()
+	return s.update(func(txn *badger.Txn) error {
+		opt := DefaultIteratorOptions
+		prefix := []byte(s.makeKey(""))
+		opt.Prefix = prefix
+		it := txn.NewIterator(opt)
+		defer it.Close()
+		tn := len(prefix)
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			v, err := s.getItemValue(item)
+			if err != nil {
+				continue
+			}
+			if attr, ok := extractor(v); ok {
+				e := badger.NewEntry([]byte(s.indexKey(name, attr, string(item.Key())[tn:])), nil)
+				if item.ExpiresAt() > 0 {
+					e.ExpiresAt = item.ExpiresAt()
+				}
+				if err := txn.SetEntry(e); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// FilterByIndex returns the keys whose indexed attribute matches the value
+func (s *Sett) FilterByIndex(name string, value string) ([]string, error) {
+	if _, ok := s.getIndexes()[name]; !ok {
+		return nil, fmt.Errorf("index %s not found", name)
+	}
+	var result []string
+	err := s.view(func(txn *badger.Txn) error {
+		opt := DefaultIteratorOptions
+		opt.PrefetchValues = false
+		prefix := []byte(s.indexKey(name, value, ""))
+		opt.Prefix = prefix
+		it := txn.NewIterator(opt)
+		defer it.Close()
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			if err := s.ctxErr(); err != nil {
+				return err
+			}
+			result = append(result, string(it.Item().Key())[len(prefix):])
+		}
+		return nil
+	})
+	return result, err
+}
+
+func (s *Sett) getIndexes() map[string]IndexFunc {
+	if s.registry == nil {
+		return nil
+	}
+	s.registry.mu.RLock()
+	defer s.registry.mu.RUnlock()
+	indexes := map[string]IndexFunc{}
+	for name, extractor := range s.registry.indexes[s.table] {
+		indexes[name] = extractor
+	}
+	return indexes
+}
+
+func (s *Sett) indexKey(name string, value string, key string) string {
+	return "__idx:" + s.table + ":" + name + ":" + value + "\x00" + key
+}
+
 type FilterFunc func(k string, v interface{}) bool
 
 func (s *Sett) Filter(filter FilterFunc) ([]string, error) {
 	var result []string
 	var err error
-	err = s.db.View(func(txn *badger.Txn) error {
+	err = s.view(func(txn *badger.Txn) error {
 		var fullFilter string
 		it := txn.NewIterator(DefaultIteratorOptions)
 		defer it.Close()
 
 		if len(s.table) > 0 {
-			fullFilter = s.table
+			fullFilter = s.table + ":"
 		}
 
-		tn := len(s.table + ":")
+		tn := len(fullFilter)
 
 		for it.Seek([]byte(fullFilter)); it.ValidForPrefix([]byte(fullFilter)); it.Next() {
+			if err := s.ctxErr(); err != nil {
+				return err
+			}
 			item := it.Item()
 			k := string(item.Key())
 			k = k[tn:]
 
-			var container genericContainer
-			var val []byte
-			val, err = item.ValueCopy(nil)
+			var v interface{}
+			v, err = s.getItemValue(item)
 			if err != nil {
 				return err
 			}
-			err = gob.NewDecoder(bytes.NewBuffer(val)).Decode(&container)
-			if err != nil {
-				return err
-			}
-			if filter(k, container.V) {
+			if filter(k, v) {
 				result = append(result, k)
 			}
 
@@ -389,13 +1174,45 @@ func (s *Sett) Filter(filter FilterFunc) ([]string, error) {
 // the caller shouldn't do any updates. The lock was already taken.
 // This is used in concurrent access scenarios
 func (s *Sett) Lock(k string) error {
-	err := s.db.Update(func(txn *badger.Txn) error {
+	err := s.update(func(txn *badger.Txn) error {
 		sit := NewSettItem(s, txn, k)
 		return sit.Lock()
 	})
 	return err
 }
 
+// TryLock locks an item on behalf of the owner for the given duration.
+// Expired locks are ignored, so the lock can't be orphaned if the owner crashes
+func (s *Sett) TryLock(k string, owner string, ttl time.Duration) error {
+	return s.update(func(txn *badger.Txn) error {
+		return NewSettItem(s, txn, k).TryLock(owner, ttl)
+	})
+}
+
+// ForceUnlock removes the lock of an item irrespective of the owner
+func (s *Sett) ForceUnlock(k string) error {
+	return s.update(func(txn *badger.Txn) error {
+		return NewSettItem(s, txn, k).ForceUnlock()
+	})
+}
+
+// LockOwner returns the owner and the deadline of the lock of an item.
+// Empty owner is returned when the item is not locked or locked without owner
+func (s *Sett) LockOwner(k string) (string, time.Time, error) {
+	var owner string
+	var deadline time.Time
+	err := s.view(func(txn *badger.Txn) error {
+		si := NewSettItem(s, txn, k)
+		if !si.IsLocked() {
+			return nil
+		}
+		var err error
+		owner, deadline, err = si.getLockInfo()
+		return err
+	})
+	return owner, deadline, err
+}
+
 type UpdateFunc func(v interface{}) error
 
 // Update - update one item. This function gets the item by the key.
@@ -404,7 +1221,7 @@ type UpdateFunc func(v interface{}) error
 func (s *Sett) Update(k string, updater UpdateFunc, unlock bool) (interface{}, error) {
 	var err error
 	var container genericContainer
-	err = s.db.Update(func(txn *badger.Txn) error {
+	err = s.update(func(txn *badger.Txn) error {
 
 		sit := NewSettItem(s, txn, k)
 		sit.Unlock(unlock)
@@ -430,7 +1247,7 @@ func (s *Sett) Update(k string, updater UpdateFunc, unlock bool) (interface{}, e
 }
 
 func (s *Sett) deleteItem(key string, unlock bool) error {
-	err := s.db.Update(func(txn *badger.Txn) error {
+	err := s.update(func(txn *badger.Txn) error {
 		sit := NewSettItem(s, txn, key)
 		sit.Unlock(unlock)
 		return sit.Delete()
@@ -449,31 +1266,121 @@ func (s *Sett) UnlockAndDelete(key string) error {
 }
 
 // Drop removes all keys with table prefix from badger,
-// the effect is as if a table was deleted
-func (s *Sett) Drop() error {
-	var err error
-	var deleteKey []string
-	err = s.db.View(func(txn *badger.Txn) error {
-		it := txn.NewIterator(DefaultIteratorOptions)
-		prefix := []byte(s.table)
-		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-			item := it.Item()
-			key := string(item.Key())
-			deleteKey = append(deleteKey, key)
+// the effect is as if a table was deleted. Index, list and lock entries
+// of the table are removed as well. Dropping a namespace removes all
+// of its tables. Returns the number of deleted keys
+func (s *Sett) Drop() (int, error) {
+	prefixes := s.keyPrefixes()
+	count := 0
+	err := s.view(func(txn *badger.Txn) error {
+		opt := DefaultIteratorOptions
+		opt.PrefetchValues = false
+		if prefixes != nil {
+			opt.Prefix = prefixes[0]
+		}
+		it := txn.NewIterator(opt)
+		defer it.Close()
+		for it.Seek(opt.Prefix); it.ValidForPrefix(opt.Prefix); it.Next() {
+			count++
 		}
-		it.Close()
 		return nil
 	})
-	err = s.db.Update(func(txn *badger.Txn) error {
-		for _, d := range deleteKey {
-			err = txn.Delete([]byte(d))
-			if err != nil {
-				break
-			}
+	if err != nil {
+		return 0, err
+	}
+	if prefixes == nil {
+		return count, s.db.DropAll()
+	}
+	return count, s.db.DropPrefix(prefixes...)
+}
+
+// keyPrefixes returns the prefixes of the keys of the table or namespace, starting with the prefix of its values and
+// followed by the prefixes of its index, list and lock entries. Nil means all the keys
+func (s *Sett) keyPrefixes() [][]byte {
+	prefix, table := s.makeKey(""), s.table+":"
+	if s.table == "" {
+		if s.namespace == "" {
+			return nil
+		}
+		prefix, table = s.namespace, s.namespace
+	}
+	return [][]byte{
+		[]byte(prefix),
+		[]byte("__idx:" + table),
+		[]byte(listItemsTable + table),
+		[]byte(listSeqTable + table),
+		[]byte("__lock:" + prefix),
+	}
+}
+
+// Backup writes the entries of the table or namespace, changed after the since version, to the writer. The instance without
+// table and namespace writes the entries of all the tables. Zero since version writes a full backup. Returns the version to be
+// used for the next incremental backup
+func (s *Sett) Backup(w io.Writer, since uint64) (uint64, error) {
+	if err := s.ctxErr(); err != nil {
+		return 0, err
+	}
+	prefixes := s.keyPrefixes()
+	if prefixes == nil {
+		return s.db.Backup(w, since)
+	}
+	var version uint64
+	for _, prefix := range prefixes {
+		stream := s.db.NewStream()
+		stream.LogPrefix = "Sett.Backup"
+		stream.Prefix = prefix
+		stream.SinceTs = since
+		v, err := stream.Backup(w, since)
+		if err != nil {
+			return 0, err
 		}
+		if v > version {
+			version = v
+		}
+	}
+	return version, nil
+}
+
+// Load restores the entries written by Backup. Existing entries with the same keys are overwritten.
+// The instance of a table or namespace rejects the backups having the entries of the other tables
+func (s *Sett) Load(r io.Reader) error {
+	if err := s.ctxErr(); err != nil {
 		return err
-	})
-	return err
+	}
+	prefixes := s.keyPrefixes()
+	if prefixes == nil {
+		return s.db.Load(r, 256)
+	}
+	// the backup is checked before loading so that it is either loaded in full or not at all
+	var checked bytes.Buffer
+	br := bufio.NewReader(r)
+	for {
+		var size uint64
+		if err := binary.Read(br, binary.LittleEndian, &size); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		b, err := io.ReadAll(io.LimitReader(br, int64(size)))
+		if err != nil {
+			return err
+		}
+		if uint64(len(b)) != size {
+			return io.ErrUnexpectedEOF
+		}
+		list := &pb.KVList{}
+		if err := list.Unmarshal(b); err != nil {
+			return err
+		}
+		for _, kv := range list.Kv {
+			if !slices.ContainsFunc(prefixes, func(prefix []byte) bool { return bytes.HasPrefix(kv.Key, prefix) }) {
+				return errors.New("the backup has the entries of the other tables")
+			}
+		}
+		binary.Write(&checked, binary.LittleEndian, size) //nolint
+		checked.Write(b)
+	}
+	return s.db.Load(&checked, 256)
 }
 
 // Close wraps badger Close method for defer
@@ -489,16 +1396,3 @@ func (s *Sett) makeKey(key string) string {
 	}
 	return s.table + ":" + key
 }
-
-func (s *Sett) Garbadge() {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-	for range ticker.C {
-	again:
-		//log.Debug().Msgf("Badger : garbadge the database")
-		err := s.db.RunValueLogGC(0.7)
-		if err == nil {
-			goto again
-		}
-	}
-}
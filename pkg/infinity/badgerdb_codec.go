@@ -0,0 +1,224 @@
+package infinity
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	// ENVELOPE_FLAG marks the struct values stored with version and codec header
+	ENVELOPE_FLAG = 0x40
+	// SETT_ENVELOPE_VERSION is the version of the envelope layout written by this release
+	SETT_ENVELOPE_VERSION byte = 1
+	GOB_CODEC             byte = 1
+	JSON_CODEC            byte = 2
+	MSGPACK_CODEC         byte = 3
+)
+
+// Codec serializes the struct values stored in Sett
+type Codec interface {
+	// ID identifies the codec in the stored values. Must be unique across registered codecs
+	ID() byte
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte) (interface{}, error)
+}
+
+var codecs = struct {
+	mu   sync.RWMutex
+	byID map[byte]Codec
+}{byID: map[byte]Codec{GOB_CODEC: GobCodec{}, JSON_CODEC: JSONCodec{}, MSGPACK_CODEC: MsgpackCodec{}}}
+
+// RegisterCodec makes the codec available for reading the values written with it
+func RegisterCodec(codec Codec) {
+	codecs.mu.Lock()
+	defer codecs.mu.Unlock()
+	codecs.byID[codec.ID()] = codec
+}
+
+func getCodec(id byte) (Codec, error) {
+	codecs.mu.RLock()
+	defer codecs.mu.RUnlock()
+	if codec, ok := codecs.byID[id]; ok {
+		return codec, nil
+	}
+	return nil, fmt.Errorf("unknown codec %d", id)
+}
+
+// GobCodec stores the values using gob. Types of the values need to be registered with gob.Register
+type GobCodec struct{}
+
+func (GobCodec) ID() byte { return GOB_CODEC }
+
+func (GobCodec) Marshal(v interface{}) ([]byte, error) {
+	b := getBuffer()
+	defer putBuffer(b)
+	if err := gob.NewEncoder(b).Encode(&genericContainer{V: v}); err != nil {
+		return nil, err
+	}
+	return bytes.Clone(b.Bytes()), nil
+}
+
+func (GobCodec) Unmarshal(data []byte) (interface{}, error) {
+	var container genericContainer
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&container); err != nil {
+		return nil, err
+	}
+	return container.V, nil
+}
+
+// JSONCodec stores the values as JSON along with the type name registered with RegisterSettType.
+// Unlike gob, the stored values survive the changes of the go types as long as the JSON fields are compatible
+type JSONCodec struct{}
+
+type jsonEnvelope struct {
+	Type  string          `json:"type,omitempty"`
+	Value json.RawMessage `json:"value"`
+}
+
+var settTypes = struct {
+	mu     sync.RWMutex
+	byName map[string]reflect.Type
+	byType map[reflect.Type]string
+}{byName: map[string]reflect.Type{}, byType: map[reflect.Type]string{}}
+
+// RegisterSettType registers the type of the value with the name used by MsgpackCodec and JSONCodec. Values of the unregistered types are read as maps
+func RegisterSettType(name string, v interface{}) {
+	settTypes.mu.Lock()
+	defer settTypes.mu.Unlock()
+	t := reflect.TypeOf(v)
+	settTypes.byName[name] = t
+	settTypes.byType[t] = name
+}
+
+func (JSONCodec) ID() byte { return JSON_CODEC }
+
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	b := getBuffer()
+	defer putBuffer(b)
+	// encoder writes the value into the pooled buffer. trailing new line of the encoder is trimmed
+	if err := json.NewEncoder(b).Encode(v); err != nil {
+		return nil, err
+	}
+	settTypes.mu.RLock()
+	name := settTypes.byType[reflect.TypeOf(v)]
+	settTypes.mu.RUnlock()
+	return json.Marshal(jsonEnvelope{Type: name, Value: bytes.TrimSuffix(b.Bytes(), []byte("\n"))})
+}
+
+func (JSONCodec) Unmarshal(data []byte) (interface{}, error) {
+	var envelope jsonEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, err
+	}
+	settTypes.mu.RLock()
+	t, ok := settTypes.byName[envelope.Type]
+	settTypes.mu.RUnlock()
+	if !ok {
+		var out interface{}
+		err := json.Unmarshal(envelope.Value, &out)
+		return out, err
+	}
+	if t.Kind() == reflect.Pointer {
+		out := reflect.New(t.Elem())
+		err := json.Unmarshal(envelope.Value, out.Interface())
+		return out.Interface(), err
+	}
+	out := reflect.New(t)
+	err := json.Unmarshal(envelope.Value, out.Interface())
+	return out.Elem().Interface(), err
+}
+
+// MsgpackCodec stores the values as msgpack along with the type name registered with RegisterSettType.
+// It is the default codec of the new values, as it is faster and more compact than gob and json.
+// Fields are named by the go field names, so the fields skipped by json such as the frames of the datasets are still stored
+type MsgpackCodec struct{}
+
+type msgpackEnvelope struct {
+	Type  string             `msgpack:"type,omitempty"`
+	Value msgpack.RawMessage `msgpack:"value"`
+}
+
+func (MsgpackCodec) ID() byte { return MSGPACK_CODEC }
+
+func (MsgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	b := getBuffer()
+	defer putBuffer(b)
+	if err := msgpack.NewEncoder(b).Encode(v); err != nil {
+		return nil, err
+	}
+	settTypes.mu.RLock()
+	name := settTypes.byType[reflect.TypeOf(v)]
+	settTypes.mu.RUnlock()
+	// marshalling the envelope copies the value out of the pooled buffer
+	return msgpack.Marshal(msgpackEnvelope{Type: name, Value: b.Bytes()})
+}
+
+func (MsgpackCodec) Unmarshal(data []byte) (interface{}, error) {
+	var envelope msgpackEnvelope
+	if err := msgpack.Unmarshal(data, &envelope); err != nil {
+		return nil, err
+	}
+	settTypes.mu.RLock()
+	t, ok := settTypes.byName[envelope.Type]
+	settTypes.mu.RUnlock()
+	if !ok {
+		var out interface{}
+		err := msgpack.Unmarshal(envelope.Value, &out)
+		return out, err
+	}
+	if t.Kind() == reflect.Pointer {
+		out := reflect.New(t.Elem())
+		err := msgpack.Unmarshal(envelope.Value, out.Interface())
+		return out.Interface(), err
+	}
+	out := reflect.New(t)
+	err := msgpack.Unmarshal(envelope.Value, out.Interface())
+	return out.Elem().Interface(), err
+}
+
+// writeCodec returns the codec of the new values of the table. Defaults to msgpack
+func (s *Sett) writeCodec() Codec {
+	if s.codec == nil {
+		return MsgpackCodec{}
+	}
+	return s.codec
+}
+
+// encodeValue returns the value wrapped in the envelope of version and codec
+func (s *Sett) encodeValue(v interface{}) ([]byte, error) {
+	codec := s.writeCodec()
+	payload, err := codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(payload)+2)
+	return append(append(out, SETT_ENVELOPE_VERSION, codec.ID()), payload...), nil
+}
+
+// decodeValue decodes the struct value. Values written before the envelopes were introduced are read as gob.
+// The returned flag reports whether the value is to be rewritten with the current codec of the table
+func (s *Sett) decodeValue(meta byte, val []byte) (interface{}, bool, error) {
+	if (meta & ENVELOPE_FLAG) == 0 {
+		v, err := GobCodec{}.Unmarshal(val)
+		return v, err == nil, err
+	}
+	if len(val) < 2 {
+		return nil, false, errors.New("invalid value envelope")
+	}
+	if val[0] != SETT_ENVELOPE_VERSION {
+		return nil, false, fmt.Errorf("unsupported value envelope version %d", val[0])
+	}
+	codec, err := getCodec(val[1])
+	if err != nil {
+		return nil, false, err
+	}
+	v, err := codec.Unmarshal(val[2:])
+	return v, err == nil && s.writeCodec().ID() != codec.ID(), err
+}
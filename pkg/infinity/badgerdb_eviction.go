@@ -0,0 +1,219 @@
+package infinity
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v3"
+)
+
+// evictionBatchSize limits the number of entries deleted in a single transaction
+const evictionBatchSize = 1000
+
+// SettLimits caps the size of a table, or the size of all the tables together when set on the root instance.
+// Zero values mean no limit
+type SettLimits struct {
+	// MaxBytes is the limit of the estimated size of the keys and values
+	MaxBytes int64
+	// MaxEntries is the limit of the number of entries
+	MaxEntries int
+}
+
+func (l SettLimits) isZero() bool {
+	return l.MaxBytes <= 0 && l.MaxEntries <= 0
+}
+
+// WithLimits sets the size limits of the table. Once the limits are exceeded, the least recently
+// used entries are evicted. Limits set on the root instance apply to all the tables together
+func (s *Sett) WithLimits(limits SettLimits) *Sett {
+	s.registry.mu.Lock()
+	defer s.registry.mu.Unlock()
+	if s.registry.limits == nil {
+		s.registry.limits = map[string]SettLimits{}
+	}
+	if limits.isZero() {
+		delete(s.registry.limits, s.table)
+		return s
+	}
+	s.registry.limits[s.table] = limits
+	return s
+}
+
+func (s *Sett) getLimits(table string) (SettLimits, bool) {
+	if s.registry == nil {
+		return SettLimits{}, false
+	}
+	s.registry.mu.RLock()
+	defer s.registry.mu.RUnlock()
+	limits, ok := s.registry.limits[table]
+	return limits, ok
+}
+
+func (s *Sett) hasLimits() bool {
+	if s.registry == nil {
+		return false
+	}
+	s.registry.mu.RLock()
+	defer s.registry.mu.RUnlock()
+	return len(s.registry.limits) > 0
+}
+
+// touch records the access time of the key. Access times are tracked only when limits are configured
+func (s *Sett) touch(fullKey string) {
+	if !s.hasLimits() {
+		return
+	}
+	s.registry.accessMu.Lock()
+	defer s.registry.accessMu.Unlock()
+	if s.registry.access == nil {
+		s.registry.access = map[string]int64{}
+	}
+	s.registry.access[fullKey] = time.Now().UnixNano()
+}
+
+// enforceLimits evicts the entries exceeding the limits after the writes. Eviction is best effort,
+// a failed eviction is retried with the next write
+func (s *Sett) enforceLimits() {
+	if !s.hasLimits() {
+		return
+	}
+	_, _ = s.Evict()
+}
+
+// Evict removes the least recently used entries until the table and the whole cache are within their limits.
+// Entries not accessed since the start are evicted first, oldest writes first. Locked entries are never evicted.
+// Returns the number of evicted entries
+func (s *Sett) Evict() (int, error) {
+	count := 0
+	tables := []string{s.table}
+	if s.table != "" {
+		tables = append(tables, "")
+	}
+	for _, table := range tables {
+		limits, ok := s.getLimits(table)
+		if !ok {
+			continue
+		}
+		t := &Sett{db: s.db, table: table, registry: s.registry, ctx: s.ctx, codec: s.codec}
+		n, err := t.evict(limits)
+		count += n
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+type settEntry struct {
+	fullKey    string
+	size       int64
+	accessedAt int64
+	version    uint64
+}
+
+func (s *Sett) evict(limits SettLimits) (int, error) {
+	prefix := []byte(s.makeKey(""))
+	var candidates []settEntry
+	var total int64
+	entries := 0
+	err := s.view(func(txn *badger.Txn) error {
+		opt := DefaultIteratorOptions
+		opt.PrefetchValues = false
+		opt.Prefix = prefix
+		it := txn.NewIterator(opt)
+		defer it.Close()
+		s.registry.accessMu.Lock()
+		defer s.registry.accessMu.Unlock()
+		seen := map[string]bool{}
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			if err := s.ctxErr(); err != nil {
+				return err
+			}
+			item := it.Item()
+			fullKey := string(item.Key())
+			if strings.HasPrefix(fullKey, "__") {
+				continue
+			}
+			seen[fullKey] = true
+			entries++
+			total += item.EstimatedSize()
+			if (item.UserMeta() & 0x80) != 0 {
+				continue
+			}
+			candidates = append(candidates, settEntry{fullKey: fullKey, size: item.EstimatedSize(), accessedAt: s.registry.access[fullKey], version: item.Version()})
+		}
+		// forget the access times of the deleted and expired keys
+		for fullKey := range s.registry.access {
+			if strings.HasPrefix(fullKey, string(prefix)) && !seen[fullKey] {
+				delete(s.registry.access, fullKey)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if withinLimits(limits, total, entries) {
+		return 0, nil
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].accessedAt != candidates[j].accessedAt {
+			return candidates[i].accessedAt < candidates[j].accessedAt
+		}
+		return candidates[i].version < candidates[j].version
+	})
+	var evicted []string
+	for _, c := range candidates {
+		if withinLimits(limits, total, entries) {
+			break
+		}
+		evicted = append(evicted, c.fullKey)
+		total -= c.size
+		entries--
+	}
+	count := 0
+	for start := 0; start < len(evicted); start += evictionBatchSize {
+		end := min(start+evictionBatchSize, len(evicted))
+		err := s.update(func(txn *badger.Txn) error {
+			for _, fullKey := range evicted[start:end] {
+				if err := s.itemForFullKey(txn, fullKey).Delete(); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return count, err
+		}
+		s.registry.accessMu.Lock()
+		for _, fullKey := range evicted[start:end] {
+			delete(s.registry.access, fullKey)
+		}
+		s.registry.accessMu.Unlock()
+		count += end - start
+	}
+	return count, nil
+}
+
+func withinLimits(limits SettLimits, size int64, entries int) bool {
+	if limits.MaxBytes > 0 && size > limits.MaxBytes {
+		return false
+	}
+	if limits.MaxEntries > 0 && entries > limits.MaxEntries {
+		return false
+	}
+	return true
+}
+
+// itemForFullKey returns the item of the stored key. Keys found from the root instance are resolved to their
+// tables, so that the indexes of the tables are maintained
+func (s *Sett) itemForFullKey(txn *badger.Txn, fullKey string) *SettItem {
+	t := s
+	if s.table == "" {
+		if table, _, found := strings.Cut(fullKey, ":"); found {
+			t = &Sett{db: s.db, table: table, registry: s.registry, ctx: s.ctx, codec: s.codec}
+		}
+	}
+	return &SettItem{fullKey: fullKey, s: t, txn: txn}
+}
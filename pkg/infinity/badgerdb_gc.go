@@ -0,0 +1,167 @@
+package infinity
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	defaultGCInterval     = 5 * time.Minute
+	defaultGCDiscardRatio = 0.7
+)
+
+var (
+	gcRunsMetric = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "infinity",
+		Subsystem: "cache",
+		Name:      "gc_runs_total",
+		Help:      "Number of value log garbage collection runs of the cache",
+	})
+	gcRewritesMetric = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "infinity",
+		Subsystem: "cache",
+		Name:      "gc_last_rewrites",
+		Help:      "Number of value log files rewritten by the last garbage collection run",
+	})
+	gcDurationMetric = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "infinity",
+		Subsystem: "cache",
+		Name:      "gc_last_duration_seconds",
+		Help:      "Duration of the last garbage collection run",
+	})
+	gcTimestampMetric = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "infinity",
+		Subsystem: "cache",
+		Name:      "gc_last_run_timestamp_seconds",
+		Help:      "Unix timestamp of the last garbage collection run",
+	})
+)
+
+// SettGCOptions configures the value log garbage collection of the cache
+type SettGCOptions struct {
+	// Interval between the garbage collection runs. Defaults to 5 minutes
+	Interval time.Duration
+	// DiscardRatio is the ratio of the discardable data above which the value log files are rewritten. Defaults to 0.7
+	DiscardRatio float64
+}
+
+// SettGCStats reports the last garbage collection run
+type SettGCStats struct {
+	Runs         int           `json:"runs"`
+	LastRun      time.Time     `json:"lastRun,omitempty"`
+	LastDuration time.Duration `json:"lastDuration,omitempty"`
+	LastRewrites int           `json:"lastRewrites"`
+	LastError    string        `json:"lastError,omitempty"`
+}
+
+// SettGC runs the value log garbage collection periodically until stopped
+type SettGC struct {
+	s        *Sett
+	options  SettGCOptions
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+	mu       sync.Mutex
+	stats    SettGCStats
+}
+
+// StartGC starts the garbage collection loop. The loop is not started for in-memory instances,
+// which don't have value log files to collect
+func (s *Sett) StartGC(options SettGCOptions) *SettGC {
+	if options.Interval <= 0 {
+		options.Interval = defaultGCInterval
+	}
+	if options.DiscardRatio <= 0 || options.DiscardRatio >= 1 {
+		options.DiscardRatio = defaultGCDiscardRatio
+	}
+	gc := &SettGC{s: s, options: options, stop: make(chan struct{}), done: make(chan struct{})}
+	if s.db.Opts().InMemory {
+		close(gc.done)
+		return gc
+	}
+	go gc.run()
+	return gc
+}
+
+func (gc *SettGC) run() {
+	defer close(gc.done)
+	ticker := time.NewTicker(gc.options.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-gc.stop:
+			return
+		case <-ticker.C:
+			gc.RunOnce()
+		}
+	}
+}
+
+// RunOnce rewrites the value log files until there is nothing left to collect and returns the stats of the run
+func (gc *SettGC) RunOnce() SettGCStats {
+	start := time.Now()
+	rewrites := 0
+	var err error
+	for {
+		select {
+		case <-gc.stop:
+			return gc.record(start, rewrites, nil)
+		default:
+		}
+		if err = gc.s.db.RunValueLogGC(gc.options.DiscardRatio); err != nil {
+			break
+		}
+		rewrites++
+	}
+	if errors.Is(err, badger.ErrNoRewrite) || errors.Is(err, badger.ErrRejected) {
+		err = nil
+	}
+	return gc.record(start, rewrites, err)
+}
+
+func (gc *SettGC) record(start time.Time, rewrites int, err error) SettGCStats {
+	duration := time.Since(start)
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	gc.stats.Runs++
+	gc.stats.LastRun = start
+	gc.stats.LastDuration = duration
+	gc.stats.LastRewrites = rewrites
+	gc.stats.LastError = ""
+	if err != nil {
+		gc.stats.LastError = err.Error()
+	}
+	gcRunsMetric.Inc()
+	gcRewritesMetric.Set(float64(rewrites))
+	gcDurationMetric.Set(duration.Seconds())
+	gcTimestampMetric.Set(float64(start.Unix()))
+	return gc.stats
+}
+
+// Options returns the options of the garbage collection, with the defaults applied
+func (gc *SettGC) Options() SettGCOptions {
+	return gc.options
+}
+
+// Stats returns the stats of the last garbage collection run
+func (gc *SettGC) Stats() SettGCStats {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	return gc.stats
+}
+
+// Stop stops the garbage collection loop and waits for the running collection to finish. Safe to call more than once
+func (gc *SettGC) Stop() {
+	if gc == nil {
+		return
+	}
+	gc.stopOnce.Do(func() {
+		close(gc.stop)
+	})
+	<-gc.done
+}
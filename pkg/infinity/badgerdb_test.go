@@ -0,0 +1,542 @@
+package infinity_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+)
+
+func TestSett_TTL(t *testing.T) {
+	s := infinity.Open()
+	defer s.Close()
+	table := s.Table("ttl")
+	require.Nil(t, table.SetWithTTL("short", "foo", time.Minute))
+	require.Nil(t, table.SetWithTTL("long", "bar", time.Hour))
+	require.Nil(t, table.SetStr("forever", "baz"))
+	ttl, err := table.TTL("short")
+	require.Nil(t, err)
+	require.True(t, ttl > 58*time.Second && ttl <= time.Minute)
+	ttl, err = table.TTL("long")
+	require.Nil(t, err)
+	require.True(t, ttl > 59*time.Minute && ttl <= time.Hour)
+	ttl, err = table.TTL("forever")
+	require.Nil(t, err)
+	require.Equal(t, time.Duration(0), ttl)
+	_, err = table.TTL("missing")
+	require.NotNil(t, err)
+	value, err := table.GetStr("long")
+	require.Nil(t, err)
+	require.Equal(t, "bar", value)
+}
+
+func TestSett_Scan(t *testing.T) {
+	s := infinity.Open()
+	defer s.Close()
+	table := s.Table("scan")
+	for _, key := range []string{"a1", "a2", "a3", "a4", "a5", "b1"} {
+		require.Nil(t, table.SetStr(key, "value-"+key))
+	}
+	require.Nil(t, s.Table("other").SetStr("a6", "other"))
+	_, _, err := table.Scan("", "", 0)
+	require.NotNil(t, err)
+	items, cursor, err := table.Scan("a", "", 2)
+	require.Nil(t, err)
+	require.Equal(t, []infinity.SettKV{{Key: "a1", Value: "value-a1"}, {Key: "a2", Value: "value-a2"}}, items)
+	require.Equal(t, "a3", cursor)
+	items, cursor, err = table.Scan("a", cursor, 2)
+	require.Nil(t, err)
+	require.Equal(t, []infinity.SettKV{{Key: "a3", Value: "value-a3"}, {Key: "a4", Value: "value-a4"}}, items)
+	require.Equal(t, "a5", cursor)
+	items, cursor, err = table.Scan("a", cursor, 2)
+	require.Nil(t, err)
+	require.Equal(t, []infinity.SettKV{{Key: "a5", Value: "value-a5"}}, items)
+	require.Equal(t, "", cursor)
+	items, cursor, err = table.Scan("", "", 10)
+	require.Nil(t, err)
+	require.Len(t, items, 6)
+	require.Equal(t, "", cursor)
+}
+
+func TestSett_Incr(t *testing.T) {
+	s := infinity.Open()
+	defer s.Close()
+	table := s.Table("counters")
+	value, err := table.Incr("hits", 5)
+	require.Nil(t, err)
+	require.Equal(t, int64(5), value)
+	value, err = table.Incr("hits", -2)
+	require.Nil(t, err)
+	require.Equal(t, int64(3), value)
+	// concurrent increments, including the increments of the other handles of the table, return unique values
+	wg := sync.WaitGroup{}
+	values := make(chan int64, 100)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			handle := table
+			if i%2 == 0 {
+				handle = s.Table("counters")
+			}
+			value, err := handle.Incr("hits", 1)
+			assert.Nil(t, err)
+			values <- value
+		}(i)
+	}
+	wg.Wait()
+	close(values)
+	seen := map[int64]bool{}
+	for value := range values {
+		require.False(t, seen[value], "duplicate counter value %d", value)
+		seen[value] = true
+	}
+	require.Len(t, seen, 100)
+	value, err = table.Counter("hits")
+	require.Nil(t, err)
+	require.Equal(t, int64(103), value)
+	// counters of the other tables with the same key are independent
+	value, err = s.Table("other").Incr("hits", 1)
+	require.Nil(t, err)
+	require.Equal(t, int64(1), value)
+	require.Nil(t, table.SetStr("name", "foo"))
+	_, err = table.Incr("name", 1)
+	require.NotNil(t, err)
+}
+
+func TestSett_List(t *testing.T) {
+	s := infinity.Open()
+	defer s.Close()
+	table := s.Table("lists")
+	for i := 1; i <= 12; i++ {
+		require.Nil(t, table.Push("jobs", fmt.Sprintf("job-%d", i)))
+	}
+	require.Nil(t, table.Push("other", "other-1"))
+	items, err := table.Range("jobs", 0, 3)
+	require.Nil(t, err)
+	require.Equal(t, []interface{}{"job-1", "job-2", "job-3"}, items)
+	items, err = table.Range("jobs", 10, 5)
+	require.Nil(t, err)
+	require.Equal(t, []interface{}{"job-11", "job-12"}, items)
+	items, err = table.PopN("jobs", 2)
+	require.Nil(t, err)
+	require.Equal(t, []interface{}{"job-1", "job-2"}, items)
+	items, err = table.Range("jobs", 0, 1)
+	require.Nil(t, err)
+	require.Equal(t, []interface{}{"job-3"}, items)
+	items, err = table.PopN("jobs", 100)
+	require.Nil(t, err)
+	require.Len(t, items, 10)
+	items, err = table.PopN("jobs", 1)
+	require.Nil(t, err)
+	require.Empty(t, items)
+	items, err = table.Range("other", 0, 10)
+	require.Nil(t, err)
+	require.Equal(t, []interface{}{"other-1"}, items)
+	t.Run("should trim the list to the last values", func(t *testing.T) {
+		removed, err := table.Trim("trimmed", 2)
+		require.Nil(t, err)
+		require.Equal(t, 0, removed)
+		for i := 1; i <= 5; i++ {
+			require.Nil(t, table.Push("trimmed", fmt.Sprintf("event-%d", i)))
+		}
+		removed, err = table.Trim("trimmed", 2)
+		require.Nil(t, err)
+		require.Equal(t, 3, removed)
+		items, err := table.Range("trimmed", 0, 10)
+		require.Nil(t, err)
+		require.Equal(t, []interface{}{"event-4", "event-5"}, items)
+		require.Nil(t, table.Push("trimmed", "event-6"))
+		removed, err = table.Trim("trimmed", 2)
+		require.Nil(t, err)
+		require.Equal(t, 1, removed)
+		removed, err = table.Trim("trimmed", 10)
+		require.Nil(t, err)
+		require.Equal(t, 0, removed)
+		items, err = table.Range("trimmed", 0, 10)
+		require.Nil(t, err)
+		require.Equal(t, []interface{}{"event-5", "event-6"}, items)
+	})
+	t.Run("should keep the list items out of the table keys", func(t *testing.T) {
+		require.Nil(t, table.Set("foo", "bar"))
+		keys, err := table.Keys()
+		require.Nil(t, err)
+		require.Equal(t, []string{"foo"}, keys)
+		keys, err = table.Filter(func(k string, v interface{}) bool { return true })
+		require.Nil(t, err)
+		require.Equal(t, []string{"foo"}, keys)
+		_, err = table.Drop()
+		require.Nil(t, err)
+		items, err := table.Range("other", 0, 10)
+		require.Nil(t, err)
+		require.Empty(t, items)
+	})
+	t.Run("should not match the tables sharing the name prefix", func(t *testing.T) {
+		require.Nil(t, s.Table("a").Set("foo", "bar"))
+		require.Nil(t, s.Table("ab").Set("baz", "qux"))
+		keys, err := s.Table("a").Filter(func(k string, v interface{}) bool { return true })
+		require.Nil(t, err)
+		require.Equal(t, []string{"foo"}, keys)
+	})
+}
+
+type settTestUser struct {
+	Name string
+	Team string
+}
+
+func TestSett_Index(t *testing.T) {
+	infinity.RegisterSettType("settTestUser", &settTestUser{})
+	s := infinity.Open()
+	defer s.Close()
+	table := s.Table("users")
+	require.Nil(t, table.SetStruct("u1", &settTestUser{Name: "foo", Team: "red"}))
+	require.Nil(t, table.AddIndex("team", func(v interface{}) (string, bool) {
+		if u, ok := v.(*settTestUser); ok {
+			return u.Team, true
+		}
+		return "", false
+	}))
+	require.Nil(t, table.SetStruct("u2", &settTestUser{Name: "bar", Team: "blue"}))
+	require.Nil(t, table.SetStruct("u3", &settTestUser{Name: "baz", Team: "red"}))
+	require.Nil(t, table.SetStr("note", "not a user"))
+	keys, err := table.FilterByIndex("team", "red")
+	require.Nil(t, err)
+	require.Equal(t, []string{"u1", "u3"}, keys)
+	require.Nil(t, table.SetStruct("u1", &settTestUser{Name: "foo", Team: "blue"}))
+	keys, err = table.FilterByIndex("team", "red")
+	require.Nil(t, err)
+	require.Equal(t, []string{"u3"}, keys)
+	require.Nil(t, table.Delete("u2"))
+	keys, err = table.FilterByIndex("team", "blue")
+	require.Nil(t, err)
+	require.Equal(t, []string{"u1"}, keys)
+	_, err = table.Cut("u1")
+	require.Nil(t, err)
+	keys, err = table.FilterByIndex("team", "blue")
+	require.Nil(t, err)
+	require.Empty(t, keys)
+	_, err = table.FilterByIndex("name", "foo")
+	require.NotNil(t, err)
+	_, err = s.Table("other").FilterByIndex("team", "red")
+	require.NotNil(t, err)
+}
+
+func TestSett_SetIfVersion(t *testing.T) {
+	s := infinity.Open()
+	defer s.Close()
+	table := s.Table("versions")
+	require.Nil(t, table.SetIfVersion("config", "v1", 0))
+	require.ErrorIs(t, table.SetIfVersion("config", "again", 0), infinity.ErrVersionMismatch)
+	value, version, err := table.GetWithVersion("config")
+	require.Nil(t, err)
+	require.Equal(t, "v1", value)
+	require.Nil(t, table.SetIfVersion("config", "v2", version))
+	require.ErrorIs(t, table.SetIfVersion("config", "stale", version), infinity.ErrVersionMismatch)
+	value, newVersion, err := table.GetWithVersion("config")
+	require.Nil(t, err)
+	require.Equal(t, "v2", value)
+	require.Greater(t, newVersion, version)
+	_, _, err = table.GetWithVersion("missing")
+	require.NotNil(t, err)
+}
+
+func TestSett_TryLock(t *testing.T) {
+	s := infinity.Open()
+	defer s.Close()
+	table := s.Table("locks")
+	require.Nil(t, table.SetStr("job", "pending"))
+	require.Nil(t, table.TryLock("job", "worker-1", 100*time.Millisecond))
+	require.NotNil(t, table.TryLock("job", "worker-2", time.Minute))
+	require.NotNil(t, table.SetStr("job", "running"))
+	require.Nil(t, table.TryLock("job", "worker-1", 100*time.Millisecond))
+	owner, deadline, err := table.LockOwner("job")
+	require.Nil(t, err)
+	require.Equal(t, "worker-1", owner)
+	require.True(t, deadline.After(time.Now()))
+	time.Sleep(150 * time.Millisecond)
+	owner, _, err = table.LockOwner("job")
+	require.Nil(t, err)
+	require.Equal(t, "", owner)
+	require.Nil(t, table.TryLock("job", "worker-2", time.Minute))
+	require.NotNil(t, table.Lock("job"))
+	require.Nil(t, table.ForceUnlock("job"))
+	require.Nil(t, table.SetStr("job", "done"))
+	require.Nil(t, table.Lock("job"))
+	require.NotNil(t, table.TryLock("job", "worker-3", time.Minute))
+	owner, deadline, err = table.LockOwner("job")
+	require.Nil(t, err)
+	require.Equal(t, "", owner)
+	require.True(t, deadline.IsZero())
+	value, err := table.GetStr("job")
+	require.Nil(t, err)
+	require.Equal(t, "done", value)
+}
+
+func TestSett_Drop(t *testing.T) {
+	s := infinity.Open()
+	defer s.Close()
+	for _, key := range []string{"a", "b", "c"} {
+		require.Nil(t, s.Table("drop").SetStr(key, key))
+	}
+	require.Nil(t, s.Table("drop2").SetStr("a", "a"))
+	count, err := s.Table("drop").Drop()
+	require.Nil(t, err)
+	require.Equal(t, 3, count)
+	keys, err := s.Table("drop").Keys()
+	require.Nil(t, err)
+	require.Empty(t, keys)
+	keys, err = s.Table("drop2").Keys()
+	require.Nil(t, err)
+	require.Equal(t, []string{"a"}, keys)
+}
+
+func TestSett_WithContext(t *testing.T) {
+	s := infinity.Open()
+	defer s.Close()
+	require.Nil(t, s.Table("ctx").SetStr("a", "a"))
+	ctx, cancel := context.WithCancel(context.Background())
+	table := s.Table("ctx").WithContext(ctx)
+	value, err := table.GetStr("a")
+	require.Nil(t, err)
+	require.Equal(t, "a", value)
+	cancel()
+	_, err = table.GetStr("a")
+	require.ErrorIs(t, err, context.Canceled)
+	require.ErrorIs(t, table.SetStr("b", "b"), context.Canceled)
+	_, err = table.Keys()
+	require.ErrorIs(t, err, context.Canceled)
+	_, err = table.Table("other").GetStr("a")
+	require.ErrorIs(t, err, context.Canceled)
+	keys, err := s.Table("ctx").Keys()
+	require.Nil(t, err)
+	require.Equal(t, []string{"a"}, keys)
+}
+
+func TestSett_Codec(t *testing.T) {
+	gob.Register(&settTestUser{})
+	infinity.RegisterSettType("settTestUser", &settTestUser{})
+	s := infinity.Open()
+	defer s.Close()
+	gobTable := s.Table("codec").WithCodec(infinity.GobCodec{})
+	require.Nil(t, gobTable.SetStruct("u1", &settTestUser{Name: "foo", Team: "red"}))
+	require.Nil(t, gobTable.SetStruct("u2", &settTestUser{Name: "bar", Team: "blue"}))
+	jsonTable := s.Table("codec").WithCodec(infinity.JSONCodec{})
+	require.Nil(t, jsonTable.SetStruct("u3", map[string]interface{}{"name": "baz"}))
+	value, err := jsonTable.GetStruct("u1")
+	require.Nil(t, err)
+	require.Equal(t, &settTestUser{Name: "foo", Team: "red"}, value)
+	count, err := jsonTable.Migrate()
+	require.Nil(t, err)
+	require.Equal(t, 1, count)
+	count, err = jsonTable.Migrate()
+	require.Nil(t, err)
+	require.Equal(t, 0, count)
+	// tables without the codec read the gob and json values and write msgpack
+	defaultTable := s.Table("codec")
+	require.Nil(t, defaultTable.SetStruct("u4", &settTestUser{Name: "qux", Team: "green"}))
+	value, err = jsonTable.GetStruct("u4")
+	require.Nil(t, err)
+	require.Equal(t, &settTestUser{Name: "qux", Team: "green"}, value)
+	require.Nil(t, gobTable.SetStruct("u5", &settTestUser{Name: "quux", Team: "red"}))
+	value, err = defaultTable.GetStruct("u5")
+	require.Nil(t, err)
+	require.Equal(t, &settTestUser{Name: "quux", Team: "red"}, value)
+	count, err = defaultTable.Migrate()
+	require.Nil(t, err)
+	require.Equal(t, 4, count)
+	for _, table := range []*infinity.Sett{gobTable, jsonTable, defaultTable} {
+		value, err = table.GetStruct("u2")
+		require.Nil(t, err)
+		require.Equal(t, &settTestUser{Name: "bar", Team: "blue"}, value)
+		value, err = table.GetStruct("u3")
+		require.Nil(t, err)
+		require.Equal(t, map[string]interface{}{"name": "baz"}, value)
+	}
+}
+
+func TestSett_CodecBuffers(t *testing.T) {
+	gob.Register(&settTestUser{})
+	infinity.RegisterSettType("settTestUser", &settTestUser{})
+	// marshalled values must not share the pooled buffers
+	for _, codec := range []infinity.Codec{infinity.GobCodec{}, infinity.JSONCodec{}, infinity.MsgpackCodec{}} {
+		a, err := codec.Marshal(&settTestUser{Name: "foo", Team: "red"})
+		require.Nil(t, err)
+		b, err := codec.Marshal(&settTestUser{Name: "bar", Team: "blue"})
+		require.Nil(t, err)
+		value, err := codec.Unmarshal(a)
+		require.Nil(t, err)
+		require.Equal(t, &settTestUser{Name: "foo", Team: "red"}, value)
+		value, err = codec.Unmarshal(b)
+		require.Nil(t, err)
+		require.Equal(t, &settTestUser{Name: "bar", Team: "blue"}, value)
+	}
+}
+
+func TestOpenWithOptions(t *testing.T) {
+	dir := t.TempDir()
+	keyA := []byte("0123456789abcdef")
+	keyB := []byte("fedcba9876543210fedcba9876543210")
+	db, err := infinity.OpenWithOptions(infinity.SettOptions{Dir: dir, EncryptionKey: keyA})
+	require.Nil(t, err)
+	require.Nil(t, db.Table("t").Set("foo", "bar"))
+	require.Nil(t, db.Close())
+
+	_, err = infinity.OpenWithOptions(infinity.SettOptions{Dir: dir, EncryptionKey: keyB})
+	require.NotNil(t, err)
+
+	db, err = infinity.OpenWithOptions(infinity.SettOptions{Dir: dir, EncryptionKey: keyB, PreviousEncryptionKey: keyA})
+	require.Nil(t, err)
+	value, err := db.Table("t").Get("foo")
+	require.Nil(t, err)
+	require.Equal(t, "bar", value)
+	require.Nil(t, db.Close())
+
+	db, err = infinity.OpenWithOptions(infinity.SettOptions{Dir: dir, EncryptionKey: keyB, PreviousEncryptionKey: keyA})
+	require.Nil(t, err)
+	value, err = db.Table("t").Get("foo")
+	require.Nil(t, err)
+	require.Equal(t, "bar", value)
+	require.Nil(t, db.Close())
+}
+
+func TestSett_Limits(t *testing.T) {
+	db := infinity.Open()
+	defer db.Close()
+	t.Run("table entries", func(t *testing.T) {
+		table := db.Table("limited").WithLimits(infinity.SettLimits{MaxEntries: 3})
+		for i := 0; i < 3; i++ {
+			require.Nil(t, table.Set(fmt.Sprintf("k%d", i), "v"))
+		}
+		_, err := table.Get("k0")
+		require.Nil(t, err)
+		require.Nil(t, table.Set("k3", "v"))
+		keys, err := db.Table("limited").Keys()
+		require.Nil(t, err)
+		require.Equal(t, []string{"k0", "k2", "k3"}, keys)
+	})
+	t.Run("locked entries are not evicted", func(t *testing.T) {
+		table := db.Table("locked").WithLimits(infinity.SettLimits{MaxEntries: 1})
+		require.Nil(t, table.Set("a", "v"))
+		require.Nil(t, table.Lock("a"))
+		require.Nil(t, table.Set("b", "v"))
+		keys, err := table.Keys()
+		require.Nil(t, err)
+		require.Equal(t, []string{"a"}, keys)
+	})
+	t.Run("global bytes", func(t *testing.T) {
+		db := infinity.Open()
+		defer db.Close()
+		db.WithLimits(infinity.SettLimits{MaxBytes: 1000})
+		for i := 0; i < 20; i++ {
+			require.Nil(t, db.Table(fmt.Sprintf("t%d", i%2)).Set(fmt.Sprintf("k%02d", i), strings.Repeat("x", 100)))
+		}
+		keys0, err := db.Table("t0").Keys()
+		require.Nil(t, err)
+		keys1, err := db.Table("t1").Keys()
+		require.Nil(t, err)
+		require.Less(t, len(keys0)+len(keys1), 10)
+		require.Contains(t, keys1, "k19")
+		require.NotContains(t, keys0, "k00")
+	})
+}
+
+func TestSett_StartGC(t *testing.T) {
+	t.Run("in-memory instance", func(t *testing.T) {
+		db := infinity.Open()
+		defer db.Close()
+		gc := db.StartGC(infinity.SettGCOptions{})
+		gc.Stop()
+		gc.Stop()
+		require.Equal(t, 0, gc.Stats().Runs)
+	})
+	t.Run("persistent instance", func(t *testing.T) {
+		db, err := infinity.OpenWithOptions(infinity.SettOptions{Dir: t.TempDir()})
+		require.Nil(t, err)
+		defer db.Close()
+		gc := db.StartGC(infinity.SettGCOptions{Interval: 10 * time.Millisecond})
+		require.Eventually(t, func() bool { return gc.Stats().Runs > 0 }, time.Second, 10*time.Millisecond)
+		gc.Stop()
+		stats := gc.Stats()
+		require.Empty(t, stats.LastError)
+		require.False(t, stats.LastRun.IsZero())
+	})
+}
+
+func TestSett_Namespace(t *testing.T) {
+	db := infinity.Open()
+	defer db.Close()
+	org1 := db.Namespace("org1/ds1")
+	org2 := db.Namespace("org2/ds1")
+	require.Nil(t, org1.Table("peers").Set("k", "one"))
+	require.Nil(t, org2.Table("peers").Set("k", "two"))
+	require.Nil(t, db.Table("peers").Set("k", "root"))
+	value, err := org1.Table("peers").Get("k")
+	require.Nil(t, err)
+	require.Equal(t, "one", value)
+	value, err = org2.Table("peers").Get("k")
+	require.Nil(t, err)
+	require.Equal(t, "two", value)
+	count, err := org1.Drop()
+	require.Nil(t, err)
+	require.Equal(t, 1, count)
+	require.False(t, org1.Table("peers").HasKey("k"))
+	require.True(t, org2.Table("peers").HasKey("k"))
+	require.True(t, db.Table("peers").HasKey("k"))
+}
+
+func TestSett_BackupAndLoad(t *testing.T) {
+	db := infinity.Open()
+	defer db.Close()
+	require.Nil(t, db.Table("t1").Set("foo", "bar"))
+	require.Nil(t, db.Table("t2").SetStruct("user", &settTestUser{Name: "foo", Team: "a"}))
+	var b bytes.Buffer
+	version, err := db.Backup(&b, 0)
+	require.Nil(t, err)
+	require.Greater(t, version, uint64(0))
+
+	restored := infinity.Open()
+	defer restored.Close()
+	require.Nil(t, restored.Load(&b))
+	value, err := restored.Table("t1").Get("foo")
+	require.Nil(t, err)
+	require.Equal(t, "bar", value)
+	value, err = restored.Table("t2").GetStruct("user")
+	require.Nil(t, err)
+	require.Equal(t, &settTestUser{Name: "foo", Team: "a"}, value)
+	t.Run("should backup and load only the entries of the namespace", func(t *testing.T) {
+		db := infinity.Open()
+		defer db.Close()
+		org1, org2 := db.Namespace("org1"), db.Namespace("org2")
+		require.Nil(t, org1.Table("peers").Set("foo", "bar"))
+		require.Nil(t, org1.Table("jobs").Push("queue", "job-1"))
+		require.Nil(t, org2.Table("peers").Set("baz", "qux"))
+		var b bytes.Buffer
+		_, err := org1.Backup(&b, 0)
+		require.Nil(t, err)
+		backup := b.Bytes()
+
+		restored := infinity.Open()
+		defer restored.Close()
+		require.Nil(t, restored.Namespace("org1").Load(bytes.NewReader(backup)))
+		value, err := restored.Namespace("org1").Table("peers").Get("foo")
+		require.Nil(t, err)
+		require.Equal(t, "bar", value)
+		items, err := restored.Namespace("org1").Table("jobs").Range("queue", 0, 10)
+		require.Nil(t, err)
+		require.Equal(t, []interface{}{"job-1"}, items)
+		require.False(t, restored.Namespace("org2").Table("peers").HasKey("baz"))
+
+		err = restored.Namespace("org2").Load(bytes.NewReader(backup))
+		require.ErrorContains(t, err, "the backup has the entries of the other tables")
+		require.False(t, restored.Namespace("org2").Table("peers").HasKey("foo"))
+	})
+}
@@ -0,0 +1,175 @@
+package infinity_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+const benchmarkRows = 1000
+
+var benchmarkColumns = []models.InfinityColumn{
+	{Selector: "name", Text: "Name", Type: "string"},
+	{Selector: "age", Text: "Age", Type: "number"},
+	{Selector: "country", Text: "Country", Type: "string"},
+	{Selector: "joined", Text: "Joined", Type: "timestamp"},
+}
+
+// benchmarkResponses returns the json, csv and xml responses of the users with the given number of rows
+func benchmarkResponses(rows int) (string, string, string) {
+	var jsonBody, csvBody, xmlBody strings.Builder
+	jsonBody.WriteString("[")
+	csvBody.WriteString("name,age,country,joined\n")
+	xmlBody.WriteString("<users>")
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			jsonBody.WriteString(",")
+		}
+		name, age, country, joined := fmt.Sprintf("user%d", i), 20+i%50, []string{"uk", "us", "in"}[i%3], time.Unix(int64(1600000000+i*3600), 0).UTC().Format(time.RFC3339)
+		fmt.Fprintf(&jsonBody, `{"name":%q,"age":%d,"country":%q,"joined":%q}`, name, age, country, joined)
+		fmt.Fprintf(&csvBody, "%s,%d,%s,%s\n", name, age, country, joined)
+		fmt.Fprintf(&xmlBody, `<user name="%s" age="%d" country="%s" joined="%s" />`, name, age, country, joined)
+	}
+	jsonBody.WriteString("]")
+	xmlBody.WriteString("</users>")
+	return jsonBody.String(), csvBody.String(), xmlBody.String()
+}
+
+func BenchmarkGetJSONBackendResponse(b *testing.B) {
+	jsonBody, _, _ := benchmarkResponses(benchmarkRows)
+	var body any
+	require.Nil(b, json.Unmarshal([]byte(jsonBody), &body))
+	query := models.Query{RefID: "A", Type: models.QueryTypeJSON, Parser: models.InfinityParserBackend, Columns: benchmarkColumns}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := infinity.GetJSONBackendResponse(context.Background(), body, query); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetCSVBackendResponse(b *testing.B) {
+	_, csvBody, _ := benchmarkResponses(benchmarkRows)
+	query := models.Query{RefID: "A", Type: models.QueryTypeCSV, Parser: models.InfinityParserBackend, Columns: benchmarkColumns}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := infinity.GetCSVBackendResponse(context.Background(), csvBody, query); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetXMLBackendResponse(b *testing.B) {
+	_, _, xmlBody := benchmarkResponses(benchmarkRows)
+	query := models.Query{RefID: "A", Type: models.QueryTypeXML, Parser: models.InfinityParserBackend, RootSelector: "users.user", Columns: benchmarkColumns[:3]}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := infinity.GetXMLBackendResponse(context.Background(), xmlBody, query); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPostProcessFrame(b *testing.B) {
+	_, csvBody, _ := benchmarkResponses(benchmarkRows)
+	query := models.Query{RefID: "A", Type: models.QueryTypeCSV, Parser: models.InfinityParserBackend, Columns: benchmarkColumns, FilterExpression: "age > 30"}
+	frame, err := infinity.GetCSVBackendResponse(context.Background(), csvBody, query)
+	require.Nil(b, err)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := infinity.PostProcessFrame(context.Background(), frame, query); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetFrameForURLSources(b *testing.B) {
+	// request logs interleave with the benchmark results otherwise
+	logger := backend.Logger
+	backend.Logger = log.NewWithLevel(log.Error)
+	defer func() { backend.Logger = logger }()
+	jsonBody, csvBody, xmlBody := benchmarkResponses(benchmarkRows)
+	bodies := map[string]string{"/users.json": jsonBody, "/users.csv": csvBody, "/users.xml": xmlBody}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, bodies[r.URL.Path])
+	}))
+	defer server.Close()
+	client := infinity.Client{Settings: models.InfinitySettings{}, HttpClient: server.Client()}
+	queries := map[string]models.Query{
+		"json": {RefID: "A", Source: "url", Type: models.QueryTypeJSON, Parser: models.InfinityParserBackend, URL: server.URL + "/users.json", Columns: benchmarkColumns},
+		"csv":  {RefID: "A", Source: "url", Type: models.QueryTypeCSV, Parser: models.InfinityParserBackend, URL: server.URL + "/users.csv", Columns: benchmarkColumns},
+		"xml":  {RefID: "A", Source: "url", Type: models.QueryTypeXML, Parser: models.InfinityParserBackend, URL: server.URL + "/users.xml", RootSelector: "users.user", Columns: benchmarkColumns[:3]},
+	}
+	for _, name := range []string{"json", "csv", "xml"} {
+		query := queries[name]
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := infinity.GetFrameForURLSources(context.Background(), query, client, map[string]string{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkResponseCache(b *testing.B) {
+	value := []byte(strings.Repeat("x", 64<<10))
+	for _, tt := range []struct {
+		name     string
+		settings models.InfinitySettings
+	}{
+		{name: "badger", settings: models.InfinitySettings{UID: "benchmark-badger"}},
+		{name: "memory", settings: models.InfinitySettings{UID: "benchmark-memory", CacheMemoryEntries: 100}},
+	} {
+		b.Run(tt.name, func(b *testing.B) {
+			client, err := infinity.NewClient(context.Background(), tt.settings)
+			require.Nil(b, err)
+			cache := client.ResponseCache()
+			require.Nil(b, cache.Set(context.Background(), "users", value, time.Minute))
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := cache.Get(context.Background(), "users"); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkSettCodecs(b *testing.B) {
+	infinity.BadgerInit()
+	value := &infinity.Mycache{Body: []byte(strings.Repeat("x", 4<<10)), StatusCode: http.StatusOK, Duration: time.Second, JsonBody: true}
+	for _, codec := range []infinity.Codec{infinity.GobCodec{}, infinity.JSONCodec{}, infinity.MsgpackCodec{}} {
+		b.Run(fmt.Sprintf("%T", codec), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				data, err := codec.Marshal(value)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := codec.Unmarshal(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
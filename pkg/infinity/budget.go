@@ -0,0 +1,78 @@
+package infinity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// ApplyFrameBudget reports the estimated frame size in the frame meta and limits the rows
+// of the frame when the number of cells exceed the given budget. Zero budget means no limit
+func ApplyFrameBudget(ctx context.Context, frame *data.Frame, maxCells int64) *data.Frame {
+	_, span := tracing.DefaultTracer().Start(ctx, "ApplyFrameBudget")
+	defer span.End()
+	if frame == nil {
+		return frame
+	}
+	if frame.Meta == nil {
+		frame.Meta = &data.FrameMeta{}
+	}
+	cells := int64(frame.Rows() * len(frame.Fields))
+	if maxCells > 0 && cells > maxCells && len(frame.Fields) > 0 {
+		rows := int(maxCells / int64(len(frame.Fields)))
+		for _, field := range frame.Fields {
+			for field.Len() > rows {
+				field.Delete(field.Len() - 1)
+			}
+		}
+		frame.AppendNotices(data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("Result has %d cells which exceeds the datasource limit of %d cells. Showing only the first %d rows.", cells, maxCells, rows),
+		})
+	}
+	frame.Meta.Stats = append(frame.Meta.Stats, data.QueryStat{
+		FieldConfig: data.FieldConfig{DisplayName: "Estimated frame size", Unit: "decbytes"},
+		Value:       float64(EstimateFrameSize(frame)),
+	})
+	return frame
+}
+
+// EstimateFrameSize returns the approximate size of the frame values in bytes
+func EstimateFrameSize(frame *data.Frame) int64 {
+	var size int64
+	if frame == nil {
+		return size
+	}
+	for _, field := range frame.Fields {
+		fieldType := field.Type()
+		switch fieldType.NonNullableType() {
+		case data.FieldTypeString:
+			for i := 0; i < field.Len(); i++ {
+				if v, ok := field.ConcreteAt(i); ok {
+					size += int64(len(v.(string)))
+				}
+			}
+		case data.FieldTypeJSON:
+			for i := 0; i < field.Len(); i++ {
+				if v, ok := field.ConcreteAt(i); ok {
+					size += int64(len(v.(json.RawMessage)))
+				}
+			}
+		case data.FieldTypeInt8, data.FieldTypeUint8, data.FieldTypeBool:
+			size += int64(field.Len())
+		case data.FieldTypeInt16, data.FieldTypeUint16:
+			size += int64(field.Len() * 2)
+		case data.FieldTypeInt32, data.FieldTypeUint32, data.FieldTypeFloat32:
+			size += int64(field.Len() * 4)
+		default:
+			size += int64(field.Len() * 8)
+		}
+		if fieldType.Nullable() {
+			size += int64((field.Len() + 7) / 8)
+		}
+	}
+	return size
+}
@@ -0,0 +1,35 @@
+package infinity_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+)
+
+func TestApplyFrameBudget(t *testing.T) {
+	getFrame := func() *data.Frame {
+		return data.NewFrame("response",
+			data.NewField("name", nil, []string{"foo", "bar", "baz", "qux"}),
+			data.NewField("value", nil, []*float64{toFP(1), toFP(2), nil, toFP(4)}),
+		)
+	}
+	t.Run("should report the estimated size without budget", func(t *testing.T) {
+		frame := infinity.ApplyFrameBudget(context.Background(), getFrame(), 0)
+		require.Equal(t, 4, frame.Rows())
+		require.Len(t, frame.Meta.Stats, 1)
+		require.Equal(t, "Estimated frame size", frame.Meta.Stats[0].DisplayName)
+		require.Equal(t, float64(12+32+1), frame.Meta.Stats[0].Value)
+		require.Empty(t, frame.Meta.Notices)
+	})
+	t.Run("should limit the rows when the budget exceeded", func(t *testing.T) {
+		frame := infinity.ApplyFrameBudget(context.Background(), getFrame(), 5)
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, "bar", frame.Fields[0].At(1))
+		require.Len(t, frame.Meta.Notices, 1)
+		require.Equal(t, data.NoticeSeverityWarning, frame.Meta.Notices[0].Severity)
+		require.Equal(t, float64(6+16+1), frame.Meta.Stats[0].Value)
+	})
+}
@@ -0,0 +1,40 @@
+package infinity
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// maxPooledBufferSize is the capacity above which the buffers are not returned to the pool,
+// so that a single large response doesn't keep the memory pinned by the pool
+const maxPooledBufferSize = 4 << 20
+
+var bufferPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(b *bytes.Buffer) {
+	if b.Cap() > maxPooledBufferSize {
+		return
+	}
+	b.Reset()
+	bufferPool.Put(b)
+}
+
+// readAll reads the reader into a pooled buffer and returns the copy of the content. sizeHint is the expected size
+// of the content, such as the content length of the response. Unlike io.ReadAll, the content is copied only once
+// into the slice of the exact size instead of growing the slice repeatedly
+func readAll(r io.Reader, sizeHint int64) ([]byte, error) {
+	b := getBuffer()
+	defer putBuffer(b)
+	if sizeHint > 0 && sizeHint <= maxPooledBufferSize {
+		b.Grow(int(sizeHint) + bytes.MinRead)
+	}
+	if _, err := b.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	return bytes.Clone(b.Bytes()), nil
+}
@@ -0,0 +1,127 @@
+package infinity
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v3"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+const (
+	CacheBackendBadger    = "badger"
+	CacheBackendRedis     = "redis"
+	CacheBackendMemcached = "memcached"
+)
+
+var (
+	// ErrCacheMiss is returned by the cache backends when the key is not found or expired
+	ErrCacheMiss = errors.New("cache miss")
+	// ErrPurgeNotSupported is returned by the cache backends which can't enumerate their keys
+	ErrPurgeNotSupported = errors.New("purge is not supported by the cache backend")
+)
+
+// CacheBackend stores the cached responses. Backends other than the default badger backend
+// can be shared by multiple Grafana instances
+type CacheBackend interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+	// Purge removes all the keys of the backend. Returns the number of removed keys
+	Purge(ctx context.Context) (int, error)
+}
+
+// NewCacheBackend returns the cache backend configured in the settings. Keys are prefixed with the namespace.
+// Nil backend is returned for the default badger backend, which is namespaced by the client
+func NewCacheBackend(settings models.InfinitySettings, namespace string) (CacheBackend, error) {
+	switch settings.CacheBackend {
+	case "", CacheBackendBadger:
+		return nil, nil
+	case CacheBackendRedis:
+		return NewRedisCacheBackend(settings.CacheBackendURL, settings.CacheBackendPassword, namespace)
+	case CacheBackendMemcached:
+		return NewMemcachedCacheBackend(settings.CacheBackendURL, namespace)
+	default:
+		return nil, fmt.Errorf("unknown cache backend %s", settings.CacheBackend)
+	}
+}
+
+// SettCacheBackend stores the cached responses in a Sett table
+type SettCacheBackend struct {
+	s *Sett
+}
+
+func NewSettCacheBackend(s *Sett) *SettCacheBackend {
+	return &SettCacheBackend{s: s}
+}
+
+func (b *SettCacheBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := b.s.WithContext(ctx).GetStr(key)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, ErrCacheMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(value), nil
+}
+
+func (b *SettCacheBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return b.s.WithContext(ctx).SetWithTTL(key, string(value), ttl)
+}
+
+func (b *SettCacheBackend) Delete(ctx context.Context, key string) error {
+	return b.s.WithContext(ctx).Delete(key)
+}
+
+func (b *SettCacheBackend) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return b.s.WithContext(ctx).TTL(key)
+}
+
+func (b *SettCacheBackend) Purge(ctx context.Context) (int, error) {
+	return b.s.WithContext(ctx).Drop()
+}
+
+// cachedResponse is the serialized form of Mycache. Errors are stored as their messages
+type cachedResponse struct {
+	Body       []byte
+	StatusCode int
+	Duration   time.Duration
+	Err        string
+	JsonBody   bool
+}
+
+// responseEnvelope writes the cached responses with the default codec of Sett, in the same envelope of version and codec
+// as the struct values of the tables. The envelope is stored along with the value, as the cache backends store only the bytes
+var responseEnvelope = &Sett{}
+
+func encodeMycache(mycache Mycache) ([]byte, error) {
+	res := cachedResponse{Body: mycache.Body, StatusCode: mycache.StatusCode, Duration: mycache.Duration, JsonBody: mycache.JsonBody}
+	if mycache.Err != nil {
+		res.Err = mycache.Err.Error()
+	}
+	return responseEnvelope.encodeValue(res)
+}
+
+// decodeMycache decodes the cached response. Responses cached before the envelope were introduced are read as gob
+func decodeMycache(value []byte) (*Mycache, error) {
+	var res cachedResponse
+	if v, _, err := responseEnvelope.decodeValue(ENVELOPE_FLAG, value); err == nil {
+		decoded, ok := v.(cachedResponse)
+		if !ok {
+			return nil, fmt.Errorf("unexpected cached response of type %T", v)
+		}
+		res = decoded
+	} else if err := gob.NewDecoder(bytes.NewReader(value)).Decode(&res); err != nil {
+		return nil, err
+	}
+	mycache := &Mycache{Body: res.Body, StatusCode: res.StatusCode, Duration: res.Duration, JsonBody: res.JsonBody}
+	if res.Err != "" {
+		mycache.Err = errors.New(res.Err)
+	}
+	return mycache, nil
+}
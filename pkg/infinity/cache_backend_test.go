@@ -0,0 +1,236 @@
+package infinity_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+)
+
+// fakeCacheServer serves the minimal subset of redis and memcached protocols used by the cache backends
+type fakeCacheServer struct {
+	mu       sync.Mutex
+	data     map[string]string
+	listener net.Listener
+}
+
+func newFakeCacheServer(t *testing.T, handle func(s *fakeCacheServer, r *bufio.Reader, w io.Writer) error) *fakeCacheServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	s := &fakeCacheServer{data: map[string]string{}, listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for handle(s, r, conn) == nil {
+				}
+			}()
+		}
+	}()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+// readRedisCommand reads the arguments of the RESP command
+func readRedisCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+	args := make([]string, n)
+	for i := range args {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(header[1:]))
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func handleRedis(s *fakeCacheServer, r *bufio.Reader, w io.Writer) error {
+	args, err := readRedisCommand(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch strings.ToUpper(args[0]) {
+	case "SET":
+		if _, ok := s.data[args[1]]; ok && len(args) > 3 && args[3] == "NX" {
+			_, err = io.WriteString(w, "$-1\r\n")
+			break
+		}
+		s.data[args[1]] = args[2]
+		_, err = io.WriteString(w, "+OK\r\n")
+	case "EVAL":
+		// compare and delete script of the lease release
+		if s.data[args[3]] != args[4] {
+			_, err = io.WriteString(w, ":0\r\n")
+			break
+		}
+		delete(s.data, args[3])
+		_, err = io.WriteString(w, ":1\r\n")
+	case "GET":
+		v, ok := s.data[args[1]]
+		if !ok {
+			_, err = io.WriteString(w, "$-1\r\n")
+			break
+		}
+		_, err = fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+	case "DEL":
+		count := 0
+		for _, k := range args[1:] {
+			if _, ok := s.data[k]; ok {
+				delete(s.data, k)
+				count++
+			}
+		}
+		_, err = fmt.Fprintf(w, ":%d\r\n", count)
+	case "SCAN":
+		var keys []string
+		for k := range s.data {
+			if ok, _ := path.Match(args[3], k); ok {
+				keys = append(keys, k)
+			}
+		}
+		_, err = fmt.Fprintf(w, "*2\r\n$1\r\n0\r\n*%d\r\n", len(keys))
+		for _, k := range keys {
+			fmt.Fprintf(w, "$%d\r\n%s\r\n", len(k), k)
+		}
+	default:
+		_, err = io.WriteString(w, "-ERR unknown command\r\n")
+	}
+	return err
+}
+
+func handleMemcached(s *fakeCacheServer, r *bufio.Reader, w io.Writer) error {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	fields := strings.Fields(line)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch fields[0] {
+	case "set":
+		size, _ := strconv.Atoi(fields[4])
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return err
+		}
+		s.data[fields[1]] = string(buf[:size])
+		_, err = io.WriteString(w, "STORED\r\n")
+	case "get":
+		if v, ok := s.data[fields[1]]; ok {
+			fmt.Fprintf(w, "VALUE %s 0 %d\r\n%s\r\n", fields[1], len(v), v)
+		}
+		_, err = io.WriteString(w, "END\r\n")
+	case "delete":
+		if _, ok := s.data[fields[1]]; !ok {
+			_, err = io.WriteString(w, "NOT_FOUND\r\n")
+			break
+		}
+		delete(s.data, fields[1])
+		_, err = io.WriteString(w, "DELETED\r\n")
+	default:
+		_, err = io.WriteString(w, "ERROR\r\n")
+	}
+	return err
+}
+
+func TestCacheBackends(t *testing.T) {
+	redisServer := newFakeCacheServer(t, handleRedis)
+	memcachedServer := newFakeCacheServer(t, handleMemcached)
+	db := infinity.Open()
+	defer db.Close()
+	redis, err := infinity.NewRedisCacheBackend("redis://"+redisServer.listener.Addr().String()+"/0", "", "org1/1/ds1")
+	require.Nil(t, err)
+	memcached, err := infinity.NewMemcachedCacheBackend(memcachedServer.listener.Addr().String(), "org1/1/ds1")
+	require.Nil(t, err)
+	backends := map[string]infinity.CacheBackend{
+		"badger":    infinity.NewSettCacheBackend(db.Table("peers")),
+		"redis":     redis,
+		"memcached": memcached,
+	}
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, err := backend.Get(ctx, "foo")
+			require.ErrorIs(t, err, infinity.ErrCacheMiss)
+			require.Nil(t, backend.Set(ctx, "foo", []byte("bar\r\nbaz"), time.Minute))
+			require.Nil(t, backend.Set(ctx, "key with spaces", []byte("qux"), time.Minute))
+			value, err := backend.Get(ctx, "foo")
+			require.Nil(t, err)
+			require.Equal(t, []byte("bar\r\nbaz"), value)
+			value, err = backend.Get(ctx, "key with spaces")
+			require.Nil(t, err)
+			require.Equal(t, []byte("qux"), value)
+			require.Nil(t, backend.Delete(ctx, "foo"))
+			_, err = backend.Get(ctx, "foo")
+			require.ErrorIs(t, err, infinity.ErrCacheMiss)
+			count, err := backend.Purge(ctx)
+			if name == "memcached" {
+				require.ErrorIs(t, err, infinity.ErrPurgeNotSupported)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, 1, count)
+			_, err = backend.Get(ctx, "key with spaces")
+			require.ErrorIs(t, err, infinity.ErrCacheMiss)
+		})
+	}
+}
+
+func TestCacheLockers(t *testing.T) {
+	redisServer := newFakeCacheServer(t, handleRedis)
+	db := infinity.Open()
+	defer db.Close()
+	redis, err := infinity.NewRedisCacheBackend(redisServer.listener.Addr().String(), "", "org1/1/ds1")
+	require.Nil(t, err)
+	lockers := map[string]infinity.CacheLocker{
+		"badger": infinity.NewSettCacheBackend(db.Table("leases")),
+		"redis":  redis,
+	}
+	for name, locker := range lockers {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			acquired, err := locker.TryLock(ctx, "job", "owner1", time.Minute)
+			require.Nil(t, err)
+			require.True(t, acquired)
+			acquired, err = locker.TryLock(ctx, "job", "owner2", time.Minute)
+			require.Nil(t, err)
+			require.False(t, acquired)
+			require.Nil(t, locker.Unlock(ctx, "job", "owner2"))
+			acquired, err = locker.TryLock(ctx, "job", "owner2", time.Minute)
+			require.Nil(t, err)
+			require.False(t, acquired)
+			require.Nil(t, locker.Unlock(ctx, "job", "owner1"))
+			acquired, err = locker.TryLock(ctx, "job", "owner2", time.Minute)
+			require.Nil(t, err)
+			require.True(t, acquired)
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package infinity
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	memcachedMaxKeyLength = 250
+	// memcachedMaxRelativeExpiry is the longest expiry memcached accepts as relative seconds.
+	// Longer expiries are to be sent as unix timestamps
+	memcachedMaxRelativeExpiry = 30 * 24 * time.Hour
+)
+
+// MemcachedCacheBackend stores the cached responses in memcached, using the text protocol.
+// Memcached can't enumerate the keys, so the backend doesn't support purge
+type MemcachedCacheBackend struct {
+	addr   string
+	prefix string
+	pool   chan *memcachedConn
+}
+
+type memcachedConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+}
+
+func NewMemcachedCacheBackend(address string, namespace string) (*MemcachedCacheBackend, error) {
+	address = strings.TrimPrefix(address, "memcached://")
+	if strings.TrimSpace(address) == "" {
+		return nil, errors.New("invalid or empty memcached address")
+	}
+	return &MemcachedCacheBackend{addr: address, prefix: "infinity:" + namespace + ":", pool: make(chan *memcachedConn, cacheBackendPoolSize)}, nil
+}
+
+// key returns the memcached key. Keys with spaces, control characters or exceeding the length limit are hashed
+func (b *MemcachedCacheBackend) key(key string) string {
+	k := b.prefix + key
+	if len(k) > memcachedMaxKeyLength || strings.IndexFunc(k, func(r rune) bool { return r <= ' ' || r == 0x7f }) >= 0 {
+		hash := sha256.Sum256([]byte(k))
+		return "infinity:" + hex.EncodeToString(hash[:])
+	}
+	return k
+}
+
+func (b *MemcachedCacheBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	err := b.do(ctx, func(c *memcachedConn) error {
+		if _, err := fmt.Fprintf(c.rw, "get %s\r\n", b.key(key)); err != nil {
+			return err
+		}
+		if err := c.rw.Flush(); err != nil {
+			return err
+		}
+		line, err := c.readLine()
+		if err != nil {
+			return err
+		}
+		if line == "END" {
+			return ErrCacheMiss
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[0] != "VALUE" {
+			return fmt.Errorf("unexpected memcached reply %q", line)
+		}
+		size, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(c.rw, buf); err != nil {
+			return err
+		}
+		value = buf[:size]
+		if line, err = c.readLine(); err != nil || line != "END" {
+			return fmt.Errorf("unexpected memcached reply %q", line)
+		}
+		return nil
+	})
+	return value, err
+}
+
+func (b *MemcachedCacheBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	expiry := int64(0)
+	if ttl > 0 {
+		expiry = int64(ttl.Seconds())
+		if expiry == 0 {
+			expiry = 1
+		}
+		if ttl > memcachedMaxRelativeExpiry {
+			expiry = time.Now().Add(ttl).Unix()
+		}
+	}
+	return b.do(ctx, func(c *memcachedConn) error {
+		if _, err := fmt.Fprintf(c.rw, "set %s 0 %d %d\r\n", b.key(key), expiry, len(value)); err != nil {
+			return err
+		}
+		if _, err := c.rw.Write(value); err != nil {
+			return err
+		}
+		if _, err := c.rw.WriteString("\r\n"); err != nil {
+			return err
+		}
+		if err := c.rw.Flush(); err != nil {
+			return err
+		}
+		line, err := c.readLine()
+		if err != nil {
+			return err
+		}
+		if line != "STORED" {
+			return fmt.Errorf("unexpected memcached reply %q", line)
+		}
+		return nil
+	})
+}
+
+func (b *MemcachedCacheBackend) Delete(ctx context.Context, key string) error {
+	return b.do(ctx, func(c *memcachedConn) error {
+		if _, err := fmt.Fprintf(c.rw, "delete %s\r\n", b.key(key)); err != nil {
+			return err
+		}
+		if err := c.rw.Flush(); err != nil {
+			return err
+		}
+		line, err := c.readLine()
+		if err != nil {
+			return err
+		}
+		if line != "DELETED" && line != "NOT_FOUND" {
+			return fmt.Errorf("unexpected memcached reply %q", line)
+		}
+		return nil
+	})
+}
+
+func (b *MemcachedCacheBackend) Purge(ctx context.Context) (int, error) {
+	return 0, ErrPurgeNotSupported
+}
+
+// Close closes the idle connections of the pool
+func (b *MemcachedCacheBackend) Close() error {
+	for {
+		select {
+		case c := <-b.pool:
+			c.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// do runs the fn on a pooled connection. Connections are returned to the pool unless the protocol state is unknown
+func (b *MemcachedCacheBackend) do(ctx context.Context, fn func(c *memcachedConn) error) error {
+	var c *memcachedConn
+	select {
+	case c = <-b.pool:
+	default:
+		dialer := net.Dialer{Timeout: cacheBackendDialTimeout}
+		conn, err := dialer.DialContext(ctx, "tcp", b.addr)
+		if err != nil {
+			return fmt.Errorf("error connecting to memcached. %w", err)
+		}
+		c = &memcachedConn{conn: conn, rw: bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))}
+	}
+	deadline := time.Now().Add(cacheBackendIOTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		c.conn.Close()
+		return err
+	}
+	err := fn(c)
+	if err != nil && !errors.Is(err, ErrCacheMiss) {
+		c.conn.Close()
+		return err
+	}
+	select {
+	case b.pool <- c:
+	default:
+		c.conn.Close()
+	}
+	return err
+}
+
+func (c *memcachedConn) readLine() (string, error) {
+	line, err := c.rw.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if strings.HasPrefix(line, "ERROR") || strings.HasPrefix(line, "CLIENT_ERROR") || strings.HasPrefix(line, "SERVER_ERROR") {
+		return "", fmt.Errorf("memcached: %s", line)
+	}
+	return line, nil
+}
@@ -0,0 +1,184 @@
+package infinity
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// maxMemoryCacheEntries caps the in-memory cache of a datasource instance
+const maxMemoryCacheEntries = 10000
+
+var (
+	cacheHitsMetric = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "infinity",
+		Subsystem: "cache",
+		Name:      "hits_total",
+		Help:      "Number of the cached responses found by the level of the cache. l1 is the in-memory cache and l2 is the cache backend",
+	}, []string{"level"})
+	cacheMissesMetric = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "infinity",
+		Subsystem: "cache",
+		Name:      "misses_total",
+		Help:      "Number of the cached responses found in neither the in-memory cache nor the cache backend",
+	})
+)
+
+type memoryCacheEntry struct {
+	value     []byte
+	expiresAt time.Time
+	// lastAccess is the tick of the last read. Entries with the smallest ticks are evicted first
+	lastAccess atomic.Int64
+}
+
+// memoryCache is the in-memory LRU of the cached responses of a datasource instance. Reads never lock, so the repeated reads of the
+// same key within a refresh cycle skip the transactions of the cache backend. Entries over the limit are evicted in batches by a
+// single writer at a time
+type memoryCache struct {
+	maxEntries int
+	entries    sync.Map
+	size       atomic.Int64
+	tick       atomic.Int64
+	evicting   atomic.Bool
+}
+
+func newMemoryCache(maxEntries int) *memoryCache {
+	if maxEntries <= 0 {
+		return nil
+	}
+	return &memoryCache{maxEntries: min(maxEntries, maxMemoryCacheEntries)}
+}
+
+func (c *memoryCache) get(key string) ([]byte, bool) {
+	v, ok := c.entries.Load(key)
+	if !ok {
+		return nil, false
+	}
+	entry := v.(*memoryCacheEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		c.delete(key)
+		return nil, false
+	}
+	entry.lastAccess.Store(c.tick.Add(1))
+	return entry.value, true
+}
+
+func (c *memoryCache) set(key string, value []byte, ttl time.Duration) {
+	entry := &memoryCacheEntry{value: value}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+	entry.lastAccess.Store(c.tick.Add(1))
+	if _, loaded := c.entries.Swap(key, entry); !loaded {
+		c.size.Add(1)
+	}
+	if c.size.Load() > int64(c.maxEntries) {
+		c.evict()
+	}
+}
+
+func (c *memoryCache) delete(key string) {
+	if _, loaded := c.entries.LoadAndDelete(key); loaded {
+		c.size.Add(-1)
+	}
+}
+
+func (c *memoryCache) clear() {
+	c.entries.Range(func(key, _ any) bool {
+		c.delete(key.(string))
+		return true
+	})
+}
+
+// evict removes the expired entries and the least recently used entries, so the cache shrinks below 90% of the limit.
+// Concurrent writers skip the eviction while another writer evicts
+func (c *memoryCache) evict() {
+	if !c.evicting.CompareAndSwap(false, true) {
+		return
+	}
+	defer c.evicting.Store(false)
+	type candidate struct {
+		key        string
+		lastAccess int64
+	}
+	now := time.Now()
+	candidates := []candidate{}
+	c.entries.Range(func(key, v any) bool {
+		entry := v.(*memoryCacheEntry)
+		if !entry.expiresAt.IsZero() && now.After(entry.expiresAt) {
+			c.delete(key.(string))
+			return true
+		}
+		candidates = append(candidates, candidate{key: key.(string), lastAccess: entry.lastAccess.Load()})
+		return true
+	})
+	excess := len(candidates) - c.maxEntries*9/10
+	if excess <= 0 {
+		return
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].lastAccess < candidates[j].lastAccess })
+	for _, candidate := range candidates[:excess] {
+		c.delete(candidate.key)
+	}
+}
+
+// cacheTTLReader is implemented by the cache backends which can report the remaining ttl of the key
+type cacheTTLReader interface {
+	TTL(ctx context.Context, key string) (time.Duration, error)
+}
+
+// MemoryCacheBackend serves the cached responses from the in-memory cache of the datasource instance before the cache backend.
+// Responses found in the cache backend are kept in memory until the ttl of the cache backend entry elapses
+type MemoryCacheBackend struct {
+	cache *memoryCache
+	next  CacheBackend
+}
+
+func (b *MemoryCacheBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	if value, ok := b.cache.get(key); ok {
+		cacheHitsMetric.WithLabelValues("l1").Inc()
+		return value, nil
+	}
+	value, err := b.next.Get(ctx, key)
+	if errors.Is(err, ErrCacheMiss) {
+		cacheMissesMetric.Inc()
+	}
+	if err != nil {
+		return nil, err
+	}
+	cacheHitsMetric.WithLabelValues("l2").Inc()
+	ttl := time.Duration(0)
+	if reader, ok := b.next.(cacheTTLReader); ok {
+		ttl, _ = reader.TTL(ctx, key)
+	}
+	if ttl > 0 {
+		// entries without the known expiry are read from the cache backend every time
+		b.cache.set(key, value, ttl)
+	}
+	return value, nil
+}
+
+func (b *MemoryCacheBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := b.next.Set(ctx, key, value, ttl); err != nil {
+		b.cache.delete(key)
+		return err
+	}
+	b.cache.set(key, value, ttl)
+	return nil
+}
+
+func (b *MemoryCacheBackend) Delete(ctx context.Context, key string) error {
+	b.cache.delete(key)
+	return b.next.Delete(ctx, key)
+}
+
+func (b *MemoryCacheBackend) Purge(ctx context.Context) (int, error) {
+	b.cache.clear()
+	return b.next.Purge(ctx)
+}
@@ -0,0 +1,92 @@
+package infinity_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestMemoryCacheBackend(t *testing.T) {
+	ctx := context.Background()
+	client, err := infinity.NewClient(ctx, models.InfinitySettings{UID: "memory-cache-test", CacheMemoryEntries: 2})
+	require.Nil(t, err)
+	l2 := infinity.NewSettCacheBackend(client.Cache().Table("peers"))
+	t.Run("should serve the repeated reads from memory", func(t *testing.T) {
+		cache := client.ResponseCache()
+		require.Nil(t, cache.Set(ctx, "foo", []byte("bar"), time.Minute))
+		// removed only from the cache backend
+		require.Nil(t, l2.Delete(ctx, "foo"))
+		value, err := cache.Get(ctx, "foo")
+		require.Nil(t, err)
+		require.Equal(t, "bar", string(value))
+		require.Nil(t, cache.Delete(ctx, "foo"))
+		_, err = cache.Get(ctx, "foo")
+		require.ErrorIs(t, err, infinity.ErrCacheMiss)
+	})
+	t.Run("should keep the responses read from the cache backend", func(t *testing.T) {
+		require.Nil(t, l2.Set(ctx, "baz", []byte("qux"), time.Minute))
+		value, err := client.ResponseCache().Get(ctx, "baz")
+		require.Nil(t, err)
+		require.Equal(t, "qux", string(value))
+		require.Nil(t, l2.Delete(ctx, "baz"))
+		value, err = client.ResponseCache().Get(ctx, "baz")
+		require.Nil(t, err)
+		require.Equal(t, "qux", string(value))
+	})
+	t.Run("should evict the least recently used responses", func(t *testing.T) {
+		cache := client.ResponseCache()
+		_, err := client.PurgeCache(ctx)
+		require.Nil(t, err)
+		require.Nil(t, cache.Set(ctx, "a", []byte("1"), time.Minute))
+		require.Nil(t, cache.Set(ctx, "b", []byte("2"), time.Minute))
+		_, err = cache.Get(ctx, "a")
+		require.Nil(t, err)
+		require.Nil(t, cache.Set(ctx, "c", []byte("3"), time.Minute))
+		for _, key := range []string{"a", "b", "c"} {
+			require.Nil(t, l2.Delete(ctx, key))
+		}
+		_, err = cache.Get(ctx, "a")
+		require.ErrorIs(t, err, infinity.ErrCacheMiss)
+		_, err = cache.Get(ctx, "b")
+		require.ErrorIs(t, err, infinity.ErrCacheMiss)
+		value, err := cache.Get(ctx, "c")
+		require.Nil(t, err)
+		require.Equal(t, "3", string(value))
+	})
+	t.Run("should expire the responses with the ttl", func(t *testing.T) {
+		cache := client.ResponseCache()
+		require.Nil(t, cache.Set(ctx, "short", []byte("lived"), time.Millisecond))
+		time.Sleep(5 * time.Millisecond)
+		_, err := cache.Get(ctx, "short")
+		require.ErrorIs(t, err, infinity.ErrCacheMiss)
+	})
+	t.Run("should clear the memory on purge", func(t *testing.T) {
+		cache := client.ResponseCache()
+		require.Nil(t, cache.Set(ctx, "foo", []byte("bar"), time.Minute))
+		_, err := client.PurgeCache(ctx)
+		require.Nil(t, err)
+		_, err = cache.Get(ctx, "foo")
+		require.ErrorIs(t, err, infinity.ErrCacheMiss)
+	})
+	t.Run("should be safe for concurrent use", func(t *testing.T) {
+		cache := client.ResponseCache()
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				key := string(rune('a' + i))
+				for j := 0; j < 50; j++ {
+					_ = cache.Set(ctx, key, []byte("v"), time.Minute)
+					_, _ = cache.Get(ctx, key)
+				}
+			}(i)
+		}
+		wg.Wait()
+	})
+}
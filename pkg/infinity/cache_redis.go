@@ -0,0 +1,259 @@
+package infinity
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	cacheBackendPoolSize    = 8
+	cacheBackendDialTimeout = 5 * time.Second
+	cacheBackendIOTimeout   = 5 * time.Second
+)
+
+// RedisCacheBackend stores the cached responses in redis, using the RESP protocol
+type RedisCacheBackend struct {
+	addr     string
+	password string
+	db       int
+	prefix   string
+	pool     chan *redisConn
+}
+
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// NewRedisCacheBackend creates the redis backend from the address or the URL in the form of redis://[:password@]host:port[/db].
+// Password given separately overrides the password of the URL
+func NewRedisCacheBackend(address string, password string, namespace string) (*RedisCacheBackend, error) {
+	b := &RedisCacheBackend{addr: address, password: password, prefix: "infinity:" + namespace + ":", pool: make(chan *redisConn, cacheBackendPoolSize)}
+	if strings.HasPrefix(address, "redis://") {
+		u, err := url.Parse(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis url. %w", err)
+		}
+		b.addr = u.Host
+		if p, ok := u.User.Password(); ok && password == "" {
+			b.password = p
+		}
+		if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+			if b.db, err = strconv.Atoi(db); err != nil {
+				return nil, fmt.Errorf("invalid redis db %s", db)
+			}
+		}
+	}
+	if strings.TrimSpace(b.addr) == "" {
+		return nil, errors.New("invalid or empty redis address")
+	}
+	return b, nil
+}
+
+func (b *RedisCacheBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	reply, err := b.do(ctx, "GET", b.prefix+key)
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, ErrCacheMiss
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected redis reply %v", reply)
+	}
+	return value, nil
+}
+
+func (b *RedisCacheBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", b.prefix + key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	_, err := b.do(ctx, args...)
+	return err
+}
+
+func (b *RedisCacheBackend) Delete(ctx context.Context, key string) error {
+	_, err := b.do(ctx, "DEL", b.prefix+key)
+	return err
+}
+
+func (b *RedisCacheBackend) Purge(ctx context.Context) (int, error) {
+	count := 0
+	cursor := "0"
+	for {
+		reply, err := b.do(ctx, "SCAN", cursor, "MATCH", b.prefix+"*", "COUNT", "100")
+		if err != nil {
+			return count, err
+		}
+		items, ok := reply.([]any)
+		if !ok || len(items) != 2 {
+			return count, fmt.Errorf("unexpected redis reply %v", reply)
+		}
+		next, _ := items[0].([]byte)
+		keys, _ := items[1].([]any)
+		if len(keys) > 0 {
+			args := []string{"DEL"}
+			for _, k := range keys {
+				if key, ok := k.([]byte); ok {
+					args = append(args, string(key))
+				}
+			}
+			deleted, err := b.do(ctx, args...)
+			if err != nil {
+				return count, err
+			}
+			if n, ok := deleted.(int64); ok {
+				count += int(n)
+			}
+		}
+		cursor = string(next)
+		if cursor == "0" || cursor == "" {
+			return count, nil
+		}
+	}
+}
+
+// do sends the command and returns the reply. Connections are returned to the pool only after successful round trips
+func (b *RedisCacheBackend) do(ctx context.Context, args ...string) (any, error) {
+	c, err := b.getConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := c.roundTrip(ctx, args...)
+	var redisErr redisError
+	if err != nil && !errors.As(err, &redisErr) {
+		c.conn.Close()
+		return nil, err
+	}
+	b.putConn(c)
+	return reply, err
+}
+
+func (b *RedisCacheBackend) getConn(ctx context.Context) (*redisConn, error) {
+	select {
+	case c := <-b.pool:
+		return c, nil
+	default:
+	}
+	dialer := net.Dialer{Timeout: cacheBackendDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", b.addr)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to redis. %w", err)
+	}
+	c := &redisConn{conn: conn, r: bufio.NewReader(conn)}
+	if b.password != "" {
+		if _, err := c.roundTrip(ctx, "AUTH", b.password); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("error authenticating with redis. %w", err)
+		}
+	}
+	if b.db > 0 {
+		if _, err := c.roundTrip(ctx, "SELECT", strconv.Itoa(b.db)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("error selecting redis db. %w", err)
+		}
+	}
+	return c, nil
+}
+
+// Close closes the idle connections of the pool
+func (b *RedisCacheBackend) Close() error {
+	for {
+		select {
+		case c := <-b.pool:
+			c.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (b *RedisCacheBackend) putConn(c *redisConn) {
+	select {
+	case b.pool <- c:
+	default:
+		c.conn.Close()
+	}
+}
+
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+func (c *redisConn) roundTrip(ctx context.Context, args ...string) (any, error) {
+	deadline := time.Now().Add(cacheBackendIOTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	var sb strings.Builder
+	sb.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		sb.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+	if _, err := io.WriteString(c.conn, sb.String()); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+func (c *redisConn) readReply() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty redis reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:size], nil
+	case '*':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		items := make([]any, size)
+		for i := range items {
+			if items[i], err = c.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected redis reply %q", line)
+	}
+}
@@ -0,0 +1,144 @@
+package infinity
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+// GetFrameForCertificate completes the tls handshake with the targets and returns a row per certificate with the expiry details.
+// Expired and untrusted certificates are returned as well, with the verified and verify_error columns. Unreachable targets are returned as the rows with the error column.
+// When the allowed hosts are configured, the target must match one of them as tls://host:port
+func GetFrameForCertificate(ctx context.Context, query models.Query, infClient Client) (*data.Frame, error) {
+	ctx, span := tracing.DefaultTracer().Start(ctx, "GetFrameForCertificate")
+	defer span.End()
+	if query.CertificateOptions == nil || len(query.CertificateOptions.Targets) == 0 {
+		return nil, UserError(errors.New("at least one target is required for the certificate query"))
+	}
+	options := *query.CertificateOptions
+	if len(options.Targets) > maxProbeTargets {
+		return nil, UserError(fmt.Errorf("too many certificate targets. targets should be up to %d", maxProbeTargets))
+	}
+	targets := make([]string, len(options.Targets))
+	for i, target := range options.Targets {
+		target = strings.TrimSpace(target)
+		if _, _, err := net.SplitHostPort(target); err != nil {
+			target = net.JoinHostPort(strings.Trim(target, "[]"), "443")
+		}
+		if !CanAllowURL("tls://"+target, infClient.Settings.AllowedHosts) {
+			return nil, UserError(fmt.Errorf("certificate target %s is not in the allowed hosts", target))
+		}
+		targets[i] = target
+	}
+	tlsConfig, err := GetTLSConfigFromSettings(infClient.Settings)
+	if err != nil {
+		return nil, err
+	}
+	timeout := time.Duration(infClient.Settings.TimeoutInSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = defaultProbeTimeout
+	}
+	results := make([][]any, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target string) {
+			defer wg.Done()
+			results[i] = getCertificateRows(ctx, target, options.Chain, timeout, tlsConfig)
+		}(i, target)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, ContextError(ctx)
+	}
+	rows := []any{}
+	for _, r := range results {
+		rows = append(rows, r...)
+	}
+	rowsQuery := query
+	rowsQuery.RootSelector = ""
+	frame, err := GetJSONBackendResponse(ctx, rows, rowsQuery)
+	if err != nil {
+		return frame, err
+	}
+	return PostProcessFrame(ctx, frame, rowsQuery)
+}
+
+func getCertificateRows(ctx context.Context, target string, chain bool, timeout time.Duration, tlsConfig *tls.Config) []any {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	host, _, _ := net.SplitHostPort(target)
+	config := tlsConfig.Clone()
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+	verifyName := config.ServerName
+	// the certificates are verified after the handshake, so the expired and the untrusted certificates can be inspected
+	config.InsecureSkipVerify = true
+	conn, err := (&tls.Dialer{Config: config}).DialContext(ctx, "tcp", target)
+	if err != nil {
+		return []any{map[string]any{"target": target, "error": err.Error()}}
+	}
+	defer conn.Close()
+	state := conn.(*tls.Conn).ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return []any{map[string]any{"target": target, "error": "no certificates presented by the server"}}
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	verified, verifyError := true, ""
+	if _, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{DNSName: verifyName, Roots: tlsConfig.RootCAs, Intermediates: intermediates}); err != nil {
+		verified, verifyError = false, err.Error()
+	}
+	certificates := state.PeerCertificates[:1]
+	if chain {
+		certificates = state.PeerCertificates
+	}
+	now := time.Now()
+	rows := make([]any, 0, len(certificates))
+	for position, cert := range certificates {
+		sans := append([]string{}, cert.DNSNames...)
+		for _, ip := range cert.IPAddresses {
+			sans = append(sans, ip.String())
+		}
+		row := map[string]any{
+			"target":              target,
+			"position":            float64(position),
+			"subject":             getCertificateName(cert.Subject.CommonName, cert.Subject.String()),
+			"issuer":              getCertificateName(cert.Issuer.CommonName, cert.Issuer.String()),
+			"sans":                strings.Join(sans, ", "),
+			"serial":              fmt.Sprintf("%X", cert.SerialNumber),
+			"signature_algorithm": cert.SignatureAlgorithm.String(),
+			"not_before":          cert.NotBefore.UTC().Format(time.RFC3339),
+			"not_after":           cert.NotAfter.UTC().Format(time.RFC3339),
+			"days_remaining":      math.Floor(cert.NotAfter.Sub(now).Hours() / 24),
+			"tls_version":         tls.VersionName(state.Version),
+			"verified":            verified,
+		}
+		if verifyError != "" {
+			row["verify_error"] = verifyError
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func getCertificateName(commonName string, name string) string {
+	if commonName != "" {
+		return commonName
+	}
+	return name
+}
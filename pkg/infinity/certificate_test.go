@@ -0,0 +1,63 @@
+package infinity_test
+
+import (
+	"context"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestGetFrameForCertificate(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	target := strings.TrimPrefix(server.URL, "https://")
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	// closed port. connections are refused
+	closed := listener.Addr().String()
+	listener.Close()
+	value := func(frame *data.Frame, name string, row int) any {
+		field, _ := frame.FieldByName(name)
+		require.NotNil(t, field, name)
+		v, _ := field.ConcreteAt(row)
+		return v
+	}
+	t.Run("untrusted certificate", func(t *testing.T) {
+		client := infinity.Client{Settings: models.InfinitySettings{TimeoutInSeconds: 2}}
+		query := models.Query{RefID: "A", Source: "certificate", CertificateOptions: &models.CertificateOptions{Targets: []string{target, closed}}}
+		frame, err := infinity.GetFrameForCertificate(context.Background(), query, client)
+		require.Nil(t, err)
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, "O=Acme Co", value(frame, "issuer", 0))
+		require.Equal(t, "example.com, *.example.com, 127.0.0.1, ::1", value(frame, "sans", 0))
+		require.Equal(t, "2084-01-29T16:00:00Z", value(frame, "not_after", 0))
+		require.Greater(t, value(frame, "days_remaining", 0), 365.0)
+		require.Equal(t, false, value(frame, "verified", 0))
+		require.Contains(t, value(frame, "verify_error", 0), "certificate signed by unknown authority")
+		require.Contains(t, value(frame, "error", 1), "connection refused")
+	})
+	t.Run("trusted by the ca certificate of the settings", func(t *testing.T) {
+		ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+		client := infinity.Client{Settings: models.InfinitySettings{TimeoutInSeconds: 2, TLSAuthWithCACert: true, TLSCACert: string(ca)}}
+		query := models.Query{RefID: "A", Source: "certificate", CertificateOptions: &models.CertificateOptions{Targets: []string{target}, Chain: true}}
+		frame, err := infinity.GetFrameForCertificate(context.Background(), query, client)
+		require.Nil(t, err)
+		require.Equal(t, 1, frame.Rows())
+		require.Equal(t, true, value(frame, "verified", 0))
+		require.Equal(t, 0.0, value(frame, "position", 0))
+	})
+	t.Run("target outside the allowed hosts", func(t *testing.T) {
+		client := infinity.Client{Settings: models.InfinitySettings{AllowedHosts: []string{"tls://example.com:443"}}}
+		query := models.Query{RefID: "A", Source: "certificate", CertificateOptions: &models.CertificateOptions{Targets: []string{"example.org"}}}
+		_, err := infinity.GetFrameForCertificate(context.Background(), query, client)
+		require.ErrorContains(t, err, "certificate target example.org:443 is not in the allowed hosts")
+	})
+}
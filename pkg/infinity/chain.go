@@ -0,0 +1,135 @@
+package infinity
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+	"golang.org/x/net/html"
+)
+
+const maxChainSteps = 5
+
+// GetFrameForChain sends the chain steps of the query in order and then the query itself. Values extracted from the response of each
+// step replace the {{name}} placeholders of the next steps and of the query. Steps are sent by the datasource client, so they use the
+// authentication and the allowed hosts of the datasource. Extracted values never leave the backend: the frame reports the query as configured
+func GetFrameForChain(ctx context.Context, query models.Query, infClient Client, requestHeaders map[string]string) (*data.Frame, error) {
+	ctx, span := tracing.DefaultTracer().Start(ctx, "GetFrameForChain")
+	defer span.End()
+	if len(query.Chain) > maxChainSteps {
+		return nil, UserError(fmt.Errorf("too many chain steps. chain should have up to %d steps", maxChainSteps))
+	}
+	variables := map[string]string{}
+	for i, step := range query.Chain {
+		name := step.Name
+		if name == "" {
+			name = fmt.Sprintf("step %d", i+1)
+		}
+		if step.SkipWithSession && hasCookieSession(ctx) {
+			continue
+		}
+		stepType := models.QueryTypeJSON
+		for _, variable := range step.Extract {
+			if variable.From == models.ChainVariableSourceMeta {
+				// html pages are read as text
+				stepType = models.QueryTypeHTML
+			}
+		}
+		stepQuery := applyChainVariables(models.Query{RefID: query.RefID, Source: "url", Type: stepType, URL: step.URL, URLOptions: step.URLOptions}, variables)
+		stepCtx, responseHeader := withResponseHeader(ctx)
+		// steps such as the login requests are never served from the cache
+		res, _, _, err := infClient.GetResults(withoutResponseCache(stepCtx), stepQuery, requestHeaders)
+		if err != nil {
+			return nil, fmt.Errorf("error in the chain step %s. %w", name, err)
+		}
+		for _, variable := range step.Extract {
+			if strings.TrimSpace(variable.Name) == "" {
+				return nil, UserError(fmt.Errorf("invalid or empty variable name in the chain step %s", name))
+			}
+			value, err := getChainVariable(res, *responseHeader, variable)
+			if err != nil {
+				return nil, UserError(fmt.Errorf("error extracting the variable %s from the response of the chain step %s. %w", variable.Name, name, err))
+			}
+			variables[strings.TrimSpace(variable.Name)] = value
+		}
+	}
+	chainedQuery := applyChainVariables(query, variables)
+	chainedQuery.Chain = nil
+	frame, err := GetFrameForURLSources(ctx, chainedQuery, infClient, requestHeaders)
+	if frame != nil && frame.Meta != nil {
+		frame.Meta.ExecutedQueryString = infClient.GetExecutedURL(ctx, query)
+		if customMeta, ok := frame.Meta.Custom.(*CustomMeta); ok && customMeta != nil {
+			customMeta.Query = query
+		}
+	}
+	return frame, err
+}
+
+func applyChainVariables(query models.Query, variables map[string]string) models.Query {
+	for name, value := range variables {
+		query = ReplacePlaceholderInQuery(query, "{{"+name+"}}", value)
+	}
+	return query
+}
+
+// getChainVariable returns the value of the variable from the body, the headers, the cookies or the html meta tags of the response
+func getChainVariable(body any, header http.Header, variable models.ChainVariable) (string, error) {
+	switch variable.From {
+	case "", models.ChainVariableSourceBody:
+		return getResponseValue(body, variable.Path)
+	case models.ChainVariableSourceHeader:
+		if value := header.Get(variable.Path); value != "" {
+			return value, nil
+		}
+		return "", fmt.Errorf("header %s not found in the response", variable.Path)
+	case models.ChainVariableSourceCookie:
+		for _, cookie := range (&http.Response{Header: header}).Cookies() {
+			if cookie.Name == variable.Path {
+				return cookie.Value, nil
+			}
+		}
+		return "", fmt.Errorf("cookie %s not found in the response", variable.Path)
+	case models.ChainVariableSourceMeta:
+		text, _ := body.(string)
+		if value, ok := getMetaContent([]byte(text), variable.Path); ok {
+			return value, nil
+		}
+		return "", fmt.Errorf("meta tag %s not found in the response", variable.Path)
+	}
+	return "", fmt.Errorf("invalid variable source %q. source should be body, header, cookie or meta", variable.From)
+}
+
+// getMetaContent returns the content of the meta tag with the name, such as <meta name="csrf-token" content="...">
+func getMetaContent(body []byte, name string) (string, bool) {
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		tt := tokenizer.Next()
+		if tt == html.ErrorToken {
+			return "", false
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		token := tokenizer.Token()
+		if token.Data != "meta" {
+			continue
+		}
+		var metaName, content string
+		for _, attr := range token.Attr {
+			switch attr.Key {
+			case "name":
+				metaName = attr.Val
+			case "content":
+				content = attr.Val
+			}
+		}
+		if strings.EqualFold(metaName, name) {
+			return content, true
+		}
+	}
+}
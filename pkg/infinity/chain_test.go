@@ -0,0 +1,133 @@
+package infinity_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestGetFrameForChain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/login":
+			body, _ := io.ReadAll(r.Body)
+			if r.Method != http.MethodPost || string(body) != `{ "user": "grafana" }` {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{ "session": { "token": "s3cr3t", "tenant": "acme" } }`)
+		case "/tenants/acme/users":
+			if r.Header.Get("Authorization") != "Bearer s3cr3t" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `[{ "name": "foo" }, { "name": "bar" }]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := infinity.Client{Settings: models.InfinitySettings{URL: server.URL}, HttpClient: server.Client()}
+	login := models.ChainStep{
+		Name:       "login",
+		URL:        "/login",
+		URLOptions: models.URLOptions{Method: "POST", BodyType: "raw", Body: `{ "user": "grafana" }`},
+		Extract:    []models.ChainVariable{{Name: "token", Path: "session.token"}, {Name: "tenant", Path: "session.tenant"}},
+	}
+	query := models.Query{
+		RefID:      "A",
+		Source:     "url",
+		Type:       models.QueryTypeJSON,
+		Parser:     models.InfinityParserBackend,
+		URL:        "/tenants/{{tenant}}/users",
+		URLOptions: models.URLOptions{Method: "GET", Headers: []models.URLOptionKeyValuePair{{Key: "Authorization", Value: "Bearer {{token}}"}}},
+		Chain:      []models.ChainStep{login},
+	}
+	t.Run("should pass the extracted values to the query", func(t *testing.T) {
+		frame, err := infinity.GetFrameForURLSources(context.Background(), query, client, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, 2, frame.Rows())
+		// extracted values are not reported back
+		require.NotContains(t, frame.Meta.ExecutedQueryString, "s3cr3t")
+		customMeta, ok := frame.Meta.Custom.(*infinity.CustomMeta)
+		require.True(t, ok)
+		require.Equal(t, "Bearer {{token}}", customMeta.Query.URLOptions.Headers[0].Value)
+		require.Equal(t, "Bearer {{token}}", query.URLOptions.Headers[0].Value)
+	})
+	t.Run("should fail when the step fails", func(t *testing.T) {
+		failedLogin := login
+		failedLogin.URLOptions.Body = `{ "user": "unknown" }`
+		q := query
+		q.Chain = []models.ChainStep{failedLogin}
+		_, err := infinity.GetFrameForURLSources(context.Background(), q, client, map[string]string{})
+		require.ErrorContains(t, err, "error in the chain step login")
+		require.Equal(t, infinity.ErrorKindDownstream, infinity.GetErrorKind(err))
+	})
+	t.Run("should fail when the value is not found in the response", func(t *testing.T) {
+		missing := login
+		missing.Extract = []models.ChainVariable{{Name: "token", Path: "session.access_token"}}
+		q := query
+		q.Chain = []models.ChainStep{missing}
+		_, err := infinity.GetFrameForURLSources(context.Background(), q, client, map[string]string{})
+		require.ErrorContains(t, err, "error extracting the variable token from the response of the chain step login")
+		require.Equal(t, infinity.ErrorKindUser, infinity.GetErrorKind(err))
+	})
+	t.Run("should extract the csrf tokens", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/form":
+				w.Header().Set("X-CSRF-Token", "from-header")
+				http.SetCookie(w, &http.Cookie{Name: "csrftoken", Value: "from-cookie"})
+				w.Header().Set("Content-Type", "text/html")
+				fmt.Fprint(w, `<html><head><meta name="csrf-token" content="from-meta" /></head><body></body></html>`)
+			case "/submit":
+				body, _ := io.ReadAll(r.Body)
+				if r.Header.Get("X-CSRF-Token") != "from-header" || r.Header.Get("Cookie") != "csrftoken=from-cookie" || string(body) != "token=from-meta" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, `[{ "ok": true }]`)
+			}
+		}))
+		defer server.Close()
+		q := models.Query{
+			RefID:  "A",
+			Source: "url",
+			Type:   models.QueryTypeJSON,
+			Parser: models.InfinityParserBackend,
+			URL:    server.URL + "/submit",
+			URLOptions: models.URLOptions{
+				Method:   "POST",
+				BodyType: "raw",
+				Body:     "token={{meta}}",
+				Headers:  []models.URLOptionKeyValuePair{{Key: "X-CSRF-Token", Value: "{{header}}"}, {Key: "Cookie", Value: "csrftoken={{cookie}}"}},
+			},
+			Chain: []models.ChainStep{{Name: "form", URL: server.URL + "/form", URLOptions: models.URLOptions{Method: "GET"}, Extract: []models.ChainVariable{
+				{Name: "header", From: models.ChainVariableSourceHeader, Path: "x-csrf-token"},
+				{Name: "cookie", From: models.ChainVariableSourceCookie, Path: "csrftoken"},
+				{Name: "meta", From: models.ChainVariableSourceMeta, Path: "csrf-token"},
+			}}},
+		}
+		frame, err := infinity.GetFrameForURLSources(context.Background(), q, infinity.Client{Settings: models.InfinitySettings{}, HttpClient: server.Client()}, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, 1, frame.Rows())
+		q.Chain[0].Extract = []models.ChainVariable{{Name: "meta", From: models.ChainVariableSourceMeta, Path: "authenticity_token"}}
+		_, err = infinity.GetFrameForURLSources(context.Background(), q, infinity.Client{Settings: models.InfinitySettings{}, HttpClient: server.Client()}, map[string]string{})
+		require.ErrorContains(t, err, "meta tag authenticity_token not found in the response")
+	})
+	t.Run("should limit the steps", func(t *testing.T) {
+		q := query
+		q.Chain = []models.ChainStep{login, login, login, login, login, login}
+		_, err := infinity.GetFrameForURLSources(context.Background(), q, client, map[string]string{})
+		require.ErrorContains(t, err, "too many chain steps")
+	})
+}
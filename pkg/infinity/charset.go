@@ -0,0 +1,79 @@
+package infinity
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+const charsetAuto = "auto"
+
+var (
+	bomUTF8    = []byte("\xef\xbb\xbf")
+	bomUTF16LE = []byte("\xff\xfe")
+	bomUTF16BE = []byte("\xfe\xff")
+)
+
+// decodeResponseCharset decodes the charset of the text responses. Binary responses such as arrow are returned as is
+func decodeResponseCharset(body []byte, contentType string, query models.Query) ([]byte, error) {
+	if query.Type == models.QueryTypeArrow {
+		return body, nil
+	}
+	return DecodeCharset(body, contentType, query.Charset)
+}
+
+// DecodeCharset converts the response body into UTF-8 and removes the byte order mark.
+// When the charset is empty or auto, the charset is detected in the following order
+//
+//   - byte order mark (UTF-8, UTF-16 LE/BE)
+//   - charset parameter of the content type
+//   - body as is, when it is a valid UTF-8
+//   - ISO-8859-1 (windows-1252) as fallback
+//
+// Multibyte legacy charsets such as Shift_JIS can't be detected reliably and require the charset parameter or the override
+func DecodeCharset(body []byte, contentType string, charset string) ([]byte, error) {
+	charset = strings.TrimSpace(charset)
+	if charset != "" && !strings.EqualFold(charset, charsetAuto) {
+		enc, err := htmlindex.Get(charset)
+		if err != nil {
+			return body, fmt.Errorf("unsupported charset %s", charset)
+		}
+		return decodeWithEncoding(body, enc)
+	}
+	switch {
+	case bytes.HasPrefix(body, bomUTF8):
+		return bytes.TrimPrefix(body, bomUTF8), nil
+	case bytes.HasPrefix(body, bomUTF16LE):
+		return decodeWithEncoding(body, unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM))
+	case bytes.HasPrefix(body, bomUTF16BE):
+		return decodeWithEncoding(body, unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM))
+	}
+	if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+		if enc, err := htmlindex.Get(params["charset"]); err == nil {
+			return decodeWithEncoding(body, enc)
+		}
+	}
+	if utf8.Valid(body) {
+		return body, nil
+	}
+	return decodeWithEncoding(body, charmap.Windows1252)
+}
+
+func decodeWithEncoding(body []byte, enc encoding.Encoding) ([]byte, error) {
+	if enc == encoding.Nop || enc == unicode.UTF8 {
+		return bytes.TrimPrefix(body, bomUTF8), nil
+	}
+	out, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return body, fmt.Errorf("error converting the response to UTF-8. %w", err)
+	}
+	return bytes.TrimPrefix(out, bomUTF8), nil
+}
@@ -0,0 +1,71 @@
+package infinity_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestDecodeCharset(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+		charset     string
+		want        string
+		wantErr     string
+	}{
+		{name: "utf-8 body should be returned as is", body: []byte("name\nMünchen"), want: "name\nMünchen"},
+		{name: "utf-8 bom should be removed", body: []byte("\xef\xbb\xbfname"), want: "name"},
+		{name: "utf-16 le with bom should be converted", body: []byte("\xff\xfen\x00a\x00\xe9\x00"), want: "naé"},
+		{name: "utf-16 be with bom should be converted", body: []byte("\xfe\xff\x00n\x00a\x00\xe9"), want: "naé"},
+		{name: "charset of the content type should be used", body: []byte("\x93\xfa\x96\x7b"), contentType: "text/csv; charset=Shift_JIS", want: "日本"},
+		{name: "invalid utf-8 should fallback to iso-8859-1", body: []byte("M\xfcnchen"), want: "München"},
+		{name: "charset override should win over the content type", body: []byte("\x93\xfa\x96\x7b"), contentType: "text/csv; charset=utf-8", charset: "shift_jis", want: "日本"},
+		{name: "auto charset should detect the charset", body: []byte("M\xfcnchen"), charset: "auto", want: "München"},
+		{name: "unknown charset should throw error", body: []byte("foo"), charset: "foo-bar", wantErr: "unsupported charset foo-bar"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := infinity.DecodeCharset(tt.body, tt.contentType, tt.charset)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestCharsetConversion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("\xff\xfe" + "c\x00i\x00t\x00y\x00\n\x00M\x00\xfc\x00n\x00c\x00h\x00e\x00n\x00"))
+	}))
+	defer server.Close()
+	client, err := infinity.NewClient(context.Background(), models.InfinitySettings{})
+	require.Nil(t, err)
+	query := models.ApplyDefaultsToQuery(context.Background(), models.Query{RefID: "A", Type: models.QueryTypeCSV, Parser: models.InfinityParserBackend, Source: "url", URL: server.URL})
+	frame, err := infinity.GetFrameForURLSources(context.Background(), query, *client, map[string]string{})
+	require.Nil(t, err)
+	field, _ := frame.FieldByName("city")
+	require.NotNil(t, field)
+	require.Equal(t, "München", *field.At(0).(*string))
+	t.Run("response content type override should parse the response as json", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte(`[{"city":"München"}]`))
+		}))
+		defer server.Close()
+		query := models.Query{Type: models.QueryTypeUQL, Source: "url", URL: server.URL, ResponseContentType: "application/json"}
+		res, _, _, err := client.GetResults(context.Background(), query, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, []any{map[string]any{"city": "München"}}, res)
+	})
+}
@@ -10,10 +10,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
@@ -27,9 +27,20 @@ type Client struct {
 	HttpClient      *http.Client
 	AzureBlobClient *azblob.Client
 	IsMock          bool
+	// CacheBackend stores the cached responses. Nil means the responses are cached in the namespace of the client in BadgerDB
+	CacheBackend CacheBackend
+	rateLimits   *rateLimits
+	memoryCache  *memoryCache
+	coalescer    *RequestCoalescer
+	databases    *sqlDatabases
 }
 
-var BadgerDB *Sett
+var (
+	badgerDB *Sett
+	badgerGC *SettGC
+	// badgerOptions are the options the open cache was opened with
+	badgerOptions SettOptions
+)
 
 // struct to cache https response
 type Mycache struct {
@@ -41,12 +52,106 @@ type Mycache struct {
 	JsonBody   bool
 }
 
+var badgerMu sync.Mutex
+
+type cacheRefreshKey struct{}
+
+// withCacheRefresh returns the context which makes the client skip the cache lookup.
+// Fresh results are still written to the cache.
+func withCacheRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheRefreshKey{}, true)
+}
+
+func isCacheRefresh(ctx context.Context) bool {
+	refresh, _ := ctx.Value(cacheRefreshKey{}).(bool)
+	return refresh
+}
+
+// BadgerInit opens the cache with the default options, unless it is already open
 func BadgerInit() {
-	if BadgerDB == nil {
-		gob.Register(&Mycache{})
-		gob.Register(&json.RawMessage{})
-		BadgerDB = Open()
+	badgerMu.Lock()
+	defer badgerMu.Unlock()
+	initBadgerDB(SettOptions{})
+}
+
+// BadgerInitWithOptions opens the cache with the given options. The cache open with other options is closed and opened again,
+// so the changes of the options take effect without restarting the plugin. Returns false when the cache was already open with
+// the options. Falls back to in-memory cache when the options are invalid
+func BadgerInitWithOptions(options SettOptions) bool {
+	badgerMu.Lock()
+	defer badgerMu.Unlock()
+	if badgerDB != nil && !badgerOptions.equal(options) {
+		backend.Logger.Info("cache options changed. reopening the cache", "dir", options.Dir)
+		if err := closeBadgerDB(); err != nil {
+			backend.Logger.Error("error closing the cache", "error", err.Error())
+		}
 	}
+	return initBadgerDB(options)
+}
+
+// GetBadgerDB returns the cache shared by all the datasource instances. The cache is initialized when it is not open
+func GetBadgerDB() *Sett {
+	badgerMu.Lock()
+	defer badgerMu.Unlock()
+	initBadgerDB(SettOptions{})
+	return badgerDB
+}
+
+// initBadgerDB must be called with the badgerMu held
+func initBadgerDB(options SettOptions) bool {
+	if badgerDB != nil {
+		return false
+	}
+	gob.Register(&Mycache{})
+	gob.Register(&IncrementalState{})
+	gob.Register(&Dataset{})
+	gob.Register(&Fixture{})
+	gob.Register(&WebhookPayload{})
+	gob.Register(&ScheduledExport{})
+	RegisterSettType("Mycache", &Mycache{})
+	RegisterSettType("IncrementalState", &IncrementalState{})
+	RegisterSettType("Dataset", &Dataset{})
+	RegisterSettType("Fixture", &Fixture{})
+	RegisterSettType("WebhookPayload", &WebhookPayload{})
+	RegisterSettType("ScheduledExport", &ScheduledExport{})
+	RegisterSettType("CachedResponse", cachedResponse{})
+	gob.Register(&json.RawMessage{})
+	db, err := OpenWithOptions(options)
+	if err != nil {
+		backend.Logger.Error("error opening the persistent cache. falling back to in-memory cache", "error", err.Error())
+		db = Open()
+	}
+	badgerDB, badgerOptions = db, options
+	// single garbage collection loop for the cache shared by the instances
+	badgerGC = db.StartGC(options.GC)
+	return true
+}
+
+// GetBadgerGC returns the garbage collection of the open cache. The garbage collection is started and stopped along with the cache,
+// so it is restarted with the new options when the cache is reopened. Returns nil when the cache is not open
+func GetBadgerGC() *SettGC {
+	badgerMu.Lock()
+	defer badgerMu.Unlock()
+	return badgerGC
+}
+
+// CloseBadgerDB closes the cache and releases the lock of the cache directory. The next BadgerInit opens the cache again,
+// so the cache directory can be reopened by the reloaded plugin. Must be called only when no queries are running
+func CloseBadgerDB() error {
+	badgerMu.Lock()
+	defer badgerMu.Unlock()
+	return closeBadgerDB()
+}
+
+// closeBadgerDB must be called with the badgerMu held
+func closeBadgerDB() error {
+	if badgerDB == nil {
+		return nil
+	}
+	badgerGC.Stop()
+	err := badgerDB.Close()
+	badgerDB, badgerGC, badgerOptions = nil, nil, SettOptions{}
+	return err
 }
 
 func GetTLSConfigFromSettings(settings models.InfinitySettings) (*tls.Config, error) {
@@ -95,15 +200,20 @@ func getBaseHTTPClient(ctx context.Context, settings models.InfinitySettings) *h
 	default:
 		transport.Proxy = http.ProxyFromEnvironment
 	}
+	applyUnixSocket(transport, settings)
 	return &http.Client{
 		Transport: transport,
 		Timeout:   time.Second * time.Duration(settings.TimeoutInSeconds),
 	}
 }
 
-func NewClient(ctx context.Context, settings models.InfinitySettings) (client *Client, err error) {
+func NewClient(ctx context.Context, settings models.InfinitySettings, opts ...ClientOption) (client *Client, err error) {
 	_, span := tracing.DefaultTracer().Start(ctx, "NewClient")
 	defer span.End()
+	options := &clientOptions{middlewares: registeredMiddlewares()}
+	for _, opt := range opts {
+		opt(options)
+	}
 	if settings.AuthenticationMethod == "" {
 		settings.AuthenticationMethod = models.AuthenticationMethodNone
 		if settings.BasicAuthEnabled {
@@ -118,13 +228,26 @@ func NewClient(ctx context.Context, settings models.InfinitySettings) (client *C
 		span.RecordError(errors.New("invalid http client"))
 		return nil, errors.New("invalid http client")
 	}
+	settings.URL = resolveDockerHost(settings)
+	baseTransport := httpClient.Transport
 	httpClient = ApplyDigestAuth(ctx, httpClient, settings)
 	httpClient = ApplyOAuthClientCredentials(ctx, httpClient, settings)
 	httpClient = ApplyOAuthJWT(ctx, httpClient, settings)
 	httpClient = ApplyAWSAuth(ctx, httpClient, settings)
+	if httpClient, settings, err = ApplyKubernetesAuth(ctx, httpClient, settings); err != nil {
+		span.RecordError(err)
+		span.SetStatus(500, err.Error())
+		return nil, err
+	}
+	httpClient = ApplyRedirectPolicy(ctx, httpClient, baseTransport, settings)
+	httpClient = ApplyMiddlewares(httpClient, settings, options.middlewares)
 	client = &Client{
-		Settings:   settings,
-		HttpClient: httpClient,
+		Settings:    settings,
+		HttpClient:  httpClient,
+		rateLimits:  &rateLimits{},
+		memoryCache: newMemoryCache(settings.CacheMemoryEntries),
+		databases:   &sqlDatabases{},
+		coalescer:   NewRequestCoalescer(),
 	}
 	if settings.AuthenticationMethod == models.AuthenticationMethodAzureBlob {
 		cred, err := azblob.NewSharedKeyCredential(settings.AzureBlobAccountName, settings.AzureBlobAccountKey)
@@ -156,6 +279,10 @@ func NewClient(ctx context.Context, settings models.InfinitySettings) (client *C
 	if settings.IsMock {
 		client.IsMock = true
 	}
+	if client.CacheBackend, err = NewCacheBackend(settings, client.CacheNamespace()); err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("invalid cache backend. %w", err)
+	}
 	return client, err
 }
 
@@ -171,6 +298,61 @@ func replaceSect(input string, settings models.InfinitySettings, includeSect boo
 	return input
 }
 
+// Close releases the idle connections of the http client, the sql connection pools and the cache backend. The badger cache is shared
+// by all the clients and is closed by CloseBadgerDB instead
+func (client *Client) Close() error {
+	if client.HttpClient != nil {
+		client.HttpClient.CloseIdleConnections()
+	}
+	err := client.databases.close()
+	if closer, ok := client.CacheBackend.(io.Closer); ok {
+		return errors.Join(err, closer.Close())
+	}
+	return err
+}
+
+// CacheNamespace returns the namespace of the cache entries of the datasource instance,
+// so that the instance never reads the responses cached by other datasources or orgs
+func (client *Client) CacheNamespace() string {
+	if client.Settings.UID == "" && client.Settings.ID == 0 {
+		return ""
+	}
+	return fmt.Sprintf("org%d/%d/%s", client.Settings.OrgID, client.Settings.ID, client.Settings.UID)
+}
+
+// Cache returns the cache of the datasource instance
+func (client *Client) Cache() *Sett {
+	return GetBadgerDB().Namespace(client.CacheNamespace())
+}
+
+// ResponseCache returns the backend of the cached responses, behind the in-memory cache of the instance when enabled
+func (client *Client) ResponseCache() CacheBackend {
+	var cache CacheBackend = client.CacheBackend
+	if cache == nil {
+		cache = NewSettCacheBackend(client.Cache().Table("peers"))
+	}
+	if client.memoryCache != nil {
+		return &MemoryCacheBackend{cache: client.memoryCache, next: cache}
+	}
+	return cache
+}
+
+// PurgeCache removes all the cache entries of the datasource instance. Returns the number of removed entries
+func (client *Client) PurgeCache(ctx context.Context) (int, error) {
+	if client.CacheNamespace() == "" {
+		return 0, errors.New("cache of the datasource instance can't be identified")
+	}
+	if client.memoryCache != nil {
+		client.memoryCache.clear()
+	}
+	count, err := client.Cache().WithContext(ctx).Drop()
+	if err != nil || client.CacheBackend == nil {
+		return count, err
+	}
+	n, err := client.CacheBackend.Purge(ctx)
+	return count + n, err
+}
+
 func getBadgerKey(headers []models.URLOptionKeyValuePair) (string, time.Duration, error) {
 	badgerkey := ""
 	badgerttl := time.Duration(60) * time.Second
@@ -190,9 +372,16 @@ func getBadgerKey(headers []models.URLOptionKeyValuePair) (string, time.Duration
 	return badgerkey, badgerttl, err
 }
 
-func setCache(headers []models.URLOptionKeyValuePair, mycache Mycache) error {
+func setCache(ctx context.Context, cache CacheBackend, headers []models.URLOptionKeyValuePair, mycache Mycache) error {
+	if isResponseCacheSkipped(ctx) {
+		return nil
+	}
 	if badgerkey, badgerttl, err := getBadgerKey(headers); err == nil {
-		if err := BadgerDB.Table("peers").WithTTL(badgerttl).SetStruct(badgerkey, &mycache); err != nil {
+		value, err := encodeMycache(mycache)
+		if err != nil {
+			return fmt.Errorf("error encoding the response for cache %w", err)
+		}
+		if err := cache.Set(ctx, badgerkey, value, badgerttl); err != nil {
 			return fmt.Errorf("@@@@@@@set data to cache error in badger  %v", err)
 		} else {
 			backend.Logger.Info("@@@@@@SetCache  to set data to cache key info,", "key", badgerkey, "ttl", badgerttl, "err", err)
@@ -204,13 +393,20 @@ func setCache(headers []models.URLOptionKeyValuePair, mycache Mycache) error {
 	return nil
 }
 
-func getCache(headers []models.URLOptionKeyValuePair) (*Mycache, error) {
+func getCache(ctx context.Context, cache CacheBackend, headers []models.URLOptionKeyValuePair) (*Mycache, error) {
+	if isResponseCacheSkipped(ctx) {
+		return nil, errors.New("response cache is skipped")
+	}
 	if badgerkey, _, err := getBadgerKey(headers); err == nil {
-		if res, err := BadgerDB.Table("peers").GetStruct(badgerkey); err != nil {
+		if value, err := cache.Get(ctx, badgerkey); err != nil {
 			return nil, err
 		} else {
-			res.(*Mycache).Duration = 0 // surdefine new time
-			return res.(*Mycache), nil
+			res, err := decodeMycache(value)
+			if err != nil {
+				return nil, err
+			}
+			res.Duration = 0 // surdefine new time
+			return res, nil
 		}
 	} else {
 		return nil, fmt.Errorf("error getting data frame from cache %v", err)
@@ -221,9 +417,19 @@ func (client *Client) req(ctx context.Context, url string, body io.Reader, setti
 	BadgerInit()
 	ctx, span := tracing.DefaultTracer().Start(ctx, "client.req")
 	defer span.End()
+	var bodyBytes []byte
+	if body != nil && query.CoalesceWindowSeconds > 0 {
+		if bodyBytes, err = readAll(body, 0); err != nil {
+			return nil, http.StatusInternalServerError, 0, fmt.Errorf("error reading request body. %w", err)
+		}
+		body = bytes.NewReader(bodyBytes)
+	}
 	req, _ := GetRequest(ctx, settings, body, query, requestHeaders, true)
 	//backend.Logger.Info("=====================>requesting URL", "url", url, "method", req.Method, "headers", query.URLOptions.Headers)
-	if cache, err := getCache(query.URLOptions.Headers); err == nil {
+	stopCacheTiming := trackTiming(ctx, cacheTiming)
+	cache, err := getCache(ctx, client.ResponseCache(), query.URLOptions.Headers)
+	stopCacheTiming()
+	if err == nil && !isCacheRefresh(ctx) {
 
 		if cache.JsonBody {
 			var out any
@@ -240,79 +446,146 @@ func (client *Client) req(ctx context.Context, url string, body io.Reader, setti
 	}
 	backend.Logger.Info("=====================>requesting URL Cache ** NOT ** found for", "url", url, "method", req.Method, "headers", query.URLOptions.Headers)
 
-	startTime := time.Now()
 	if !CanAllowURL(req.URL.String(), settings.AllowedHosts) {
 		backend.Logger.Error("url is not in the allowed list. make sure to match the base URL with the settings", "url", req.URL.String())
-		return nil, http.StatusUnauthorized, 0, errors.New("requested URL is not allowed. To allow this URL, update the datasource config Security -> Allowed Hosts section")
+		return nil, http.StatusUnauthorized, 0, UserError(errors.New("requested URL is not allowed. To allow this URL, update the datasource config Security -> Allowed Hosts section"))
+	}
+	if err := client.rateLimits.waitForRateLimit(ctx, req.URL.Host); err != nil {
+		return nil, http.StatusTooManyRequests, 0, err
+	}
+	if query.CoalesceWindowSeconds > 0 {
+		window := time.Duration(query.CoalesceWindowSeconds) * time.Second
+		return client.coalescer.Do(ctx, GetCoalesceKey(req, bodyBytes), window, func(ctx context.Context) (any, int, time.Duration, error) {
+			return client.do(ctx, req.WithContext(ctx), url, query)
+		})
 	}
+	return client.do(ctx, req, url, query)
+}
+
+func (client *Client) do(ctx context.Context, req *http.Request, url string, query models.Query) (obj any, statusCode int, duration time.Duration, err error) {
+	startTime := time.Now()
 	backend.Logger.Debug("yesoreyeram-infinity-datasource plugin is now requesting URL", "url", req.URL.String())
+	req = req.WithContext(withClientTrace(req.Context(), startTime))
 	res, err := client.HttpClient.Do(req)
 	duration = time.Since(startTime)
+	recordFinalURL(ctx, req, res)
+	recordResponseHeader(ctx, res)
+	if res != nil {
+		client.rateLimits.record(req.URL.Host, getRateLimit(res.Header, time.Now()))
+	}
 	if res != nil {
 		defer res.Body.Close()
 	}
+	if err != nil {
+		if ctxErr := ContextError(ctx); ctxErr != nil {
+			return nil, http.StatusInternalServerError, duration, ctxErr
+		}
+	}
 	if err != nil && res != nil {
 		backend.Logger.Error("error getting response from server", "url", url, "method", req.Method, "error", err.Error(), "status code", res.StatusCode)
-		return nil, res.StatusCode, duration, fmt.Errorf("error getting response from %s", url)
+		return nil, res.StatusCode, duration, DownstreamError(fmt.Errorf("error getting response from %s. %w", url, err), res.StatusCode)
 	}
 	if err != nil && res == nil {
 		backend.Logger.Error("error getting response from server. no response received", "url", url, "error", err.Error())
-		return nil, http.StatusInternalServerError, duration, fmt.Errorf("error getting response from url %s. no response received. Error: %s", url, err.Error())
+		return nil, http.StatusInternalServerError, duration, DownstreamError(fmt.Errorf("error getting response from url %s. no response received. Error: %s", url, err.Error()), 0)
 	}
 	if err == nil && res == nil {
 		backend.Logger.Error("invalid response from server and also no error", "url", url, "method", req.Method)
-		return nil, http.StatusInternalServerError, duration, fmt.Errorf("invalid response received for the URL %s", url)
+		return nil, http.StatusInternalServerError, duration, DownstreamError(fmt.Errorf("invalid response received for the URL %s", url), 0)
 	}
 	if res.StatusCode >= http.StatusBadRequest {
-		return nil, res.StatusCode, duration, errors.New(res.Status)
+		if query.URLOptions.BodyType == "soap" {
+			// SOAP services return the faults with the error status code
+			if body, err := io.ReadAll(res.Body); err == nil {
+				if _, err := ExtractSOAPResult(body, query); errors.Is(err, ErrSOAPFault) {
+					return nil, res.StatusCode, duration, DownstreamError(err, res.StatusCode)
+				}
+			}
+		}
+		return nil, res.StatusCode, duration, DownstreamError(errors.New(res.Status), res.StatusCode)
 	}
-	bodyBytes, err := io.ReadAll(res.Body)
+	stopDownloadTiming := trackTiming(ctx, downloadTiming)
+	bodyBytes, err := readAll(res.Body, res.ContentLength)
+	stopDownloadTiming()
 	if err != nil {
+		if ctxErr := ContextError(ctx); ctxErr != nil {
+			// download is aborted mid-body when the query is cancelled
+			return nil, res.StatusCode, duration, ctxErr
+		}
 		backend.Logger.Error("error reading response body", "url", url, "error", err.Error())
-		return nil, res.StatusCode, duration, err
+		return nil, res.StatusCode, duration, DownstreamError(err, 0)
+	}
+	if query.ResponseContentType != "" {
+		res.Header.Set(headerKeyContentType, query.ResponseContentType)
+	}
+	bodyBytes, err = decodeResponseCharset(bodyBytes, res.Header.Get(headerKeyContentType), query)
+	if err != nil {
+		return nil, res.StatusCode, duration, UserError(err)
+	}
+	bodyBytes, err = ApplyPreParseSteps(bodyBytes, query.PreParse)
+	if err != nil {
+		return nil, res.StatusCode, duration, UserError(err)
+	}
+	bodyBytes, err = ExtractSOAPResult(bodyBytes, query)
+	if errors.Is(err, ErrSOAPFault) {
+		return nil, res.StatusCode, duration, DownstreamError(err, res.StatusCode)
+	}
+	if err != nil {
+		return nil, res.StatusCode, duration, UserError(err)
 	}
-	bodyBytes = removeBOMContent(bodyBytes)
 	if CanParseAsJSON(query.Type, res.Header) {
 		var out any
+		stopParseTiming := trackTiming(ctx, parseTiming)
 		err := json.Unmarshal(bodyBytes, &out)
+		stopParseTiming()
 		if err != nil {
 			backend.Logger.Error("error un-marshaling JSON response", "url", url, "error", err.Error())
+			err = DownstreamError(err, 0)
 		}
 		mycache := Mycache{bodyBytes, res.StatusCode, duration, err, true}
-		errset := setCache(query.URLOptions.Headers, mycache)
+		errset := setCache(ctx, client.ResponseCache(), query.URLOptions.Headers, mycache)
 		backend.Logger.Info("SetCache  to set data to cache json", errset)
 		return out, res.StatusCode, duration, err
 	}
 	mycache := Mycache{bodyBytes, res.StatusCode, duration, err, false}
-	errset := setCache(query.URLOptions.Headers, mycache)
+	errset := setCache(ctx, client.ResponseCache(), query.URLOptions.Headers, mycache)
 	backend.Logger.Info("SetCache  to set data to cache string", errset)
 	return string(bodyBytes), res.StatusCode, duration, err
 }
 
-// https://stackoverflow.com/questions/31398044/got-error-invalid-character-%C3%AF-looking-for-beginning-of-value-from-json-unmar
-func removeBOMContent(input []byte) []byte {
-	return bytes.TrimPrefix(input, []byte("\xef\xbb\xbf"))
-}
-
 func (client *Client) GetResults(ctx context.Context, query models.Query, requestHeaders map[string]string) (o any, statusCode int, duration time.Duration, err error) {
+	if query.UseSample {
+		return client.getSampleResults(ctx, query)
+	}
 	if query.Source == "azure-blob" {
 		if strings.TrimSpace(query.AzBlobContainerName) == "" || strings.TrimSpace(query.AzBlobName) == "" {
-			return nil, http.StatusBadRequest, 0, errors.New("invalid/empty container name/blob name")
+			return nil, http.StatusBadRequest, 0, UserError(errors.New("invalid/empty container name/blob name"))
 		}
 		if client.AzureBlobClient == nil {
 			return nil, http.StatusInternalServerError, 0, errors.New("invalid azure blob client")
 		}
 		blobDownloadResponse, err := client.AzureBlobClient.DownloadStream(ctx, strings.TrimSpace(query.AzBlobContainerName), strings.TrimSpace(query.AzBlobName), nil)
 		if err != nil {
-			return nil, http.StatusInternalServerError, 0, err
+			return nil, http.StatusInternalServerError, 0, DownstreamError(err, 0)
 		}
 		reader := blobDownloadResponse.Body
-		bodyBytes, err := io.ReadAll(reader)
+		bodyBytes, err := readAll(reader, 0)
+		if err != nil {
+			return nil, http.StatusInternalServerError, 0, DownstreamError(fmt.Errorf("error reading blob content. %w", err), 0)
+		}
+		contentType := query.ResponseContentType
+		if contentType == "" && blobDownloadResponse.ContentType != nil {
+			contentType = *blobDownloadResponse.ContentType
+		}
+		bodyBytes, err = decodeResponseCharset(bodyBytes, contentType, query)
+		if err != nil {
+			return nil, http.StatusBadRequest, 0, UserError(err)
+		}
+		bodyBytes, err = ApplyPreParseSteps(bodyBytes, query.PreParse)
 		if err != nil {
-			return nil, http.StatusInternalServerError, 0, fmt.Errorf("error reading blob content. %w", err)
+			return nil, http.StatusBadRequest, 0, UserError(err)
 		}
-		bodyBytes = removeBOMContent(bodyBytes)
-		if CanParseAsJSON(query.Type, http.Header{}) {
+		if CanParseAsJSON(query.Type, http.Header{headerKeyContentType: []string{contentType}}) {
 			var out any
 			err := json.Unmarshal(bodyBytes, &out)
 			if err != nil {
@@ -324,7 +597,12 @@ func (client *Client) GetResults(ctx context.Context, query models.Query, reques
 	}
 	switch strings.ToUpper(query.URLOptions.Method) {
 	case http.MethodPost:
-		body := GetQueryBody(query)
+		if query.URLOptions.BodyType == "form-data" {
+			if _, err := getFormDataBody(query, client.Settings, false); err != nil {
+				return nil, http.StatusBadRequest, 0, UserError(err)
+			}
+		}
+		body := GetQueryBody(query, client.Settings, true)
 		return client.req(ctx, query.URL, body, client.Settings, query, requestHeaders)
 	default:
 		return client.req(ctx, query.URL, nil, client.Settings, query, requestHeaders)
@@ -357,20 +635,17 @@ func CanAllowURL(url string, allowedHosts []string) bool {
 	return allow
 }
 
-func GetQueryBody(query models.Query) io.Reader {
+// GetQueryBody returns the request body of the query. The secure form files are replaced with the dummy value unless includeSect is set
+func GetQueryBody(query models.Query, settings models.InfinitySettings, includeSect bool) io.Reader {
 	var body io.Reader
 	if strings.EqualFold(query.URLOptions.Method, http.MethodPost) {
 		switch query.URLOptions.BodyType {
 		case "raw":
 			body = strings.NewReader(query.URLOptions.Body)
 		case "form-data":
-			payload := &bytes.Buffer{}
-			writer := multipart.NewWriter(payload)
-			for _, f := range query.URLOptions.BodyForm {
-				_ = writer.WriteField(f.Key, f.Value)
-			}
-			if err := writer.Close(); err != nil {
-				backend.Logger.Error("error closing the query body reader")
+			payload, err := getFormDataBody(query, settings, includeSect)
+			if err != nil {
+				backend.Logger.Error("error building the form-data body", "error", err.Error())
 				return nil
 			}
 			body = payload
@@ -380,6 +655,8 @@ func GetQueryBody(query models.Query) io.Reader {
 				form.Set(f.Key, f.Value)
 			}
 			body = strings.NewReader(form.Encode())
+		case "soap":
+			body = strings.NewReader(GetSOAPEnvelope(query.URLOptions.Body, query.SOAPOptions))
 		case "graphql":
 			var variables map[string]interface{}
 			if query.URLOptions.BodyGraphQLVariables != "" {
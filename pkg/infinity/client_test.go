@@ -6,9 +6,15 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
 	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
 )
@@ -79,6 +85,208 @@ func TestInfinityClient_GetResults(t *testing.T) {
 	}
 }
 
+func TestInfinityClient_GetResultsWithCoalesceWindow(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		time.Sleep(100 * time.Millisecond)
+		fmt.Fprintf(w, `{ "message" : "%s" }`, r.URL.Query().Get("q"))
+	}))
+	defer server.Close()
+	client, err := infinity.NewClient(context.Background(), models.InfinitySettings{})
+	require.Nil(t, err)
+	query := models.Query{Type: models.QueryTypeJSON, URL: server.URL + "?q=coalesce", CoalesceWindowSeconds: 5}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			o, statusCode, _, err := client.GetResults(context.Background(), query, map[string]string{})
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, statusCode)
+			assert.Equal(t, map[string]any{"message": "coalesce"}, o)
+		}()
+	}
+	wg.Wait()
+	_, _, _, err = client.GetResults(context.Background(), query, map[string]string{})
+	require.Nil(t, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(&hits))
+	query.URL = server.URL + "?q=other"
+	_, _, _, err = client.GetResults(context.Background(), query, map[string]string{})
+	require.Nil(t, err)
+	require.Equal(t, int32(2), atomic.LoadInt32(&hits))
+}
+
+type bearerTransport struct {
+	token string
+	next  http.RoundTripper
+}
+
+func (t bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.next.RoundTrip(req)
+}
+
+func TestInfinityClient_CoalesceIsolation(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		time.Sleep(100 * time.Millisecond)
+		fmt.Fprintf(w, `{ "token" : "%s" }`, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	}))
+	defer server.Close()
+	query := models.Query{Type: models.QueryTypeJSON, URL: server.URL, CoalesceWindowSeconds: 5}
+	wg := sync.WaitGroup{}
+	for _, token := range []string{"foo", "bar"} {
+		client, err := infinity.NewClient(context.Background(), models.InfinitySettings{})
+		require.Nil(t, err)
+		client.HttpClient.Transport = bearerTransport{token: token, next: http.DefaultTransport}
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func(token string) {
+				defer wg.Done()
+				o, _, _, err := client.GetResults(context.Background(), query, map[string]string{})
+				assert.Nil(t, err)
+				assert.Equal(t, map[string]any{"token": token}, o)
+			}(token)
+		}
+	}
+	wg.Wait()
+	require.Equal(t, int32(2), atomic.LoadInt32(&hits))
+}
+
+func TestInfinityClient_CacheIsolation(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{ "hits" : %d }`, atomic.AddInt32(&hits, 1))
+	}))
+	defer server.Close()
+	query := models.Query{Type: models.QueryTypeJSON, URL: server.URL, URLOptions: models.URLOptions{Headers: []models.URLOptionKeyValuePair{{Key: "cacheq", Value: "isolation"}}}}
+	client1, err := infinity.NewClient(context.Background(), models.InfinitySettings{OrgID: 1, UID: "ds1"})
+	require.Nil(t, err)
+	client2, err := infinity.NewClient(context.Background(), models.InfinitySettings{OrgID: 2, UID: "ds1"})
+	require.Nil(t, err)
+	for i := 0; i < 2; i++ {
+		o, _, _, err := client1.GetResults(context.Background(), query, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, map[string]any{"hits": float64(1)}, o)
+	}
+	o, _, _, err := client2.GetResults(context.Background(), query, map[string]string{})
+	require.Nil(t, err)
+	require.Equal(t, map[string]any{"hits": float64(2)}, o)
+	count, err := client1.PurgeCache(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, 1, count)
+	o, _, _, err = client1.GetResults(context.Background(), query, map[string]string{})
+	require.Nil(t, err)
+	require.Equal(t, map[string]any{"hits": float64(3)}, o)
+	o, _, _, err = client2.GetResults(context.Background(), query, map[string]string{})
+	require.Nil(t, err)
+	require.Equal(t, map[string]any{"hits": float64(2)}, o)
+}
+
+func TestInfinityClient_CacheEnvelope(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{ "hits" : %d }`, atomic.AddInt32(&hits, 1))
+	}))
+	defer server.Close()
+	query := models.Query{Type: models.QueryTypeJSON, URL: server.URL, URLOptions: models.URLOptions{Headers: []models.URLOptionKeyValuePair{{Key: "cacheq", Value: "envelope"}}}}
+	client, err := infinity.NewClient(context.Background(), models.InfinitySettings{OrgID: 1, UID: "envelope"})
+	require.Nil(t, err)
+	for i := 0; i < 2; i++ {
+		o, _, _, err := client.GetResults(context.Background(), query, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, map[string]any{"hits": float64(1)}, o)
+	}
+	// responses are cached in the envelope of the default codec of the tables
+	value, err := client.ResponseCache().Get(context.Background(), "envelope")
+	require.Nil(t, err)
+	require.Equal(t, []byte{infinity.SETT_ENVELOPE_VERSION, infinity.MSGPACK_CODEC}, value[:2])
+}
+
+func TestInfinityClient_AcquireLease(t *testing.T) {
+	client, err := infinity.NewClient(context.Background(), models.InfinitySettings{OrgID: 1, UID: "lease"})
+	require.Nil(t, err)
+	lease, err := client.TryAcquireLease(context.Background(), "job", time.Minute)
+	require.Nil(t, err)
+	require.NotNil(t, lease)
+	other, err := client.TryAcquireLease(context.Background(), "job", time.Minute)
+	require.Nil(t, err)
+	require.Nil(t, other)
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	_, err = client.AcquireLease(ctx, "job", time.Minute)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		lease.Release(context.Background()) //nolint
+	}()
+	other, err = client.AcquireLease(context.Background(), "job", time.Minute)
+	require.Nil(t, err)
+	require.NotNil(t, other)
+	require.Nil(t, other.Release(context.Background()))
+}
+
+func TestCloseBadgerDB(t *testing.T) {
+	require.Nil(t, infinity.CloseBadgerDB())
+	dir := t.TempDir()
+	require.True(t, infinity.BadgerInitWithOptions(infinity.SettOptions{Dir: dir}))
+	require.Nil(t, infinity.GetBadgerDB().Set("foo", "bar"))
+	require.Nil(t, infinity.CloseBadgerDB())
+	// reopening the same directory fails when the lock of the directory is not released
+	require.True(t, infinity.BadgerInitWithOptions(infinity.SettOptions{Dir: dir}))
+	got, err := infinity.GetBadgerDB().Get("foo")
+	require.Nil(t, err)
+	require.Equal(t, "bar", got)
+	require.Nil(t, infinity.CloseBadgerDB())
+}
+
+func TestBadgerInitWithOptions(t *testing.T) {
+	require.Nil(t, infinity.CloseBadgerDB())
+	t.Cleanup(func() { _ = infinity.CloseBadgerDB() })
+	dirA, dirB := t.TempDir(), t.TempDir()
+	require.True(t, infinity.BadgerInitWithOptions(infinity.SettOptions{Dir: dirA}))
+	require.False(t, infinity.BadgerInitWithOptions(infinity.SettOptions{Dir: dirA}))
+	// the cache open is kept by the requests opening the cache with the default options
+	infinity.BadgerInit()
+	require.Nil(t, infinity.GetBadgerDB().Set("foo", "bar"))
+	// the changed options reopen the cache
+	require.True(t, infinity.BadgerInitWithOptions(infinity.SettOptions{Dir: dirB}))
+	require.False(t, infinity.GetBadgerDB().HasKey("foo"))
+	require.True(t, infinity.BadgerInitWithOptions(infinity.SettOptions{Dir: dirA, GC: infinity.SettGCOptions{Interval: 10 * time.Millisecond}}))
+	got, err := infinity.GetBadgerDB().Get("foo")
+	require.Nil(t, err)
+	require.Equal(t, "bar", got)
+	// the garbage collection runs with the options of the reopened cache
+	gc := infinity.GetBadgerGC()
+	require.Equal(t, 10*time.Millisecond, gc.Options().Interval)
+	require.Eventually(t, func() bool { return gc.Stats().Runs > 0 }, time.Second, 10*time.Millisecond)
+	require.Nil(t, infinity.CloseBadgerDB())
+	require.Nil(t, infinity.GetBadgerGC())
+}
+
+func TestGetBadgerDB(t *testing.T) {
+	require.Nil(t, infinity.CloseBadgerDB())
+	require.True(t, infinity.BadgerInitWithOptions(infinity.SettOptions{Dir: t.TempDir()}))
+	// the cache is closed by the disposer of the last instance while the other goroutines look it up
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.NotNil(t, infinity.GetBadgerDB())
+		}()
+		go func() {
+			defer wg.Done()
+			_ = infinity.CloseBadgerDB()
+		}()
+	}
+	wg.Wait()
+	require.Nil(t, infinity.CloseBadgerDB())
+}
+
 func TestCanAllowURL(t *testing.T) {
 	tests := []struct {
 		name         string
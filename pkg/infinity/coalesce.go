@@ -0,0 +1,112 @@
+package infinity
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestCoalescer de-duplicates identical requests. While a request is in flight, identical requests wait for its result
+// instead of hitting the upstream. Once completed, the result is shared with the identical requests arriving within the window.
+// Each client has its own coalescer, as the credentials added by the transport of the client are not part of the key
+type RequestCoalescer struct {
+	mu    sync.Mutex
+	calls map[string]*coalescedCall
+}
+
+type coalescedCall struct {
+	ctx        context.Context
+	cancel     context.CancelFunc
+	waiters    int
+	done       chan struct{}
+	expiresAt  time.Time
+	obj        any
+	statusCode int
+	duration   time.Duration
+	err        error
+}
+
+func NewRequestCoalescer() *RequestCoalescer {
+	return &RequestCoalescer{calls: map[string]*coalescedCall{}}
+}
+
+// Do executes the fn once per key and window. All the callers with the same key receive the same result.
+// fn runs detached from the context of the callers, so the callers still waiting receive the result when the first caller is cancelled.
+// The context of the fn is cancelled once all the callers have gone. Nil coalescer runs the fn for every caller
+func (c *RequestCoalescer) Do(ctx context.Context, key string, window time.Duration, fn func(ctx context.Context) (any, int, time.Duration, error)) (any, int, time.Duration, error) {
+	if c == nil {
+		return fn(ctx)
+	}
+	c.mu.Lock()
+	call, ok := c.calls[key]
+	if ok && call.expiresAt.IsZero() && call.ctx.Err() != nil {
+		// in flight call abandoned by all the callers
+		ok = false
+	}
+	if ok && !call.expiresAt.IsZero() && !time.Now().Before(call.expiresAt) {
+		ok = false
+	}
+	if !ok {
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &coalescedCall{ctx: callCtx, cancel: cancel, done: make(chan struct{})}
+		c.calls[key] = call
+		go c.run(key, window, call, fn)
+	}
+	call.waiters++
+	c.mu.Unlock()
+	select {
+	case <-call.done:
+		return call.obj, call.statusCode, call.duration, call.err
+	case <-ctx.Done():
+		c.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			call.cancel()
+		}
+		c.mu.Unlock()
+		return nil, http.StatusInternalServerError, 0, ctx.Err()
+	}
+}
+
+func (c *RequestCoalescer) run(key string, window time.Duration, call *coalescedCall, fn func(ctx context.Context) (any, int, time.Duration, error)) {
+	obj, statusCode, duration, err := fn(call.ctx)
+	c.mu.Lock()
+	call.obj, call.statusCode, call.duration, call.err = obj, statusCode, duration, err
+	call.expiresAt = time.Now().Add(window)
+	if call.ctx.Err() != nil {
+		// results of the cancelled requests are not shared with the later requests
+		call.expiresAt = time.Now()
+	}
+	close(call.done)
+	c.mu.Unlock()
+	call.cancel()
+	time.AfterFunc(window, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.calls[key] == call {
+			delete(c.calls, key)
+		}
+	})
+}
+
+// GetCoalesceKey returns the key which identifies identical requests of the client. Method, URL, headers and body are part of the key,
+// so that requests with different credentials in the headers never share the results
+func GetCoalesceKey(req *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(req.Method + "\n" + req.URL.String() + "\n"))
+	keys := make([]string, 0, len(req.Header))
+	for k := range req.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		h.Write([]byte(k + ":" + strings.Join(req.Header[k], ",") + "\n"))
+	}
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,77 @@
+package infinity_test
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+)
+
+func TestRequestCoalescer(t *testing.T) {
+	t.Run("should share the result with the waiters when the first caller is cancelled", func(t *testing.T) {
+		c := infinity.NewRequestCoalescer()
+		started, release := make(chan struct{}), make(chan struct{})
+		var calls int32
+		fn := func(ctx context.Context) (any, int, time.Duration, error) {
+			atomic.AddInt32(&calls, 1)
+			close(started)
+			select {
+			case <-release:
+				return "foo", http.StatusOK, 0, nil
+			case <-ctx.Done():
+				return nil, http.StatusInternalServerError, 0, ctx.Err()
+			}
+		}
+		leaderCtx, cancelLeader := context.WithCancel(context.Background())
+		leaderErr := make(chan error)
+		go func() {
+			_, _, _, err := c.Do(leaderCtx, "key", time.Second, fn)
+			leaderErr <- err
+		}()
+		<-started
+		waiter := make(chan any)
+		go func() {
+			obj, _, _, err := c.Do(context.Background(), "key", time.Second, fn)
+			if err != nil {
+				obj = err
+			}
+			waiter <- obj
+		}()
+		// wait for the waiter to join the in flight call
+		time.Sleep(50 * time.Millisecond)
+		cancelLeader()
+		require.ErrorIs(t, <-leaderErr, context.Canceled)
+		close(release)
+		require.Equal(t, "foo", <-waiter)
+		require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+	t.Run("should cancel the call once all the callers have gone", func(t *testing.T) {
+		c := infinity.NewRequestCoalescer()
+		cancelled := make(chan struct{})
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+		}()
+		_, _, _, err := c.Do(ctx, "key", time.Second, func(ctx context.Context) (any, int, time.Duration, error) {
+			<-ctx.Done()
+			close(cancelled)
+			return nil, http.StatusInternalServerError, 0, ctx.Err()
+		})
+		require.ErrorIs(t, err, context.Canceled)
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			t.Fatal("call is not cancelled")
+		}
+		obj, _, _, err := c.Do(context.Background(), "key", time.Second, func(ctx context.Context) (any, int, time.Duration, error) {
+			return "bar", http.StatusOK, 0, nil
+		})
+		require.Nil(t, err)
+		require.Equal(t, "bar", obj)
+	})
+}
@@ -0,0 +1,213 @@
+package infinity
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+// ApplyColumnOptions applies the per column options such as duration, geo and formatted number parsing, value mappings and units
+// to the fields of the frame. Fields are matched by the column alias or by the selector when alias is empty.
+func ApplyColumnOptions(ctx context.Context, frame *data.Frame, query models.Query) (*data.Frame, error) {
+	_, span := tracing.DefaultTracer().Start(ctx, "ApplyColumnOptions")
+	defer span.End()
+	if frame == nil {
+		return frame, nil
+	}
+	for _, column := range query.Columns {
+		fieldIndex := getColumnFieldIndex(frame, column)
+		if fieldIndex < 0 {
+			continue
+		}
+		switch column.Type {
+		case "duration":
+			frame.Fields[fieldIndex] = getFieldWithDurations(frame.Fields[fieldIndex])
+		case "latitude", "longitude":
+			frame.Fields[fieldIndex] = getCoordinateField(frame.Fields[fieldIndex])
+		case "geohash":
+			frame = getFrameWithSplitCoordinates(frame, fieldIndex, DecodeGeohash)
+		case "latlon":
+			frame = getFrameWithSplitCoordinates(frame, fieldIndex, ParseLatLon)
+		}
+		if column.ParseFormatted {
+			frame.Fields[fieldIndex] = getFieldWithFormattedNumbers(frame.Fields[fieldIndex])
+		}
+		if len(column.ValueMappings) > 0 {
+			frame.Fields[fieldIndex] = getFieldWithValueMappings(frame.Fields[fieldIndex], column.ValueMappings)
+		}
+		if column.Unit != "" {
+			if frame.Fields[fieldIndex].Config == nil {
+				frame.Fields[fieldIndex].Config = &data.FieldConfig{}
+			}
+			frame.Fields[fieldIndex].Config.Unit = column.Unit
+		}
+	}
+	return frame, nil
+}
+
+func getColumnFieldName(column models.InfinityColumn) string {
+	if column.Text != "" {
+		return column.Text
+	}
+	return column.Selector
+}
+
+func getColumnFieldIndex(frame *data.Frame, column models.InfinityColumn) int {
+	name := getColumnFieldName(column)
+	if name == "" {
+		return -1
+	}
+	for i, field := range frame.Fields {
+		if field.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// getFieldWithValueMappings sets the mappings as the value mappings of the field config, so grafana displays the mapped
+// text while the field keeps its type and values. Numeric fields remain usable in the calculations, thresholds and alerts
+func getFieldWithValueMappings(field *data.Field, mappings []models.InfinityValueMapping) *data.Field {
+	mapper := data.ValueMapper{}
+	for i, mapping := range mappings {
+		mapper[mapping.Value] = data.ValueMappingResult{Text: mapping.Text, Index: i}
+	}
+	if field.Config == nil {
+		field.Config = &data.FieldConfig{}
+	}
+	field.Config.Mappings = append(field.Config.Mappings, mapper)
+	return field
+}
+
+// getFieldWithFormattedNumbers converts a field with display formatted numbers such as "$1,234.56", "1.234,56 €", "3.4M" or "12k"
+// into a nullable float64 field. Values which can't be parsed are set to null.
+func getFieldWithFormattedNumbers(field *data.Field) *data.Field {
+	out := data.NewFieldFromFieldType(data.FieldTypeNullableFloat64, field.Len())
+	out.Name = field.Name
+	out.Labels = field.Labels
+	out.Config = field.Config
+	for i := 0; i < field.Len(); i++ {
+		value, ok := field.ConcreteAt(i)
+		if !ok {
+			continue
+		}
+		switch v := value.(type) {
+		case float64:
+			out.Set(i, &v)
+		case string:
+			if number, err := ParseFormattedNumber(v); err == nil {
+				out.Set(i, &number)
+			}
+		}
+	}
+	return out
+}
+
+// getFieldWithDurations converts a field with duration strings into a nullable float64 field with the duration in seconds.
+// Values which can't be parsed are set to null.
+func getFieldWithDurations(field *data.Field) *data.Field {
+	out := data.NewFieldFromFieldType(data.FieldTypeNullableFloat64, field.Len())
+	out.Name = field.Name
+	out.Labels = field.Labels
+	out.Config = field.Config
+	if out.Config == nil {
+		out.Config = &data.FieldConfig{}
+	}
+	if out.Config.Unit == "" {
+		out.Config.Unit = "s"
+	}
+	for i := 0; i < field.Len(); i++ {
+		value, ok := field.ConcreteAt(i)
+		if !ok {
+			continue
+		}
+		switch v := value.(type) {
+		case float64:
+			out.Set(i, &v)
+		case string:
+			if seconds, err := ParseDurationToSeconds(v); err == nil {
+				out.Set(i, &seconds)
+			}
+		}
+	}
+	return out
+}
+
+var formattedNumberMultipliers = map[string]float64{
+	"k":  1e3,
+	"m":  1e6,
+	"mm": 1e6,
+	"b":  1e9,
+	"bn": 1e9,
+	"t":  1e12,
+}
+
+// ParseFormattedNumber parses a display formatted number into float64.
+// Currency symbols and codes are ignored, thousand and decimal separators are detected from their position
+// and the suffixes k, m, b and t are applied as multipliers. Numbers within parenthesis are treated as negative.
+func ParseFormattedNumber(input string) (float64, error) {
+	input = strings.TrimSpace(input)
+	negative := false
+	if strings.HasPrefix(input, "(") && strings.HasSuffix(input, ")") {
+		negative = true
+		input = strings.TrimSuffix(strings.TrimPrefix(input, "("), ")")
+	}
+	lastDigit := strings.LastIndexFunc(input, unicode.IsDigit)
+	if lastDigit < 0 {
+		return 0, fmt.Errorf("invalid formatted number %s", input)
+	}
+	multiplier := 1.0
+	suffix := strings.ToLower(strings.TrimSpace(input[lastDigit+1:]))
+	suffix = strings.TrimFunc(suffix, func(r rune) bool { return !unicode.IsLetter(r) })
+	if m, ok := formattedNumberMultipliers[suffix]; ok {
+		multiplier = m
+	}
+	number := strings.Builder{}
+	for _, r := range input[:lastDigit+1] {
+		if unicode.IsDigit(r) || r == '.' || r == ',' {
+			number.WriteRune(r)
+			continue
+		}
+		if r == '-' || r == '\u2212' {
+			negative = true
+		}
+	}
+	numberString := normalizeDecimalSeparator(number.String())
+	value, err := strconv.ParseFloat(numberString, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid formatted number %s", input)
+	}
+	if negative {
+		value = -value
+	}
+	return value * multiplier, nil
+}
+
+// normalizeDecimalSeparator returns the number with the thousand separators removed and with "." as decimal separator
+func normalizeDecimalSeparator(input string) string {
+	lastDot := strings.LastIndex(input, ".")
+	lastComma := strings.LastIndex(input, ",")
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		if lastComma > lastDot {
+			return strings.Replace(strings.ReplaceAll(input, ".", ""), ",", ".", 1)
+		}
+		return strings.ReplaceAll(input, ",", "")
+	case lastComma >= 0:
+		if strings.Count(input, ",") > 1 || len(input)-lastComma-1 == 3 {
+			return strings.ReplaceAll(input, ",", "")
+		}
+		return strings.Replace(input, ",", ".", 1)
+	case lastDot >= 0:
+		if strings.Count(input, ".") > 1 {
+			return strings.ReplaceAll(input, ".", "")
+		}
+	}
+	return input
+}
@@ -0,0 +1,154 @@
+package infinity_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestApplyColumnOptions(t *testing.T) {
+	t.Run("value mappings and unit", func(t *testing.T) {
+		frame := data.NewFrame("A",
+			data.NewField("status", nil, []*float64{toFP(0), toFP(1), toFP(2), nil}),
+			data.NewField("latency", nil, []*float64{toFP(10), toFP(20), toFP(30), toFP(40)}),
+		)
+		got, err := infinity.ApplyColumnOptions(context.Background(), frame, models.Query{Columns: []models.InfinityColumn{
+			{Selector: "status", ValueMappings: []models.InfinityValueMapping{{Value: "0", Text: "down"}, {Value: "1", Text: "up"}}},
+			{Selector: "response_time", Text: "latency", Unit: "ms"},
+		}})
+		require.Nil(t, err)
+		require.Equal(t, data.FieldTypeNullableFloat64, got.Fields[0].Type())
+		require.Equal(t, 0.0, *(got.Fields[0].At(0).(*float64)))
+		require.Nil(t, got.Fields[0].At(3))
+		require.Equal(t, data.ValueMappings{data.ValueMapper{
+			"0": {Text: "down", Index: 0},
+			"1": {Text: "up", Index: 1},
+		}}, got.Fields[0].Config.Mappings)
+		require.Equal(t, "ms", got.Fields[1].Config.Unit)
+	})
+	t.Run("parse formatted numbers", func(t *testing.T) {
+		frame := data.NewFrame("A", data.NewField("price", nil, []*string{toSP("$1,234.56"), toSP("foo"), nil}))
+		got, err := infinity.ApplyColumnOptions(context.Background(), frame, models.Query{Columns: []models.InfinityColumn{{Selector: "price", ParseFormatted: true}}})
+		require.Nil(t, err)
+		require.Equal(t, data.FieldTypeNullableFloat64, got.Fields[0].Type())
+		require.Equal(t, 1234.56, *(got.Fields[0].At(0).(*float64)))
+		require.Nil(t, got.Fields[0].At(1))
+		require.Nil(t, got.Fields[0].At(2))
+	})
+	t.Run("duration", func(t *testing.T) {
+		frame := data.NewFrame("A", data.NewField("uptime", nil, []*string{toSP("1h1s"), toSP("PT5M"), toSP("foo")}))
+		got, err := infinity.ApplyColumnOptions(context.Background(), frame, models.Query{Columns: []models.InfinityColumn{{Selector: "uptime", Type: "duration"}}})
+		require.Nil(t, err)
+		require.Equal(t, data.FieldTypeNullableFloat64, got.Fields[0].Type())
+		require.Equal(t, 3601.0, *(got.Fields[0].At(0).(*float64)))
+		require.Equal(t, 300.0, *(got.Fields[0].At(1).(*float64)))
+		require.Nil(t, got.Fields[0].At(2))
+		require.Equal(t, "s", got.Fields[0].Config.Unit)
+	})
+	t.Run("geo columns", func(t *testing.T) {
+		frame := data.NewFrame("A",
+			data.NewField("lat", nil, []*string{toSP("57.64"), toSP("foo")}),
+			data.NewField("hash", nil, []*string{toSP("u4pruydqqvj"), toSP("!")}),
+			data.NewField("location", nil, []*string{toSP("51.5, -0.12"), toSP("91,0")}),
+		)
+		got, err := infinity.ApplyColumnOptions(context.Background(), frame, models.Query{Columns: []models.InfinityColumn{
+			{Selector: "lat", Type: "latitude"},
+			{Selector: "hash", Type: "geohash"},
+			{Selector: "location", Type: "latlon"},
+		}})
+		require.Nil(t, err)
+		require.Equal(t, 7, len(got.Fields))
+		require.Equal(t, data.FieldTypeNullableFloat64, got.Fields[0].Type())
+		require.Equal(t, 57.64, *(got.Fields[0].At(0).(*float64)))
+		require.Nil(t, got.Fields[0].At(1))
+		require.Equal(t, "latitude", got.Fields[3].Name)
+		require.Equal(t, "longitude", got.Fields[4].Name)
+		require.InDelta(t, 57.64911, *(got.Fields[3].At(0).(*float64)), 0.0001)
+		require.InDelta(t, 10.40744, *(got.Fields[4].At(0).(*float64)), 0.0001)
+		require.Nil(t, got.Fields[3].At(1))
+		require.Equal(t, "location_latitude", got.Fields[5].Name)
+		require.Equal(t, "location_longitude", got.Fields[6].Name)
+		require.Equal(t, 51.5, *(got.Fields[5].At(0).(*float64)))
+		require.Equal(t, -0.12, *(got.Fields[6].At(0).(*float64)))
+		require.Nil(t, got.Fields[5].At(1))
+	})
+}
+
+func TestParseFormattedNumber(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    float64
+		wantErr bool
+	}{
+		{input: "1234", want: 1234},
+		{input: "$1,234.56", want: 1234.56},
+		{input: "1.234,56 €", want: 1234.56},
+		{input: "1,234,567", want: 1234567},
+		{input: "1.234.567", want: 1234567},
+		{input: "1,5", want: 1.5},
+		{input: "3.4M", want: 3400000},
+		{input: "12k", want: 12000},
+		{input: "2.5 bn", want: 2500000000},
+		{input: "-€12.50", want: -12.5},
+		{input: "(1,000)", want: -1000},
+		{input: "100 USD", want: 100},
+		{input: "45%", want: 45},
+		{input: "n/a", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := infinity.ParseFormattedNumber(tt.input)
+			if tt.wantErr {
+				require.NotNil(t, err)
+				return
+			}
+			require.Nil(t, err)
+			require.InDelta(t, tt.want, got, 0.0001)
+		})
+	}
+}
+
+func TestParseDurationToSeconds(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    float64
+		wantErr bool
+	}{
+		{input: "90", want: 90},
+		{input: "1h23m", want: 4980},
+		{input: "1.5s", want: 1.5},
+		{input: "250ms", want: 0.25},
+		{input: "2d3h", want: 183600},
+		{input: "-1d1h", want: -90000},
+		{input: "00:45:12", want: 2712},
+		{input: "05:30", want: 330},
+		{input: "PT5M", want: 300},
+		{input: "P1DT2H30M15.5S", want: 95415.5},
+		{input: "P2W", want: 1209600},
+		{input: "-PT1M", want: -60},
+		{input: "PT", wantErr: true},
+		{input: "P", wantErr: true},
+		{input: "1:x", wantErr: true},
+		{input: "foo", wantErr: true},
+		{input: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := infinity.ParseDurationToSeconds(tt.input)
+			if tt.wantErr {
+				require.NotNil(t, err)
+				return
+			}
+			require.Nil(t, err)
+			require.InDelta(t, tt.want, got, 0.0001)
+		})
+	}
+}
+
+func toFP(v float64) *float64 {
+	return &v
+}
@@ -0,0 +1,166 @@
+package infinity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	cookieJarCacheTable   = "cookies"
+	defaultCookieJarTTL   = 15 * time.Minute
+	maxCookieJarTTL       = 24 * time.Hour
+	maxCookieJarResponses = 100
+)
+
+type cookieSessionKey struct{}
+
+// withCookieSession marks the requests as the part of the session restored from the cache
+func withCookieSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cookieSessionKey{}, true)
+}
+
+func hasCookieSession(ctx context.Context) bool {
+	restored, _ := ctx.Value(cookieSessionKey{}).(bool)
+	return restored
+}
+
+// sessionCookies are the cookies set by the response of the url
+type sessionCookies struct {
+	URL     string         `json:"url"`
+	Cookies []*http.Cookie `json:"cookies"`
+}
+
+// sessionJar records the cookies set by the responses, so that the cookies can be stored in the cache and restored by the next runs.
+// Expiry, domain and path of the cookies are still enforced by the cookie jar
+type sessionJar struct {
+	jar       *cookiejar.Jar
+	mu        sync.Mutex
+	responses []sessionCookies
+	restored  int
+}
+
+func newSessionJar() *sessionJar {
+	jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	return &sessionJar{jar: jar}
+}
+
+func (j *sessionJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.responses = append(j.responses, sessionCookies{URL: u.String(), Cookies: cookies})
+	if len(j.responses) > maxCookieJarResponses {
+		j.responses = j.responses[len(j.responses)-maxCookieJarResponses:]
+	}
+}
+
+func (j *sessionJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// GetFrameWithCookieJar runs the url query with the cookie jar, so the session cookies set by the login step of the chain are sent with
+// the query. Cookies are kept in the cache of the datasource, so the next runs reuse the session and skip the steps marked to skip with
+// the session. When the restored session is rejected with 401 or 403, the session is started again with all the steps.
+// Cookie jars are shared by all the users of the datasource, the same way as the credentials of the datasource
+func GetFrameWithCookieJar(ctx context.Context, query models.Query, infClient Client, requestHeaders map[string]string) (*data.Frame, error) {
+	ctx, span := tracing.DefaultTracer().Start(ctx, "GetFrameWithCookieJar")
+	defer span.End()
+	name := strings.TrimSpace(query.CookieJar.Name)
+	if name == "" {
+		name = query.RefID
+	}
+	ttl := defaultCookieJarTTL
+	if query.CookieJar.TTL != "" {
+		d, err := time.ParseDuration(query.CookieJar.TTL)
+		if err != nil || d <= 0 || d > maxCookieJarTTL {
+			return nil, UserError(fmt.Errorf("invalid cookie jar ttl %s. ttl should be a duration up to %s", query.CookieJar.TTL, maxCookieJarTTL))
+		}
+		ttl = d
+	}
+	jarQuery := query
+	jarQuery.CookieJar = nil
+	table := infClient.Cache().Table(cookieJarCacheTable).WithContext(ctx)
+	jar := loadSessionJar(table, name)
+	frame, err := runWithSessionJar(ctx, jarQuery, infClient, requestHeaders, jar)
+	if jar.restored > 0 && isUnauthorizedError(err) {
+		// the session restored from the cache has expired on the server
+		backend.Logger.Debug("cached session is rejected. starting a new session", "jar", name)
+		if derr := table.Delete(name); derr != nil {
+			backend.Logger.Warn("error removing the cached session", "jar", name, "error", derr.Error())
+		}
+		jar = newSessionJar()
+		frame, err = runWithSessionJar(ctx, jarQuery, infClient, requestHeaders, jar)
+	}
+	if err == nil {
+		saveSessionJar(table.WithTTL(ttl), name, jar)
+	}
+	return frame, err
+}
+
+func runWithSessionJar(ctx context.Context, query models.Query, infClient Client, requestHeaders map[string]string, jar *sessionJar) (*data.Frame, error) {
+	httpClient := &http.Client{}
+	if infClient.HttpClient != nil {
+		*httpClient = *infClient.HttpClient
+	}
+	httpClient.Jar = jar
+	infClient.HttpClient = httpClient
+	if jar.restored > 0 {
+		ctx = withCookieSession(ctx)
+	}
+	return GetFrameForURLSources(ctx, query, infClient, requestHeaders)
+}
+
+func loadSessionJar(table *Sett, name string) *sessionJar {
+	jar := newSessionJar()
+	value, err := table.GetStr(name)
+	if err != nil || value == "" {
+		return jar
+	}
+	var responses []sessionCookies
+	if err := json.Unmarshal([]byte(value), &responses); err != nil {
+		backend.Logger.Warn("error reading the cached session", "jar", name, "error", err.Error())
+		return jar
+	}
+	for _, response := range responses {
+		if u, err := url.Parse(response.URL); err == nil {
+			jar.SetCookies(u, response.Cookies)
+		}
+	}
+	jar.restored = len(jar.responses)
+	return jar
+}
+
+// saveSessionJar stores the cookies of the jar, when the responses set any new cookies
+func saveSessionJar(table *Sett, name string, jar *sessionJar) {
+	jar.mu.Lock()
+	defer jar.mu.Unlock()
+	if len(jar.responses) == 0 || len(jar.responses) == jar.restored {
+		return
+	}
+	b, err := json.Marshal(jar.responses)
+	if err == nil {
+		err = table.SetStr(name, string(b))
+	}
+	if err != nil {
+		backend.Logger.Warn("error caching the session", "jar", name, "error", err.Error())
+	}
+}
+
+func isUnauthorizedError(err error) bool {
+	var e classifiedError
+	return errors.As(err, &e) && (e.status == http.StatusUnauthorized || e.status == http.StatusForbidden)
+}
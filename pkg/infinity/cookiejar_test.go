@@ -0,0 +1,82 @@
+package infinity_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestGetFrameWithCookieJar(t *testing.T) {
+	logins, session := 0, ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/login":
+			logins++
+			session = fmt.Sprintf("session-%d", logins)
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: session, Path: "/", HttpOnly: true})
+			fmt.Fprint(w, `{ "ok": true }`)
+		case "/users":
+			if c, err := r.Cookie("sid"); err != nil || c.Value != session {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `[{ "name": "foo" }, { "name": "bar" }]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := infinity.Client{Settings: models.InfinitySettings{URL: server.URL, UID: "cookie-jar-test"}, HttpClient: server.Client()}
+	query := models.Query{
+		RefID:      "A",
+		Source:     "url",
+		Type:       models.QueryTypeJSON,
+		Parser:     models.InfinityParserBackend,
+		URL:        "/users",
+		URLOptions: models.URLOptions{Method: "GET"},
+		Chain:      []models.ChainStep{{Name: "login", URL: "/login", URLOptions: models.URLOptions{Method: "POST"}, SkipWithSession: true}},
+		CookieJar:  &models.CookieJarOptions{TTL: "1h"},
+	}
+	t.Run("should send the cookies set by the login step", func(t *testing.T) {
+		frame, err := infinity.GetFrameForURLSources(context.Background(), query, client, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, 1, logins)
+	})
+	t.Run("should reuse the cached session", func(t *testing.T) {
+		frame, err := infinity.GetFrameForURLSources(context.Background(), query, client, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, 1, logins)
+	})
+	t.Run("should login again when the cached session is rejected", func(t *testing.T) {
+		session = "expired"
+		frame, err := infinity.GetFrameForURLSources(context.Background(), query, client, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, 2, logins)
+		_, err = infinity.GetFrameForURLSources(context.Background(), query, client, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, 2, logins)
+	})
+	t.Run("should not share the cookies without the cookie jar", func(t *testing.T) {
+		q := query
+		q.CookieJar = nil
+		_, err := infinity.GetFrameForURLSources(context.Background(), q, client, map[string]string{})
+		require.ErrorContains(t, err, "401")
+	})
+	t.Run("should validate the ttl", func(t *testing.T) {
+		q := query
+		q.CookieJar = &models.CookieJarOptions{TTL: "48h"}
+		_, err := infinity.GetFrameForURLSources(context.Background(), q, client, map[string]string{})
+		require.ErrorContains(t, err, "invalid cookie jar ttl 48h")
+		require.Equal(t, infinity.ErrorKindUser, infinity.GetErrorKind(err))
+	})
+}
@@ -0,0 +1,436 @@
+package infinity
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+	"golang.org/x/net/html"
+)
+
+const (
+	crawlUserAgent       = "Grafana-Infinity-Crawler"
+	defaultCrawlDepth    = 1
+	maxCrawlDepth        = 3
+	defaultCrawlMaxPages = 50
+	maxCrawlMaxPages     = 500
+	defaultCrawlDelay    = 500 * time.Millisecond
+	minCrawlDelay        = 100 * time.Millisecond
+	maxCrawlDelay        = 10 * time.Second
+	// maxCrawlBodySize is the size of the pages, sitemaps and robots.txt read by the crawler
+	maxCrawlBodySize = 5 << 20
+)
+
+// crawler fetches the pages of the crawl source, one at a time with the politeness delay between the requests
+type crawler struct {
+	client       *http.Client
+	settings     models.InfinitySettings
+	seed         *url.URL
+	delay        time.Duration
+	ignoreRobots bool
+	robots       map[string][]crawlRobotsRule
+	lastRequest  time.Time
+}
+
+// crawlPage is the response of the page
+type crawlPage struct {
+	Status      int
+	Latency     time.Duration
+	ContentType string
+	Body        []byte
+}
+
+// crawlRobotsRule is the allow or disallow rule of robots.txt
+type crawlRobotsRule struct {
+	Path  string
+	Allow bool
+}
+
+// GetFrameForCrawl crawls the url of the query and returns a row per page with the url, status, latency and title columns.
+// Only the pages of the host of the url, and of the allowed hosts when configured, are fetched. robots.txt is honoured unless ignored
+func GetFrameForCrawl(ctx context.Context, query models.Query, infClient Client) (*data.Frame, error) {
+	ctx, span := tracing.DefaultTracer().Start(ctx, "GetFrameForCrawl")
+	defer span.End()
+	options := models.CrawlOptions{}
+	if query.CrawlOptions != nil {
+		options = *query.CrawlOptions
+	}
+	if options.Mode == "" {
+		options.Mode = models.CrawlModeLinks
+	}
+	if options.Mode != models.CrawlModeLinks && options.Mode != models.CrawlModeSitemap {
+		return nil, UserError(fmt.Errorf("invalid crawl mode %s. mode should be links or sitemap", options.Mode))
+	}
+	if options.Depth <= 0 {
+		options.Depth = defaultCrawlDepth
+	}
+	if options.Depth > maxCrawlDepth {
+		return nil, UserError(fmt.Errorf("invalid crawl depth %d. depth should be up to %d", options.Depth, maxCrawlDepth))
+	}
+	if options.MaxPages <= 0 {
+		options.MaxPages = defaultCrawlMaxPages
+	}
+	if options.MaxPages > maxCrawlMaxPages {
+		return nil, UserError(fmt.Errorf("invalid crawl max pages %d. max pages should be up to %d", options.MaxPages, maxCrawlMaxPages))
+	}
+	delay := defaultCrawlDelay
+	if options.Delay != "" {
+		d, err := time.ParseDuration(options.Delay)
+		if err != nil || d < minCrawlDelay || d > maxCrawlDelay {
+			return nil, UserError(fmt.Errorf("invalid crawl delay %s. delay should be a duration between %s and %s", options.Delay, minCrawlDelay, maxCrawlDelay))
+		}
+		delay = d
+	}
+	seedURL := query.URL
+	if !strings.HasPrefix(seedURL, "http://") && !strings.HasPrefix(seedURL, "https://") {
+		seedURL = infClient.Settings.URL + seedURL
+	}
+	seed, err := url.Parse(seedURL)
+	if err != nil || (seed.Scheme != "http" && seed.Scheme != "https") || seed.Host == "" {
+		return nil, UserError(fmt.Errorf("invalid crawl url %s", seedURL))
+	}
+	if !CanAllowURL(seed.String(), infClient.Settings.AllowedHosts) {
+		return nil, UserError(fmt.Errorf("crawl url %s is not in the allowed hosts", seed.String()))
+	}
+	httpClient := infClient.HttpClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	c := &crawler{client: httpClient, settings: infClient.Settings, seed: seed, delay: delay, ignoreRobots: options.IgnoreRobots, robots: map[string][]crawlRobotsRule{}}
+	var rows []any
+	if options.Mode == models.CrawlModeSitemap {
+		rows, err = c.crawlSitemap(ctx, options.Depth, options.MaxPages)
+	} else {
+		rows, err = c.crawlLinks(ctx, options.Depth, options.MaxPages)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rowsQuery := query
+	rowsQuery.RootSelector = ""
+	frame, err := GetJSONBackendResponse(ctx, rows, rowsQuery)
+	if err != nil {
+		return frame, err
+	}
+	return PostProcessFrame(ctx, frame, rowsQuery)
+}
+
+// crawlLinks fetches the pages breadth first from the seed, following the links up to the depth
+func (c *crawler) crawlLinks(ctx context.Context, depth int, maxPages int) ([]any, error) {
+	type queued struct {
+		url    string
+		depth  int
+		parent string
+	}
+	rows := []any{}
+	queue := []queued{{url: c.seed.String()}}
+	seen := map[string]bool{c.seed.String(): true}
+	for len(queue) > 0 && len(rows) < maxPages {
+		next := queue[0]
+		queue = queue[1:]
+		if !c.allowedByRobots(ctx, next.url) {
+			continue
+		}
+		page, err := c.fetch(ctx, next.url)
+		if ctx.Err() != nil {
+			return nil, ContextError(ctx)
+		}
+		row := getCrawlRow(next.url, next.depth, page, err)
+		if next.parent != "" {
+			row["parent"] = next.parent
+		}
+		if err == nil && strings.Contains(page.ContentType, "html") {
+			links := getCrawlLinks(next.url, page.Body)
+			row["links"] = float64(len(links))
+			if next.depth < depth {
+				for _, link := range links {
+					if seen[link] || !c.canCrawl(link) {
+						continue
+					}
+					seen[link] = true
+					queue = append(queue, queued{url: link, depth: next.depth + 1, parent: next.url})
+				}
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// crawlSitemap fetches the pages listed in the sitemap. sitemap indexes are followed up to the depth
+func (c *crawler) crawlSitemap(ctx context.Context, depth int, maxPages int) ([]any, error) {
+	pages := []string{}
+	seen := map[string]bool{}
+	sitemaps := []string{c.seed.String()}
+	for level := 0; level < depth && len(sitemaps) > 0 && len(pages) < maxPages; level++ {
+		children := []string{}
+		for _, sitemap := range sitemaps {
+			page, err := c.fetch(ctx, sitemap)
+			if ctx.Err() != nil {
+				return nil, ContextError(ctx)
+			}
+			if err != nil || page.Status != http.StatusOK {
+				if sitemap == c.seed.String() {
+					return nil, DownstreamError(fmt.Errorf("error fetching the sitemap %s. %w", sitemap, getCrawlError(page, err)), 0)
+				}
+				continue
+			}
+			urls, indexes, err := parseCrawlSitemap(page.Body)
+			if err != nil && sitemap == c.seed.String() {
+				return nil, UserError(fmt.Errorf("invalid sitemap %s. %w", sitemap, err))
+			}
+			for _, u := range urls {
+				if !seen[u] && c.canCrawl(u) && len(pages) < maxPages {
+					seen[u] = true
+					pages = append(pages, u)
+				}
+			}
+			for _, index := range indexes {
+				if c.canCrawl(index) {
+					children = append(children, index)
+				}
+			}
+		}
+		sitemaps = children
+	}
+	rows := []any{}
+	for _, u := range pages {
+		if !c.allowedByRobots(ctx, u) {
+			continue
+		}
+		page, err := c.fetch(ctx, u)
+		if ctx.Err() != nil {
+			return nil, ContextError(ctx)
+		}
+		rows = append(rows, getCrawlRow(u, 1, page, err))
+	}
+	return rows, nil
+}
+
+// canCrawl returns true for the http urls of the seed host. Other hosts are crawled only when they are in the allowed hosts
+func (c *crawler) canCrawl(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	if len(c.settings.AllowedHosts) == 0 {
+		return u.Host == c.seed.Host
+	}
+	return CanAllowURL(link, c.settings.AllowedHosts)
+}
+
+// fetch gets the url after the politeness delay. Credentials of the datasource are sent only to the urls of the datasource
+func (c *crawler) fetch(ctx context.Context, link string) (*crawlPage, error) {
+	if wait := c.delay - time.Since(c.lastRequest); !c.lastRequest.IsZero() && wait > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	defer func() { c.lastRequest = time.Now() }()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.settings.URL != "" && strings.HasPrefix(link, c.settings.URL) {
+		req = ApplyHeadersFromSettings(c.settings, req, true)
+		req = ApplyBasicAuth(c.settings, req, true)
+		req = ApplyBearerToken(c.settings, req, true)
+		req = ApplyApiKeyAuth(c.settings, req, true)
+	}
+	req.Header.Set("User-Agent", crawlUserAgent)
+	start := time.Now()
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxCrawlBodySize))
+	if err != nil {
+		return nil, err
+	}
+	return &crawlPage{Status: res.StatusCode, Latency: time.Since(start), ContentType: res.Header.Get("Content-Type"), Body: body}, nil
+}
+
+// allowedByRobots checks the url against the robots.txt of the host. robots.txt is fetched once per host, and missing robots.txt allows everything
+func (c *crawler) allowedByRobots(ctx context.Context, link string) bool {
+	if c.ignoreRobots {
+		return true
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	origin := u.Scheme + "://" + u.Host
+	rules, ok := c.robots[origin]
+	if !ok {
+		if page, err := c.fetch(ctx, origin+"/robots.txt"); err == nil && page.Status == http.StatusOK {
+			rules = parseCrawlRobots(page.Body)
+		}
+		c.robots[origin] = rules
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	// the longest matching rule wins. allow wins the ties
+	allowed, matched := true, -1
+	for _, rule := range rules {
+		if !strings.HasPrefix(path, rule.Path) || len(rule.Path) < matched || (len(rule.Path) == matched && !rule.Allow) {
+			continue
+		}
+		allowed, matched = rule.Allow, len(rule.Path)
+	}
+	return allowed
+}
+
+// parseCrawlRobots returns the rules of the groups for all the user agents (*) and for the crawler
+func parseCrawlRobots(body []byte) []crawlRobotsRule {
+	rules := []crawlRobotsRule{}
+	applies, inAgents := false, false
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			// consecutive user-agent lines share the group
+			if !inAgents {
+				applies = false
+			}
+			inAgents = true
+			if value == "*" || strings.EqualFold(value, crawlUserAgent) {
+				applies = true
+			}
+		case "allow", "disallow":
+			inAgents = false
+			if applies && value != "" {
+				rules = append(rules, crawlRobotsRule{Path: value, Allow: key == "allow"})
+			}
+		default:
+			inAgents = false
+		}
+	}
+	return rules
+}
+
+// parseCrawlSitemap returns the page urls of the urlset and the sitemap urls of the sitemap index
+func parseCrawlSitemap(body []byte) ([]string, []string, error) {
+	var sitemap struct {
+		XMLName xml.Name
+		URLs    []struct {
+			Loc string `xml:"loc"`
+		} `xml:"url"`
+		Sitemaps []struct {
+			Loc string `xml:"loc"`
+		} `xml:"sitemap"`
+	}
+	if err := xml.Unmarshal(body, &sitemap); err != nil {
+		return nil, nil, err
+	}
+	if sitemap.XMLName.Local != "urlset" && sitemap.XMLName.Local != "sitemapindex" {
+		return nil, nil, fmt.Errorf("unexpected root element %s", sitemap.XMLName.Local)
+	}
+	urls, indexes := []string{}, []string{}
+	for _, u := range sitemap.URLs {
+		urls = append(urls, strings.TrimSpace(u.Loc))
+	}
+	for _, s := range sitemap.Sitemaps {
+		indexes = append(indexes, strings.TrimSpace(s.Loc))
+	}
+	return urls, indexes, nil
+}
+
+// getCrawlLinks returns the distinct absolute urls of the anchors of the page, without the fragments
+func getCrawlLinks(pageURL string, body []byte) []string {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+	links := []string{}
+	seen := map[string]bool{}
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		tt := tokenizer.Next()
+		if tt == html.ErrorToken {
+			return links
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		token := tokenizer.Token()
+		if token.Data != "a" {
+			continue
+		}
+		for _, attr := range token.Attr {
+			if attr.Key != "href" {
+				continue
+			}
+			u, err := base.Parse(strings.TrimSpace(attr.Val))
+			if err != nil {
+				continue
+			}
+			u.Fragment = ""
+			if link := u.String(); !seen[link] {
+				seen[link] = true
+				links = append(links, link)
+			}
+		}
+	}
+}
+
+// getCrawlTitle returns the text of the title element of the page
+func getCrawlTitle(body []byte) string {
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == "title" && tokenizer.Next() == html.TextToken {
+				return strings.TrimSpace(html.UnescapeString(string(tokenizer.Text())))
+			}
+		}
+	}
+}
+
+func getCrawlRow(link string, depth int, page *crawlPage, err error) map[string]any {
+	row := map[string]any{"url": link, "depth": float64(depth)}
+	if err != nil {
+		row["error"] = err.Error()
+		return row
+	}
+	row["status"] = float64(page.Status)
+	row["latency_ms"] = float64(page.Latency.Microseconds()) / 1000
+	row["content_type"] = page.ContentType
+	if strings.Contains(page.ContentType, "html") {
+		row["title"] = getCrawlTitle(page.Body)
+	}
+	return row
+}
+
+func getCrawlError(page *crawlPage, err error) error {
+	if err != nil {
+		return err
+	}
+	return errors.New(http.StatusText(page.Status))
+}
@@ -0,0 +1,95 @@
+package infinity_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestGetFrameForCrawl(t *testing.T) {
+	var mu sync.Mutex
+	requests := []time.Time{}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, time.Now())
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private\nAllow: /private/public\n")
+		case "/":
+			fmt.Fprint(w, `<html><head><title>Home &amp; Docs</title></head><body><a href="/docs#intro">docs</a><a href="missing">missing</a><a href="/private/admin">admin</a><a href="https://example.com/">external</a></body></html>`)
+		case "/docs":
+			fmt.Fprint(w, `<html><head><title>Docs</title></head><body><a href="/docs/deep">deep</a><a href="/">home</a></body></html>`)
+		case "/sitemap.xml":
+			w.Header().Set("Content-Type", "application/xml")
+			fmt.Fprint(w, `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><sitemap><loc>`+server.URL+`/pages.xml</loc></sitemap></sitemapindex>`)
+		case "/pages.xml":
+			w.Header().Set("Content-Type", "application/xml")
+			fmt.Fprint(w, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>`+server.URL+`/docs</loc></url><url><loc>`+server.URL+`/private/public</loc></url></urlset>`)
+		case "/private/public":
+			fmt.Fprint(w, `<html><head><title>Public</title></head></html>`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := infinity.Client{Settings: models.InfinitySettings{}, HttpClient: server.Client()}
+	value := func(frame *data.Frame, name string, row int) any {
+		field, _ := frame.FieldByName(name)
+		require.NotNil(t, field, name)
+		v, _ := field.ConcreteAt(row)
+		return v
+	}
+	t.Run("links", func(t *testing.T) {
+		requests = nil
+		query := models.Query{RefID: "A", Source: "crawl", URL: server.URL + "/", CrawlOptions: &models.CrawlOptions{Delay: "100ms"}}
+		frame, err := infinity.GetFrameForCrawl(context.Background(), query, client)
+		require.Nil(t, err)
+		// robots.txt disallows /private/admin, external links and the links beyond the depth are not followed
+		require.Equal(t, 3, frame.Rows())
+		require.Equal(t, "Home & Docs", value(frame, "title", 0))
+		require.Equal(t, 4.0, value(frame, "links", 0))
+		require.Equal(t, server.URL+"/docs", value(frame, "url", 1))
+		require.Equal(t, 1.0, value(frame, "depth", 1))
+		require.Equal(t, server.URL+"/", value(frame, "parent", 1))
+		require.Equal(t, 404.0, value(frame, "status", 2))
+		for i := 1; i < len(requests); i++ {
+			require.GreaterOrEqual(t, requests[i].Sub(requests[i-1]), 90*time.Millisecond)
+		}
+	})
+	t.Run("sitemap index", func(t *testing.T) {
+		query := models.Query{RefID: "A", Source: "crawl", URL: server.URL + "/sitemap.xml", CrawlOptions: &models.CrawlOptions{Mode: models.CrawlModeSitemap, Depth: 2, Delay: "100ms"}}
+		frame, err := infinity.GetFrameForCrawl(context.Background(), query, client)
+		require.Nil(t, err)
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, "Docs", value(frame, "title", 0))
+		require.Equal(t, "Public", value(frame, "title", 1))
+	})
+	t.Run("max pages", func(t *testing.T) {
+		query := models.Query{RefID: "A", Source: "crawl", URL: server.URL + "/", CrawlOptions: &models.CrawlOptions{Depth: 2, MaxPages: 2, Delay: "100ms", IgnoreRobots: true}}
+		frame, err := infinity.GetFrameForCrawl(context.Background(), query, client)
+		require.Nil(t, err)
+		require.Equal(t, 2, frame.Rows())
+	})
+	t.Run("invalid delay", func(t *testing.T) {
+		query := models.Query{RefID: "A", Source: "crawl", URL: server.URL + "/", CrawlOptions: &models.CrawlOptions{Delay: "10ms"}}
+		_, err := infinity.GetFrameForCrawl(context.Background(), query, client)
+		require.ErrorContains(t, err, "invalid crawl delay")
+	})
+	t.Run("url outside the allowed hosts", func(t *testing.T) {
+		client := infinity.Client{Settings: models.InfinitySettings{AllowedHosts: []string{"https://example.com"}}}
+		query := models.Query{RefID: "A", Source: "crawl", URL: server.URL + "/"}
+		_, err := infinity.GetFrameForCrawl(context.Background(), query, client)
+		require.ErrorContains(t, err, "not in the allowed hosts")
+	})
+}
@@ -10,10 +10,12 @@ import (
 	"github.com/yesoreyeram/grafana-plugins/lib/go/gframer"
 )
 
-func GetCSVBackendResponse(ctx context.Context, responseString string, query models.Query) (*data.Frame, error) {
+func GetCSVBackendResponse(ctx context.Context, responseString string, query models.Query) (frame *data.Frame, err error) {
 	_, span := tracing.DefaultTracer().Start(ctx, "GetCSVBackendResponse")
 	defer span.End()
-	frame := GetDummyFrame(query)
+	defer trackTiming(ctx, parseTiming)()
+	frame = GetDummyFrame(query)
+	defer recoverParserPanic(string(query.Type), &err)
 	columns := []gframer.ColumnSelector{}
 	for _, c := range query.Columns {
 		columns = append(columns, gframer.ColumnSelector{
@@ -23,23 +25,26 @@ func GetCSVBackendResponse(ctx context.Context, responseString string, query mod
 			TimeFormat: c.TimeStampFormat,
 		})
 	}
+	responseString, delimiter, err := normalizeCSV(responseString, query.Type, query.CSVOptions)
+	if err != nil {
+		return frame, err
+	}
 	csvOptions := csvframer.FramerOptions{
 		FrameName:          query.RefID,
 		Columns:            columns,
-		Comment:            query.CSVOptions.Comment,
-		Delimiter:          query.CSVOptions.Delimiter,
+		Delimiter:          delimiter,
 		SkipLinesWithError: query.CSVOptions.SkipLinesWithError,
 		RelaxColumnCount:   query.CSVOptions.RelaxColumnCount,
 	}
+	if len(query.CSVOptions.Comment) == 1 {
+		csvOptions.Comment = query.CSVOptions.Comment
+	}
 	if query.CSVOptions.Columns != "" && query.CSVOptions.Columns != "-" && query.CSVOptions.Columns != "none" {
 		responseString = query.CSVOptions.Columns + "\n" + responseString
 	}
 	if query.CSVOptions.Columns == "-" || query.CSVOptions.Columns == "none" {
 		csvOptions.NoHeaders = true
 	}
-	if query.Type == models.QueryTypeTSV {
-		csvOptions.Delimiter = "\t"
-	}
 	newFrame, err := csvframer.ToFrame(responseString, csvOptions)
 	if newFrame != nil {
 		frame.Fields = append(frame.Fields, newFrame.Fields...)
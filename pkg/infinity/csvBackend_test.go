@@ -0,0 +1,142 @@
+package infinity_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestGetCSVBackendResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		queryType  models.QueryType
+		csvOptions models.InfinityCSVOptions
+		wantNames  []string
+		wantValues []string
+		wantErr    string
+	}{
+		{
+			name:       "should remove the utf-8 bom",
+			input:      "\ufeffname,value\nfoo,1",
+			wantNames:  []string{"name", "value"},
+			wantValues: []string{"foo"},
+		},
+		{
+			name:       "should detect semicolon delimiter",
+			input:      "name;value\n\"foo,bar\";1\nbaz;2",
+			csvOptions: models.InfinityCSVOptions{Delimiter: "auto"},
+			wantNames:  []string{"name", "value"},
+			wantValues: []string{"foo,bar", "baz"},
+		},
+		{
+			name:       "should detect tab delimiter",
+			input:      "name\tvalue\nfoo, bar\t1",
+			csvOptions: models.InfinityCSVOptions{Delimiter: "auto"},
+			wantNames:  []string{"name", "value"},
+			wantValues: []string{"foo, bar"},
+		},
+		{
+			name:       "should detect pipe delimiter",
+			input:      "name|value\nfoo|1\nbar|2",
+			csvOptions: models.InfinityCSVOptions{Delimiter: "auto"},
+			wantNames:  []string{"name", "value"},
+			wantValues: []string{"foo", "bar"},
+		},
+		{
+			name:       "tsv should use tab delimiter",
+			input:      "name\tvalue\nfoo,bar\t1",
+			queryType:  models.QueryTypeTSV,
+			wantNames:  []string{"name", "value"},
+			wantValues: []string{"foo,bar"},
+		},
+		{
+			name:       "should use the escaped tab delimiter",
+			input:      "name\tvalue\nfoo\t1",
+			csvOptions: models.InfinityCSVOptions{Delimiter: `\t`},
+			wantNames:  []string{"name", "value"},
+			wantValues: []string{"foo"},
+		},
+		{
+			name:       "should use the custom quote character",
+			input:      "name,value\n'foo, ''bar''',1\n'baz \"qux\"',2",
+			csvOptions: models.InfinityCSVOptions{Quote: "'"},
+			wantNames:  []string{"name", "value"},
+			wantValues: []string{"foo, 'bar'", `baz "qux"`},
+		},
+		{
+			name:       "should skip the header and footer lines",
+			input:      "report generated at 2023-01-01\n\nname,value\nfoo,1\nbar,2\ntotal rows: 2\n\n",
+			csvOptions: models.InfinityCSVOptions{SkipHeaderLines: 2, SkipFooterLines: 1},
+			wantNames:  []string{"name", "value"},
+			wantValues: []string{"foo", "bar"},
+		},
+		{
+			name:       "should remove the lines with comment prefix",
+			input:      "// comment\nname,value\n//foo,1\nbar,2",
+			csvOptions: models.InfinityCSVOptions{Comment: "//"},
+			wantNames:  []string{"name", "value"},
+			wantValues: []string{"bar"},
+		},
+		{
+			name:       "should tolerate ragged rows when relax column count is set",
+			input:      "name,value\nfoo\nbar,2,3",
+			csvOptions: models.InfinityCSVOptions{RelaxColumnCount: true},
+			wantNames:  []string{"name", "value"},
+			wantValues: []string{"foo", "bar"},
+		},
+		{
+			name:       "should parse the fixed width content",
+			input:      "NAME      VALUE\nfoo bar   1\n\nbaz,qux   22\nshort",
+			csvOptions: models.InfinityCSVOptions{FixedWidths: "10,5", RelaxColumnCount: true},
+			wantNames:  []string{"NAME", "VALUE"},
+			wantValues: []string{"foo bar", "baz,qux", "short"},
+		},
+		{
+			name:       "should parse the fixed width content with the given headers",
+			input:      "foo  1\nbar  2",
+			csvOptions: models.InfinityCSVOptions{FixedWidths: "5, 1", Columns: "name,value"},
+			wantNames:  []string{"name", "value"},
+			wantValues: []string{"foo", "bar"},
+		},
+		{
+			name:       "should throw error for invalid fixed widths",
+			input:      "foo  1",
+			csvOptions: models.InfinityCSVOptions{FixedWidths: "5,x"},
+			wantErr:    `invalid fixed width "x"`,
+		},
+		{
+			name:       "should throw error for unterminated quote",
+			input:      "name,value\n'foo,1",
+			csvOptions: models.InfinityCSVOptions{Quote: "'"},
+			wantErr:    "unterminated quoted value",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queryType := tt.queryType
+			if queryType == "" {
+				queryType = models.QueryTypeCSV
+			}
+			frame, err := infinity.GetCSVBackendResponse(context.Background(), tt.input, models.Query{RefID: "A", Type: queryType, CSVOptions: tt.csvOptions})
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.Nil(t, err)
+			names := []string{}
+			for _, field := range frame.Fields {
+				names = append(names, field.Name)
+			}
+			require.Equal(t, tt.wantNames, names)
+			values := []string{}
+			for i := 0; i < frame.Rows(); i++ {
+				values = append(values, *frame.Fields[0].At(i).(*string))
+			}
+			require.Equal(t, tt.wantValues, values)
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package infinity
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+const csvDelimiterAuto = "auto"
+
+var csvDelimiterCandidates = []rune{',', ';', '\t', '|'}
+
+// normalizeCSV applies the csv options which are not supported by the csv framer and returns the csv content
+// along with the delimiter to be used by the framer
+func normalizeCSV(input string, queryType models.QueryType, options models.InfinityCSVOptions) (string, string, error) {
+	input = strings.TrimPrefix(input, "\ufeff")
+	input = skipCSVLines(input, options.SkipHeaderLines, options.SkipFooterLines)
+	if prefix := options.Comment; len(prefix) > 1 {
+		input = removeCSVCommentLines(input, prefix)
+	}
+	if strings.TrimSpace(options.FixedWidths) != "" {
+		return normalizeFixedWidth(input, options.FixedWidths)
+	}
+	quote := '"'
+	if options.Quote != "" {
+		quote = []rune(options.Quote)[0]
+	}
+	delimiter := options.Delimiter
+	switch {
+	case queryType == models.QueryTypeTSV || delimiter == `\t`:
+		delimiter = "\t"
+	case strings.EqualFold(delimiter, csvDelimiterAuto):
+		delimiter = string(sniffCSVDelimiter(input, quote, options.Comment))
+	}
+	if quote == '"' {
+		return input, delimiter, nil
+	}
+	// encoding/csv only supports double quotes. so re-encode the content with the standard quotes
+	comma := ','
+	if delimiter != "" {
+		comma = []rune(delimiter)[0]
+	}
+	records, err := splitCSVRecords(input, comma, quote)
+	if err != nil {
+		return input, delimiter, err
+	}
+	var out bytes.Buffer
+	w := csv.NewWriter(&out)
+	w.Comma = comma
+	if err := w.WriteAll(records); err != nil {
+		return input, delimiter, err
+	}
+	return out.String(), delimiter, nil
+}
+
+// normalizeFixedWidth converts the fixed width content into csv. widths are the comma separated column widths in characters
+func normalizeFixedWidth(input string, widths string) (string, string, error) {
+	columnWidths := []int{}
+	for _, item := range strings.Split(widths, ",") {
+		width, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil || width <= 0 {
+			return input, ",", fmt.Errorf("invalid fixed width %q. widths must be comma separated positive numbers", item)
+		}
+		columnWidths = append(columnWidths, width)
+	}
+	records := [][]string{}
+	for _, line := range strings.Split(input, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		records = append(records, splitFixedWidthLine(line, columnWidths))
+	}
+	var out bytes.Buffer
+	if err := csv.NewWriter(&out).WriteAll(records); err != nil {
+		return input, ",", err
+	}
+	return out.String(), ",", nil
+}
+
+// splitFixedWidthLine splits the line by the column widths. Values are trimmed and the short lines result in less values
+func splitFixedWidthLine(line string, widths []int) []string {
+	runes := []rune(line)
+	values := []string{}
+	start := 0
+	for _, width := range widths {
+		if start >= len(runes) {
+			break
+		}
+		end := start + width
+		if end > len(runes) {
+			end = len(runes)
+		}
+		values = append(values, strings.TrimSpace(string(runes[start:end])))
+		start = end
+	}
+	return values
+}
+
+// skipCSVLines removes the leading and trailing lines. trailing empty lines are not counted as footer lines
+func skipCSVLines(input string, header int, footer int) string {
+	if header <= 0 && footer <= 0 {
+		return input
+	}
+	lines := strings.Split(strings.TrimRight(input, "\r\n"), "\n")
+	if header > len(lines) {
+		header = len(lines)
+	}
+	lines = lines[header:]
+	if footer > len(lines) {
+		footer = len(lines)
+	}
+	lines = lines[:len(lines)-footer]
+	return strings.Join(lines, "\n")
+}
+
+func removeCSVCommentLines(input string, prefix string) string {
+	lines := []string{}
+	for _, line := range strings.Split(input, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), prefix) {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sniffCSVDelimiter picks the candidate delimiter which appears the same number of times on the first few lines.
+// When no candidate is consistent, the most frequent candidate is used. Defaults to comma
+func sniffCSVDelimiter(input string, quote rune, comment string) rune {
+	lines := []string{}
+	for _, line := range strings.Split(input, "\n") {
+		if strings.TrimSpace(line) == "" || (comment != "" && strings.HasPrefix(strings.TrimSpace(line), comment)) {
+			continue
+		}
+		lines = append(lines, line)
+		if len(lines) == 10 {
+			break
+		}
+	}
+	best, bestCount, bestConsistent := ',', 0, false
+	for _, candidate := range csvDelimiterCandidates {
+		total, consistent := 0, true
+		for i, line := range lines {
+			count := countOutsideQuotes(line, candidate, quote)
+			if i > 0 && count != total/i {
+				consistent = false
+			}
+			total += count
+		}
+		if total == 0 {
+			continue
+		}
+		if (consistent && !bestConsistent) || (consistent == bestConsistent && total > bestCount) {
+			best, bestCount, bestConsistent = candidate, total, consistent
+		}
+	}
+	return best
+}
+
+func countOutsideQuotes(line string, delimiter rune, quote rune) int {
+	count, quoted := 0, false
+	for _, r := range line {
+		switch r {
+		case quote:
+			quoted = !quoted
+		case delimiter:
+			if !quoted {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// splitCSVRecords reads the csv content with the custom quote character. quote characters inside the quoted values are escaped by doubling them
+func splitCSVRecords(input string, delimiter rune, quote rune) ([][]string, error) {
+	records := [][]string{}
+	record := []string{}
+	var field strings.Builder
+	runes := []rune(input)
+	quoted := false
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quoted && r == quote && i+1 < len(runes) && runes[i+1] == quote:
+			field.WriteRune(quote)
+			i++
+		case r == quote && (quoted || field.Len() == 0):
+			quoted = !quoted
+		case quoted:
+			field.WriteRune(r)
+		case r == delimiter:
+			record = append(record, field.String())
+			field.Reset()
+		case r == '\r' && i+1 < len(runes) && runes[i+1] == '\n':
+		case r == '\n' && len(record) == 0 && field.Len() == 0:
+		case r == '\n':
+			record = append(record, field.String())
+			field.Reset()
+			records = append(records, record)
+			record = []string{}
+		default:
+			field.WriteRune(r)
+		}
+	}
+	if quoted {
+		return records, errors.New("error reading csv response. unterminated quoted value")
+	}
+	if field.Len() > 0 || len(record) > 0 {
+		records = append(records, append(record, field.String()))
+	}
+	return records, nil
+}
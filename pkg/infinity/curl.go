@@ -0,0 +1,243 @@
+package infinity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+	"moul.io/http2curl"
+)
+
+// GetCurlCommand returns the curl command of the query. Secrets of the datasource are masked in the command
+func GetCurlCommand(ctx context.Context, settings models.InfinitySettings, query models.Query) (string, error) {
+	if query.Source != "url" {
+		return "", UserError(fmt.Errorf("curl command is not available for the source %s", query.Source))
+	}
+	req, err := GetRequest(ctx, settings, GetQueryBody(query, settings, false), query, map[string]string{}, false)
+	if err != nil {
+		return "", UserError(err)
+	}
+	command, err := http2curl.GetCurlCommand(req)
+	if err != nil {
+		return "", err
+	}
+	return command.String(), nil
+}
+
+// ParseCurlCommand converts the curl command into the infinity query. Credentials (-u and the authorization header) are not imported;
+// the authentication is returned as the hint instead. Unsupported flags are reported as the warnings
+func ParseCurlCommand(command string) (ImportedQuery, error) {
+	imported := ImportedQuery{Name: "curl"}
+	args, err := splitShellArgs(command)
+	if err != nil {
+		return imported, UserError(fmt.Errorf("invalid curl command. %w", err))
+	}
+	if len(args) == 0 || args[0] != "curl" {
+		return imported, UserError(errors.New("invalid curl command. command should start with curl"))
+	}
+	query := models.Query{Type: models.QueryTypeJSON, Format: "table", Source: "url", Parser: models.InfinityParserBackend}
+	method := ""
+	data := []string{}
+	useGet := false
+	dataIsJSON := false
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		value := func() (string, error) {
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("missing value for the flag %s", arg)
+			}
+			i++
+			return args[i], nil
+		}
+		// long flags with = syntax. ex: --request=POST
+		if strings.HasPrefix(arg, "--") && strings.Contains(arg, "=") {
+			name, v, _ := strings.Cut(arg, "=")
+			args = append(args[:i+1], append([]string{v}, args[i+1:]...)...)
+			arg = name
+		}
+		var v string
+		switch arg {
+		case "-X", "--request":
+			v, err = value()
+			method = strings.ToUpper(v)
+		case "-H", "--header":
+			v, err = value()
+			key, val, _ := strings.Cut(v, ":")
+			key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+			switch {
+			case strings.EqualFold(key, headerKeyContentType):
+				query.URLOptions.BodyContentType = val
+			case strings.EqualFold(key, "Authorization"):
+				imported.AuthHint = getCurlAuthHint(val)
+				imported.Warnings = append(imported.Warnings, "authorization header is not imported. configure the authentication in the datasource settings")
+			default:
+				query.URLOptions.Headers = append(query.URLOptions.Headers, models.URLOptionKeyValuePair{Key: key, Value: val})
+			}
+		case "-A", "--user-agent":
+			v, err = value()
+			query.URLOptions.Headers = append(query.URLOptions.Headers, models.URLOptionKeyValuePair{Key: "User-Agent", Value: v})
+		case "-e", "--referer":
+			v, err = value()
+			query.URLOptions.Headers = append(query.URLOptions.Headers, models.URLOptionKeyValuePair{Key: "Referer", Value: v})
+		case "-b", "--cookie":
+			v, err = value()
+			query.URLOptions.Headers = append(query.URLOptions.Headers, models.URLOptionKeyValuePair{Key: "Cookie", Value: v})
+		case "-d", "--data", "--data-raw", "--data-binary", "--data-ascii", "--data-urlencode":
+			v, err = value()
+			if strings.HasPrefix(v, "@") && arg != "--data-raw" {
+				imported.Warnings = append(imported.Warnings, fmt.Sprintf("data file %s is not imported", v))
+				continue
+			}
+			data = append(data, v)
+		case "--json":
+			v, err = value()
+			data = append(data, v)
+			dataIsJSON = true
+		case "-F", "--form":
+			v, err = value()
+			key, val, _ := strings.Cut(v, "=")
+			if strings.HasPrefix(val, "@") || strings.HasPrefix(val, "<") {
+				imported.Warnings = append(imported.Warnings, fmt.Sprintf("form file %s is not imported. configure the file in the datasource settings", key))
+				continue
+			}
+			query.URLOptions.BodyType = "form-data"
+			query.URLOptions.BodyForm = append(query.URLOptions.BodyForm, models.URLOptionKeyValuePair{Key: key, Value: val})
+		case "-u", "--user":
+			_, err = value()
+			imported.AuthHint = models.AuthenticationMethodBasic
+			imported.Warnings = append(imported.Warnings, "user credentials are not imported. configure the basic authentication in the datasource settings")
+		case "-G", "--get":
+			useGet = true
+		case "--url":
+			query.URL, err = value()
+		case "-s", "--silent", "-S", "--show-error", "-k", "--insecure", "-L", "--location", "-v", "--verbose", "-i", "--include", "--compressed", "-g", "--globoff", "-f", "--fail":
+		case "-o", "--output", "-m", "--max-time", "--connect-timeout", "-w", "--write-out", "--retry":
+			_, err = value()
+		default:
+			if strings.HasPrefix(arg, "-") {
+				imported.Warnings = append(imported.Warnings, fmt.Sprintf("flag %s is not supported", arg))
+				continue
+			}
+			query.URL = arg
+		}
+		if err != nil {
+			return imported, UserError(fmt.Errorf("invalid curl command. %w", err))
+		}
+	}
+	if query.URL == "" {
+		return imported, UserError(errors.New("invalid curl command. url not found"))
+	}
+	if useGet && len(data) > 0 {
+		separator := "?"
+		if strings.Contains(query.URL, "?") {
+			separator = "&"
+		}
+		query.URL += separator + strings.Join(data, "&")
+		data = nil
+	}
+	if method == "" {
+		method = http.MethodGet
+		if len(data) > 0 || query.URLOptions.BodyType == "form-data" {
+			method = http.MethodPost
+		}
+	}
+	if method != http.MethodGet && method != http.MethodPost {
+		imported.Warnings = append(imported.Warnings, fmt.Sprintf("method %s is not supported", method))
+	}
+	query.URLOptions.Method = method
+	if len(data) > 0 {
+		query.URLOptions.BodyType = "raw"
+		query.URLOptions.Body = strings.Join(data, "&")
+		if dataIsJSON {
+			query.URLOptions.BodyContentType = "application/json"
+		}
+		if query.URLOptions.BodyContentType == "" || query.URLOptions.BodyContentType == "application/x-www-form-urlencoded" {
+			query.URLOptions.BodyType = "x-www-form-urlencoded"
+			query.URLOptions.BodyContentType = ""
+			query.URLOptions.Body = ""
+			for _, pair := range strings.Split(strings.Join(data, "&"), "&") {
+				key, val, _ := strings.Cut(pair, "=")
+				query.URLOptions.BodyForm = append(query.URLOptions.BodyForm, models.URLOptionKeyValuePair{Key: key, Value: val})
+			}
+		}
+	}
+	imported.Query = query
+	return imported, nil
+}
+
+func getCurlAuthHint(value string) string {
+	scheme, _, _ := strings.Cut(strings.TrimSpace(value), " ")
+	switch strings.ToLower(scheme) {
+	case "basic":
+		return models.AuthenticationMethodBasic
+	case "bearer":
+		return models.AuthenticationMethodBearerToken
+	case "digest":
+		return models.AuthenticationMethodDigestAuth
+	}
+	return models.AuthenticationMethodApiKey
+}
+
+// splitShellArgs splits the command into arguments using the posix shell quoting rules. Line continuations are ignored
+func splitShellArgs(command string) ([]string, error) {
+	args := []string{}
+	current := strings.Builder{}
+	inArg := false
+	var quote rune
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+				continue
+			}
+			current.WriteRune(r)
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+				continue
+			}
+			if r == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\$`\n", runes[i+1]) {
+				i++
+				if runes[i] != '\n' {
+					current.WriteRune(runes[i])
+				}
+				continue
+			}
+			current.WriteRune(r)
+		case r == '\\':
+			if i+1 < len(runes) {
+				i++
+				if runes[i] == '\n' || runes[i] == '\r' {
+					continue
+				}
+				current.WriteRune(runes[i])
+				inArg = true
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,109 @@
+package infinity_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestParseCurlCommand(t *testing.T) {
+	tests := []struct {
+		name         string
+		command      string
+		wantURL      string
+		wantOptions  models.URLOptions
+		wantAuthHint string
+		wantWarnings []string
+		wantErr      string
+	}{
+		{
+			name:        "simple get",
+			command:     `curl https://example.com/users`,
+			wantURL:     "https://example.com/users",
+			wantOptions: models.URLOptions{Method: "GET"},
+		},
+		{
+			name: "post with json body and headers",
+			command: `curl -X POST 'https://example.com/users?page=1' \
+  -H "Content-Type: application/json" \
+  -H 'Accept: application/json' \
+  -H 'Authorization: Bearer abc' \
+  --data-raw '{"name":"foo \"bar\""}' --compressed`,
+			wantURL: "https://example.com/users?page=1",
+			wantOptions: models.URLOptions{
+				Method:          "POST",
+				Headers:         []models.URLOptionKeyValuePair{{Key: "Accept", Value: "application/json"}},
+				BodyType:        "raw",
+				BodyContentType: "application/json",
+				Body:            `{"name":"foo \"bar\""}`,
+			},
+			wantAuthHint: models.AuthenticationMethodBearerToken,
+			wantWarnings: []string{"authorization header is not imported. configure the authentication in the datasource settings"},
+		},
+		{
+			name:    "form url encoded data",
+			command: `curl https://example.com/login -d user=foo -d "scope=read write" -u foo:bar --retry 3 --foo`,
+			wantURL: "https://example.com/login",
+			wantOptions: models.URLOptions{
+				Method:   "POST",
+				BodyType: "x-www-form-urlencoded",
+				BodyForm: []models.URLOptionKeyValuePair{{Key: "user", Value: "foo"}, {Key: "scope", Value: "read write"}},
+			},
+			wantAuthHint: models.AuthenticationMethodBasic,
+			wantWarnings: []string{"user credentials are not imported. configure the basic authentication in the datasource settings", "flag --foo is not supported"},
+		},
+		{
+			name:        "get with data",
+			command:     `curl -G --url=https://example.com/search?x=1 --data-urlencode q=foo`,
+			wantURL:     "https://example.com/search?x=1&q=foo",
+			wantOptions: models.URLOptions{Method: "GET"},
+		},
+		{
+			name:    "multipart form",
+			command: `curl -F kind=csv -F file=@data.csv https://example.com/upload`,
+			wantURL: "https://example.com/upload",
+			wantOptions: models.URLOptions{
+				Method:   "POST",
+				BodyType: "form-data",
+				BodyForm: []models.URLOptionKeyValuePair{{Key: "kind", Value: "csv"}},
+			},
+			wantWarnings: []string{"form file file is not imported. configure the file in the datasource settings"},
+		},
+		{name: "not a curl command", command: `wget https://example.com`, wantErr: "invalid curl command. command should start with curl"},
+		{name: "unterminated quote", command: `curl 'https://example.com`, wantErr: "invalid curl command. unterminated quote"},
+		{name: "missing url", command: `curl -X GET`, wantErr: "invalid curl command. url not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := infinity.ParseCurlCommand(tt.command)
+			if tt.wantErr != "" {
+				require.NotNil(t, err)
+				require.Equal(t, tt.wantErr, err.Error())
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tt.wantURL, got.Query.URL)
+			require.Equal(t, tt.wantOptions, got.Query.URLOptions)
+			require.Equal(t, tt.wantAuthHint, got.AuthHint)
+			require.Equal(t, tt.wantWarnings, got.Warnings)
+		})
+	}
+}
+
+func TestGetCurlCommand(t *testing.T) {
+	settings := models.InfinitySettings{
+		AuthenticationMethod: models.AuthenticationMethodBearerToken,
+		BearerToken:          "my-token",
+	}
+	query := models.Query{Source: "url", URL: "https://example.com/users", URLOptions: models.URLOptions{Method: "POST", BodyType: "raw", Body: `{"a":1}`, BodyContentType: "application/json"}}
+	got, err := infinity.GetCurlCommand(context.Background(), settings, query)
+	require.Nil(t, err)
+	require.Contains(t, got, "curl -X 'POST' -d '{\"a\":1}'")
+	require.Contains(t, got, "-H 'Authorization: Bearer xxxxxxxx'")
+	require.NotContains(t, got, "my-token")
+	_, err = infinity.GetCurlCommand(context.Background(), settings, models.Query{Source: "inline"})
+	require.NotNil(t, err)
+}
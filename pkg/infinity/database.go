@@ -0,0 +1,168 @@
+package infinity
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	_ "github.com/lib/pq"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+// databaseMaxRows is the number of rows returned by the database source. results are truncated beyond it
+const databaseMaxRows = 50000
+
+// databaseDrivers are the supported drivers of the sql connections
+var databaseDrivers = map[string]bool{"postgres": true, "mysql": true}
+
+// databaseConnMaxIdleTime is how long the idle connections of the sql connection pools are kept open
+const databaseConnMaxIdleTime = 5 * time.Minute
+
+// databaseReadOnlyStatements are the statements allowed in the database source
+var databaseReadOnlyStatements = []string{"SELECT", "WITH", "SHOW", "EXPLAIN", "DESCRIBE", "VALUES", "TABLE"}
+
+// GetFrameForDatabase runs the sql query against the sql connection of the datasource.
+// Only the single read only statement is allowed, and it runs in a read only transaction which is always rolled back
+func GetFrameForDatabase(ctx context.Context, query models.Query, infClient Client) (*data.Frame, error) {
+	ctx, span := tracing.DefaultTracer().Start(ctx, "GetFrameForDatabase")
+	defer span.End()
+	connection, err := getSQLConnection(query.SQLConnection, infClient.Settings)
+	if err != nil {
+		return nil, err
+	}
+	statement, err := getReadOnlyStatement(query.SQLQuery)
+	if err != nil {
+		return nil, err
+	}
+	db, release, err := infClient.databases.open(connection)
+	if err != nil {
+		return nil, UserError(fmt.Errorf("invalid sql connection %s. %w", connection.Name, err))
+	}
+	defer release()
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, getDatabaseError(ctx, err)
+	}
+	defer tx.Rollback() //nolint
+	rows, err := tx.QueryContext(ctx, statement)
+	if err != nil {
+		return nil, getDatabaseError(ctx, err)
+	}
+	defer rows.Close()
+	frame, truncated, err := getSQLFrame(rows, databaseMaxRows)
+	if err != nil {
+		return nil, getDatabaseError(ctx, err)
+	}
+	frame.Name = query.RefID
+	frame.Meta = &data.FrameMeta{ExecutedQueryString: statement}
+	if truncated {
+		frame.AppendNotices(data.Notice{Severity: data.NoticeSeverityWarning, Text: fmt.Sprintf("Results are truncated to the first %d rows", databaseMaxRows)})
+	}
+	return PostProcessFrame(ctx, frame, query)
+}
+
+// sqlDatabases are the connection pools of the sql connections of the client, keyed by the driver and the dsn.
+// Pools are shared by the queries of the datasource and closed along with the client
+type sqlDatabases struct {
+	mu  sync.Mutex
+	dbs map[string]*sql.DB
+}
+
+// open returns the connection pool of the sql connection and the func to call once the query is done. Clients not created
+// by NewClient don't have the pools, so the pool is opened per query and closed by the func
+func (d *sqlDatabases) open(connection models.SQLConnection) (*sql.DB, func(), error) {
+	if d == nil {
+		db, err := sql.Open(connection.Driver, connection.DSN)
+		if err != nil {
+			return nil, nil, err
+		}
+		return db, func() { db.Close() }, nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := connection.Driver + "\x00" + connection.DSN
+	if db, ok := d.dbs[key]; ok {
+		return db, func() {}, nil
+	}
+	db, err := sql.Open(connection.Driver, connection.DSN)
+	if err != nil {
+		return nil, nil, err
+	}
+	db.SetConnMaxIdleTime(databaseConnMaxIdleTime)
+	if d.dbs == nil {
+		d.dbs = map[string]*sql.DB{}
+	}
+	d.dbs[key] = db
+	return db, func() {}, nil
+}
+
+// close closes all the connection pools
+func (d *sqlDatabases) close() error {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var errs []error
+	for _, db := range d.dbs {
+		errs = append(errs, db.Close())
+	}
+	d.dbs = nil
+	return errors.Join(errs...)
+}
+
+// getSQLConnection returns the sql connection by the name. the first connection is used when the name is empty
+func getSQLConnection(name string, settings models.InfinitySettings) (models.SQLConnection, error) {
+	if len(settings.SQLConnections) == 0 {
+		return models.SQLConnection{}, UserError(errors.New("no sql connections are configured in the datasource settings"))
+	}
+	for _, connection := range settings.SQLConnections {
+		if connection.Name != name && name != "" {
+			continue
+		}
+		if !databaseDrivers[connection.Driver] {
+			return connection, UserError(fmt.Errorf("invalid driver %s of the sql connection %s. supported drivers are postgres and mysql", connection.Driver, connection.Name))
+		}
+		if strings.TrimSpace(connection.DSN) == "" {
+			return connection, UserError(fmt.Errorf("invalid or empty dsn of the sql connection %s", connection.Name))
+		}
+		return connection, nil
+	}
+	return models.SQLConnection{}, UserError(fmt.Errorf("sql connection %s not found", name))
+}
+
+// getReadOnlyStatement returns the statement without the trailing semicolon. multiple statements and the statements other than the queries are rejected
+func getReadOnlyStatement(statement string) (string, error) {
+	statement = strings.TrimRight(strings.TrimSpace(statement), "; \t\r\n")
+	if statement == "" {
+		return "", UserError(errors.New("invalid or empty sql query"))
+	}
+	if strings.Contains(statement, ";") {
+		return "", UserError(errors.New("multiple sql statements are not allowed"))
+	}
+	fields := strings.Fields(strings.TrimLeft(statement, "("))
+	if len(fields) == 0 {
+		return "", UserError(errors.New("invalid or empty sql query"))
+	}
+	keyword := strings.ToUpper(fields[0])
+	for _, allowed := range databaseReadOnlyStatements {
+		if keyword == allowed {
+			return statement, nil
+		}
+	}
+	return "", UserError(fmt.Errorf("sql statement %s is not allowed. only the read only queries are supported", keyword))
+}
+
+func getDatabaseError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ContextError(ctx)
+	}
+	return DownstreamError(fmt.Errorf("error querying the database. %w", err), 0)
+}
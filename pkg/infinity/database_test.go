@@ -0,0 +1,132 @@
+package infinity_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestGetFrameForDatabase(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	// closed port. connections are refused
+	addr := listener.Addr().String()
+	listener.Close()
+	client := infinity.Client{Settings: models.InfinitySettings{SQLConnections: []models.SQLConnection{
+		{Name: "reference", Driver: "postgres", DSN: "postgres://user:pass@" + addr + "/db?sslmode=disable&connect_timeout=1"},
+		{Name: "legacy", Driver: "sqlserver", DSN: "sqlserver://localhost"},
+		{Name: "empty", Driver: "mysql"},
+	}}}
+	tests := []struct {
+		name       string
+		connection string
+		sql        string
+		wantErr    string
+		wantKind   infinity.ErrorKind
+	}{
+		{name: "write statements are rejected", sql: "DELETE FROM users", wantErr: "sql statement DELETE is not allowed", wantKind: infinity.ErrorKindUser},
+		{name: "multiple statements are rejected", sql: "SELECT 1; DROP TABLE users", wantErr: "multiple sql statements are not allowed", wantKind: infinity.ErrorKindUser},
+		{name: "empty query", sql: " ; ", wantErr: "invalid or empty sql query", wantKind: infinity.ErrorKindUser},
+		{name: "unknown connection", connection: "other", sql: "SELECT 1", wantErr: "sql connection other not found", wantKind: infinity.ErrorKindUser},
+		{name: "unsupported driver", connection: "legacy", sql: "SELECT 1", wantErr: "invalid driver sqlserver of the sql connection legacy", wantKind: infinity.ErrorKindUser},
+		{name: "empty dsn", connection: "empty", sql: "SELECT 1", wantErr: "invalid or empty dsn of the sql connection empty", wantKind: infinity.ErrorKindUser},
+		{name: "first connection is the default", sql: "(SELECT 1);", wantErr: "error querying the database", wantKind: infinity.ErrorKindDownstream},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := models.Query{RefID: "A", Source: "database", SQLConnection: tt.connection, SQLQuery: tt.sql}
+			_, err := infinity.GetFrameForDatabase(context.Background(), query, client)
+			require.ErrorContains(t, err, tt.wantErr)
+			require.Equal(t, tt.wantKind, infinity.GetErrorKind(err))
+		})
+	}
+}
+
+func TestGetFrameForDatabase_ConnectionPool(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer listener.Close()
+	var connections, closed atomic.Int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			connections.Add(1)
+			go func() {
+				defer closed.Add(1)
+				servePostgres(conn)
+			}()
+		}
+	}()
+	client, err := infinity.NewClient(context.Background(), models.InfinitySettings{SQLConnections: []models.SQLConnection{
+		{Name: "reference", Driver: "postgres", DSN: "postgres://user:pass@" + listener.Addr().String() + "/db?sslmode=disable"},
+	}})
+	require.Nil(t, err)
+	query := models.Query{RefID: "A", Source: "database", SQLQuery: "SELECT 1 AS id"}
+	for i := 0; i < 3; i++ {
+		frame, err := infinity.GetFrameForDatabase(context.Background(), query, *client)
+		require.Nil(t, err)
+		require.Equal(t, 1, frame.Rows())
+	}
+	// the queries of the client share the connection pool
+	require.Equal(t, int32(1), connections.Load())
+	require.Nil(t, client.Close())
+	require.Eventually(t, func() bool { return closed.Load() == 1 }, time.Second, 10*time.Millisecond)
+}
+
+// servePostgres answers the simple queries of the postgres protocol with a single row, until the client terminates the connection
+func servePostgres(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	write := func(kind byte, body []byte) {
+		msg := append([]byte{kind}, binary.BigEndian.AppendUint32(nil, uint32(len(body)+4))...)
+		conn.Write(append(msg, body...)) //nolint
+	}
+	var size uint32
+	if binary.Read(r, binary.BigEndian, &size) != nil {
+		return
+	}
+	if _, err := io.CopyN(io.Discard, r, int64(size)-4); err != nil {
+		return
+	}
+	write('R', binary.BigEndian.AppendUint32(nil, 0))
+	write('Z', []byte{'I'})
+	for {
+		kind, err := r.ReadByte()
+		if err != nil || binary.Read(r, binary.BigEndian, &size) != nil {
+			return
+		}
+		body := make([]byte, size-4)
+		if _, err := io.ReadFull(r, body); err != nil || kind != 'Q' {
+			return
+		}
+		statement := strings.TrimRight(string(body), "\x00")
+		switch {
+		case strings.HasPrefix(statement, "BEGIN"):
+			write('C', []byte("BEGIN\x00"))
+			write('Z', []byte{'T'})
+		case strings.HasPrefix(statement, "ROLLBACK"):
+			write('C', []byte("ROLLBACK\x00"))
+			write('Z', []byte{'I'})
+		default:
+			// single text column named id
+			field := append([]byte("id\x00"), 0, 0, 0, 0, 0, 0, 0, 0, 0, 25, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0, 0)
+			write('T', append([]byte{0, 1}, field...))
+			write('D', []byte{0, 1, 0, 0, 0, 1, '1'})
+			write('C', []byte("SELECT 1\x00"))
+			write('Z', []byte{'T'})
+		}
+	}
+}
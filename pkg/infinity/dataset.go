@@ -0,0 +1,122 @@
+package infinity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v3"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+const (
+	datasetCacheTable = "datasets"
+	// DatasetSourcePrefix is the prefix of the query source which reads the materialized dataset
+	DatasetSourcePrefix = "dataset:"
+)
+
+// Dataset is the materialized frame of a query, reusable by the other queries of the datasource
+type Dataset struct {
+	Name      string       `json:"name"`
+	Query     models.Query `json:"query"`
+	Frame     []byte       `json:"-"`
+	Rows      int          `json:"rows"`
+	UpdatedAt time.Time    `json:"updatedAt"`
+	ExpiresAt time.Time    `json:"expiresAt,omitempty"`
+}
+
+// GetDatasetName returns the name of the dataset referenced by the query source
+func GetDatasetName(query models.Query) (string, bool) {
+	name, ok := strings.CutPrefix(query.Source, DatasetSourcePrefix)
+	return strings.TrimSpace(name), ok
+}
+
+// SaveDataset materializes the frame of the query under the name. Zero ttl keeps the dataset until deleted
+func SaveDataset(ctx context.Context, infClient Client, name string, query models.Query, frame *data.Frame, ttl time.Duration) error {
+	_, span := tracing.DefaultTracer().Start(ctx, "SaveDataset")
+	defer span.End()
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("invalid or empty dataset name")
+	}
+	if frame == nil {
+		return fmt.Errorf("no data to materialize as dataset %s", name)
+	}
+	frameBytes, err := frame.MarshalArrow()
+	if err != nil {
+		return err
+	}
+	dataset := &Dataset{Name: name, Query: query, Frame: frameBytes, Rows: frame.Rows(), UpdatedAt: time.Now()}
+	if ttl > 0 {
+		dataset.ExpiresAt = dataset.UpdatedAt.Add(ttl)
+	}
+	return infClient.Cache().Table(datasetCacheTable).WithContext(ctx).SetWithTTL(name, dataset, ttl)
+}
+
+// GetDataset returns the dataset stored under the name
+func GetDataset(ctx context.Context, infClient Client, name string) (*Dataset, error) {
+	res, err := infClient.Cache().Table(datasetCacheTable).WithContext(ctx).GetStruct(name)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, fmt.Errorf("dataset %s not found or expired", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	dataset, ok := res.(*Dataset)
+	if !ok || dataset == nil {
+		return nil, fmt.Errorf("invalid dataset %s", name)
+	}
+	return dataset, nil
+}
+
+// GetFrameForDataset returns the frame of the dataset referenced by the query source
+func GetFrameForDataset(ctx context.Context, query models.Query, infClient Client) (*data.Frame, error) {
+	ctx, span := tracing.DefaultTracer().Start(ctx, "GetFrameForDataset")
+	defer span.End()
+	name, _ := GetDatasetName(query)
+	dataset, err := GetDataset(ctx, infClient, name)
+	if err != nil {
+		return nil, err
+	}
+	frame, err := data.UnmarshalArrowFrame(dataset.Frame)
+	if err != nil {
+		return nil, fmt.Errorf("error reading dataset %s. %w", name, err)
+	}
+	frame.Name = query.RefID
+	return frame, nil
+}
+
+// ListDatasets returns the datasets of the datasource sorted by name. Frames are not included
+func ListDatasets(ctx context.Context, infClient Client) ([]Dataset, error) {
+	table := infClient.Cache().Table(datasetCacheTable).WithContext(ctx)
+	keys, err := table.Keys()
+	if err != nil {
+		return nil, err
+	}
+	out := []Dataset{}
+	for _, key := range keys {
+		res, err := table.GetStruct(key)
+		if err != nil {
+			continue
+		}
+		if dataset, ok := res.(*Dataset); ok && dataset != nil {
+			dataset.Frame = nil
+			out = append(out, *dataset)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// DeleteDataset removes the dataset
+func DeleteDataset(ctx context.Context, infClient Client, name string) error {
+	if _, err := GetDataset(ctx, infClient, name); err != nil {
+		return err
+	}
+	return infClient.Cache().Table(datasetCacheTable).WithContext(ctx).Delete(name)
+}
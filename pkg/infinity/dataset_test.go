@@ -0,0 +1,45 @@
+package infinity_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestDatasets(t *testing.T) {
+	ctx := context.Background()
+	client, err := infinity.NewClient(ctx, models.InfinitySettings{OrgID: 1, UID: "datasets"})
+	require.Nil(t, err)
+	frame := data.NewFrame("users", data.NewField("name", nil, []string{"foo", "bar"}))
+	require.Nil(t, infinity.SaveDataset(ctx, *client, "users", models.Query{RefID: "A", URL: "https://example.com"}, frame, time.Minute))
+	require.NotNil(t, infinity.SaveDataset(ctx, *client, " ", models.Query{}, frame, 0))
+
+	got, err := infinity.GetFrameForDataset(ctx, models.Query{RefID: "B", Source: "dataset:users"}, *client)
+	require.Nil(t, err)
+	require.Equal(t, "B", got.Name)
+	require.Equal(t, 2, got.Rows())
+	require.Equal(t, "bar", got.Fields[0].At(1))
+
+	datasets, err := infinity.ListDatasets(ctx, *client)
+	require.Nil(t, err)
+	require.Len(t, datasets, 1)
+	require.Equal(t, "users", datasets[0].Name)
+	require.Equal(t, 2, datasets[0].Rows)
+	require.Equal(t, "https://example.com", datasets[0].Query.URL)
+	require.False(t, datasets[0].ExpiresAt.IsZero())
+
+	other, err := infinity.NewClient(ctx, models.InfinitySettings{OrgID: 2, UID: "datasets"})
+	require.Nil(t, err)
+	_, err = infinity.GetFrameForDataset(ctx, models.Query{Source: "dataset:users"}, *other)
+	require.NotNil(t, err)
+
+	require.Nil(t, infinity.DeleteDataset(ctx, *client, "users"))
+	_, err = infinity.GetFrameForDataset(ctx, models.Query{Source: "dataset:users"}, *client)
+	require.NotNil(t, err)
+	require.NotNil(t, infinity.DeleteDataset(ctx, *client, "users"))
+}
@@ -0,0 +1,38 @@
+package infinity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+// WithQueryDeadline returns the context of the query, which expires when the upstream requests of the query can't complete
+// within the timeout of the datasource. Paginated queries get the timeout for each page. Earlier deadline of the incoming
+// request is kept, so the query is also cancelled when grafana cancels the request. ex: user navigates away from the dashboard
+func WithQueryDeadline(ctx context.Context, settings models.InfinitySettings, query models.Query) (context.Context, context.CancelFunc) {
+	timeout := time.Duration(settings.TimeoutInSeconds) * time.Second
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	if query.PageMode != "" && query.PageMode != models.PaginationModeNone && query.PageMaxPages > 1 {
+		timeout *= time.Duration(query.PageMaxPages)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// ContextError returns the classified error when the query context is cancelled or expired. Returns nil otherwise
+func ContextError(ctx context.Context) error {
+	err := ctx.Err()
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, context.DeadlineExceeded):
+		return DownstreamError(fmt.Errorf("query timed out. %w", err), http.StatusGatewayTimeout)
+	default:
+		return UserError(fmt.Errorf("query cancelled. %w", err))
+	}
+}
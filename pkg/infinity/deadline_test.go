@@ -0,0 +1,72 @@
+package infinity_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestWithQueryDeadline(t *testing.T) {
+	t.Run("should use the timeout of the datasource for each page", func(t *testing.T) {
+		ctx, cancel := infinity.WithQueryDeadline(context.Background(), models.InfinitySettings{TimeoutInSeconds: 10}, models.Query{PageMode: models.PaginationModePage, PageMaxPages: 3})
+		defer cancel()
+		deadline, ok := ctx.Deadline()
+		require.True(t, ok)
+		require.True(t, time.Until(deadline) > 29*time.Second && time.Until(deadline) <= 30*time.Second)
+	})
+	t.Run("should keep the earlier deadline of the request", func(t *testing.T) {
+		parent, parentCancel := context.WithTimeout(context.Background(), time.Second)
+		defer parentCancel()
+		ctx, cancel := infinity.WithQueryDeadline(parent, models.InfinitySettings{TimeoutInSeconds: 10}, models.Query{})
+		defer cancel()
+		deadline, _ := ctx.Deadline()
+		parentDeadline, _ := parent.Deadline()
+		require.Equal(t, parentDeadline, deadline)
+	})
+}
+
+func TestContextError(t *testing.T) {
+	require.Nil(t, infinity.ContextError(context.Background()))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Equal(t, infinity.ErrorKindUser, infinity.GetErrorKind(infinity.ContextError(ctx)))
+	ctx, cancel = context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	require.Equal(t, infinity.ErrorKindDownstream, infinity.GetErrorKind(infinity.ContextError(ctx)))
+}
+
+func TestQueryCancellation(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, `[{ "id" : 1 }`)
+		w.(http.Flusher).Flush()
+		// body never completes unless the client aborts the download
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+	}))
+	defer server.Close()
+	client, err := infinity.NewClient(context.TODO(), models.InfinitySettings{})
+	require.Nil(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	query := models.Query{Type: models.QueryTypeJSON, Source: "url", Parser: models.InfinityParserBackend, URL: server.URL, URLOptions: models.URLOptions{Method: http.MethodGet},
+		PageMode: models.PaginationModePage, PageMaxPages: 5, PageParamPageFieldName: "page", PageErrorMode: models.PaginationErrorModeBestEffort}
+	_, err = infinity.GetFrameForURLSources(ctx, query, *client, map[string]string{})
+	require.NotNil(t, err)
+	require.ErrorContains(t, err, "query timed out")
+	require.Less(t, time.Since(start), 5*time.Second)
+	require.Equal(t, int32(1), requests.Load())
+}
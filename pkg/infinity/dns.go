@@ -0,0 +1,168 @@
+package infinity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/miekg/dns"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+// maxDNSLookups is the number of the names multiplied by the record types allowed in the single query
+const maxDNSLookups = 100
+
+// dnsRecordTypes are the record types supported by the dns source
+var dnsRecordTypes = map[string]uint16{
+	"A":     dns.TypeA,
+	"AAAA":  dns.TypeAAAA,
+	"TXT":   dns.TypeTXT,
+	"MX":    dns.TypeMX,
+	"SRV":   dns.TypeSRV,
+	"CNAME": dns.TypeCNAME,
+	"NS":    dns.TypeNS,
+}
+
+// GetFrameForDNS looks up the record types of every name against the resolver and returns the answers with the ttl as the rows.
+// Names without the answers return a single row with the status, so the failing names are visible in the health dashboards.
+// When the allowed hosts are configured, the resolver must match one of them as dns://host:port
+func GetFrameForDNS(ctx context.Context, query models.Query, infClient Client) (*data.Frame, error) {
+	ctx, span := tracing.DefaultTracer().Start(ctx, "GetFrameForDNS")
+	defer span.End()
+	if query.DNSOptions == nil || len(query.DNSOptions.Names) == 0 {
+		return nil, UserError(errors.New("at least one name is required for the dns query"))
+	}
+	options := *query.DNSOptions
+	if len(options.RecordTypes) == 0 {
+		options.RecordTypes = []string{"A"}
+	}
+	for _, recordType := range options.RecordTypes {
+		if _, ok := dnsRecordTypes[strings.ToUpper(recordType)]; !ok {
+			return nil, UserError(fmt.Errorf("invalid dns record type %s. supported record types are A, AAAA, TXT, MX, SRV, CNAME and NS", recordType))
+		}
+	}
+	if len(options.Names)*len(options.RecordTypes) > maxDNSLookups {
+		return nil, UserError(fmt.Errorf("too many dns lookups. names multiplied by the record types should be up to %d", maxDNSLookups))
+	}
+	resolver, err := getDNSResolver(options.Resolver)
+	if err != nil {
+		return nil, err
+	}
+	if !CanAllowURL("dns://"+resolver, infClient.Settings.AllowedHosts) {
+		return nil, UserError(fmt.Errorf("dns resolver %s is not in the allowed hosts", resolver))
+	}
+	timeout := time.Duration(infClient.Settings.TimeoutInSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	client := &dns.Client{Timeout: timeout}
+	rows := []any{}
+	failures := 0
+	var lookupErr error
+	for _, name := range options.Names {
+		name = strings.TrimSpace(name)
+		for _, recordType := range options.RecordTypes {
+			recordType = strings.ToUpper(recordType)
+			answers, err := lookupDNS(ctx, client, resolver, name, dnsRecordTypes[recordType])
+			if ctx.Err() != nil {
+				return nil, ContextError(ctx)
+			}
+			if err != nil {
+				failures++
+				lookupErr = err
+				rows = append(rows, map[string]any{"name": name, "type": recordType, "status": "ERROR", "error": err.Error(), "resolver": resolver})
+				continue
+			}
+			rows = append(rows, answers...)
+		}
+	}
+	if failures == len(options.Names)*len(options.RecordTypes) {
+		return nil, DownstreamError(fmt.Errorf("error querying the dns resolver %s. %w", resolver, lookupErr), 0)
+	}
+	rowsQuery := query
+	rowsQuery.RootSelector = ""
+	frame, err := GetJSONBackendResponse(ctx, rows, rowsQuery)
+	if err != nil {
+		return frame, err
+	}
+	return PostProcessFrame(ctx, frame, rowsQuery)
+}
+
+// getDNSResolver returns the host:port of the resolver. the first nameserver of /etc/resolv.conf is used when the resolver is empty
+func getDNSResolver(resolver string) (string, error) {
+	resolver = strings.TrimSpace(resolver)
+	if resolver == "" {
+		config, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+		if err != nil || len(config.Servers) == 0 {
+			return "", UserError(errors.New("dns resolver is required. system resolver is not available"))
+		}
+		return net.JoinHostPort(config.Servers[0], config.Port), nil
+	}
+	if _, _, err := net.SplitHostPort(resolver); err != nil {
+		return net.JoinHostPort(strings.Trim(resolver, "[]"), "53"), nil
+	}
+	return resolver, nil
+}
+
+// lookupDNS returns the rows of the answers. the query is retried over tcp when the udp response is truncated
+func lookupDNS(ctx context.Context, client *dns.Client, resolver string, name string, recordType uint16) ([]any, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), recordType)
+	msg.RecursionDesired = true
+	start := time.Now()
+	response, _, err := client.ExchangeContext(ctx, msg, resolver)
+	if err == nil && response.Truncated {
+		tcpClient := *client
+		tcpClient.Net = "tcp"
+		response, _, err = tcpClient.ExchangeContext(ctx, msg, resolver)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rtt := float64(time.Since(start).Microseconds()) / 1000
+	status := dns.RcodeToString[response.Rcode]
+	rows := []any{}
+	for _, rr := range response.Answer {
+		header := rr.Header()
+		row := map[string]any{
+			"name":     name,
+			"type":     dns.TypeToString[header.Rrtype],
+			"status":   status,
+			"ttl":      float64(header.Ttl),
+			"resolver": resolver,
+			"rtt_ms":   rtt,
+		}
+		switch record := rr.(type) {
+		case *dns.A:
+			row["answer"] = record.A.String()
+		case *dns.AAAA:
+			row["answer"] = record.AAAA.String()
+		case *dns.TXT:
+			row["answer"] = strings.Join(record.Txt, "")
+		case *dns.MX:
+			row["answer"] = record.Mx
+			row["priority"] = float64(record.Preference)
+		case *dns.SRV:
+			row["answer"] = fmt.Sprintf("%s:%d", record.Target, record.Port)
+			row["priority"] = float64(record.Priority)
+			row["weight"] = float64(record.Weight)
+			row["port"] = float64(record.Port)
+		case *dns.CNAME:
+			row["answer"] = record.Target
+		case *dns.NS:
+			row["answer"] = record.Ns
+		default:
+			row["answer"] = strings.TrimPrefix(rr.String(), header.String())
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		rows = append(rows, map[string]any{"name": name, "type": dns.TypeToString[recordType], "status": status, "resolver": resolver, "rtt_ms": rtt})
+	}
+	return rows, nil
+}
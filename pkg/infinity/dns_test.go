@@ -0,0 +1,90 @@
+package infinity_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestGetFrameForDNS(t *testing.T) {
+	resolver := startDNSServer(t, map[string][]string{
+		"example.com. A":             {"example.com. 300 IN A 93.184.216.34"},
+		"example.com. MX":            {"example.com. 3600 IN MX 10 mail.example.com.", "example.com. 3600 IN MX 20 backup.example.com."},
+		"example.com. TXT":           {`example.com. 120 IN TXT "v=spf1 " "-all"`},
+		"_sip._tcp.example.com. SRV": {"_sip._tcp.example.com. 60 IN SRV 5 10 5060 sip.example.com."},
+	})
+	client := infinity.Client{Settings: models.InfinitySettings{TimeoutInSeconds: 2}}
+	value := func(frame *data.Frame, name string, row int) any {
+		field, _ := frame.FieldByName(name)
+		require.NotNil(t, field, name)
+		v, _ := field.ConcreteAt(row)
+		return v
+	}
+	t.Run("lookups", func(t *testing.T) {
+		query := models.Query{RefID: "A", Source: "dns", DNSOptions: &models.DNSOptions{Names: []string{"example.com", "missing.example.com"}, RecordTypes: []string{"a", "MX"}, Resolver: resolver}}
+		frame, err := infinity.GetFrameForDNS(context.Background(), query, client)
+		require.Nil(t, err)
+		require.Equal(t, 5, frame.Rows())
+		require.Equal(t, "93.184.216.34", value(frame, "answer", 0))
+		require.Equal(t, 300.0, value(frame, "ttl", 0))
+		require.Equal(t, "backup.example.com.", value(frame, "answer", 2))
+		require.Equal(t, 20.0, value(frame, "priority", 2))
+		require.Equal(t, "missing.example.com", value(frame, "name", 3))
+		require.Equal(t, "NXDOMAIN", value(frame, "status", 3))
+	})
+	t.Run("txt and srv", func(t *testing.T) {
+		query := models.Query{RefID: "A", Source: "dns", DNSOptions: &models.DNSOptions{Names: []string{"example.com"}, RecordTypes: []string{"TXT"}, Resolver: resolver}}
+		frame, err := infinity.GetFrameForDNS(context.Background(), query, client)
+		require.Nil(t, err)
+		require.Equal(t, "v=spf1 -all", value(frame, "answer", 0))
+		query.DNSOptions = &models.DNSOptions{Names: []string{"_sip._tcp.example.com"}, RecordTypes: []string{"SRV"}, Resolver: resolver}
+		frame, err = infinity.GetFrameForDNS(context.Background(), query, client)
+		require.Nil(t, err)
+		require.Equal(t, "sip.example.com.:5060", value(frame, "answer", 0))
+		require.Equal(t, 5060.0, value(frame, "port", 0))
+	})
+	t.Run("invalid record type", func(t *testing.T) {
+		query := models.Query{RefID: "A", Source: "dns", DNSOptions: &models.DNSOptions{Names: []string{"example.com"}, RecordTypes: []string{"PTR"}, Resolver: resolver}}
+		_, err := infinity.GetFrameForDNS(context.Background(), query, client)
+		require.ErrorContains(t, err, "invalid dns record type PTR")
+	})
+	t.Run("resolver outside the allowed hosts", func(t *testing.T) {
+		client := infinity.Client{Settings: models.InfinitySettings{AllowedHosts: []string{"dns://10.0.0.53:53"}}}
+		query := models.Query{RefID: "A", Source: "dns", DNSOptions: &models.DNSOptions{Names: []string{"example.com"}, Resolver: resolver}}
+		_, err := infinity.GetFrameForDNS(context.Background(), query, client)
+		require.ErrorContains(t, err, "not in the allowed hosts")
+	})
+}
+
+// startDNSServer answers the questions from the records keyed by the name and the type. ex: example.com. A
+func startDNSServer(t *testing.T, records map[string][]string) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err)
+	started := make(chan struct{})
+	server := &dns.Server{PacketConn: conn, NotifyStartedFunc: func() { close(started) }, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		question := r.Question[0]
+		answers, ok := records[question.Name+" "+dns.TypeToString[question.Qtype]]
+		if !ok {
+			m.Rcode = dns.RcodeNameError
+		}
+		for _, answer := range answers {
+			rr, err := dns.NewRR(answer)
+			require.Nil(t, err)
+			m.Answer = append(m.Answer, rr)
+		}
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	<-started
+	t.Cleanup(func() { _ = server.Shutdown() })
+	return conn.LocalAddr().String()
+}
@@ -0,0 +1,193 @@
+package infinity
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+// unixSocketBaseURL is the url of the requests sent over the unix socket. docker and podman ignore the host of the request
+const unixSocketBaseURL = "http://localhost"
+
+// getUnixSocketPath returns the socket of the unix:// url of the datasource. ex: unix:///var/run/docker.sock
+func getUnixSocketPath(settingsURL string) (string, bool) {
+	if !strings.HasPrefix(settingsURL, "unix://") {
+		return "", false
+	}
+	path := strings.TrimPrefix(settingsURL, "unix://")
+	return path, path != ""
+}
+
+// applyUnixSocket dials the unix socket of the datasource url for every request, irrespective of the host of the request
+func applyUnixSocket(transport *http.Transport, settings models.InfinitySettings) {
+	socketPath, ok := getUnixSocketPath(settings.URL)
+	if !ok {
+		return
+	}
+	dialer := &net.Dialer{}
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, "unix", socketPath)
+	}
+}
+
+// resolveDockerHost returns the http url of the DOCKER_HOST style urls of the datasource.
+// unix:// is served by the socket dialer and tcp:// uses https when the TLS client or CA certificates are configured
+func resolveDockerHost(settings models.InfinitySettings) string {
+	if _, ok := getUnixSocketPath(settings.URL); ok {
+		return unixSocketBaseURL
+	}
+	if host, ok := strings.CutPrefix(settings.URL, "tcp://"); ok {
+		if settings.TLSClientAuth || settings.TLSAuthWithCACert {
+			return "https://" + host
+		}
+		return "http://" + host
+	}
+	return settings.URL
+}
+
+// getDockerStatsRow returns the usage of the container from the /containers/<id>/stats response, calculated the same way as docker stats
+func getDockerStatsRow(stats map[string]any) map[string]any {
+	cpuDelta := getDockerNumber(stats, "cpu_stats.cpu_usage.total_usage") - getDockerNumber(stats, "precpu_stats.cpu_usage.total_usage")
+	systemDelta := getDockerNumber(stats, "cpu_stats.system_cpu_usage") - getDockerNumber(stats, "precpu_stats.system_cpu_usage")
+	onlineCPUs := getDockerNumber(stats, "cpu_stats.online_cpus")
+	if onlineCPUs == 0 {
+		perCPU, _ := getDockerValue(stats, "cpu_stats.cpu_usage.percpu_usage").([]any)
+		onlineCPUs = float64(len(perCPU))
+	}
+	cpuPercent := 0.0
+	if cpuDelta > 0 && systemDelta > 0 {
+		cpuPercent = cpuDelta / systemDelta * onlineCPUs * 100
+	}
+	// page cache is excluded from the usage. inactive_file on cgroup v2, total_inactive_file on cgroup v1
+	memoryUsage := getDockerNumber(stats, "memory_stats.usage")
+	for _, k := range []string{"memory_stats.stats.inactive_file", "memory_stats.stats.total_inactive_file"} {
+		if v := getDockerNumber(stats, k); v > 0 && v < memoryUsage {
+			memoryUsage -= v
+			break
+		}
+	}
+	memoryLimit := getDockerNumber(stats, "memory_stats.limit")
+	memoryPercent := 0.0
+	if memoryLimit > 0 {
+		memoryPercent = memoryUsage / memoryLimit * 100
+	}
+	var rxBytes, txBytes float64
+	networks, _ := stats["networks"].(map[string]any)
+	for _, n := range networks {
+		network, _ := n.(map[string]any)
+		rxBytes += getDockerNumber(network, "rx_bytes")
+		txBytes += getDockerNumber(network, "tx_bytes")
+	}
+	var readBytes, writeBytes float64
+	entries, _ := getDockerValue(stats, "blkio_stats.io_service_bytes_recursive").([]any)
+	for _, e := range entries {
+		entry, _ := e.(map[string]any)
+		op, _ := entry["op"].(string)
+		switch strings.ToLower(op) {
+		case "read":
+			readBytes += getDockerNumber(entry, "value")
+		case "write":
+			writeBytes += getDockerNumber(entry, "value")
+		}
+	}
+	name, _ := stats["name"].(string)
+	return map[string]any{
+		"read":           stats["read"],
+		"id":             stats["id"],
+		"name":           strings.TrimPrefix(name, "/"),
+		"cpu_percent":    cpuPercent,
+		"memory_usage":   memoryUsage,
+		"memory_limit":   memoryLimit,
+		"memory_percent": memoryPercent,
+		"network_rx":     rxBytes,
+		"network_tx":     txBytes,
+		"block_read":     readBytes,
+		"block_write":    writeBytes,
+		"pids":           getDockerNumber(stats, "pids_stats.current"),
+	}
+}
+
+// flattenDockerItem converts the container and image summaries into rows. Names and the lists are joined and Created becomes the time.
+// Nested objects such as HostConfig and NetworkSettings are retained, so the columns can still select them with their path
+func flattenDockerItem(item any) map[string]any {
+	in, _ := item.(map[string]any)
+	row := make(map[string]any, len(in))
+	for k, v := range in {
+		switch value := v.(type) {
+		case []any:
+			row[k] = formatDockerList(k, value)
+		case map[string]any:
+			row[k] = value
+			if k == "Labels" {
+				row[k] = formatDockerLabels(value)
+			}
+		default:
+			row[k] = value
+		}
+	}
+	if created, ok := row["Created"].(float64); ok {
+		row["Created"] = time.Unix(int64(created), 0).UTC().Format(time.RFC3339Nano)
+	}
+	return row
+}
+
+func formatDockerList(key string, values []any) any {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		switch value := v.(type) {
+		case string:
+			out = append(out, strings.TrimPrefix(value, "/"))
+		case map[string]any:
+			if key != "Ports" {
+				return values
+			}
+			out = append(out, formatDockerPort(value))
+		default:
+			return values
+		}
+	}
+	return strings.Join(out, ", ")
+}
+
+// formatDockerPort formats the port the same way as docker ps. ex: 0.0.0.0:8080->80/tcp
+func formatDockerPort(port map[string]any) string {
+	private := fmt.Sprintf("%v/%v", port["PrivatePort"], port["Type"])
+	if _, ok := port["PublicPort"]; !ok {
+		return private
+	}
+	ip, _ := port["IP"].(string)
+	return fmt.Sprintf("%s:%v->%s", ip, port["PublicPort"], private)
+}
+
+func formatDockerLabels(labels map[string]any) string {
+	out := make([]string, 0, len(labels))
+	for k, v := range labels {
+		out = append(out, fmt.Sprintf("%s=%v", k, v))
+	}
+	sort.Strings(out)
+	return strings.Join(out, ", ")
+}
+
+func getDockerValue(in map[string]any, path string) any {
+	var value any = in
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
+
+func getDockerNumber(in map[string]any, path string) float64 {
+	v, _ := getDockerValue(in, path).(float64)
+	return v
+}
@@ -0,0 +1,89 @@
+package infinity_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestDockerPreset(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socketPath)
+	require.Nil(t, err)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1.43/containers/json":
+			_, _ = w.Write([]byte(`[{
+				"Id": "abc", "Names": ["/web"], "Image": "nginx", "State": "running", "Created": 1672531200,
+				"Ports": [{ "IP": "0.0.0.0", "PrivatePort": 80, "PublicPort": 8080, "Type": "tcp" }, { "PrivatePort": 443, "Type": "tcp" }],
+				"Labels": { "b": "2", "a": "1" }, "HostConfig": { "NetworkMode": "bridge" }
+			}]`))
+		case "/v1.43/containers/abc/stats":
+			require.Equal(t, "false", r.URL.Query().Get("stream"))
+			_, _ = w.Write([]byte(`{
+				"read": "2023-01-01T00:00:01.5Z", "id": "abc", "name": "/web",
+				"cpu_stats": { "cpu_usage": { "total_usage": 300 }, "system_cpu_usage": 2000, "online_cpus": 2 },
+				"precpu_stats": { "cpu_usage": { "total_usage": 100 }, "system_cpu_usage": 1000 },
+				"memory_stats": { "usage": 300, "limit": 1000, "stats": { "inactive_file": 100 } },
+				"networks": { "eth0": { "rx_bytes": 10, "tx_bytes": 20 }, "eth1": { "rx_bytes": 1, "tx_bytes": 2 } },
+				"blkio_stats": { "io_service_bytes_recursive": [{ "op": "read", "value": 5 }, { "op": "write", "value": 7 }] },
+				"pids_stats": { "current": 3 }
+			}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+	client, err := infinity.NewClient(context.Background(), models.InfinitySettings{URL: "unix://" + socketPath})
+	require.Nil(t, err)
+	require.Equal(t, "http://localhost", client.Settings.URL)
+	t.Run("containers", func(t *testing.T) {
+		query := models.ApplyDefaultsToQuery(context.Background(), models.Query{RefID: "A", Source: "url", URL: "/v1.43/containers/json", SourcePreset: models.SourcePresetDocker})
+		frame, err := infinity.GetFrameForURLSources(context.Background(), query, *client, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, 1, frame.Rows())
+		names, _ := frame.FieldByName("Names")
+		require.Equal(t, "web", *names.At(0).(*string))
+		ports, _ := frame.FieldByName("Ports")
+		require.Equal(t, "0.0.0.0:8080->80/tcp, 443/tcp", *ports.At(0).(*string))
+		labels, _ := frame.FieldByName("Labels")
+		require.Equal(t, "a=1, b=2", *labels.At(0).(*string))
+		created, _ := frame.FieldByName("Created")
+		require.Equal(t, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), created.At(0).(*time.Time).UTC())
+		_, idx := frame.FieldByName("HostConfig")
+		require.Equal(t, -1, idx)
+	})
+	t.Run("stats", func(t *testing.T) {
+		query := models.ApplyDefaultsToQuery(context.Background(), models.Query{RefID: "A", Source: "url", URL: "/v1.43/containers/abc/stats", SourcePreset: models.SourcePresetDocker})
+		frame, err := infinity.GetFrameForURLSources(context.Background(), query, *client, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, 1, frame.Rows())
+		for name, want := range map[string]float64{"cpu_percent": 40, "memory_usage": 200, "memory_percent": 20, "network_rx": 11, "network_tx": 22, "block_read": 5, "block_write": 7, "pids": 3} {
+			field, _ := frame.FieldByName(name)
+			require.NotNil(t, field, name)
+			require.Equal(t, want, *field.At(0).(*float64), name)
+		}
+		name, _ := frame.FieldByName("name")
+		require.Equal(t, "web", *name.At(0).(*string))
+	})
+}
+
+func TestDockerHost(t *testing.T) {
+	client, err := infinity.NewClient(context.Background(), models.InfinitySettings{URL: "tcp://docker.example.com:2376", TLSAuthWithCACert: true})
+	require.Nil(t, err)
+	require.Equal(t, "https://docker.example.com:2376", client.Settings.URL)
+	client, err = infinity.NewClient(context.Background(), models.InfinitySettings{URL: "tcp://docker.example.com:2375"})
+	require.Nil(t, err)
+	require.Equal(t, "http://docker.example.com:2375", client.Settings.URL)
+}
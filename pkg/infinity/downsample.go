@@ -0,0 +1,149 @@
+package infinity
+
+import (
+	"math"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+// DownsampleFrame reduces the number of rows of a time series frame to the given number of points.
+// Frames without time field or with fewer rows than the points are returned as is
+func DownsampleFrame(frame *data.Frame, mode models.DownsampleMode, points int) (*data.Frame, error) {
+	if frame == nil || points <= 0 || frame.Rows() <= points {
+		return frame, nil
+	}
+	timeFieldIndex := -1
+	for i, field := range frame.Fields {
+		if field.Type().Time() {
+			timeFieldIndex = i
+			break
+		}
+	}
+	if timeFieldIndex == -1 {
+		return frame, nil
+	}
+	switch mode {
+	case models.DownsampleModeLTTB:
+		valueFieldIndex := -1
+		for i, field := range frame.Fields {
+			if field.Type().Numeric() {
+				valueFieldIndex = i
+				break
+			}
+		}
+		if valueFieldIndex == -1 {
+			return frame, nil
+		}
+		return getFrameWithRows(frame, getLTTBIndices(frame.Fields[timeFieldIndex], frame.Fields[valueFieldIndex], points)), nil
+	case models.DownsampleModeAverage:
+		return getAverageBucketFrame(frame, points), nil
+	default:
+		return frame, nil
+	}
+}
+
+// getLTTBIndices returns the row indices selected by the largest triangle three buckets algorithm
+func getLTTBIndices(timeField *data.Field, valueField *data.Field, points int) []int {
+	rows := timeField.Len()
+	if points < 3 {
+		points = 3
+	}
+	x := make([]float64, rows)
+	y := make([]float64, rows)
+	for i := 0; i < rows; i++ {
+		if t, ok := timeField.ConcreteAt(i); ok {
+			x[i] = float64(t.(time.Time).UnixMilli())
+		}
+		if v, err := valueField.NullableFloatAt(i); err == nil && v != nil {
+			y[i] = *v
+		}
+	}
+	indices := []int{0}
+	every := float64(rows-2) / float64(points-2)
+	a := 0
+	for i := 0; i < points-2; i++ {
+		avgStart := int(math.Floor(float64(i+1)*every)) + 1
+		avgEnd := int(math.Floor(float64(i+2)*every)) + 1
+		if avgEnd > rows {
+			avgEnd = rows
+		}
+		avgX, avgY := 0.0, 0.0
+		for j := avgStart; j < avgEnd; j++ {
+			avgX += x[j]
+			avgY += y[j]
+		}
+		if avgEnd > avgStart {
+			avgX /= float64(avgEnd - avgStart)
+			avgY /= float64(avgEnd - avgStart)
+		}
+		rangeStart := int(math.Floor(float64(i)*every)) + 1
+		rangeEnd := int(math.Floor(float64(i+1)*every)) + 1
+		maxArea := -1.0
+		next := rangeStart
+		for j := rangeStart; j < rangeEnd; j++ {
+			area := math.Abs((x[a]-avgX)*(y[j]-y[a])-(x[a]-x[j])*(avgY-y[a])) / 2
+			if area > maxArea {
+				maxArea = area
+				next = j
+			}
+		}
+		indices = append(indices, next)
+		a = next
+	}
+	return append(indices, rows-1)
+}
+
+// getAverageBucketFrame splits the rows into equal sized buckets and returns one row per bucket.
+// Numeric fields are averaged and other fields use the first value of the bucket
+func getAverageBucketFrame(frame *data.Frame, points int) *data.Frame {
+	rows := frame.Rows()
+	out := data.NewFrame(frame.Name).SetMeta(frame.Meta)
+	for _, field := range frame.Fields {
+		fieldType := field.Type()
+		if fieldType.Numeric() {
+			fieldType = data.FieldTypeNullableFloat64
+		}
+		newField := data.NewFieldFromFieldType(fieldType, points)
+		newField.Name = field.Name
+		newField.Labels = field.Labels
+		newField.Config = field.Config
+		for bucket := 0; bucket < points; bucket++ {
+			start := bucket * rows / points
+			end := (bucket + 1) * rows / points
+			if !field.Type().Numeric() {
+				newField.Set(bucket, field.CopyAt(start))
+				continue
+			}
+			sum, count := 0.0, 0
+			for i := start; i < end; i++ {
+				if v, err := field.NullableFloatAt(i); err == nil && v != nil {
+					sum += *v
+					count++
+				}
+			}
+			if count > 0 {
+				avg := sum / float64(count)
+				newField.Set(bucket, &avg)
+			}
+		}
+		out.Fields = append(out.Fields, newField)
+	}
+	return out
+}
+
+func getFrameWithRows(frame *data.Frame, indices []int) *data.Frame {
+	out := data.NewFrame(frame.Name).SetMeta(frame.Meta)
+	for _, field := range frame.Fields {
+		newField := data.NewFieldFromFieldType(field.Type(), len(indices))
+		newField.Name = field.Name
+		newField.Labels = field.Labels
+		newField.Config = field.Config
+		for i, idx := range indices {
+			newField.Set(i, field.CopyAt(idx))
+		}
+		out.Fields = append(out.Fields, newField)
+	}
+	return out
+}
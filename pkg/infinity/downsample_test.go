@@ -0,0 +1,63 @@
+package infinity_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestDownsampleFrame(t *testing.T) {
+	getFrame := func(rows int) *data.Frame {
+		times := []time.Time{}
+		values := []*float64{}
+		names := []string{}
+		for i := 0; i < rows; i++ {
+			times = append(times, time.UnixMilli(int64(i*1000)))
+			value := float64(i % 10)
+			if i == 55 {
+				value = 100
+			}
+			values = append(values, &value)
+			names = append(names, "host")
+		}
+		return data.NewFrame("response", data.NewField("time", nil, times), data.NewField("value", nil, values), data.NewField("name", nil, names))
+	}
+	t.Run("should not downsample when the rows are within the limit", func(t *testing.T) {
+		frame, err := infinity.DownsampleFrame(getFrame(10), models.DownsampleModeLTTB, 20)
+		require.Nil(t, err)
+		require.Equal(t, 10, frame.Rows())
+	})
+	t.Run("should not downsample frames without time field", func(t *testing.T) {
+		frame, err := infinity.DownsampleFrame(data.NewFrame("response", data.NewField("value", nil, []float64{1, 2, 3})), models.DownsampleModeLTTB, 2)
+		require.Nil(t, err)
+		require.Equal(t, 3, frame.Rows())
+	})
+	t.Run("should downsample using lttb and keep the peaks", func(t *testing.T) {
+		frame, err := infinity.DownsampleFrame(getFrame(100), models.DownsampleModeLTTB, 10)
+		require.Nil(t, err)
+		require.Equal(t, 10, frame.Rows())
+		require.Equal(t, time.UnixMilli(0), frame.Fields[0].At(0))
+		require.Equal(t, time.UnixMilli(99000), frame.Fields[0].At(9))
+		peak := false
+		for i := 0; i < frame.Rows(); i++ {
+			if *frame.Fields[1].At(i).(*float64) == 100 {
+				peak = true
+			}
+		}
+		require.True(t, peak)
+		require.Equal(t, data.FieldTypeNullableFloat64, frame.Fields[1].Type())
+	})
+	t.Run("should downsample using average buckets", func(t *testing.T) {
+		frame, err := infinity.DownsampleFrame(getFrame(100), models.DownsampleModeAverage, 10)
+		require.Nil(t, err)
+		require.Equal(t, 10, frame.Rows())
+		require.Equal(t, time.UnixMilli(10000), frame.Fields[0].At(1))
+		require.Equal(t, 4.5, *frame.Fields[1].At(0).(*float64))
+		require.Equal(t, 14.0, *frame.Fields[1].At(5).(*float64))
+		require.Equal(t, "host", frame.Fields[2].At(9))
+	})
+}
@@ -0,0 +1,33 @@
+package infinity
+
+import (
+	"context"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// ToSchemaFrame returns the frame without the rows, keeping the field names, types, field configs and the meta of the frame.
+// The number of rows is reported in the frame meta as the row count stat. Used by the dry run queries
+func ToSchemaFrame(ctx context.Context, frame *data.Frame) *data.Frame {
+	_, span := tracing.DefaultTracer().Start(ctx, "ToSchemaFrame")
+	defer span.End()
+	if frame == nil {
+		return frame
+	}
+	rows := frame.Rows()
+	out := frame.EmptyCopy()
+	for i, field := range frame.Fields {
+		out.Fields[i].Config = field.Config
+	}
+	out.Meta = &data.FrameMeta{}
+	if frame.Meta != nil {
+		meta := *frame.Meta
+		out.Meta = &meta
+	}
+	out.Meta.Stats = append(out.Meta.Stats, data.QueryStat{
+		FieldConfig: data.FieldConfig{DisplayName: "Row count"},
+		Value:       float64(rows),
+	})
+	return out
+}
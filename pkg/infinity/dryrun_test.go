@@ -0,0 +1,27 @@
+package infinity_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+)
+
+func TestToSchemaFrame(t *testing.T) {
+	frame := data.NewFrame("A",
+		data.NewField("name", nil, []string{"foo", "bar", "baz"}),
+		data.NewField("age", nil, []*float64{toFP(1), nil, toFP(3)}).SetConfig(&data.FieldConfig{Unit: "s"}),
+	).SetMeta(&data.FrameMeta{ExecutedQueryString: "https://example.com"})
+	got := infinity.ToSchemaFrame(context.Background(), frame)
+	require.Equal(t, 0, got.Rows())
+	require.Equal(t, 2, len(got.Fields))
+	require.Equal(t, "name", got.Fields[0].Name)
+	require.Equal(t, data.FieldTypeString, got.Fields[0].Type())
+	require.Equal(t, data.FieldTypeNullableFloat64, got.Fields[1].Type())
+	require.Equal(t, "s", got.Fields[1].Config.Unit)
+	require.Equal(t, "https://example.com", got.Meta.ExecutedQueryString)
+	require.Equal(t, []data.QueryStat{{FieldConfig: data.FieldConfig{DisplayName: "Row count"}, Value: 3}}, got.Meta.Stats)
+	require.Nil(t, infinity.ToSchemaFrame(context.Background(), nil))
+}
@@ -0,0 +1,116 @@
+package infinity
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var iso8601DurationRegex = regexp.MustCompile(`^([-+])?P(?:(\d+(?:[.,]\d+)?)Y)?(?:(\d+(?:[.,]\d+)?)M)?(?:(\d+(?:[.,]\d+)?)W)?(?:(\d+(?:[.,]\d+)?)D)?(?:T(?:(\d+(?:[.,]\d+)?)H)?(?:(\d+(?:[.,]\d+)?)M)?(?:(\d+(?:[.,]\d+)?)S)?)?$`)
+
+var iso8601DurationUnits = []float64{
+	365 * 24 * 60 * 60, // years
+	30 * 24 * 60 * 60,  // months
+	7 * 24 * 60 * 60,   // weeks
+	24 * 60 * 60,       // days
+	60 * 60,            // hours
+	60,                 // minutes
+	1,                  // seconds
+}
+
+// ParseDurationToSeconds parses the duration strings into number of seconds. Supported formats are
+// golang style durations (1h23m, 1d2h), clock style durations (hh:mm:ss, mm:ss) and ISO8601 durations (PT5M, P1DT2H).
+func ParseDurationToSeconds(input string) (float64, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return 0, fmt.Errorf("invalid or empty duration")
+	}
+	if v, err := strconv.ParseFloat(input, 64); err == nil {
+		return v, nil
+	}
+	if strings.HasPrefix(strings.ToUpper(strings.TrimLeft(input, "+-")), "P") {
+		return parseISO8601Duration(input)
+	}
+	if strings.Contains(input, ":") {
+		return parseClockDuration(input)
+	}
+	return parseGoDuration(input)
+}
+
+func parseISO8601Duration(input string) (float64, error) {
+	matches := iso8601DurationRegex.FindStringSubmatch(strings.ToUpper(input))
+	if matches == nil || strings.HasSuffix(strings.ToUpper(input), "T") {
+		return 0, fmt.Errorf("invalid ISO8601 duration %s", input)
+	}
+	seconds := 0.0
+	found := false
+	for i, unit := range iso8601DurationUnits {
+		if matches[i+2] == "" {
+			continue
+		}
+		found = true
+		v, err := strconv.ParseFloat(strings.Replace(matches[i+2], ",", ".", 1), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid ISO8601 duration %s", input)
+		}
+		seconds += v * unit
+	}
+	if !found {
+		return 0, fmt.Errorf("invalid ISO8601 duration %s", input)
+	}
+	if matches[1] == "-" {
+		seconds = -seconds
+	}
+	return seconds, nil
+}
+
+func parseClockDuration(input string) (float64, error) {
+	negative := strings.HasPrefix(input, "-")
+	parts := strings.Split(strings.TrimLeft(input, "+-"), ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid duration %s", input)
+	}
+	seconds := 0.0
+	for _, part := range parts {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil || v < 0 {
+			return 0, fmt.Errorf("invalid duration %s", input)
+		}
+		seconds = seconds*60 + v
+	}
+	if negative {
+		seconds = -seconds
+	}
+	return seconds, nil
+}
+
+// parseGoDuration parses the golang style durations. In addition to the units supported by time.ParseDuration, "d" is supported as days
+func parseGoDuration(input string) (float64, error) {
+	days := 0.0
+	if index := strings.Index(input, "d"); index > 0 {
+		d, err := strconv.ParseFloat(strings.TrimLeft(input[:index], "+-"), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %s", input)
+		}
+		days = d
+		rest := input[index+1:]
+		if strings.HasPrefix(input, "-") {
+			days = -days
+			if rest != "" {
+				rest = "-" + rest
+			}
+		}
+		input = rest
+	}
+	seconds := days * 24 * 60 * 60
+	if input == "" {
+		return seconds, nil
+	}
+	d, err := time.ParseDuration(input)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %s", input)
+	}
+	return seconds + d.Seconds(), nil
+}
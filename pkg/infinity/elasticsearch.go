@@ -0,0 +1,119 @@
+package infinity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+const elasticsearchMaxPages = 10
+
+// GetElasticsearchFrame runs the _search request built from the elasticsearch options and follows the search_after pagination.
+// _source of the hits is flattened into the rows, nested objects become the columns with dotted names unless the columns are specified in the query
+func GetElasticsearchFrame(ctx context.Context, query models.Query, infClient Client, requestHeaders map[string]string) (*data.Frame, error) {
+	ctx, span := tracing.DefaultTracer().Start(ctx, "GetElasticsearchFrame")
+	defer span.End()
+	// pages share the cache key of the query. so the responses are never cached
+	ctx = withoutResponseCache(ctx)
+	frame := GetDummyFrame(query)
+	body := map[string]any{}
+	if err := json.Unmarshal([]byte(query.URLOptions.Body), &body); err != nil {
+		return frame, UserError(fmt.Errorf("invalid elasticsearch search body. %w", err))
+	}
+	size, _ := body["size"].(float64)
+	maxPages := query.PageMaxPages
+	if maxPages <= 0 {
+		maxPages = elasticsearchMaxPages
+	}
+	rows := []any{}
+	truncated := false
+	for page := 0; ; page++ {
+		if page >= maxPages {
+			truncated = true
+			break
+		}
+		res, _, _, err := infClient.GetResults(ctx, query, requestHeaders)
+		if err != nil {
+			frame.Meta.Custom = &CustomMeta{Query: query, Error: err.Error()}
+			return frame, err
+		}
+		hits, err := getElasticsearchHits(res)
+		if err != nil {
+			return frame, err
+		}
+		for _, hit := range hits {
+			rows = append(rows, getElasticsearchRow(hit, len(query.Columns) == 0))
+		}
+		if len(hits) == 0 || float64(len(hits)) < size {
+			break
+		}
+		lastHit, _ := hits[len(hits)-1].(map[string]any)
+		searchAfter, ok := lastHit["sort"].([]any)
+		if !ok {
+			break
+		}
+		body["search_after"] = searchAfter
+		nextBody, err := json.Marshal(body)
+		if err != nil {
+			return frame, err
+		}
+		query.URLOptions.Body = string(nextBody)
+	}
+	rowsQuery := query
+	rowsQuery.RootSelector = ""
+	frame, err := GetJSONBackendResponse(ctx, rows, rowsQuery)
+	if err != nil {
+		return frame, err
+	}
+	frame, err = PostProcessFrame(ctx, frame, rowsQuery)
+	if frame != nil && truncated {
+		frame.AppendNotices(data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("Results are truncated to the first %d pages", maxPages),
+		})
+	}
+	return frame, err
+}
+
+func getElasticsearchHits(res any) ([]any, error) {
+	out, ok := res.(map[string]any)
+	if !ok {
+		return nil, DownstreamError(errors.New("unexpected response from elasticsearch. expected json object"), 0)
+	}
+	hits, ok := out["hits"].(map[string]any)
+	if !ok {
+		return nil, DownstreamError(errors.New("hits are missing in the elasticsearch response"), 0)
+	}
+	items, _ := hits["hits"].([]any)
+	return items, nil
+}
+
+// getElasticsearchRow returns the _source of the hit along with the _id and _index of the document
+func getElasticsearchRow(hit any, flatten bool) map[string]any {
+	h, _ := hit.(map[string]any)
+	source, _ := h["_source"].(map[string]any)
+	row := map[string]any{"_id": h["_id"], "_index": h["_index"]}
+	if !flatten {
+		for k, v := range source {
+			row[k] = v
+		}
+		return row
+	}
+	flattenElasticsearchSource("", source, row)
+	return row
+}
+
+func flattenElasticsearchSource(prefix string, source map[string]any, row map[string]any) {
+	for k, v := range source {
+		if nested, ok := v.(map[string]any); ok {
+			flattenElasticsearchSource(prefix+k+".", nested, row)
+			continue
+		}
+		row[prefix+k] = v
+	}
+}
@@ -0,0 +1,70 @@
+package infinity_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestGetElasticsearchFrame(t *testing.T) {
+	bodies := []map[string]any{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		b, _ := io.ReadAll(r.Body)
+		body := map[string]any{}
+		require.Nil(t, json.Unmarshal(b, &body))
+		bodies = append(bodies, body)
+		w.Header().Set("Content-Type", "application/json")
+		if body["search_after"] == nil {
+			_, _ = w.Write([]byte(`{ "hits": { "hits": [
+				{ "_id": "1", "_index": "logs", "_source": { "message": "foo", "host": { "name": "a" } }, "sort": [1672531200000] },
+				{ "_id": "2", "_index": "logs", "_source": { "message": "bar", "host": { "name": "b" } }, "sort": [1672531100000] }
+			] } }`))
+			return
+		}
+		_, _ = w.Write([]byte(`{ "hits": { "hits": [{ "_id": "3", "_index": "logs", "_source": { "message": "baz", "host": { "name": "c" } }, "sort": [1672531000000] }] } }`))
+	}))
+	defer server.Close()
+	timeRange := backend.TimeRange{From: time.UnixMilli(1672527600000), To: time.UnixMilli(1672531200000)}
+	query := models.Query{RefID: "A", Type: models.QueryTypeJSON, Source: "url", Parser: models.InfinityParserBackend, URL: server.URL + "/logs/_search",
+		ElasticsearchOptions: &models.ElasticsearchOptions{Query: "level:error", TimeField: "@timestamp", Size: 2}}
+	query, err := models.ApplyElasticsearchOptions(query, timeRange, backend.PluginContext{})
+	require.Nil(t, err)
+	require.JSONEq(t, `{
+		"size": 2,
+		"query": { "bool": { "filter": [
+			{ "range": { "@timestamp": { "gte": 1672527600000, "lte": 1672531200000, "format": "epoch_millis" } } },
+			{ "query_string": { "query": "level:error" } }
+		] } },
+		"sort": [{ "@timestamp": { "order": "desc" } }]
+	}`, query.URLOptions.Body)
+	client, err := infinity.NewClient(context.Background(), models.InfinitySettings{})
+	require.Nil(t, err)
+	frame, err := infinity.GetFrameForURLSources(context.Background(), query, *client, map[string]string{})
+	require.Nil(t, err)
+	require.Len(t, bodies, 2)
+	require.Equal(t, []any{float64(1672531100000)}, bodies[1]["search_after"])
+	require.Equal(t, 3, frame.Rows())
+	host, _ := frame.FieldByName("host.name")
+	require.NotNil(t, host)
+	require.Equal(t, "c", *host.At(2).(*string))
+	t.Run("should keep the nested objects when the columns are specified", func(t *testing.T) {
+		bodies = bodies[:0]
+		withColumns := query
+		withColumns.Columns = []models.InfinityColumn{{Selector: "host.name", Text: "host", Type: "string"}}
+		frame, err := infinity.GetFrameForURLSources(context.Background(), withColumns, *client, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, 3, frame.Rows())
+		host, _ := frame.FieldByName("host")
+		require.Equal(t, "a", *host.At(0).(*string))
+	})
+}
@@ -0,0 +1,96 @@
+package infinity
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// ErrorKind classifies the errors so that grafana can attribute the failures to the right party
+type ErrorKind string
+
+const (
+	// ErrorKindDownstream is the failure of the remote API. ex: connection errors, non 2xx responses, invalid response body
+	ErrorKindDownstream ErrorKind = "downstream"
+	// ErrorKindUser is the misconfiguration of the query or the datasource. ex: invalid query, URL not in the allowed hosts list
+	ErrorKindUser ErrorKind = "user"
+	// ErrorKindPlugin is the failure of the plugin itself. Errors without classification are considered as plugin errors
+	ErrorKindPlugin ErrorKind = "plugin"
+)
+
+type classifiedError struct {
+	kind   ErrorKind
+	status backend.Status
+	err    error
+}
+
+func (e classifiedError) Error() string { return e.err.Error() }
+
+func (e classifiedError) Unwrap() error { return e.err }
+
+// DownstreamError marks the error as the failure of the remote API. statusCode is the http status code returned by the API, if any
+func DownstreamError(err error, statusCode int) error {
+	if err == nil {
+		return nil
+	}
+	return classifiedError{kind: ErrorKindDownstream, status: backend.Status(statusCode), err: err}
+}
+
+// UserError marks the error as the misconfiguration of the query or datasource
+func UserError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return classifiedError{kind: ErrorKindUser, status: backend.StatusBadRequest, err: err}
+}
+
+// ErrParserPanic is returned when the parser panics on the response
+var ErrParserPanic = errors.New("unable to parse the response")
+
+// recoverParserPanic recovers the panic of the parser and sets it as the error. The responses the parsers can't handle,
+// such as the json arrays of mixed types, are to be handled before parsing, so the remaining panics are the bugs of the
+// plugin and are left unclassified. Use it with defer in the functions with the named error result
+func recoverParserPanic(parser string, err *error) {
+	if r := recover(); r != nil {
+		backend.Logger.Error("parser panic on the response", "parser", parser, "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+		*err = fmt.Errorf("%w as %s. %v", ErrParserPanic, parser, r)
+	}
+}
+
+// GetErrorKind returns the classification of the error. The outermost classification of the error chain wins
+func GetErrorKind(err error) ErrorKind {
+	var e classifiedError
+	if errors.As(err, &e) {
+		return e.kind
+	}
+	return ErrorKindPlugin
+}
+
+// ClassifyResponse sets the error source and status of the response based on the classification of the error.
+// User errors are reported as downstream, as they are not caused by the plugin
+func ClassifyResponse(response backend.DataResponse) backend.DataResponse {
+	if response.Error == nil {
+		return response
+	}
+	var e classifiedError
+	if !errors.As(response.Error, &e) {
+		response.ErrorSource = backend.ErrorSourcePlugin
+		if response.Status == 0 {
+			response.Status = backend.StatusInternal
+		}
+		return response
+	}
+	response.ErrorSource = backend.ErrorSourceDownstream
+	if e.kind == ErrorKindDownstream && e.status >= 400 {
+		response.ErrorSource = backend.ErrorSourceFromHTTPStatus(int(e.status))
+	}
+	if response.Status == 0 {
+		response.Status = e.status
+		if response.Status == 0 {
+			response.Status = backend.StatusBadGateway
+		}
+	}
+	return response
+}
@@ -0,0 +1,39 @@
+package infinity_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+)
+
+func TestClassifyResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantKind   infinity.ErrorKind
+		wantSource backend.ErrorSource
+		wantStatus backend.Status
+	}{
+		{name: "unclassified", err: errors.New("foo"), wantKind: infinity.ErrorKindPlugin, wantSource: backend.ErrorSourcePlugin, wantStatus: backend.StatusInternal},
+		{name: "user", err: fmt.Errorf("wrapped. %w", infinity.UserError(errors.New("foo"))), wantKind: infinity.ErrorKindUser, wantSource: backend.ErrorSourceDownstream, wantStatus: backend.StatusBadRequest},
+		{name: "downstream", err: infinity.DownstreamError(errors.New("foo"), http.StatusServiceUnavailable), wantKind: infinity.ErrorKindDownstream, wantSource: backend.ErrorSourceDownstream, wantStatus: http.StatusServiceUnavailable},
+		{name: "downstream without status", err: infinity.DownstreamError(errors.New("foo"), 0), wantKind: infinity.ErrorKindDownstream, wantSource: backend.ErrorSourceDownstream, wantStatus: backend.StatusBadGateway},
+		{name: "downstream rejecting the request", err: infinity.DownstreamError(errors.New("foo"), http.StatusMethodNotAllowed), wantKind: infinity.ErrorKindDownstream, wantSource: backend.ErrorSourcePlugin, wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.wantKind, infinity.GetErrorKind(tt.err))
+			got := infinity.ClassifyResponse(backend.DataResponse{Error: tt.err})
+			require.Equal(t, tt.wantSource, got.ErrorSource)
+			require.Equal(t, tt.wantStatus, got.Status)
+			require.Equal(t, tt.err.Error(), got.Error.Error())
+		})
+	}
+	require.Nil(t, infinity.UserError(nil))
+	require.Equal(t, backend.DataResponse{}, infinity.ClassifyResponse(backend.DataResponse{}))
+}
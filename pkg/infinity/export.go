@@ -0,0 +1,483 @@
+package infinity
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+const (
+	exportsCacheTable = "exports"
+	// exportTimestampPlaceholder in the s3 key or the file path is replaced with the time of the export
+	exportTimestampPlaceholder = "${__timestamp}"
+)
+
+type ExportFormat string
+
+const (
+	ExportFormatCSV  ExportFormat = "csv"
+	ExportFormatJSON ExportFormat = "json"
+)
+
+type ExportTargetType string
+
+const (
+	ExportTargetWebhook ExportTargetType = "webhook"
+	ExportTargetS3      ExportTargetType = "s3"
+	ExportTargetFile    ExportTargetType = "file"
+)
+
+// ExportTarget is the destination of the exported results
+type ExportTarget struct {
+	Type     ExportTargetType `json:"type"`
+	URL      string           `json:"url,omitempty"`      // url of the webhook. results are posted to the url
+	Bucket   string           `json:"bucket,omitempty"`   // bucket of the s3 target
+	Key      string           `json:"key,omitempty"`      // object key of the s3 target
+	Region   string           `json:"region,omitempty"`   // region of the s3 bucket. defaults to the region of the aws settings of the datasource
+	Endpoint string           `json:"endpoint,omitempty"` // s3 compatible endpoint such as minio. objects are addressed with the path style urls
+	Path     string           `json:"path,omitempty"`     // path of the file target, relative to the export directory of the datasource
+}
+
+// ScheduledExport is a query executed in the background by the scheduler. Results of the query are encoded as csv or json
+// and delivered to the target. Scheduled exports are stored in the cache of the datasource, so they survive the plugin restarts
+type ScheduledExport struct {
+	ID        string       `json:"id"`
+	Schedule  string       `json:"schedule"`
+	Query     models.Query `json:"query"`
+	Format    ExportFormat `json:"format,omitempty"`
+	Target    ExportTarget `json:"target"`
+	LastRun   time.Time    `json:"lastRun,omitempty"`
+	NextRun   time.Time    `json:"nextRun,omitempty"`
+	LastError string       `json:"lastError,omitempty"`
+	LastRows  int          `json:"lastRows,omitempty"`
+	Role      string       `json:"role,omitempty"` // org role of the user registering the export. the query of the export runs with the role
+}
+
+// QueryRunner runs the query as the user with the org role and returns the resulting frame
+type QueryRunner func(ctx context.Context, query models.Query, role string) (*data.Frame, error)
+
+// validateExport validates the scheduled export and applies the defaults
+func validateExport(item ScheduledExport, settings models.InfinitySettings) (ScheduledExport, Schedule, error) {
+	item.ID = strings.TrimSpace(item.ID)
+	if item.ID == "" {
+		return item, nil, errors.New("invalid or empty scheduled export id")
+	}
+	schedule, err := ParseSchedule(item.Schedule)
+	if err != nil {
+		return item, nil, err
+	}
+	if is, ok := schedule.(intervalSchedule); ok && is.interval < minimumScheduleInterval {
+		return item, nil, fmt.Errorf("schedule interval should be at least %s", minimumScheduleInterval)
+	}
+	if item.Query.Type == models.QueryTypeTransformations || item.Query.Type == models.QueryTypeSQL {
+		return item, nil, fmt.Errorf("%s queries can't be exported as they depend on the results of the other queries", item.Query.Type)
+	}
+	if item.Query.Source == "mutation" {
+		return item, nil, errors.New("mutation queries can't be exported")
+	}
+	if item.Format == "" {
+		item.Format = ExportFormatCSV
+	}
+	if item.Format != ExportFormatCSV && item.Format != ExportFormatJSON {
+		return item, nil, fmt.Errorf("invalid export format %s. format should be csv or json", item.Format)
+	}
+	target := item.Target
+	switch target.Type {
+	case ExportTargetWebhook:
+		if !strings.HasPrefix(target.URL, "https://") && !strings.HasPrefix(target.URL, "http://") {
+			return item, nil, fmt.Errorf("invalid export webhook url %s", target.URL)
+		}
+		if !CanAllowURL(target.URL, settings.AllowedHosts) {
+			return item, nil, fmt.Errorf("export webhook url %s is not in the allowed hosts", target.URL)
+		}
+	case ExportTargetS3:
+		if strings.TrimSpace(target.Bucket) == "" || strings.TrimSpace(target.Key) == "" {
+			return item, nil, errors.New("s3 exports require the bucket and the key")
+		}
+		if settings.AWSAccessKey == "" || settings.AWSSecretKey == "" {
+			return item, nil, errors.New("s3 exports require the aws access key and secret key in the datasource settings")
+		}
+		objectURL := getS3ObjectURL(target, getS3Region(target, settings), time.Now())
+		if !CanAllowURL(objectURL, settings.AllowedHosts) {
+			return item, nil, fmt.Errorf("s3 url %s is not in the allowed hosts", objectURL)
+		}
+	case ExportTargetFile:
+		if settings.ExportDirectory == "" {
+			return item, nil, errors.New("file exports require the export directory in the plugin settings (export_directory)")
+		}
+		if !filepath.IsLocal(target.Path) {
+			return item, nil, fmt.Errorf("invalid export file path %s. path should be relative to the export directory", target.Path)
+		}
+	default:
+		return item, nil, fmt.Errorf("invalid export target %s. target should be webhook, s3 or file", target.Type)
+	}
+	return item, schedule, nil
+}
+
+// EncodeFrame encodes the frame as csv with the header row, or as the json array of the row objects
+func EncodeFrame(frame *data.Frame, format ExportFormat) ([]byte, string, error) {
+	if frame == nil {
+		frame = data.NewFrame("")
+	}
+	if format == ExportFormatJSON {
+		rows := make([]map[string]any, 0, frame.Rows())
+		for i := 0; i < frame.Rows(); i++ {
+			row := map[string]any{}
+			for _, field := range frame.Fields {
+				value, ok := field.ConcreteAt(i)
+				if !ok {
+					value = nil
+				}
+				row[field.Name] = value
+			}
+			rows = append(rows, row)
+		}
+		b, err := json.Marshal(rows)
+		return b, "application/json", err
+	}
+	records := [][]string{{}}
+	for _, field := range frame.Fields {
+		records[0] = append(records[0], field.Name)
+	}
+	for i := 0; i < frame.Rows(); i++ {
+		record := make([]string, 0, len(frame.Fields))
+		for _, field := range frame.Fields {
+			value, ok := field.ConcreteAt(i)
+			if !ok {
+				record = append(record, "")
+				continue
+			}
+			record = append(record, formatExportValue(value))
+		}
+		records = append(records, record)
+	}
+	var out bytes.Buffer
+	if err := csv.NewWriter(&out).WriteAll(records); err != nil {
+		return nil, "", err
+	}
+	return out.Bytes(), "text/csv", nil
+}
+
+func formatExportValue(value any) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case json.RawMessage:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// deliverExport writes the encoded results to the target
+func deliverExport(ctx context.Context, settings models.InfinitySettings, target ExportTarget, body []byte, contentType string, now time.Time) error {
+	timeout := time.Duration(settings.TimeoutInSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	// the datasource http client is not used, so the credentials of the datasource are never sent to the export targets
+	httpClient := &http.Client{Timeout: timeout, CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("too many redirects")
+		}
+		if !CanAllowURL(req.URL.String(), settings.AllowedHosts) {
+			return fmt.Errorf("export redirect %s is not in the allowed hosts", req.URL.String())
+		}
+		return nil
+	}}
+	switch target.Type {
+	case ExportTargetWebhook:
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", contentType)
+		return doExportRequest(httpClient, req)
+	case ExportTargetS3:
+		region := getS3Region(target, settings)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, getS3ObjectURL(target, region, now), bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", contentType)
+		signer := v4.NewSigner(credentials.NewStaticCredentials(settings.AWSAccessKey, settings.AWSSecretKey, ""))
+		if _, err := signer.Sign(req, bytes.NewReader(body), "s3", region, now); err != nil {
+			return fmt.Errorf("error signing the s3 request. %w", err)
+		}
+		return doExportRequest(httpClient, req)
+	case ExportTargetFile:
+		return writeExportFile(settings.ExportDirectory, expandExportName(target.Path, now), body)
+	}
+	return fmt.Errorf("invalid export target %s", target.Type)
+}
+
+func doExportRequest(httpClient *http.Client, req *http.Request) error {
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("export target %s responded with status %d. %s", req.URL.Host, res.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// writeExportFile replaces the file atomically, so the readers never see the partially written results
+func writeExportFile(dir string, name string, body []byte) error {
+	path, err := resolveConfinedPath(dir, name)
+	if err != nil {
+		return fmt.Errorf("invalid export file path. %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".export-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) //nolint
+	if _, err := f.Write(body); err != nil {
+		f.Close() //nolint
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0o640); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func getS3Region(target ExportTarget, settings models.InfinitySettings) string {
+	if target.Region != "" {
+		return target.Region
+	}
+	if settings.AWSSettings.Region != "" {
+		return settings.AWSSettings.Region
+	}
+	return "us-east-1"
+}
+
+func getS3ObjectURL(target ExportTarget, region string, now time.Time) string {
+	segments := strings.Split(strings.TrimPrefix(expandExportName(target.Key, now), "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	key := strings.Join(segments, "/")
+	if target.Endpoint != "" {
+		return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(target.Endpoint, "/"), url.PathEscape(target.Bucket), key)
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", target.Bucket, region, key)
+}
+
+func expandExportName(name string, now time.Time) string {
+	return strings.ReplaceAll(name, exportTimestampPlaceholder, now.UTC().Format("20060102T150405Z"))
+}
+
+// runExport runs the query of the scheduled export and delivers the results to the target. Returns the number of exported rows
+func runExport(ctx context.Context, client *Client, runner QueryRunner, item ScheduledExport) (int, error) {
+	if runner == nil {
+		runner = func(ctx context.Context, query models.Query, _ string) (*data.Frame, error) {
+			return GetFrameForURLSources(ctx, query, *client, map[string]string{})
+		}
+	}
+	frame, err := runner(ctx, item.Query, item.Role)
+	if err != nil {
+		return 0, fmt.Errorf("error running the query of the export. %w", err)
+	}
+	body, contentType, err := EncodeFrame(frame, item.Format)
+	if err != nil {
+		return 0, fmt.Errorf("error encoding the results of the export. %w", err)
+	}
+	if err := deliverExport(ctx, client.Settings, item.Target, body, contentType, time.Now()); err != nil {
+		backend.Logger.Error("error delivering the scheduled export", "id", item.ID, "target", string(item.Target.Type), "error", err.Error())
+		return 0, fmt.Errorf("error delivering the export. %w", err)
+	}
+	rows := 0
+	if frame != nil {
+		rows = frame.Rows()
+	}
+	return rows, nil
+}
+
+var (
+	// ErrScheduledExportNotFound is returned when the scheduled export is not registered
+	ErrScheduledExportNotFound = errors.New("scheduled export not found")
+	// errExportRunning is returned when another replica sharing the cache backend is already running the export
+	errExportRunning = errors.New("export is being run by another replica")
+)
+
+// RegisterExport validates, stores and starts the scheduled export. Existing export with the same id will be replaced
+func (s *Scheduler) RegisterExport(item ScheduledExport) (ScheduledExport, error) {
+	item, schedule, err := validateExport(item, s.client.Settings)
+	if err != nil {
+		return item, err
+	}
+	if s.ctx.Err() != nil {
+		return item, errors.New("scheduler is stopped")
+	}
+	item.Query = models.ApplyDefaultsToQuery(context.Background(), item.Query)
+	item.LastRun = time.Time{}
+	item.LastError = ""
+	item.LastRows = 0
+	item.NextRun = schedule.Next(time.Now())
+	if err := s.client.Cache().Table(exportsCacheTable).SetStruct(item.ID, &item); err != nil {
+		return item, fmt.Errorf("error storing the scheduled export. %w", err)
+	}
+	return item, s.startExport(item, schedule)
+}
+
+// RestoreExports starts the scheduled exports stored in the cache of the datasource. Exports no longer valid
+// with the current settings of the datasource are skipped
+func (s *Scheduler) RestoreExports() error {
+	table := s.client.Cache().Table(exportsCacheTable)
+	keys, err := table.Keys()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		res, err := table.GetStruct(key)
+		if err != nil {
+			continue
+		}
+		stored, ok := res.(*ScheduledExport)
+		if !ok || stored == nil {
+			continue
+		}
+		item, schedule, err := validateExport(*stored, s.client.Settings)
+		if err != nil {
+			backend.Logger.Warn("skipping the invalid scheduled export", "id", stored.ID, "error", err.Error())
+			continue
+		}
+		item.NextRun = schedule.Next(time.Now())
+		if err := s.startExport(item, schedule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListExports returns the scheduled exports sorted by id
+func (s *Scheduler) ListExports() []ScheduledExport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []ScheduledExport{}
+	for _, job := range s.exports {
+		out = append(out, job.item)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// RemoveExport stops the scheduled export and removes it from the cache
+func (s *Scheduler) RemoveExport(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.exports[id]
+	if !ok {
+		return fmt.Errorf("%w. %s", ErrScheduledExportNotFound, id)
+	}
+	close(job.stop)
+	delete(s.exports, id)
+	return s.client.Cache().Table(exportsCacheTable).Delete(id)
+}
+
+// RunExport runs the scheduled export immediately. The next run of the export is not changed
+func (s *Scheduler) RunExport(id string) (ScheduledExport, error) {
+	s.mu.Lock()
+	job, ok := s.exports[id]
+	s.mu.Unlock()
+	if !ok {
+		return ScheduledExport{}, fmt.Errorf("%w. %s", ErrScheduledExportNotFound, id)
+	}
+	return s.runExportJob(job, false)
+}
+
+func (s *Scheduler) startExport(item ScheduledExport, schedule Schedule) error {
+	job := &exportJob{item: item, schedule: schedule, stop: make(chan struct{})}
+	s.mu.Lock()
+	if s.ctx.Err() != nil {
+		s.mu.Unlock()
+		return errors.New("scheduler is stopped")
+	}
+	if existing, ok := s.exports[item.ID]; ok {
+		close(existing.stop)
+	}
+	s.exports[item.ID] = job
+	s.wg.Add(1)
+	s.mu.Unlock()
+	go s.loop(job.stop, func() time.Time { return job.item.NextRun }, func() {
+		s.runExportJob(job, true) //nolint
+	})
+	return nil
+}
+
+// runExportJob runs the export unless another replica is already running it, and records the outcome of the run in the cache
+func (s *Scheduler) runExportJob(job *exportJob, reschedule bool) (ScheduledExport, error) {
+	s.mu.Lock()
+	item, runner := job.item, s.runner
+	s.mu.Unlock()
+	timeout := time.Duration(s.client.Settings.TimeoutInSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, timeout)
+	defer cancel()
+	rows, err := 0, errExportRunning
+	lease, leaseErr := s.client.TryAcquireLease(ctx, "export:"+item.ID, timeout)
+	if leaseErr != nil {
+		err = fmt.Errorf("error acquiring the lease of scheduled export. %w", leaseErr)
+	}
+	if lease != nil {
+		rows, err = runExport(ctx, s.client, runner, item)
+		lease.Release(context.Background()) //nolint
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if reschedule {
+		job.item.NextRun = job.schedule.Next(time.Now())
+	}
+	if errors.Is(err, errExportRunning) {
+		backend.Logger.Debug("scheduled export is being run by another replica", "id", item.ID)
+		return job.item, err
+	}
+	job.item.LastRun = time.Now()
+	job.item.LastRows = rows
+	job.item.LastError = ""
+	if err != nil {
+		job.item.LastError = err.Error()
+	}
+	// the export removed or replaced during the run is not written back
+	if s.exports[item.ID] == job {
+		stored := job.item
+		if serr := s.client.Cache().Table(exportsCacheTable).SetStruct(stored.ID, &stored); serr != nil {
+			backend.Logger.Warn("error storing the scheduled export", "id", stored.ID, "error", serr.Error())
+		}
+	}
+	return job.item, err
+}
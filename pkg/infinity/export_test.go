@@ -0,0 +1,177 @@
+package infinity_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestEncodeFrame(t *testing.T) {
+	frame := data.NewFrame("A",
+		data.NewField("time", nil, []time.Time{time.Date(2023, 10, 14, 10, 0, 0, 0, time.UTC), time.Date(2023, 10, 14, 11, 0, 0, 0, time.UTC)}),
+		data.NewField("host", nil, []*string{toSP("web,1"), nil}),
+		data.NewField("cpu", nil, []*float64{toFP(10.5), toFP(20.0)}),
+	)
+	t.Run("csv", func(t *testing.T) {
+		got, contentType, err := infinity.EncodeFrame(frame, infinity.ExportFormatCSV)
+		require.Nil(t, err)
+		require.Equal(t, "text/csv", contentType)
+		require.Equal(t, "time,host,cpu\n2023-10-14T10:00:00Z,\"web,1\",10.5\n2023-10-14T11:00:00Z,,20\n", string(got))
+	})
+	t.Run("json", func(t *testing.T) {
+		got, contentType, err := infinity.EncodeFrame(frame, infinity.ExportFormatJSON)
+		require.Nil(t, err)
+		require.Equal(t, "application/json", contentType)
+		require.JSONEq(t, `[{"time":"2023-10-14T10:00:00Z","host":"web,1","cpu":10.5},{"time":"2023-10-14T11:00:00Z","host":null,"cpu":20}]`, string(got))
+	})
+}
+
+func TestScheduledExports(t *testing.T) {
+	frame := data.NewFrame("A", data.NewField("host", nil, []string{"web1", "web2"}), data.NewField("cpu", nil, []float64{10, 20}))
+	runner := func(ctx context.Context, query models.Query, role string) (*data.Frame, error) { return frame, nil }
+	query := models.Query{Source: "inline", Type: models.QueryTypeCSV, Data: "host,cpu\nweb1,10"}
+	t.Run("should validate the scheduled exports", func(t *testing.T) {
+		s := infinity.NewScheduler(&infinity.Client{Settings: models.InfinitySettings{UID: "exports-validation", AllowedHosts: []string{"https://hooks.example.com"}}})
+		defer s.Stop()
+		tests := []struct {
+			name    string
+			item    infinity.ScheduledExport
+			wantErr string
+		}{
+			{name: "empty id", item: infinity.ScheduledExport{Schedule: "1m", Target: infinity.ExportTarget{Type: infinity.ExportTargetWebhook, URL: "https://hooks.example.com/a"}}, wantErr: "invalid or empty scheduled export id"},
+			{name: "short interval", item: infinity.ScheduledExport{ID: "a", Schedule: "1s", Target: infinity.ExportTarget{Type: infinity.ExportTargetWebhook, URL: "https://hooks.example.com/a"}}, wantErr: "schedule interval should be at least 10s"},
+			{name: "format", item: infinity.ScheduledExport{ID: "a", Schedule: "1m", Format: "xml", Target: infinity.ExportTarget{Type: infinity.ExportTargetWebhook, URL: "https://hooks.example.com/a"}}, wantErr: "invalid export format xml"},
+			{name: "sql query", item: infinity.ScheduledExport{ID: "a", Schedule: "1m", Query: models.Query{Type: models.QueryTypeSQL}, Target: infinity.ExportTarget{Type: infinity.ExportTargetWebhook, URL: "https://hooks.example.com/a"}}, wantErr: "depend on the results of the other queries"},
+			{name: "webhook outside the allowed hosts", item: infinity.ScheduledExport{ID: "a", Schedule: "1m", Target: infinity.ExportTarget{Type: infinity.ExportTargetWebhook, URL: "https://example.com/a"}}, wantErr: "not in the allowed hosts"},
+			{name: "s3 without credentials", item: infinity.ScheduledExport{ID: "a", Schedule: "1m", Target: infinity.ExportTarget{Type: infinity.ExportTargetS3, Bucket: "reports", Key: "a.csv"}}, wantErr: "require the aws access key and secret key"},
+			{name: "file without export directory", item: infinity.ScheduledExport{ID: "a", Schedule: "1m", Target: infinity.ExportTarget{Type: infinity.ExportTargetFile, Path: "a.csv"}}, wantErr: "require the export directory"},
+			{name: "target", item: infinity.ScheduledExport{ID: "a", Schedule: "1m", Target: infinity.ExportTarget{Type: "ftp"}}, wantErr: "invalid export target ftp"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := s.RegisterExport(tt.item)
+				require.ErrorContains(t, err, tt.wantErr)
+			})
+		}
+		s = infinity.NewScheduler(&infinity.Client{Settings: models.InfinitySettings{UID: "exports-validation", ExportDirectory: t.TempDir()}})
+		defer s.Stop()
+		_, err := s.RegisterExport(infinity.ScheduledExport{ID: "a", Schedule: "1m", Target: infinity.ExportTarget{Type: infinity.ExportTargetFile, Path: "../a.csv"}})
+		require.ErrorContains(t, err, "path should be relative to the export directory")
+		require.Equal(t, 0, len(s.ListExports()))
+	})
+	t.Run("should post the results to the webhook", func(t *testing.T) {
+		var got, contentType string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			got, contentType = string(b), r.Header.Get("Content-Type")
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer server.Close()
+		s := infinity.NewScheduler(&infinity.Client{Settings: models.InfinitySettings{UID: "exports-webhook"}})
+		defer s.Stop()
+		var role string
+		s.SetQueryRunner(func(ctx context.Context, query models.Query, r string) (*data.Frame, error) {
+			role = r
+			return runner(ctx, query, r)
+		})
+		item, err := s.RegisterExport(infinity.ScheduledExport{ID: "a", Schedule: "1h", Query: query, Target: infinity.ExportTarget{Type: infinity.ExportTargetWebhook, URL: server.URL + "/hooks"}, Role: "Editor"})
+		require.Nil(t, err)
+		require.Equal(t, infinity.ExportFormatCSV, item.Format)
+		item, err = s.RunExport("a")
+		require.Nil(t, err)
+		require.Equal(t, 2, item.LastRows)
+		require.False(t, item.LastRun.IsZero())
+		require.Equal(t, "text/csv", contentType)
+		require.Equal(t, "host,cpu\nweb1,10\nweb2,20\n", got)
+		// the query of the export runs with the role of the user registering the export
+		require.Equal(t, "Editor", role)
+		_, err = s.RunExport("b")
+		require.ErrorIs(t, err, infinity.ErrScheduledExportNotFound)
+	})
+	t.Run("should record the delivery errors", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "bucket not found", http.StatusNotFound)
+		}))
+		defer server.Close()
+		s := infinity.NewScheduler(&infinity.Client{Settings: models.InfinitySettings{UID: "exports-errors"}})
+		defer s.Stop()
+		s.SetQueryRunner(runner)
+		_, err := s.RegisterExport(infinity.ScheduledExport{ID: "a", Schedule: "1h", Query: query, Target: infinity.ExportTarget{Type: infinity.ExportTargetWebhook, URL: server.URL}})
+		require.Nil(t, err)
+		_, err = s.RunExport("a")
+		require.ErrorContains(t, err, "responded with status 404. bucket not found")
+		require.Contains(t, s.ListExports()[0].LastError, "bucket not found")
+	})
+	t.Run("should upload the results to s3", func(t *testing.T) {
+		var path, authorization, sha string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path, authorization, sha = r.URL.Path, r.Header.Get("Authorization"), r.Header.Get("X-Amz-Content-Sha256")
+			require.Equal(t, http.MethodPut, r.Method)
+		}))
+		defer server.Close()
+		s := infinity.NewScheduler(&infinity.Client{Settings: models.InfinitySettings{UID: "exports-s3", AWSAccessKey: "key", AWSSecretKey: "secret"}})
+		defer s.Stop()
+		s.SetQueryRunner(runner)
+		_, err := s.RegisterExport(infinity.ScheduledExport{ID: "a", Schedule: "1h", Query: query, Format: infinity.ExportFormatJSON, Target: infinity.ExportTarget{Type: infinity.ExportTargetS3, Endpoint: server.URL, Bucket: "reports", Key: "daily/cpu ${__timestamp}.json", Region: "eu-west-1"}})
+		require.Nil(t, err)
+		_, err = s.RunExport("a")
+		require.Nil(t, err)
+		require.True(t, strings.HasPrefix(path, "/reports/daily/cpu "), path)
+		require.True(t, strings.HasSuffix(path, "Z.json"), path)
+		require.True(t, strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=key/"), authorization)
+		require.Contains(t, authorization, "/eu-west-1/s3/aws4_request")
+		require.NotEmpty(t, sha)
+	})
+	t.Run("should write the results to the file and restore the exports", func(t *testing.T) {
+		dir := t.TempDir()
+		client := &infinity.Client{Settings: models.InfinitySettings{UID: "exports-file", ExportDirectory: dir}}
+		s := infinity.NewScheduler(client)
+		s.SetQueryRunner(runner)
+		_, err := s.RegisterExport(infinity.ScheduledExport{ID: "a", Schedule: "0 9 * * *", Query: query, Format: infinity.ExportFormatJSON, Target: infinity.ExportTarget{Type: infinity.ExportTargetFile, Path: "reports/cpu.json"}})
+		require.Nil(t, err)
+		_, err = s.RunExport("a")
+		require.Nil(t, err)
+		b, err := os.ReadFile(filepath.Join(dir, "reports", "cpu.json"))
+		require.Nil(t, err)
+		require.JSONEq(t, `[{"host":"web1","cpu":10},{"host":"web2","cpu":20}]`, string(b))
+		s.Stop()
+		s = infinity.NewScheduler(client)
+		defer s.Stop()
+		require.Nil(t, s.RestoreExports())
+		exports := s.ListExports()
+		require.Equal(t, 1, len(exports))
+		require.Equal(t, "reports/cpu.json", exports[0].Target.Path)
+		require.Equal(t, 2, exports[0].LastRows)
+		require.Equal(t, "host,cpu\nweb1,10", exports[0].Query.Data)
+		require.Nil(t, s.RemoveExport("a"))
+		require.ErrorIs(t, s.RemoveExport("a"), infinity.ErrScheduledExportNotFound)
+		s2 := infinity.NewScheduler(client)
+		defer s2.Stop()
+		require.Nil(t, s2.RestoreExports())
+		require.Equal(t, 0, len(s2.ListExports()))
+	})
+	t.Run("should not write the results through the symbolic links", func(t *testing.T) {
+		dir, outside := t.TempDir(), t.TempDir()
+		require.Nil(t, os.Symlink(outside, filepath.Join(dir, "reports")))
+		s := infinity.NewScheduler(&infinity.Client{Settings: models.InfinitySettings{UID: "exports-symlink", ExportDirectory: dir}})
+		defer s.Stop()
+		s.SetQueryRunner(runner)
+		_, err := s.RegisterExport(infinity.ScheduledExport{ID: "a", Schedule: "0 9 * * *", Query: query, Target: infinity.ExportTarget{Type: infinity.ExportTargetFile, Path: "reports/cpu.csv"}})
+		require.Nil(t, err)
+		_, err = s.RunExport("a")
+		require.ErrorIs(t, err, infinity.ErrPathOutsideBase)
+		_, err = os.Stat(filepath.Join(outside, "cpu.csv"))
+		require.True(t, os.IsNotExist(err))
+	})
+}
@@ -0,0 +1,122 @@
+package infinity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v3"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+const fixtureCacheTable = "fixtures"
+
+// Fixture is the sample response stored in the datasource. Queries with use_sample run against the fixture instead of the live API,
+// which allows building the dashboards before the credentials of the API are available
+type Fixture struct {
+	Name        string    `json:"name"`
+	ContentType string    `json:"contentType,omitempty"`
+	Body        []byte    `json:"-"`
+	Size        int       `json:"size"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// SaveFixture stores the sample response under the name, replacing the existing fixture
+func SaveFixture(ctx context.Context, infClient Client, name string, contentType string, body []byte) (*Fixture, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("invalid or empty fixture name")
+	}
+	fixture := &Fixture{Name: name, ContentType: contentType, Body: body, Size: len(body), UpdatedAt: time.Now()}
+	if err := infClient.Cache().Table(fixtureCacheTable).WithContext(ctx).SetStruct(name, fixture); err != nil {
+		return nil, err
+	}
+	return fixture, nil
+}
+
+// GetFixture returns the fixture stored under the name
+func GetFixture(ctx context.Context, infClient Client, name string) (*Fixture, error) {
+	res, err := infClient.Cache().Table(fixtureCacheTable).WithContext(ctx).GetStruct(strings.TrimSpace(name))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, fmt.Errorf("fixture %s not found", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	fixture, ok := res.(*Fixture)
+	if !ok || fixture == nil {
+		return nil, fmt.Errorf("invalid fixture %s", name)
+	}
+	return fixture, nil
+}
+
+// ListFixtures returns the fixtures of the datasource sorted by name. Bodies are not included
+func ListFixtures(ctx context.Context, infClient Client) ([]Fixture, error) {
+	table := infClient.Cache().Table(fixtureCacheTable).WithContext(ctx)
+	keys, err := table.Keys()
+	if err != nil {
+		return nil, err
+	}
+	out := []Fixture{}
+	for _, key := range keys {
+		res, err := table.GetStruct(key)
+		if err != nil {
+			continue
+		}
+		if fixture, ok := res.(*Fixture); ok && fixture != nil {
+			fixture.Body = nil
+			out = append(out, *fixture)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// DeleteFixture removes the fixture
+func DeleteFixture(ctx context.Context, infClient Client, name string) error {
+	if _, err := GetFixture(ctx, infClient, name); err != nil {
+		return err
+	}
+	return infClient.Cache().Table(fixtureCacheTable).WithContext(ctx).Delete(strings.TrimSpace(name))
+}
+
+// getSampleResults returns the sample fixture of the query, parsed the same way as the responses of the live API
+func (client *Client) getSampleResults(ctx context.Context, query models.Query) (any, int, time.Duration, error) {
+	ctx, span := tracing.DefaultTracer().Start(ctx, "client.getSampleResults")
+	defer span.End()
+	if strings.TrimSpace(query.SampleFixture) == "" {
+		return nil, http.StatusBadRequest, 0, UserError(errors.New("sample fixture is not selected"))
+	}
+	fixture, err := GetFixture(ctx, *client, query.SampleFixture)
+	if err != nil {
+		return nil, http.StatusBadRequest, 0, UserError(err)
+	}
+	contentType := fixture.ContentType
+	if query.ResponseContentType != "" {
+		contentType = query.ResponseContentType
+	}
+	body, err := decodeResponseCharset(fixture.Body, contentType, query)
+	if err != nil {
+		return nil, http.StatusBadRequest, 0, UserError(err)
+	}
+	if body, err = ApplyPreParseSteps(body, query.PreParse); err != nil {
+		return nil, http.StatusBadRequest, 0, UserError(err)
+	}
+	if body, err = ExtractSOAPResult(body, query); err != nil {
+		return nil, http.StatusBadRequest, 0, UserError(err)
+	}
+	if CanParseAsJSON(query.Type, http.Header{headerKeyContentType: []string{contentType}}) {
+		var out any
+		if err := json.Unmarshal(body, &out); err != nil {
+			return nil, http.StatusBadRequest, 0, UserError(fmt.Errorf("invalid JSON in the sample fixture %s. %w", fixture.Name, err))
+		}
+		return out, http.StatusOK, 0, nil
+	}
+	return string(body), http.StatusOK, 0, nil
+}
@@ -0,0 +1,51 @@
+package infinity_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestFixtures(t *testing.T) {
+	ctx := context.Background()
+	client, err := infinity.NewClient(ctx, models.InfinitySettings{OrgID: 1, UID: "fixtures"})
+	require.Nil(t, err)
+	_, err = infinity.SaveFixture(ctx, *client, " ", "", nil)
+	require.NotNil(t, err)
+	_, err = infinity.SaveFixture(ctx, *client, "users", "application/json", []byte(`{ "data": "[{\"name\":\"foo\"}]" }`))
+	require.Nil(t, err)
+	_, err = infinity.SaveFixture(ctx, *client, "users-csv", "text/csv", []byte("name\nfoo\nbar"))
+	require.Nil(t, err)
+
+	fixtures, err := infinity.ListFixtures(ctx, *client)
+	require.Nil(t, err)
+	require.Len(t, fixtures, 2)
+	require.Equal(t, "users", fixtures[0].Name)
+	require.Equal(t, "application/json", fixtures[0].ContentType)
+	require.Nil(t, fixtures[0].Body)
+
+	t.Run("should run the query against the sample", func(t *testing.T) {
+		query := models.Query{Type: models.QueryTypeJSON, Source: "url", URL: "https://example.com/not-reachable", UseSample: true, SampleFixture: "users",
+			PreParse: []models.PreParseStep{{Type: models.PreParseJSONUnwrap, Selector: "data"}}}
+		got, statusCode, _, err := client.GetResults(ctx, query, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, 200, statusCode)
+		require.Equal(t, map[string]any{"data": []any{map[string]any{"name": "foo"}}}, got)
+		got, _, _, err = client.GetResults(ctx, models.Query{Type: models.QueryTypeCSV, Source: "url", UseSample: true, SampleFixture: "users-csv"}, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, "name\nfoo\nbar", got)
+	})
+	t.Run("should return user error when the sample not found", func(t *testing.T) {
+		_, _, _, err := client.GetResults(ctx, models.Query{Source: "url", UseSample: true, SampleFixture: "foo"}, map[string]string{})
+		require.NotNil(t, err)
+		require.Equal(t, infinity.ErrorKindUser, infinity.GetErrorKind(err))
+		_, _, _, err = client.GetResults(ctx, models.Query{Source: "url", UseSample: true}, map[string]string{})
+		require.Equal(t, "sample fixture is not selected", err.Error())
+	})
+
+	require.Nil(t, infinity.DeleteFixture(ctx, *client, "users"))
+	require.NotNil(t, infinity.DeleteFixture(ctx, *client, "users"))
+}
@@ -0,0 +1,167 @@
+package infinity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+const (
+	defaultForecastHorizon = 10
+	maxForecastHorizon     = 10000
+	defaultForecastAlpha   = 0.5
+	defaultForecastBeta    = 0.1
+	defaultForecastGamma   = 0.1
+)
+
+// GetForecastFrame predicts the future points of the numeric field using Holt-Winters (additive) exponential smoothing.
+// The interval of the predicted points is the median interval of the existing points. When the seasonality is not set
+// or there are not enough points for two seasons, the seasonal component is ignored (Holt's linear trend)
+func GetForecastFrame(ctx context.Context, frame *data.Frame, options models.ForecastOptions) (*data.Frame, error) {
+	_, span := tracing.DefaultTracer().Start(ctx, "GetForecastFrame")
+	defer span.End()
+	if frame == nil {
+		return nil, errors.New("forecast requires the results")
+	}
+	field, idx := frame.FieldByName(options.Field)
+	if idx == -1 {
+		return nil, fmt.Errorf("forecast field %s not found. found %s", options.Field, describeFields(frame))
+	}
+	if !field.Type().Numeric() {
+		return nil, fmt.Errorf("forecast field %s must be a numeric field", options.Field)
+	}
+	var timeField *data.Field
+	for _, f := range frame.Fields {
+		if f.Type().Time() {
+			timeField = f
+			break
+		}
+	}
+	if timeField == nil {
+		return nil, fmt.Errorf("forecast requires a time field. found %s", describeFields(frame))
+	}
+	horizon := options.Horizon
+	if horizon <= 0 {
+		horizon = defaultForecastHorizon
+	}
+	if horizon > maxForecastHorizon {
+		return nil, fmt.Errorf("forecast horizon %d exceeds the limit of %d", horizon, maxForecastHorizon)
+	}
+	alpha, beta, gamma := options.Alpha, options.Beta, options.Gamma
+	if alpha == 0 {
+		alpha = defaultForecastAlpha
+	}
+	if beta == 0 {
+		beta = defaultForecastBeta
+	}
+	if gamma == 0 {
+		gamma = defaultForecastGamma
+	}
+	for _, v := range []float64{alpha, beta, gamma} {
+		if v < 0 || v > 1 {
+			return nil, errors.New("forecast smoothing factors must be between 0 and 1")
+		}
+	}
+	times, values, err := getForecastSeries(timeField, field)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) < 2 {
+		return nil, errors.New("forecast requires at least two points")
+	}
+	intervals := make([]time.Duration, len(times)-1)
+	for i := 1; i < len(times); i++ {
+		intervals[i-1] = times[i].Sub(times[i-1])
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i] < intervals[j] })
+	step := intervals[len(intervals)/2]
+	if step <= 0 {
+		return nil, errors.New("forecast requires points with distinct timestamps")
+	}
+	predicted := holtWinters(values, options.Seasonality, alpha, beta, gamma, horizon)
+	predictedTimes := make([]time.Time, horizon)
+	for i := range predictedTimes {
+		predictedTimes[i] = times[len(times)-1].Add(time.Duration(i+1) * step)
+	}
+	out := data.NewFrame("forecast",
+		data.NewField(timeField.Name, nil, predictedTimes),
+		data.NewField(field.Name+"_forecast", field.Labels, predicted),
+	)
+	out.Meta = &data.FrameMeta{PreferredVisualization: data.VisTypeGraph}
+	return out, nil
+}
+
+// getForecastSeries returns the non null values of the field ordered by time
+func getForecastSeries(timeField *data.Field, field *data.Field) ([]time.Time, []float64, error) {
+	rows := []int{}
+	for i := 0; i < field.Len(); i++ {
+		if _, ok := timeField.ConcreteAt(i); ok {
+			rows = append(rows, i)
+		}
+	}
+	sort.SliceStable(rows, func(a, b int) bool {
+		ta, _ := timeField.ConcreteAt(rows[a])
+		tb, _ := timeField.ConcreteAt(rows[b])
+		return ta.(time.Time).Before(tb.(time.Time))
+	})
+	times := []time.Time{}
+	values := []float64{}
+	for _, row := range rows {
+		v, err := field.NullableFloatAt(row)
+		if err != nil {
+			return nil, nil, err
+		}
+		if v == nil {
+			continue
+		}
+		t, _ := timeField.ConcreteAt(row)
+		times = append(times, t.(time.Time))
+		values = append(values, *v)
+	}
+	return times, values, nil
+}
+
+func holtWinters(values []float64, seasonality int, alpha, beta, gamma float64, horizon int) []float64 {
+	n := len(values)
+	predicted := make([]float64, horizon)
+	if seasonality < 2 || n < 2*seasonality {
+		level, trend := values[0], values[1]-values[0]
+		for t := 1; t < n; t++ {
+			previous := level
+			level = alpha*values[t] + (1-alpha)*(level+trend)
+			trend = beta*(level-previous) + (1-beta)*trend
+		}
+		for h := range predicted {
+			predicted[h] = level + float64(h+1)*trend
+		}
+		return predicted
+	}
+	m := seasonality
+	firstSeason, secondSeason := 0.0, 0.0
+	for i := 0; i < m; i++ {
+		firstSeason += values[i]
+		secondSeason += values[m+i]
+	}
+	firstSeason, secondSeason = firstSeason/float64(m), secondSeason/float64(m)
+	level, trend := firstSeason, (secondSeason-firstSeason)/float64(m)
+	seasonals := make([]float64, m)
+	for i := 0; i < m; i++ {
+		seasonals[i] = values[i] - level
+	}
+	for t := m; t < n; t++ {
+		previous := level
+		level = alpha*(values[t]-seasonals[t%m]) + (1-alpha)*(level+trend)
+		trend = beta*(level-previous) + (1-beta)*trend
+		seasonals[t%m] = gamma*(values[t]-level) + (1-gamma)*seasonals[t%m]
+	}
+	for h := range predicted {
+		predicted[h] = level + float64(h+1)*trend + seasonals[(n+h)%m]
+	}
+	return predicted
+}
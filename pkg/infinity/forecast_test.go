@@ -0,0 +1,48 @@
+package infinity_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestGetForecastFrame(t *testing.T) {
+	t0 := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	getFrame := func(values []float64) *data.Frame {
+		times := []time.Time{}
+		for i := range values {
+			times = append(times, t0.Add(time.Duration(i)*time.Hour))
+		}
+		return data.NewFrame("response", data.NewField("time", nil, times), data.NewField("usage", nil, values))
+	}
+	t.Run("linear trend", func(t *testing.T) {
+		frame, err := infinity.GetForecastFrame(context.Background(), getFrame([]float64{0, 1, 2, 3, 4, 5}), models.ForecastOptions{Field: "usage", Horizon: 3})
+		require.Nil(t, err)
+		require.Equal(t, "forecast", frame.Name)
+		require.Equal(t, "usage_forecast", frame.Fields[1].Name)
+		require.Equal(t, []time.Time{t0.Add(6 * time.Hour), t0.Add(7 * time.Hour), t0.Add(8 * time.Hour)}, []time.Time{frame.Fields[0].At(0).(time.Time), frame.Fields[0].At(1).(time.Time), frame.Fields[0].At(2).(time.Time)})
+		for i, want := range []float64{6, 7, 8} {
+			require.InDelta(t, want, frame.Fields[1].At(i).(float64), 0.0001)
+		}
+	})
+	t.Run("seasonal", func(t *testing.T) {
+		frame, err := infinity.GetForecastFrame(context.Background(), getFrame([]float64{1, 3, 1, 3, 1, 3, 1, 3}), models.ForecastOptions{Field: "usage", Horizon: 4, Seasonality: 2})
+		require.Nil(t, err)
+		for i, want := range []float64{1, 3, 1, 3} {
+			require.InDelta(t, want, frame.Fields[1].At(i).(float64), 0.0001)
+		}
+	})
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := infinity.GetForecastFrame(context.Background(), getFrame([]float64{1, 2}), models.ForecastOptions{Field: "foo"})
+		require.ErrorContains(t, err, "forecast field foo not found")
+		_, err = infinity.GetForecastFrame(context.Background(), getFrame([]float64{1}), models.ForecastOptions{Field: "usage"})
+		require.ErrorContains(t, err, "forecast requires at least two points")
+		_, err = infinity.GetForecastFrame(context.Background(), getFrame([]float64{1, 2}), models.ForecastOptions{Field: "usage", Alpha: 2})
+		require.ErrorContains(t, err, "between 0 and 1")
+	})
+}
@@ -0,0 +1,92 @@
+package infinity
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+// getFormDataBoundary returns the multipart boundary of the query. The boundary is derived from the query,
+// so the body and the content type header built separately use the same boundary
+func getFormDataBoundary(query models.Query) string {
+	h := sha256.New()
+	for _, f := range query.URLOptions.BodyForm {
+		fmt.Fprintf(h, "%s=%s\n", f.Key, f.Value)
+	}
+	for _, name := range query.URLOptions.BodyFormFiles {
+		fmt.Fprintf(h, "file:%s\n", name)
+	}
+	return "infinity" + hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+func getFormDataContentType(query models.Query) string {
+	return "multipart/form-data; boundary=" + getFormDataBoundary(query)
+}
+
+// getFormDataBody returns the multipart body with the form fields of the query and the form files selected by the query.
+// The content of the files is replaced with the dummy value unless includeSect is set
+func getFormDataBody(query models.Query, settings models.InfinitySettings, includeSect bool) (*bytes.Buffer, error) {
+	payload := &bytes.Buffer{}
+	writer := multipart.NewWriter(payload)
+	if err := writer.SetBoundary(getFormDataBoundary(query)); err != nil {
+		return nil, err
+	}
+	for _, f := range query.URLOptions.BodyForm {
+		if err := writer.WriteField(f.Key, f.Value); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range query.URLOptions.BodyFormFiles {
+		file, ok := getFormFile(settings, name)
+		if !ok {
+			return nil, fmt.Errorf("form file %s not found in the datasource settings", name)
+		}
+		fileName := file.FileName
+		if fileName == "" {
+			fileName = file.Name
+		}
+		contentType := file.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(file.Name), escapeQuotes(fileName)))
+		header.Set(headerKeyContentType, contentType)
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		content := dummyHeader
+		if includeSect {
+			content = file.Content
+		}
+		if _, err := part.Write([]byte(content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+func getFormFile(settings models.InfinitySettings, name string) (models.FormFile, bool) {
+	for _, file := range settings.FormFiles {
+		if file.Name == name {
+			return file, true
+		}
+	}
+	return models.FormFile{}, false
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
@@ -0,0 +1,62 @@
+package infinity_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestFormDataBody(t *testing.T) {
+	var fields map[string][]string
+	var files map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Nil(t, r.ParseMultipartForm(1<<20))
+		fields = r.MultipartForm.Value
+		files = map[string]string{}
+		for name, headers := range r.MultipartForm.File {
+			f, err := headers[0].Open()
+			require.Nil(t, err)
+			b, _ := io.ReadAll(f)
+			files[name] = headers[0].Filename + ":" + headers[0].Header.Get("Content-Type") + ":" + string(b)
+		}
+		_, _ = w.Write([]byte(`[{"name":"foo"}]`))
+	}))
+	defer server.Close()
+	client, err := infinity.NewClient(context.Background(), models.InfinitySettings{
+		FormFiles: []models.FormFile{{Name: "query", FileName: "query.sql", ContentType: "text/plain", Content: "select 1"}, {Name: "other", Content: "secret"}},
+	})
+	require.Nil(t, err)
+	query := models.ApplyDefaultsToQuery(context.Background(), models.Query{RefID: "A", Type: models.QueryTypeJSON, Parser: models.InfinityParserBackend, Source: "url", URL: server.URL, URLOptions: models.URLOptions{
+		Method:        http.MethodPost,
+		BodyType:      "form-data",
+		BodyForm:      []models.URLOptionKeyValuePair{{Key: "format", Value: "json"}},
+		BodyFormFiles: []string{"query"},
+	}})
+	frame, err := infinity.GetFrameForURLSources(context.Background(), query, *client, map[string]string{})
+	require.Nil(t, err)
+	require.Equal(t, 1, frame.Rows())
+	require.Equal(t, map[string][]string{"format": {"json"}}, fields)
+	require.Equal(t, map[string]string{"query": "query.sql:text/plain:select 1"}, files)
+	t.Run("executed url should not include the file content", func(t *testing.T) {
+		executed := client.GetExecutedURL(context.Background(), query)
+		require.NotContains(t, executed, "select 1")
+		require.Contains(t, executed, "query.sql")
+	})
+	t.Run("unknown form file should throw error", func(t *testing.T) {
+		query.URLOptions.BodyFormFiles = []string{"foo"}
+		_, err := infinity.GetFrameForURLSources(context.Background(), query, *client, map[string]string{})
+		require.ErrorContains(t, err, "form file foo not found in the datasource settings")
+	})
+	t.Run("url encoded form", func(t *testing.T) {
+		body := infinity.GetQueryBody(models.Query{URLOptions: models.URLOptions{Method: http.MethodPost, BodyType: "x-www-form-urlencoded", BodyForm: []models.URLOptionKeyValuePair{{Key: "a", Value: "b c"}}}}, client.Settings, true)
+		b, _ := io.ReadAll(body)
+		require.Equal(t, "a=b+c", strings.TrimSpace(string(b)))
+	})
+}
@@ -0,0 +1,95 @@
+package infinity
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+type skipResponseCacheKey struct{}
+
+// withoutResponseCache returns the context which makes the client skip reading and writing the raw response cache,
+// as the results are cached as the parsed frame instead
+func withoutResponseCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipResponseCacheKey{}, true)
+}
+
+func isResponseCacheSkipped(ctx context.Context) bool {
+	skip, _ := ctx.Value(skipResponseCacheKey{}).(bool)
+	return skip
+}
+
+// getFrameCacheKey returns the key of the cached frame. Parsed frame depends on the whole query, such as the columns
+// and the filters, so the key includes the hash of the query along with the cache key of the query (cacheq header)
+func getFrameCacheKey(query models.Query) (string, error) {
+	cacheKey, _, err := getBadgerKey(query.URLOptions.Headers)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(query)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(b)
+	return fmt.Sprintf("frame:%s:%s", cacheKey, hex.EncodeToString(hash[:])), nil
+}
+
+// getCachedFrameForURLSources returns the parsed frame from the cache. Frames are stored in the arrow format, so the
+// cache hits skip both the request and the parsing. Queries without the cache key are executed without the cache
+func getCachedFrameForURLSources(ctx context.Context, query models.Query, infClient Client, requestHeaders map[string]string) (*data.Frame, error) {
+	key, err := getFrameCacheKey(query)
+	if err != nil {
+		query.CacheMode = models.CacheModeRaw
+		return GetFrameForURLSources(ctx, query, infClient, requestHeaders)
+	}
+	_, ttl, _ := getBadgerKey(query.URLOptions.Headers)
+	cache := infClient.ResponseCache()
+	if !isCacheRefresh(ctx) {
+		stopCacheTiming := trackTiming(ctx, cacheTiming)
+		value, err := cache.Get(ctx, key)
+		stopCacheTiming()
+		if err == nil {
+			if frame, err := data.UnmarshalArrowFrame(value); err == nil {
+				return frame, nil
+			}
+			backend.Logger.Warn("error reading the cached frame. executing the query", "key", key)
+		}
+	}
+	rawQuery := query
+	rawQuery.CacheMode = models.CacheModeRaw
+	frame, err := GetFrameForURLSources(withoutResponseCache(ctx), rawQuery, infClient, requestHeaders)
+	if err != nil || frame == nil {
+		return frame, err
+	}
+	value, err := marshalFrameForCache(frame)
+	if err != nil {
+		backend.Logger.Warn("error serializing the frame for cache", "error", err.Error())
+		return frame, nil
+	}
+	if err := cache.Set(ctx, key, value, ttl); err != nil {
+		backend.Logger.Warn("error writing the frame to cache", "error", err.Error())
+	}
+	return frame, nil
+}
+
+// marshalFrameForCache serializes the frame without the raw response kept in the custom meta,
+// which would otherwise store the response along with the parsed frame
+func marshalFrameForCache(frame *data.Frame) ([]byte, error) {
+	cached := *frame
+	if frame.Meta != nil {
+		meta := *frame.Meta
+		if custom, ok := meta.Custom.(*CustomMeta); ok && custom != nil {
+			c := *custom
+			c.Data = nil
+			meta.Custom = &c
+		}
+		cached.Meta = &meta
+	}
+	return cached.MarshalArrow()
+}
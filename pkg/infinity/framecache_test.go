@@ -0,0 +1,46 @@
+package infinity_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestFrameCache(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `[{ "name" : "foo", "hits" : %d }, { "name" : "bar", "hits" : 0 }]`, atomic.AddInt32(&hits, 1))
+	}))
+	defer server.Close()
+	client, err := infinity.NewClient(context.Background(), models.InfinitySettings{OrgID: 1, UID: "framecache"})
+	require.Nil(t, err)
+	query := models.Query{Type: models.QueryTypeJSON, Source: "url", Parser: models.InfinityParserBackend, URL: server.URL, CacheMode: models.CacheModeFrame,
+		URLOptions: models.URLOptions{Method: http.MethodGet, Headers: []models.URLOptionKeyValuePair{{Key: "cacheq", Value: "frames"}}}}
+	for i := 0; i < 2; i++ {
+		frame, err := infinity.GetFrameForURLSources(context.Background(), query, *client, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, 2, frame.Rows())
+		hitsField, _ := frame.FieldByName("hits")
+		require.Equal(t, float64(1), *(hitsField.At(0).(*float64)))
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&hits))
+	t.Run("should not cache the raw response", func(t *testing.T) {
+		_, err := client.ResponseCache().Get(context.Background(), "frames")
+		require.NotNil(t, err)
+	})
+	t.Run("should cache the frames per query", func(t *testing.T) {
+		filtered := query
+		filtered.FilterExpression = `name == "bar"`
+		frame, err := infinity.GetFrameForURLSources(context.Background(), filtered, *client, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, 1, frame.Rows())
+		require.Equal(t, int32(2), atomic.LoadInt32(&hits))
+	})
+}
@@ -0,0 +1,110 @@
+package infinity_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+// Fuzz targets only check that the malformed inputs return errors instead of panics.
+// Run them with go test ./pkg/infinity -run '^$' -fuzz FuzzGetCSVBackendResponse -fuzztime 1m
+
+func FuzzGetCSVBackendResponse(f *testing.F) {
+	f.Add("name,age\nfoo,12\nbar,13\n", "", true, false)
+	f.Add("name,age\nfoo\nbar,13,uk\n", "", false, true)
+	f.Add("name,age\nfoo\nbar,13,uk\n", "", true, true)
+	f.Add("a;b\n1;2", ";", true, false)
+	f.Add("\"unterminated\n,\n", "", true, false)
+	f.Add("", "", false, false)
+	f.Fuzz(func(t *testing.T, body string, delimiter string, withColumns bool, relaxColumnCount bool) {
+		query := models.Query{RefID: "A", Type: models.QueryTypeCSV, Parser: models.InfinityParserBackend, CSVOptions: models.InfinityCSVOptions{Delimiter: delimiter, RelaxColumnCount: relaxColumnCount}}
+		if withColumns {
+			query.Columns = []models.InfinityColumn{{Selector: "name", Type: "string"}, {Selector: "age", Type: "number"}, {Selector: "joined", Type: "timestamp"}}
+		}
+		_, _ = infinity.GetCSVBackendResponse(context.Background(), body, query)
+	})
+}
+
+func FuzzGetJSONBackendResponse(f *testing.F) {
+	f.Add(`[{"name":"foo","age":12},{"name":"bar","age":13}]`, "", true)
+	f.Add(`[{"name":"foo","age":"12"},{"name":1,"age":[1]},null,2,"x"]`, "", true)
+	f.Add(`{"users":[[1,"a"],[{"b":2}]]}`, "users", false)
+	f.Add(`[1,"a",true,null,{}]`, "", false)
+	f.Fuzz(func(t *testing.T, body string, rootSelector string, withColumns bool) {
+		var response any
+		if json.Unmarshal([]byte(body), &response) != nil {
+			return
+		}
+		query := models.Query{RefID: "A", Type: models.QueryTypeJSON, Parser: models.InfinityParserBackend, RootSelector: rootSelector}
+		if withColumns {
+			query.Columns = []models.InfinityColumn{{Selector: "name", Type: "string"}, {Selector: "age", Type: "number"}, {Selector: "joined", Type: "timestamp"}}
+		}
+		_, _ = infinity.GetJSONBackendResponse(context.Background(), response, query)
+	})
+}
+
+func TestGetJSONBackendResponseWithMixedTypes(t *testing.T) {
+	var response any
+	require.Nil(t, json.Unmarshal([]byte(`[{"name":"foo","age":12},{"name":"bar","age":"13"}]`), &response))
+	frame, err := infinity.GetJSONBackendResponse(context.Background(), response, models.Query{RefID: "A", Type: models.QueryTypeJSON, Parser: models.InfinityParserBackend})
+	require.Nil(t, err)
+	require.Equal(t, 2, frame.Rows())
+	age, _ := frame.FieldByName("age")
+	require.NotNil(t, age)
+	require.Equal(t, data.FieldTypeNullableString, age.Type())
+	require.Equal(t, "12", *age.At(0).(*string))
+	require.Equal(t, "13", *age.At(1).(*string))
+	t.Run("should convert the nested values of mixed types to json", func(t *testing.T) {
+		var response any
+		require.Nil(t, json.Unmarshal([]byte(`{"rows":[{"name":"foo","meta":{"tags":["a"]}},{"name":"bar","meta":{"tags":"b"}},{"name":"baz","meta":null}]}`), &response))
+		frame, err := infinity.GetJSONBackendResponse(context.Background(), response, models.Query{RefID: "A", Type: models.QueryTypeJSON, Parser: models.InfinityParserBackend, RootSelector: "rows"})
+		require.Nil(t, err)
+		require.Equal(t, 3, frame.Rows())
+	})
+}
+
+func FuzzGetXMLBackendResponse(f *testing.F) {
+	f.Add(`<users><user name="foo" age="12" /><user name="bar" age="13" /></users>`, "users.user", true)
+	f.Add(`<users><user><name>foo</name></user><user name="bar"><name>baz</name></user></users>`, "users.user", false)
+	f.Add(`<users><user`, "users", false)
+	f.Fuzz(func(t *testing.T, body string, rootSelector string, withColumns bool) {
+		query := models.Query{RefID: "A", Type: models.QueryTypeXML, Parser: models.InfinityParserBackend, RootSelector: rootSelector}
+		if withColumns {
+			query.Columns = []models.InfinityColumn{{Selector: "name", Type: "string"}, {Selector: "age", Type: "number"}}
+		}
+		_, _ = infinity.GetXMLBackendResponse(context.Background(), body, query)
+	})
+}
+
+func FuzzGetHTMLBackendResponse(f *testing.F) {
+	f.Add(`<html><body><table><tr><td>foo</td></tr></table></body></html>`, "html.body.table.tr")
+	f.Add(`<html><body><p>unclosed <b>tags</body>`, "html.body.p")
+	f.Add(`<!DOCTYPE html><meta charset="utf-8"><br>`, "")
+	f.Fuzz(func(t *testing.T, body string, rootSelector string) {
+		query := models.Query{RefID: "A", Type: models.QueryTypeHTML, Parser: models.InfinityParserBackend, RootSelector: rootSelector}
+		_, _ = infinity.GetXMLBackendResponse(context.Background(), body, query)
+	})
+}
+
+func FuzzSettCodecs(f *testing.F) {
+	for _, v := range []any{"foo", 42, 1.5} {
+		for _, codec := range []infinity.Codec{infinity.GobCodec{}, infinity.JSONCodec{}, infinity.MsgpackCodec{}} {
+			b, err := codec.Marshal(v)
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(b)
+		}
+	}
+	f.Add([]byte{0x0f, 0xff, 0x81, 0x03, 0x01})
+	f.Fuzz(func(t *testing.T, value []byte) {
+		_, _ = infinity.GobCodec{}.Unmarshal(value)
+		_, _ = infinity.JSONCodec{}.Unmarshal(value)
+		_, _ = infinity.MsgpackCodec{}.Unmarshal(value)
+	})
+}
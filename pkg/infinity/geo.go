@@ -0,0 +1,113 @@
+package infinity
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+const geohashBase32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// DecodeGeohash decodes the geohash string into the latitude and longitude of the center of the geohash cell
+func DecodeGeohash(geohash string) (latitude float64, longitude float64, err error) {
+	geohash = strings.ToLower(strings.TrimSpace(geohash))
+	if geohash == "" {
+		return 0, 0, fmt.Errorf("invalid or empty geohash")
+	}
+	latRange := []float64{-90, 90}
+	lonRange := []float64{-180, 180}
+	isLongitude := true
+	for _, c := range geohash {
+		index := strings.IndexRune(geohashBase32, c)
+		if index < 0 {
+			return 0, 0, fmt.Errorf("invalid geohash %s", geohash)
+		}
+		for bit := 4; bit >= 0; bit-- {
+			r := latRange
+			if isLongitude {
+				r = lonRange
+			}
+			mid := (r[0] + r[1]) / 2
+			if (index>>bit)&1 == 1 {
+				r[0] = mid
+			} else {
+				r[1] = mid
+			}
+			isLongitude = !isLongitude
+		}
+	}
+	return (latRange[0] + latRange[1]) / 2, (lonRange[0] + lonRange[1]) / 2, nil
+}
+
+// ParseLatLon parses the "lat,lon" or "lat lon" formatted string into latitude and longitude
+func ParseLatLon(input string) (latitude float64, longitude float64, err error) {
+	parts := strings.FieldsFunc(input, func(r rune) bool { return r == ',' || r == ' ' || r == ';' })
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid lat/lon value %s", input)
+	}
+	if latitude, err = strconv.ParseFloat(parts[0], 64); err != nil || latitude < -90 || latitude > 90 {
+		return 0, 0, fmt.Errorf("invalid latitude in %s", input)
+	}
+	if longitude, err = strconv.ParseFloat(parts[1], 64); err != nil || longitude < -180 || longitude > 180 {
+		return 0, 0, fmt.Errorf("invalid longitude in %s", input)
+	}
+	return latitude, longitude, nil
+}
+
+// getCoordinateField converts the latitude or longitude field into nullable float64 field
+func getCoordinateField(field *data.Field) *data.Field {
+	out := data.NewFieldFromFieldType(data.FieldTypeNullableFloat64, field.Len())
+	out.Name = field.Name
+	out.Labels = field.Labels
+	out.Config = field.Config
+	for i := 0; i < field.Len(); i++ {
+		value, ok := field.ConcreteAt(i)
+		if !ok {
+			continue
+		}
+		switch v := value.(type) {
+		case float64:
+			out.Set(i, &v)
+		case string:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				out.Set(i, &f)
+			}
+		}
+	}
+	return out
+}
+
+// getFrameWithSplitCoordinates decodes the geohash or lat/lon string field and appends latitude and longitude fields to the frame,
+// so that the geomap panel can pick them up automatically
+func getFrameWithSplitCoordinates(frame *data.Frame, fieldIndex int, decode func(string) (float64, float64, error)) *data.Frame {
+	field := frame.Fields[fieldIndex]
+	latitudeField := data.NewFieldFromFieldType(data.FieldTypeNullableFloat64, field.Len())
+	latitudeField.Name = getUniqueFieldName(frame, "latitude", field.Name+"_latitude")
+	longitudeField := data.NewFieldFromFieldType(data.FieldTypeNullableFloat64, field.Len())
+	longitudeField.Name = getUniqueFieldName(frame, "longitude", field.Name+"_longitude")
+	for i := 0; i < field.Len(); i++ {
+		value, ok := field.ConcreteAt(i)
+		if !ok {
+			continue
+		}
+		if v, ok := value.(string); ok {
+			if latitude, longitude, err := decode(v); err == nil {
+				latitudeField.Set(i, &latitude)
+				longitudeField.Set(i, &longitude)
+			}
+		}
+	}
+	frame.Fields = append(frame.Fields, latitudeField, longitudeField)
+	return frame
+}
+
+func getUniqueFieldName(frame *data.Frame, name string, fallback string) string {
+	for _, field := range frame.Fields {
+		if field.Name == name {
+			return fallback
+		}
+	}
+	return name
+}
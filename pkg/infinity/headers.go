@@ -1,11 +1,10 @@
 package infinity
 
 import (
-	"bytes"
 	"encoding/base64"
 	"fmt"
-	"mime/multipart"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
@@ -16,6 +15,8 @@ const dummyHeader = "xxxxxxxx"
 const (
 	contentTypeJSON           = "application/json"
 	contentTypeFormURLEncoded = "application/x-www-form-urlencoded"
+	contentTypeArrowStream    = "application/vnd.apache.arrow.stream"
+	contentTypeArrowFile      = "application/vnd.apache.arrow.file"
 )
 
 const (
@@ -35,6 +36,9 @@ func ApplyAcceptHeader(query models.Query, settings models.InfinitySettings, req
 	if query.Type == models.QueryTypeXML {
 		req.Header.Set(headerKeyAccept, `text/xml;q=0.9,text/plain`)
 	}
+	if query.Type == models.QueryTypeArrow {
+		req.Header.Set(headerKeyAccept, contentTypeArrowStream+`, `+contentTypeArrowFile+`;q=0.9`)
+	}
 	return req
 }
 
@@ -46,16 +50,11 @@ func ApplyContentTypeHeader(query models.Query, settings models.InfinitySettings
 				req.Header.Set(headerKeyContentType, query.URLOptions.BodyContentType)
 			}
 		case "form-data":
-			writer := multipart.NewWriter(&bytes.Buffer{})
-			for _, f := range query.URLOptions.BodyForm {
-				_ = writer.WriteField(f.Key, f.Value)
-			}
-			if err := writer.Close(); err != nil {
-				return req
-			}
-			req.Header.Set(headerKeyContentType, writer.FormDataContentType())
+			req.Header.Set(headerKeyContentType, getFormDataContentType(query))
 		case "x-www-form-urlencoded":
 			req.Header.Set(headerKeyContentType, contentTypeFormURLEncoded)
+		case "soap":
+			req = ApplySOAPHeaders(query, req)
 		case "graphql":
 			req.Header.Set(headerKeyContentType, contentTypeJSON)
 		default:
@@ -81,6 +80,53 @@ func ApplyHeadersFromSettings(settings models.InfinitySettings, req *http.Reques
 	return req
 }
 
+// GetHeaderProfile returns the header profile selected by the query. When the query doesn't select a profile, the first profile
+// configured for the host of the url is used
+func GetHeaderProfile(settings models.InfinitySettings, query models.Query, requestURL string) (*models.HeaderProfile, error) {
+	name := strings.TrimSpace(query.HeaderProfile)
+	if name == "none" || len(settings.HeaderProfiles) == 0 {
+		return nil, nil
+	}
+	if name != "" {
+		for i := range settings.HeaderProfiles {
+			if settings.HeaderProfiles[i].Name == name {
+				return &settings.HeaderProfiles[i], nil
+			}
+		}
+		return nil, fmt.Errorf("header profile %s not found", name)
+	}
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		return nil, nil
+	}
+	host := strings.ToLower(u.Hostname())
+	for i, profile := range settings.HeaderProfiles {
+		for _, h := range profile.Hosts {
+			h = strings.ToLower(strings.TrimSpace(h))
+			if h == host || (strings.HasPrefix(h, "*.") && strings.HasSuffix(host, h[1:])) {
+				return &settings.HeaderProfiles[i], nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func ApplyHeaderProfile(profile *models.HeaderProfile, req *http.Request, includeSect bool) *http.Request {
+	if profile == nil {
+		return req
+	}
+	for key, value := range profile.Headers {
+		val := dummyHeader
+		if includeSect {
+			val = value
+		}
+		if key != "" {
+			req.Header.Set(key, val)
+		}
+	}
+	return req
+}
+
 func ApplyHeadersFromQuery(query models.Query, settings models.InfinitySettings, req *http.Request, includeSect bool) *http.Request {
 	for _, header := range query.URLOptions.Headers {
 		value := dummyHeader
@@ -0,0 +1,115 @@
+package infinity
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+const frameTypeHeatmapRows data.FrameType = "heatmap-rows"
+
+type heatmapBucket struct {
+	label string
+	upper float64
+}
+
+// GetHeatmapFrame converts the rows of time, bucket and count into the heatmap rows frame, where each bucket becomes a numeric field
+// named by the upper bound of the bucket. Buckets are sorted by their upper bound and the missing counts are filled with zero.
+// When cumulative is set (prometheus style le buckets), the counts are converted into the counts per bucket
+func GetHeatmapFrame(frame *data.Frame, timeField string, bucketField string, countField string, cumulative bool) (*data.Frame, error) {
+	if frame == nil {
+		return frame, nil
+	}
+	if strings.TrimSpace(timeField) == "" || strings.TrimSpace(bucketField) == "" || strings.TrimSpace(countField) == "" {
+		return frame, fmt.Errorf("heatmap requires time, bucket and count fields")
+	}
+	tf, tIdx := frame.FieldByName(timeField)
+	bf, bIdx := frame.FieldByName(bucketField)
+	cf, cIdx := frame.FieldByName(countField)
+	if tIdx == -1 || bIdx == -1 || cIdx == -1 {
+		return frame, fmt.Errorf("heatmap fields %s, %s, %s not found. found %s", timeField, bucketField, countField, describeFields(frame))
+	}
+	times := []time.Time{}
+	counts := map[time.Time]map[string]float64{}
+	buckets := map[string]heatmapBucket{}
+	for i := 0; i < frame.Rows(); i++ {
+		tv, ok := tf.ConcreteAt(i)
+		if !ok {
+			continue
+		}
+		t, ok := tv.(time.Time)
+		if !ok {
+			return frame, fmt.Errorf("heatmap time field %s must be a time field", timeField)
+		}
+		bucket, err := getHeatmapBucket(bf, i)
+		if err != nil {
+			return frame, err
+		}
+		count, err := cf.NullableFloatAt(i)
+		if err != nil {
+			return frame, fmt.Errorf("heatmap count field %s must be a numeric field", countField)
+		}
+		if _, ok := counts[t]; !ok {
+			times = append(times, t)
+			counts[t] = map[string]float64{}
+		}
+		buckets[bucket.label] = bucket
+		if count != nil {
+			counts[t][bucket.label] += *count
+		}
+	}
+	sortedBuckets := []heatmapBucket{}
+	for _, b := range buckets {
+		sortedBuckets = append(sortedBuckets, b)
+	}
+	sort.Slice(sortedBuckets, func(i, j int) bool { return sortedBuckets[i].upper < sortedBuckets[j].upper })
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	out := data.NewFrame(frame.Name, data.NewField(timeField, nil, times))
+	values := make([][]float64, len(sortedBuckets))
+	for b := range sortedBuckets {
+		values[b] = make([]float64, len(times))
+	}
+	for i, t := range times {
+		previous := 0.0
+		for b, bucket := range sortedBuckets {
+			value := counts[t][bucket.label]
+			if cumulative {
+				value, previous = math.Max(value-previous, 0), math.Max(value, previous)
+			}
+			values[b][i] = value
+		}
+	}
+	for b, bucket := range sortedBuckets {
+		out.Fields = append(out.Fields, data.NewField(bucket.label, nil, values[b]))
+	}
+	out.Meta = &data.FrameMeta{Type: frameTypeHeatmapRows}
+	if frame.Meta != nil {
+		out.Meta.Custom = frame.Meta.Custom
+		out.Meta.ExecutedQueryString = frame.Meta.ExecutedQueryString
+	}
+	return out, nil
+}
+
+func getHeatmapBucket(field *data.Field, row int) (heatmapBucket, error) {
+	v, ok := field.ConcreteAt(row)
+	if !ok {
+		return heatmapBucket{}, fmt.Errorf("heatmap bucket field %s has empty value", field.Name)
+	}
+	if s, ok := v.(string); ok {
+		upper, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return heatmapBucket{}, fmt.Errorf("invalid heatmap bucket %s. bucket must be the numeric upper bound", s)
+		}
+		return heatmapBucket{label: strings.TrimSpace(s), upper: upper}, nil
+	}
+	upper, err := field.NullableFloatAt(row)
+	if err != nil || upper == nil {
+		return heatmapBucket{}, fmt.Errorf("heatmap bucket field %s must be a numeric or string field", field.Name)
+	}
+	return heatmapBucket{label: strconv.FormatFloat(*upper, 'f', -1, 64), upper: *upper}, nil
+}
@@ -0,0 +1,217 @@
+package infinity
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+	"github.com/yesoreyeram/grafana-plugins/lib/go/transformations"
+)
+
+const incrementalCacheTable = "incremental"
+
+// IncrementalState holds the high-watermark and the historical frame of an incremental query
+type IncrementalState struct {
+	Watermark string
+	Frame     []byte
+}
+
+// GetIncrementalResults fetches only the records newer than the stored high-watermark
+// and merges them with the historical frame stored in the cache
+func GetIncrementalResults(ctx context.Context, query models.Query, infClient Client, requestHeaders map[string]string) (*data.Frame, error) {
+	ctx, span := tracing.DefaultTracer().Start(ctx, "GetIncrementalResults")
+	defer span.End()
+	cache := infClient.Cache().Table(incrementalCacheTable).WithContext(ctx)
+	key := GetIncrementalKey(infClient.Settings, query)
+	state := &IncrementalState{}
+	if res, err := cache.GetStruct(key); err == nil {
+		if s, ok := res.(*IncrementalState); ok && s != nil {
+			state = s
+		}
+	}
+	since := query.IncrementalInitialValue
+	if state.Watermark != "" {
+		since = state.Watermark
+	}
+	currentQuery := query
+	if query.IncrementalParamType == models.PaginationParamTypeReplace {
+		currentQuery = ReplacePlaceholderInQuery(currentQuery, query.IncrementalParamName, since)
+	} else {
+		currentQuery = ApplyPaginationItemToQuery(currentQuery, query.IncrementalParamType, query.IncrementalParamName, since)
+	}
+	frame, _, err := GetFrameForURLSourcesWithPostProcessing(ctx, currentQuery, infClient, requestHeaders, false)
+	if err != nil {
+		return frame, err
+	}
+	if frame.Rows() > 0 && state.Watermark != "" {
+		if frame, err = filterRowsAfterWatermark(frame, query.IncrementalWatermarkField, state.Watermark); err != nil {
+			return frame, err
+		}
+	}
+	mergedFrame := frame
+	if len(state.Frame) > 0 {
+		historicalFrame, err := data.UnmarshalArrowFrame(state.Frame)
+		if err != nil {
+			backend.Logger.Warn("error reading the historical frame of incremental query", "error", err.Error())
+		}
+		if err == nil && frame.Rows() == 0 {
+			mergedFrame = historicalFrame
+		}
+		if err == nil && frame.Rows() > 0 {
+			if mergedFrame, err = transformations.Merge([]*data.Frame{historicalFrame, frame}, transformations.MergeFramesOptions{}); err != nil {
+				backend.Logger.Warn("discarding the historical frame of incremental query due to schema change", "error", err.Error())
+				mergedFrame = frame
+			}
+		}
+	}
+	mergedFrame.Name = frame.Name
+	mergedFrame.Meta = frame.Meta
+	watermark := state.Watermark
+	if mergedFrame.Rows() > 0 {
+		if watermark, err = GetWatermark(mergedFrame, query.IncrementalWatermarkField); err != nil {
+			return mergedFrame, err
+		}
+	}
+	if err := saveIncrementalState(cache, key, watermark, mergedFrame); err != nil {
+		backend.Logger.Error("error saving the incremental query state", "error", err.Error())
+	}
+	return PostProcessFrame(ctx, mergedFrame, query)
+}
+
+// GetIncrementalKey returns the cache key used to store the incremental state of the query
+func GetIncrementalKey(settings models.InfinitySettings, query models.Query) string {
+	parts := []string{settings.URL, query.IncrementalWatermarkField}
+	if query.IncrementalKey != "" {
+		parts = append(parts, query.IncrementalKey)
+	}
+	if query.IncrementalKey == "" {
+		parts = append(parts, string(query.Type), query.URL, query.URLOptions.Method, query.URLOptions.Body, query.RootSelector)
+		for _, param := range query.URLOptions.Params {
+			parts = append(parts, param.Key+"="+param.Value)
+		}
+	}
+	hash := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(hash[:])
+}
+
+// GetWatermark returns the max value of the given field as string
+func GetWatermark(frame *data.Frame, fieldName string) (string, error) {
+	field, _ := frame.FieldByName(fieldName)
+	if field == nil {
+		return "", fmt.Errorf("incremental watermark field %s not found", fieldName)
+	}
+	var watermark any
+	for i := 0; i < field.Len(); i++ {
+		value, ok := field.ConcreteAt(i)
+		if !ok {
+			continue
+		}
+		if watermark == nil || compareWatermarkValues(value, watermark) > 0 {
+			watermark = value
+		}
+	}
+	return formatWatermark(watermark), nil
+}
+
+func filterRowsAfterWatermark(frame *data.Frame, fieldName string, watermark string) (*data.Frame, error) {
+	_, fieldIndex := frame.FieldByName(fieldName)
+	if fieldIndex == -1 {
+		return frame, fmt.Errorf("incremental watermark field %s not found", fieldName)
+	}
+	return frame.FilterRowsByField(fieldIndex, func(i interface{}) (bool, error) {
+		value := getConcreteValue(i)
+		if value == nil {
+			return false, nil
+		}
+		previous, err := parseWatermark(watermark, value)
+		if err != nil {
+			return true, nil
+		}
+		return compareWatermarkValues(value, previous) > 0, nil
+	})
+}
+
+func saveIncrementalState(cache *Sett, key string, watermark string, frame *data.Frame) error {
+	frameBytes, err := frame.MarshalArrow()
+	if err != nil {
+		return err
+	}
+	return cache.SetStruct(key, &IncrementalState{Watermark: watermark, Frame: frameBytes})
+}
+
+func getConcreteValue(value any) any {
+	switch v := value.(type) {
+	case *float64:
+		if v != nil {
+			return *v
+		}
+		return nil
+	case *time.Time:
+		if v != nil {
+			return *v
+		}
+		return nil
+	case *string:
+		if v != nil {
+			return *v
+		}
+		return nil
+	default:
+		return value
+	}
+}
+
+func formatWatermark(value any) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func parseWatermark(watermark string, like any) (any, error) {
+	switch like.(type) {
+	case time.Time:
+		return time.Parse(time.RFC3339Nano, watermark)
+	case float64:
+		return strconv.ParseFloat(watermark, 64)
+	case string:
+		return watermark, nil
+	default:
+		return nil, errors.New("unsupported watermark field type")
+	}
+}
+
+func compareWatermarkValues(a any, b any) int {
+	switch x := a.(type) {
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			return x.Compare(y)
+		}
+	case float64:
+		if y, ok := b.(float64); ok {
+			switch {
+			case x > y:
+				return 1
+			case x < y:
+				return -1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(formatWatermark(a), formatWatermark(b))
+}
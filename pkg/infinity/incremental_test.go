@@ -0,0 +1,55 @@
+package infinity_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestGetIncrementalResults(t *testing.T) {
+	sinceValues := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		since := r.URL.Query().Get("since")
+		sinceValues = append(sinceValues, since)
+		sinceID, _ := strconv.Atoi(since)
+		records := []string{}
+		for id := sinceID; id <= sinceID+2 && id <= 4; id++ {
+			records = append(records, fmt.Sprintf(`{ "id": %d, "value": "v%d" }`, id, id))
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(records, ","))
+	}))
+	defer server.Close()
+	client, err := infinity.NewClient(context.Background(), models.InfinitySettings{})
+	require.Nil(t, err)
+	query := models.ApplyDefaultsToQuery(context.Background(), models.Query{
+		RefID:                     "A",
+		Type:                      models.QueryTypeJSON,
+		Parser:                    models.InfinityParserBackend,
+		Source:                    "url",
+		URL:                       server.URL + "?since=${__since}",
+		IncrementalWatermarkField: "id",
+		IncrementalInitialValue:   "1",
+		IncrementalKey:            "incremental-test",
+	})
+	frame, err := infinity.GetFrameForURLSources(context.Background(), query, *client, map[string]string{})
+	require.Nil(t, err)
+	require.Equal(t, 3, frame.Rows())
+	frame, err = infinity.GetFrameForURLSources(context.Background(), query, *client, map[string]string{})
+	require.Nil(t, err)
+	require.Equal(t, 4, frame.Rows())
+	frame, err = infinity.GetFrameForURLSources(context.Background(), query, *client, map[string]string{})
+	require.Nil(t, err)
+	require.Equal(t, 4, frame.Rows())
+	require.Equal(t, []string{"1", "3", "4"}, sinceValues)
+	field, _ := frame.FieldByName("value")
+	require.NotNil(t, field)
+	require.Equal(t, "v4", *field.At(3).(*string))
+}
@@ -2,14 +2,18 @@ package infinity
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
 	"github.com/yesoreyeram/grafana-plugins/lib/go/jsonframer"
 )
 
-func GetFrameForInlineSources(ctx context.Context, query models.Query) (*data.Frame, error) {
+func GetFrameForInlineSources(ctx context.Context, query models.Query) (_ *data.Frame, err error) {
+	defer recoverParserPanic(string(query.Type), &err)
 	frame := GetDummyFrame(query)
 	if query.Type == models.QueryTypeGROQ || query.Type == models.QueryTypeUQL {
 		return frame, nil
@@ -30,6 +34,23 @@ func GetFrameForInlineSources(ctx context.Context, query models.Query) (*data.Fr
 			return frame, err
 		}
 		return PostProcessFrame(ctx, frame, query)
+	case models.QueryTypeLogs:
+		frame, err := GetLogsBackendResponse(ctx, query.Data, query)
+		if err != nil {
+			return frame, err
+		}
+		return PostProcessFrame(ctx, frame, query)
+	case models.QueryTypeArrow:
+		// inline arrow data is base64 encoded, as the query model is json
+		body, err := base64.StdEncoding.DecodeString(strings.TrimSpace(query.Data))
+		if err != nil {
+			return nil, UserError(fmt.Errorf("inline arrow data should be base64 encoded. %w", err))
+		}
+		frame, err := GetArrowBackendResponse(ctx, body, query)
+		if err != nil {
+			return frame, err
+		}
+		return PostProcessFrame(ctx, frame, query)
 	case models.QueryTypeJSON, models.QueryTypeGraphQL:
 		columns := []jsonframer.ColumnSelector{}
 		for _, c := range query.Columns {
@@ -0,0 +1,106 @@
+package infinity
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+const (
+	reverseDNSCacheTable   = "rdns"
+	reverseDNSCacheTTL     = time.Hour
+	reverseDNSLookupTimout = 2 * time.Second
+)
+
+// GetFrameWithIPEnrichment enriches the IP address field of the frame with the hostname (reverse DNS) and the tag of the
+// first matching CIDR block. Reverse DNS results, including failed lookups, are cached in the badger store.
+func GetFrameWithIPEnrichment(frame *data.Frame, fieldName string, reverseDNS bool, cidrTags []models.CIDRTag) (*data.Frame, error) {
+	if frame == nil {
+		return frame, nil
+	}
+	fieldIndex := -1
+	for i, field := range frame.Fields {
+		if field.Name == fieldName {
+			fieldIndex = i
+			break
+		}
+	}
+	if fieldIndex < 0 {
+		return frame, fmt.Errorf("ip field %s not found", fieldName)
+	}
+	networks := []*net.IPNet{}
+	for _, tag := range cidrTags {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(tag.CIDR))
+		if err != nil {
+			return frame, fmt.Errorf("invalid CIDR %s. %w", tag.CIDR, err)
+		}
+		networks = append(networks, network)
+	}
+	field := frame.Fields[fieldIndex]
+	hostnameField := data.NewFieldFromFieldType(data.FieldTypeNullableString, field.Len())
+	hostnameField.Name = fieldName + "_hostname"
+	tagField := data.NewFieldFromFieldType(data.FieldTypeNullableString, field.Len())
+	tagField.Name = fieldName + "_tag"
+	if reverseDNS {
+		BadgerInit()
+	}
+	for i := 0; i < field.Len(); i++ {
+		value, ok := field.ConcreteAt(i)
+		if !ok {
+			continue
+		}
+		ipString, ok := value.(string)
+		if !ok {
+			continue
+		}
+		ip := net.ParseIP(strings.TrimSpace(ipString))
+		if ip == nil {
+			continue
+		}
+		for j, network := range networks {
+			if network.Contains(ip) {
+				tagField.Set(i, &cidrTags[j].Label)
+				break
+			}
+		}
+		if reverseDNS {
+			if hostname := getHostname(ip.String()); hostname != "" {
+				hostnameField.Set(i, &hostname)
+			}
+		}
+	}
+	if reverseDNS {
+		frame.Fields = append(frame.Fields, hostnameField)
+	}
+	if len(cidrTags) > 0 {
+		frame.Fields = append(frame.Fields, tagField)
+	}
+	return frame, nil
+}
+
+func getHostname(ip string) string {
+	cache := GetBadgerDB().Table(reverseDNSCacheTable)
+	if hostname, err := cache.GetStr(ip); err == nil {
+		return hostname
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), reverseDNSLookupTimout)
+	defer cancel()
+	hostname := ""
+	names, err := net.DefaultResolver.LookupAddr(ctx, ip)
+	if err != nil {
+		backend.Logger.Debug("reverse dns lookup failed", "ip", ip, "error", err.Error())
+	}
+	if err == nil && len(names) > 0 {
+		hostname = strings.TrimSuffix(names[0], ".")
+	}
+	if err := cache.WithTTL(reverseDNSCacheTTL).SetStr(ip, hostname); err != nil {
+		backend.Logger.Error("error caching reverse dns lookup", "ip", ip, "error", err.Error())
+	}
+	return hostname
+}
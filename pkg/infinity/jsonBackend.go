@@ -12,11 +12,14 @@ import (
 	"github.com/yesoreyeram/grafana-plugins/lib/go/jsonframer"
 )
 
-func GetJSONBackendResponse(ctx context.Context, urlResponseObject any, query models.Query) (*data.Frame, error) {
+func GetJSONBackendResponse(ctx context.Context, urlResponseObject any, query models.Query) (frame *data.Frame, err error) {
 	_, span := tracing.DefaultTracer().Start(ctx, "GetJSONBackendResponse")
 	defer span.End()
-	frame := GetDummyFrame(query)
-	responseString, err := json.Marshal(urlResponseObject)
+	defer trackTiming(ctx, parseTiming)()
+	frame = GetDummyFrame(query)
+	defer recoverParserPanic("json", &err)
+	urlResponseObject, query = applySourcePresetToResponse(query, urlResponseObject)
+	responseString, err := json.Marshal(normalizeMixedTypes(urlResponseObject))
 	if err != nil {
 		backend.Logger.Error("error json parsing root data", "error", err.Error())
 		frame.Meta.Custom = &CustomMeta{Query: query, Error: err.Error()}
@@ -41,3 +44,98 @@ func GetJSONBackendResponse(ctx context.Context, urlResponseObject any, query mo
 	}
 	return frame, err
 }
+
+// normalizeMixedTypes returns the copy of the response in which the fields having the values of different json types across
+// the rows of an array, such as the number in one row and the string in another, are converted to strings. Objects and arrays
+// of such fields are converted to their json. The frame fields are typed by the values, so the mixed fields become string fields
+func normalizeMixedTypes(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, item := range t {
+			out[k] = normalizeMixedTypes(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, item := range t {
+			out[i] = normalizeMixedTypes(item)
+		}
+		kinds := map[string]map[string]bool{}
+		for _, row := range out {
+			collectJSONKinds("", row, kinds)
+		}
+		mixed := map[string]bool{}
+		for path, k := range kinds {
+			if len(k) > 1 {
+				mixed[path] = true
+			}
+		}
+		if len(mixed) == 0 {
+			return out
+		}
+		for i, row := range out {
+			out[i] = stringifyMixedPaths("", row, mixed)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func jsonKind(v any) string {
+	switch v.(type) {
+	case nil:
+		return ""
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	default:
+		return "number"
+	}
+}
+
+func collectJSONKinds(path string, v any, kinds map[string]map[string]bool) {
+	kind := jsonKind(v)
+	if kind == "" {
+		return
+	}
+	if kinds[path] == nil {
+		kinds[path] = map[string]bool{}
+	}
+	kinds[path][kind] = true
+	if m, ok := v.(map[string]any); ok {
+		for k, item := range m {
+			collectJSONKinds(path+"."+k, item, kinds)
+		}
+	}
+}
+
+func stringifyMixedPaths(path string, v any, mixed map[string]bool) any {
+	if v == nil {
+		return nil
+	}
+	if mixed[path] {
+		if s, ok := v.(string); ok {
+			return s
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return v
+	}
+	for k, item := range m {
+		m[k] = stringifyMixedPaths(path+"."+k, item, mixed)
+	}
+	return m
+}
@@ -0,0 +1,285 @@
+package infinity
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	kubernetesServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	// kubernetesTokenRefreshInterval is how often the token file is read again. projected service account tokens are rotated by the kubelet
+	kubernetesTokenRefreshInterval = time.Minute
+)
+
+// kubernetesCredentials are the api server and the credentials of the cluster
+type kubernetesCredentials struct {
+	Server             string
+	CAData             []byte
+	InsecureSkipVerify bool
+	Token              string
+	TokenFile          string
+	ClientCert         []byte
+	ClientKey          []byte
+}
+
+// kubeconfig is the subset of the kubeconfig file used by the plugin. exec and auth-provider plugins are not supported
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string `yaml:"token"`
+			TokenFile             string `yaml:"tokenFile"`
+			ClientCertificate     string `yaml:"client-certificate"`
+			ClientCertificateData string `yaml:"client-certificate-data"`
+			ClientKey             string `yaml:"client-key"`
+			ClientKeyData         string `yaml:"client-key-data"`
+			Exec                  any    `yaml:"exec"`
+			AuthProvider          any    `yaml:"auth-provider"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster string `yaml:"cluster"`
+			User    string `yaml:"user"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+// ApplyKubernetesAuth authenticates the requests to the kubernetes api server with the in-cluster service account or the kubeconfig context.
+// The url of the datasource defaults to the api server. Credentials are never sent to the other hosts
+func ApplyKubernetesAuth(ctx context.Context, httpClient *http.Client, settings models.InfinitySettings) (*http.Client, models.InfinitySettings, error) {
+	_, span := tracing.DefaultTracer().Start(ctx, "ApplyKubernetesAuth")
+	defer span.End()
+	if settings.AuthenticationMethod != models.AuthenticationMethodKubernetes {
+		return httpClient, settings, nil
+	}
+	var creds *kubernetesCredentials
+	var err error
+	switch settings.KubernetesSettings.CredentialsType {
+	case models.KubernetesCredentialsKubeconfig:
+		creds, err = getKubeconfigCredentials(settings.KubernetesSettings)
+	default:
+		creds, err = getInClusterCredentials()
+	}
+	if err != nil {
+		return httpClient, settings, err
+	}
+	serverURL, err := url.Parse(creds.Server)
+	if err != nil || serverURL.Host == "" {
+		return httpClient, settings, fmt.Errorf("invalid kubernetes api server %s", creds.Server)
+	}
+	if settings.URL == "" {
+		settings.URL = strings.TrimSuffix(creds.Server, "/")
+	}
+	base, ok := httpClient.Transport.(*http.Transport)
+	if !ok {
+		return httpClient, settings, errors.New("invalid http transport for kubernetes authentication")
+	}
+	transport := base.Clone()
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	if len(creds.CAData) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(creds.CAData) {
+			return httpClient, settings, errors.New("invalid kubernetes certificate authority")
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+	transport.TLSClientConfig.InsecureSkipVerify = transport.TLSClientConfig.InsecureSkipVerify || creds.InsecureSkipVerify
+	if len(creds.ClientCert) > 0 {
+		cert, err := tls.X509KeyPair(creds.ClientCert, creds.ClientKey)
+		if err != nil {
+			return httpClient, settings, fmt.Errorf("invalid kubernetes client certificate. %w", err)
+		}
+		transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	}
+	httpClient.Transport = &kubernetesTransport{next: transport, host: serverURL.Host, creds: creds}
+	return httpClient, settings, nil
+}
+
+func getInClusterCredentials() (*kubernetesCredentials, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("in-cluster kubernetes credentials are only available when grafana runs in the cluster. use kubeconfig credentials instead")
+	}
+	ca, err := os.ReadFile(filepath.Join(kubernetesServiceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading the service account certificate authority. %w", err)
+	}
+	return &kubernetesCredentials{
+		Server:    "https://" + net.JoinHostPort(host, port),
+		CAData:    ca,
+		TokenFile: filepath.Join(kubernetesServiceAccountDir, "token"),
+	}, nil
+}
+
+// getKubeconfigCredentials reads the kubeconfig selected by the datasource from the kubeconfig directory of the plugin settings.
+// Without the selection, the default kubeconfig of the plugin process is used
+func getKubeconfigCredentials(settings models.KubernetesSettings) (*kubernetesCredentials, error) {
+	path := ""
+	if settings.KubeconfigPath != "" {
+		if settings.KubeconfigDirectory == "" {
+			return nil, errors.New("kubeconfig path requires the kubeconfig directory in the plugin settings (kubeconfig_directory)")
+		}
+		var err error
+		if path, err = resolveConfinedPath(settings.KubeconfigDirectory, settings.KubeconfigPath); err != nil {
+			return nil, fmt.Errorf("invalid kubeconfig path. %w", err)
+		}
+	}
+	if path == "" {
+		path = strings.Split(os.Getenv("KUBECONFIG"), string(os.PathListSeparator))[0]
+	}
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(home, ".kube", "config")
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading kubeconfig. %w", err)
+	}
+	config := kubeconfig{}
+	if err := yaml.Unmarshal(b, &config); err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig. %w", err)
+	}
+	contextName := settings.Context
+	if contextName == "" {
+		contextName = config.CurrentContext
+	}
+	creds := &kubernetesCredentials{}
+	found := false
+	for _, c := range config.Contexts {
+		if c.Name != contextName {
+			continue
+		}
+		found = true
+		for _, cluster := range config.Clusters {
+			if cluster.Name == c.Context.Cluster {
+				creds.Server = cluster.Cluster.Server
+				creds.InsecureSkipVerify = cluster.Cluster.InsecureSkipTLSVerify
+				if creds.CAData, err = readKubeconfigData(path, cluster.Cluster.CertificateAuthorityData, cluster.Cluster.CertificateAuthority); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for _, user := range config.Users {
+			if user.Name != c.Context.User {
+				continue
+			}
+			if user.User.Exec != nil || user.User.AuthProvider != nil {
+				return nil, fmt.Errorf("exec and auth-provider credentials of the kubeconfig user %s are not supported. use token or client certificate", user.Name)
+			}
+			creds.Token = user.User.Token
+			if user.User.TokenFile != "" {
+				creds.TokenFile = resolveKubeconfigPath(path, user.User.TokenFile)
+			}
+			if creds.ClientCert, err = readKubeconfigData(path, user.User.ClientCertificateData, user.User.ClientCertificate); err != nil {
+				return nil, err
+			}
+			if creds.ClientKey, err = readKubeconfigData(path, user.User.ClientKeyData, user.User.ClientKey); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("kubeconfig context %s not found", contextName)
+	}
+	return creds, nil
+}
+
+// readKubeconfigData returns the base64 encoded inline data or the content of the file relative to the kubeconfig
+func readKubeconfigData(kubeconfigPath string, data string, file string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if file == "" {
+		return nil, nil
+	}
+	return os.ReadFile(resolveKubeconfigPath(kubeconfigPath, file))
+}
+
+func resolveKubeconfigPath(kubeconfigPath string, file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(filepath.Dir(kubeconfigPath), file)
+}
+
+// kubernetesTransport adds the bearer token to the requests of the api server
+type kubernetesTransport struct {
+	next  http.RoundTripper
+	host  string
+	creds *kubernetesCredentials
+
+	mu        sync.Mutex
+	token     string
+	tokenRead time.Time
+}
+
+func (t *kubernetesTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !strings.EqualFold(req.URL.Host, t.host) {
+		return t.next.RoundTrip(req)
+	}
+	token, err := t.getToken()
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req = req.Clone(req.Context())
+		req.Header.Set(headerKeyAuthorization, "Bearer "+token)
+	}
+	return t.next.RoundTrip(req)
+}
+
+func (t *kubernetesTransport) getToken() (string, error) {
+	if t.creds.TokenFile == "" {
+		return t.creds.Token, nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token != "" && time.Since(t.tokenRead) < kubernetesTokenRefreshInterval {
+		return t.token, nil
+	}
+	b, err := os.ReadFile(t.creds.TokenFile)
+	if err != nil {
+		return "", fmt.Errorf("error reading the kubernetes token. %w", err)
+	}
+	t.token, t.tokenRead = strings.TrimSpace(string(b)), time.Now()
+	return t.token, nil
+}
+
+func (t *kubernetesTransport) CloseIdleConnections() {
+	if c, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
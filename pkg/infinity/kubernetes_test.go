@@ -0,0 +1,133 @@
+package infinity_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func writeKubeconfig(t *testing.T, server *httptest.Server, user string) string {
+	t.Helper()
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	path := filepath.Join(t.TempDir(), "config")
+	config := fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: test
+clusters:
+- name: test
+  cluster:
+    server: %s
+    certificate-authority-data: %s
+users:
+- name: test
+  user:
+%s
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: test
+`, server.URL, base64.StdEncoding.EncodeToString(ca), user)
+	require.Nil(t, os.WriteFile(path, []byte(config), 0600))
+	return path
+}
+
+func kubeconfigSettings(path string) models.KubernetesSettings {
+	return models.KubernetesSettings{CredentialsType: models.KubernetesCredentialsKubeconfig, KubeconfigDirectory: filepath.Dir(path), KubeconfigPath: filepath.Base(path)}
+}
+
+func TestKubernetesSource(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		require.Equal(t, "/api/v1/pods", r.URL.Path)
+		require.Equal(t, "500", r.URL.Query().Get("limit"))
+		require.Equal(t, "app=web", r.URL.Query().Get("labelSelector"))
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("continue") == "" {
+			_, _ = w.Write([]byte(`{ "kind": "PodList", "metadata": { "continue": "next" }, "items": [{
+				"metadata": { "name": "web-1", "namespace": "default", "labels": { "app": "web" }, "creationTimestamp": "2023-01-01T10:00:00Z", "managedFields": [{}] },
+				"status": { "phase": "Running", "podIP": "10.0.0.1" }
+			}] }`))
+			return
+		}
+		_, _ = w.Write([]byte(`{ "kind": "PodList", "metadata": {}, "items": [{
+			"metadata": { "name": "web-2", "namespace": "default", "labels": { "app": "web" }, "creationTimestamp": "2023-01-01T11:00:00Z" },
+			"status": { "phase": "Pending" }
+		}] }`))
+	}))
+	defer server.Close()
+	settings := models.InfinitySettings{
+		AuthenticationMethod: models.AuthenticationMethodKubernetes,
+		KubernetesSettings:   kubeconfigSettings(writeKubeconfig(t, server, "    token: secret")),
+	}
+	require.Nil(t, settings.Validate())
+	client, err := infinity.NewClient(context.Background(), settings)
+	require.Nil(t, err)
+	require.Equal(t, server.URL, client.Settings.URL)
+	query := models.ApplyDefaultsToQuery(context.Background(), models.Query{RefID: "A", Source: "url", URL: "/api/v1/pods", SourcePreset: models.SourcePresetKubernetes, PageMaxPages: 2,
+		URLOptions: models.URLOptions{Params: []models.URLOptionKeyValuePair{{Key: "labelSelector", Value: "app=web"}}}})
+	frame, err := infinity.GetFrameForURLSources(context.Background(), query, *client, map[string]string{})
+	require.NoError(t, err)
+	require.Equal(t, 2, frame.Rows())
+	name, _ := frame.FieldByName("metadata.name")
+	require.Equal(t, "web-2", *name.At(1).(*string))
+	podIP, _ := frame.FieldByName("status.podIP")
+	require.Nil(t, podIP.At(1))
+	created, _ := frame.FieldByName("metadata.creationTimestamp")
+	require.Equal(t, time.Date(2023, 1, 1, 11, 0, 0, 0, time.UTC), created.At(1).(*time.Time).UTC())
+	phase, _ := frame.FieldByName("status.phase")
+	require.Equal(t, "Pending", *phase.At(1).(*string))
+	_, idx := frame.FieldByName("metadata.managedFields")
+	require.Equal(t, -1, idx)
+	t.Run("should not send the token to the other hosts", func(t *testing.T) {
+		other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Empty(t, r.Header.Get("Authorization"))
+		}))
+		defer other.Close()
+		res, err := client.HttpClient.Get(other.URL)
+		require.Nil(t, err)
+		res.Body.Close()
+	})
+	t.Run("should reject the exec credentials", func(t *testing.T) {
+		settings := models.InfinitySettings{
+			AuthenticationMethod: models.AuthenticationMethodKubernetes,
+			KubernetesSettings:   kubeconfigSettings(writeKubeconfig(t, server, "    exec:\n      command: aws")),
+		}
+		_, err := infinity.NewClient(context.Background(), settings)
+		require.ErrorContains(t, err, "exec and auth-provider credentials of the kubeconfig user test are not supported")
+	})
+	t.Run("should confine the kubeconfig path to the kubeconfig directory", func(t *testing.T) {
+		path := writeKubeconfig(t, server, "    token: secret")
+		settings := models.InfinitySettings{AuthenticationMethod: models.AuthenticationMethodKubernetes, KubernetesSettings: kubeconfigSettings(path)}
+		settings.KubernetesSettings.KubeconfigDirectory = ""
+		_, err := infinity.NewClient(context.Background(), settings)
+		require.ErrorContains(t, err, "kubeconfig path requires the kubeconfig directory in the plugin settings")
+		settings.KubernetesSettings.KubeconfigDirectory = t.TempDir()
+		settings.KubernetesSettings.KubeconfigPath = path
+		_, err = infinity.NewClient(context.Background(), settings)
+		require.ErrorIs(t, err, infinity.ErrPathOutsideBase)
+		require.Nil(t, os.Symlink(path, filepath.Join(settings.KubernetesSettings.KubeconfigDirectory, "config")))
+		settings.KubernetesSettings.KubeconfigPath = "config"
+		_, err = infinity.NewClient(context.Background(), settings)
+		require.ErrorIs(t, err, infinity.ErrPathOutsideBase)
+	})
+	t.Run("should fail in-cluster credentials outside the cluster", func(t *testing.T) {
+		t.Setenv("KUBERNETES_SERVICE_HOST", "")
+		_, err := infinity.NewClient(context.Background(), models.InfinitySettings{AuthenticationMethod: models.AuthenticationMethodKubernetes})
+		require.ErrorContains(t, err, "in-cluster kubernetes credentials are only available when grafana runs in the cluster")
+	})
+}
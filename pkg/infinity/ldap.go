@@ -0,0 +1,166 @@
+package infinity
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+const (
+	defaultLDAPFilter    = "(objectClass=*)"
+	defaultLDAPSizeLimit = 1000
+	maxLDAPSizeLimit     = 10000
+)
+
+// GetFrameForLDAP searches the directory and returns the entries as the rows, with the dn column followed by the attributes.
+// Single valued attributes are returned as the strings, multi valued attributes as the arrays
+func GetFrameForLDAP(ctx context.Context, query models.Query, infClient Client) (*data.Frame, error) {
+	ctx, span := tracing.DefaultTracer().Start(ctx, "GetFrameForLDAP")
+	defer span.End()
+	if query.LDAPOptions == nil || strings.TrimSpace(query.LDAPOptions.BaseDN) == "" {
+		return nil, UserError(errors.New("invalid or empty base dn of the ldap query"))
+	}
+	options := *query.LDAPOptions
+	if options.Server == "" {
+		options.Server = infClient.Settings.URL
+	}
+	if !strings.HasPrefix(options.Server, "ldap://") && !strings.HasPrefix(options.Server, "ldaps://") {
+		return nil, UserError(fmt.Errorf("invalid ldap server %s. server should start with ldap:// or ldaps://", options.Server))
+	}
+	if !CanAllowURL(options.Server, infClient.Settings.AllowedHosts) {
+		return nil, UserError(fmt.Errorf("ldap server %s is not in the allowed hosts", options.Server))
+	}
+	if options.Filter == "" {
+		options.Filter = defaultLDAPFilter
+	}
+	if _, err := ldap.CompileFilter(options.Filter); err != nil {
+		return nil, UserError(fmt.Errorf("invalid ldap filter %s. %w", options.Filter, err))
+	}
+	scope, err := getLDAPScope(options.Scope)
+	if err != nil {
+		return nil, err
+	}
+	if options.SizeLimit <= 0 {
+		options.SizeLimit = defaultLDAPSizeLimit
+	}
+	if options.SizeLimit > maxLDAPSizeLimit {
+		return nil, UserError(fmt.Errorf("invalid ldap size limit %d. size limit should be up to %d", options.SizeLimit, maxLDAPSizeLimit))
+	}
+	conn, err := getLDAPConnection(options.Server, infClient.Settings)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	// go-ldap doesn't accept the context, so the connection is closed when the query is cancelled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	if infClient.Settings.BasicAuthEnabled || infClient.Settings.AuthenticationMethod == models.AuthenticationMethodBasic {
+		if err := conn.Bind(infClient.Settings.UserName, infClient.Settings.Password); err != nil {
+			return nil, getLDAPError(ctx, fmt.Errorf("error binding to the ldap server. %w", err))
+		}
+	}
+	request := ldap.NewSearchRequest(options.BaseDN, scope, ldap.NeverDerefAliases, options.SizeLimit, 0, false, options.Filter, options.Attributes, nil)
+	result, err := conn.Search(request)
+	truncated := ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded)
+	if err != nil && !truncated {
+		return nil, getLDAPError(ctx, fmt.Errorf("error searching the ldap server. %w", err))
+	}
+	rows := getLDAPRows(result.Entries)
+	rowsQuery := query
+	rowsQuery.RootSelector = ""
+	frame, err := GetJSONBackendResponse(ctx, rows, rowsQuery)
+	if err != nil {
+		return frame, err
+	}
+	frame, err = PostProcessFrame(ctx, frame, rowsQuery)
+	if frame != nil && truncated {
+		frame.AppendNotices(data.Notice{Severity: data.NoticeSeverityWarning, Text: fmt.Sprintf("Results are truncated to the first %d entries", options.SizeLimit)})
+	}
+	return frame, err
+}
+
+func getLDAPScope(scope models.LDAPScope) (int, error) {
+	switch scope {
+	case models.LDAPScopeBase:
+		return ldap.ScopeBaseObject, nil
+	case models.LDAPScopeOne:
+		return ldap.ScopeSingleLevel, nil
+	case models.LDAPScopeSub, "":
+		return ldap.ScopeWholeSubtree, nil
+	default:
+		return 0, UserError(fmt.Errorf("invalid ldap scope %s. scope should be base, one or sub", scope))
+	}
+}
+
+func getLDAPConnection(server string, settings models.InfinitySettings) (*ldap.Conn, error) {
+	tlsConfig, err := GetTLSConfigFromSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+	timeout := time.Duration(settings.TimeoutInSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	conn, err := ldap.DialURL(server, ldap.DialWithTLSConfig(tlsConfig), ldap.DialWithDialer(&net.Dialer{Timeout: timeout}))
+	if err != nil {
+		return nil, DownstreamError(fmt.Errorf("error connecting to the ldap server %s. %w", server, err), 0)
+	}
+	conn.SetTimeout(timeout)
+	return conn, nil
+}
+
+// getLDAPError returns the downstream error for the network failures and the server errors, and the user error for the errors caused by the query or the credentials
+func getLDAPError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ContextError(ctx)
+	}
+	var ldapErr *ldap.Error
+	if errors.As(err, &ldapErr) {
+		switch ldapErr.ResultCode {
+		case ldap.LDAPResultInvalidCredentials, ldap.LDAPResultNoSuchObject, ldap.LDAPResultInvalidDNSyntax, ldap.LDAPResultInsufficientAccessRights, ldap.LDAPResultFilterError:
+			return UserError(err)
+		}
+	}
+	return DownstreamError(err, 0)
+}
+
+func getLDAPRows(entries []*ldap.Entry) []any {
+	rows := make([]any, 0, len(entries))
+	for _, entry := range entries {
+		row := map[string]any{"dn": entry.DN}
+		for _, attribute := range entry.Attributes {
+			values := make([]any, 0, len(attribute.Values))
+			for _, v := range attribute.Values {
+				// binary attributes such as objectGUID and jpegPhoto are base64 encoded
+				if !utf8.ValidString(v) {
+					v = base64.StdEncoding.EncodeToString([]byte(v))
+				}
+				values = append(values, v)
+			}
+			if len(values) == 1 {
+				row[attribute.Name] = values[0]
+				continue
+			}
+			row[attribute.Name] = values
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
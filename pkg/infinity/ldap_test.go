@@ -0,0 +1,156 @@
+package infinity_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestGetFrameForLDAP(t *testing.T) {
+	server := startLDAPServer(t, "cn=admin,dc=example,dc=com", "secret", []ldapEntry{
+		{DN: "uid=alice,ou=people,dc=example,dc=com", Attributes: map[string][]string{"cn": {"Alice"}, "mail": {"alice@example.com"}, "memberOf": {"cn=ops", "cn=dev"}}},
+		{DN: "uid=bob,ou=people,dc=example,dc=com", Attributes: map[string][]string{"cn": {"Bob"}, "mail": {"bob@example.com"}}},
+	})
+	settings := models.InfinitySettings{URL: server, BasicAuthEnabled: true, UserName: "cn=admin,dc=example,dc=com", Password: "secret", TimeoutInSeconds: 2}
+	t.Run("search", func(t *testing.T) {
+		query := models.Query{RefID: "A", Source: "ldap", LDAPOptions: &models.LDAPOptions{BaseDN: "ou=people,dc=example,dc=com", Filter: "(objectClass=person)", Attributes: []string{"cn", "mail"}}}
+		frame, err := infinity.GetFrameForLDAP(context.Background(), query, infinity.Client{Settings: settings})
+		require.Nil(t, err)
+		require.Equal(t, 2, frame.Rows())
+		dn, _ := frame.FieldByName("dn")
+		require.Equal(t, "uid=bob,ou=people,dc=example,dc=com", *dn.At(1).(*string))
+		mail, _ := frame.FieldByName("mail")
+		require.Equal(t, "alice@example.com", *mail.At(0).(*string))
+		_, index := frame.FieldByName("memberOf")
+		require.Equal(t, -1, index)
+	})
+	t.Run("size limit truncates the results", func(t *testing.T) {
+		query := models.Query{RefID: "A", Source: "ldap", LDAPOptions: &models.LDAPOptions{BaseDN: "ou=people,dc=example,dc=com", SizeLimit: 1}}
+		frame, err := infinity.GetFrameForLDAP(context.Background(), query, infinity.Client{Settings: settings})
+		require.Nil(t, err)
+		require.Equal(t, 1, frame.Rows())
+		require.Equal(t, "Results are truncated to the first 1 entries", frame.Meta.Notices[0].Text)
+		memberOf, _ := frame.FieldByName("memberOf")
+		require.Equal(t, `["cn=ops","cn=dev"]`, *memberOf.At(0).(*string))
+	})
+	t.Run("invalid credentials", func(t *testing.T) {
+		settings := settings
+		settings.Password = "wrong"
+		query := models.Query{RefID: "A", Source: "ldap", LDAPOptions: &models.LDAPOptions{BaseDN: "dc=example,dc=com"}}
+		_, err := infinity.GetFrameForLDAP(context.Background(), query, infinity.Client{Settings: settings})
+		require.ErrorContains(t, err, "error binding to the ldap server")
+		require.Equal(t, infinity.ErrorKindUser, infinity.GetErrorKind(err))
+	})
+	t.Run("invalid filter", func(t *testing.T) {
+		query := models.Query{RefID: "A", Source: "ldap", LDAPOptions: &models.LDAPOptions{BaseDN: "dc=example,dc=com", Filter: "(cn=alice"}}
+		_, err := infinity.GetFrameForLDAP(context.Background(), query, infinity.Client{Settings: settings})
+		require.ErrorContains(t, err, "invalid ldap filter")
+	})
+	t.Run("server outside the allowed hosts", func(t *testing.T) {
+		settings := settings
+		settings.AllowedHosts = []string{"ldaps://directory.example.com"}
+		query := models.Query{RefID: "A", Source: "ldap", LDAPOptions: &models.LDAPOptions{BaseDN: "dc=example,dc=com"}}
+		_, err := infinity.GetFrameForLDAP(context.Background(), query, infinity.Client{Settings: settings})
+		require.ErrorContains(t, err, "not in the allowed hosts")
+	})
+}
+
+type ldapEntry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// startLDAPServer answers the simple bind and the search requests. search requests return the entries in order, limited by the size limit of the request
+func startLDAPServer(t *testing.T, bindDN string, password string, entries []ldapEntry) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveLDAPClient(conn, bindDN, password, entries)
+		}
+	}()
+	return "ldap://" + listener.Addr().String()
+}
+
+func serveLDAPClient(conn net.Conn, bindDN string, password string, entries []ldapEntry) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		id := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+		switch op.Tag {
+		case 0: // BindRequest
+			code := int64(0)
+			if op.Children[1].Value.(string) != bindDN || op.Children[2].Data.String() != password {
+				code = 49 // invalidCredentials
+			}
+			_, _ = conn.Write(ldapResponse(id, 1, code).Bytes())
+		case 3: // SearchRequest
+			attributes := map[string]bool{}
+			for _, a := range op.Children[7].Children {
+				attributes[a.Value.(string)] = true
+			}
+			limit := int(op.Children[3].Value.(int64))
+			code := int64(0)
+			for i, entry := range entries {
+				if limit > 0 && i >= limit {
+					code = 4 // sizeLimitExceeded
+					break
+				}
+				_, _ = conn.Write(ldapEntryPacket(id, entry, attributes).Bytes())
+			}
+			_, _ = conn.Write(ldapResponse(id, 5, code).Bytes())
+		case 2: // UnbindRequest
+			return
+		}
+	}
+}
+
+func ldapResponse(id int64, tag ber.Tag, code int64) *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, ""))
+	response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "")
+	response.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, ""))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	packet.AppendChild(response)
+	return packet
+}
+
+func ldapEntryPacket(id int64, entry ldapEntry, attributes map[string]bool) *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, ""))
+	response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, 4, nil, "")
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, ""))
+	list := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	for name, values := range entry.Attributes {
+		if len(attributes) > 0 && !attributes[name] {
+			continue
+		}
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, ""))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "")
+		for _, v := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, ""))
+		}
+		attribute.AppendChild(set)
+		list.AppendChild(attribute)
+	}
+	response.AppendChild(list)
+	packet.AppendChild(response)
+	return packet
+}
@@ -0,0 +1,150 @@
+package infinity
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v3"
+)
+
+const (
+	leaseCacheTable   = "leases"
+	leasePollInterval = 200 * time.Millisecond
+)
+
+// CacheLocker grants the leases. Leases granted by the shared cache backends are exclusive across all the
+// Grafana replicas using the backend
+type CacheLocker interface {
+	// TryLock acquires the lease of the key for the owner until the ttl elapses. Returns false when held by another owner
+	TryLock(ctx context.Context, key string, owner string, ttl time.Duration) (bool, error)
+	// Unlock releases the lease, only when it is still held by the owner
+	Unlock(ctx context.Context, key string, owner string) error
+}
+
+// Lease is an exclusive lock on a key, held until released or expired
+type Lease struct {
+	locker CacheLocker
+	key    string
+	owner  string
+}
+
+// Release releases the lease. Leases already expired and acquired by other owners are left as is
+func (l *Lease) Release(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	return l.locker.Unlock(ctx, l.key, l.owner)
+}
+
+// locker returns the locker of the cache backend. Falls back to the leases in BadgerDB, which are local to the replica
+func (client *Client) locker() CacheLocker {
+	if locker, ok := client.CacheBackend.(CacheLocker); ok {
+		return locker
+	}
+	return NewSettCacheBackend(client.Cache().Table(leaseCacheTable))
+}
+
+// TryAcquireLease acquires the lease of the key. Returns nil lease when the lease is held by another owner
+func (client *Client) TryAcquireLease(ctx context.Context, key string, ttl time.Duration) (*Lease, error) {
+	lease := &Lease{locker: client.locker(), key: key, owner: newLeaseOwner()}
+	acquired, err := lease.locker.TryLock(ctx, key, lease.owner, ttl)
+	if err != nil || !acquired {
+		return nil, err
+	}
+	return lease, nil
+}
+
+// AcquireLease waits until the lease of the key is acquired or the context is done
+func (client *Client) AcquireLease(ctx context.Context, key string, ttl time.Duration) (*Lease, error) {
+	ticker := time.NewTicker(leasePollInterval)
+	defer ticker.Stop()
+	for {
+		lease, err := client.TryAcquireLease(ctx, key, ttl)
+		if err != nil || lease != nil {
+			return lease, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("error acquiring the lease %s. %w", key, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// newLeaseOwner returns the owner token which is unique across the replicas and the acquisitions
+func newLeaseOwner() string {
+	hostname, _ := os.Hostname()
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%s:%d:%s", hostname, os.Getpid(), hex.EncodeToString(b))
+}
+
+func (b *SettCacheBackend) TryLock(ctx context.Context, key string, owner string, ttl time.Duration) (bool, error) {
+	acquired := false
+	s := b.s.WithContext(ctx)
+	err := s.update(func(txn *badger.Txn) error {
+		k := []byte(s.makeKey(key))
+		item, err := txn.Get(k)
+		if err == nil {
+			current, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			if string(current) != owner {
+				return nil
+			}
+		}
+		if err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+			return err
+		}
+		acquired = true
+		return txn.SetEntry(badger.NewEntry(k, []byte(owner)).WithTTL(ttl).WithMeta(STRING_TYPE))
+	})
+	if errors.Is(err, badger.ErrConflict) {
+		return false, nil
+	}
+	return acquired, err
+}
+
+func (b *SettCacheBackend) Unlock(ctx context.Context, key string, owner string) error {
+	s := b.s.WithContext(ctx)
+	return s.update(func(txn *badger.Txn) error {
+		k := []byte(s.makeKey(key))
+		item, err := txn.Get(k)
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		current, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		if string(current) != owner {
+			return nil
+		}
+		return txn.Delete(k)
+	})
+}
+
+// redisUnlockScript deletes the lease only when held by the owner, so that an expired and re-acquired lease is never released
+const redisUnlockScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end`
+
+func (b *RedisCacheBackend) TryLock(ctx context.Context, key string, owner string, ttl time.Duration) (bool, error) {
+	reply, err := b.do(ctx, "SET", b.prefix+"lease:"+key, owner, "NX", "PX", fmt.Sprintf("%d", max(ttl.Milliseconds(), 1)))
+	if err != nil {
+		return false, err
+	}
+	return reply == "OK", nil
+}
+
+func (b *RedisCacheBackend) Unlock(ctx context.Context, key string, owner string) error {
+	_, err := b.do(ctx, "EVAL", redisUnlockScript, "1", b.prefix+"lease:"+key, owner)
+	return err
+}
@@ -0,0 +1,256 @@
+package infinity
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+	"github.com/yesoreyeram/grafana-plugins/lib/go/transformations"
+	"gopkg.in/Knetic/govaluate.v3"
+)
+
+type LintSeverity string
+
+const (
+	// LintSeverityError is the problem which fails the query
+	LintSeverityError LintSeverity = "error"
+	// LintSeverityWarning is the problem which may produce the unexpected results
+	LintSeverityWarning LintSeverity = "warning"
+)
+
+// LintWarning is the problem found in the query by LintQuery. Field is the json name of the query field
+type LintWarning struct {
+	Field    string       `json:"field"`
+	Severity LintSeverity `json:"severity"`
+	Message  string       `json:"message"`
+}
+
+// knownMacros are the macros and global variables interpolated either by the plugin or by the grafana frontend
+var knownMacros = map[string]bool{
+	"combineValues": true, "customInterval": true,
+	"from": true, "to": true, "interval": true, "interval_ms": true, "rate_interval": true,
+	"range": true, "range_ms": true, "range_s": true, "since": true, "timezone": true,
+	"user": true, "org": true, "qs": true, "dashboard": true, "all_variables": true, "url_time_range": true,
+}
+
+var macroNameRegex = regexp.MustCompile(`\$\{?__([a-zA-Z_]+)(\.[a-zA-Z0-9_\-]+)?`)
+
+// LintQuery statically checks the query without executing it. The problems are sorted by the field name
+func LintQuery(settings models.InfinitySettings, query models.Query) []LintWarning {
+	warnings := []LintWarning{}
+	add := func(field string, severity LintSeverity, format string, args ...any) {
+		warnings = append(warnings, LintWarning{Field: field, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+	for field, value := range getLintTextFields(query) {
+		for _, match := range macroNameRegex.FindAllStringSubmatch(value, -1) {
+			if !knownMacros[match[1]] {
+				add(field, LintSeverityWarning, "unknown macro $__%s", match[1])
+				continue
+			}
+			if match[1] == "qs" && match[2] != "" {
+				if _, ok := settings.SecureQueryFields[strings.TrimPrefix(match[2], ".")]; !ok {
+					add(field, LintSeverityError, "secure query field %s is not configured in the datasource settings", strings.TrimPrefix(match[2], "."))
+				}
+			}
+		}
+	}
+	lintSelector := func(field string, selector string) {
+		if err := checkSelectorSyntax(selector); err != nil {
+			add(field, LintSeverityError, "invalid selector %s. %s", selector, err.Error())
+		}
+	}
+	lintSelector("root_selector", query.RootSelector)
+	for i, c := range query.Columns {
+		lintSelector(fmt.Sprintf("columns[%d].selector", i), c.Selector)
+	}
+	if query.NodeGraphOptions != nil {
+		lintSelector("node_graph_options.nodes_selector", query.NodeGraphOptions.NodesSelector)
+		lintSelector("node_graph_options.edges_selector", query.NodeGraphOptions.EdgesSelector)
+	}
+	if query.Parser == models.InfinityParserBackend {
+		if strings.TrimSpace(query.FilterExpression) != "" {
+			if _, err := govaluate.NewEvaluableExpressionWithFunctions(query.FilterExpression, transformations.ExpressionFunctions); err != nil {
+				add("filterExpression", LintSeverityError, "invalid filter expression. %s", err.Error())
+			}
+		}
+		for i, c := range query.ComputedColumns {
+			if _, err := govaluate.NewEvaluableExpressionWithFunctions(c.Selector, transformations.ExpressionFunctions); err != nil {
+				add(fmt.Sprintf("computed_columns[%d].selector", i), LintSeverityError, "invalid computed column expression %s. %s", c.Selector, err.Error())
+			}
+		}
+	}
+	if query.Source == "url" {
+		warnings = append(warnings, lintQueryURL(settings, query)...)
+		warnings = append(warnings, lintPagination(query)...)
+	}
+	sort.SliceStable(warnings, func(i, j int) bool { return warnings[i].Field < warnings[j].Field })
+	return warnings
+}
+
+func getLintTextFields(query models.Query) map[string]string {
+	fields := map[string]string{
+		"url":                            query.URL,
+		"data":                           query.Data,
+		"uql":                            query.UQL,
+		"groq":                           query.GROQ,
+		"filterExpression":               query.FilterExpression,
+		"url_options.data":               query.URLOptions.Body,
+		"url_options.body_graphql_query": query.URLOptions.BodyGraphQLQuery,
+	}
+	for i, p := range query.URLOptions.Params {
+		fields[fmt.Sprintf("url_options.params[%d]", i)] = p.Value
+	}
+	for i, h := range query.URLOptions.Headers {
+		fields[fmt.Sprintf("url_options.headers[%d]", i)] = h.Value
+	}
+	return fields
+}
+
+// checkSelectorSyntax checks the brackets and quotes of the gjson / JSONata selectors are balanced
+func checkSelectorSyntax(selector string) error {
+	pairs := map[rune]rune{')': '(', ']': '[', '}': '{'}
+	stack := []rune{}
+	var quote rune
+	escaped := false
+	for _, r := range selector {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'' || r == '`':
+			quote = r
+		case r == '(' || r == '[' || r == '{':
+			stack = append(stack, r)
+		case r == ')' || r == ']' || r == '}':
+			if len(stack) == 0 || stack[len(stack)-1] != pairs[r] {
+				return fmt.Errorf("unexpected %c", r)
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if quote != 0 {
+		return fmt.Errorf("unterminated quote %c", quote)
+	}
+	if len(stack) > 0 {
+		return fmt.Errorf("unclosed %c", stack[len(stack)-1])
+	}
+	return nil
+}
+
+func lintQueryURL(settings models.InfinitySettings, query models.Query) []LintWarning {
+	warnings := []LintWarning{}
+	fullURL := query.URL
+	if !strings.HasPrefix(fullURL, settings.URL) {
+		fullURL = settings.URL + fullURL
+	}
+	// urls starting with the variables can't be checked before the interpolation
+	if strings.HasPrefix(fullURL, "$") {
+		return warnings
+	}
+	if !CanAllowURL(fullURL, settings.AllowedHosts) {
+		warnings = append(warnings, LintWarning{Field: "url", Severity: LintSeverityError, Message: "requested URL is not allowed. To allow this URL, update the datasource config Security -> Allowed Hosts section"})
+	}
+	u, err := url.Parse(fullURL)
+	if err != nil {
+		return append(warnings, LintWarning{Field: "url", Severity: LintSeverityError, Message: fmt.Sprintf("invalid url. %s", err.Error())})
+	}
+	hasAuth := settings.AuthenticationMethod != "" && settings.AuthenticationMethod != models.AuthenticationMethodNone
+	if (hasAuth || settings.HaveSecureHeaders()) && u.Scheme == "http" && !isLocalHost(u.Hostname()) {
+		warnings = append(warnings, LintWarning{Field: "url", Severity: LintSeverityWarning, Message: "credentials of the datasource are sent over the insecure http connection. use https instead"})
+	}
+	for i, h := range query.URLOptions.Headers {
+		if strings.EqualFold(h.Key, "Authorization") && !strings.Contains(h.Value, "${__qs.") {
+			warnings = append(warnings, LintWarning{Field: fmt.Sprintf("url_options.headers[%d]", i), Severity: LintSeverityWarning, Message: "authorization header is visible to the dashboard viewers. configure the authentication or secure headers in the datasource settings"})
+		}
+	}
+	return warnings
+}
+
+func isLocalHost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
+
+func lintPagination(query models.Query) []LintWarning {
+	warnings := []LintWarning{}
+	if query.PageMode == "" || query.PageMode == models.PaginationModeNone {
+		return warnings
+	}
+	add := func(field string, severity LintSeverity, message string) {
+		warnings = append(warnings, LintWarning{Field: field, Severity: severity, Message: message})
+	}
+	switch query.PageMode {
+	case models.PaginationModeOffset, models.PaginationModePage, models.PaginationModeCursor, models.PaginationModeList:
+	default:
+		add("pagination_mode", LintSeverityError, fmt.Sprintf("unknown pagination mode %s", query.PageMode))
+		return warnings
+	}
+	if query.Parser != models.InfinityParserBackend {
+		add("pagination_mode", LintSeverityWarning, "pagination is only supported with the backend parser")
+	}
+	if query.PageMaxPages > 5 {
+		add("pagination_max_pages", LintSeverityWarning, "maximum 5 pages are fetched. remaining pages are ignored")
+	}
+	if query.PageMode == models.PaginationModeCursor && strings.TrimSpace(query.PageParamCursorFieldExtractionPath) == "" {
+		add("pagination_param_cursor_extraction_path", LintSeverityError, "cursor extraction path is required for the cursor pagination")
+	}
+	if query.PageMode == models.PaginationModeList {
+		if strings.TrimSpace(query.PageParamListFieldName) == "" {
+			add("pagination_param_list_field_name", LintSeverityError, "pagination_param_list_field_name cannot be empty")
+		}
+		if strings.TrimSpace(query.PageParamListFieldValue) == "" {
+			add("pagination_param_list_value", LintSeverityWarning, "list values are empty. only one request is made")
+		}
+	}
+	params := map[string]struct {
+		name      string
+		paramType models.PaginationParamType
+	}{
+		"pagination_param_size_field_type":   {query.PageParamSizeFieldName, query.PageParamSizeFieldType},
+		"pagination_param_offset_field_type": {query.PageParamOffsetFieldName, query.PageParamOffsetFieldType},
+		"pagination_param_page_field_type":   {query.PageParamPageFieldName, query.PageParamPageFieldType},
+		"pagination_param_cursor_field_type": {query.PageParamCursorFieldName, query.PageParamCursorFieldType},
+		"pagination_param_list_field_type":   {query.PageParamListFieldName, query.PageParamListFieldType},
+	}
+	for field, p := range params {
+		switch p.paramType {
+		case "", models.PaginationParamTypeQuery, models.PaginationParamTypeHeader:
+		case models.PaginationParamTypeBodyData:
+			if !strings.EqualFold(query.URLOptions.Method, "POST") || (query.URLOptions.BodyType != "form-data" && query.URLOptions.BodyType != "x-www-form-urlencoded") {
+				add(field, LintSeverityWarning, "body_data pagination parameter is only sent with the form-data or x-www-form-urlencoded POST body")
+			}
+		case models.PaginationParamTypeBodyJson:
+			add(field, LintSeverityWarning, "body_json pagination parameter is not supported yet. the parameter is sent as the query parameter")
+		case models.PaginationParamTypeReplace:
+			if p.name != "" && !hasPlaceholder(query, p.name) {
+				add(field, LintSeverityWarning, fmt.Sprintf("placeholder %s is not found in the query", p.name))
+			}
+		default:
+			add(field, LintSeverityError, fmt.Sprintf("unknown pagination parameter type %s", p.paramType))
+		}
+	}
+	return warnings
+}
+
+// hasPlaceholder reports whether the placeholder is found in any of the fields replaced by ReplacePlaceholderInQuery
+func hasPlaceholder(query models.Query, placeholder string) bool {
+	values := []string{query.URL, query.URLOptions.Body, query.URLOptions.BodyGraphQLQuery}
+	for _, items := range [][]models.URLOptionKeyValuePair{query.URLOptions.Headers, query.URLOptions.Params, query.URLOptions.BodyForm} {
+		for _, item := range items {
+			values = append(values, item.Key, item.Value)
+		}
+	}
+	for _, v := range values {
+		if strings.Contains(v, placeholder) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package infinity_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestLintQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings models.InfinitySettings
+		query    models.Query
+		want     []infinity.LintWarning
+	}{
+		{
+			name:  "valid query",
+			query: models.Query{Source: "url", Parser: "backend", URL: "https://example.com/users?from=${__from}&interval=$__interval", RootSelector: "data.items", FilterExpression: "age > 10"},
+			want:  []infinity.LintWarning{},
+		},
+		{
+			name:     "unknown macros and secure query fields",
+			settings: models.InfinitySettings{SecureQueryFields: map[string]string{"key": "secret"}},
+			query: models.Query{Source: "url", URL: "https://example.com?a=${__foo}&k=${__qs.key}", URLOptions: models.URLOptions{
+				Params: []models.URLOptionKeyValuePair{{Key: "token", Value: "${__qs.token}"}, {Key: "from", Value: "$__fromTime"}},
+			}},
+			want: []infinity.LintWarning{
+				{Field: "url", Severity: "warning", Message: "unknown macro $__foo"},
+				{Field: "url_options.params[0]", Severity: "error", Message: "secure query field token is not configured in the datasource settings"},
+				{Field: "url_options.params[1]", Severity: "warning", Message: "unknown macro $__fromTime"},
+			},
+		},
+		{
+			name: "selector and expression syntax errors",
+			query: models.Query{Source: "inline", Parser: "backend", RootSelector: "items[0", FilterExpression: "age >", Columns: []models.InfinityColumn{{Selector: `name"`}},
+				ComputedColumns: []models.InfinityColumn{{Selector: "a + b", Text: "c"}}},
+			want: []infinity.LintWarning{
+				{Field: "columns[0].selector", Severity: "error", Message: `invalid selector name". unterminated quote "`},
+				{Field: "filterExpression", Severity: "error", Message: "invalid filter expression. Unexpected end of expression"},
+				{Field: "root_selector", Severity: "error", Message: "invalid selector items[0. unclosed ["},
+			},
+		},
+		{
+			name:     "credentials over http and hosts not allowed",
+			settings: models.InfinitySettings{AuthenticationMethod: models.AuthenticationMethodBearerToken, BearerToken: "token", AllowedHosts: []string{"https://example.com"}},
+			query: models.Query{Source: "url", URL: "http://foo.com/users", URLOptions: models.URLOptions{
+				Headers: []models.URLOptionKeyValuePair{{Key: "Authorization", Value: "Bearer abc"}},
+			}},
+			want: []infinity.LintWarning{
+				{Field: "url", Severity: "error", Message: "requested URL is not allowed. To allow this URL, update the datasource config Security -> Allowed Hosts section"},
+				{Field: "url", Severity: "warning", Message: "credentials of the datasource are sent over the insecure http connection. use https instead"},
+				{Field: "url_options.headers[0]", Severity: "warning", Message: "authorization header is visible to the dashboard viewers. configure the authentication or secure headers in the datasource settings"},
+			},
+		},
+		{
+			name: "pagination misconfiguration",
+			query: models.Query{Source: "url", Parser: "simple", URL: "https://example.com/users", URLOptions: models.URLOptions{Method: "GET"},
+				PageMode: models.PaginationModeCursor, PageMaxPages: 10, PageParamCursorFieldName: "{{cursor}}", PageParamCursorFieldType: models.PaginationParamTypeReplace,
+				PageParamSizeFieldType: models.PaginationParamTypeBodyData},
+			want: []infinity.LintWarning{
+				{Field: "pagination_max_pages", Severity: "warning", Message: "maximum 5 pages are fetched. remaining pages are ignored"},
+				{Field: "pagination_mode", Severity: "warning", Message: "pagination is only supported with the backend parser"},
+				{Field: "pagination_param_cursor_extraction_path", Severity: "error", Message: "cursor extraction path is required for the cursor pagination"},
+				{Field: "pagination_param_cursor_field_type", Severity: "warning", Message: "placeholder {{cursor}} is not found in the query"},
+				{Field: "pagination_param_size_field_type", Severity: "warning", Message: "body_data pagination parameter is only sent with the form-data or x-www-form-urlencoded POST body"},
+			},
+		},
+		{
+			name:  "unknown pagination mode",
+			query: models.Query{Source: "url", Parser: "backend", URL: "https://example.com", PageMode: "foo"},
+			want:  []infinity.LintWarning{{Field: "pagination_mode", Severity: "error", Message: "unknown pagination mode foo"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, infinity.LintQuery(tt.settings, tt.query))
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package infinity
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/araddon/dateparse"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+// grokPatterns are the commonly used grok patterns. Patterns can refer other patterns using %{NAME}
+var grokPatterns = map[string]string{
+	"WORD":              `\b\w+\b`,
+	"NOTSPACE":          `\S+`,
+	"SPACE":             `\s*`,
+	"DATA":              `.*?`,
+	"GREEDYDATA":        `.*`,
+	"INT":               `[+-]?\d+`,
+	"NUMBER":            `[+-]?(?:\d+(?:\.\d+)?|\.\d+)`,
+	"BASE10NUM":         `%{NUMBER}`,
+	"POSINT":            `\b[1-9]\d*\b`,
+	"QUOTEDSTRING":      `"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'`,
+	"QS":                `%{QUOTEDSTRING}`,
+	"UUID":              `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+	"IPV4":              `(?:\d{1,3}\.){3}\d{1,3}`,
+	"IPV6":              `[0-9A-Fa-f:]*:[0-9A-Fa-f:.]+`,
+	"IP":                `(?:%{IPV4}|%{IPV6})`,
+	"HOSTNAME":          `\b[0-9A-Za-z][0-9A-Za-z-]{0,62}(?:\.[0-9A-Za-z][0-9A-Za-z-]{0,62})*\.?\b`,
+	"IPORHOST":          `(?:%{IP}|%{HOSTNAME})`,
+	"USER":              `[a-zA-Z0-9._-]+`,
+	"PATH":              `(?:/[^\s?#]*)+`,
+	"URIPATHPARAM":      `/[^\s]*`,
+	"URI":               `[A-Za-z][A-Za-z0-9+.-]*://[^\s]+`,
+	"LOGLEVEL":          `(?i:trace|debug|info|notice|warn(?:ing)?|error|err|crit(?:ical)?|fatal|panic|alert|emerg(?:ency)?)`,
+	"YEAR":              `\d{4}`,
+	"MONTHNUM":          `(?:0?[1-9]|1[0-2])`,
+	"MONTHDAY":          `(?:0?[1-9]|[12]\d|3[01])`,
+	"MONTH":             `\b(?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec)[a-z]*\b`,
+	"TIME":              `\d{2}:\d{2}(?::\d{2}(?:[.,]\d+)?)?`,
+	"ISO8601_TIMEZONE":  `(?:Z|[+-]\d{2}:?\d{2})`,
+	"TIMESTAMP_ISO8601": `%{YEAR}-%{MONTHNUM}-%{MONTHDAY}[T ]%{TIME}%{ISO8601_TIMEZONE}?`,
+	"HTTPDATE":          `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} [+-]\d{4}`,
+	"SYSLOGTIMESTAMP":   `%{MONTH} +%{MONTHDAY} %{TIME}`,
+	"COMMONAPACHELOG":   `%{IPORHOST:clientip} %{USER:ident} %{USER:auth} \[%{HTTPDATE:timestamp}\] "(?:%{WORD:verb} %{NOTSPACE:request}(?: HTTP/%{NUMBER:httpversion})?|%{DATA:rawrequest})" %{NUMBER:response} (?:%{NUMBER:bytes}|-)`,
+	"COMBINEDAPACHELOG": `%{COMMONAPACHELOG} %{QS:referrer} %{QS:agent}`,
+}
+
+var (
+	grokReferenceRegex = regexp.MustCompile(`%\{(\w+)(?::([\w.@\[\]-]+))?\}`)
+	logTimestampRegex  = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?|\d{2}/[A-Z][a-z]{2}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}`)
+	logLevelRegex      = regexp.MustCompile(`(?i)\b(trace|debug|info|notice|warn|warning|error|err|crit|critical|fatal|panic|alert|emerg|emergency)\b`)
+	invalidGroupName   = regexp.MustCompile(`\W`)
+)
+
+// CompileGrokPattern converts the grok pattern into a regular expression. %{PATTERN:name} becomes the named capture group.
+// Regular expressions with named groups can be used as is
+func CompileGrokPattern(pattern string) (*regexp.Regexp, error) {
+	expanded, err := expandGrokPattern(pattern, 0)
+	if err != nil {
+		return nil, err
+	}
+	return regexp.Compile(expanded)
+}
+
+func expandGrokPattern(pattern string, depth int) (string, error) {
+	if depth > 10 {
+		return pattern, fmt.Errorf("grok pattern nesting is too deep")
+	}
+	var expandErr error
+	out := grokReferenceRegex.ReplaceAllStringFunc(pattern, func(match string) string {
+		parts := grokReferenceRegex.FindStringSubmatch(match)
+		def, ok := grokPatterns[parts[1]]
+		if !ok {
+			expandErr = fmt.Errorf("unknown grok pattern %s", parts[1])
+			return match
+		}
+		def, err := expandGrokPattern(def, depth+1)
+		if err != nil {
+			expandErr = err
+			return match
+		}
+		if parts[2] == "" {
+			return "(?:" + def + ")"
+		}
+		return "(?P<" + invalidGroupName.ReplaceAllString(parts[2], "_") + ">" + def + ")"
+	})
+	return out, expandErr
+}
+
+// GetLogsBackendResponse parses the plain text response line by line into the logs frame.
+// timestamp and severity are taken from the captures named timestamp/time and level/severity, otherwise detected from the line.
+// message/msg capture becomes the body of the log line, otherwise the whole line is used. Other captures become the fields of the frame.
+// Timestamps without time zone are considered as UTC
+func GetLogsBackendResponse(ctx context.Context, responseString string, query models.Query) (*data.Frame, error) {
+	_, span := tracing.DefaultTracer().Start(ctx, "GetLogsBackendResponse")
+	defer span.End()
+	defer trackTiming(ctx, parseTiming)()
+	frame := GetDummyFrame(query)
+	var pattern *regexp.Regexp
+	if strings.TrimSpace(query.LogPattern) != "" {
+		p, err := CompileGrokPattern(query.LogPattern)
+		if err != nil {
+			return frame, UserError(fmt.Errorf("invalid log pattern. %w", err))
+		}
+		pattern = p
+	}
+	timestamps := []*time.Time{}
+	bodies := []string{}
+	levels := []string{}
+	captures := []*data.Field{}
+	captureIndex := map[int]int{}
+	if pattern != nil {
+		for i, name := range pattern.SubexpNames() {
+			switch strings.ToLower(name) {
+			case "", "timestamp", "time", "level", "severity", "message", "msg":
+				continue
+			}
+			captureIndex[i] = len(captures)
+			captures = append(captures, data.NewField(name, nil, []*string{}))
+		}
+	}
+	for _, line := range strings.Split(responseString, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		body, timeValue, level := line, "", ""
+		var match []string
+		if pattern != nil {
+			match = pattern.FindStringSubmatch(line)
+		}
+		if match != nil {
+			for i, name := range pattern.SubexpNames() {
+				switch strings.ToLower(name) {
+				case "timestamp", "time":
+					timeValue = match[i]
+				case "level", "severity":
+					level = match[i]
+				case "message", "msg":
+					body = match[i]
+				}
+			}
+		}
+		for i, idx := range captureIndex {
+			var value *string
+			if match != nil {
+				v := match[i]
+				value = &v
+			}
+			captures[idx].Append(value)
+		}
+		if timeValue == "" {
+			timeValue = logTimestampRegex.FindString(line)
+		}
+		if level == "" {
+			level = logLevelRegex.FindString(line)
+		}
+		timestamps = append(timestamps, parseLogTimestamp(timeValue))
+		bodies = append(bodies, body)
+		levels = append(levels, normalizeLogLevel(level))
+	}
+	frame.Fields = append(frame.Fields,
+		data.NewField("timestamp", nil, timestamps),
+		data.NewField("body", nil, bodies),
+		data.NewField("severity", nil, levels),
+	)
+	frame.Fields = append(frame.Fields, captures...)
+	return frame, nil
+}
+
+func parseLogTimestamp(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	if t, err := time.Parse("02/Jan/2006:15:04:05 -0700", value); err == nil {
+		return &t
+	}
+	t, err := dateparse.ParseIn(strings.Replace(value, ",", ".", 1), time.UTC)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// normalizeLogLevel converts the level into one of the log levels known by grafana
+func normalizeLogLevel(level string) string {
+	switch strings.ToLower(level) {
+	case "":
+		return "unknown"
+	case "warn", "warning":
+		return "warning"
+	case "err", "error":
+		return "error"
+	case "crit", "critical", "fatal", "panic", "alert", "emerg", "emergency":
+		return "critical"
+	case "notice":
+		return "info"
+	default:
+		return strings.ToLower(level)
+	}
+}
@@ -0,0 +1,61 @@
+package infinity_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestCompileGrokPattern(t *testing.T) {
+	re, err := infinity.CompileGrokPattern(`%{IP:client.ip} %{WORD:method} %{NUMBER}`)
+	require.Nil(t, err)
+	require.Equal(t, []string{"", "client_ip", "method"}, re.SubexpNames())
+	require.Equal(t, []string{"10.0.0.1 GET 200", "10.0.0.1", "GET"}, re.FindStringSubmatch("10.0.0.1 GET 200"))
+	_, err = infinity.CompileGrokPattern(`%{FOO:bar}`)
+	require.ErrorContains(t, err, "unknown grok pattern FOO")
+}
+
+func TestGetLogsBackendResponse(t *testing.T) {
+	t.Run("should detect the time and level without pattern", func(t *testing.T) {
+		input := "2023-01-02T10:00:00Z INFO service started\r\n\n2023-01-02 10:00:05,250 [WARN] disk is almost full\nno timestamp here"
+		frame, err := infinity.GetLogsBackendResponse(context.Background(), input, models.Query{RefID: "A", Type: models.QueryTypeLogs})
+		require.Nil(t, err)
+		require.Equal(t, 3, frame.Rows())
+		require.Equal(t, time.Date(2023, 1, 2, 10, 0, 0, 0, time.UTC), *frame.Fields[0].At(0).(*time.Time))
+		require.Equal(t, time.Date(2023, 1, 2, 10, 0, 5, 250000000, time.UTC), frame.Fields[0].At(1).(*time.Time).UTC())
+		require.Nil(t, frame.Fields[0].At(2))
+		require.Equal(t, "2023-01-02T10:00:00Z INFO service started", frame.Fields[1].At(0))
+		require.Equal(t, []string{"info", "warning", "unknown"}, []string{frame.Fields[2].At(0).(string), frame.Fields[2].At(1).(string), frame.Fields[2].At(2).(string)})
+	})
+	t.Run("should parse the lines using grok pattern", func(t *testing.T) {
+		input := `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326` + "\nnot an access log line"
+		frame, err := infinity.GetLogsBackendResponse(context.Background(), input, models.Query{RefID: "A", Type: models.QueryTypeLogs, LogPattern: "%{COMMONAPACHELOG}"})
+		require.Nil(t, err)
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, time.Date(2000, 10, 10, 20, 55, 36, 0, time.UTC), frame.Fields[0].At(0).(*time.Time).UTC())
+		response, _ := frame.FieldByName("response")
+		require.NotNil(t, response)
+		require.Equal(t, "200", *response.At(0).(*string))
+		require.Nil(t, response.At(1))
+		verb, _ := frame.FieldByName("verb")
+		require.Equal(t, "GET", *verb.At(0).(*string))
+	})
+	t.Run("should use the named groups of the regular expression", func(t *testing.T) {
+		input := "level=error ts=2023-01-02T10:00:00Z msg=\"connection refused\" host=db1"
+		frame, err := infinity.GetLogsBackendResponse(context.Background(), input, models.Query{RefID: "A", Type: models.QueryTypeLogs, LogPattern: `level=(?P<level>\w+) ts=(?P<time>\S+) msg="(?P<msg>[^"]*)" host=(?P<host>\S+)`})
+		require.Nil(t, err)
+		require.Equal(t, "connection refused", frame.Fields[1].At(0))
+		require.Equal(t, "error", frame.Fields[2].At(0))
+		host, _ := frame.FieldByName("host")
+		require.Equal(t, "db1", *host.At(0).(*string))
+	})
+	t.Run("should throw error for invalid pattern", func(t *testing.T) {
+		_, err := infinity.GetLogsBackendResponse(context.Background(), "foo", models.Query{RefID: "A", Type: models.QueryTypeLogs, LogPattern: "(?P<foo"})
+		require.ErrorContains(t, err, "invalid log pattern")
+		require.Equal(t, infinity.ErrorKindUser, infinity.GetErrorKind(err))
+	})
+}
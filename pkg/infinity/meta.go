@@ -15,6 +15,9 @@ type CustomMeta struct {
 	ResponseCodeFromServer int           `json:"responseCodeFromServer"`
 	Duration               time.Duration `json:"duration"`
 	Error                  string        `json:"error"`
+	Timings                *Timings      `json:"timings,omitempty"`
+	FinalURL               string        `json:"finalUrl,omitempty"`
+	RateLimit              *RateLimit    `json:"rateLimit,omitempty"`
 }
 
 func GetDummyFrame(query models.Query) *data.Frame {
@@ -0,0 +1,80 @@
+package infinity
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+// Middleware wraps the round tripper of the http client. Middlewares can modify the outgoing requests (ex: request signing, header policies)
+// and inspect the responses (ex: audit logging). The settings are the settings of the datasource the client is created for
+type Middleware interface {
+	Wrap(settings models.InfinitySettings, next http.RoundTripper) http.RoundTripper
+}
+
+// MiddlewareFunc is the function adapter of the Middleware interface
+type MiddlewareFunc func(settings models.InfinitySettings, next http.RoundTripper) http.RoundTripper
+
+func (f MiddlewareFunc) Wrap(settings models.InfinitySettings, next http.RoundTripper) http.RoundTripper {
+	return f(settings, next)
+}
+
+// RoundTripperFunc is the function adapter of the http.RoundTripper interface, handy for writing the middlewares
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+var (
+	middlewaresMu sync.RWMutex
+	middlewares   []Middleware
+)
+
+// RegisterMiddleware registers the middleware applied to all the clients created afterwards.
+// Forks are expected to register their middlewares from the init function or before serving the plugin
+func RegisterMiddleware(middleware Middleware) {
+	middlewaresMu.Lock()
+	defer middlewaresMu.Unlock()
+	middlewares = append(middlewares, middleware)
+}
+
+func registeredMiddlewares() []Middleware {
+	middlewaresMu.RLock()
+	defer middlewaresMu.RUnlock()
+	return append([]Middleware{}, middlewares...)
+}
+
+type clientOptions struct {
+	middlewares []Middleware
+}
+
+// ClientOption configures the client created by NewClient
+type ClientOption func(*clientOptions)
+
+// WithMiddlewares adds the middlewares to the client, in addition to the registered middlewares
+func WithMiddlewares(middlewares ...Middleware) ClientOption {
+	return func(o *clientOptions) {
+		o.middlewares = append(o.middlewares, middlewares...)
+	}
+}
+
+// ApplyMiddlewares wraps the transport of the client with the middlewares. Middlewares wrap the authentication and redirect
+// handling, so they see every request before the credentials are added. The first middleware is the outermost one
+func ApplyMiddlewares(httpClient *http.Client, settings models.InfinitySettings, middlewares []Middleware) *http.Client {
+	if len(middlewares) == 0 {
+		return httpClient
+	}
+	transport := httpClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if middlewares[i] != nil {
+			transport = middlewares[i].Wrap(settings, transport)
+		}
+	}
+	httpClient.Transport = transport
+	return httpClient
+}
@@ -0,0 +1,46 @@
+package infinity_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestMiddlewares(t *testing.T) {
+	var gotHeaders http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeaders = r.Header.Clone()
+		_, _ = w.Write([]byte(`[{"name":"foo"}]`))
+	}))
+	defer server.Close()
+	calls := []string{}
+	audited := 0
+	middleware := func(name string) infinity.Middleware {
+		return infinity.MiddlewareFunc(func(settings models.InfinitySettings, next http.RoundTripper) http.RoundTripper {
+			return infinity.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				calls = append(calls, name)
+				req.Header.Set("X-Signed-By", name)
+				res, err := next.RoundTrip(req)
+				if err == nil && res.StatusCode == http.StatusOK {
+					audited++
+				}
+				return res, err
+			})
+		})
+	}
+	client, err := infinity.NewClient(context.Background(), models.InfinitySettings{CustomHeaders: map[string]string{"X-Api-Key": "key"}}, infinity.WithMiddlewares(middleware("first"), middleware("second")))
+	require.Nil(t, err)
+	query := models.ApplyDefaultsToQuery(context.Background(), models.Query{RefID: "A", Type: models.QueryTypeJSON, Parser: models.InfinityParserBackend, Source: "url", URL: server.URL})
+	frame, err := infinity.GetFrameForURLSources(context.Background(), query, *client, map[string]string{})
+	require.Nil(t, err)
+	require.Equal(t, 1, frame.Rows())
+	require.Equal(t, []string{"first", "second"}, calls)
+	require.Equal(t, 2, audited)
+	require.Equal(t, "second", gotHeaders.Get("X-Signed-By"))
+	require.Equal(t, "key", gotHeaders.Get("X-Api-Key"))
+}
@@ -0,0 +1,186 @@
+package infinity
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+const (
+	defaultMQTTWindow = 2 * time.Second
+	maxMQTTWindow     = time.Minute
+	// mqttRetainedIdle is how long the retained mode waits for the next retained message. brokers send them right after the subscription
+	mqttRetainedIdle = 500 * time.Millisecond
+)
+
+// mqttMessage is the latest message of the topic
+type mqttMessage struct {
+	Payload  []byte
+	Retained bool
+	Received time.Time
+}
+
+// GetFrameForMQTT subscribes to the topics of the query and returns the latest message of every topic as the rows.
+// JSON object payloads become the columns of the row, other payloads are returned as the payload column
+func GetFrameForMQTT(ctx context.Context, query models.Query, infClient Client) (*data.Frame, error) {
+	ctx, span := tracing.DefaultTracer().Start(ctx, "GetFrameForMQTT")
+	defer span.End()
+	if query.MQTTOptions == nil || len(query.MQTTOptions.Topics) == 0 {
+		return nil, UserError(errors.New("at least one topic is required for the mqtt query"))
+	}
+	options := *query.MQTTOptions
+	if options.Broker == "" {
+		options.Broker = infClient.Settings.URL
+	}
+	if options.Broker == "" {
+		return nil, UserError(errors.New("invalid or empty mqtt broker"))
+	}
+	if !CanAllowURL(options.Broker, infClient.Settings.AllowedHosts) {
+		return nil, UserError(fmt.Errorf("mqtt broker %s is not in the allowed hosts", options.Broker))
+	}
+	if options.QoS > 2 {
+		return nil, UserError(fmt.Errorf("invalid mqtt qos %d", options.QoS))
+	}
+	window := defaultMQTTWindow
+	if options.Window != "" {
+		d, err := time.ParseDuration(options.Window)
+		if err != nil || d <= 0 || d > maxMQTTWindow {
+			return nil, UserError(fmt.Errorf("invalid mqtt window %s. window should be a duration up to %s", options.Window, maxMQTTWindow))
+		}
+		window = d
+	}
+	client, err := getMQTTClient(options.Broker, infClient.Settings)
+	if err != nil {
+		return nil, err
+	}
+	connect := client.Connect()
+	if err := waitForMQTTToken(ctx, connect, infClient.Settings); err != nil {
+		return nil, DownstreamError(fmt.Errorf("error connecting to the mqtt broker %s. %w", options.Broker, err), 0)
+	}
+	defer client.Disconnect(0)
+	var mu sync.Mutex
+	messages := map[string]mqttMessage{}
+	received := make(chan struct{}, 1)
+	filters := map[string]byte{}
+	for _, topic := range options.Topics {
+		filters[topic] = options.QoS
+	}
+	subscribe := client.SubscribeMultiple(filters, func(_ mqtt.Client, msg mqtt.Message) {
+		if options.Mode == models.MQTTModeRetained && !msg.Retained() {
+			return
+		}
+		mu.Lock()
+		messages[msg.Topic()] = mqttMessage{Payload: msg.Payload(), Retained: msg.Retained(), Received: time.Now().UTC()}
+		mu.Unlock()
+		select {
+		case received <- struct{}{}:
+		default:
+		}
+	})
+	if err := waitForMQTTToken(ctx, subscribe, infClient.Settings); err != nil {
+		return nil, DownstreamError(fmt.Errorf("error subscribing to the mqtt topics. %w", err), 0)
+	}
+	deadline := time.NewTimer(window)
+	defer deadline.Stop()
+	idle := time.After(mqttRetainedIdle)
+	for collecting := true; collecting; {
+		select {
+		case <-ctx.Done():
+			return nil, ContextError(ctx)
+		case <-deadline.C:
+			collecting = false
+		case <-received:
+			idle = time.After(mqttRetainedIdle)
+		case <-idle:
+			collecting = options.Mode != models.MQTTModeRetained
+		}
+	}
+	mu.Lock()
+	rows := getMQTTRows(messages)
+	mu.Unlock()
+	rowsQuery := query
+	rowsQuery.RootSelector = ""
+	frame, err := GetJSONBackendResponse(ctx, rows, rowsQuery)
+	if err != nil {
+		return frame, err
+	}
+	return PostProcessFrame(ctx, frame, rowsQuery)
+}
+
+func getMQTTClient(broker string, settings models.InfinitySettings) (mqtt.Client, error) {
+	tlsConfig, err := GetTLSConfigFromSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	opts := mqtt.NewClientOptions().
+		AddBroker(strings.Replace(strings.Replace(broker, "mqtts://", "ssl://", 1), "mqtt://", "tcp://", 1)).
+		SetClientID("grafana-infinity-" + hex.EncodeToString(id)).
+		SetCleanSession(true).
+		SetAutoReconnect(false).
+		SetConnectRetry(false).
+		SetTLSConfig(tlsConfig)
+	if settings.BasicAuthEnabled || settings.AuthenticationMethod == models.AuthenticationMethodBasic {
+		opts.SetUsername(settings.UserName).SetPassword(settings.Password)
+	}
+	return mqtt.NewClient(opts), nil
+}
+
+func waitForMQTTToken(ctx context.Context, token mqtt.Token, settings models.InfinitySettings) error {
+	timeout := time.Duration(settings.TimeoutInSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	select {
+	case <-ctx.Done():
+		return ContextError(ctx)
+	case <-token.Done():
+		return token.Error()
+	case <-time.After(timeout):
+		return errors.New("timeout waiting for the broker")
+	}
+}
+
+// getMQTTRows returns the rows of the latest messages sorted by the topic
+func getMQTTRows(messages map[string]mqttMessage) []any {
+	topics := make([]string, 0, len(messages))
+	for topic := range messages {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	rows := make([]any, 0, len(topics))
+	for _, topic := range topics {
+		msg := messages[topic]
+		row := map[string]any{}
+		var payload any
+		if err := json.Unmarshal(msg.Payload, &payload); err == nil {
+			if object, ok := payload.(map[string]any); ok {
+				row = object
+			} else {
+				row["payload"] = payload
+			}
+		} else {
+			row["payload"] = string(msg.Payload)
+		}
+		row["topic"] = topic
+		row["retained"] = msg.Retained
+		row["received"] = msg.Received.Format(time.RFC3339Nano)
+		rows = append(rows, row)
+	}
+	return rows
+}
@@ -0,0 +1,143 @@
+package infinity_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestGetFrameForMQTT(t *testing.T) {
+	broker := startMQTTBroker(t, map[string]string{
+		"sensors/room1":  `{ "temperature": 21.5, "humidity": 40 }`,
+		"sensors/room2":  `{ "temperature": 23, "humidity": 35 }`,
+		"status/gateway": `online`,
+	})
+	client := &infinity.Client{Settings: models.InfinitySettings{TimeoutInSeconds: 2}}
+	t.Run("retained", func(t *testing.T) {
+		query := models.Query{RefID: "A", Source: "mqtt", MQTTOptions: &models.MQTTOptions{Broker: broker, Topics: []string{"sensors/#"}, Mode: models.MQTTModeRetained}}
+		start := time.Now()
+		frame, err := infinity.GetFrameForMQTT(context.Background(), query, *client)
+		require.Nil(t, err)
+		require.Less(t, time.Since(start), 2*time.Second)
+		require.Equal(t, 2, frame.Rows())
+		topic, _ := frame.FieldByName("topic")
+		require.Equal(t, "sensors/room1", *topic.At(0).(*string))
+		temperature, _ := frame.FieldByName("temperature")
+		require.Equal(t, 23.0, *temperature.At(1).(*float64))
+	})
+	t.Run("window keeps the latest message", func(t *testing.T) {
+		query := models.Query{RefID: "A", Source: "mqtt", MQTTOptions: &models.MQTTOptions{Broker: broker, Topics: []string{"status/gateway"}, Window: "1s"}}
+		frame, err := infinity.GetFrameForMQTT(context.Background(), query, *client)
+		require.Nil(t, err)
+		require.Equal(t, 1, frame.Rows())
+		payload, _ := frame.FieldByName("payload")
+		require.Equal(t, "live", *payload.At(0).(*string))
+		retained, _ := frame.FieldByName("retained")
+		require.Equal(t, false, *retained.At(0).(*bool))
+	})
+	t.Run("broker outside the allowed hosts", func(t *testing.T) {
+		client := &infinity.Client{Settings: models.InfinitySettings{AllowedHosts: []string{"mqtt://iot.example.com"}}}
+		query := models.Query{RefID: "A", Source: "mqtt", MQTTOptions: &models.MQTTOptions{Broker: broker, Topics: []string{"#"}}}
+		_, err := infinity.GetFrameForMQTT(context.Background(), query, *client)
+		require.ErrorContains(t, err, "not in the allowed hosts")
+	})
+	t.Run("invalid window", func(t *testing.T) {
+		query := models.Query{RefID: "A", Source: "mqtt", MQTTOptions: &models.MQTTOptions{Broker: broker, Topics: []string{"#"}, Window: "1h"}}
+		_, err := infinity.GetFrameForMQTT(context.Background(), query, *client)
+		require.ErrorContains(t, err, "invalid mqtt window")
+	})
+}
+
+// startMQTTBroker accepts the mqtt 3.1.1 clients. subscribers get the retained messages of the matching topics and then a live "live" message
+func startMQTTBroker(t *testing.T, retained map[string]string) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveMQTTClient(conn, retained)
+		}
+	}()
+	return "mqtt://" + listener.Addr().String()
+}
+
+func serveMQTTClient(conn net.Conn, retained map[string]string) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		header, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+		length, multiplier := 0, 1
+		for {
+			b, err := r.ReadByte()
+			if err != nil {
+				return
+			}
+			length += int(b&127) * multiplier
+			multiplier *= 128
+			if b&128 == 0 {
+				break
+			}
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return
+		}
+		switch header >> 4 {
+		case 1: // CONNECT
+			_, _ = conn.Write([]byte{0x20, 0x02, 0x00, 0x00})
+		case 8: // SUBSCRIBE
+			filters := []string{}
+			for i := 2; i+2 <= len(body); {
+				n := int(binary.BigEndian.Uint16(body[i:]))
+				filters = append(filters, string(body[i+2:i+2+n]))
+				i += 2 + n + 1
+			}
+			suback := append([]byte{0x90, byte(2 + len(filters)), body[0], body[1]}, make([]byte, len(filters))...)
+			_, _ = conn.Write(suback)
+			for _, filter := range filters {
+				for topic, payload := range retained {
+					if topic == filter || filter == "#" || (strings.HasSuffix(filter, "/#") && strings.HasPrefix(topic, strings.TrimSuffix(filter, "#"))) {
+						_, _ = conn.Write(mqttPublishPacket(topic, payload, true))
+					}
+				}
+				go func(filter string) {
+					time.Sleep(200 * time.Millisecond)
+					if !strings.Contains(filter, "#") {
+						_, _ = conn.Write(mqttPublishPacket(filter, "live", false))
+					}
+				}(filter)
+			}
+		case 12: // PINGREQ
+			_, _ = conn.Write([]byte{0xd0, 0x00})
+		case 14: // DISCONNECT
+			return
+		}
+	}
+}
+
+func mqttPublishPacket(topic string, payload string, retain bool) []byte {
+	header := byte(0x30)
+	if retain {
+		header |= 0x01
+	}
+	body := append([]byte{byte(len(topic) >> 8), byte(len(topic))}, topic...)
+	body = append(body, payload...)
+	return append([]byte{header, byte(len(body))}, body...)
+}
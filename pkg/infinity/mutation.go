@@ -0,0 +1,92 @@
+package infinity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+type mutationActionKey struct{}
+
+// WithMutationAction marks the context as the explicit action of the user, such as the run mutation resource call.
+// Only the mutations run with this context send the request
+func WithMutationAction(ctx context.Context) context.Context {
+	return context.WithValue(ctx, mutationActionKey{}, true)
+}
+
+func isMutationAction(ctx context.Context) bool {
+	action, _ := ctx.Value(mutationActionKey{}).(bool)
+	return action
+}
+
+// GetFrameForMutation sends the request of the query with the method of the mutation, such as acknowledging an incident with the values
+// of the dashboard variables, and returns the parsed response. Mutations must be enabled in the datasource settings and are restricted
+// to the mutation methods (POST by default) and the mutation hosts of the datasource. Mutations are never cached or coalesced,
+// alerting and server side expressions can't send them and the dry run queries only validate them.
+//
+// Panel queries run on every dashboard load and refresh, so the request is sent only when the context is marked with
+// WithMutationAction by the explicit action of the user (POST /mutations resource call). Otherwise the mutation is only
+// validated, the same way as the dry run
+func GetFrameForMutation(ctx context.Context, query models.Query, infClient Client, requestHeaders map[string]string) (*data.Frame, error) {
+	ctx, span := tracing.DefaultTracer().Start(ctx, "GetFrameForMutation")
+	defer span.End()
+	settings := infClient.Settings
+	if !settings.AllowMutations {
+		return nil, UserError(errors.New("mutation queries are not enabled. enable them in the datasource settings"))
+	}
+	if IsHeadlessRequest(requestHeaders) {
+		return nil, UserError(errors.New("mutation queries can't be run by alerting or server side expressions"))
+	}
+	method := http.MethodPost
+	if query.MutationOptions != nil && strings.TrimSpace(query.MutationOptions.Method) != "" {
+		method = strings.ToUpper(strings.TrimSpace(query.MutationOptions.Method))
+	}
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return nil, UserError(fmt.Errorf("invalid mutation method %s. method should be POST, PUT, PATCH or DELETE", method))
+	}
+	allowedMethods := settings.MutationMethods
+	if len(allowedMethods) == 0 {
+		allowedMethods = []string{http.MethodPost}
+	}
+	if !slices.ContainsFunc(allowedMethods, func(m string) bool { return strings.EqualFold(strings.TrimSpace(m), method) }) {
+		return nil, UserError(fmt.Errorf("mutation method %s is not allowed. allowed methods are %s", method, strings.Join(allowedMethods, ", ")))
+	}
+	if len(settings.MutationHosts) == 0 {
+		return nil, UserError(errors.New("mutation queries require the mutation hosts in the datasource settings"))
+	}
+	// the body of the mutation is built the same way as the body of the POST queries
+	mutationQuery := query
+	mutationQuery.URLOptions.Method = http.MethodPost
+	mutationQuery.CoalesceWindowSeconds = 0
+	if mutationQuery.Parser != models.InfinityParserBackend && mutationQuery.Parser != models.InfinityParserSQLite {
+		mutationQuery.Parser = models.InfinityParserBackend
+	}
+	url, err := GetQueryURL(ctx, settings, mutationQuery, false)
+	if err != nil {
+		return nil, UserError(fmt.Errorf("invalid mutation url. %w", err))
+	}
+	if !CanAllowURL(url, settings.MutationHosts) {
+		return nil, UserError(fmt.Errorf("mutation url %s is not in the mutation hosts of the datasource", url))
+	}
+	if query.DryRun {
+		frame := GetDummyFrame(query)
+		frame.AppendNotices(data.Notice{Severity: data.NoticeSeverityInfo, Text: fmt.Sprintf("%s %s is not sent by the dry run", method, url)})
+		return frame, nil
+	}
+	if !isMutationAction(ctx) {
+		frame := GetDummyFrame(query)
+		frame.AppendNotices(data.Notice{Severity: data.NoticeSeverityInfo, Text: fmt.Sprintf("%s %s is not sent by the panel queries. run the mutation to send it", method, url)})
+		return frame, nil
+	}
+	frame, _, err := GetFrameForURLSourcesWithPostProcessing(withMutationMethod(withoutResponseCache(ctx), method), mutationQuery, infClient, requestHeaders, true)
+	return frame, err
+}
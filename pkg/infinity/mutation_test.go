@@ -0,0 +1,109 @@
+package infinity_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestGetFrameForMutation(t *testing.T) {
+	var method, body string
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		b, _ := io.ReadAll(r.Body)
+		method, body = r.Method, string(b)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{ "incident": { "id": "INC-1", "status": "acknowledged" } }`)
+	}))
+	defer server.Close()
+	settings := models.InfinitySettings{URL: server.URL, AllowMutations: true, MutationMethods: []string{"POST", "patch"}, MutationHosts: []string{server.URL + "/incidents"}}
+	client := infinity.Client{Settings: settings, HttpClient: server.Client()}
+	query := func(method string) models.Query {
+		return models.Query{
+			RefID:           "A",
+			Source:          "mutation",
+			Type:            models.QueryTypeJSON,
+			URL:             "/incidents/INC-1",
+			RootSelector:    "incident",
+			URLOptions:      models.URLOptions{BodyType: "raw", Body: `{ "status": "acknowledged" }`, Headers: []models.URLOptionKeyValuePair{{Key: "cacheq", Value: "incident"}}},
+			MutationOptions: &models.MutationOptions{Method: method},
+		}
+	}
+	action := infinity.WithMutationAction(context.Background())
+	t.Run("should send the mutation with the method and return the response", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			frame, err := infinity.GetFrameForMutation(action, query("patch"), client, map[string]string{})
+			require.Nil(t, err)
+			require.Equal(t, 1, frame.Rows())
+			status, _ := frame.FieldByName("status")
+			require.NotNil(t, status)
+			v, _ := status.ConcreteAt(0)
+			require.Equal(t, "acknowledged", v)
+		}
+		// mutations are never served from the cache
+		require.Equal(t, 2, requests)
+		require.Equal(t, http.MethodPatch, method)
+		require.Equal(t, `{ "status": "acknowledged" }`, body)
+	})
+	t.Run("should default to POST", func(t *testing.T) {
+		_, err := infinity.GetFrameForMutation(action, query(""), client, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, http.MethodPost, method)
+	})
+	t.Run("should not send the mutation without the explicit action", func(t *testing.T) {
+		requests = 0
+		frame, err := infinity.GetFrameForMutation(context.Background(), query(""), client, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, 0, requests)
+		require.Contains(t, frame.Meta.Notices[0].Text, "is not sent by the panel queries")
+	})
+	t.Run("should not send the mutation in dry run", func(t *testing.T) {
+		requests = 0
+		q := query("")
+		q.DryRun = true
+		frame, err := infinity.GetFrameForMutation(action, q, client, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, 0, requests)
+		require.Contains(t, frame.Meta.Notices[0].Text, "is not sent by the dry run")
+	})
+	t.Run("should restrict the mutations", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			settings func(s models.InfinitySettings) models.InfinitySettings
+			query    func(q models.Query) models.Query
+			headers  map[string]string
+			wantErr  string
+		}{
+			{name: "disabled", settings: func(s models.InfinitySettings) models.InfinitySettings { s.AllowMutations = false; return s }, wantErr: "mutation queries are not enabled"},
+			{name: "alerting", headers: map[string]string{"FromAlert": "true"}, wantErr: "can't be run by alerting"},
+			{name: "method not allowed", query: func(q models.Query) models.Query { q.MutationOptions.Method = "DELETE"; return q }, wantErr: "mutation method DELETE is not allowed"},
+			{name: "invalid method", query: func(q models.Query) models.Query { q.MutationOptions.Method = "GET"; return q }, wantErr: "invalid mutation method GET"},
+			{name: "without hosts", settings: func(s models.InfinitySettings) models.InfinitySettings { s.MutationHosts = nil; return s }, wantErr: "require the mutation hosts"},
+			{name: "host not allowed", query: func(q models.Query) models.Query { q.URL = "/flags/beta"; return q }, wantErr: "is not in the mutation hosts"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				requests = 0
+				s, q := settings, query("")
+				if tt.settings != nil {
+					s = tt.settings(s)
+				}
+				if tt.query != nil {
+					q = tt.query(q)
+				}
+				_, err := infinity.GetFrameForMutation(action, q, infinity.Client{Settings: s, HttpClient: server.Client()}, tt.headers)
+				require.ErrorContains(t, err, tt.wantErr)
+				require.Equal(t, infinity.ErrorKindUser, infinity.GetErrorKind(err))
+				require.Equal(t, 0, requests)
+			})
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package infinity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+	"github.com/yesoreyeram/grafana-plugins/lib/go/jsonframer"
+)
+
+// nodeGraphFieldNames are the field names expected by the node graph panel, keyed by the lower case names and the common aliases
+var nodeGraphFieldNames = map[string]string{
+	"id":            "id",
+	"title":         "title",
+	"subtitle":      "subtitle",
+	"mainstat":      "mainstat",
+	"secondarystat": "secondarystat",
+	"color":         "color",
+	"icon":          "icon",
+	"noderadius":    "nodeRadius",
+	"source":        "source",
+	"from":          "source",
+	"target":        "target",
+	"to":            "target",
+}
+
+// ToNodeGraphFrames returns the nodes and edges frames required by the node graph panel from the response of the query.
+// Nodes and edges are selected from the same response using the node graph selectors of the query
+func ToNodeGraphFrames(ctx context.Context, frame *data.Frame, query models.Query) ([]*data.Frame, error) {
+	_, span := tracing.DefaultTracer().Start(ctx, "ToNodeGraphFrames")
+	defer span.End()
+	if (query.Type != models.QueryTypeJSON && query.Type != models.QueryTypeGraphQL) || query.Parser != models.InfinityParserBackend {
+		return nil, errors.New("node graph format is supported only for json and graphql queries with backend parser")
+	}
+	if query.NodeGraphOptions == nil || strings.TrimSpace(query.NodeGraphOptions.NodesSelector) == "" {
+		return nil, errors.New("node graph format requires the nodes selector")
+	}
+	if frame == nil || frame.Meta == nil {
+		return nil, errors.New("node graph format requires the response data")
+	}
+	customMeta, ok := frame.Meta.Custom.(*CustomMeta)
+	if !ok {
+		return nil, errors.New("node graph format requires the response data")
+	}
+	body, ok := customMeta.Data.(string)
+	if !ok {
+		b, err := json.Marshal(customMeta.Data)
+		if err != nil {
+			return nil, fmt.Errorf("error while marshaling the response object. %w", err)
+		}
+		body = string(b)
+	}
+	nodes, err := toNodeGraphFrame(body, "nodes", query.NodeGraphOptions.NodesSelector)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the nodes. %w", err)
+	}
+	nodes.Meta = frame.Meta
+	edges := data.NewFrame("edges")
+	if strings.TrimSpace(query.NodeGraphOptions.EdgesSelector) != "" {
+		if edges, err = toNodeGraphFrame(body, "edges", query.NodeGraphOptions.EdgesSelector); err != nil {
+			return nil, fmt.Errorf("error reading the edges. %w", err)
+		}
+		addEdgeIDs(edges)
+	}
+	for _, f := range []*data.Frame{nodes, edges} {
+		if f.Meta == nil {
+			f.Meta = &data.FrameMeta{}
+		}
+		f.Meta.PreferredVisualization = data.VisTypeNodeGraph
+	}
+	return []*data.Frame{nodes, edges}, nil
+}
+
+func toNodeGraphFrame(body string, name string, selector string) (*data.Frame, error) {
+	frame, err := jsonframer.ToFrame(body, jsonframer.FramerOptions{FrameName: name, RootSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	frame.Name = name
+	for _, field := range frame.Fields {
+		if n, ok := nodeGraphFieldNames[strings.ToLower(field.Name)]; ok {
+			field.Name = n
+		}
+	}
+	return frame, nil
+}
+
+// addEdgeIDs adds the id field to the edges using the source and target, as the node graph panel requires the edge ids
+func addEdgeIDs(edges *data.Frame) {
+	if _, idx := edges.FieldByName("id"); idx != -1 {
+		return
+	}
+	source, sourceIdx := edges.FieldByName("source")
+	target, targetIdx := edges.FieldByName("target")
+	if sourceIdx == -1 || targetIdx == -1 {
+		return
+	}
+	ids := make([]string, edges.Rows())
+	for i := range ids {
+		s, _ := source.ConcreteAt(i)
+		t, _ := target.ConcreteAt(i)
+		ids[i] = fmt.Sprintf("%v-%v", s, t)
+	}
+	edges.Fields = append([]*data.Field{data.NewField("id", nil, ids)}, edges.Fields...)
+}
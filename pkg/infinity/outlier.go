@@ -0,0 +1,99 @@
+package infinity
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+const (
+	defaultOutlierWindow    = 20
+	defaultOutlierThreshold = 3.0
+	// madScale makes the median absolute deviation comparable with the standard deviation of normally distributed values
+	madScale = 1.4826
+)
+
+// getOutlierFlags returns the boolean field flagging the rows deviating from the preceding window of each series by more than the threshold.
+// Rows without enough preceding points to compare with are not flagged
+func getOutlierFlags(fn models.ColumnFunction, field *data.Field, groups map[string][]int, rowsCount int) (*data.Field, error) {
+	method := strings.ToLower(strings.TrimSpace(fn.Method))
+	if method != "" && method != "mad" && method != "sigma" {
+		return nil, fmt.Errorf("unknown outlier method %s. method must be mad or sigma", fn.Method)
+	}
+	window := fn.Window
+	if window <= 0 {
+		window = defaultOutlierWindow
+	}
+	threshold := fn.Threshold
+	if threshold <= 0 {
+		threshold = defaultOutlierThreshold
+	}
+	name := fn.Alias
+	if strings.TrimSpace(name) == "" {
+		name = field.Name + "_outlier"
+	}
+	flags := make([]*bool, rowsCount)
+	for _, rows := range groups {
+		points := []float64{}
+		for _, row := range rows {
+			current, err := field.NullableFloatAt(row)
+			if err != nil {
+				return nil, err
+			}
+			if current == nil {
+				continue
+			}
+			flag := false
+			if len(points) >= 3 {
+				center, scale := getMedianAndMAD(points)
+				if method == "sigma" {
+					center, scale = getMeanAndStdDev(points)
+				}
+				deviation := math.Abs(*current - center)
+				flag = (scale == 0 && deviation > 0) || (scale > 0 && deviation/scale > threshold)
+			}
+			flags[row] = &flag
+			points = append(points, *current)
+			if len(points) > window {
+				points = points[1:]
+			}
+		}
+	}
+	return data.NewField(name, field.Labels, flags), nil
+}
+
+func getMedianAndMAD(points []float64) (float64, float64) {
+	median := getMedian(points)
+	deviations := make([]float64, len(points))
+	for i, p := range points {
+		deviations[i] = math.Abs(p - median)
+	}
+	return median, getMedian(deviations) * madScale
+}
+
+func getMedian(points []float64) float64 {
+	sorted := append([]float64{}, points...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+func getMeanAndStdDev(points []float64) (float64, float64) {
+	mean := 0.0
+	for _, p := range points {
+		mean += p
+	}
+	mean /= float64(len(points))
+	variance := 0.0
+	for _, p := range points {
+		variance += (p - mean) * (p - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(points)))
+}
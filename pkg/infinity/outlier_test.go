@@ -0,0 +1,51 @@
+package infinity_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestApplyColumnFunctionsOutlier(t *testing.T) {
+	t0 := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	getFrame := func() *data.Frame {
+		times := []time.Time{}
+		for i := 0; i < 7; i++ {
+			times = append(times, t0.Add(time.Duration(i)*time.Minute))
+		}
+		return data.NewFrame("response",
+			data.NewField("time", nil, times),
+			data.NewField("latency", nil, []float64{10, 12, 11, 9, 10, 100, 11}),
+		)
+	}
+	flags := func(field *data.Field) []bool {
+		out := []bool{}
+		for i := 0; i < field.Len(); i++ {
+			out = append(out, *field.At(i).(*bool))
+		}
+		return out
+	}
+	for _, method := range []string{"", "mad", "sigma"} {
+		t.Run("method "+method, func(t *testing.T) {
+			frame, err := infinity.ApplyColumnFunctions(getFrame(), []models.ColumnFunction{{Field: "latency", Function: models.ColumnFunctionOutlier, Method: method}})
+			require.Nil(t, err)
+			require.Len(t, frame.Fields, 3)
+			require.Equal(t, "latency_outlier", frame.Fields[2].Name)
+			require.Equal(t, []bool{false, false, false, false, false, true, false}, flags(frame.Fields[2]))
+		})
+	}
+	t.Run("alias and threshold", func(t *testing.T) {
+		frame, err := infinity.ApplyColumnFunctions(getFrame(), []models.ColumnFunction{{Field: "latency", Function: models.ColumnFunctionOutlier, Alias: "anomaly", Threshold: 100}})
+		require.Nil(t, err)
+		require.Equal(t, "anomaly", frame.Fields[2].Name)
+		require.Equal(t, []bool{false, false, false, false, false, false, false}, flags(frame.Fields[2]))
+	})
+	t.Run("unknown method should throw error", func(t *testing.T) {
+		_, err := infinity.ApplyColumnFunctions(getFrame(), []models.ColumnFunction{{Field: "latency", Function: models.ColumnFunctionOutlier, Method: "iqr"}})
+		require.ErrorContains(t, err, "unknown outlier method iqr")
+	})
+}
@@ -0,0 +1,38 @@
+package infinity
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrPathOutsideBase is returned when the path is not confined to its base directory
+var ErrPathOutsideBase = errors.New("path is outside of the base directory")
+
+// resolveConfinedPath returns the path of the name within the base directory. The name should be relative to the base
+// and the existing entries of the path should not be symbolic links, so the path can't point outside of the base directory
+func resolveConfinedPath(base string, name string) (string, error) {
+	if base == "" {
+		return "", errors.New("base directory is not configured")
+	}
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("%w. invalid path %s", ErrPathOutsideBase, name)
+	}
+	path := base
+	for _, segment := range strings.Split(filepath.Clean(name), string(filepath.Separator)) {
+		path = filepath.Join(path, segment)
+		info, err := os.Lstat(path)
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("%w. symbolic links are not allowed in the path %s", ErrPathOutsideBase, name)
+		}
+	}
+	return filepath.Join(base, name), nil
+}
@@ -0,0 +1,311 @@
+package infinity
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+// ImportedQuery is the infinity query converted from the request of the external tool such as Postman
+type ImportedQuery struct {
+	Name     string       `json:"name"`
+	Folder   string       `json:"folder,omitempty"`
+	Query    models.Query `json:"query"`
+	AuthHint string       `json:"authHint,omitempty"` // authentication method to be configured in the datasource. ex: bearerToken
+	Warnings []string     `json:"warnings,omitempty"`
+}
+
+type postmanCollection struct {
+	Info struct {
+		Name   string `json:"name"`
+		Schema string `json:"schema"`
+	} `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Auth     *postmanAuth      `json:"auth"`
+	Variable []postmanKeyValue `json:"variable"`
+}
+
+type postmanItem struct {
+	Name    string          `json:"name"`
+	Item    []postmanItem   `json:"item"`
+	Request json.RawMessage `json:"request"`
+	Auth    *postmanAuth    `json:"auth"`
+}
+
+type postmanRequest struct {
+	Method string            `json:"method"`
+	Header []postmanKeyValue `json:"header"`
+	URL    json.RawMessage   `json:"url"`
+	Auth   *postmanAuth      `json:"auth"`
+	Body   *struct {
+		Mode       string            `json:"mode"`
+		Raw        string            `json:"raw"`
+		URLEncoded []postmanKeyValue `json:"urlencoded"`
+		FormData   []postmanKeyValue `json:"formdata"`
+		GraphQL    struct {
+			Query     string `json:"query"`
+			Variables string `json:"variables"`
+		} `json:"graphql"`
+		Options struct {
+			Raw struct {
+				Language string `json:"language"`
+			} `json:"raw"`
+		} `json:"options"`
+	} `json:"body"`
+}
+
+type postmanURL struct {
+	Raw      string            `json:"raw"`
+	Query    []postmanKeyValue `json:"query"`
+	Variable []postmanKeyValue `json:"variable"`
+}
+
+type postmanKeyValue struct {
+	Key      string `json:"key"`
+	Value    any    `json:"value"`
+	Type     string `json:"type"`
+	Disabled bool   `json:"disabled"`
+}
+
+func (kv postmanKeyValue) value() string {
+	if kv.Value == nil {
+		return ""
+	}
+	if s, ok := kv.Value.(string); ok {
+		return s
+	}
+	return fmt.Sprintf("%v", kv.Value)
+}
+
+type postmanAuth struct {
+	Type string `json:"type"`
+}
+
+var postmanAuthMethods = map[string]string{
+	"noauth": models.AuthenticationMethodNone,
+	"basic":  models.AuthenticationMethodBasic,
+	"digest": models.AuthenticationMethodDigestAuth,
+	"bearer": models.AuthenticationMethodBearerToken,
+	"apikey": models.AuthenticationMethodApiKey,
+	"oauth2": models.AuthenticationMethodOAuth,
+	"awsv4":  models.AuthenticationMethodAWS,
+}
+
+var postmanContentTypes = map[string]string{
+	"json":       "application/json",
+	"xml":        "application/xml",
+	"html":       "text/html",
+	"javascript": "application/javascript",
+	"text":       "text/plain",
+}
+
+var postmanVariableRegex = regexp.MustCompile(`{{\s*([^{}\s]+)\s*}}`)
+
+// ImportPostmanCollection converts the requests of the Postman collection (v2.0 / v2.1) into the infinity queries.
+// Requests are selected by the name or the folder path (folder/name). When no requests are selected, all the requests are converted.
+// Collection variables are replaced with their values. Credentials are not imported; the authentication is returned as the hint instead
+func ImportPostmanCollection(body []byte, selected []string) ([]ImportedQuery, error) {
+	var collection postmanCollection
+	if err := json.Unmarshal(body, &collection); err != nil {
+		return nil, UserError(fmt.Errorf("invalid postman collection. %w", err))
+	}
+	if collection.Item == nil {
+		return nil, UserError(errors.New("invalid postman collection. only the collections of the format v2.0 and v2.1 are supported"))
+	}
+	variables := map[string]string{}
+	for _, v := range collection.Variable {
+		if !v.Disabled {
+			variables[v.Key] = v.value()
+		}
+	}
+	out := []ImportedQuery{}
+	var walk func(items []postmanItem, folder string, auth *postmanAuth) error
+	walk = func(items []postmanItem, folder string, auth *postmanAuth) error {
+		for _, item := range items {
+			itemAuth := auth
+			if item.Auth != nil {
+				itemAuth = item.Auth
+			}
+			if item.Request == nil {
+				if err := walk(item.Item, strings.TrimPrefix(folder+"/"+item.Name, "/"), itemAuth); err != nil {
+					return err
+				}
+				continue
+			}
+			if !isPostmanRequestSelected(item.Name, folder, selected) {
+				continue
+			}
+			imported, err := getPostmanQuery(item, itemAuth, variables)
+			if err != nil {
+				return UserError(fmt.Errorf("error importing the request %s. %w", item.Name, err))
+			}
+			imported.Folder = folder
+			out = append(out, imported)
+		}
+		return nil
+	}
+	if err := walk(collection.Item, "", collection.Auth); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func isPostmanRequestSelected(name string, folder string, selected []string) bool {
+	if len(selected) == 0 {
+		return true
+	}
+	for _, s := range selected {
+		if s == name || s == strings.TrimPrefix(folder+"/"+name, "/") {
+			return true
+		}
+	}
+	return false
+}
+
+func getPostmanQuery(item postmanItem, auth *postmanAuth, variables map[string]string) (ImportedQuery, error) {
+	imported := ImportedQuery{Name: item.Name}
+	replace := func(s string) string {
+		return postmanVariableRegex.ReplaceAllStringFunc(s, func(m string) string {
+			if v, ok := variables[postmanVariableRegex.FindStringSubmatch(m)[1]]; ok {
+				return v
+			}
+			imported.Warnings = appendUnique(imported.Warnings, fmt.Sprintf("variable %s is not defined in the collection", m))
+			return m
+		})
+	}
+	req := postmanRequest{}
+	var rawURL string
+	if err := json.Unmarshal(item.Request, &rawURL); err != nil {
+		if err := json.Unmarshal(item.Request, &req); err != nil {
+			return imported, err
+		}
+		if rawURL, err = getPostmanURL(req.URL, replace); err != nil {
+			return imported, err
+		}
+	}
+	query := models.Query{
+		Type:   models.QueryTypeJSON,
+		Format: "table",
+		Source: "url",
+		Parser: models.InfinityParserBackend,
+		URL:    replace(rawURL),
+	}
+	query.URLOptions.Method = strings.ToUpper(req.Method)
+	if query.URLOptions.Method == "" {
+		query.URLOptions.Method = "GET"
+	}
+	for _, h := range req.Header {
+		if h.Disabled || h.Key == "" {
+			continue
+		}
+		if strings.EqualFold(h.Key, headerKeyContentType) {
+			query.URLOptions.BodyContentType = replace(h.value())
+			continue
+		}
+		query.URLOptions.Headers = append(query.URLOptions.Headers, models.URLOptionKeyValuePair{Key: replace(h.Key), Value: replace(h.value())})
+	}
+	if req.Body != nil {
+		switch req.Body.Mode {
+		case "raw":
+			query.URLOptions.BodyType = "raw"
+			query.URLOptions.Body = replace(req.Body.Raw)
+			if query.URLOptions.BodyContentType == "" {
+				query.URLOptions.BodyContentType = postmanContentTypes[req.Body.Options.Raw.Language]
+			}
+		case "urlencoded":
+			query.URLOptions.BodyType = "x-www-form-urlencoded"
+			query.URLOptions.BodyContentType = ""
+			query.URLOptions.BodyForm = getPostmanFormFields(req.Body.URLEncoded, replace, &imported)
+		case "formdata":
+			query.URLOptions.BodyType = "form-data"
+			query.URLOptions.BodyContentType = ""
+			query.URLOptions.BodyForm = getPostmanFormFields(req.Body.FormData, replace, &imported)
+		case "graphql":
+			query.Type = models.QueryTypeGraphQL
+			query.URLOptions.BodyType = "graphql"
+			query.URLOptions.BodyContentType = "application/json"
+			query.URLOptions.BodyGraphQLQuery = replace(req.Body.GraphQL.Query)
+			query.URLOptions.BodyGraphQLVariables = replace(req.Body.GraphQL.Variables)
+		case "":
+		default:
+			imported.Warnings = append(imported.Warnings, fmt.Sprintf("body mode %s is not supported", req.Body.Mode))
+		}
+	}
+	if req.Auth != nil {
+		auth = req.Auth
+	}
+	if auth != nil && auth.Type != "" {
+		hint, ok := postmanAuthMethods[auth.Type]
+		if !ok {
+			imported.Warnings = append(imported.Warnings, fmt.Sprintf("authentication type %s is not supported", auth.Type))
+		}
+		if hint != models.AuthenticationMethodNone {
+			imported.AuthHint = hint
+		}
+	}
+	imported.Query = query
+	return imported, nil
+}
+
+// getPostmanURL returns the url of the request. The disabled query params are removed and the path variables (:id) are replaced
+func getPostmanURL(input json.RawMessage, replace func(string) string) (string, error) {
+	if len(input) == 0 {
+		return "", errors.New("url is required")
+	}
+	var s string
+	if err := json.Unmarshal(input, &s); err == nil {
+		return s, nil
+	}
+	var u postmanURL
+	if err := json.Unmarshal(input, &u); err != nil {
+		return "", fmt.Errorf("invalid url. %w", err)
+	}
+	rawURL := u.Raw
+	if u.Query != nil {
+		rawURL, _, _ = strings.Cut(rawURL, "?")
+		params := []string{}
+		for _, q := range u.Query {
+			if q.Disabled {
+				continue
+			}
+			params = append(params, q.Key+"="+q.value())
+		}
+		if len(params) > 0 {
+			rawURL += "?" + strings.Join(params, "&")
+		}
+	}
+	for _, v := range u.Variable {
+		if v.Key != "" && v.Value != nil {
+			rawURL = strings.ReplaceAll(rawURL, ":"+v.Key, replace(v.value()))
+		}
+	}
+	return rawURL, nil
+}
+
+func getPostmanFormFields(fields []postmanKeyValue, replace func(string) string, imported *ImportedQuery) []models.URLOptionKeyValuePair {
+	out := []models.URLOptionKeyValuePair{}
+	for _, f := range fields {
+		if f.Disabled {
+			continue
+		}
+		if f.Type == "file" {
+			imported.Warnings = append(imported.Warnings, fmt.Sprintf("form file %s is not imported. configure the file in the datasource settings", f.Key))
+			continue
+		}
+		out = append(out, models.URLOptionKeyValuePair{Key: replace(f.Key), Value: replace(f.value())})
+	}
+	return out
+}
+
+func appendUnique(items []string, item string) []string {
+	for _, i := range items {
+		if i == item {
+			return items
+		}
+	}
+	return append(items, item)
+}
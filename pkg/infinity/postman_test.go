@@ -0,0 +1,109 @@
+package infinity_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+const testPostmanCollection = `{
+	"info": { "name": "demo", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json" },
+	"auth": { "type": "bearer", "bearer": [{ "key": "token", "value": "secret" }] },
+	"variable": [{ "key": "baseUrl", "value": "https://api.example.com" }],
+	"item": [
+		{ "name": "ping", "request": "https://api.example.com/ping" },
+		{
+			"name": "users",
+			"item": [
+				{
+					"name": "get user",
+					"request": {
+						"method": "get",
+						"header": [{ "key": "Accept", "value": "application/json" }, { "key": "X-Debug", "value": "1", "disabled": true }],
+						"url": {
+							"raw": "{{baseUrl}}/users/:id?fields=name&page=1",
+							"query": [{ "key": "fields", "value": "name" }, { "key": "page", "value": "1", "disabled": true }],
+							"variable": [{ "key": "id", "value": "{{userId}}" }]
+						}
+					}
+				},
+				{
+					"name": "create user",
+					"request": {
+						"method": "POST",
+						"auth": { "type": "basic" },
+						"header": [{ "key": "Content-Type", "value": "application/json" }],
+						"url": "{{baseUrl}}/users",
+						"body": { "mode": "raw", "raw": "{ \"name\": \"foo\" }" }
+					}
+				},
+				{
+					"name": "upload",
+					"request": {
+						"method": "POST",
+						"auth": { "type": "hawk" },
+						"url": "{{baseUrl}}/upload",
+						"body": { "mode": "formdata", "formdata": [{ "key": "kind", "value": "csv" }, { "key": "file", "type": "file", "src": "a.csv" }] }
+					}
+				},
+				{
+					"name": "search",
+					"request": {
+						"method": "POST",
+						"url": "{{baseUrl}}/graphql",
+						"body": { "mode": "graphql", "graphql": { "query": "{ users { name } }", "variables": "{}" } }
+					}
+				}
+			]
+		}
+	]
+}`
+
+func TestImportPostmanCollection(t *testing.T) {
+	t.Run("should convert all the requests", func(t *testing.T) {
+		got, err := infinity.ImportPostmanCollection([]byte(testPostmanCollection), nil)
+		require.Nil(t, err)
+		require.Equal(t, 5, len(got))
+
+		require.Equal(t, "ping", got[0].Name)
+		require.Equal(t, "", got[0].Folder)
+		require.Equal(t, "https://api.example.com/ping", got[0].Query.URL)
+		require.Equal(t, "GET", got[0].Query.URLOptions.Method)
+		require.Equal(t, models.AuthenticationMethodBearerToken, got[0].AuthHint)
+
+		require.Equal(t, "users", got[1].Folder)
+		require.Equal(t, "https://api.example.com/users/{{userId}}?fields=name", got[1].Query.URL)
+		require.Equal(t, []models.URLOptionKeyValuePair{{Key: "Accept", Value: "application/json"}}, got[1].Query.URLOptions.Headers)
+		require.Equal(t, []string{"variable {{userId}} is not defined in the collection"}, got[1].Warnings)
+
+		require.Equal(t, "POST", got[2].Query.URLOptions.Method)
+		require.Equal(t, "raw", got[2].Query.URLOptions.BodyType)
+		require.Equal(t, "application/json", got[2].Query.URLOptions.BodyContentType)
+		require.Equal(t, `{ "name": "foo" }`, got[2].Query.URLOptions.Body)
+		require.Equal(t, models.AuthenticationMethodBasic, got[2].AuthHint)
+
+		require.Equal(t, "form-data", got[3].Query.URLOptions.BodyType)
+		require.Equal(t, []models.URLOptionKeyValuePair{{Key: "kind", Value: "csv"}}, got[3].Query.URLOptions.BodyForm)
+		require.Equal(t, []string{
+			"form file file is not imported. configure the file in the datasource settings",
+			"authentication type hawk is not supported",
+		}, got[3].Warnings)
+
+		require.Equal(t, models.QueryTypeGraphQL, got[4].Query.Type)
+		require.Equal(t, "{ users { name } }", got[4].Query.URLOptions.BodyGraphQLQuery)
+	})
+	t.Run("should convert the selected requests", func(t *testing.T) {
+		got, err := infinity.ImportPostmanCollection([]byte(testPostmanCollection), []string{"ping", "users/search"})
+		require.Nil(t, err)
+		require.Equal(t, 2, len(got))
+		require.Equal(t, "ping", got[0].Name)
+		require.Equal(t, "search", got[1].Name)
+	})
+	t.Run("should return error for the invalid collections", func(t *testing.T) {
+		_, err := infinity.ImportPostmanCollection([]byte(`{ "requests": [] }`), nil)
+		require.NotNil(t, err)
+		require.Equal(t, infinity.ErrorKindUser, infinity.GetErrorKind(err))
+	})
+}
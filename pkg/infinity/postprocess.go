@@ -15,11 +15,18 @@ import (
 func PostProcessFrame(ctx context.Context, frame *data.Frame, query models.Query) (*data.Frame, error) {
 	ctx, span := tracing.DefaultTracer().Start(ctx, "PostProcessFrame")
 	defer span.End()
+	defer trackTiming(ctx, transformTiming)()
+	frame, err := ApplyColumnOptions(ctx, frame, query)
+	if err != nil {
+		backend.Logger.Error("error applying column options", "error", err.Error())
+		frame.Meta.Custom = &CustomMeta{Query: query, Error: err.Error()}
+		return frame, err
+	}
 	cc := []transformations.ComputedColumn{}
 	for _, c := range query.ComputedColumns {
 		cc = append(cc, transformations.ComputedColumn{Selector: c.Selector, Text: c.Text})
 	}
-	frame, err := transformations.GetFrameWithComputedColumns(frame, cc)
+	frame, err = transformations.GetFrameWithComputedColumns(frame, cc)
 	if err != nil {
 		backend.Logger.Error("error getting computed column", "error", err.Error())
 		frame.Meta.Custom = &CustomMeta{Query: query, Error: err.Error()}
@@ -31,6 +38,12 @@ func PostProcessFrame(ctx context.Context, frame *data.Frame, query models.Query
 		frame.Meta.Custom = &CustomMeta{Query: query, Error: err.Error()}
 		return frame, fmt.Errorf("error applying filter. %w", err)
 	}
+	frame, err = ApplyColumnFunctions(frame, query.ColumnFunctions)
+	if err != nil {
+		backend.Logger.Error("error applying column functions", "error", err.Error())
+		frame.Meta.Custom = &CustomMeta{Query: query, Error: err.Error()}
+		return frame, err
+	}
 	if strings.TrimSpace(query.SummarizeExpression) != "" {
 		return transformations.GetSummaryFrame(frame, query.SummarizeExpression, query.SummarizeBy, "summary")
 	}
@@ -46,10 +59,16 @@ func PostProcessFrame(ctx context.Context, frame *data.Frame, query models.Query
 			return frame, err
 		}
 	}
+	frame, err = ResampleFrame(frame, query.ResampleInterval, query.ResampleFill)
+	if err != nil {
+		backend.Logger.Error("error resampling the frame", "error", err.Error())
+		frame.Meta.Custom = &CustomMeta{Query: query, Error: err.Error()}
+		return frame, err
+	}
 	if query.Format == "timeseries" && frame.TimeSeriesSchema().Type == data.TimeSeriesTypeLong {
 		if wFrame, err := data.LongToWide(frame, &data.FillMissing{Mode: data.FillModeNull}); err == nil {
-			return wFrame, err
+			frame = wFrame
 		}
 	}
-	return frame, nil
+	return DownsampleFrame(frame, query.DownsampleMode, query.DownsamplePoints)
 }
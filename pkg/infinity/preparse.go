@@ -0,0 +1,119 @@
+package infinity
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+// ApplyPreParseSteps transforms the response body before parsing. This allows parsing the responses such as JSON encoded as string
+// inside the JSON or base64 encoded payloads
+func ApplyPreParseSteps(body []byte, steps []models.PreParseStep) ([]byte, error) {
+	var err error
+	for i, step := range steps {
+		switch step.Type {
+		case models.PreParseRegexReplace:
+			re, e := regexp.Compile(step.Pattern)
+			if e != nil {
+				return body, fmt.Errorf("invalid pre-parse regex %s. %w", step.Pattern, e)
+			}
+			body = re.ReplaceAll(body, []byte(step.Replacement))
+		case models.PreParseJSONUnwrap:
+			body, err = transformJSONString(body, step.Selector, unwrapJSON)
+		case models.PreParseBase64Decode:
+			body, err = transformJSONString(body, step.Selector, decodeBase64)
+		default:
+			return body, fmt.Errorf("unknown pre-parse step %s", step.Type)
+		}
+		if err != nil {
+			return body, fmt.Errorf("error applying pre-parse step %d (%s). %w", i+1, step.Type, err)
+		}
+	}
+	return body, nil
+}
+
+// transformJSONString applies the transform to the string selected by the selector. When the selector is empty, the body is transformed
+func transformJSONString(body []byte, selector string, transform func(string) (any, error)) ([]byte, error) {
+	if strings.TrimSpace(selector) == "" {
+		input := string(bytes.TrimSpace(body))
+		var s string
+		if err := json.Unmarshal([]byte(input), &s); err == nil {
+			input = s
+		}
+		out, err := transform(input)
+		if err != nil {
+			return body, err
+		}
+		if s, ok := out.(string); ok {
+			return []byte(s), nil
+		}
+		return json.Marshal(out)
+	}
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return body, fmt.Errorf("response is not a valid JSON. %w", err)
+	}
+	doc, err := transformJSONPath(doc, strings.Split(selector, "."), transform)
+	if err != nil {
+		return body, err
+	}
+	return json.Marshal(doc)
+}
+
+func transformJSONPath(node any, path []string, transform func(string) (any, error)) (any, error) {
+	if len(path) == 0 {
+		s, ok := node.(string)
+		if !ok {
+			return node, errors.New("selected field is not a string")
+		}
+		return transform(s)
+	}
+	switch n := node.(type) {
+	case map[string]any:
+		child, ok := n[path[0]]
+		if !ok {
+			return node, nil
+		}
+		v, err := transformJSONPath(child, path[1:], transform)
+		if err != nil {
+			return node, fmt.Errorf("%s. %w", path[0], err)
+		}
+		n[path[0]] = v
+	case []any:
+		if path[0] != "*" {
+			return node, fmt.Errorf("use * to select the array items instead of %s", path[0])
+		}
+		for i, item := range n {
+			v, err := transformJSONPath(item, path[1:], transform)
+			if err != nil {
+				return node, err
+			}
+			n[i] = v
+		}
+	}
+	return node, nil
+}
+
+func unwrapJSON(input string) (any, error) {
+	var out any
+	if err := json.Unmarshal([]byte(input), &out); err != nil {
+		return nil, fmt.Errorf("selected field is not a valid JSON. %w", err)
+	}
+	return out, nil
+}
+
+func decodeBase64(input string) (any, error) {
+	input = strings.TrimSpace(input)
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if out, err := encoding.DecodeString(input); err == nil {
+			return string(out), nil
+		}
+	}
+	return nil, errors.New("selected field is not a valid base64 string")
+}
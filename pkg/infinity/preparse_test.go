@@ -0,0 +1,84 @@
+package infinity_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestApplyPreParseSteps(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		steps   []models.PreParseStep
+		want    string
+		wantErr string
+	}{
+		{
+			name: "no steps",
+			body: `{"a":1}`,
+			want: `{"a":1}`,
+		},
+		{
+			name:  "regex replace",
+			body:  `while(1);{"a":1}`,
+			steps: []models.PreParseStep{{Type: models.PreParseRegexReplace, Pattern: `^while\(1\);`}},
+			want:  `{"a":1}`,
+		},
+		{
+			name:  "json unwrap of the body",
+			body:  `"{\"a\":1}"`,
+			steps: []models.PreParseStep{{Type: models.PreParseJSONUnwrap}},
+			want:  `{"a":1}`,
+		},
+		{
+			name:  "json unwrap of the nested field",
+			body:  `{"status":"ok","data":{"payload":"[{\"a\":1},{\"a\":2}]"}}`,
+			steps: []models.PreParseStep{{Type: models.PreParseJSONUnwrap, Selector: "data.payload"}},
+			want:  `{"data":{"payload":[{"a":1},{"a":2}]},"status":"ok"}`,
+		},
+		{
+			name:  "json unwrap of the array items",
+			body:  `{"items":[{"value":"{\"a\":1}"},{"value":"{\"a\":2}"}]}`,
+			steps: []models.PreParseStep{{Type: models.PreParseJSONUnwrap, Selector: "items.*.value"}},
+			want:  `{"items":[{"value":{"a":1}},{"value":{"a":2}}]}`,
+		},
+		{
+			name:  "base64 decode of the field followed by json unwrap",
+			body:  `{"content":"eyJhIjoxfQ=="}`,
+			steps: []models.PreParseStep{{Type: models.PreParseBase64Decode, Selector: "content"}, {Type: models.PreParseJSONUnwrap, Selector: "content"}},
+			want:  `{"content":{"a":1}}`,
+		},
+		{
+			name:  "base64 decode of the body",
+			body:  "bmFtZSxhZ2UKZm9vLDEw\n",
+			steps: []models.PreParseStep{{Type: models.PreParseBase64Decode}},
+			want:  "name,age\nfoo,10",
+		},
+		{
+			name:    "invalid json in the field",
+			body:    `{"data":"foo"}`,
+			steps:   []models.PreParseStep{{Type: models.PreParseJSONUnwrap, Selector: "data"}},
+			wantErr: "error applying pre-parse step 1 (json_unwrap). data. selected field is not a valid JSON",
+		},
+		{
+			name:    "invalid regex",
+			body:    `{}`,
+			steps:   []models.PreParseStep{{Type: models.PreParseRegexReplace, Pattern: `(`}},
+			wantErr: "invalid pre-parse regex",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := infinity.ApplyPreParseSteps([]byte(tt.body), tt.steps)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tt.want, string(got))
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package infinity
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+// sourcePresetTimeFields are the date fields of the presets, converted into the time columns
+var sourcePresetTimeFields = map[models.SourcePreset][]string{
+	models.SourcePresetJira:       {"created", "updated", "resolutiondate", "lastViewed"},
+	models.SourcePresetServiceNow: {"sys_created_on", "sys_updated_on", "opened_at", "closed_at", "resolved_at"},
+	models.SourcePresetDocker:     {"Created", "read"},
+}
+
+var sourcePresetTimeLayouts = []string{
+	"2006-01-02T15:04:05.000-0700", // jira
+	"2006-01-02 15:04:05",          // servicenow, in UTC
+	time.RFC3339Nano,
+}
+
+// applySourcePresetToResponse selects the items of the preset response and flattens the nested fields of every item.
+// When the query has no columns, the columns are derived from the items with the date fields as the time columns
+func applySourcePresetToResponse(query models.Query, response any) (any, models.Query) {
+	switch query.SourcePreset {
+	case models.SourcePresetJira, models.SourcePresetServiceNow, models.SourcePresetKubernetes, models.SourcePresetDocker:
+	default:
+		return response, query
+	}
+	if stats, ok := response.(map[string]any); ok && query.SourcePreset == models.SourcePresetDocker && stats["cpu_stats"] != nil {
+		response = []any{getDockerStatsRow(stats)}
+	}
+	items, ok := response.([]any)
+	if m, isObject := response.(map[string]any); isObject {
+		items, ok = m[query.RootSelector].([]any)
+	}
+	if !ok {
+		return response, query
+	}
+	if query.SourcePreset == models.SourcePresetKubernetes {
+		// resources are kept as is. columns select the nested fields with their path. ex: metadata.name, status.phase
+		query.RootSelector = ""
+		if len(query.Columns) == 0 {
+			query.Columns = getKubernetesColumns(items)
+		}
+		return items, query
+	}
+	rows := make([]any, 0, len(items))
+	for _, item := range items {
+		rows = append(rows, flattenSourcePresetItem(query.SourcePreset, item))
+	}
+	query.RootSelector = ""
+	if len(query.Columns) == 0 {
+		query.Columns = getSourcePresetColumns(query.SourcePreset, rows)
+	}
+	return rows, query
+}
+
+func flattenSourcePresetItem(preset models.SourcePreset, item any) map[string]any {
+	row := map[string]any{}
+	in, _ := item.(map[string]any)
+	if preset == models.SourcePresetJira {
+		// issue fields are nested under the fields object
+		fields, _ := in["fields"].(map[string]any)
+		for k, v := range fields {
+			row[k] = flattenSourcePresetValue(v)
+		}
+		for _, k := range []string{"id", "key", "self"} {
+			row[k] = in[k]
+		}
+	} else if preset == models.SourcePresetDocker {
+		row = flattenDockerItem(in)
+	} else {
+		for k, v := range in {
+			row[k] = flattenSourcePresetValue(v)
+		}
+	}
+	for _, k := range sourcePresetTimeFields[preset] {
+		if s, ok := row[k].(string); ok {
+			row[k] = formatSourcePresetTime(s)
+		}
+	}
+	return row
+}
+
+// flattenSourcePresetValue replaces the nested objects such as status, assignee (jira) or the reference fields (servicenow) with their display value
+func flattenSourcePresetValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for _, k := range []string{"display_value", "displayName", "name", "value", "key"} {
+			if s, ok := v[k]; ok {
+				return flattenSourcePresetValue(s)
+			}
+		}
+		return v
+	case []any:
+		values := []string{}
+		for _, item := range v {
+			if s, ok := flattenSourcePresetValue(item).(string); ok {
+				values = append(values, s)
+			}
+		}
+		if len(values) == len(v) {
+			return strings.Join(values, ", ")
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func formatSourcePresetTime(s string) string {
+	for _, layout := range sourcePresetTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return s
+}
+
+func getSourcePresetColumns(preset models.SourcePreset, rows []any) []models.InfinityColumn {
+	types := map[string]string{}
+	for _, r := range rows {
+		row, _ := r.(map[string]any)
+		for k, v := range row {
+			if _, ok := types[k]; ok || v == nil {
+				continue
+			}
+			switch v.(type) {
+			case float64:
+				types[k] = "number"
+			case bool:
+				types[k] = "boolean"
+			case map[string]any, []any:
+				// docker keeps the nested objects in the rows. they are selected with their path instead
+				if preset != models.SourcePresetDocker {
+					types[k] = "string"
+				}
+			default:
+				types[k] = "string"
+			}
+		}
+	}
+	for _, k := range sourcePresetTimeFields[preset] {
+		if _, ok := types[k]; ok {
+			types[k] = "timestamp"
+		}
+	}
+	names := make([]string, 0, len(types))
+	for k := range types {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	columns := make([]models.InfinityColumn, 0, len(names))
+	for _, name := range names {
+		columns = append(columns, models.InfinityColumn{Selector: name, Text: name, Type: types[name]})
+	}
+	return columns
+}
+
+// kubernetesMapFields are the maps of the resources kept as a single column instead of a column per key
+var kubernetesMapFields = map[string]bool{"metadata.labels": true, "metadata.annotations": true, "spec.selector": true, "spec.nodeSelector": true}
+
+// getKubernetesColumns returns the columns of the leaf fields of the resources. metadata.managedFields is skipped and the arrays are kept as a single column
+func getKubernetesColumns(items []any) []models.InfinityColumn {
+	types := map[string]string{}
+	for _, item := range items {
+		if resource, ok := item.(map[string]any); ok {
+			collectKubernetesFields("", resource, types)
+		}
+	}
+	names := make([]string, 0, len(types))
+	for k := range types {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	columns := make([]models.InfinityColumn, 0, len(names))
+	for _, name := range names {
+		columns = append(columns, models.InfinityColumn{Selector: name, Text: name, Type: types[name]})
+	}
+	return columns
+}
+
+func collectKubernetesFields(prefix string, resource map[string]any, types map[string]string) {
+	for k, v := range resource {
+		path := prefix + k
+		if path == "metadata.managedFields" || v == nil {
+			continue
+		}
+		if nested, ok := v.(map[string]any); ok && !kubernetesMapFields[path] {
+			collectKubernetesFields(path+".", nested, types)
+			continue
+		}
+		if _, ok := types[path]; ok {
+			continue
+		}
+		switch value := v.(type) {
+		case float64:
+			types[path] = "number"
+		case bool:
+			types[path] = "boolean"
+		case string:
+			types[path] = "string"
+			if _, err := time.Parse(time.RFC3339, value); err == nil {
+				types[path] = "timestamp"
+			}
+		default:
+			types[path] = "string"
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package infinity_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestSourcePresets(t *testing.T) {
+	t.Run("jira", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "2", r.URL.Query().Get("maxResults"))
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{ "startAt": %s, "issues": [{
+				"id": "100%s", "key": "PRJ-%s",
+				"fields": { "summary": "foo", "status": { "name": "Done" }, "labels": ["a", "b"], "created": "2023-01-01T10:00:00.000+0100", "updated": null }
+			}] }`, r.URL.Query().Get("startAt"), r.URL.Query().Get("startAt"), r.URL.Query().Get("startAt"))
+		}))
+		defer server.Close()
+		client, err := infinity.NewClient(context.Background(), models.InfinitySettings{})
+		require.Nil(t, err)
+		query := models.ApplyDefaultsToQuery(context.Background(), models.Query{RefID: "A", Source: "url", URL: server.URL + "/rest/api/2/search", SourcePreset: models.SourcePresetJira, PageMaxPages: 2, PageParamSizeFieldVal: 2})
+		require.Equal(t, models.PaginationModeOffset, query.PageMode)
+		frame, err := infinity.GetFrameForURLSources(context.Background(), query, *client, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, 2, frame.Rows())
+		key, _ := frame.FieldByName("key")
+		require.Equal(t, "PRJ-2", *key.At(1).(*string))
+		status, _ := frame.FieldByName("status")
+		require.Equal(t, "Done", *status.At(0).(*string))
+		labels, _ := frame.FieldByName("labels")
+		require.Equal(t, "a, b", *labels.At(0).(*string))
+		created, _ := frame.FieldByName("created")
+		require.Equal(t, time.Date(2023, 1, 1, 9, 0, 0, 0, time.UTC), created.At(0).(*time.Time).UTC())
+	})
+	t.Run("servicenow", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "1000", r.URL.Query().Get("sysparm_limit"))
+			require.Equal(t, "0", r.URL.Query().Get("sysparm_offset"))
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{ "result": [{
+				"number": "INC001", "priority": "1", "sys_created_on": "2023-01-01 10:00:00",
+				"assigned_to": { "link": "https://example.service-now.com/api/now/table/sys_user/1", "value": "1" }
+			}] }`))
+		}))
+		defer server.Close()
+		client, err := infinity.NewClient(context.Background(), models.InfinitySettings{})
+		require.Nil(t, err)
+		query := models.ApplyDefaultsToQuery(context.Background(), models.Query{RefID: "A", Source: "url", URL: server.URL + "/api/now/table/incident", SourcePreset: models.SourcePresetServiceNow})
+		frame, err := infinity.GetFrameForURLSources(context.Background(), query, *client, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, 1, frame.Rows())
+		assignedTo, _ := frame.FieldByName("assigned_to")
+		require.Equal(t, "1", *assignedTo.At(0).(*string))
+		created, _ := frame.FieldByName("sys_created_on")
+		require.Equal(t, time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC), created.At(0).(*time.Time).UTC())
+	})
+}
@@ -0,0 +1,216 @@
+package infinity
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	maxProbeTargets = 50
+	maxProbeCount   = 10
+	// defaultProbeTimeout is the timeout of the single attempt when the datasource timeout is not configured
+	defaultProbeTimeout = 5 * time.Second
+)
+
+// probeResult is the result of the single attempt
+type probeResult struct {
+	Latency    time.Duration
+	TLSVersion string
+	Err        error
+}
+
+// GetFrameForProbe checks the targets concurrently and returns a row per target with the success, latency and loss columns.
+// Unreachable targets are not the query errors. When the allowed hosts are configured, the target must match one of them as mode://host:port
+func GetFrameForProbe(ctx context.Context, query models.Query, infClient Client) (*data.Frame, error) {
+	ctx, span := tracing.DefaultTracer().Start(ctx, "GetFrameForProbe")
+	defer span.End()
+	if query.ProbeOptions == nil || len(query.ProbeOptions.Targets) == 0 {
+		return nil, UserError(errors.New("at least one target is required for the probe query"))
+	}
+	options := *query.ProbeOptions
+	if options.Mode == "" {
+		options.Mode = models.ProbeModeTCP
+	}
+	if options.Mode != models.ProbeModeTCP && options.Mode != models.ProbeModeTLS && options.Mode != models.ProbeModeICMP {
+		return nil, UserError(fmt.Errorf("invalid probe mode %s. mode should be tcp, tls or icmp", options.Mode))
+	}
+	if len(options.Targets) > maxProbeTargets {
+		return nil, UserError(fmt.Errorf("too many probe targets. targets should be up to %d", maxProbeTargets))
+	}
+	if options.Count <= 0 {
+		options.Count = 1
+	}
+	if options.Count > maxProbeCount {
+		return nil, UserError(fmt.Errorf("invalid probe count %d. count should be up to %d", options.Count, maxProbeCount))
+	}
+	for i, target := range options.Targets {
+		target = strings.TrimSpace(target)
+		if options.Mode != models.ProbeModeICMP {
+			if _, _, err := net.SplitHostPort(target); err != nil {
+				return nil, UserError(fmt.Errorf("invalid probe target %s. target should be host:port", target))
+			}
+		}
+		if !CanAllowURL(fmt.Sprintf("%s://%s", options.Mode, target), infClient.Settings.AllowedHosts) {
+			return nil, UserError(fmt.Errorf("probe target %s is not in the allowed hosts", target))
+		}
+		options.Targets[i] = target
+	}
+	timeout := time.Duration(infClient.Settings.TimeoutInSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = defaultProbeTimeout
+	}
+	var tlsConfig *tls.Config
+	if options.Mode == models.ProbeModeTLS {
+		config, err := GetTLSConfigFromSettings(infClient.Settings)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig = config
+	}
+	rows := make([]any, len(options.Targets))
+	var wg sync.WaitGroup
+	for i, target := range options.Targets {
+		wg.Add(1)
+		go func(i int, target string) {
+			defer wg.Done()
+			results := make([]probeResult, 0, options.Count)
+			for attempt := 0; attempt < options.Count && ctx.Err() == nil; attempt++ {
+				results = append(results, probeTarget(ctx, options.Mode, target, timeout, tlsConfig))
+			}
+			rows[i] = getProbeRow(options.Mode, target, results)
+		}(i, target)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, ContextError(ctx)
+	}
+	rowsQuery := query
+	rowsQuery.RootSelector = ""
+	frame, err := GetJSONBackendResponse(ctx, rows, rowsQuery)
+	if err != nil {
+		return frame, err
+	}
+	return PostProcessFrame(ctx, frame, rowsQuery)
+}
+
+func probeTarget(ctx context.Context, mode models.ProbeMode, target string, timeout time.Duration, tlsConfig *tls.Config) probeResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	switch mode {
+	case models.ProbeModeICMP:
+		return probeICMP(ctx, target)
+	case models.ProbeModeTLS:
+		host, _, _ := net.SplitHostPort(target)
+		config := tlsConfig.Clone()
+		if config.ServerName == "" {
+			config.ServerName = host
+		}
+		conn, err := (&tls.Dialer{Config: config}).DialContext(ctx, "tcp", target)
+		if err != nil {
+			return probeResult{Err: err}
+		}
+		defer conn.Close()
+		return probeResult{Latency: time.Since(start), TLSVersion: tls.VersionName(conn.(*tls.Conn).ConnectionState().Version)}
+	default:
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", target)
+		if err != nil {
+			return probeResult{Err: err}
+		}
+		defer conn.Close()
+		return probeResult{Latency: time.Since(start)}
+	}
+}
+
+// probeICMP sends the echo request using the unprivileged datagram socket, and the raw socket when the datagram sockets are not permitted
+func probeICMP(ctx context.Context, host string) probeResult {
+	addr, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return probeResult{Err: err}
+	}
+	if len(addr) == 0 {
+		return probeResult{Err: fmt.Errorf("no addresses found for %s", host)}
+	}
+	ip := addr[0].IP
+	network, privileged, protocol := "udp4", "ip4:icmp", 1
+	var echoType, replyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	if ip.To4() == nil {
+		network, privileged, protocol = "udp6", "ip6:ipv6-icmp", 58
+		echoType, replyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+	var dst net.Addr = &net.UDPAddr{IP: ip, Zone: addr[0].Zone}
+	conn, err := icmp.ListenPacket(network, "")
+	if err != nil {
+		if conn, err = icmp.ListenPacket(privileged, ""); err != nil {
+			return probeResult{Err: fmt.Errorf("icmp is not permitted. %w", err)}
+		}
+		dst = &net.IPAddr{IP: ip, Zone: addr[0].Zone}
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+	id, seq := os.Getpid()&0xffff, int(time.Now().UnixNano()&0xffff)
+	request, err := (&icmp.Message{Type: echoType, Body: &icmp.Echo{ID: id, Seq: seq, Data: []byte("grafana-infinity")}}).Marshal(nil)
+	if err != nil {
+		return probeResult{Err: err}
+	}
+	start := time.Now()
+	if _, err := conn.WriteTo(request, dst); err != nil {
+		return probeResult{Err: err}
+	}
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return probeResult{Err: err}
+		}
+		reply, err := icmp.ParseMessage(protocol, buf[:n])
+		if err != nil || reply.Type != replyType {
+			continue
+		}
+		// datagram sockets rewrite the echo id, so only the sequence is matched
+		if echo, ok := reply.Body.(*icmp.Echo); ok && echo.Seq == seq {
+			return probeResult{Latency: time.Since(start)}
+		}
+	}
+}
+
+// getProbeRow aggregates the attempts of the target. latency is the average of the successful attempts and error is the last error
+func getProbeRow(mode models.ProbeMode, target string, results []probeResult) map[string]any {
+	row := map[string]any{"target": target, "mode": string(mode), "success": false}
+	var total time.Duration
+	succeeded := 0
+	for _, result := range results {
+		if result.Err != nil {
+			row["error"] = result.Err.Error()
+			continue
+		}
+		succeeded++
+		total += result.Latency
+		if result.TLSVersion != "" {
+			row["tls_version"] = result.TLSVersion
+		}
+	}
+	if succeeded > 0 {
+		row["success"] = true
+		row["latency_ms"] = float64((total / time.Duration(succeeded)).Microseconds()) / 1000
+	}
+	if len(results) > 0 {
+		row["loss_percent"] = float64(len(results)-succeeded) * 100 / float64(len(results))
+	}
+	return row
+}
@@ -0,0 +1,80 @@
+package infinity_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestGetFrameForProbe(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	target := strings.TrimPrefix(server.URL, "https://")
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	// closed port. connections are refused
+	closed := listener.Addr().String()
+	listener.Close()
+	client := infinity.Client{Settings: models.InfinitySettings{TimeoutInSeconds: 2, InsecureSkipVerify: true}}
+	value := func(frame *data.Frame, name string, row int) any {
+		field, _ := frame.FieldByName(name)
+		require.NotNil(t, field, name)
+		v, _ := field.ConcreteAt(row)
+		return v
+	}
+	t.Run("tcp", func(t *testing.T) {
+		query := models.Query{RefID: "A", Source: "probe", ProbeOptions: &models.ProbeOptions{Targets: []string{target, closed}, Count: 2}}
+		frame, err := infinity.GetFrameForProbe(context.Background(), query, client)
+		require.Nil(t, err)
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, true, value(frame, "success", 0))
+		require.Equal(t, 0.0, value(frame, "loss_percent", 0))
+		require.NotNil(t, value(frame, "latency_ms", 0))
+		require.Equal(t, false, value(frame, "success", 1))
+		require.Equal(t, 100.0, value(frame, "loss_percent", 1))
+		require.Contains(t, value(frame, "error", 1), "connection refused")
+	})
+	t.Run("tls", func(t *testing.T) {
+		query := models.Query{RefID: "A", Source: "probe", ProbeOptions: &models.ProbeOptions{Mode: models.ProbeModeTLS, Targets: []string{target}}}
+		frame, err := infinity.GetFrameForProbe(context.Background(), query, client)
+		require.Nil(t, err)
+		require.Equal(t, true, value(frame, "success", 0))
+		require.Equal(t, "TLS 1.3", value(frame, "tls_version", 0))
+	})
+	t.Run("tls handshake failure", func(t *testing.T) {
+		client := infinity.Client{Settings: models.InfinitySettings{TimeoutInSeconds: 2}}
+		query := models.Query{RefID: "A", Source: "probe", ProbeOptions: &models.ProbeOptions{Mode: models.ProbeModeTLS, Targets: []string{target}}}
+		frame, err := infinity.GetFrameForProbe(context.Background(), query, client)
+		require.Nil(t, err)
+		require.Equal(t, false, value(frame, "success", 0))
+		require.Contains(t, value(frame, "error", 0), "certificate")
+	})
+	t.Run("icmp", func(t *testing.T) {
+		query := models.Query{RefID: "A", Source: "probe", ProbeOptions: &models.ProbeOptions{Mode: models.ProbeModeICMP, Targets: []string{"127.0.0.1"}}}
+		frame, err := infinity.GetFrameForProbe(context.Background(), query, client)
+		require.Nil(t, err)
+		if value(frame, "success", 0) == false && strings.Contains(value(frame, "error", 0).(string), "icmp is not permitted") {
+			t.Skip("icmp sockets are not permitted")
+		}
+		require.Equal(t, true, value(frame, "success", 0))
+	})
+	t.Run("invalid target", func(t *testing.T) {
+		query := models.Query{RefID: "A", Source: "probe", ProbeOptions: &models.ProbeOptions{Targets: []string{"db.example.com"}}}
+		_, err := infinity.GetFrameForProbe(context.Background(), query, client)
+		require.ErrorContains(t, err, "target should be host:port")
+	})
+	t.Run("target outside the allowed hosts", func(t *testing.T) {
+		client := infinity.Client{Settings: models.InfinitySettings{AllowedHosts: []string{"tcp://db.example.com:5432"}}}
+		query := models.Query{RefID: "A", Source: "probe", ProbeOptions: &models.ProbeOptions{Targets: []string{target}}}
+		_, err := infinity.GetFrameForProbe(context.Background(), query, client)
+		require.ErrorContains(t, err, "not in the allowed hosts")
+	})
+}
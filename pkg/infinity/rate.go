@@ -0,0 +1,163 @@
+package infinity
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+// ApplyColumnFunctions computes the rate, delta, cumulative sum, moving average, ewma or outlier flags of the numeric columns over the time ordered rows.
+// Rows are grouped by the string fields, so each series of the long frames is computed separately.
+// When the alias is empty the column is replaced, otherwise the result is added as a new column. Outlier flags are always added as a new boolean column
+func ApplyColumnFunctions(frame *data.Frame, functions []models.ColumnFunction) (*data.Frame, error) {
+	if frame == nil || len(functions) == 0 || frame.Rows() == 0 {
+		return frame, nil
+	}
+	var timeField *data.Field
+	for _, field := range frame.Fields {
+		if field.Type().Time() {
+			timeField = field
+			break
+		}
+	}
+	if timeField == nil {
+		return frame, fmt.Errorf("column functions require a time field. found %s", describeFields(frame))
+	}
+	groups := getSeriesGroups(frame, timeField)
+	for _, fn := range functions {
+		field, idx := frame.FieldByName(fn.Field)
+		if idx == -1 {
+			return frame, fmt.Errorf("column function field %s not found", fn.Field)
+		}
+		if !field.Type().Numeric() {
+			return frame, fmt.Errorf("column function field %s must be a numeric field", fn.Field)
+		}
+		if fn.Function == models.ColumnFunctionOutlier {
+			flags, err := getOutlierFlags(fn, field, groups, frame.Rows())
+			if err != nil {
+				return frame, err
+			}
+			frame.Fields = append(frame.Fields, flags)
+			continue
+		}
+		values := make([]*float64, frame.Rows())
+		for _, rows := range groups {
+			if err := applyColumnFunction(fn, timeField, field, rows, values); err != nil {
+				return frame, err
+			}
+		}
+		out := data.NewField(field.Name, field.Labels, values)
+		if strings.TrimSpace(fn.Alias) == "" {
+			frame.Fields[idx] = out
+			continue
+		}
+		out.Name = fn.Alias
+		frame.Fields = append(frame.Fields, out)
+	}
+	return frame, nil
+}
+
+// getSeriesGroups returns the row indices of each series, sorted by time. rows without time are ignored
+func getSeriesGroups(frame *data.Frame, timeField *data.Field) map[string][]int {
+	groups := map[string][]int{}
+	for i := 0; i < frame.Rows(); i++ {
+		if _, ok := timeField.ConcreteAt(i); !ok {
+			continue
+		}
+		key := []string{}
+		for _, field := range frame.Fields {
+			if field.Type() == data.FieldTypeString || field.Type() == data.FieldTypeNullableString {
+				v, _ := field.ConcreteAt(i)
+				key = append(key, fmt.Sprintf("%v", v))
+			}
+		}
+		k := strings.Join(key, "\x00")
+		groups[k] = append(groups[k], i)
+	}
+	for _, rows := range groups {
+		sort.SliceStable(rows, func(a, b int) bool {
+			ta, _ := timeField.ConcreteAt(rows[a])
+			tb, _ := timeField.ConcreteAt(rows[b])
+			return ta.(time.Time).Before(tb.(time.Time))
+		})
+	}
+	return groups
+}
+
+// defaultSmoothingWindow is the number of points used for smoothing when the window is not set
+const defaultSmoothingWindow = 5
+
+func applyColumnFunction(fn models.ColumnFunction, timeField *data.Field, field *data.Field, rows []int, values []*float64) error {
+	var previous *float64
+	var previousTime time.Time
+	previousRow := -1
+	sum := 0.0
+	window := fn.Window
+	if window <= 0 {
+		window = defaultSmoothingWindow
+	}
+	alpha := fn.Alpha
+	if alpha == 0 {
+		alpha = 2 / float64(window+1)
+	}
+	if fn.Function == models.ColumnFunctionEWMA && (alpha < 0 || alpha > 1) {
+		return fmt.Errorf("invalid ewma alpha %v. alpha must be between 0 and 1", fn.Alpha)
+	}
+	points := []float64{}
+	for _, row := range rows {
+		current, err := field.NullableFloatAt(row)
+		if err != nil {
+			return err
+		}
+		t, _ := timeField.ConcreteAt(row)
+		currentTime := t.(time.Time)
+		if current == nil {
+			continue
+		}
+		switch fn.Function {
+		case models.ColumnFunctionMovingAverage:
+			// trailing window. the first points are averaged over the available points
+			points = append(points, *current)
+			if len(points) > window {
+				sum -= points[0]
+				points = points[1:]
+			}
+			sum += *current
+			v := sum / float64(len(points))
+			values[row] = &v
+		case models.ColumnFunctionEWMA:
+			v := *current
+			if previous != nil {
+				v = alpha*(*current) + (1-alpha)*(*values[previousRow])
+			}
+			values[row] = &v
+		case models.ColumnFunctionCumulativeSum:
+			sum += *current
+			v := sum
+			values[row] = &v
+		case models.ColumnFunctionDelta:
+			if previous != nil {
+				v := *current - *previous
+				values[row] = &v
+			}
+		case models.ColumnFunctionRate:
+			if previous != nil && currentTime.After(previousTime) {
+				// counter reset. the current value is the increase since the reset
+				increase := *current - *previous
+				if increase < 0 {
+					increase = *current
+				}
+				v := increase / currentTime.Sub(previousTime).Seconds()
+				values[row] = &v
+			}
+		default:
+			return fmt.Errorf("unknown column function %s", fn.Function)
+		}
+		previous, previousTime, previousRow = current, currentTime, row
+	}
+	return nil
+}
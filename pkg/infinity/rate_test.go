@@ -0,0 +1,67 @@
+package infinity_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestApplyColumnFunctions(t *testing.T) {
+	t0 := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	getFrame := func() *data.Frame {
+		return data.NewFrame("response",
+			data.NewField("time", nil, []time.Time{t0.Add(20 * time.Second), t0, t0.Add(10 * time.Second), t0, t0.Add(10 * time.Second), t0.Add(30 * time.Second)}),
+			data.NewField("host", nil, []string{"a", "a", "a", "b", "b", "a"}),
+			data.NewField("requests", nil, []*float64{toFP(300), toFP(100), toFP(200), toFP(5), toFP(10), toFP(50)}),
+		)
+	}
+	values := func(field *data.Field) []*float64 {
+		out := []*float64{}
+		for i := 0; i < field.Len(); i++ {
+			out = append(out, field.At(i).(*float64))
+		}
+		return out
+	}
+	t.Run("rate should be computed per series and handle counter resets", func(t *testing.T) {
+		frame, err := infinity.ApplyColumnFunctions(getFrame(), []models.ColumnFunction{{Field: "requests", Function: models.ColumnFunctionRate}})
+		require.Nil(t, err)
+		require.Equal(t, 3, len(frame.Fields))
+		require.Equal(t, []*float64{toFP(10), nil, toFP(10), nil, toFP(0.5), toFP(5)}, values(frame.Fields[2]))
+	})
+	t.Run("delta should be added as new column when alias is set", func(t *testing.T) {
+		frame, err := infinity.ApplyColumnFunctions(getFrame(), []models.ColumnFunction{{Field: "requests", Function: models.ColumnFunctionDelta, Alias: "delta"}})
+		require.Nil(t, err)
+		require.Equal(t, 4, len(frame.Fields))
+		require.Equal(t, "delta", frame.Fields[3].Name)
+		require.Equal(t, []*float64{toFP(100), nil, toFP(100), nil, toFP(5), toFP(-250)}, values(frame.Fields[3]))
+	})
+	t.Run("cumulative sum", func(t *testing.T) {
+		frame, err := infinity.ApplyColumnFunctions(getFrame(), []models.ColumnFunction{{Field: "requests", Function: models.ColumnFunctionCumulativeSum}})
+		require.Nil(t, err)
+		require.Equal(t, []*float64{toFP(600), toFP(100), toFP(300), toFP(5), toFP(15), toFP(650)}, values(frame.Fields[2]))
+	})
+	t.Run("moving average", func(t *testing.T) {
+		frame, err := infinity.ApplyColumnFunctions(getFrame(), []models.ColumnFunction{{Field: "requests", Function: models.ColumnFunctionMovingAverage, Window: 2}})
+		require.Nil(t, err)
+		require.Equal(t, []*float64{toFP(250), toFP(100), toFP(150), toFP(5), toFP(7.5), toFP(175)}, values(frame.Fields[2]))
+	})
+	t.Run("ewma", func(t *testing.T) {
+		frame, err := infinity.ApplyColumnFunctions(getFrame(), []models.ColumnFunction{{Field: "requests", Function: models.ColumnFunctionEWMA, Alpha: 0.5}})
+		require.Nil(t, err)
+		require.Equal(t, []*float64{toFP(225), toFP(100), toFP(150), toFP(5), toFP(7.5), toFP(137.5)}, values(frame.Fields[2]))
+		_, err = infinity.ApplyColumnFunctions(getFrame(), []models.ColumnFunction{{Field: "requests", Function: models.ColumnFunctionEWMA, Alpha: 1.5}})
+		require.ErrorContains(t, err, "invalid ewma alpha")
+	})
+	t.Run("should throw error for non numeric fields", func(t *testing.T) {
+		_, err := infinity.ApplyColumnFunctions(getFrame(), []models.ColumnFunction{{Field: "host", Function: models.ColumnFunctionRate}})
+		require.ErrorContains(t, err, "column function field host must be a numeric field")
+	})
+	t.Run("should throw error without time field", func(t *testing.T) {
+		_, err := infinity.ApplyColumnFunctions(data.NewFrame("response", data.NewField("value", nil, []float64{1})), []models.ColumnFunction{{Field: "value", Function: models.ColumnFunctionRate}})
+		require.ErrorContains(t, err, "column functions require a time field")
+	})
+}
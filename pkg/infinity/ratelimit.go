@@ -0,0 +1,123 @@
+package infinity
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// maxRateLimitWait is the longest the request is held back for the quota to reset. Requests fail when the reset is later
+	maxRateLimitWait = 30 * time.Second
+	// rateLimitReservePercent of the quota is left for the other queries. link pagination stops when the remaining quota is below it
+	rateLimitReservePercent = 5
+)
+
+// RateLimit is the quota of the API reported by the X-RateLimit-* (github) or RateLimit-* (gitlab) response headers
+type RateLimit struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+// nearLimit reports whether the remaining quota is within the reserve
+func (r RateLimit) nearLimit(now time.Time) bool {
+	if !r.Reset.IsZero() && now.After(r.Reset) {
+		return false
+	}
+	reserve := r.Limit * rateLimitReservePercent / 100
+	if reserve < 1 {
+		reserve = 1
+	}
+	return r.Remaining <= reserve
+}
+
+// getRateLimit reads the quota from the response headers. Reset is either the epoch seconds or the seconds until the reset
+func getRateLimit(header http.Header, now time.Time) *RateLimit {
+	for _, prefix := range []string{"X-RateLimit-", "RateLimit-"} {
+		remaining, err := strconv.Atoi(header.Get(prefix + "Remaining"))
+		if err != nil {
+			continue
+		}
+		out := &RateLimit{Remaining: remaining}
+		out.Limit, _ = strconv.Atoi(header.Get(prefix + "Limit"))
+		if reset, err := strconv.ParseInt(header.Get(prefix+"Reset"), 10, 64); err == nil {
+			if reset > 1e9 {
+				out.Reset = time.Unix(reset, 0).UTC()
+			} else {
+				out.Reset = now.Add(time.Duration(reset) * time.Second).UTC()
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// rateLimits are the last known quota of the hosts, shared by the copies of the client
+type rateLimits struct {
+	mu    sync.Mutex
+	hosts map[string]RateLimit
+}
+
+func (r *rateLimits) record(host string, limit *RateLimit) {
+	if r == nil || limit == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.hosts == nil {
+		r.hosts = map[string]RateLimit{}
+	}
+	r.hosts[host] = *limit
+}
+
+func (r *rateLimits) get(host string) (RateLimit, bool) {
+	if r == nil {
+		return RateLimit{}, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	limit, ok := r.hosts[host]
+	return limit, ok
+}
+
+// waitForRateLimit holds the request until the exhausted quota of the host resets
+func (r *rateLimits) waitForRateLimit(ctx context.Context, host string) error {
+	limit, ok := r.get(host)
+	if !ok || limit.Remaining > 0 || limit.Reset.IsZero() {
+		return nil
+	}
+	wait := time.Until(limit.Reset)
+	if wait <= 0 {
+		return nil
+	}
+	if wait > maxRateLimitWait {
+		return DownstreamError(fmt.Errorf("rate limit of %s is exhausted. quota resets at %s", host, limit.Reset.Format(time.RFC3339)), http.StatusTooManyRequests)
+	}
+	select {
+	case <-ctx.Done():
+		return ContextError(ctx)
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+type responseHeaderContextKey struct{}
+
+// withResponseHeader returns the context which records the headers of the response
+func withResponseHeader(ctx context.Context) (context.Context, *http.Header) {
+	header := &http.Header{}
+	return context.WithValue(ctx, responseHeaderContextKey{}, header), header
+}
+
+func recordResponseHeader(ctx context.Context, res *http.Response) {
+	if res == nil {
+		return
+	}
+	if header, ok := ctx.Value(responseHeaderContextKey{}).(*http.Header); ok {
+		*header = res.Header.Clone()
+	}
+}
@@ -0,0 +1,80 @@
+package infinity_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestLinkPaginationWithRateLimit(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Unix()
+	newServer := func(remaining func(page int) int) *httptest.Server {
+		var server *httptest.Server
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "100", r.URL.Query().Get("per_page"))
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			if page == 0 {
+				page = 1
+			}
+			if page < 3 {
+				w.Header().Set("Link", fmt.Sprintf(`<%s/repos?per_page=100&page=%d>; rel="next", <%s/repos?per_page=100&page=3>; rel="last"`, server.URL, page+1, server.URL))
+			}
+			w.Header().Set("X-RateLimit-Limit", "100")
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining(page)))
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `[{ "name": "repo-%d" }]`, page)
+		}))
+		return server
+	}
+	query := models.ApplyDefaultsToQuery(context.Background(), models.Query{RefID: "A", Source: "url", SourcePreset: models.SourcePresetGitHub, PageMaxPages: 5})
+	require.Equal(t, models.PaginationModeLink, query.PageMode)
+	t.Run("should follow the next links", func(t *testing.T) {
+		server := newServer(func(page int) int { return 100 - page })
+		defer server.Close()
+		client, err := infinity.NewClient(context.Background(), models.InfinitySettings{})
+		require.Nil(t, err)
+		q := query
+		q.URL = server.URL + "/repos"
+		frame, err := infinity.GetFrameForURLSources(context.Background(), q, *client, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, 3, frame.Rows())
+		rateLimit := frame.Meta.Custom.(*infinity.CustomMeta).RateLimit
+		require.Equal(t, &infinity.RateLimit{Limit: 100, Remaining: 97, Reset: time.Unix(reset, 0).UTC()}, rateLimit)
+	})
+	t.Run("should stop when the rate limit is almost exhausted", func(t *testing.T) {
+		server := newServer(func(page int) int { return 6 - page })
+		defer server.Close()
+		client, err := infinity.NewClient(context.Background(), models.InfinitySettings{})
+		require.Nil(t, err)
+		q := query
+		q.URL = server.URL + "/repos"
+		frame, err := infinity.GetFrameForURLSources(context.Background(), q, *client, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, 1, frame.Rows())
+		require.Len(t, frame.Meta.Notices, 1)
+		require.Contains(t, frame.Meta.Notices[0].Text, "rate limit is almost exhausted")
+	})
+	t.Run("should fail fast when the rate limit is exhausted", func(t *testing.T) {
+		server := newServer(func(page int) int { return 0 })
+		defer server.Close()
+		client, err := infinity.NewClient(context.Background(), models.InfinitySettings{})
+		require.Nil(t, err)
+		q := query
+		q.URL = server.URL + "/repos"
+		q.PageMode = models.PaginationModeNone
+		q.URLOptions.Params = []models.URLOptionKeyValuePair{{Key: "per_page", Value: "100"}}
+		_, err = infinity.GetFrameForURLSources(context.Background(), q, *client, map[string]string{})
+		require.Nil(t, err)
+		_, err = infinity.GetFrameForURLSources(context.Background(), q, *client, map[string]string{})
+		require.ErrorContains(t, err, "rate limit of "+server.Listener.Addr().String()+" is exhausted")
+	})
+}
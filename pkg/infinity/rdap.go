@@ -0,0 +1,217 @@
+package infinity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+const (
+	defaultRDAPServer = "https://rdap.org"
+	rdapCacheTable    = "rdap"
+	// rdapCacheTTL is a day, since the registration data rarely changes and the registries rate limit the lookups aggressively
+	rdapCacheTTL   = 24 * time.Hour
+	maxRDAPDomains = 50
+)
+
+// rdapDomain is the registration data of the domain, cached in the badger store
+type rdapDomain struct {
+	Registrar   string `json:"registrar,omitempty"`
+	IANAID      string `json:"iana_id,omitempty"`
+	Registered  string `json:"registered,omitempty"`
+	Expires     string `json:"expires,omitempty"`
+	LastChanged string `json:"last_changed,omitempty"`
+	Status      string `json:"status,omitempty"`
+	Nameservers string `json:"nameservers,omitempty"`
+}
+
+// rdapResponse is the subset of the rdap domain response (RFC 9083) used by the rdap source
+type rdapResponse struct {
+	Status []string `json:"status"`
+	Events []struct {
+		Action string `json:"eventAction"`
+		Date   string `json:"eventDate"`
+	} `json:"events"`
+	Entities []struct {
+		Roles     []string `json:"roles"`
+		VCard     []any    `json:"vcardArray"`
+		PublicIDs []struct {
+			Type       string `json:"type"`
+			Identifier string `json:"identifier"`
+		} `json:"publicIds"`
+	} `json:"entities"`
+	Nameservers []struct {
+		Name string `json:"ldhName"`
+	} `json:"nameservers"`
+}
+
+// GetFrameForRDAP looks up the registration data of the domains and returns a row per domain with the registrar and the expiry columns.
+// Lookups are cached for a day. Failed lookups are returned as the rows with the error column and are not cached
+func GetFrameForRDAP(ctx context.Context, query models.Query, infClient Client) (*data.Frame, error) {
+	ctx, span := tracing.DefaultTracer().Start(ctx, "GetFrameForRDAP")
+	defer span.End()
+	if query.RDAPOptions == nil || len(query.RDAPOptions.Domains) == 0 {
+		return nil, UserError(errors.New("at least one domain is required for the rdap query"))
+	}
+	options := *query.RDAPOptions
+	if len(options.Domains) > maxRDAPDomains {
+		return nil, UserError(fmt.Errorf("too many rdap domains. domains should be up to %d", maxRDAPDomains))
+	}
+	server := strings.TrimSuffix(strings.TrimSpace(options.Server), "/")
+	if server == "" {
+		server = defaultRDAPServer
+	}
+	if !strings.HasPrefix(server, "https://") && !strings.HasPrefix(server, "http://") {
+		return nil, UserError(fmt.Errorf("invalid rdap server %s", server))
+	}
+	if !CanAllowURL(server, infClient.Settings.AllowedHosts) {
+		return nil, UserError(fmt.Errorf("rdap server %s is not in the allowed hosts", server))
+	}
+	timeout := time.Duration(infClient.Settings.TimeoutInSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	// the datasource http client is not used, so the credentials of the datasource are never sent to the rdap services
+	httpClient := &http.Client{Timeout: timeout, CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("too many redirects")
+		}
+		if !CanAllowURL(req.URL.String(), infClient.Settings.AllowedHosts) {
+			return fmt.Errorf("rdap redirect %s is not in the allowed hosts", req.URL.String())
+		}
+		return nil
+	}}
+	cache := GetBadgerDB().Table(rdapCacheTable)
+	now := time.Now()
+	rows := make([]any, 0, len(options.Domains))
+	for _, domain := range options.Domains {
+		domain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+		row := map[string]any{"domain": domain}
+		key := server + "/domain/" + url.PathEscape(domain)
+		var registration rdapDomain
+		cached, err := cache.GetStr(key)
+		if err != nil || json.Unmarshal([]byte(cached), &registration) != nil {
+			if registration, err = lookupRDAP(ctx, httpClient, key); err != nil {
+				if ctx.Err() != nil {
+					return nil, ContextError(ctx)
+				}
+				row["error"] = err.Error()
+				rows = append(rows, row)
+				continue
+			}
+			if value, err := json.Marshal(registration); err == nil {
+				if err := cache.WithTTL(rdapCacheTTL).SetStr(key, string(value)); err != nil {
+					backend.Logger.Error("error caching rdap lookup", "domain", domain, "error", err.Error())
+				}
+			}
+		}
+		for k, v := range map[string]string{"registrar": registration.Registrar, "registrar_iana_id": registration.IANAID, "registered": registration.Registered, "expires": registration.Expires, "last_changed": registration.LastChanged, "status": registration.Status, "nameservers": registration.Nameservers} {
+			if v != "" {
+				row[k] = v
+			}
+		}
+		if expires, err := time.Parse(time.RFC3339, registration.Expires); err == nil {
+			row["days_remaining"] = math.Floor(expires.Sub(now).Hours() / 24)
+		}
+		rows = append(rows, row)
+	}
+	rowsQuery := query
+	rowsQuery.RootSelector = ""
+	frame, err := GetJSONBackendResponse(ctx, rows, rowsQuery)
+	if err != nil {
+		return frame, err
+	}
+	return PostProcessFrame(ctx, frame, rowsQuery)
+}
+
+func lookupRDAP(ctx context.Context, httpClient *http.Client, lookupURL string) (rdapDomain, error) {
+	registration := rdapDomain{}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, lookupURL, nil)
+	if err != nil {
+		return registration, err
+	}
+	req.Header.Set("Accept", "application/rdap+json")
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return registration, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return registration, errors.New("domain not found")
+	}
+	if res.StatusCode != http.StatusOK {
+		return registration, fmt.Errorf("rdap lookup failed with status %s", res.Status)
+	}
+	var response rdapResponse
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&response); err != nil {
+		return registration, fmt.Errorf("invalid rdap response. %w", err)
+	}
+	for _, event := range response.Events {
+		switch event.Action {
+		case "registration":
+			registration.Registered = event.Date
+		case "expiration":
+			registration.Expires = event.Date
+		case "last changed":
+			registration.LastChanged = event.Date
+		}
+	}
+	for _, entity := range response.Entities {
+		if !hasRDAPRole(entity.Roles, "registrar") {
+			continue
+		}
+		registration.Registrar = getRDAPVCardName(entity.VCard)
+		for _, id := range entity.PublicIDs {
+			if id.Type == "IANA Registrar ID" {
+				registration.IANAID = id.Identifier
+			}
+		}
+	}
+	nameservers := make([]string, 0, len(response.Nameservers))
+	for _, ns := range response.Nameservers {
+		nameservers = append(nameservers, strings.ToLower(ns.Name))
+	}
+	registration.Status = strings.Join(response.Status, ", ")
+	registration.Nameservers = strings.Join(nameservers, ", ")
+	return registration, nil
+}
+
+func hasRDAPRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// getRDAPVCardName returns the fn property of the jCard (RFC 7095). ex: ["vcard", [["fn", {}, "text", "Example Registrar"]]]
+func getRDAPVCardName(vcard []any) string {
+	if len(vcard) < 2 {
+		return ""
+	}
+	properties, _ := vcard[1].([]any)
+	for _, p := range properties {
+		property, _ := p.([]any)
+		if len(property) < 4 {
+			continue
+		}
+		if name, _ := property[0].(string); name == "fn" {
+			value, _ := property[3].(string)
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package infinity_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestGetFrameForRDAP(t *testing.T) {
+	var lookups atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups.Add(1)
+		if r.URL.Path != "/domain/example.com" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rdap+json")
+		fmt.Fprint(w, `{
+			"ldhName": "EXAMPLE.COM",
+			"status": ["client delete prohibited", "client transfer prohibited"],
+			"events": [
+				{ "eventAction": "registration", "eventDate": "1995-08-14T04:00:00Z" },
+				{ "eventAction": "expiration", "eventDate": "2099-08-13T04:00:00Z" },
+				{ "eventAction": "last changed", "eventDate": "2024-08-14T07:01:34Z" }
+			],
+			"entities": [{
+				"roles": ["registrar"],
+				"publicIds": [{ "type": "IANA Registrar ID", "identifier": "376" }],
+				"vcardArray": ["vcard", [["version", {}, "text", "4.0"], ["fn", {}, "text", "RESERVED-Internet Assigned Numbers Authority"]]]
+			}],
+			"nameservers": [{ "ldhName": "A.IANA-SERVERS.NET" }, { "ldhName": "B.IANA-SERVERS.NET" }]
+		}`)
+	}))
+	defer server.Close()
+	client := infinity.Client{Settings: models.InfinitySettings{TimeoutInSeconds: 2}}
+	value := func(frame *data.Frame, name string, row int) any {
+		field, _ := frame.FieldByName(name)
+		require.NotNil(t, field, name)
+		v, _ := field.ConcreteAt(row)
+		return v
+	}
+	query := models.Query{RefID: "A", Source: "rdap", RDAPOptions: &models.RDAPOptions{Domains: []string{"Example.com.", "missing.test"}, Server: server.URL + "/"}}
+	frame, err := infinity.GetFrameForRDAP(context.Background(), query, client)
+	require.Nil(t, err)
+	require.Equal(t, 2, frame.Rows())
+	require.Equal(t, "example.com", value(frame, "domain", 0))
+	require.Equal(t, "RESERVED-Internet Assigned Numbers Authority", value(frame, "registrar", 0))
+	require.Equal(t, "376", value(frame, "registrar_iana_id", 0))
+	require.Equal(t, "2099-08-13T04:00:00Z", value(frame, "expires", 0))
+	require.Greater(t, value(frame, "days_remaining", 0), 365.0)
+	require.Equal(t, "a.iana-servers.net, b.iana-servers.net", value(frame, "nameservers", 0))
+	require.Equal(t, "domain not found", value(frame, "error", 1))
+	require.Equal(t, int32(2), lookups.Load())
+	t.Run("lookups are cached", func(t *testing.T) {
+		frame, err := infinity.GetFrameForRDAP(context.Background(), query, client)
+		require.Nil(t, err)
+		require.Equal(t, "2099-08-13T04:00:00Z", value(frame, "expires", 0))
+		// failed lookups are not cached
+		require.Equal(t, int32(3), lookups.Load())
+	})
+	t.Run("server outside the allowed hosts", func(t *testing.T) {
+		client := infinity.Client{Settings: models.InfinitySettings{AllowedHosts: []string{"https://rdap.org"}}}
+		query := models.Query{RefID: "A", Source: "rdap", RDAPOptions: &models.RDAPOptions{Domains: []string{"example.com"}, Server: strings.TrimSuffix(server.URL, "/")}}
+		_, err := infinity.GetFrameForRDAP(context.Background(), query, client)
+		require.ErrorContains(t, err, "not in the allowed hosts")
+	})
+}
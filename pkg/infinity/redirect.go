@@ -0,0 +1,122 @@
+package infinity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+const (
+	defaultMaxRedirects = 10
+	// crossOriginRedirectHeader marks the redirected requests which must not carry the datasource credentials.
+	// The header is removed by the redirectTransport before sending the request
+	crossOriginRedirectHeader = "X-Infinity-Cross-Origin-Redirect"
+)
+
+// redirectSafeHeaders are the headers kept when the redirect changes the host. Every other header may carry credentials
+var redirectSafeHeaders = map[string]bool{
+	"Accept":          true,
+	"Accept-Encoding": true,
+	"Accept-Language": true,
+	"Content-Type":    true,
+	"User-Agent":      true,
+	"Referer":         true,
+}
+
+var ErrRedirectToPrivateAddress = errors.New("redirect to private address is not allowed")
+
+// ApplyRedirectPolicy caps the number of redirects, strips the credentials when the redirect changes the host and
+// optionally blocks the redirects to private addresses. Cross-origin redirects are sent using the baseTransport,
+// which is the transport without the authentication round trippers
+func ApplyRedirectPolicy(ctx context.Context, httpClient *http.Client, baseTransport http.RoundTripper, settings models.InfinitySettings) *http.Client {
+	maxRedirects := settings.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = defaultMaxRedirects
+	}
+	transport := httpClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if baseTransport == nil {
+		baseTransport = http.DefaultTransport
+	}
+	httpClient.Transport = &redirectTransport{authenticated: transport, unauthenticated: baseTransport}
+	httpClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		if settings.BlockPrivateRedirects {
+			if err := checkPublicHost(req.Context(), req.URL.Hostname()); err != nil {
+				return err
+			}
+		}
+		if !strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+			for key := range req.Header {
+				if !redirectSafeHeaders[http.CanonicalHeaderKey(key)] {
+					req.Header.Del(key)
+				}
+			}
+			req.Header.Set(crossOriginRedirectHeader, "true")
+		}
+		return nil
+	}
+	return httpClient
+}
+
+type redirectTransport struct {
+	authenticated   http.RoundTripper
+	unauthenticated http.RoundTripper
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get(crossOriginRedirectHeader) == "" {
+		return t.authenticated.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Del(crossOriginRedirectHeader)
+	return t.unauthenticated.RoundTrip(req)
+}
+
+// checkPublicHost returns error when the host is or resolves to a loopback, private, link local or unspecified address
+func checkPublicHost(ctx context.Context, host string) error {
+	ips := []net.IP{}
+	if ip := net.ParseIP(host); ip != nil {
+		ips = append(ips, ip)
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return fmt.Errorf("unable to resolve the redirect host %s. %w", host, err)
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+	for _, ip := range ips {
+		if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+			return fmt.Errorf("%w. %s resolves to %s", ErrRedirectToPrivateAddress, host, ip.String())
+		}
+	}
+	return nil
+}
+
+type finalURLContextKey struct{}
+
+// withFinalURL returns the context which records the URL of the response when the request was redirected
+func withFinalURL(ctx context.Context) (context.Context, *string) {
+	finalURL := new(string)
+	return context.WithValue(ctx, finalURLContextKey{}, finalURL), finalURL
+}
+
+func recordFinalURL(ctx context.Context, req *http.Request, res *http.Response) {
+	if res == nil || res.Request == nil || res.Request.URL.String() == req.URL.String() {
+		return
+	}
+	if finalURL, ok := ctx.Value(finalURLContextKey{}).(*string); ok {
+		*finalURL = res.Request.URL.String()
+	}
+}
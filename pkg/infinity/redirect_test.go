@@ -0,0 +1,92 @@
+package infinity_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestRedirectPolicy(t *testing.T) {
+	t.Run("should strip the credentials when the redirect changes the host and expose the final url", func(t *testing.T) {
+		var gotHeaders http.Header
+		target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotHeaders = r.Header.Clone()
+			_, _ = w.Write([]byte(`[{"name":"foo"}]`))
+		}))
+		defer target.Close()
+		var originHeaders http.Header
+		origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			originHeaders = r.Header.Clone()
+			http.Redirect(w, r, target.URL+"/final", http.StatusFound)
+		}))
+		defer origin.Close()
+		client, err := infinity.NewClient(context.Background(), models.InfinitySettings{
+			BasicAuthEnabled: true,
+			UserName:         "user",
+			Password:         "secret",
+			CustomHeaders:    map[string]string{"X-Api-Key": "key"},
+		})
+		require.Nil(t, err)
+		query := models.ApplyDefaultsToQuery(context.Background(), models.Query{RefID: "A", Type: models.QueryTypeJSON, Parser: models.InfinityParserBackend, Source: "url", URL: origin.URL})
+		frame, err := infinity.GetFrameForURLSources(context.Background(), query, *client, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, 1, frame.Rows())
+		require.NotEmpty(t, originHeaders.Get("Authorization"))
+		require.Equal(t, "key", originHeaders.Get("X-Api-Key"))
+		require.Empty(t, gotHeaders.Get("Authorization"))
+		require.Empty(t, gotHeaders.Get("X-Api-Key"))
+		require.Empty(t, gotHeaders.Get("X-Infinity-Cross-Origin-Redirect"))
+		customMeta, ok := frame.Meta.Custom.(*infinity.CustomMeta)
+		require.True(t, ok)
+		require.Equal(t, target.URL+"/final", customMeta.FinalURL)
+	})
+	t.Run("should keep the credentials when the redirect is on the same host", func(t *testing.T) {
+		var gotHeaders http.Header
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/final" {
+				http.Redirect(w, r, "/final", http.StatusFound)
+				return
+			}
+			gotHeaders = r.Header.Clone()
+			_, _ = w.Write([]byte(`[{"name":"foo"}]`))
+		}))
+		defer server.Close()
+		client, err := infinity.NewClient(context.Background(), models.InfinitySettings{CustomHeaders: map[string]string{"X-Api-Key": "key"}})
+		require.Nil(t, err)
+		_, _, _, err = client.GetResults(context.Background(), models.Query{Type: models.QueryTypeJSON, Source: "url", URL: server.URL}, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, "key", gotHeaders.Get("X-Api-Key"))
+	})
+	t.Run("should stop after max redirects", func(t *testing.T) {
+		count := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			count++
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		}))
+		defer server.Close()
+		client, err := infinity.NewClient(context.Background(), models.InfinitySettings{MaxRedirects: 3})
+		require.Nil(t, err)
+		_, _, _, err = client.GetResults(context.Background(), models.Query{Type: models.QueryTypeJSON, Source: "url", URL: server.URL}, map[string]string{})
+		require.ErrorContains(t, err, "stopped after 3 redirects")
+		require.Equal(t, 3, count)
+	})
+	t.Run("should block the redirects to private addresses", func(t *testing.T) {
+		target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`[]`))
+		}))
+		defer target.Close()
+		origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, target.URL, http.StatusFound)
+		}))
+		defer origin.Close()
+		client, err := infinity.NewClient(context.Background(), models.InfinitySettings{BlockPrivateRedirects: true})
+		require.Nil(t, err)
+		_, _, _, err = client.GetResults(context.Background(), models.Query{Type: models.QueryTypeJSON, Source: "url", URL: origin.URL}, map[string]string{})
+		require.ErrorIs(t, err, infinity.ErrRedirectToPrivateAddress)
+	})
+}
@@ -0,0 +1,229 @@
+package infinity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+// redisMaxScanKeys is the number of keys returned by SCAN. results are truncated beyond it
+const redisMaxScanKeys = 10000
+
+// redisReadOnlyCommands are the commands allowed in the redis source
+var redisReadOnlyCommands = map[string]bool{
+	"GET": true, "MGET": true, "STRLEN": true, "EXISTS": true, "TYPE": true, "TTL": true, "PTTL": true,
+	"HGET": true, "HMGET": true, "HGETALL": true, "HKEYS": true, "HVALS": true, "HLEN": true,
+	"LRANGE": true, "LLEN": true, "LINDEX": true,
+	"SMEMBERS": true, "SCARD": true, "SISMEMBER": true,
+	"ZRANGE": true, "ZREVRANGE": true, "ZRANGEBYSCORE": true, "ZSCORE": true, "ZCARD": true,
+	"SCAN": true, "INFO": true, "DBSIZE": true,
+}
+
+// GetFrameForRedis runs the read only command against the redis instance of the datasource and converts the reply into the rows.
+// JSON documents stored in the strings are returned as is, so the root selector and the columns apply to them
+func GetFrameForRedis(ctx context.Context, query models.Query, infClient Client) (*data.Frame, error) {
+	ctx, span := tracing.DefaultTracer().Start(ctx, "GetFrameForRedis")
+	defer span.End()
+	if query.RedisOptions == nil || strings.TrimSpace(query.RedisOptions.Command) == "" {
+		return nil, UserError(errors.New("invalid or empty redis command"))
+	}
+	command := strings.ToUpper(strings.TrimSpace(query.RedisOptions.Command))
+	args := query.RedisOptions.Args
+	if !redisReadOnlyCommands[command] {
+		return nil, UserError(fmt.Errorf("redis command %s is not allowed. only the read only commands are supported", command))
+	}
+	if strings.TrimSpace(infClient.Settings.RedisURL) == "" {
+		return nil, UserError(errors.New("redis instance is not configured in the datasource settings"))
+	}
+	conn, err := NewRedisCacheBackend(infClient.Settings.RedisURL, infClient.Settings.RedisPassword, "")
+	if err != nil {
+		return nil, UserError(err)
+	}
+	defer conn.Close()
+	var response any
+	document, truncated := false, false
+	if command == "SCAN" {
+		response, truncated, err = scanRedisKeys(ctx, conn, args)
+	} else {
+		var reply any
+		if reply, err = conn.do(ctx, append([]string{command}, args...)...); err == nil {
+			response, document = getRedisResponse(command, args, reply)
+		}
+	}
+	if err != nil {
+		var redisErr redisError
+		if errors.As(err, &redisErr) {
+			return nil, UserError(err)
+		}
+		if ctx.Err() != nil {
+			return nil, ContextError(ctx)
+		}
+		return nil, DownstreamError(err, 0)
+	}
+	rowsQuery := query
+	if !document {
+		rowsQuery.RootSelector = ""
+	}
+	frame, err := GetJSONBackendResponse(ctx, response, rowsQuery)
+	if err != nil {
+		return frame, err
+	}
+	frame, err = PostProcessFrame(ctx, frame, rowsQuery)
+	if frame != nil && truncated {
+		frame.AppendNotices(data.Notice{Severity: data.NoticeSeverityWarning, Text: fmt.Sprintf("Results are truncated to the first %d keys", redisMaxScanKeys)})
+	}
+	return frame, err
+}
+
+// scanRedisKeys iterates the SCAN cursor and returns the distinct keys as the rows
+func scanRedisKeys(ctx context.Context, conn *RedisCacheBackend, args []string) ([]any, bool, error) {
+	rows := []any{}
+	seen := map[string]bool{}
+	cursor := "0"
+	for {
+		reply, err := conn.do(ctx, append([]string{"SCAN", cursor}, args...)...)
+		if err != nil {
+			return nil, false, err
+		}
+		items, ok := reply.([]any)
+		if !ok || len(items) != 2 {
+			return nil, false, fmt.Errorf("unexpected redis reply %v", reply)
+		}
+		next, _ := items[0].([]byte)
+		keys, _ := items[1].([]any)
+		for _, k := range keys {
+			key, _ := k.([]byte)
+			if seen[string(key)] {
+				continue
+			}
+			if len(rows) >= redisMaxScanKeys {
+				return rows, true, nil
+			}
+			seen[string(key)] = true
+			rows = append(rows, map[string]any{"key": string(key)})
+		}
+		cursor = string(next)
+		if cursor == "0" || cursor == "" {
+			return rows, false, nil
+		}
+	}
+}
+
+// getRedisResponse converts the reply into the rows. Replies of the single value commands holding the JSON objects or arrays are returned as the document
+func getRedisResponse(command string, args []string, reply any) (any, bool) {
+	switch value := reply.(type) {
+	case nil:
+		return []any{}, false
+	case []byte:
+		if command == "INFO" {
+			return getRedisInfoRows(string(value)), false
+		}
+		var doc any
+		if err := json.Unmarshal(value, &doc); err == nil {
+			switch doc.(type) {
+			case map[string]any, []any:
+				return doc, true
+			}
+		}
+		return getRedisNumbers([]any{map[string]any{"value": getRedisValue(value)}}), false
+	case []any:
+		rows := make([]any, 0, len(value))
+		switch {
+		case command == "HGETALL":
+			for i := 0; i+1 < len(value); i += 2 {
+				rows = append(rows, map[string]any{"field": getRedisValue(value[i]), "value": getRedisValue(value[i+1])})
+			}
+		case strings.HasPrefix(command, "Z") && hasRedisArg(args, "WITHSCORES"):
+			for i := 0; i+1 < len(value); i += 2 {
+				rows = append(rows, map[string]any{"member": getRedisValue(value[i]), "score": getRedisValue(value[i+1])})
+			}
+		case command == "MGET" || command == "HMGET":
+			// MGET args are the keys, HMGET args are the key followed by the fields
+			names, name := args, "key"
+			if command == "HMGET" && len(args) > 0 {
+				names, name = args[1:], "field"
+			}
+			for i, v := range value {
+				row := map[string]any{"value": getRedisValue(v)}
+				if i < len(names) {
+					row[name] = names[i]
+				}
+				rows = append(rows, row)
+			}
+		default:
+			for _, v := range value {
+				rows = append(rows, map[string]any{"value": getRedisValue(v)})
+			}
+		}
+		return getRedisNumbers(rows), false
+	case int64:
+		return []any{map[string]any{"value": float64(value)}}, false
+	default:
+		return []any{map[string]any{"value": value}}, false
+	}
+}
+
+func getRedisValue(v any) any {
+	if value, ok := v.([]byte); ok {
+		return string(value)
+	}
+	return v
+}
+
+// getRedisNumbers converts the value and score columns into numbers when every value of the column is numeric, since the counters are stored as strings in redis
+func getRedisNumbers(rows []any) []any {
+	for _, column := range []string{"value", "score"} {
+		numbers := map[int]float64{}
+		for i, r := range rows {
+			s, ok := r.(map[string]any)[column].(string)
+			if !ok {
+				continue
+			}
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				numbers = nil
+				break
+			}
+			numbers[i] = f
+		}
+		for i, f := range numbers {
+			rows[i].(map[string]any)[column] = f
+		}
+	}
+	return rows
+}
+
+// getRedisInfoRows returns the section, key and the value of the INFO reply lines. ex: # Memory, used_memory:1024
+func getRedisInfoRows(info string) []any {
+	rows := []any{}
+	section := ""
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") {
+			section = strings.TrimSpace(strings.TrimPrefix(line, "#"))
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		rows = append(rows, map[string]any{"section": section, "key": key, "value": value})
+	}
+	return rows
+}
+
+func hasRedisArg(args []string, arg string) bool {
+	for _, a := range args {
+		if strings.EqualFold(a, arg) {
+			return true
+		}
+	}
+	return false
+}
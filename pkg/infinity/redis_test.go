@@ -0,0 +1,136 @@
+package infinity_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func writeRedisArray(w io.Writer, values ...string) {
+	fmt.Fprintf(w, "*%d\r\n", len(values))
+	for _, v := range values {
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+	}
+}
+
+// handleRedisSource answers the read commands of the redis source from the string values
+func handleRedisSource(values map[string]string) func(*fakeCacheServer, *bufio.Reader, io.Writer) error {
+	return func(_ *fakeCacheServer, r *bufio.Reader, w io.Writer) error {
+		args, err := readRedisCommand(r)
+		if err != nil {
+			return err
+		}
+		switch strings.ToUpper(args[0]) {
+		case "AUTH":
+			if args[1] != "secret" {
+				_, err = io.WriteString(w, "-WRONGPASS invalid password\r\n")
+				break
+			}
+			_, err = io.WriteString(w, "+OK\r\n")
+		case "GET":
+			v := values[args[1]]
+			_, err = fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+		case "MGET":
+			writeRedisArray(w, values[args[1]], values[args[2]])
+		case "HGETALL":
+			writeRedisArray(w, "orders", "10", "errors", "2")
+		case "ZRANGE":
+			writeRedisArray(w, "alice", "30", "bob", "12.5")
+		case "LLEN":
+			_, err = io.WriteString(w, ":3\r\n")
+		case "SCAN":
+			// two pages, with a duplicate key
+			if args[1] == "0" {
+				_, err = io.WriteString(w, "*2\r\n$2\r\n17\r\n")
+				writeRedisArray(w, "orders:1", "orders:2")
+				break
+			}
+			_, err = io.WriteString(w, "*2\r\n$1\r\n0\r\n")
+			writeRedisArray(w, "orders:2", "orders:3")
+		case "INFO":
+			v := "# Memory\r\nused_memory:1024\r\n\r\n# Clients\r\nconnected_clients:2\r\n"
+			_, err = fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+		default:
+			_, err = io.WriteString(w, "-ERR unknown command\r\n")
+		}
+		return err
+	}
+}
+
+func TestGetFrameForRedis(t *testing.T) {
+	server := newFakeCacheServer(t, handleRedisSource(map[string]string{
+		"visits:today": "42",
+		"visits:total": "1042",
+		"config":       `{ "services": [{ "name": "api", "replicas": 3 }, { "name": "web", "replicas": 2 }] }`,
+	}))
+	client := infinity.Client{Settings: models.InfinitySettings{RedisURL: server.listener.Addr().String(), RedisPassword: "secret"}}
+	run := func(t *testing.T, query models.Query) *data.Frame {
+		t.Helper()
+		query.RefID, query.Source = "A", "redis"
+		frame, err := infinity.GetFrameForRedis(context.Background(), query, client)
+		require.Nil(t, err)
+		return frame
+	}
+	value := func(frame *data.Frame, name string, row int) any {
+		field, _ := frame.FieldByName(name)
+		require.NotNil(t, field, name)
+		v, _ := field.ConcreteAt(row)
+		return v
+	}
+	t.Run("get counter", func(t *testing.T) {
+		frame := run(t, models.Query{RedisOptions: &models.RedisOptions{Command: "get", Args: []string{"visits:today"}}})
+		require.Equal(t, 42.0, value(frame, "value", 0))
+	})
+	t.Run("get json document with root selector", func(t *testing.T) {
+		frame := run(t, models.Query{RootSelector: "services", RedisOptions: &models.RedisOptions{Command: "GET", Args: []string{"config"}}})
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, "web", value(frame, "name", 1))
+	})
+	t.Run("mget", func(t *testing.T) {
+		frame := run(t, models.Query{RedisOptions: &models.RedisOptions{Command: "MGET", Args: []string{"visits:today", "visits:total"}}})
+		require.Equal(t, "visits:total", value(frame, "key", 1))
+		require.Equal(t, 1042.0, value(frame, "value", 1))
+	})
+	t.Run("hgetall", func(t *testing.T) {
+		frame := run(t, models.Query{RedisOptions: &models.RedisOptions{Command: "HGETALL", Args: []string{"stats"}}})
+		require.Equal(t, "errors", value(frame, "field", 1))
+		require.Equal(t, 2.0, value(frame, "value", 1))
+	})
+	t.Run("zrange with scores", func(t *testing.T) {
+		frame := run(t, models.Query{RedisOptions: &models.RedisOptions{Command: "ZRANGE", Args: []string{"leaderboard", "0", "-1", "withscores"}}})
+		require.Equal(t, "bob", value(frame, "member", 1))
+		require.Equal(t, 12.5, value(frame, "score", 1))
+	})
+	t.Run("integer reply", func(t *testing.T) {
+		frame := run(t, models.Query{RedisOptions: &models.RedisOptions{Command: "LLEN", Args: []string{"queue"}}})
+		require.Equal(t, 3.0, value(frame, "value", 0))
+	})
+	t.Run("scan follows the cursor", func(t *testing.T) {
+		frame := run(t, models.Query{RedisOptions: &models.RedisOptions{Command: "SCAN", Args: []string{"MATCH", "orders:*"}}})
+		require.Equal(t, 3, frame.Rows())
+		require.Equal(t, "orders:3", value(frame, "key", 2))
+	})
+	t.Run("info", func(t *testing.T) {
+		frame := run(t, models.Query{RedisOptions: &models.RedisOptions{Command: "INFO"}})
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, "Clients", value(frame, "section", 1))
+		require.Equal(t, "connected_clients", value(frame, "key", 1))
+	})
+	t.Run("write commands are rejected", func(t *testing.T) {
+		_, err := infinity.GetFrameForRedis(context.Background(), models.Query{RedisOptions: &models.RedisOptions{Command: "FLUSHALL"}}, client)
+		require.ErrorContains(t, err, "redis command FLUSHALL is not allowed")
+	})
+	t.Run("redis errors", func(t *testing.T) {
+		client := infinity.Client{Settings: models.InfinitySettings{RedisURL: server.listener.Addr().String()}}
+		_, err := infinity.GetFrameForRedis(context.Background(), models.Query{RedisOptions: &models.RedisOptions{Command: "DBSIZE"}}, client)
+		require.ErrorContains(t, err, "redis: ERR unknown command")
+	})
+}
@@ -5,7 +5,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
@@ -18,6 +21,27 @@ import (
 func GetFrameForURLSources(ctx context.Context, query models.Query, infClient Client, requestHeaders map[string]string) (*data.Frame, error) {
 	ctx, span := tracing.DefaultTracer().Start(ctx, "GetFrameForURLSources")
 	defer span.End()
+	if query.CookieJar != nil {
+		return GetFrameWithCookieJar(ctx, query, infClient, requestHeaders)
+	}
+	if len(query.Chain) > 0 {
+		return GetFrameForChain(ctx, query, infClient, requestHeaders)
+	}
+	if len(query.Assertions) > 0 {
+		return GetAssertionsFrame(ctx, query, infClient, requestHeaders)
+	}
+	if query.CacheMode == models.CacheModeFrame {
+		return getCachedFrameForURLSources(ctx, query, infClient, requestHeaders)
+	}
+	if query.ResultsAPI != "" {
+		return GetResultsAPIFrame(ctx, query, infClient, requestHeaders)
+	}
+	if query.ElasticsearchOptions != nil {
+		return GetElasticsearchFrame(ctx, query, infClient, requestHeaders)
+	}
+	if query.Parser == models.InfinityParserBackend && query.IncrementalWatermarkField != "" {
+		return GetIncrementalResults(ctx, query, infClient, requestHeaders)
+	}
 	if query.Type == models.QueryTypeJSON && query.Parser == models.InfinityParserBackend && query.PageMode != models.PaginationModeNone && query.PageMode != "" {
 		return GetPaginatedResults(ctx, query, infClient, requestHeaders)
 	}
@@ -25,6 +49,8 @@ func GetFrameForURLSources(ctx context.Context, query models.Query, infClient Cl
 	return frame, err
 }
 
+const paginationLeaseTTL = 5 * time.Minute
+
 func GetPaginatedResults(ctx context.Context, query models.Query, infClient Client, requestHeaders map[string]string) (*data.Frame, error) {
 	ctx, span := tracing.DefaultTracer().Start(ctx, "GetPaginatedResults")
 	defer span.End()
@@ -61,45 +87,172 @@ func GetPaginatedResults(ctx context.Context, query models.Query, infClient Clie
 			currentQuery = ApplyPaginationItemToQuery(currentQuery, query.PageParamListFieldType, query.PageParamListFieldName, strings.TrimSpace(listItem))
 			queries = append(queries, currentQuery)
 		}
-	case models.PaginationModeCursor:
+	case models.PaginationModeCursor, models.PaginationModeLink:
 		queries = append(queries, query)
 	default:
 		frame, _, err := GetFrameForURLSourcesWithPostProcessing(ctx, query, infClient, requestHeaders, true)
 		return frame, err
 	}
-	if query.PageMode != models.PaginationModeCursor {
+	if cacheKey, _, err := getBadgerKey(query.URLOptions.Headers); err == nil {
+		// only one replica crawls the cached query at a time. Others wait and read the pages from the cache
+		lease, err := infClient.AcquireLease(ctx, "crawl:"+cacheKey, paginationLeaseTTL)
+		if err != nil {
+			return nil, err
+		}
+		defer lease.Release(context.Background()) //nolint
+	}
+	bestEffort := query.PageErrorMode == models.PaginationErrorModeBestEffort
+	pages := 0
+	followsCursor := query.PageMode == models.PaginationModeCursor || query.PageMode == models.PaginationModeLink
+	var rateLimit *RateLimit
+	if !followsCursor {
 		for _, currentQuery := range queries {
+			if err := ContextError(ctx); err != nil {
+				// cancelled crawl returns no results, as the remaining pages are never fetched
+				return nil, err
+			}
+			pages++
 			frame, _, err := GetFrameForURLSourcesWithPostProcessing(ctx, currentQuery, infClient, requestHeaders, false)
+			if err != nil {
+				errs = errors.Join(errs, err)
+				if !bestEffort {
+					break
+				}
+				continue
+			}
 			frames = append(frames, frame)
-			errs = errors.Join(errs, err)
 		}
 	}
-	if query.PageMode == models.PaginationModeCursor {
+	rateLimited := false
+	if followsCursor {
 		i := 0
 		oCursor := ""
+		if query.PageMode == models.PaginationModeLink {
+			query = ApplyPaginationItemToQuery(query, query.PageParamSizeFieldType, query.PageParamSizeFieldName, fmt.Sprintf("%d", query.PageParamSizeFieldVal))
+		}
 		for {
 			currentQuery := query
 			if i > 0 && oCursor != "" {
-				currentQuery = ApplyPaginationItemToQuery(currentQuery, query.PageParamCursorFieldType, query.PageParamCursorFieldName, oCursor)
+				currentQuery = getNextPageQuery(currentQuery, oCursor)
 			}
 			if i > query.PageMaxPages || (i > 0 && oCursor == "") {
 				break
 			}
+			if err := ContextError(ctx); err != nil {
+				return nil, err
+			}
+			if i > 0 && rateLimit != nil && rateLimit.nearLimit(time.Now()) {
+				// remaining pages are left, so the quota is not exhausted by a single query
+				rateLimited = true
+				break
+			}
 			i++
+			pages++
 			frame, cursor, err := GetFrameForURLSourcesWithPostProcessing(ctx, currentQuery, infClient, requestHeaders, false)
+			if err != nil {
+				// next cursor is unknown when the page fails. so the crawl stops irrespective of the error mode
+				errs = errors.Join(errs, err)
+				break
+			}
+			if customMeta, ok := frame.Meta.Custom.(*CustomMeta); ok && customMeta.RateLimit != nil {
+				rateLimit = customMeta.RateLimit
+			}
 			oCursor = cursor
 			frames = append(frames, frame)
-			errs = errors.Join(errs, err)
 		}
 	}
-	if errs != nil {
+	if errs != nil && (!bestEffort || len(frames) == 0) {
 		return nil, errs
 	}
-	mergedFrame, err := transformations.Merge(frames, transformations.MergeFramesOptions{})
+	mergedFrame, err := transformations.Merge(alignFrameFields(frames), transformations.MergeFramesOptions{})
 	if err != nil {
 		return nil, err
 	}
-	return PostProcessFrame(ctx, mergedFrame, query)
+	frame, err := PostProcessFrame(ctx, mergedFrame, query)
+	if frame != nil && rateLimit != nil {
+		if frame.Meta == nil {
+			frame.Meta = &data.FrameMeta{}
+		}
+		if customMeta, ok := frame.Meta.Custom.(*CustomMeta); ok {
+			customMeta.RateLimit = rateLimit
+		} else {
+			frame.Meta.Custom = &CustomMeta{Query: query, RateLimit: rateLimit}
+		}
+	}
+	if frame != nil && rateLimited {
+		frame.AppendNotices(data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("Partial results. pagination stopped after %d pages as the rate limit is almost exhausted. %d requests remaining until %s", pages, rateLimit.Remaining, rateLimit.Reset.Format(time.RFC3339)),
+		})
+	}
+	if errs != nil && frame != nil {
+		backend.Logger.Warn("returning partial results of the paginated query", "error", errs.Error())
+		frame.AppendNotices(data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("Partial results. %d of %d pages failed. %s", pages-len(frames), pages, errs.Error()),
+		})
+	}
+	return frame, err
+}
+
+// alignFrameFields adds the fields missing in some of the pages as null values, so that the pages with the derived columns can be merged
+func alignFrameFields(frames []*data.Frame) []*data.Frame {
+	names := []string{}
+	types := map[string]data.FieldType{}
+	for _, frame := range frames {
+		for _, field := range frame.Fields {
+			if _, ok := types[field.Name]; !ok {
+				names = append(names, field.Name)
+				types[field.Name] = field.Type().NullableType()
+			}
+		}
+	}
+	for _, frame := range frames {
+		if len(frame.Fields) == len(names) {
+			continue
+		}
+		fields := make([]*data.Field, 0, len(names))
+		for _, name := range names {
+			field, idx := frame.FieldByName(name)
+			if idx == -1 {
+				field = data.NewFieldFromFieldType(types[name], frame.Rows())
+				field.Name = name
+			}
+			fields = append(fields, field)
+		}
+		frame.Fields = fields
+	}
+	return frames
+}
+
+// getNextPageQuery returns the query of the next page. Cursor is the next url of the link pagination, which already has the parameters of the query
+func getNextPageQuery(query models.Query, cursor string) models.Query {
+	if query.PageMode == models.PaginationModeLink {
+		query.URL = cursor
+		query.URLOptions.Params = nil
+		return query
+	}
+	return ApplyPaginationItemToQuery(query, query.PageParamCursorFieldType, query.PageParamCursorFieldName, cursor)
+}
+
+// getNextLink returns the url of the rel="next" link of the Link header. ex: <https://api.github.com/repos?page=2>; rel="next", <...>; rel="last"
+func getNextLink(header http.Header) string {
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range parts[1:] {
+				key, val, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if strings.EqualFold(key, "rel") && strings.EqualFold(strings.Trim(val, `"`), "next") {
+					return strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">")
+				}
+			}
+		}
+	}
+	return ""
 }
 
 func ApplyPaginationItemToQuery(currentQuery models.Query, fieldType models.PaginationParamType, fieldName string, fieldValue string) models.Query {
@@ -113,33 +266,49 @@ func ApplyPaginationItemToQuery(currentQuery models.Query, fieldType models.Pagi
 	case models.PaginationParamTypeBodyData:
 		currentQuery.URLOptions.BodyForm = append(currentQuery.URLOptions.BodyForm, field)
 	case models.PaginationParamTypeReplace:
-		currentQuery.URL = strings.ReplaceAll(currentQuery.URL, fieldName, field.Value)
-		currentQuery.URLOptions.Body = strings.ReplaceAll(currentQuery.URLOptions.Body, fieldName, field.Value)
-		currentQuery.URLOptions.BodyGraphQLQuery = strings.ReplaceAll(currentQuery.URLOptions.BodyGraphQLQuery, fieldName, fieldValue)
-		for headerIndex, header := range currentQuery.URLOptions.Headers {
-			currentQuery.URLOptions.Headers[headerIndex].Key = strings.ReplaceAll(header.Key, fieldName, field.Value)
-			currentQuery.URLOptions.Headers[headerIndex].Value = strings.ReplaceAll(header.Value, fieldName, field.Value)
-		}
-		for paramIndex, param := range currentQuery.URLOptions.Params {
-			currentQuery.URLOptions.Params[paramIndex].Key = strings.ReplaceAll(param.Key, fieldName, field.Value)
-			currentQuery.URLOptions.Params[paramIndex].Value = strings.ReplaceAll(param.Value, fieldName, field.Value)
-		}
-		for bodyFormIndex, bodyFormItem := range currentQuery.URLOptions.BodyForm {
-			currentQuery.URLOptions.BodyForm[bodyFormIndex].Key = strings.ReplaceAll(bodyFormItem.Key, fieldName, field.Value)
-			currentQuery.URLOptions.BodyForm[bodyFormIndex].Value = strings.ReplaceAll(bodyFormItem.Value, fieldName, field.Value)
-		}
+		currentQuery = ReplacePlaceholderInQuery(currentQuery, fieldName, fieldValue)
 	default:
 		currentQuery.URLOptions.Params = append(currentQuery.URLOptions.Params, field)
 	}
 	return currentQuery
 }
 
+// ReplacePlaceholderInQuery replaces the placeholder in url, body, headers, params and form fields of the query
+func ReplacePlaceholderInQuery(currentQuery models.Query, placeholder string, value string) models.Query {
+	// the copies of the query share the slices with the query. so the slices are cloned before the replacement
+	currentQuery.URLOptions.Headers = slices.Clone(currentQuery.URLOptions.Headers)
+	currentQuery.URLOptions.Params = slices.Clone(currentQuery.URLOptions.Params)
+	currentQuery.URLOptions.BodyForm = slices.Clone(currentQuery.URLOptions.BodyForm)
+	currentQuery.URL = strings.ReplaceAll(currentQuery.URL, placeholder, value)
+	currentQuery.URLOptions.Body = strings.ReplaceAll(currentQuery.URLOptions.Body, placeholder, value)
+	currentQuery.URLOptions.BodyGraphQLQuery = strings.ReplaceAll(currentQuery.URLOptions.BodyGraphQLQuery, placeholder, value)
+	for headerIndex, header := range currentQuery.URLOptions.Headers {
+		currentQuery.URLOptions.Headers[headerIndex].Key = strings.ReplaceAll(header.Key, placeholder, value)
+		currentQuery.URLOptions.Headers[headerIndex].Value = strings.ReplaceAll(header.Value, placeholder, value)
+	}
+	for paramIndex, param := range currentQuery.URLOptions.Params {
+		currentQuery.URLOptions.Params[paramIndex].Key = strings.ReplaceAll(param.Key, placeholder, value)
+		currentQuery.URLOptions.Params[paramIndex].Value = strings.ReplaceAll(param.Value, placeholder, value)
+	}
+	for bodyFormIndex, bodyFormItem := range currentQuery.URLOptions.BodyForm {
+		currentQuery.URLOptions.BodyForm[bodyFormIndex].Key = strings.ReplaceAll(bodyFormItem.Key, placeholder, value)
+		currentQuery.URLOptions.BodyForm[bodyFormIndex].Value = strings.ReplaceAll(bodyFormItem.Value, placeholder, value)
+	}
+	return currentQuery
+}
+
 func GetFrameForURLSourcesWithPostProcessing(ctx context.Context, query models.Query, infClient Client, requestHeaders map[string]string, postProcessingRequired bool) (*data.Frame, string, error) {
 	ctx, span := tracing.DefaultTracer().Start(ctx, "GetFrameForURLSourcesWithPostProcessing")
 	defer span.End()
 	frame := GetDummyFrame(query)
 	cursor := ""
+	ctx, finalURL := withFinalURL(ctx)
+	ctx, responseHeader := withResponseHeader(ctx)
 	urlResponseObject, statusCode, duration, err := infClient.GetResults(ctx, query, requestHeaders)
+	if err == nil {
+		// parsing the response is skipped when the query is cancelled during the download
+		err = ContextError(ctx)
+	}
 	frame.Meta.ExecutedQueryString = infClient.GetExecutedURL(ctx, query)
 	if infClient.IsMock {
 		duration = 123
@@ -153,6 +322,7 @@ func GetFrameForURLSourcesWithPostProcessing(ctx context.Context, query models.Q
 			Duration:               duration,
 			Query:                  query,
 			Error:                  err.Error(),
+			FinalURL:               *finalURL,
 		}
 		return frame, cursor, err
 	}
@@ -181,6 +351,20 @@ func GetFrameForURLSourcesWithPostProcessing(ctx context.Context, query models.Q
 				}
 			}
 		}
+		if query.Type == models.QueryTypeLogs {
+			if responseString, ok := urlResponseObject.(string); ok {
+				if frame, err = GetLogsBackendResponse(ctx, responseString, query); err != nil {
+					return frame, cursor, err
+				}
+			}
+		}
+		if query.Type == models.QueryTypeArrow {
+			if responseString, ok := urlResponseObject.(string); ok {
+				if frame, err = GetArrowBackendResponse(ctx, []byte(responseString), query); err != nil {
+					return frame, cursor, err
+				}
+			}
+		}
 		if postProcessingRequired {
 			frame, err = PostProcessFrame(ctx, frame, query)
 		}
@@ -190,15 +374,21 @@ func GetFrameForURLSourcesWithPostProcessing(ctx context.Context, query models.Q
 		if strings.TrimSpace(sqliteQuery) == "" {
 			sqliteQuery = "SELECT * FROM input"
 		}
+		if sqliteQuery, err = getReadOnlyStatement(sqliteQuery); err != nil {
+			return frame, cursor, err
+		}
 		body, err := json.Marshal(urlResponseObject)
 		if err != nil {
 			return frame, cursor, fmt.Errorf("error while marshaling the response object. %w", err)
 		}
-		if frame, err = jsonframer.ToFrame(string(body), jsonframer.FramerOptions{
+		stopParseTiming := trackTiming(ctx, parseTiming)
+		frame, err = jsonframer.ToFrame(string(body), jsonframer.FramerOptions{
 			FramerType:   jsonframer.FramerTypeSQLite3,
 			SQLite3Query: sqliteQuery,
 			RootSelector: query.RootSelector,
-		}); err != nil {
+		})
+		stopParseTiming()
+		if err != nil {
 			return frame, cursor, err
 		}
 	}
@@ -214,6 +404,8 @@ func GetFrameForURLSourcesWithPostProcessing(ctx context.Context, query models.Q
 		Data:                   urlResponseObject,
 		ResponseCodeFromServer: statusCode,
 		Duration:               duration,
+		FinalURL:               *finalURL,
+		RateLimit:              getRateLimit(*responseHeader, time.Now()),
 	}
 	if err != nil {
 		backend.Logger.Error("error getting response for query", "error", err.Error())
@@ -223,9 +415,13 @@ func GetFrameForURLSourcesWithPostProcessing(ctx context.Context, query models.Q
 			Duration:               duration,
 			Query:                  query,
 			Error:                  err.Error(),
+			FinalURL:               *finalURL,
 		}
 		return frame, cursor, err
 	}
+	if query.PageMode == models.PaginationModeLink {
+		cursor = getNextLink(*responseHeader)
+	}
 	if query.PageMode == models.PaginationModeCursor && strings.TrimSpace(query.PageParamCursorFieldExtractionPath) != "" {
 		body, err := json.Marshal(urlResponseObject)
 		if err != nil {
@@ -233,7 +429,9 @@ func GetFrameForURLSourcesWithPostProcessing(ctx context.Context, query models.Q
 		}
 		cursor, err = jsonframer.GetRootData(string(body), query.PageParamCursorFieldExtractionPath)
 		if err != nil {
-			return frame, cursor, errors.New("error while extracting the cursor value")
+			// last page of the APIs such as kubernetes omits the cursor. so the missing cursor ends the pagination
+			backend.Logger.Debug("cursor not found in the response. pagination ends", "path", query.PageParamCursorFieldExtractionPath)
+			cursor = ""
 		}
 	}
 	return frame, cursor, nil
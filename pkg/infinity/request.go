@@ -13,6 +13,14 @@ import (
 	"moul.io/http2curl"
 )
 
+type mutationMethodKey struct{}
+
+// withMutationMethod sends the requests with the method of the mutation query. Only the mutation source sets the method,
+// so the other queries never send the requests other than GET and POST
+func withMutationMethod(ctx context.Context, method string) context.Context {
+	return context.WithValue(ctx, mutationMethodKey{}, method)
+}
+
 func GetRequest(ctx context.Context, settings models.InfinitySettings, body io.Reader, query models.Query, requestHeaders map[string]string, includeSect bool) (req *http.Request, err error) {
 	ctx, span := tracing.DefaultTracer().Start(ctx, "GetRequest")
 	defer span.End()
@@ -20,15 +28,23 @@ func GetRequest(ctx context.Context, settings models.InfinitySettings, body io.R
 	if err != nil {
 		return nil, err
 	}
+	profile, err := GetHeaderProfile(settings, query, url)
+	if err != nil {
+		return nil, err
+	}
 	switch strings.ToUpper(query.URLOptions.Method) {
 	case http.MethodPost:
-		req, err = http.NewRequest(http.MethodPost, url, body)
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, url, body)
 	default:
-		req, err = http.NewRequest(http.MethodGet, url, nil)
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	}
+	if method, ok := ctx.Value(mutationMethodKey{}).(string); ok && err == nil {
+		req.Method = method
 	}
 	req = ApplyAcceptHeader(query, settings, req, includeSect)
 	req = ApplyContentTypeHeader(query, settings, req, includeSect)
 	req = ApplyHeadersFromSettings(settings, req, includeSect)
+	req = ApplyHeaderProfile(profile, req, includeSect)
 	req = ApplyHeadersFromQuery(query, settings, req, includeSect)
 	req = ApplyBasicAuth(settings, req, includeSect)
 	req = ApplyBearerToken(settings, req, includeSect)
@@ -90,7 +106,7 @@ func NormalizeURL(u string) string {
 func (client *Client) GetExecutedURL(ctx context.Context, query models.Query) string {
 	out := []string{}
 	if query.Source != "inline" && query.Source != "azure-blob" {
-		req, err := GetRequest(ctx, client.Settings, GetQueryBody(query), query, map[string]string{}, false)
+		req, err := GetRequest(ctx, client.Settings, GetQueryBody(query, client.Settings, false), query, map[string]string{}, false)
 		if err != nil {
 			return fmt.Sprintf("error retrieving full url. %s", query.URL)
 		}
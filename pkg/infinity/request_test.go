@@ -212,6 +212,39 @@ func TestClient_GetExecutedURL(t *testing.T) {
 	}
 }
 
+func TestGetRequestWithHeaderProfiles(t *testing.T) {
+	settings := models.InfinitySettings{HeaderProfiles: []models.HeaderProfile{
+		{Name: "github", Hosts: []string{"api.github.com"}, Headers: map[string]string{"Authorization": "token foo"}},
+		{Name: "internal", Hosts: []string{"*.example.com"}, Headers: map[string]string{"X-Api-Key": "key"}},
+	}}
+	tests := []struct {
+		name    string
+		query   models.Query
+		want    map[string]string
+		wantErr string
+	}{
+		{name: "profile selected by host", query: models.Query{URL: "https://api.github.com/repos"}, want: map[string]string{"Authorization": "token foo"}},
+		{name: "profile selected by wildcard host", query: models.Query{URL: "https://foo.example.com/api"}, want: map[string]string{"X-Api-Key": "key"}},
+		{name: "profile selected by name", query: models.Query{URL: "https://foo.com/api", HeaderProfile: "github"}, want: map[string]string{"Authorization": "token foo"}},
+		{name: "no matching profile", query: models.Query{URL: "https://foo.com/api"}, want: map[string]string{"Authorization": "", "X-Api-Key": ""}},
+		{name: "profiles disabled", query: models.Query{URL: "https://api.github.com/repos", HeaderProfile: "none"}, want: map[string]string{"Authorization": ""}},
+		{name: "unknown profile", query: models.Query{URL: "https://foo.com/api", HeaderProfile: "foo"}, wantErr: "header profile foo not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := infinity.GetRequest(context.Background(), settings, nil, tt.query, map[string]string{}, true)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.Nil(t, err)
+			for k, v := range tt.want {
+				require.Equal(t, v, req.Header.Get(k))
+			}
+		})
+	}
+}
+
 func TestNormalizeURL(t *testing.T) {
 	tests := []struct {
 		name string
@@ -0,0 +1,153 @@
+package infinity
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+// maxResampleBuckets limits the number of rows generated per series, so a small interval over a long time range doesn't exhaust the memory
+const maxResampleBuckets = 100000
+
+// ResampleFrame aligns the rows of the frame into the buckets of fixed interval. When multiple rows fall into the same bucket, the last one wins.
+// Missing buckets are filled as per the fill strategy. Rows are grouped by the string fields, so each series of the long frames is resampled separately.
+// Numeric fields are converted into nullable float fields and the fields other than time, string and numeric are dropped
+func ResampleFrame(frame *data.Frame, interval string, fill models.ResampleFill) (*data.Frame, error) {
+	if frame == nil || strings.TrimSpace(interval) == "" || frame.Rows() == 0 {
+		return frame, nil
+	}
+	seconds, err := ParseDurationToSeconds(interval)
+	if err != nil || seconds <= 0 {
+		return frame, fmt.Errorf("invalid resample interval %s", interval)
+	}
+	step := time.Duration(seconds * float64(time.Second))
+	switch fill {
+	case "", models.ResampleFillNull, models.ResampleFillPrevious, models.ResampleFillZero, models.ResampleFillLinear:
+	default:
+		return frame, fmt.Errorf("unknown resample fill %s", fill)
+	}
+	var timeField *data.Field
+	for _, field := range frame.Fields {
+		if field.Type().Time() {
+			timeField = field
+			break
+		}
+	}
+	if timeField == nil {
+		return frame, fmt.Errorf("resample requires a time field. found %s", describeFields(frame))
+	}
+	groups := getSeriesGroups(frame, timeField)
+	if len(groups) == 0 {
+		return frame, nil
+	}
+	var start, end time.Time
+	for _, rows := range groups {
+		first, _ := timeField.ConcreteAt(rows[0])
+		last, _ := timeField.ConcreteAt(rows[len(rows)-1])
+		if start.IsZero() || first.(time.Time).Before(start) {
+			start = first.(time.Time)
+		}
+		if end.IsZero() || last.(time.Time).After(end) {
+			end = last.(time.Time)
+		}
+	}
+	start, end = start.Truncate(step), end.Truncate(step)
+	buckets := int(end.Sub(start)/step) + 1
+	if buckets > maxResampleBuckets {
+		return frame, fmt.Errorf("resample interval %s is too small for the time range. %d buckets exceed the limit of %d", interval, buckets, maxResampleBuckets)
+	}
+	keys := []string{}
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	rows := buckets * len(keys)
+	out := data.NewFrame(frame.Name)
+	out.Meta = frame.Meta
+	times := make([]time.Time, rows)
+	for b := 0; b < buckets; b++ {
+		for g := range keys {
+			times[b*len(keys)+g] = start.Add(time.Duration(b) * step)
+		}
+	}
+	for _, field := range frame.Fields {
+		switch {
+		case field == timeField:
+			out.Fields = append(out.Fields, data.NewField(field.Name, field.Labels, times))
+		case field.Type() == data.FieldTypeString || field.Type() == data.FieldTypeNullableString:
+			values := data.NewFieldFromFieldType(field.Type(), rows)
+			values.Name, values.Labels = field.Name, field.Labels
+			for g, k := range keys {
+				v := field.At(groups[k][0])
+				for b := 0; b < buckets; b++ {
+					values.Set(b*len(keys)+g, v)
+				}
+			}
+			out.Fields = append(out.Fields, values)
+		case field.Type().Numeric():
+			values := make([]*float64, rows)
+			for g, k := range keys {
+				series, err := resampleSeries(timeField, field, groups[k], start, step, buckets, fill)
+				if err != nil {
+					return frame, err
+				}
+				for b, v := range series {
+					values[b*len(keys)+g] = v
+				}
+			}
+			out.Fields = append(out.Fields, data.NewField(field.Name, field.Labels, values))
+		}
+	}
+	return out, nil
+}
+
+func resampleSeries(timeField *data.Field, field *data.Field, rows []int, start time.Time, step time.Duration, buckets int, fill models.ResampleFill) ([]*float64, error) {
+	series := make([]*float64, buckets)
+	for _, row := range rows {
+		value, err := field.NullableFloatAt(row)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			continue
+		}
+		t, _ := timeField.ConcreteAt(row)
+		v := *value
+		series[int(t.(time.Time).Truncate(step).Sub(start)/step)] = &v
+	}
+	switch fill {
+	case models.ResampleFillZero:
+		for i := range series {
+			if series[i] == nil {
+				v := 0.0
+				series[i] = &v
+			}
+		}
+	case models.ResampleFillPrevious:
+		for i := 1; i < len(series); i++ {
+			if series[i] == nil {
+				series[i] = series[i-1]
+			}
+		}
+	case models.ResampleFillLinear:
+		// leading and trailing gaps are left empty, as there is nothing to interpolate between
+		previous := -1
+		for i := range series {
+			if series[i] == nil {
+				continue
+			}
+			if previous != -1 {
+				for j := previous + 1; j < i; j++ {
+					v := *series[previous] + (*series[i]-*series[previous])*float64(j-previous)/float64(i-previous)
+					series[j] = &v
+				}
+			}
+			previous = i
+		}
+	}
+	return series, nil
+}
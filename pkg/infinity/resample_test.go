@@ -0,0 +1,74 @@
+package infinity_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestResampleFrame(t *testing.T) {
+	t0 := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	getFrame := func() *data.Frame {
+		return data.NewFrame("response",
+			data.NewField("time", nil, []time.Time{t0.Add(5 * time.Second), t0.Add(40 * time.Second), t0.Add(50 * time.Second), t0.Add(4 * time.Minute)}),
+			data.NewField("value", nil, []int64{10, 20, 30, 60}),
+			data.NewField("enabled", nil, []bool{true, true, false, true}),
+		)
+	}
+	values := func(field *data.Field) []*float64 {
+		out := []*float64{}
+		for i := 0; i < field.Len(); i++ {
+			out = append(out, field.At(i).(*float64))
+		}
+		return out
+	}
+	tests := []struct {
+		name string
+		fill models.ResampleFill
+		want []*float64
+	}{
+		{name: "null", fill: models.ResampleFillNull, want: []*float64{toFP(30), nil, nil, nil, toFP(60)}},
+		{name: "previous", fill: models.ResampleFillPrevious, want: []*float64{toFP(30), toFP(30), toFP(30), toFP(30), toFP(60)}},
+		{name: "zero", fill: models.ResampleFillZero, want: []*float64{toFP(30), toFP(0), toFP(0), toFP(0), toFP(60)}},
+		{name: "linear", fill: models.ResampleFillLinear, want: []*float64{toFP(30), toFP(37.5), toFP(45), toFP(52.5), toFP(60)}},
+	}
+	for _, tt := range tests {
+		t.Run("fill "+tt.name, func(t *testing.T) {
+			frame, err := infinity.ResampleFrame(getFrame(), "1m", tt.fill)
+			require.Nil(t, err)
+			require.Len(t, frame.Fields, 2)
+			require.Equal(t, t0, frame.Fields[0].At(0))
+			require.Equal(t, t0.Add(4*time.Minute), frame.Fields[0].At(4))
+			require.Equal(t, tt.want, values(frame.Fields[1]))
+		})
+	}
+	t.Run("series should be resampled separately", func(t *testing.T) {
+		frame := data.NewFrame("response",
+			data.NewField("time", nil, []time.Time{t0, t0.Add(2 * time.Minute), t0.Add(time.Minute)}),
+			data.NewField("host", nil, []string{"a", "a", "b"}),
+			data.NewField("value", nil, []float64{1, 3, 5}),
+		)
+		frame, err := infinity.ResampleFrame(frame, "1m", models.ResampleFillPrevious)
+		require.Nil(t, err)
+		require.Equal(t, 6, frame.Rows())
+		require.Equal(t, []*float64{toFP(1), nil, toFP(1), toFP(5), toFP(3), toFP(5)}, values(frame.Fields[2]))
+		require.Equal(t, "b", frame.Fields[1].At(5))
+	})
+	t.Run("empty interval should return the frame as is", func(t *testing.T) {
+		frame, err := infinity.ResampleFrame(getFrame(), "", models.ResampleFillNull)
+		require.Nil(t, err)
+		require.Equal(t, 4, frame.Rows())
+	})
+	t.Run("invalid interval should throw error", func(t *testing.T) {
+		_, err := infinity.ResampleFrame(getFrame(), "$__interval", models.ResampleFillNull)
+		require.ErrorContains(t, err, "invalid resample interval")
+	})
+	t.Run("too many buckets should throw error", func(t *testing.T) {
+		_, err := infinity.ResampleFrame(getFrame(), "1ms", models.ResampleFillNull)
+		require.ErrorContains(t, err, "exceed the limit")
+	})
+}
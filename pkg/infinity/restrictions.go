@@ -0,0 +1,163 @@
+package infinity
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+// CheckQueryRestrictions rejects the query using the options restricted by the query restrictions of the datasource for the user
+func CheckQueryRestrictions(ctx context.Context, settings models.InfinitySettings, query models.Query, user *backend.User) error {
+	restrictions := settings.QueryRestrictions
+	if !restrictions.AppliesTo(userRole(user)) {
+		return nil
+	}
+	if !restrictions.AllowMutations && query.Source == "mutation" {
+		return restrictedError("mutation queries")
+	}
+	if !restrictions.AllowLocalHost && isLocalHostQuery(ctx, settings, query) {
+		return restrictedError("sources reaching the plugin host")
+	}
+	requests := []models.Query{}
+	if query.Source == "url" || query.Source == "mutation" {
+		requests = append(requests, query)
+	}
+	for _, step := range query.Chain {
+		requests = append(requests, models.Query{Source: "url", URL: step.URL, URLOptions: step.URLOptions})
+	}
+	for _, request := range requests {
+		if restrictions.BlockCustomHeaders {
+			for _, header := range request.URLOptions.Headers {
+				// cacheq and cachettl headers control the response cache
+				if header.Key != "" && header.Key != "cacheq" && header.Key != "cachettl" {
+					return restrictedError("custom headers")
+				}
+			}
+		}
+		if restrictions.RequireHTTPS {
+			rawURL, err := GetQueryURL(ctx, settings, request, false)
+			if err != nil {
+				return UserError(fmt.Errorf("invalid url. %w", err))
+			}
+			// urls built from the chain variables are rejected unless the https scheme is part of the url
+			if u, err := url.Parse(rawURL); err != nil || u.Scheme != "https" {
+				return restrictedError("non https urls")
+			}
+		}
+	}
+	return nil
+}
+
+// CheckExportRestrictions rejects the scheduled export when the query or the target are restricted for the user
+func CheckExportRestrictions(ctx context.Context, settings models.InfinitySettings, item ScheduledExport, user *backend.User) error {
+	if err := CheckQueryRestrictions(ctx, settings, item.Query, user); err != nil {
+		return err
+	}
+	if settings.QueryRestrictions.AppliesTo(userRole(user)) && !settings.QueryRestrictions.AllowLocalHost && item.Target.Type == ExportTargetFile {
+		return restrictedError("file exports")
+	}
+	return nil
+}
+
+// CheckMaterializeRestrictions rejects materializing the result as dataset for the restricted users. Datasets are shared by all
+// the users of the datasource, the same as the refresh and the delete of the datasets allowed only for the admins
+func CheckMaterializeRestrictions(settings models.InfinitySettings, user *backend.User) error {
+	if settings.QueryRestrictions.AppliesTo(userRole(user)) {
+		return restrictedError("materialized datasets")
+	}
+	return nil
+}
+
+// isLocalHostQuery reports whether the query reads from the plugin host, either by the source, by the docker socket of the datasource
+// or by the target of the network source resolving to the plugin host. sql of the queries is not considered as the sql engine is
+// restricted to the in-memory database
+func isLocalHostQuery(ctx context.Context, settings models.InfinitySettings, query models.Query) bool {
+	if query.Source == "crawl" || query.Source == "database" {
+		return true
+	}
+	if _, socket := getUnixSocketPath(settings.URL); socket && (query.Source == "url" || query.Source == "mutation" || len(query.Chain) > 0) {
+		return true
+	}
+	for _, target := range getNetworkTargets(settings, query) {
+		if isLocalHostTarget(ctx, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// getNetworkTargets returns the targets dialed by the network sources, as urls, host:port or host. Targets defaulting to the
+// datasource url are included, as the url of the datasource is not checked against the allowed hosts by these sources
+func getNetworkTargets(settings models.InfinitySettings, query models.Query) []string {
+	switch {
+	case query.Source == "probe" && query.ProbeOptions != nil:
+		return query.ProbeOptions.Targets
+	case query.Source == "certificate" && query.CertificateOptions != nil:
+		return query.CertificateOptions.Targets
+	case query.Source == "snmp" && query.SNMPOptions != nil:
+		return []string{query.SNMPOptions.Target}
+	case query.Source == "dns" && query.DNSOptions != nil:
+		// the system resolver is the configuration of the plugin host, not the target of the query
+		return []string{query.DNSOptions.Resolver}
+	case query.Source == "ldap" && query.LDAPOptions != nil && query.LDAPOptions.Server != "":
+		return []string{query.LDAPOptions.Server}
+	case query.Source == "mqtt" && query.MQTTOptions != nil && query.MQTTOptions.Broker != "":
+		return []string{query.MQTTOptions.Broker}
+	case query.Source == "ldap" || query.Source == "mqtt":
+		return []string{settings.URL}
+	case query.Source == "redis":
+		return []string{settings.RedisURL}
+	case query.Source == "rdap" && query.RDAPOptions != nil:
+		return []string{query.RDAPOptions.Server}
+	}
+	return nil
+}
+
+// isLocalHostTarget reports whether the host of the target resolves to the loopback, unspecified or link-local addresses.
+// Targets failing to resolve are left to fail by the source
+func isLocalHostTarget(ctx context.Context, target string) bool {
+	target = strings.TrimSpace(target)
+	host := target
+	if u, err := url.Parse(target); err == nil && strings.Contains(target, "://") {
+		host = u.Hostname()
+	} else if h, _, err := net.SplitHostPort(target); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if host == "" {
+		return false
+	}
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return false
+		}
+		ips = ips[:0]
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+	for _, ip := range ips {
+		if ip.IsLoopback() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() {
+			return true
+		}
+	}
+	return false
+}
+
+func restrictedError(option string) error {
+	return UserError(fmt.Errorf("%s are not allowed by the query restrictions of the datasource", option))
+}
+
+func userRole(user *backend.User) string {
+	if user == nil {
+		return ""
+	}
+	return user.Role
+}
@@ -0,0 +1,214 @@
+package infinity_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestCheckQueryRestrictions(t *testing.T) {
+	all := models.QueryRestrictions{RequireHTTPS: true, BlockCustomHeaders: true, ExemptRole: "Admin"}
+	tests := []struct {
+		name         string
+		restrictions models.QueryRestrictions
+		settingsURL  string
+		query        models.Query
+		user         *backend.User
+		wantErr      string
+	}{
+		{
+			name:         "no restrictions",
+			restrictions: models.QueryRestrictions{AllowLocalHost: true, AllowMutations: true},
+			query:        models.Query{Source: "mutation", URL: "http://foo", URLOptions: models.URLOptions{Headers: []models.URLOptionKeyValuePair{{Key: "X-Foo", Value: "bar"}}}},
+		},
+		{
+			name:    "mutation denied by default",
+			query:   models.Query{Source: "mutation", URL: "https://foo"},
+			user:    &backend.User{Role: "Editor"},
+			wantErr: "mutation queries are not allowed by the query restrictions of the datasource",
+		},
+		{
+			name:  "mutation allowed for the admins by default",
+			query: models.Query{Source: "mutation", URL: "https://foo"},
+			user:  &backend.User{Role: "Admin"},
+		},
+		{
+			name:    "crawl denied by default",
+			query:   models.Query{Source: "crawl", URL: "https://foo"},
+			user:    &backend.User{Role: "Viewer"},
+			wantErr: "sources reaching the plugin host are not allowed by the query restrictions of the datasource",
+		},
+		{
+			name:    "database denied without the user",
+			query:   models.Query{Source: "database", SQLQuery: "SELECT 1"},
+			wantErr: "sources reaching the plugin host are not allowed by the query restrictions of the datasource",
+		},
+		{
+			name:        "docker socket",
+			settingsURL: "unix:///var/run/docker.sock",
+			query:       models.Query{Source: "url", URL: "/containers/json"},
+			user:        &backend.User{Role: "Viewer"},
+			wantErr:     "sources reaching the plugin host are not allowed by the query restrictions of the datasource",
+		},
+		{
+			name:         "docker socket allowed",
+			restrictions: models.QueryRestrictions{AllowLocalHost: true},
+			settingsURL:  "unix:///var/run/docker.sock",
+			query:        models.Query{Source: "url", URL: "/containers/json"},
+			user:         &backend.User{Role: "Viewer"},
+		},
+		{
+			name:        "inline query on the docker socket datasource",
+			settingsURL: "unix:///var/run/docker.sock",
+			query:       models.Query{Source: "inline", Data: "[]"},
+			user:        &backend.User{Role: "Viewer"},
+		},
+		{
+			name:    "probe target of the plugin host",
+			query:   models.Query{Source: "probe", ProbeOptions: &models.ProbeOptions{Targets: []string{"203.0.113.1:443", "localhost:6379"}}},
+			user:    &backend.User{Role: "Viewer"},
+			wantErr: "sources reaching the plugin host are not allowed by the query restrictions of the datasource",
+		},
+		{
+			name:  "probe target of the other host",
+			query: models.Query{Source: "probe", ProbeOptions: &models.ProbeOptions{Mode: models.ProbeModeICMP, Targets: []string{"203.0.113.1"}}},
+			user:  &backend.User{Role: "Viewer"},
+		},
+		{
+			name:    "certificate target of the metadata service",
+			query:   models.Query{Source: "certificate", CertificateOptions: &models.CertificateOptions{Targets: []string{"169.254.169.254"}}},
+			user:    &backend.User{Role: "Viewer"},
+			wantErr: "sources reaching the plugin host are not allowed by the query restrictions of the datasource",
+		},
+		{
+			name:    "snmp target of the plugin host",
+			query:   models.Query{Source: "snmp", SNMPOptions: &models.SNMPOptions{Target: "[::1]:161"}},
+			user:    &backend.User{Role: "Viewer"},
+			wantErr: "sources reaching the plugin host are not allowed by the query restrictions of the datasource",
+		},
+		{
+			name:    "dns resolver of the plugin host",
+			query:   models.Query{Source: "dns", DNSOptions: &models.DNSOptions{Names: []string{"example.com"}, Resolver: "127.0.0.1"}},
+			user:    &backend.User{Role: "Viewer"},
+			wantErr: "sources reaching the plugin host are not allowed by the query restrictions of the datasource",
+		},
+		{
+			name:  "dns system resolver",
+			query: models.Query{Source: "dns", DNSOptions: &models.DNSOptions{Names: []string{"example.com"}}},
+			user:  &backend.User{Role: "Viewer"},
+		},
+		{
+			name:    "ldap server of the plugin host",
+			query:   models.Query{Source: "ldap", LDAPOptions: &models.LDAPOptions{Server: "ldap://0.0.0.0:389"}},
+			user:    &backend.User{Role: "Viewer"},
+			wantErr: "sources reaching the plugin host are not allowed by the query restrictions of the datasource",
+		},
+		{
+			name:        "mqtt broker of the datasource on the plugin host",
+			settingsURL: "mqtt://localhost:1883",
+			query:       models.Query{Source: "mqtt", MQTTOptions: &models.MQTTOptions{Topics: []string{"foo"}}},
+			user:        &backend.User{Role: "Viewer"},
+			wantErr:     "sources reaching the plugin host are not allowed by the query restrictions of the datasource",
+		},
+		{
+			name:         "mqtt broker of the plugin host allowed",
+			restrictions: models.QueryRestrictions{AllowLocalHost: true},
+			query:        models.Query{Source: "mqtt", MQTTOptions: &models.MQTTOptions{Broker: "mqtt://127.0.0.1:1883", Topics: []string{"foo"}}},
+			user:         &backend.User{Role: "Viewer"},
+		},
+		{
+			name:         "allowed query",
+			restrictions: all,
+			query:        models.Query{Source: "url", URL: "https://foo", URLOptions: models.URLOptions{Headers: []models.URLOptionKeyValuePair{{Key: "cacheq", Value: "1"}}}},
+			user:         &backend.User{Role: "Viewer"},
+		},
+		{
+			name:         "exempt role",
+			restrictions: models.QueryRestrictions{RequireHTTPS: true, AllowMutations: true, ExemptRole: "Editor"},
+			query:        models.Query{Source: "url", URL: "http://foo"},
+			user:         &backend.User{Role: "Editor"},
+		},
+		{
+			name:         "mutation",
+			restrictions: all,
+			query:        models.Query{Source: "mutation", URL: "https://foo"},
+			user:         &backend.User{Role: "Editor"},
+			wantErr:      "mutation queries are not allowed by the query restrictions of the datasource",
+		},
+		{
+			name:         "non https url",
+			restrictions: all,
+			query:        models.Query{Source: "url", URL: "http://foo"},
+			wantErr:      "non https urls are not allowed by the query restrictions of the datasource",
+		},
+		{
+			name:         "non https url of the datasource",
+			restrictions: models.QueryRestrictions{RequireHTTPS: true},
+			settingsURL:  "http://foo",
+			query:        models.Query{Source: "url", URL: "/users"},
+			user:         &backend.User{Role: "Editor"},
+			wantErr:      "non https urls are not allowed by the query restrictions of the datasource",
+		},
+		{
+			name:         "non https url of the chain step",
+			restrictions: all,
+			query:        models.Query{Source: "url", URL: "https://foo", Chain: []models.ChainStep{{URL: "{{login_url}}"}}},
+			wantErr:      "non https urls are not allowed by the query restrictions of the datasource",
+		},
+		{
+			name:         "inline query",
+			restrictions: all,
+			query:        models.Query{Source: "inline", URL: "http://foo"},
+		},
+		{
+			name:         "custom headers",
+			restrictions: all,
+			query:        models.Query{Source: "url", URL: "https://foo", URLOptions: models.URLOptions{Headers: []models.URLOptionKeyValuePair{{Key: "Authorization", Value: "Bearer foo"}}}},
+			wantErr:      "custom headers are not allowed by the query restrictions of the datasource",
+		},
+		{
+			name:         "custom headers of the chain step",
+			restrictions: all,
+			query:        models.Query{Source: "url", URL: "https://foo", Chain: []models.ChainStep{{URL: "https://foo/login", URLOptions: models.URLOptions{Headers: []models.URLOptionKeyValuePair{{Key: "X-Foo", Value: "bar"}}}}}},
+			wantErr:      "custom headers are not allowed by the query restrictions of the datasource",
+		},
+		{
+			name:         "sql",
+			restrictions: all,
+			query:        models.Query{Type: models.QueryTypeSQL, SQLQuery: "SELECT attachment FROM A"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := models.InfinitySettings{URL: tt.settingsURL, QueryRestrictions: tt.restrictions}
+			err := infinity.CheckQueryRestrictions(context.Background(), settings, tt.query, tt.user)
+			if tt.wantErr == "" {
+				require.Nil(t, err)
+				return
+			}
+			require.NotNil(t, err)
+			require.Equal(t, tt.wantErr, err.Error())
+			require.Equal(t, infinity.ErrorKindUser, infinity.GetErrorKind(err))
+		})
+	}
+}
+
+func TestCheckExportRestrictions(t *testing.T) {
+	settings := models.InfinitySettings{ExportDirectory: "/tmp/exports"}
+	item := infinity.ScheduledExport{ID: "foo", Query: models.Query{Source: "url", URL: "https://foo"}, Target: infinity.ExportTarget{Type: infinity.ExportTargetFile, Path: "foo.csv"}}
+	require.Nil(t, infinity.CheckExportRestrictions(context.Background(), settings, item, &backend.User{Role: "Admin"}))
+	err := infinity.CheckExportRestrictions(context.Background(), settings, item, &backend.User{Role: "Editor"})
+	require.NotNil(t, err)
+	require.Equal(t, "file exports are not allowed by the query restrictions of the datasource", err.Error())
+	settings.QueryRestrictions.AllowLocalHost = true
+	require.Nil(t, infinity.CheckExportRestrictions(context.Background(), settings, item, &backend.User{Role: "Editor"}))
+	item.Query.Source = "crawl"
+	settings.QueryRestrictions.AllowLocalHost = false
+	err = infinity.CheckExportRestrictions(context.Background(), settings, item, &backend.User{Role: "Editor"})
+	require.NotNil(t, err)
+	require.Equal(t, "sources reaching the plugin host are not allowed by the query restrictions of the datasource", err.Error())
+}
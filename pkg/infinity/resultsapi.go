@@ -0,0 +1,411 @@
+package infinity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+const (
+	resultsAPIPollInterval = 500 * time.Millisecond
+	resultsAPIMaxPages     = 100
+)
+
+// resultsAPIColumn is the column of the warehouse results mapped to the infinity column type
+type resultsAPIColumn struct {
+	Name string
+	Type string
+}
+
+// resultsAPIResults are the rows collected from all the pages of the results
+type resultsAPIResults struct {
+	Columns   []resultsAPIColumn
+	Rows      []any
+	Truncated bool
+}
+
+// GetResultsAPIFrame runs the query against the asynchronous results APIs of BigQuery and Databricks. The response of the query url is polled until the
+// job is complete and the rows of all the pages are merged into a single frame. Columns are derived from the schema of the results unless specified in the query
+func GetResultsAPIFrame(ctx context.Context, query models.Query, infClient Client, requestHeaders map[string]string) (*data.Frame, error) {
+	ctx, span := tracing.DefaultTracer().Start(ctx, "GetResultsAPIFrame")
+	defer span.End()
+	// polling requests share the cache key of the query. so the responses are never cached
+	ctx = withoutResponseCache(ctx)
+	var results *resultsAPIResults
+	var err error
+	switch query.ResultsAPI {
+	case models.ResultsAPIBigQuery:
+		results, err = getBigQueryResults(ctx, query, infClient, requestHeaders)
+	case models.ResultsAPIDatabricks:
+		results, err = getDatabricksResults(ctx, query, infClient, requestHeaders)
+	default:
+		err = UserError(fmt.Errorf("unsupported results api %s", query.ResultsAPI))
+	}
+	if err != nil {
+		frame := GetDummyFrame(query)
+		frame.Meta.Custom = &CustomMeta{Query: query, Error: err.Error()}
+		return frame, err
+	}
+	rowsQuery := query
+	rowsQuery.RootSelector = ""
+	if len(rowsQuery.Columns) == 0 {
+		for _, c := range results.Columns {
+			rowsQuery.Columns = append(rowsQuery.Columns, models.InfinityColumn{Selector: c.Name, Text: c.Name, Type: c.Type})
+		}
+	}
+	frame, err := GetJSONBackendResponse(ctx, results.Rows, rowsQuery)
+	if err != nil {
+		return frame, err
+	}
+	frame, err = PostProcessFrame(ctx, frame, rowsQuery)
+	if frame != nil && frame.Meta != nil {
+		frame.Meta.ExecutedQueryString = infClient.GetExecutedURL(ctx, query)
+	}
+	if frame != nil && results.Truncated {
+		frame.AppendNotices(data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("Results are truncated to the first %d pages", getResultsAPIMaxPages(query)),
+		})
+	}
+	return frame, err
+}
+
+func getResultsAPIMaxPages(query models.Query) int {
+	if query.PageMaxPages > 0 {
+		return query.PageMaxPages
+	}
+	return resultsAPIMaxPages
+}
+
+// getResultsAPIResponse sends the query. When the link is not empty, the link is requested with GET instead, keeping the headers of the query
+func getResultsAPIResponse(ctx context.Context, query models.Query, infClient Client, requestHeaders map[string]string, link string) (map[string]any, error) {
+	if link != "" {
+		query.URL = link
+		query.URLOptions = models.URLOptions{Method: http.MethodGet, Headers: query.URLOptions.Headers}
+	}
+	res, _, _, err := infClient.GetResults(ctx, query, requestHeaders)
+	if err != nil {
+		return nil, err
+	}
+	out, ok := res.(map[string]any)
+	if !ok {
+		return nil, DownstreamError(errors.New("unexpected response from the results api. expected json object"), 0)
+	}
+	return out, nil
+}
+
+// resolveResultsAPILink resolves the path of the polling and paging requests against the url of the query
+func resolveResultsAPILink(settings models.InfinitySettings, query models.Query, path string, params url.Values) (string, error) {
+	base := query.URL
+	if !strings.HasPrefix(base, settings.URL) {
+		base = settings.URL + base
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", UserError(fmt.Errorf("invalid url. %w", err))
+	}
+	return u.ResolveReference(&url.URL{Path: path, RawQuery: params.Encode()}).String(), nil
+}
+
+func getResultsAPIPath(ctx context.Context, settings models.InfinitySettings, query models.Query) string {
+	queryURL, err := GetQueryURL(ctx, settings, query, false)
+	if err != nil {
+		return ""
+	}
+	u, err := url.Parse(queryURL)
+	if err != nil {
+		return ""
+	}
+	return u.Path
+}
+
+func waitForResultsAPI(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ContextError(ctx)
+	case <-time.After(resultsAPIPollInterval):
+		return nil
+	}
+}
+
+func getBigQueryResults(ctx context.Context, query models.Query, infClient Client, requestHeaders map[string]string) (*resultsAPIResults, error) {
+	prefix, _, found := strings.Cut(getResultsAPIPath(ctx, infClient.Settings, query), "/projects/")
+	if !found {
+		return nil, UserError(errors.New("bigquery url should be of the jobs api. example: https://bigquery.googleapis.com/bigquery/v2/projects/<project>/queries"))
+	}
+	res, err := getResultsAPIResponse(ctx, query, infClient, requestHeaders, "")
+	if err != nil {
+		return nil, err
+	}
+	results := &resultsAPIResults{}
+	var fields []any
+	for page := 0; ; {
+		complete, _ := res["jobComplete"].(bool)
+		if complete {
+			if fields == nil {
+				schema, _ := res["schema"].(map[string]any)
+				fields, _ = schema["fields"].([]any)
+				results.Columns = getBigQueryColumns(fields)
+			}
+			rows, _ := res["rows"].([]any)
+			for _, row := range rows {
+				results.Rows = append(results.Rows, getBigQueryRecord(fields, row))
+			}
+			page++
+		}
+		pageToken, _ := res["pageToken"].(string)
+		if complete && pageToken == "" {
+			return results, nil
+		}
+		if page >= getResultsAPIMaxPages(query) {
+			results.Truncated = true
+			return results, nil
+		}
+		jobReference, _ := res["jobReference"].(map[string]any)
+		projectID, _ := jobReference["projectId"].(string)
+		jobID, _ := jobReference["jobId"].(string)
+		if projectID == "" || jobID == "" {
+			return nil, DownstreamError(errors.New("job reference is missing in the bigquery response"), 0)
+		}
+		params := url.Values{}
+		if location, _ := jobReference["location"].(string); location != "" {
+			params.Set("location", location)
+		}
+		if complete {
+			params.Set("pageToken", pageToken)
+		} else {
+			if err := waitForResultsAPI(ctx); err != nil {
+				return nil, err
+			}
+			params.Set("timeoutMs", "10000")
+		}
+		link, err := resolveResultsAPILink(infClient.Settings, query, fmt.Sprintf("%s/projects/%s/queries/%s", prefix, url.PathEscape(projectID), url.PathEscape(jobID)), params)
+		if err != nil {
+			return nil, err
+		}
+		if res, err = getResultsAPIResponse(ctx, query, infClient, requestHeaders, link); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func getBigQueryColumns(fields []any) []resultsAPIColumn {
+	columns := []resultsAPIColumn{}
+	for _, f := range fields {
+		field, _ := f.(map[string]any)
+		name, _ := field["name"].(string)
+		fieldType, _ := field["type"].(string)
+		mode, _ := field["mode"].(string)
+		column := resultsAPIColumn{Name: name, Type: "string"}
+		if mode != "REPEATED" {
+			switch fieldType {
+			case "INTEGER", "INT64", "FLOAT", "FLOAT64", "NUMERIC", "BIGNUMERIC":
+				column.Type = "number"
+			case "BOOLEAN", "BOOL":
+				column.Type = "boolean"
+			case "TIMESTAMP", "DATE", "DATETIME":
+				column.Type = "timestamp"
+			}
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// getBigQueryRecord converts the {"f":[{"v":...}]} row of bigquery into an object using the schema fields
+func getBigQueryRecord(fields []any, row any) map[string]any {
+	record := map[string]any{}
+	r, _ := row.(map[string]any)
+	values, _ := r["f"].([]any)
+	for i, f := range fields {
+		if i >= len(values) {
+			break
+		}
+		field, _ := f.(map[string]any)
+		name, _ := field["name"].(string)
+		cell, _ := values[i].(map[string]any)
+		record[name] = getBigQueryValue(field, cell["v"])
+	}
+	return record
+}
+
+func getBigQueryValue(field map[string]any, value any) any {
+	if value == nil {
+		return nil
+	}
+	if mode, _ := field["mode"].(string); mode == "REPEATED" {
+		items, _ := value.([]any)
+		out := make([]any, 0, len(items))
+		itemField := map[string]any{"type": field["type"], "fields": field["fields"]}
+		for _, item := range items {
+			cell, _ := item.(map[string]any)
+			out = append(out, getBigQueryValue(itemField, cell["v"]))
+		}
+		return out
+	}
+	fieldType, _ := field["type"].(string)
+	if fieldType == "RECORD" || fieldType == "STRUCT" {
+		fields, _ := field["fields"].([]any)
+		return getBigQueryRecord(fields, value)
+	}
+	s, ok := value.(string)
+	if !ok {
+		return value
+	}
+	switch fieldType {
+	case "INTEGER", "INT64", "FLOAT", "FLOAT64", "NUMERIC", "BIGNUMERIC":
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			return v
+		}
+	case "BOOLEAN", "BOOL":
+		if v, err := strconv.ParseBool(s); err == nil {
+			return v
+		}
+	case "TIMESTAMP":
+		// timestamps are returned as the seconds since epoch in the scientific notation
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			return time.UnixMicro(int64(v * 1e6)).UTC().Format(time.RFC3339Nano)
+		}
+	case "DATE", "DATETIME":
+		return formatResultsAPITime(s)
+	}
+	return s
+}
+
+func getDatabricksResults(ctx context.Context, query models.Query, infClient Client, requestHeaders map[string]string) (*resultsAPIResults, error) {
+	statementsPath := strings.TrimSuffix(getResultsAPIPath(ctx, infClient.Settings, query), "/")
+	if !strings.HasSuffix(statementsPath, "/statements") {
+		return nil, UserError(errors.New("databricks url should be of the statement execution api. example: https://<workspace>/api/2.0/sql/statements"))
+	}
+	res, err := getResultsAPIResponse(ctx, query, infClient, requestHeaders, "")
+	if err != nil {
+		return nil, err
+	}
+	for {
+		status, _ := res["status"].(map[string]any)
+		state, _ := status["state"].(string)
+		if state == "SUCCEEDED" {
+			break
+		}
+		if state != "PENDING" && state != "RUNNING" {
+			statusError, _ := status["error"].(map[string]any)
+			message, _ := statusError["message"].(string)
+			return nil, DownstreamError(fmt.Errorf("databricks statement %s. %s", strings.ToLower(state), message), 0)
+		}
+		statementID, _ := res["statement_id"].(string)
+		if statementID == "" {
+			return nil, DownstreamError(errors.New("statement id is missing in the databricks response"), 0)
+		}
+		if err := waitForResultsAPI(ctx); err != nil {
+			return nil, err
+		}
+		link, err := resolveResultsAPILink(infClient.Settings, query, statementsPath+"/"+url.PathEscape(statementID), nil)
+		if err != nil {
+			return nil, err
+		}
+		if res, err = getResultsAPIResponse(ctx, query, infClient, requestHeaders, link); err != nil {
+			return nil, err
+		}
+	}
+	manifest, _ := res["manifest"].(map[string]any)
+	schema, _ := manifest["schema"].(map[string]any)
+	columns, _ := schema["columns"].([]any)
+	results := &resultsAPIResults{Columns: getDatabricksColumns(columns)}
+	chunk, _ := res["result"].(map[string]any)
+	for page := 1; chunk != nil; page++ {
+		if _, ok := chunk["external_links"]; ok {
+			return nil, UserError(errors.New("only the INLINE disposition of the databricks results is supported"))
+		}
+		rows, _ := chunk["data_array"].([]any)
+		for _, row := range rows {
+			values, _ := row.([]any)
+			record := map[string]any{}
+			for i, c := range results.Columns {
+				if i < len(values) {
+					record[c.Name] = getDatabricksValue(c, values[i])
+				}
+			}
+			results.Rows = append(results.Rows, record)
+		}
+		next, _ := chunk["next_chunk_internal_link"].(string)
+		if next == "" {
+			break
+		}
+		if page >= getResultsAPIMaxPages(query) {
+			results.Truncated = true
+			break
+		}
+		nextURL, err := url.Parse(next)
+		if err != nil {
+			return nil, DownstreamError(fmt.Errorf("invalid next chunk link. %w", err), 0)
+		}
+		link, err := resolveResultsAPILink(infClient.Settings, query, nextURL.Path, nextURL.Query())
+		if err != nil {
+			return nil, err
+		}
+		if chunk, err = getResultsAPIResponse(ctx, query, infClient, requestHeaders, link); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+func getDatabricksColumns(columns []any) []resultsAPIColumn {
+	out := []resultsAPIColumn{}
+	for _, c := range columns {
+		column, _ := c.(map[string]any)
+		name, _ := column["name"].(string)
+		typeName, _ := column["type_name"].(string)
+		item := resultsAPIColumn{Name: name, Type: "string"}
+		switch typeName {
+		case "BYTE", "SHORT", "INT", "LONG", "FLOAT", "DOUBLE", "DECIMAL":
+			item.Type = "number"
+		case "BOOLEAN":
+			item.Type = "boolean"
+		case "TIMESTAMP", "TIMESTAMP_NTZ", "DATE":
+			item.Type = "timestamp"
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
+// getDatabricksValue converts the values of the JSON_ARRAY format, which are always strings, into the type of the column
+func getDatabricksValue(column resultsAPIColumn, value any) any {
+	s, ok := value.(string)
+	if !ok {
+		return value
+	}
+	switch column.Type {
+	case "number":
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			return v
+		}
+	case "boolean":
+		if v, err := strconv.ParseBool(s); err == nil {
+			return v
+		}
+	case "timestamp":
+		return formatResultsAPITime(s)
+	}
+	return s
+}
+
+// formatResultsAPITime converts the date and datetime values without the zone into RFC3339 in UTC
+func formatResultsAPITime(s string) string {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02 15:04:05.999999999", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return s
+}
@@ -0,0 +1,102 @@
+package infinity_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestGetResultsAPIFrame(t *testing.T) {
+	t.Run("should poll and page through the bigquery results", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch {
+			case r.Method == http.MethodPost && r.URL.Path == "/bigquery/v2/projects/p1/queries":
+				_, _ = w.Write([]byte(`{ "jobComplete": false, "jobReference": { "projectId": "p1", "jobId": "j1", "location": "EU" } }`))
+			case r.URL.Path == "/bigquery/v2/projects/p1/queries/j1" && r.URL.Query().Get("pageToken") == "":
+				require.Equal(t, "EU", r.URL.Query().Get("location"))
+				_, _ = w.Write([]byte(`{
+					"jobComplete": true, "pageToken": "t2", "jobReference": { "projectId": "p1", "jobId": "j1", "location": "EU" },
+					"schema": { "fields": [{ "name": "name", "type": "STRING" }, { "name": "count", "type": "INTEGER" }, { "name": "ts", "type": "TIMESTAMP" }] },
+					"rows": [{ "f": [{ "v": "foo" }, { "v": "1" }, { "v": "1.6725312E9" }] }]
+				}`))
+			case r.URL.Path == "/bigquery/v2/projects/p1/queries/j1" && r.URL.Query().Get("pageToken") == "t2":
+				_, _ = w.Write([]byte(`{ "jobComplete": true, "jobReference": { "projectId": "p1", "jobId": "j1" }, "rows": [{ "f": [{ "v": "bar" }, { "v": "2" }, { "v": null }] }] }`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer server.Close()
+		client, err := infinity.NewClient(context.Background(), models.InfinitySettings{URL: server.URL})
+		require.Nil(t, err)
+		query := models.Query{RefID: "A", Type: models.QueryTypeJSON, Source: "url", Parser: models.InfinityParserBackend, ResultsAPI: models.ResultsAPIBigQuery,
+			URL: "/bigquery/v2/projects/p1/queries", URLOptions: models.URLOptions{Method: http.MethodPost, Body: `{ "query": "SELECT 1" }`}}
+		frame, err := infinity.GetFrameForURLSources(context.Background(), query, *client, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, 2, frame.Rows())
+		name, _ := frame.FieldByName("name")
+		require.Equal(t, "bar", *name.At(1).(*string))
+		count, _ := frame.FieldByName("count")
+		require.Equal(t, float64(2), *count.At(1).(*float64))
+		ts, _ := frame.FieldByName("ts")
+		require.Equal(t, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), ts.At(0).(*time.Time).UTC())
+		require.Nil(t, ts.At(1))
+	})
+	t.Run("should poll the databricks statement and read the chunks", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch r.URL.Path {
+			case "/api/2.0/sql/statements":
+				_, _ = w.Write([]byte(`{ "statement_id": "s1", "status": { "state": "PENDING" } }`))
+			case "/api/2.0/sql/statements/s1":
+				_, _ = w.Write([]byte(`{
+					"statement_id": "s1", "status": { "state": "SUCCEEDED" },
+					"manifest": { "schema": { "columns": [{ "name": "name", "type_name": "STRING" }, { "name": "enabled", "type_name": "BOOLEAN" }] } },
+					"result": { "chunk_index": 0, "data_array": [["foo", "true"]], "next_chunk_internal_link": "/api/2.0/sql/statements/s1/result/chunks/1" }
+				}`))
+			case "/api/2.0/sql/statements/s1/result/chunks/1":
+				_, _ = w.Write([]byte(`{ "chunk_index": 1, "data_array": [["bar", "false"]] }`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer server.Close()
+		client, err := infinity.NewClient(context.Background(), models.InfinitySettings{})
+		require.Nil(t, err)
+		query := models.Query{RefID: "A", Type: models.QueryTypeJSON, Source: "url", Parser: models.InfinityParserBackend, ResultsAPI: models.ResultsAPIDatabricks,
+			URL: server.URL + "/api/2.0/sql/statements", URLOptions: models.URLOptions{Method: http.MethodPost, Body: `{ "statement": "SELECT 1", "warehouse_id": "w1" }`}}
+		frame, err := infinity.GetFrameForURLSources(context.Background(), query, *client, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, 2, frame.Rows())
+		name, _ := frame.FieldByName("name")
+		require.Equal(t, "bar", *name.At(1).(*string))
+		enabled, _ := frame.FieldByName("enabled")
+		require.Equal(t, false, *enabled.At(1).(*bool))
+	})
+	t.Run("should fail when the databricks statement fails", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{ "statement_id": "s1", "status": { "state": "FAILED", "error": { "message": "table not found" } } }`))
+		}))
+		defer server.Close()
+		client, err := infinity.NewClient(context.Background(), models.InfinitySettings{})
+		require.Nil(t, err)
+		query := models.Query{RefID: "A", Type: models.QueryTypeJSON, Source: "url", Parser: models.InfinityParserBackend, ResultsAPI: models.ResultsAPIDatabricks,
+			URL: server.URL + "/api/2.0/sql/statements", URLOptions: models.URLOptions{Method: http.MethodPost}}
+		_, err = infinity.GetFrameForURLSources(context.Background(), query, *client, map[string]string{})
+		require.ErrorContains(t, err, "databricks statement failed. table not found")
+	})
+	t.Run("should validate the url", func(t *testing.T) {
+		client, err := infinity.NewClient(context.Background(), models.InfinitySettings{})
+		require.Nil(t, err)
+		query := models.Query{RefID: "A", Type: models.QueryTypeJSON, Source: "url", ResultsAPI: models.ResultsAPIBigQuery, URL: "https://example.com/foo"}
+		_, err = infinity.GetFrameForURLSources(context.Background(), query, *client, map[string]string{})
+		require.ErrorContains(t, err, "bigquery url should be of the jobs api")
+	})
+}
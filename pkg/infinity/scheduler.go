@@ -0,0 +1,211 @@
+package infinity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+const (
+	minimumScheduleInterval = 10 * time.Second
+	// scheduleLeaseMargin is the time before the next run when the lease of the refreshed query expires, so the replica holding
+	// the lease is never blocked by its own lease at the next run
+	scheduleLeaseMargin = time.Second
+)
+
+// ScheduledQuery is a query refreshed in the background by the scheduler. Results are written to the cache
+// using the cache key of the query (cacheq header), so that the dashboard loads always hit a warm cache.
+type ScheduledQuery struct {
+	ID        string       `json:"id"`
+	Schedule  string       `json:"schedule"`
+	Query     models.Query `json:"query"`
+	LastRun   time.Time    `json:"lastRun,omitempty"`
+	NextRun   time.Time    `json:"nextRun,omitempty"`
+	LastError string       `json:"lastError,omitempty"`
+}
+
+type scheduledJob struct {
+	item     ScheduledQuery
+	schedule Schedule
+	stop     chan struct{}
+}
+
+type exportJob struct {
+	item     ScheduledExport
+	schedule Schedule
+	stop     chan struct{}
+}
+
+// Scheduler refreshes the registered queries and runs the scheduled exports in the background
+type Scheduler struct {
+	client  *Client
+	runner  QueryRunner
+	mu      sync.Mutex
+	jobs    map[string]*scheduledJob
+	exports map[string]*exportJob
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func NewScheduler(client *Client) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{client: client, jobs: map[string]*scheduledJob{}, exports: map[string]*exportJob{}, ctx: ctx, cancel: cancel}
+}
+
+// SetQueryRunner sets the runner of the exported queries. Without the runner, only the url queries can be exported
+func (s *Scheduler) SetQueryRunner(runner QueryRunner) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runner = runner
+}
+
+// Register validates and starts the scheduled query. Existing query with the same id will be replaced.
+func (s *Scheduler) Register(item ScheduledQuery) (ScheduledQuery, error) {
+	item.ID = strings.TrimSpace(item.ID)
+	if item.ID == "" {
+		return item, errors.New("invalid or empty scheduled query id")
+	}
+	schedule, err := ParseSchedule(item.Schedule)
+	if err != nil {
+		return item, err
+	}
+	if is, ok := schedule.(intervalSchedule); ok && is.interval < minimumScheduleInterval {
+		return item, fmt.Errorf("schedule interval should be at least %s", minimumScheduleInterval)
+	}
+	if _, _, err := getBadgerKey(item.Query.URLOptions.Headers); err != nil {
+		return item, errors.New("scheduled queries require the cacheq header to be set in the query")
+	}
+	if item.Query.Source != "url" {
+		return item, errors.New("only url queries can be scheduled")
+	}
+	item.Query = models.ApplyDefaultsToQuery(context.Background(), item.Query)
+	item.LastRun = time.Time{}
+	item.LastError = ""
+	item.NextRun = schedule.Next(time.Now())
+	job := &scheduledJob{item: item, schedule: schedule, stop: make(chan struct{})}
+	s.mu.Lock()
+	if s.ctx.Err() != nil {
+		s.mu.Unlock()
+		return item, errors.New("scheduler is stopped")
+	}
+	if existing, ok := s.jobs[item.ID]; ok {
+		close(existing.stop)
+	}
+	s.jobs[item.ID] = job
+	s.wg.Add(1)
+	s.mu.Unlock()
+	go s.run(job)
+	return item, nil
+}
+
+// List returns the registered scheduled queries sorted by id
+func (s *Scheduler) List() []ScheduledQuery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []ScheduledQuery{}
+	for _, job := range s.jobs {
+		out = append(out, job.item)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Remove stops and removes the scheduled query
+func (s *Scheduler) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return fmt.Errorf("scheduled query %s not found", id)
+	}
+	close(job.stop)
+	delete(s.jobs, id)
+	return nil
+}
+
+// Stop stops all the scheduled queries and exports. In-flight runs are cancelled and Stop waits for them to return.
+// Scheduled exports remain stored in the cache, so they are restored by the next instance of the datasource
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	s.cancel()
+	for id, job := range s.jobs {
+		close(job.stop)
+		delete(s.jobs, id)
+	}
+	for id, job := range s.exports {
+		close(job.stop)
+		delete(s.exports, id)
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *Scheduler) run(job *scheduledJob) {
+	s.loop(job.stop, func() time.Time { return job.item.NextRun }, func() {
+		err := s.refresh(job.item.ID, job.item.Query, job.schedule)
+		s.mu.Lock()
+		job.item.LastRun = time.Now()
+		job.item.LastError = ""
+		if err != nil {
+			job.item.LastError = err.Error()
+		}
+		job.item.NextRun = job.schedule.Next(time.Now())
+		s.mu.Unlock()
+	})
+}
+
+// loop calls tick at every next run until the job is stopped. next is called with the lock held
+func (s *Scheduler) loop(stop chan struct{}, next func() time.Time, tick func()) {
+	defer s.wg.Done()
+	for {
+		s.mu.Lock()
+		at := next()
+		s.mu.Unlock()
+		if at.IsZero() {
+			return
+		}
+		timer := time.NewTimer(time.Until(at))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		tick()
+	}
+}
+
+// refresh runs the query unless another replica sharing the cache backend already refreshed it in this run of the schedule.
+// The timers of the replicas don't fire at the same time, so the lease is held until the next run instead of being released
+// after the refresh. Failed refreshes release the lease, so the other replicas retry
+func (s *Scheduler) refresh(id string, query models.Query, schedule Schedule) error {
+	timeout := time.Duration(s.client.Settings.TimeoutInSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	ttl := max(time.Until(schedule.Next(time.Now()))-scheduleLeaseMargin, timeout)
+	ctx, cancel := context.WithTimeout(withCacheRefresh(s.ctx), timeout)
+	defer cancel()
+	lease, err := s.client.TryAcquireLease(ctx, "scheduled:"+id, ttl)
+	if err != nil {
+		return fmt.Errorf("error acquiring the lease of scheduled query. %w", err)
+	}
+	if lease == nil {
+		backend.Logger.Debug("scheduled query is already refreshed by another replica", "id", id)
+		return nil
+	}
+	_, _, _, err = s.client.GetResults(ctx, query, map[string]string{})
+	if err != nil {
+		backend.Logger.Error("error refreshing scheduled query", "url", query.URL, "error", err.Error())
+		lease.Release(context.Background()) //nolint
+	}
+	return err
+}
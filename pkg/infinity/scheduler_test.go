@@ -0,0 +1,118 @@
+package infinity_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestParseSchedule(t *testing.T) {
+	from := time.Date(2023, 10, 14, 10, 17, 30, 0, time.UTC) // saturday
+	tests := []struct {
+		spec    string
+		want    time.Time
+		wantErr bool
+	}{
+		{spec: "5m", want: from.Add(5 * time.Minute)},
+		{spec: "@every 1h", want: from.Add(time.Hour)},
+		{spec: "* * * * *", want: time.Date(2023, 10, 14, 10, 18, 0, 0, time.UTC)},
+		{spec: "*/15 * * * *", want: time.Date(2023, 10, 14, 10, 30, 0, 0, time.UTC)},
+		{spec: "0 9 * * *", want: time.Date(2023, 10, 15, 9, 0, 0, 0, time.UTC)},
+		{spec: "30 8 * * 1-5", want: time.Date(2023, 10, 16, 8, 30, 0, 0, time.UTC)},
+		{spec: "0 0 1 1 *", want: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 12 * * 7", want: time.Date(2023, 10, 15, 12, 0, 0, 0, time.UTC)},
+		{spec: "0,45 10 * * *", want: time.Date(2023, 10, 14, 10, 45, 0, 0, time.UTC)},
+		{spec: "", wantErr: true},
+		{spec: "-5m", wantErr: true},
+		{spec: "* * * *", wantErr: true},
+		{spec: "60 * * * *", wantErr: true},
+		{spec: "*/0 * * * *", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := infinity.ParseSchedule(tt.spec)
+			if tt.wantErr {
+				require.NotNil(t, err)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tt.want, got.Next(from))
+		})
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	cachedQuery := models.Query{Source: "url", URL: "https://foo.com", URLOptions: models.URLOptions{Headers: []models.URLOptionKeyValuePair{{Key: "cacheq", Value: "foo"}}}}
+	t.Run("should validate the scheduled queries", func(t *testing.T) {
+		s := infinity.NewScheduler(&infinity.Client{})
+		defer s.Stop()
+		_, err := s.Register(infinity.ScheduledQuery{ID: "", Schedule: "1m", Query: cachedQuery})
+		require.NotNil(t, err)
+		_, err = s.Register(infinity.ScheduledQuery{ID: "a", Schedule: "1s", Query: cachedQuery})
+		require.NotNil(t, err)
+		_, err = s.Register(infinity.ScheduledQuery{ID: "a", Schedule: "1m", Query: models.Query{Source: "url", URL: "https://foo.com"}})
+		require.NotNil(t, err)
+		_, err = s.Register(infinity.ScheduledQuery{ID: "a", Schedule: "1m", Query: models.Query{Source: "inline", URLOptions: cachedQuery.URLOptions}})
+		require.NotNil(t, err)
+		require.Equal(t, 0, len(s.List()))
+	})
+	t.Run("should register, list and remove the scheduled queries", func(t *testing.T) {
+		s := infinity.NewScheduler(&infinity.Client{})
+		defer s.Stop()
+		item, err := s.Register(infinity.ScheduledQuery{ID: "b", Schedule: "*/5 * * * *", Query: cachedQuery})
+		require.Nil(t, err)
+		require.False(t, item.NextRun.IsZero())
+		_, err = s.Register(infinity.ScheduledQuery{ID: "a", Schedule: "10m", Query: cachedQuery})
+		require.Nil(t, err)
+		list := s.List()
+		require.Equal(t, 2, len(list))
+		require.Equal(t, "a", list[0].ID)
+		require.Equal(t, "b", list[1].ID)
+		require.Nil(t, s.Remove("a"))
+		require.NotNil(t, s.Remove("a"))
+		require.Equal(t, 1, len(s.List()))
+	})
+	t.Run("should stop all the scheduled queries", func(t *testing.T) {
+		s := infinity.NewScheduler(&infinity.Client{})
+		_, err := s.Register(infinity.ScheduledQuery{ID: "a", Schedule: "10m", Query: cachedQuery})
+		require.Nil(t, err)
+		s.Stop()
+		require.Equal(t, 0, len(s.List()))
+		_, err = s.Register(infinity.ScheduledQuery{ID: "b", Schedule: "10m", Query: cachedQuery})
+		require.NotNil(t, err)
+		require.Equal(t, "scheduler is stopped", err.Error())
+	})
+	t.Run("should refresh the scheduled query once per run across the replicas", func(t *testing.T) {
+		var hits atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits.Add(1)
+			fmt.Fprintf(w, `{ "message" : "OK" }`)
+		}))
+		defer server.Close()
+		// the replicas share the leases of the cache backend
+		client, err := infinity.NewClient(context.Background(), models.InfinitySettings{UID: "scheduler-replicas"})
+		require.Nil(t, err)
+		query := cachedQuery
+		query.URL = server.URL
+		replicaA, replicaB := infinity.NewScheduler(client), infinity.NewScheduler(client)
+		defer replicaA.Stop()
+		defer replicaB.Stop()
+		_, err = replicaA.Register(infinity.ScheduledQuery{ID: "a", Schedule: "10s", Query: query})
+		require.Nil(t, err)
+		// the timer of the other replica fires after the first replica completed the refresh
+		time.Sleep(2 * time.Second)
+		_, err = replicaB.Register(infinity.ScheduledQuery{ID: "a", Schedule: "10s", Query: query})
+		require.Nil(t, err)
+		require.Eventually(t, func() bool { return !replicaB.List()[0].LastRun.IsZero() }, 15*time.Second, 100*time.Millisecond)
+		require.Equal(t, int32(1), hits.Load())
+		require.Equal(t, "", replicaA.List()[0].LastError)
+	})
+}
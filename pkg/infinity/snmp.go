@@ -0,0 +1,245 @@
+package infinity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+const defaultSNMPPort = 161
+
+var snmpAuthProtocols = map[string]gosnmp.SnmpV3AuthProtocol{
+	"MD5": gosnmp.MD5, "SHA": gosnmp.SHA, "SHA224": gosnmp.SHA224, "SHA256": gosnmp.SHA256, "SHA384": gosnmp.SHA384, "SHA512": gosnmp.SHA512,
+}
+
+var snmpPrivProtocols = map[string]gosnmp.SnmpV3PrivProtocol{
+	"DES": gosnmp.DES, "AES": gosnmp.AES, "AES192": gosnmp.AES192, "AES256": gosnmp.AES256,
+}
+
+// GetFrameForSNMP performs the snmp get or walk of the query against the target and returns the oids and the values as the rows.
+// When the allowed hosts are configured, the target must match one of them as snmp://host:port
+func GetFrameForSNMP(ctx context.Context, query models.Query, infClient Client) (*data.Frame, error) {
+	ctx, span := tracing.DefaultTracer().Start(ctx, "GetFrameForSNMP")
+	defer span.End()
+	if query.SNMPOptions == nil || strings.TrimSpace(query.SNMPOptions.Target) == "" {
+		return nil, UserError(errors.New("invalid or empty snmp target"))
+	}
+	options := *query.SNMPOptions
+	if options.Operation == "" {
+		options.Operation = models.SNMPOperationGet
+	}
+	if len(options.OIDs) == 0 {
+		return nil, UserError(errors.New("at least one oid is required for the snmp query"))
+	}
+	client, err := getSNMPClient(ctx, options.Target, infClient.Settings)
+	if err != nil {
+		return nil, err
+	}
+	if !CanAllowURL(fmt.Sprintf("snmp://%s", net.JoinHostPort(client.Target, strconv.Itoa(int(client.Port)))), infClient.Settings.AllowedHosts) {
+		return nil, UserError(fmt.Errorf("snmp target %s is not in the allowed hosts", options.Target))
+	}
+	if err := client.Connect(); err != nil {
+		return nil, DownstreamError(fmt.Errorf("error connecting to the snmp agent %s. %w", options.Target, err), 0)
+	}
+	defer client.Conn.Close()
+	variables, err := getSNMPVariables(client, options)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ContextError(ctx)
+		}
+		return nil, DownstreamError(fmt.Errorf("error performing snmp %s against %s. %w", options.Operation, options.Target, err), 0)
+	}
+	frame := getSNMPFrame(query.RefID, variables)
+	return PostProcessFrame(ctx, frame, query)
+}
+
+func getSNMPClient(ctx context.Context, target string, settings models.InfinitySettings) (*gosnmp.GoSNMP, error) {
+	host, port := target, defaultSNMPPort
+	if h, p, err := net.SplitHostPort(target); err == nil {
+		if port, err = strconv.Atoi(p); err != nil || port <= 0 || port > 65535 {
+			return nil, UserError(fmt.Errorf("invalid snmp target port %s", p))
+		}
+		host = h
+	}
+	timeout := time.Duration(settings.TimeoutInSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	client := &gosnmp.GoSNMP{
+		Context:        ctx,
+		Target:         host,
+		Port:           uint16(port),
+		Transport:      "udp",
+		Timeout:        timeout,
+		Retries:        1,
+		MaxOids:        gosnmp.MaxOids,
+		MaxRepetitions: 25,
+	}
+	snmp := settings.SNMPSettings
+	switch snmp.Version {
+	case "", "2c":
+		client.Version, client.Community = gosnmp.Version2c, snmp.Community
+	case "1":
+		client.Version, client.Community = gosnmp.Version1, snmp.Community
+	case "3":
+		usm := &gosnmp.UsmSecurityParameters{UserName: snmp.Username}
+		client.Version, client.SecurityModel, client.SecurityParameters = gosnmp.Version3, gosnmp.UserSecurityModel, usm
+		switch snmp.SecurityLevel {
+		case "noAuthNoPriv":
+			client.MsgFlags = gosnmp.NoAuthNoPriv
+		case "", "authNoPriv", "authPriv":
+			client.MsgFlags = gosnmp.AuthNoPriv
+			usm.AuthenticationPassphrase = snmp.AuthPassphrase
+			if usm.AuthenticationProtocol = snmpAuthProtocols[strings.ToUpper(snmp.AuthProtocol)]; snmp.AuthProtocol == "" {
+				usm.AuthenticationProtocol = gosnmp.SHA
+			}
+			if usm.AuthenticationProtocol == 0 {
+				return nil, UserError(fmt.Errorf("invalid snmp auth protocol %s", snmp.AuthProtocol))
+			}
+			if snmp.SecurityLevel == "authPriv" {
+				client.MsgFlags = gosnmp.AuthPriv
+				usm.PrivacyPassphrase = snmp.PrivPassphrase
+				if usm.PrivacyProtocol = snmpPrivProtocols[strings.ToUpper(snmp.PrivProtocol)]; snmp.PrivProtocol == "" {
+					usm.PrivacyProtocol = gosnmp.AES
+				}
+				if usm.PrivacyProtocol == 0 {
+					return nil, UserError(fmt.Errorf("invalid snmp privacy protocol %s", snmp.PrivProtocol))
+				}
+			}
+		default:
+			return nil, UserError(fmt.Errorf("invalid snmp security level %s", snmp.SecurityLevel))
+		}
+	default:
+		return nil, UserError(fmt.Errorf("invalid snmp version %s", snmp.Version))
+	}
+	return client, nil
+}
+
+func getSNMPVariables(client *gosnmp.GoSNMP, options models.SNMPOptions) ([]gosnmp.SnmpPDU, error) {
+	variables := []gosnmp.SnmpPDU{}
+	switch options.Operation {
+	case models.SNMPOperationGet:
+		// agents reject the requests with more than MaxOids variables
+		for start := 0; start < len(options.OIDs); start += client.MaxOids {
+			end := min(start+client.MaxOids, len(options.OIDs))
+			res, err := client.Get(options.OIDs[start:end])
+			if err != nil {
+				return nil, err
+			}
+			if res.Error != gosnmp.NoError {
+				return nil, fmt.Errorf("agent returned %s for oid %d", res.Error, res.ErrorIndex)
+			}
+			variables = append(variables, res.Variables...)
+		}
+	case models.SNMPOperationWalk:
+		for _, oid := range options.OIDs {
+			walk := client.BulkWalk
+			if client.Version == gosnmp.Version1 {
+				walk = client.Walk
+			}
+			err := walk(oid, func(pdu gosnmp.SnmpPDU) error {
+				variables = append(variables, pdu)
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, UserError(fmt.Errorf("invalid snmp operation %s", options.Operation))
+	}
+	return variables, nil
+}
+
+// getSNMPFrame returns the oid, type and the value of the variables. Value is a number field when every value is numeric
+func getSNMPFrame(refID string, variables []gosnmp.SnmpPDU) *data.Frame {
+	numeric := len(variables) > 0
+	for _, v := range variables {
+		if _, ok := getSNMPNumber(v); !ok && !isSNMPException(v.Type) {
+			numeric = false
+		}
+	}
+	oids := make([]string, 0, len(variables))
+	types := make([]string, 0, len(variables))
+	numbers := make([]*float64, 0, len(variables))
+	values := make([]*string, 0, len(variables))
+	for _, v := range variables {
+		oids = append(oids, strings.TrimPrefix(v.Name, "."))
+		types = append(types, v.Type.String())
+		if numeric {
+			var number *float64
+			if n, ok := getSNMPNumber(v); ok {
+				number = &n
+			}
+			numbers = append(numbers, number)
+			continue
+		}
+		values = append(values, getSNMPString(v))
+	}
+	frame := data.NewFrame(refID, data.NewField("oid", nil, oids), data.NewField("type", nil, types))
+	if numeric {
+		frame.Fields = append(frame.Fields, data.NewField("value", nil, numbers))
+	} else {
+		frame.Fields = append(frame.Fields, data.NewField("value", nil, values))
+	}
+	return frame
+}
+
+func isSNMPException(t gosnmp.Asn1BER) bool {
+	return t == gosnmp.NoSuchObject || t == gosnmp.NoSuchInstance || t == gosnmp.EndOfMibView || t == gosnmp.Null
+}
+
+func getSNMPNumber(v gosnmp.SnmpPDU) (float64, bool) {
+	switch v.Type {
+	case gosnmp.Integer, gosnmp.Counter32, gosnmp.Gauge32, gosnmp.TimeTicks, gosnmp.Counter64, gosnmp.Uinteger32:
+		f, _ := new(big.Float).SetInt(gosnmp.ToBigInt(v.Value)).Float64()
+		return f, true
+	case gosnmp.OpaqueFloat, gosnmp.OpaqueDouble:
+		switch n := v.Value.(type) {
+		case float32:
+			return float64(n), true
+		case float64:
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// getSNMPString returns the value as text. octet strings which are not printable, such as the mac addresses, are formatted as colon separated hex
+func getSNMPString(v gosnmp.SnmpPDU) *string {
+	if isSNMPException(v.Type) {
+		return nil
+	}
+	var out string
+	switch value := v.Value.(type) {
+	case []byte:
+		out = string(value)
+		if !utf8.Valid(value) || strings.IndexFunc(out, func(r rune) bool { return !unicode.IsPrint(r) && !unicode.IsSpace(r) }) >= 0 {
+			out = net.HardwareAddr(value).String()
+		}
+	case string:
+		out = value
+		if v.Type == gosnmp.ObjectIdentifier {
+			out = strings.TrimPrefix(value, ".")
+		}
+	default:
+		if n, ok := getSNMPNumber(v); ok {
+			out = strconv.FormatFloat(n, 'f', -1, 64)
+		} else {
+			out = fmt.Sprintf("%v", value)
+		}
+	}
+	return &out
+}
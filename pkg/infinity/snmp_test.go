@@ -0,0 +1,131 @@
+package infinity_test
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestGetFrameForSNMP(t *testing.T) {
+	target := startSNMPAgent(t, "secret", []gosnmp.SnmpPDU{
+		{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(12345)},
+		{Name: ".1.3.6.1.2.1.1.5.0", Type: gosnmp.OctetString, Value: "switch1"},
+		{Name: ".1.3.6.1.2.1.2.2.1.2.1", Type: gosnmp.OctetString, Value: "eth0"},
+		{Name: ".1.3.6.1.2.1.2.2.1.2.2", Type: gosnmp.OctetString, Value: "eth1"},
+		{Name: ".1.3.6.1.2.1.2.2.1.6.1", Type: gosnmp.OctetString, Value: []byte{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e}},
+		{Name: ".1.3.6.1.2.1.2.2.1.10.1", Type: gosnmp.Counter32, Value: uint32(100)},
+		{Name: ".1.3.6.1.2.1.2.2.1.10.2", Type: gosnmp.Counter32, Value: uint32(200)},
+		{Name: ".1.3.6.1.2.1.2.2.1.10.10", Type: gosnmp.Counter32, Value: uint32(300)},
+	})
+	client := &infinity.Client{Settings: models.InfinitySettings{TimeoutInSeconds: 2, SNMPSettings: models.SNMPSettings{Community: "secret"}}}
+	t.Run("get", func(t *testing.T) {
+		query := models.Query{RefID: "A", Source: "snmp", SNMPOptions: &models.SNMPOptions{Target: target, OIDs: []string{".1.3.6.1.2.1.1.5.0", ".1.3.6.1.2.1.1.99.0"}}}
+		frame, err := infinity.GetFrameForSNMP(context.Background(), query, *client)
+		require.Nil(t, err)
+		require.Equal(t, 2, frame.Rows())
+		oid, _ := frame.FieldByName("oid")
+		require.Equal(t, "1.3.6.1.2.1.1.5.0", oid.At(0))
+		value, _ := frame.FieldByName("value")
+		require.Equal(t, "switch1", *value.At(0).(*string))
+		require.Nil(t, value.At(1))
+		valueType, _ := frame.FieldByName("type")
+		require.Equal(t, "NoSuchObject", valueType.At(1))
+	})
+	t.Run("walk numeric values", func(t *testing.T) {
+		query := models.Query{RefID: "A", Source: "snmp", SNMPOptions: &models.SNMPOptions{Target: target, Operation: models.SNMPOperationWalk, OIDs: []string{".1.3.6.1.2.1.2.2.1.10"}}}
+		frame, err := infinity.GetFrameForSNMP(context.Background(), query, *client)
+		require.Nil(t, err)
+		require.Equal(t, 3, frame.Rows())
+		value, _ := frame.FieldByName("value")
+		require.Equal(t, []float64{100, 200, 300}, []float64{*value.At(0).(*float64), *value.At(1).(*float64), *value.At(2).(*float64)})
+	})
+	t.Run("walk mac address", func(t *testing.T) {
+		query := models.Query{RefID: "A", Source: "snmp", SNMPOptions: &models.SNMPOptions{Target: target, Operation: models.SNMPOperationWalk, OIDs: []string{".1.3.6.1.2.1.2.2.1.6"}}}
+		frame, err := infinity.GetFrameForSNMP(context.Background(), query, *client)
+		require.Nil(t, err)
+		value, _ := frame.FieldByName("value")
+		require.Equal(t, "00:1a:2b:3c:4d:5e", *value.At(0).(*string))
+	})
+	t.Run("target outside the allowed hosts", func(t *testing.T) {
+		client := &infinity.Client{Settings: models.InfinitySettings{AllowedHosts: []string{"snmp://switch1"}}}
+		query := models.Query{RefID: "A", Source: "snmp", SNMPOptions: &models.SNMPOptions{Target: target, OIDs: []string{".1.3.6.1.2.1.1.5.0"}}}
+		_, err := infinity.GetFrameForSNMP(context.Background(), query, *client)
+		require.ErrorContains(t, err, "not in the allowed hosts")
+	})
+	t.Run("invalid v3 auth protocol", func(t *testing.T) {
+		client := &infinity.Client{Settings: models.InfinitySettings{SNMPSettings: models.SNMPSettings{Version: "3", Username: "user", AuthProtocol: "SHA1024"}}}
+		query := models.Query{RefID: "A", Source: "snmp", SNMPOptions: &models.SNMPOptions{Target: target, OIDs: []string{".1.3.6.1.2.1.1.5.0"}}}
+		_, err := infinity.GetFrameForSNMP(context.Background(), query, *client)
+		require.ErrorContains(t, err, "invalid snmp auth protocol SHA1024")
+	})
+}
+
+// startSNMPAgent answers the snmp v2c get and getbulk requests with the community from the sorted variables
+func startSNMPAgent(t *testing.T, community string, variables []gosnmp.SnmpPDU) string {
+	t.Helper()
+	sort.Slice(variables, func(i, j int) bool { return compareOIDs(variables[i].Name, variables[j].Name) < 0 })
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		decoder := &gosnmp.GoSNMP{Version: gosnmp.Version2c}
+		buf := make([]byte, 65535)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			req, err := decoder.SnmpDecodePacket(buf[:n])
+			if err != nil || req.Community != community {
+				continue
+			}
+			res := &gosnmp.SnmpPacket{Version: gosnmp.Version2c, Community: community, PDUType: gosnmp.GetResponse, RequestID: req.RequestID}
+			for _, v := range req.Variables {
+				switch req.PDUType {
+				case gosnmp.GetRequest:
+					pdu := gosnmp.SnmpPDU{Name: v.Name, Type: gosnmp.NoSuchObject}
+					for _, variable := range variables {
+						if variable.Name == v.Name {
+							pdu = variable
+						}
+					}
+					res.Variables = append(res.Variables, pdu)
+				case gosnmp.GetBulkRequest:
+					for _, variable := range variables {
+						if compareOIDs(variable.Name, v.Name) > 0 && len(res.Variables) < 2 {
+							res.Variables = append(res.Variables, variable)
+						}
+					}
+					if len(res.Variables) == 0 {
+						res.Variables = append(res.Variables, gosnmp.SnmpPDU{Name: v.Name, Type: gosnmp.EndOfMibView})
+					}
+				}
+			}
+			out, err := res.MarshalMsg()
+			if err == nil {
+				_, _ = conn.WriteTo(out, addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func compareOIDs(a, b string) int {
+	x, y := strings.Split(strings.Trim(a, "."), "."), strings.Split(strings.Trim(b, "."), ".")
+	for i := 0; i < len(x) && i < len(y); i++ {
+		m, _ := strconv.Atoi(x[i])
+		n, _ := strconv.Atoi(y[i])
+		if m != n {
+			return m - n
+		}
+	}
+	return len(x) - len(y)
+}
@@ -0,0 +1,214 @@
+package infinity
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+const (
+	soap11Namespace = "http://schemas.xmlsoap.org/soap/envelope/"
+	soap12Namespace = "http://www.w3.org/2003/05/soap-envelope"
+)
+
+var ErrSOAPFault = errors.New("SOAP fault")
+
+// GetSOAPEnvelope wraps the body in the SOAP envelope. Bodies which are already SOAP envelopes are returned as is
+func GetSOAPEnvelope(body string, options *models.SOAPOptions) string {
+	trimmed := strings.TrimSpace(body)
+	if root, err := parseXMLNodes([]byte(trimmed)); err == nil && len(root.children) == 1 && root.children[0].name.Local == "Envelope" {
+		return trimmed
+	}
+	namespace := soap11Namespace
+	if options != nil && options.Version == "1.2" {
+		namespace = soap12Namespace
+	}
+	return fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?><soap:Envelope xmlns:soap="%s"><soap:Body>%s</soap:Body></soap:Envelope>`, namespace, trimmed)
+}
+
+// getSOAPContentType returns the content type of the SOAP request. SOAP 1.2 sends the action as the content type parameter
+func getSOAPContentType(options *models.SOAPOptions) string {
+	if options != nil && options.Version == "1.2" {
+		if options.Action != "" {
+			return fmt.Sprintf(`application/soap+xml; charset=utf-8; action="%s"`, options.Action)
+		}
+		return "application/soap+xml; charset=utf-8"
+	}
+	return "text/xml; charset=utf-8"
+}
+
+// ApplySOAPHeaders sets the content type and the SOAPAction header (SOAP 1.1) of the SOAP requests
+func ApplySOAPHeaders(query models.Query, req *http.Request) *http.Request {
+	if query.URLOptions.BodyType != "soap" {
+		return req
+	}
+	req.Header.Set(headerKeyContentType, getSOAPContentType(query.SOAPOptions))
+	if query.SOAPOptions == nil {
+		req.Header.Set("SOAPAction", `""`)
+		return req
+	}
+	if query.SOAPOptions.Version != "1.2" {
+		req.Header.Set("SOAPAction", fmt.Sprintf(`"%s"`, query.SOAPOptions.Action))
+	}
+	return req
+}
+
+// ExtractSOAPResult returns the SOAP fault as error and extracts the result using the result xpath of the query
+func ExtractSOAPResult(body []byte, query models.Query) ([]byte, error) {
+	if query.URLOptions.BodyType != "soap" {
+		return body, nil
+	}
+	root, err := parseXMLNodes(body)
+	if err != nil {
+		return body, fmt.Errorf("invalid SOAP response. %w", err)
+	}
+	if fault := getSOAPFault(root); fault != nil {
+		return body, fault
+	}
+	if query.SOAPOptions == nil || strings.TrimSpace(query.SOAPOptions.ResultXPath) == "" {
+		return body, nil
+	}
+	return selectXPath(body, root, query.SOAPOptions.ResultXPath)
+}
+
+func getSOAPFault(root *xmlNode) error {
+	faults := root.find([]xpathStep{{name: "Envelope"}, {name: "Body"}, {name: "Fault"}})
+	if len(faults) == 0 {
+		return nil
+	}
+	message := ""
+	for _, name := range []string{"faultstring", "Text"} {
+		if nodes := faults[0].find([]xpathStep{{name: name, descendant: true}}); len(nodes) > 0 {
+			message = strings.TrimSpace(nodes[0].text)
+			break
+		}
+	}
+	if message == "" {
+		message = "unknown error"
+	}
+	return fmt.Errorf("%w. %s", ErrSOAPFault, message)
+}
+
+// selectXPath returns the elements matching the xpath. Leaf elements return their text, which allows the results
+// returned as the escaped XML or JSON string. Multiple matches are wrapped in the results element
+func selectXPath(body []byte, root *xmlNode, path string) ([]byte, error) {
+	steps, err := parseXPath(path)
+	if err != nil {
+		return body, err
+	}
+	nodes := root.find(steps)
+	if len(nodes) == 0 {
+		return body, fmt.Errorf("no element found for the xpath %s", path)
+	}
+	if len(nodes) == 1 && len(nodes[0].children) == 0 {
+		return []byte(strings.TrimSpace(nodes[0].text)), nil
+	}
+	if len(nodes) == 1 {
+		return body[nodes[0].start:nodes[0].end], nil
+	}
+	out := bytes.NewBufferString("<results>")
+	for _, n := range nodes {
+		out.Write(body[n.start:n.end])
+	}
+	out.WriteString("</results>")
+	return out.Bytes(), nil
+}
+
+type xmlNode struct {
+	name     xml.Name
+	children []*xmlNode
+	text     string
+	start    int64
+	end      int64
+}
+
+func parseXMLNodes(body []byte) (*xmlNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	root := &xmlNode{}
+	stack := []*xmlNode{root}
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		current := stack[len(stack)-1]
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: t.Name, start: offset}
+			current.children = append(current.children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				current.end = decoder.InputOffset()
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			current.text += string(t)
+		}
+	}
+	if len(stack) > 1 {
+		return nil, errors.New("unexpected end of the xml")
+	}
+	return root, nil
+}
+
+type xpathStep struct {
+	name       string
+	descendant bool
+}
+
+// parseXPath parses the subset of xpath with the absolute (/a/b) and descendant (//b) steps and * wildcard.
+// Namespace prefixes are ignored and the elements are matched by the local name
+func parseXPath(path string) ([]xpathStep, error) {
+	path = strings.TrimSpace(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "//" + path
+	}
+	steps := []xpathStep{}
+	descendant := false
+	for _, part := range strings.Split(path, "/")[1:] {
+		if part == "" {
+			descendant = true
+			continue
+		}
+		if strings.ContainsAny(part, "[]()@=") {
+			return nil, fmt.Errorf("unsupported xpath %s. only the element names, * and // are supported", path)
+		}
+		if idx := strings.Index(part, ":"); idx != -1 {
+			part = part[idx+1:]
+		}
+		steps = append(steps, xpathStep{name: part, descendant: descendant})
+		descendant = false
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("invalid xpath %s", path)
+	}
+	return steps, nil
+}
+
+func (n *xmlNode) find(steps []xpathStep) []*xmlNode {
+	if len(steps) == 0 {
+		return []*xmlNode{n}
+	}
+	out := []*xmlNode{}
+	for _, child := range n.children {
+		if steps[0].name == "*" || steps[0].name == child.name.Local {
+			out = append(out, child.find(steps[1:])...)
+		}
+		if steps[0].descendant {
+			out = append(out, child.find(steps)...)
+		}
+	}
+	return out
+}
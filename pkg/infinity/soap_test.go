@@ -0,0 +1,79 @@
+package infinity_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestSOAP(t *testing.T) {
+	var gotBody, gotAction, gotContentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody, gotAction, gotContentType = string(b), r.Header.Get("SOAPAction"), r.Header.Get("Content-Type")
+		if r.URL.Path == "/fault" {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><soap:Fault><faultcode>soap:Client</faultcode><faultstring>Invalid city</faultstring></soap:Fault></soap:Body></soap:Envelope>`))
+			return
+		}
+		_, _ = w.Write([]byte(`<?xml version="1.0"?><soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><m:GetUsersResponse xmlns:m="urn:users"><m:GetUsersResult><user><name>foo</name><age>10</age></user><user><name>bar</name><age>20</age></user></m:GetUsersResult></m:GetUsersResponse></soap:Body></soap:Envelope>`))
+	}))
+	defer server.Close()
+	client, err := infinity.NewClient(context.Background(), models.InfinitySettings{})
+	require.Nil(t, err)
+	getQuery := func(url string) models.Query {
+		return models.ApplyDefaultsToQuery(context.Background(), models.Query{
+			RefID: "A", Type: models.QueryTypeXML, Parser: models.InfinityParserBackend, Source: "url", URL: url, RootSelector: "GetUsersResult.user",
+			URLOptions:  models.URLOptions{BodyType: "soap", Body: `<m:GetUsers xmlns:m="urn:users"><m:City>London</m:City></m:GetUsers>`},
+			SOAPOptions: &models.SOAPOptions{Action: "urn:users/GetUsers", ResultXPath: "//GetUsersResult"},
+		})
+	}
+	t.Run("should wrap the body in the envelope and extract the result", func(t *testing.T) {
+		frame, err := infinity.GetFrameForURLSources(context.Background(), getQuery(server.URL), *client, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, `"urn:users/GetUsers"`, gotAction)
+		require.Equal(t, "text/xml; charset=utf-8", gotContentType)
+		require.Equal(t, `<?xml version="1.0" encoding="utf-8"?><soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><m:GetUsers xmlns:m="urn:users"><m:City>London</m:City></m:GetUsers></soap:Body></soap:Envelope>`, gotBody)
+	})
+	t.Run("should return the SOAP fault as error", func(t *testing.T) {
+		_, err := infinity.GetFrameForURLSources(context.Background(), getQuery(server.URL+"/fault"), *client, map[string]string{})
+		require.ErrorContains(t, err, "SOAP fault. Invalid city")
+	})
+}
+
+func TestExtractSOAPResult(t *testing.T) {
+	body := `<Envelope><Body><Response><Result>{"a":1}</Result><Item><id>1</id></Item><Item><id>2</id></Item></Response></Body></Envelope>`
+	query := models.Query{URLOptions: models.URLOptions{BodyType: "soap"}, SOAPOptions: &models.SOAPOptions{}}
+	tests := []struct {
+		xpath   string
+		want    string
+		wantErr string
+	}{
+		{xpath: "", want: body},
+		{xpath: "//Result", want: `{"a":1}`},
+		{xpath: "/Envelope/Body/*/Result", want: `{"a":1}`},
+		{xpath: "//soap:Item", want: `<results><Item><id>1</id></Item><Item><id>2</id></Item></results>`},
+		{xpath: "//Response/Item/id", want: `<results><id>1</id><id>2</id></results>`},
+		{xpath: "//Foo", wantErr: "no element found for the xpath //Foo"},
+		{xpath: "//Item[1]", wantErr: "unsupported xpath"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.xpath, func(t *testing.T) {
+			query.SOAPOptions.ResultXPath = tt.xpath
+			got, err := infinity.ExtractSOAPResult([]byte(body), query)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tt.want, string(got))
+		})
+	}
+}
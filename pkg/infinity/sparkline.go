@@ -0,0 +1,78 @@
+package infinity
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// GetFrameWithSparklineField converts a delimiter separated numeric string field (for example "1,4,2,8")
+// into a per-row numeric array field, so that the values can be visualized as sparklines.
+// When alias is empty, the source field is replaced. Otherwise a new field is appended with the alias as name.
+func GetFrameWithSparklineField(frame *data.Frame, fieldName string, delimiter string, alias string) (*data.Frame, error) {
+	if frame == nil {
+		return frame, nil
+	}
+	if strings.TrimSpace(fieldName) == "" {
+		return frame, fmt.Errorf("invalid or empty sparkline field name")
+	}
+	if delimiter == "" {
+		delimiter = ","
+	}
+	fieldIndex := -1
+	for i, field := range frame.Fields {
+		if field.Name == fieldName {
+			fieldIndex = i
+			break
+		}
+	}
+	if fieldIndex < 0 {
+		return frame, fmt.Errorf("sparkline field %s not found", fieldName)
+	}
+	sourceField := frame.Fields[fieldIndex]
+	sparklineField := data.NewFieldFromFieldType(data.FieldTypeNullableJSON, sourceField.Len())
+	sparklineField.Name = fieldName
+	if alias != "" {
+		sparklineField.Name = alias
+	}
+	sparklineField.Labels = sourceField.Labels
+	for i := 0; i < sourceField.Len(); i++ {
+		value, ok := sourceField.ConcreteAt(i)
+		if !ok {
+			continue
+		}
+		input, ok := value.(string)
+		if !ok {
+			continue
+		}
+		values, err := getSparklineValues(input, delimiter)
+		if err != nil {
+			return frame, err
+		}
+		sparklineField.Set(i, &values)
+	}
+	if alias == "" {
+		frame.Fields[fieldIndex] = sparklineField
+		return frame, nil
+	}
+	frame.Fields = append(frame.Fields, sparklineField)
+	return frame, nil
+}
+
+func getSparklineValues(input string, delimiter string) (json.RawMessage, error) {
+	values := []*float64{}
+	if strings.TrimSpace(input) != "" {
+		for _, item := range strings.Split(input, delimiter) {
+			item = strings.TrimSpace(item)
+			if v, err := strconv.ParseFloat(item, 64); err == nil {
+				values = append(values, &v)
+				continue
+			}
+			values = append(values, nil)
+		}
+	}
+	return json.Marshal(values)
+}
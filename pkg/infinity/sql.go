@@ -0,0 +1,196 @@
+package infinity
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+	"github.com/yesoreyeram/grafana-plugins/lib/go/framesql"
+	"modernc.org/sqlite"
+)
+
+// sqliteLimitAttached is SQLITE_LIMIT_ATTACHED. 0 prevents the sql from attaching the database files of the plugin host
+const sqliteLimitAttached = 7
+
+// ApplySQL runs the SQL of the query against the results of the other queries and the materialized datasets.
+// Results of the queries are loaded as the tables named by their ref ids, datasets are loaded as the tables named by their names
+func ApplySQL(ctx context.Context, query models.Query, input *backend.QueryDataResponse, infClient Client) backend.DataResponse {
+	ctx, span := tracing.DefaultTracer().Start(ctx, "ApplySQL")
+	defer span.End()
+	var err error
+	tables := map[string]*data.Frame{}
+	for refID, res := range input.Responses {
+		if res.Error != nil {
+			err = errors.Join(err, res.Error)
+			continue
+		}
+		for i, frame := range res.Frames {
+			name := refID
+			if i > 0 {
+				name = fmt.Sprintf("%s_%d", refID, i)
+			}
+			tables[name] = frame
+		}
+	}
+	if err != nil {
+		return backend.ErrDataResponseWithSource(backend.StatusBadRequest, backend.ErrorSourceDownstream, "unable to apply sql due to existing errors: "+err.Error())
+	}
+	for _, name := range query.SQLDatasets {
+		frame, err := GetFrameForDataset(ctx, models.Query{Source: DatasetSourcePrefix + name}, infClient)
+		if err != nil {
+			return backend.ErrDataResponseWithSource(backend.StatusBadRequest, backend.ErrorSourceDownstream, err.Error())
+		}
+		tables[strings.TrimSpace(name)] = frame
+	}
+	frame, err := ExecuteSQL(ctx, query.SQLQuery, tables)
+	if err != nil {
+		span.RecordError(err)
+		return backend.ErrDataResponseWithSource(backend.StatusBadRequest, backend.ErrorSourcePlugin, fmt.Sprintf("error executing sql. %s", err.Error()))
+	}
+	frame.Name = query.RefID
+	frame.Meta = &data.FrameMeta{ExecutedQueryString: query.SQLQuery}
+	return backend.DataResponse{Frames: data.Frames{frame}}
+}
+
+// ExecuteSQL loads the frames into an in-memory SQLite database as the tables named by the map keys and runs the query.
+// Only the read only statements are allowed and the database is switched to the query only mode before running the query
+func ExecuteSQL(ctx context.Context, query string, tables map[string]*data.Frame) (*data.Frame, error) {
+	query, err := getReadOnlyStatement(query)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	// every connection of sqlite in-memory database is a separate database
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if _, err := sqlite.Limit(conn, sqliteLimitAttached, 0); err != nil {
+		return nil, err
+	}
+	for name, frame := range tables {
+		if err := loadFrameIntoSQL(ctx, conn, name, frame); err != nil {
+			return nil, fmt.Errorf("error loading table %s. %w", name, err)
+		}
+	}
+	// the statements such as WITH ... INSERT pass the read only check
+	if _, err := conn.ExecContext(ctx, "PRAGMA query_only = 1"); err != nil {
+		return nil, err
+	}
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	frame, _, err := getSQLFrame(rows, 0)
+	return frame, err
+}
+
+// getSQLFrame converts the rows into the frame. Rows beyond the maxRows are not read and the results are reported as truncated. 0 reads all the rows
+func getSQLFrame(rows *sql.Rows, maxRows int) (*data.Frame, bool, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, false, err
+	}
+	values := make([][]any, len(columns))
+	count, truncated := 0, false
+	for rows.Next() {
+		if maxRows > 0 && count >= maxRows {
+			truncated = true
+			break
+		}
+		count++
+		row := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range row {
+			pointers[i] = &row[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, false, err
+		}
+		for i, v := range row {
+			if b, ok := v.([]byte); ok {
+				v = string(b)
+			}
+			values[i] = append(values[i], v)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	frame := data.NewFrame("")
+	for i, column := range columns {
+		frame.Fields = append(frame.Fields, framesql.ConvertFieldValuesToField(values[i], column))
+	}
+	return frame, truncated, nil
+}
+
+func loadFrameIntoSQL(ctx context.Context, db *sql.Conn, name string, frame *data.Frame) error {
+	if frame == nil || len(frame.Fields) == 0 {
+		return nil
+	}
+	columns := make([]string, len(frame.Fields))
+	placeholders := make([]string, len(frame.Fields))
+	for i, field := range frame.Fields {
+		columns[i] = quoteSQLIdentifier(field.Name) + " " + sqlColumnType(field.Type())
+		placeholders[i] = "?"
+	}
+	if _, err := db.ExecContext(ctx, fmt.Sprintf("CREATE TABLE %s (%s)", quoteSQLIdentifier(name), strings.Join(columns, ", "))); err != nil {
+		return err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf("INSERT INTO %s VALUES (%s)", quoteSQLIdentifier(name), strings.Join(placeholders, ", ")))
+	if err != nil {
+		tx.Rollback() //nolint
+		return err
+	}
+	defer stmt.Close()
+	for row := 0; row < frame.Rows(); row++ {
+		args := make([]any, len(frame.Fields))
+		for i, field := range frame.Fields {
+			if v, ok := field.ConcreteAt(row); ok {
+				args[i] = v
+				if raw, ok := v.(json.RawMessage); ok {
+					args[i] = string(raw)
+				}
+			}
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			tx.Rollback() //nolint
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func sqlColumnType(fieldType data.FieldType) string {
+	switch {
+	case fieldType.Time():
+		return "TIMESTAMP"
+	case fieldType.NonNullableType() == data.FieldTypeBool:
+		return "BOOLEAN"
+	case fieldType.Numeric():
+		return "REAL"
+	default:
+		return "TEXT"
+	}
+}
+
+func quoteSQLIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
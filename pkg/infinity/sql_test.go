@@ -0,0 +1,79 @@
+package infinity_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestExecuteSQL(t *testing.T) {
+	ts := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	users := data.NewFrame("users",
+		data.NewField("name", nil, []string{"foo", "bar", "baz"}),
+		data.NewField("team", nil, []*string{toSP("a"), toSP("b"), nil}),
+		data.NewField("joined", nil, []time.Time{ts, ts.Add(time.Hour), ts.Add(2 * time.Hour)}),
+	)
+	teams := data.NewFrame("teams",
+		data.NewField("id", nil, []string{"a", "b"}),
+		data.NewField("budget", nil, []float64{10, 20.5}),
+	)
+	t.Run("join", func(t *testing.T) {
+		frame, err := infinity.ExecuteSQL(context.Background(), `SELECT u.name, t.budget, u.joined FROM A u JOIN "my teams" t ON u.team = t.id ORDER BY t.budget DESC`, map[string]*data.Frame{"A": users, "my teams": teams})
+		require.Nil(t, err)
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, "bar", *frame.Fields[0].At(0).(*string))
+		require.Equal(t, 20.5, *frame.Fields[1].At(0).(*float64))
+		require.Equal(t, ts.Add(time.Hour), frame.Fields[2].At(0).(*time.Time).UTC())
+	})
+	t.Run("window functions", func(t *testing.T) {
+		frame, err := infinity.ExecuteSQL(context.Background(), `SELECT id, SUM(budget) OVER (ORDER BY id) AS running FROM teams`, map[string]*data.Frame{"teams": teams})
+		require.Nil(t, err)
+		require.Equal(t, 30.5, *frame.Fields[1].At(1).(*float64))
+	})
+	t.Run("invalid query", func(t *testing.T) {
+		_, err := infinity.ExecuteSQL(context.Background(), `SELECT * FROM missing`, map[string]*data.Frame{})
+		require.NotNil(t, err)
+		_, err = infinity.ExecuteSQL(context.Background(), ``, map[string]*data.Frame{})
+		require.NotNil(t, err)
+	})
+	t.Run("statements accessing the local files", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "out.db")
+		for _, query := range []string{
+			"VACUUM INTO '" + file + "'",
+			"VACUUM/**/INTO '" + file + "'",
+			"ATTACH DATABASE '" + file + "' AS foo",
+			"SELECT 1; ATTACH DATABASE '" + file + "' AS foo",
+			"PRAGMA table_info(teams)",
+			"WITH t AS (SELECT 1) INSERT INTO teams SELECT 'c', 1 FROM t",
+		} {
+			_, err := infinity.ExecuteSQL(context.Background(), query, map[string]*data.Frame{"teams": teams})
+			require.NotNil(t, err, query)
+			_, err = os.Stat(file)
+			require.True(t, os.IsNotExist(err), query)
+		}
+	})
+}
+
+func TestApplySQL(t *testing.T) {
+	ctx := context.Background()
+	client, err := infinity.NewClient(ctx, models.InfinitySettings{OrgID: 1, UID: "sql"})
+	require.Nil(t, err)
+	require.Nil(t, infinity.SaveDataset(ctx, *client, "teams", models.Query{}, data.NewFrame("teams", data.NewField("id", nil, []string{"a"})), 0))
+	input := backend.NewQueryDataResponse()
+	input.Responses["A"] = backend.DataResponse{Frames: data.Frames{data.NewFrame("users", data.NewField("team", nil, []string{"a", "b"}))}}
+	res := infinity.ApplySQL(ctx, models.Query{RefID: "B", SQLQuery: "SELECT team FROM A WHERE team IN (SELECT id FROM teams)", SQLDatasets: []string{"teams"}}, input, *client)
+	require.Nil(t, res.Error)
+	require.Equal(t, "B", res.Frames[0].Name)
+	require.Equal(t, 1, res.Frames[0].Rows())
+	input.Responses["C"] = backend.DataResponse{Error: context.Canceled}
+	res = infinity.ApplySQL(ctx, models.Query{RefID: "B", SQLQuery: "SELECT * FROM A"}, input, *client)
+	require.NotNil(t, res.Error)
+}
@@ -0,0 +1,122 @@
+package infinity
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// Timings is the breakdown of the time spent by a query. Durations are summed across the pages of paginated queries
+type Timings struct {
+	DNS       time.Duration `json:"dns"`
+	Connect   time.Duration `json:"connect"`
+	TLS       time.Duration `json:"tls"`
+	TTFB      time.Duration `json:"ttfb"`
+	Download  time.Duration `json:"download"`
+	Parse     time.Duration `json:"parse"`
+	Transform time.Duration `json:"transform"`
+	Cache     time.Duration `json:"cache"`
+	Total     time.Duration `json:"total"`
+}
+
+type timingsRecorder struct {
+	mu      sync.Mutex
+	start   time.Time
+	timings Timings
+}
+
+type timingsContextKey struct{}
+
+// WithTimings returns the context which collects the timings of the query
+func WithTimings(ctx context.Context) context.Context {
+	return context.WithValue(ctx, timingsContextKey{}, &timingsRecorder{start: time.Now()})
+}
+
+// GetTimings returns the timings collected so far. Returns nil when the context doesn't collect the timings
+func GetTimings(ctx context.Context) *Timings {
+	r, ok := ctx.Value(timingsContextKey{}).(*timingsRecorder)
+	if !ok {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	timings := r.timings
+	timings.Total = time.Since(r.start)
+	return &timings
+}
+
+// recordTiming adds the duration to the timing picked by the fn. No-op when the context doesn't collect the timings
+func recordTiming(ctx context.Context, fn func(t *Timings) *time.Duration, d time.Duration) {
+	r, ok := ctx.Value(timingsContextKey{}).(*timingsRecorder)
+	if !ok {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*fn(&r.timings) += d
+}
+
+// trackTiming records the time elapsed since the start when the returned func is called
+func trackTiming(ctx context.Context, fn func(t *Timings) *time.Duration) func() {
+	start := time.Now()
+	return func() {
+		recordTiming(ctx, fn, time.Since(start))
+	}
+}
+
+func dnsTiming(t *Timings) *time.Duration       { return &t.DNS }
+func connectTiming(t *Timings) *time.Duration   { return &t.Connect }
+func tlsTiming(t *Timings) *time.Duration       { return &t.TLS }
+func ttfbTiming(t *Timings) *time.Duration      { return &t.TTFB }
+func downloadTiming(t *Timings) *time.Duration  { return &t.Download }
+func parseTiming(t *Timings) *time.Duration     { return &t.Parse }
+func transformTiming(t *Timings) *time.Duration { return &t.Transform }
+func cacheTiming(t *Timings) *time.Duration     { return &t.Cache }
+
+// withClientTrace attaches the http trace which records the network timings of the request
+func withClientTrace(ctx context.Context, start time.Time) context.Context {
+	if _, ok := ctx.Value(timingsContextKey{}).(*timingsRecorder); !ok {
+		return ctx
+	}
+	// hooks can be called from the dialer goroutines, for example when dialing ipv4 and ipv6 addresses in parallel
+	var mu sync.Mutex
+	starts := map[string]time.Time{}
+	begin := func(key string) {
+		mu.Lock()
+		defer mu.Unlock()
+		starts[key] = time.Now()
+	}
+	end := func(key string, fn func(t *Timings) *time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		if start, ok := starts[key]; ok {
+			recordTiming(ctx, fn, time.Since(start))
+			delete(starts, key)
+		}
+	}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { begin("dns") },
+		DNSDone:           func(httptrace.DNSDoneInfo) { end("dns", dnsTiming) },
+		ConnectStart:      func(network, addr string) { begin("connect:" + addr) },
+		ConnectDone:       func(network, addr string, err error) { end("connect:"+addr, connectTiming) },
+		TLSHandshakeStart: func() { begin("tls") },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { end("tls", tlsTiming) },
+		GotFirstResponseByte: func() {
+			recordTiming(ctx, ttfbTiming, time.Since(start))
+		},
+	})
+}
+
+// ApplyTimingsToFrame copies the collected timings into the custom meta of the frame
+func ApplyTimingsToFrame(ctx context.Context, frame *data.Frame) {
+	if frame == nil || frame.Meta == nil {
+		return
+	}
+	if customMeta, ok := frame.Meta.Custom.(*CustomMeta); ok {
+		customMeta.Timings = GetTimings(ctx)
+	}
+}
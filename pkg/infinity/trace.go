@@ -0,0 +1,198 @@
+package infinity
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+type traceColumn struct {
+	name     string
+	selector func(o models.TraceOptions) string
+	aliases  []string
+}
+
+// traceColumns are the columns of the grafana trace frame along with the default column names looked up in the results
+var traceColumns = []traceColumn{
+	{name: "traceID", selector: func(o models.TraceOptions) string { return o.TraceID }, aliases: []string{"traceid", "trace_id", "trace"}},
+	{name: "spanID", selector: func(o models.TraceOptions) string { return o.SpanID }, aliases: []string{"spanid", "span_id", "id"}},
+	{name: "parentSpanID", selector: func(o models.TraceOptions) string { return o.ParentSpanID }, aliases: []string{"parentspanid", "parent_span_id", "parentid", "parent_id", "parent"}},
+	{name: "operationName", selector: func(o models.TraceOptions) string { return o.OperationName }, aliases: []string{"operationname", "operation_name", "operation", "name"}},
+	{name: "serviceName", selector: func(o models.TraceOptions) string { return o.ServiceName }, aliases: []string{"servicename", "service_name", "service"}},
+	{name: "startTime", selector: func(o models.TraceOptions) string { return o.StartTime }, aliases: []string{"starttime", "start_time", "start", "timestamp"}},
+	{name: "duration", selector: func(o models.TraceOptions) string { return o.Duration }, aliases: []string{"duration", "duration_ms"}},
+}
+
+// traceFrameColumns are passed through as is, as they are already in the trace frame shape
+var traceFrameColumns = map[string]bool{"serviceTags": true, "tags": true, "logs": true, "references": true, "warnings": true, "stackTraces": true, "kind": true, "statusCode": true, "statusMessage": true}
+
+// toTraceFrame maps the columns of the results into the trace frame expected by the traces and node graph panels.
+// Columns not mapped to the trace fields become the span tags. When no mapping is configured and the results doesn't
+// have trace and span ids, the frame is returned as is
+func toTraceFrame(frame *data.Frame, traceOptions *models.TraceOptions) (*data.Frame, error) {
+	options := models.TraceOptions{}
+	if traceOptions != nil {
+		options = *traceOptions
+	}
+	mapped := map[string]*data.Field{}
+	used := map[*data.Field]bool{}
+	for _, column := range traceColumns {
+		if field := findTraceField(frame, column.selector(options), column.aliases); field != nil && !used[field] {
+			mapped[column.name] = field
+			used[field] = true
+		}
+	}
+	if mapped["traceID"] == nil || mapped["spanID"] == nil {
+		if options == (models.TraceOptions{}) {
+			return frame, nil
+		}
+		return frame, fmt.Errorf("trace format requires trace id and span id fields. found %s", describeFields(frame))
+	}
+	rows := frame.Rows()
+	out := data.NewFrame(frame.Name)
+	out.Meta = frame.Meta
+	for _, column := range traceColumns {
+		field := mapped[column.name]
+		switch column.name {
+		case "startTime":
+			values := make([]float64, rows)
+			for i := 0; field != nil && i < rows; i++ {
+				values[i] = traceStartTime(field, i)
+			}
+			out.Fields = append(out.Fields, data.NewField(column.name, nil, values))
+		case "duration":
+			values := make([]float64, rows)
+			for i := 0; field != nil && i < rows; i++ {
+				values[i] = traceDuration(field, i, options.DurationUnit)
+			}
+			out.Fields = append(out.Fields, data.NewField(column.name, nil, values))
+		default:
+			values := make([]string, rows)
+			for i := 0; field != nil && i < rows; i++ {
+				values[i] = traceString(field, i)
+			}
+			out.Fields = append(out.Fields, data.NewField(column.name, nil, values))
+		}
+	}
+	tagFields := []*data.Field{}
+	for _, field := range frame.Fields {
+		if used[field] {
+			continue
+		}
+		if traceFrameColumns[field.Name] {
+			out.Fields = append(out.Fields, field)
+			continue
+		}
+		tagFields = append(tagFields, field)
+	}
+	if len(tagFields) > 0 {
+		if _, idx := out.FieldByName("tags"); idx == -1 {
+			tags := make([]json.RawMessage, rows)
+			for i := 0; i < rows; i++ {
+				items := []map[string]any{}
+				for _, field := range tagFields {
+					if v, ok := field.ConcreteAt(i); ok {
+						items = append(items, map[string]any{"key": field.Name, "value": v})
+					}
+				}
+				tags[i], _ = json.Marshal(items)
+			}
+			out.Fields = append(out.Fields, data.NewField("tags", nil, tags))
+		}
+	}
+	if out.Meta == nil {
+		out.Meta = &data.FrameMeta{}
+	}
+	out.Meta.PreferredVisualization = data.VisTypeTrace
+	return out, nil
+}
+
+func findTraceField(frame *data.Frame, selector string, aliases []string) *data.Field {
+	if selector != "" {
+		if field, idx := frame.FieldByName(selector); idx != -1 {
+			return field
+		}
+		return nil
+	}
+	for _, alias := range aliases {
+		for _, field := range frame.Fields {
+			if strings.EqualFold(field.Name, alias) {
+				return field
+			}
+		}
+	}
+	return nil
+}
+
+func traceString(field *data.Field, row int) string {
+	v, ok := field.ConcreteAt(row)
+	if !ok {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// traceStartTime returns the start time in epoch milliseconds. numeric start times are considered as epoch milliseconds
+func traceStartTime(field *data.Field, row int) float64 {
+	v, ok := field.ConcreteAt(row)
+	if !ok {
+		return 0
+	}
+	switch t := v.(type) {
+	case time.Time:
+		return float64(t.UnixNano()) / float64(time.Millisecond)
+	case string:
+		if parsed := parseLogTimestamp(t); parsed != nil {
+			return float64(parsed.UnixNano()) / float64(time.Millisecond)
+		}
+		return 0
+	}
+	value, err := field.NullableFloatAt(row)
+	if err != nil || value == nil {
+		return 0
+	}
+	return *value
+}
+
+// traceDuration returns the duration in milliseconds. Duration strings such as 1.5s are supported irrespective of the unit
+func traceDuration(field *data.Field, row int, unit string) float64 {
+	var value float64
+	v, ok := field.ConcreteAt(row)
+	if !ok {
+		return 0
+	}
+	if s, ok := v.(string); ok {
+		if d, err := time.ParseDuration(s); err == nil {
+			return float64(d) / float64(time.Millisecond)
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return 0
+		}
+		value = f
+	} else {
+		f, err := field.NullableFloatAt(row)
+		if err != nil || f == nil {
+			return 0
+		}
+		value = *f
+	}
+	switch unit {
+	case "ns":
+		return value / 1e6
+	case "us":
+		return value / 1e3
+	case "s":
+		return value * 1e3
+	default:
+		return value
+	}
+}
@@ -0,0 +1,64 @@
+package infinity_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestShapeFrameForTraceFormat(t *testing.T) {
+	start := time.Date(2023, 1, 2, 10, 0, 0, 0, time.UTC)
+	t.Run("should map the common column names into trace fields", func(t *testing.T) {
+		frame := data.NewFrame("A",
+			data.NewField("trace_id", nil, []string{"t1", "t1"}),
+			data.NewField("span_id", nil, []string{"s1", "s2"}),
+			data.NewField("parent_id", nil, []*string{nil, toSP("s1")}),
+			data.NewField("name", nil, []string{"GET /", "SELECT"}),
+			data.NewField("service", nil, []string{"web", "db"}),
+			data.NewField("start_time", nil, []time.Time{start, start.Add(time.Millisecond)}),
+			data.NewField("duration", nil, []float64{1500, 800}),
+			data.NewField("http_status", nil, []*float64{toFP(200), nil}),
+		)
+		out, err := infinity.ShapeFrameForFormat(context.Background(), frame, models.Query{Format: "trace", TraceOptions: &models.TraceOptions{DurationUnit: "us"}}, false)
+		require.Nil(t, err)
+		names := []string{}
+		for _, field := range out.Fields {
+			names = append(names, field.Name)
+		}
+		require.Equal(t, []string{"traceID", "spanID", "parentSpanID", "operationName", "serviceName", "startTime", "duration", "tags"}, names)
+		require.Equal(t, "s1", out.Fields[2].At(1))
+		require.Equal(t, float64(start.UnixMilli()+1), out.Fields[5].At(1))
+		require.Equal(t, 1.5, out.Fields[6].At(0))
+		require.Equal(t, `[{"key":"http_status","value":200}]`, string(out.Fields[7].At(0).(json.RawMessage)))
+		require.Equal(t, `[]`, string(out.Fields[7].At(1).(json.RawMessage)))
+		require.Equal(t, data.VisTypeTrace, string(out.Meta.PreferredVisualization))
+	})
+	t.Run("should use the configured column mapping", func(t *testing.T) {
+		frame := data.NewFrame("A",
+			data.NewField("tid", nil, []string{"t1"}),
+			data.NewField("sid", nil, []string{"s1"}),
+			data.NewField("took", nil, []string{"2s"}),
+		)
+		out, err := infinity.ShapeFrameForFormat(context.Background(), frame, models.Query{Format: "trace", TraceOptions: &models.TraceOptions{TraceID: "tid", SpanID: "sid", Duration: "took"}}, false)
+		require.Nil(t, err)
+		require.Equal(t, "t1", out.Fields[0].At(0))
+		require.Equal(t, float64(2000), out.Fields[6].At(0))
+	})
+	t.Run("should throw error when the configured columns are missing", func(t *testing.T) {
+		frame := data.NewFrame("A", data.NewField("foo", nil, []string{"t1"}))
+		_, err := infinity.ShapeFrameForFormat(context.Background(), frame, models.Query{Format: "trace", TraceOptions: &models.TraceOptions{TraceID: "tid"}}, false)
+		require.ErrorContains(t, err, "trace format requires trace id and span id fields. found fields foo (string)")
+	})
+	t.Run("should return the frame as is when the results are not trace like", func(t *testing.T) {
+		frame := data.NewFrame("A", data.NewField("foo", nil, []string{"t1"}))
+		out, err := infinity.ShapeFrameForFormat(context.Background(), frame, models.Query{Format: "trace"}, false)
+		require.Nil(t, err)
+		require.Equal(t, frame, out)
+	})
+}
@@ -83,6 +83,51 @@ func ApplyTransformation(query models.Query, transformation models.Transformatio
 			}
 			response.Responses[pk] = backend.DataResponse{Frames: frames, Error: err}
 		}
+	case models.SparklineTransformation:
+		var err error
+		for pk, pr := range input.Responses {
+			frames := []*data.Frame{}
+			for _, frame := range pr.Frames {
+				frame, err1 := GetFrameWithSparklineField(frame, transformation.Sparkline.Field, transformation.Sparkline.Delimiter, transformation.Sparkline.Alias)
+				if err1 != nil {
+					err = errors.Join(errors.New("error applying sparkline"), err1, err)
+				}
+				if frame != nil {
+					frames = append(frames, frame)
+				}
+			}
+			response.Responses[pk] = backend.DataResponse{Frames: frames, Error: err}
+		}
+	case models.IPEnrichmentTransformation:
+		var err error
+		for pk, pr := range input.Responses {
+			frames := []*data.Frame{}
+			for _, frame := range pr.Frames {
+				frame, err1 := GetFrameWithIPEnrichment(frame, transformation.IPEnrichment.Field, transformation.IPEnrichment.ReverseDNS, transformation.IPEnrichment.CIDRTags)
+				if err1 != nil {
+					err = errors.Join(errors.New("error applying ip enrichment"), err1, err)
+				}
+				if frame != nil {
+					frames = append(frames, frame)
+				}
+			}
+			response.Responses[pk] = backend.DataResponse{Frames: frames, Error: err}
+		}
+	case models.HeatmapTransformation:
+		var err error
+		for pk, pr := range input.Responses {
+			frames := []*data.Frame{}
+			for _, frame := range pr.Frames {
+				frame, err1 := GetHeatmapFrame(frame, transformation.Heatmap.TimeField, transformation.Heatmap.BucketField, transformation.Heatmap.CountField, transformation.Heatmap.Cumulative)
+				if err1 != nil {
+					err = errors.Join(errors.New("error applying heatmap"), err1, err)
+				}
+				if frame != nil {
+					frames = append(frames, frame)
+				}
+			}
+			response.Responses[pk] = backend.DataResponse{Frames: frames, Error: err}
+		}
 	default:
 		return input, nil
 	}
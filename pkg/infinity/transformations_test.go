@@ -0,0 +1,131 @@
+package infinity_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestApplyTransformation(t *testing.T) {
+	t.Run("sparkline", func(t *testing.T) {
+		input := backend.NewQueryDataResponse()
+		input.Responses["A"] = backend.DataResponse{Frames: data.Frames{data.NewFrame("A",
+			data.NewField("name", nil, []string{"foo", "bar"}),
+			data.NewField("trend", nil, []*string{toSP("1, 2,3"), toSP("4;x")}),
+		)}}
+		transformation := models.TransformationItem{Type: models.SparklineTransformation}
+		transformation.Sparkline.Field = "trend"
+		got, err := infinity.ApplyTransformation(models.Query{}, transformation, input)
+		require.Nil(t, err)
+		require.Nil(t, got.Responses["A"].Error)
+		frame := got.Responses["A"].Frames[0]
+		require.Equal(t, 2, len(frame.Fields))
+		require.Equal(t, data.FieldTypeNullableJSON, frame.Fields[1].Type())
+		require.Equal(t, json.RawMessage(`[1,2,3]`), *(frame.Fields[1].At(0).(*json.RawMessage)))
+		require.Equal(t, json.RawMessage(`[null]`), *(frame.Fields[1].At(1).(*json.RawMessage)))
+	})
+	t.Run("sparkline with alias and delimiter", func(t *testing.T) {
+		input := backend.NewQueryDataResponse()
+		input.Responses["A"] = backend.DataResponse{Frames: data.Frames{data.NewFrame("A",
+			data.NewField("trend", nil, []string{"4;x;5.5"}),
+		)}}
+		transformation := models.TransformationItem{Type: models.SparklineTransformation}
+		transformation.Sparkline.Field = "trend"
+		transformation.Sparkline.Delimiter = ";"
+		transformation.Sparkline.Alias = "spark"
+		got, err := infinity.ApplyTransformation(models.Query{}, transformation, input)
+		require.Nil(t, err)
+		frame := got.Responses["A"].Frames[0]
+		require.Equal(t, 2, len(frame.Fields))
+		require.Equal(t, "spark", frame.Fields[1].Name)
+		require.Equal(t, json.RawMessage(`[4,null,5.5]`), *(frame.Fields[1].At(0).(*json.RawMessage)))
+	})
+	t.Run("sparkline with missing field", func(t *testing.T) {
+		input := backend.NewQueryDataResponse()
+		input.Responses["A"] = backend.DataResponse{Frames: data.Frames{data.NewFrame("A", data.NewField("name", nil, []string{"foo"}))}}
+		transformation := models.TransformationItem{Type: models.SparklineTransformation}
+		transformation.Sparkline.Field = "trend"
+		got, err := infinity.ApplyTransformation(models.Query{}, transformation, input)
+		require.Nil(t, err)
+		require.NotNil(t, got.Responses["A"].Error)
+	})
+	t.Run("ip enrichment with cidr tags", func(t *testing.T) {
+		input := backend.NewQueryDataResponse()
+		input.Responses["A"] = backend.DataResponse{Frames: data.Frames{data.NewFrame("A",
+			data.NewField("ip", nil, []*string{toSP("10.1.2.3"), toSP("192.168.1.10"), toSP("8.8.8.8"), toSP("foo"), nil}),
+		)}}
+		transformation := models.TransformationItem{Type: models.IPEnrichmentTransformation}
+		transformation.IPEnrichment.Field = "ip"
+		transformation.IPEnrichment.CIDRTags = []models.CIDRTag{{CIDR: "10.0.0.0/8", Label: "datacenter"}, {CIDR: "192.168.0.0/16", Label: "office"}}
+		got, err := infinity.ApplyTransformation(models.Query{}, transformation, input)
+		require.Nil(t, err)
+		require.Nil(t, got.Responses["A"].Error)
+		frame := got.Responses["A"].Frames[0]
+		require.Equal(t, 2, len(frame.Fields))
+		require.Equal(t, "ip_tag", frame.Fields[1].Name)
+		require.Equal(t, "datacenter", *(frame.Fields[1].At(0).(*string)))
+		require.Equal(t, "office", *(frame.Fields[1].At(1).(*string)))
+		require.Nil(t, frame.Fields[1].At(2))
+		require.Nil(t, frame.Fields[1].At(3))
+		require.Nil(t, frame.Fields[1].At(4))
+	})
+	t.Run("ip enrichment with invalid cidr", func(t *testing.T) {
+		input := backend.NewQueryDataResponse()
+		input.Responses["A"] = backend.DataResponse{Frames: data.Frames{data.NewFrame("A", data.NewField("ip", nil, []string{"10.1.2.3"}))}}
+		transformation := models.TransformationItem{Type: models.IPEnrichmentTransformation}
+		transformation.IPEnrichment.Field = "ip"
+		transformation.IPEnrichment.CIDRTags = []models.CIDRTag{{CIDR: "10.0.0.0", Label: "datacenter"}}
+		got, err := infinity.ApplyTransformation(models.Query{}, transformation, input)
+		require.Nil(t, err)
+		require.NotNil(t, got.Responses["A"].Error)
+	})
+	t.Run("heatmap", func(t *testing.T) {
+		t1 := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		t2 := t1.Add(time.Minute)
+		input := backend.NewQueryDataResponse()
+		input.Responses["A"] = backend.DataResponse{Frames: data.Frames{data.NewFrame("A",
+			data.NewField("time", nil, []time.Time{t2, t2, t2, t1, t1, t1}),
+			data.NewField("le", nil, []string{"0.5", "+Inf", "0.1", "0.1", "0.5", "+Inf"}),
+			data.NewField("count", nil, []*float64{toFP(6), toFP(10), toFP(2), toFP(1), toFP(3), toFP(3)}),
+		)}}
+		transformation := models.TransformationItem{Type: models.HeatmapTransformation}
+		transformation.Heatmap.TimeField = "time"
+		transformation.Heatmap.BucketField = "le"
+		transformation.Heatmap.CountField = "count"
+		transformation.Heatmap.Cumulative = true
+		got, err := infinity.ApplyTransformation(models.Query{}, transformation, input)
+		require.Nil(t, err)
+		require.Nil(t, got.Responses["A"].Error)
+		frame := got.Responses["A"].Frames[0]
+		require.Equal(t, data.FrameType("heatmap-rows"), frame.Meta.Type)
+		names := []string{}
+		for _, field := range frame.Fields {
+			names = append(names, field.Name)
+		}
+		require.Equal(t, []string{"time", "0.1", "0.5", "+Inf"}, names)
+		require.Equal(t, t1, frame.Fields[0].At(0))
+		require.Equal(t, []float64{1, 2, 0}, []float64{frame.Fields[1].At(0).(float64), frame.Fields[2].At(0).(float64), frame.Fields[3].At(0).(float64)})
+		require.Equal(t, []float64{2, 4, 4}, []float64{frame.Fields[1].At(1).(float64), frame.Fields[2].At(1).(float64), frame.Fields[3].At(1).(float64)})
+	})
+	t.Run("heatmap with missing fields", func(t *testing.T) {
+		input := backend.NewQueryDataResponse()
+		input.Responses["A"] = backend.DataResponse{Frames: data.Frames{data.NewFrame("A", data.NewField("le", nil, []string{"1"}))}}
+		transformation := models.TransformationItem{Type: models.HeatmapTransformation}
+		transformation.Heatmap.TimeField = "time"
+		transformation.Heatmap.BucketField = "le"
+		transformation.Heatmap.CountField = "count"
+		got, err := infinity.ApplyTransformation(models.Query{}, transformation, input)
+		require.Nil(t, err)
+		require.ErrorContains(t, got.Responses["A"].Error, "heatmap fields time, le, count not found. found fields le (string)")
+	})
+}
+
+func toSP(v string) *string {
+	return &v
+}
@@ -0,0 +1,207 @@
+package infinity
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+	"github.com/yesoreyeram/grafana-plugins/lib/go/jsonframer"
+)
+
+const (
+	webhookCacheTable         = "webhooks"
+	defaultWebhookMaxPayloads = 100
+	maxWebhookMaxPayloads     = 1000
+)
+
+var (
+	// ErrWebhookChannelNotFound is returned when the channel is not configured in the datasource settings
+	ErrWebhookChannelNotFound = errors.New("webhook channel not found")
+	// ErrWebhookUnauthorized is returned when the token doesn't match the token of the channel
+	ErrWebhookUnauthorized = errors.New("invalid or missing webhook token")
+)
+
+// WebhookPayload is the payload pushed to the webhook channel
+type WebhookPayload struct {
+	Channel     string    `json:"channel"`
+	ContentType string    `json:"contentType,omitempty"`
+	Body        []byte    `json:"-"`
+	Size        int       `json:"size"`
+	ReceivedAt  time.Time `json:"receivedAt"`
+}
+
+// ReceiveWebhookPayload stores the payload pushed to the channel after validating the token.
+// Only the latest payloads of the channel are kept, up to the max payloads of the channel
+func ReceiveWebhookPayload(ctx context.Context, infClient Client, name string, token string, contentType string, body []byte) (*WebhookPayload, error) {
+	channel, err := getWebhookChannel(infClient.Settings, name)
+	if err != nil {
+		return nil, err
+	}
+	// channels without the token never accept the payloads
+	if channel.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(channel.Token)) != 1 {
+		return nil, ErrWebhookUnauthorized
+	}
+	if strings.Contains(contentType, "json") && !json.Valid(body) {
+		return nil, errors.New("invalid json payload")
+	}
+	payload := &WebhookPayload{Channel: channel.Name, ContentType: contentType, Body: body, Size: len(body), ReceivedAt: time.Now().UTC()}
+	table := infClient.Cache().Table(webhookCacheTable).WithContext(ctx)
+	if err := table.Push(channel.Name, payload); err != nil {
+		return nil, err
+	}
+	maxPayloads := channel.MaxPayloads
+	if maxPayloads <= 0 {
+		maxPayloads = defaultWebhookMaxPayloads
+	}
+	if maxPayloads > maxWebhookMaxPayloads {
+		maxPayloads = maxWebhookMaxPayloads
+	}
+	if _, err := table.Trim(channel.Name, maxPayloads); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// GetWebhookPayloads returns the payloads kept by the channel, oldest first
+func GetWebhookPayloads(ctx context.Context, infClient Client, name string) ([]*WebhookPayload, error) {
+	channel, err := getWebhookChannel(infClient.Settings, name)
+	if err != nil {
+		return nil, err
+	}
+	values, err := infClient.Cache().Table(webhookCacheTable).WithContext(ctx).Range(channel.Name, 0, math.MaxInt32)
+	if err != nil {
+		return nil, err
+	}
+	payloads := make([]*WebhookPayload, 0, len(values))
+	for _, v := range values {
+		if payload, ok := v.(*WebhookPayload); ok && payload != nil {
+			payloads = append(payloads, payload)
+		}
+	}
+	return payloads, nil
+}
+
+// GetFrameForWebhook returns the payloads pushed to the webhook channel. The payloads are parsed by the type of the query, csv and tsv or json.
+// In the all mode, the root selector applies to every payload and the json objects without received_at get the time the payload was received
+func GetFrameForWebhook(ctx context.Context, query models.Query, infClient Client) (*data.Frame, error) {
+	ctx, span := tracing.DefaultTracer().Start(ctx, "GetFrameForWebhook")
+	defer span.End()
+	if query.WebhookOptions == nil || strings.TrimSpace(query.WebhookOptions.Channel) == "" {
+		return nil, UserError(errors.New("invalid or empty webhook channel"))
+	}
+	options := *query.WebhookOptions
+	if options.Mode == "" {
+		options.Mode = models.WebhookModeLatest
+	}
+	if options.Mode != models.WebhookModeLatest && options.Mode != models.WebhookModeAll {
+		return nil, UserError(fmt.Errorf("invalid webhook mode %s. mode should be latest or all", options.Mode))
+	}
+	payloads, err := GetWebhookPayloads(ctx, infClient, options.Channel)
+	if err != nil {
+		if errors.Is(err, ErrWebhookChannelNotFound) {
+			return nil, UserError(err)
+		}
+		return nil, err
+	}
+	if len(payloads) == 0 {
+		rowsQuery := query
+		rowsQuery.RootSelector = ""
+		frame, err := GetJSONBackendResponse(ctx, []any{}, rowsQuery)
+		if frame != nil {
+			frame.AppendNotices(data.Notice{Severity: data.NoticeSeverityInfo, Text: fmt.Sprintf("No payloads received on the webhook channel %s", options.Channel)})
+		}
+		return frame, err
+	}
+	if options.Mode == models.WebhookModeLatest {
+		payloads = payloads[len(payloads)-1:]
+	}
+	isCSV := query.Type == models.QueryTypeCSV || query.Type == models.QueryTypeTSV
+	var frame *data.Frame
+	switch {
+	case isCSV:
+		// the header of the payloads other than the first payload is skipped
+		lines := []string{}
+		for i, payload := range payloads {
+			body := strings.TrimRight(string(payload.Body), "\r\n")
+			if i > 0 {
+				_, body, _ = strings.Cut(body, "\n")
+			}
+			if body != "" {
+				lines = append(lines, body)
+			}
+		}
+		frame, err = GetCSVBackendResponse(ctx, strings.Join(lines, "\n"), query)
+	case options.Mode == models.WebhookModeLatest:
+		var doc any
+		if err := json.Unmarshal(payloads[0].Body, &doc); err != nil {
+			return nil, UserError(fmt.Errorf("invalid json payload of the webhook channel %s. %w", options.Channel, err))
+		}
+		frame, err = GetJSONBackendResponse(ctx, doc, query)
+	default:
+		rows, err := getWebhookRows(payloads, query.RootSelector)
+		if err != nil {
+			return nil, UserError(err)
+		}
+		rowsQuery := query
+		rowsQuery.RootSelector = ""
+		frame, err = GetJSONBackendResponse(ctx, rows, rowsQuery)
+		if err != nil {
+			return frame, err
+		}
+		return PostProcessFrame(ctx, frame, rowsQuery)
+	}
+	if err != nil {
+		return frame, err
+	}
+	return PostProcessFrame(ctx, frame, query)
+}
+
+// getWebhookRows returns the rows of the json payloads. arrays are flattened and the objects are returned as the single rows
+func getWebhookRows(payloads []*WebhookPayload, rootSelector string) ([]any, error) {
+	rows := []any{}
+	for _, payload := range payloads {
+		body := string(payload.Body)
+		if rootSelector != "" {
+			root, err := jsonframer.GetRootData(body, rootSelector)
+			if err != nil {
+				return nil, fmt.Errorf("error applying the root selector to the payload received at %s. %w", payload.ReceivedAt.Format(time.RFC3339), err)
+			}
+			body = root
+		}
+		var doc any
+		if err := json.Unmarshal([]byte(body), &doc); err != nil {
+			return nil, fmt.Errorf("invalid json payload received at %s. %w", payload.ReceivedAt.Format(time.RFC3339), err)
+		}
+		items, ok := doc.([]any)
+		if !ok {
+			items = []any{doc}
+		}
+		for _, item := range items {
+			if row, ok := item.(map[string]any); ok {
+				if _, exists := row["received_at"]; !exists {
+					row["received_at"] = payload.ReceivedAt.Format(time.RFC3339Nano)
+				}
+			}
+			rows = append(rows, item)
+		}
+	}
+	return rows, nil
+}
+
+func getWebhookChannel(settings models.InfinitySettings, name string) (models.WebhookChannel, error) {
+	name = strings.TrimSpace(name)
+	for _, channel := range settings.WebhookChannels {
+		if channel.Name == name {
+			return channel, nil
+		}
+	}
+	return models.WebhookChannel{}, fmt.Errorf("%w. %s", ErrWebhookChannelNotFound, name)
+}
@@ -0,0 +1,78 @@
+package infinity_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestWebhookChannels(t *testing.T) {
+	ctx := context.Background()
+	client, err := infinity.NewClient(ctx, models.InfinitySettings{OrgID: 1, UID: "webhooks", WebhookChannels: []models.WebhookChannel{
+		{Name: "deployments", MaxPayloads: 2, Token: "secret"},
+		{Name: "metrics", Token: "secret"},
+		{Name: "untokened"},
+	}})
+	require.Nil(t, err)
+	value := func(frame *data.Frame, name string, row int) any {
+		field, _ := frame.FieldByName(name)
+		require.NotNil(t, field, name)
+		v, _ := field.ConcreteAt(row)
+		return v
+	}
+	t.Run("payloads are authenticated by the token of the channel", func(t *testing.T) {
+		_, err := infinity.ReceiveWebhookPayload(ctx, *client, "deployments", "wrong", "application/json", []byte(`{}`))
+		require.ErrorIs(t, err, infinity.ErrWebhookUnauthorized)
+		_, err = infinity.ReceiveWebhookPayload(ctx, *client, "untokened", "", "application/json", []byte(`{}`))
+		require.ErrorIs(t, err, infinity.ErrWebhookUnauthorized)
+		_, err = infinity.ReceiveWebhookPayload(ctx, *client, "other", "secret", "application/json", []byte(`{}`))
+		require.ErrorIs(t, err, infinity.ErrWebhookChannelNotFound)
+		_, err = infinity.ReceiveWebhookPayload(ctx, *client, "deployments", "secret", "application/json", []byte(`{`))
+		require.ErrorContains(t, err, "invalid json payload")
+	})
+	t.Run("empty channel", func(t *testing.T) {
+		frame, err := infinity.GetFrameForWebhook(ctx, models.Query{RefID: "A", Source: "webhook", WebhookOptions: &models.WebhookOptions{Channel: "deployments"}}, *client)
+		require.Nil(t, err)
+		require.Equal(t, "No payloads received on the webhook channel deployments", frame.Meta.Notices[0].Text)
+	})
+	for i := 1; i <= 3; i++ {
+		_, err := infinity.ReceiveWebhookPayload(ctx, *client, "deployments", "secret", "application/json", []byte(fmt.Sprintf(`{ "deployment": { "service": "api", "version": "v%d" } }`, i)))
+		require.Nil(t, err)
+	}
+	t.Run("only the latest payloads are kept", func(t *testing.T) {
+		payloads, err := infinity.GetWebhookPayloads(ctx, *client, "deployments")
+		require.Nil(t, err)
+		require.Len(t, payloads, 2)
+	})
+	t.Run("latest", func(t *testing.T) {
+		query := models.Query{RefID: "A", Type: models.QueryTypeJSON, Source: "webhook", RootSelector: "deployment", WebhookOptions: &models.WebhookOptions{Channel: "deployments"}}
+		frame, err := infinity.GetFrameForWebhook(ctx, query, *client)
+		require.Nil(t, err)
+		require.Equal(t, 1, frame.Rows())
+		require.Equal(t, "v3", value(frame, "version", 0))
+	})
+	t.Run("all", func(t *testing.T) {
+		query := models.Query{RefID: "A", Type: models.QueryTypeJSON, Source: "webhook", RootSelector: "deployment", WebhookOptions: &models.WebhookOptions{Channel: "deployments", Mode: models.WebhookModeAll}}
+		frame, err := infinity.GetFrameForWebhook(ctx, query, *client)
+		require.Nil(t, err)
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, "v2", value(frame, "version", 0))
+		require.NotEmpty(t, value(frame, "received_at", 0))
+	})
+	t.Run("all csv", func(t *testing.T) {
+		_, err := infinity.ReceiveWebhookPayload(ctx, *client, "metrics", "secret", "text/csv", []byte("host,cpu\nweb1,10\n"))
+		require.Nil(t, err)
+		_, err = infinity.ReceiveWebhookPayload(ctx, *client, "metrics", "secret", "text/csv", []byte("host,cpu\nweb2,20\nweb3,30"))
+		require.Nil(t, err)
+		query := models.Query{RefID: "A", Type: models.QueryTypeCSV, Source: "webhook", WebhookOptions: &models.WebhookOptions{Channel: "metrics", Mode: models.WebhookModeAll}}
+		frame, err := infinity.GetFrameForWebhook(ctx, query, *client)
+		require.Nil(t, err)
+		require.Equal(t, 3, frame.Rows())
+		require.Equal(t, "web3", value(frame, "host", 2))
+	})
+}
@@ -10,10 +10,12 @@ import (
 	"github.com/yesoreyeram/grafana-plugins/lib/go/xmlframer"
 )
 
-func GetXMLBackendResponse(ctx context.Context, inputString string, query models.Query) (*data.Frame, error) {
+func GetXMLBackendResponse(ctx context.Context, inputString string, query models.Query) (frame *data.Frame, err error) {
 	_, span := tracing.DefaultTracer().Start(ctx, "GetXMLBackendResponse")
 	defer span.End()
-	frame := GetDummyFrame(query)
+	defer trackTiming(ctx, parseTiming)()
+	frame = GetDummyFrame(query)
+	defer recoverParserPanic(string(query.Type), &err)
 	columns := []jsonframer.ColumnSelector{}
 	for _, c := range query.Columns {
 		columns = append(columns, jsonframer.ColumnSelector{
@@ -16,7 +16,9 @@ func main() {
 	if err := backend.SetupTracer(pluginID, tracing.Opts{}); err != nil {
 		backend.Logger.Error("error setting up tracer", "error", err.Error())
 	}
-	if err := datasource.Serve(pluginhost.NewDatasource()); err != nil {
+	err := datasource.Serve(pluginhost.NewDatasource())
+	pluginhost.Shutdown()
+	if err != nil {
 		backend.Logger.Error("error starting infinity plugin", "error", err.Error())
 		os.Exit(1)
 	}
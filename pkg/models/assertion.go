@@ -0,0 +1,21 @@
+package models
+
+// AssertionType is the check performed by the assertion against the response of the url query
+type AssertionType string
+
+const (
+	// AssertionTypeStatusCode passes when the status code of the response equals the value
+	AssertionTypeStatusCode AssertionType = "status_code"
+	// AssertionTypeBody passes when the value at the path of the response body matches the regex of the value
+	AssertionTypeBody AssertionType = "body"
+	// AssertionTypeLatency passes when the response is received within the value in milliseconds
+	AssertionTypeLatency AssertionType = "latency"
+)
+
+// Assertion is evaluated against the response of the url query. Queries with the assertions return the assertion results
+// instead of the parsed response
+type Assertion struct {
+	Type  AssertionType `json:"type"`
+	Path  string        `json:"path,omitempty"` // json path (gjson or jsonata) of the body assertions. whole body is matched when empty
+	Value string        `json:"value"`          // expected status code, regex of the body value or the max latency in milliseconds
+}
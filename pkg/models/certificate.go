@@ -0,0 +1,7 @@
+package models
+
+// CertificateOptions are the targets of the certificate source
+type CertificateOptions struct {
+	Targets []string `json:"targets"`         // host or host:port. port defaults to 443. ex: example.com, smtp.example.com:465
+	Chain   bool     `json:"chain,omitempty"` // returns the intermediate certificates along with the leaf certificate
+}
@@ -0,0 +1,29 @@
+package models
+
+// ChainStep is the request sent before the url query. Values extracted from the response of the step replace the {{name}}
+// placeholders in the url, headers, params and body of the next steps and of the query, such as the token returned by a login request
+type ChainStep struct {
+	Name            string          `json:"name,omitempty"`
+	URL             string          `json:"url"`
+	URLOptions      URLOptions      `json:"url_options"`
+	Extract         []ChainVariable `json:"extract,omitempty"`
+	SkipWithSession bool            `json:"skip_with_session,omitempty"` // skips the step, such as the login, while the cookie jar of the query holds the cookies of the previous runs
+}
+
+// ChainVariableSource is the part of the chain step response the variable is extracted from
+type ChainVariableSource string
+
+const (
+	ChainVariableSourceBody   ChainVariableSource = "body"
+	ChainVariableSourceHeader ChainVariableSource = "header"
+	ChainVariableSourceCookie ChainVariableSource = "cookie"
+	ChainVariableSourceMeta   ChainVariableSource = "meta"
+)
+
+// ChainVariable is the value extracted from the response of the chain step, such as the token of the json body or the csrf token
+// of the response header, of the cookie or of the html meta tag
+type ChainVariable struct {
+	Name string              `json:"name"`
+	From ChainVariableSource `json:"from,omitempty"` // defaults to body
+	Path string              `json:"path"`           // json path (gjson or jsonata) of the body, or the name of the header, cookie or meta tag
+}
@@ -0,0 +1,8 @@
+package models
+
+// CookieJarOptions enables the cookie jar of the url query, so the session cookies set by the responses, such as the response of the
+// login step of the chain, are sent with the next requests. Cookies are kept in the cache until the ttl elapses
+type CookieJarOptions struct {
+	Name string `json:"name,omitempty"` // queries with the same jar name share the cookies. defaults to the ref id of the query
+	TTL  string `json:"ttl,omitempty"`  // duration the cookies are kept in the cache. defaults to 15m, up to 24h
+}
@@ -0,0 +1,20 @@
+package models
+
+// CrawlMode is how the pages of the crawl source are discovered
+type CrawlMode string
+
+const (
+	// CrawlModeLinks follows the links of the html pages from the url of the query
+	CrawlModeLinks CrawlMode = "links"
+	// CrawlModeSitemap fetches the pages listed in the sitemap at the url of the query
+	CrawlModeSitemap CrawlMode = "sitemap"
+)
+
+// CrawlOptions bound the crawl source. Pages outside the host of the url and the allowed hosts are never fetched
+type CrawlOptions struct {
+	Mode         CrawlMode `json:"mode,omitempty"`          // 'links' (default) | 'sitemap'
+	Depth        int       `json:"depth,omitempty"`         // link depth followed from the url, or the nesting of the sitemap indexes. defaults to 1, up to 3
+	MaxPages     int       `json:"max_pages,omitempty"`     // defaults to 50, up to 500
+	Delay        string    `json:"delay,omitempty"`         // politeness delay between the requests. ex: 1s. defaults to 500ms, at least 100ms
+	IgnoreRobots bool      `json:"ignore_robots,omitempty"` // fetches the pages disallowed by robots.txt
+}
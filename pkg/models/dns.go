@@ -0,0 +1,8 @@
+package models
+
+// DNSOptions are the names and the record types of the dns source
+type DNSOptions struct {
+	Names       []string `json:"names"`                  // ex: example.com, _sip._tcp.example.com
+	RecordTypes []string `json:"record_types,omitempty"` // 'A' (default) | 'AAAA' | 'TXT' | 'MX' | 'SRV' | 'CNAME' | 'NS'
+	Resolver    string   `json:"resolver,omitempty"`     // host or host:port of the resolver. port defaults to 53. defaults to the resolver of the system
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+const defaultElasticsearchSize = 500
+
+// ElasticsearchOptions builds the body of the elasticsearch _search request instead of the hand written query DSL
+type ElasticsearchOptions struct {
+	Query     string `json:"query,omitempty"`      // lucene query string. empty matches all the documents
+	TimeField string `json:"time_field,omitempty"` // date field filtered by the dashboard time range. empty skips the time filter
+	Size      int    `json:"size,omitempty"`       // hits per page. defaults to 500
+	SortField string `json:"sort_field,omitempty"` // defaults to the time field. pages are fetched with search_after of this sort
+	SortOrder string `json:"sort_order,omitempty"` // 'desc' (default) | 'asc'
+}
+
+// ApplyElasticsearchOptions replaces the body of the query with the _search request built from the elasticsearch options and the time range
+func ApplyElasticsearchOptions(query Query, timeRange backend.TimeRange, pluginContext backend.PluginContext) (Query, error) {
+	options := query.ElasticsearchOptions
+	if options == nil {
+		return query, nil
+	}
+	luceneQuery, err := InterPolateMacros(options.Query, timeRange, pluginContext)
+	if err != nil {
+		return query, fmt.Errorf("error applying macros to elasticsearch query. %s", err.Error())
+	}
+	filters := []any{}
+	if strings.TrimSpace(options.TimeField) != "" {
+		filters = append(filters, map[string]any{"range": map[string]any{options.TimeField: map[string]any{
+			"gte":    timeRange.From.UnixMilli(),
+			"lte":    timeRange.To.UnixMilli(),
+			"format": "epoch_millis",
+		}}})
+	}
+	if strings.TrimSpace(luceneQuery) != "" {
+		filters = append(filters, map[string]any{"query_string": map[string]any{"query": luceneQuery}})
+	}
+	size := options.Size
+	if size <= 0 {
+		size = defaultElasticsearchSize
+	}
+	sortField := options.SortField
+	if sortField == "" {
+		sortField = options.TimeField
+	}
+	if sortField == "" {
+		sortField = "_doc"
+	}
+	sortOrder := strings.ToLower(options.SortOrder)
+	if sortOrder != "asc" {
+		sortOrder = "desc"
+	}
+	body, err := json.Marshal(map[string]any{
+		"size":  size,
+		"query": map[string]any{"bool": map[string]any{"filter": filters}},
+		"sort":  []any{map[string]any{sortField: map[string]any{"order": sortOrder}}},
+	})
+	if err != nil {
+		return query, err
+	}
+	query.URLOptions.Method = http.MethodPost
+	query.URLOptions.BodyType = "raw"
+	query.URLOptions.BodyContentType = "application/json"
+	query.URLOptions.Body = string(body)
+	return query, nil
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"sort"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// FeatureFlag gates the experimental subsystems per datasource. Flags are set in the featureFlags of the jsonData.
+// ex: { "featureFlags" : { "openApi" : true, "scheduler" : false } }
+type FeatureFlag string
+
+const (
+	FeatureFlagScheduler FeatureFlag = "scheduler" // scheduled queries and scheduled exports
+	FeatureFlagOpenAPI   FeatureFlag = "openApi"   // open api operations resource. the open api spec resource is not gated
+)
+
+// featureFlagDefaults are the states of the flags not set in the datasource. Flags of the released subsystems are enabled by default
+var featureFlagDefaults = map[FeatureFlag]bool{
+	FeatureFlagScheduler: true,
+	FeatureFlagOpenAPI:   false,
+}
+
+// IsFeatureEnabled returns the state of the flag set in the datasource or the default state of the flag
+func (s InfinitySettings) IsFeatureEnabled(flag FeatureFlag) bool {
+	if enabled, ok := s.FeatureFlags[string(flag)]; ok {
+		return enabled
+	}
+	return featureFlagDefaults[flag]
+}
+
+// getFeatureFlags returns the known flags of the datasource. Unknown flags, such as the flags of the newer or the older versions
+// of the plugin, are ignored with a warning instead of failing the datasource
+func getFeatureFlags(flags map[string]bool) map[string]bool {
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var out map[string]bool
+	for _, name := range names {
+		if _, ok := featureFlagDefaults[FeatureFlag(name)]; !ok {
+			backend.Logger.Warn("ignoring the unknown feature flag", "flag", name)
+			continue
+		}
+		if out == nil {
+			out = map[string]bool{}
+		}
+		out[name] = flags[name]
+	}
+	return out
+}
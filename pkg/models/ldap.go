@@ -0,0 +1,23 @@
+package models
+
+// LDAPScope is the search scope of the ldap source
+type LDAPScope string
+
+const (
+	// LDAPScopeBase searches the base dn entry only
+	LDAPScopeBase LDAPScope = "base"
+	// LDAPScopeOne searches the immediate children of the base dn
+	LDAPScopeOne LDAPScope = "one"
+	// LDAPScopeSub searches the whole subtree of the base dn
+	LDAPScopeSub LDAPScope = "sub"
+)
+
+// LDAPOptions is the search of the ldap source. Username and password of the basic auth settings are used as the bind dn and the password
+type LDAPOptions struct {
+	Server     string    `json:"server,omitempty"`     // ex: ldap://directory:389, ldaps://directory:636. defaults to the datasource url
+	BaseDN     string    `json:"base_dn"`              // ex: ou=people,dc=example,dc=com
+	Filter     string    `json:"filter,omitempty"`     // ex: (&(objectClass=person)(department=ops)). defaults to (objectClass=*)
+	Attributes []string  `json:"attributes,omitempty"` // attributes returned as the columns. all the user attributes are returned when empty
+	Scope      LDAPScope `json:"scope,omitempty"`      // 'base' | 'one' | 'sub' (default)
+	SizeLimit  int       `json:"size_limit,omitempty"` // maximum number of the entries. defaults to 1000
+}
@@ -5,7 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
@@ -104,6 +106,15 @@ func InterPolateMacros(queryString string, timeRange backend.TimeRange, pluginCo
 		queryString = strings.ReplaceAll(queryString, "${__user.email}", pluginContext.User.Email)
 		queryString = strings.ReplaceAll(queryString, "${__user.login}", pluginContext.User.Login)
 	}
+	if pluginContext.OrgID != 0 {
+		queryString = strings.ReplaceAll(queryString, "${__org.id}", strconv.FormatInt(pluginContext.OrgID, 10))
+		queryString = strings.ReplaceAll(queryString, "${__org}", strconv.FormatInt(pluginContext.OrgID, 10))
+	}
+	queryString = strings.NewReplacer(
+		"${__range_ms}", strconv.FormatInt(timeRangeInMilliSeconds, 10),
+		"${__range_s}", strconv.FormatInt(timeRangeInMilliSeconds/1000, 10),
+		"${__range}", formatInterval(time.Duration(timeRangeInMilliSeconds)*time.Millisecond),
+	).Replace(queryString)
 	return strings.Trim(queryString, " "), nil
 }
 
@@ -169,3 +180,50 @@ func ApplyMacros(ctx context.Context, query Query, timeRange backend.TimeRange,
 
 	return query, nil
 }
+
+// ApplyIntervalVariables interpolates the interval variables which are usually interpolated by the grafana frontend.
+// Queries coming from alerting, recorded queries and server side expressions still have them in the query
+func ApplyIntervalVariables(query Query, interval time.Duration) Query {
+	if interval <= 0 {
+		return query
+	}
+	replacer := strings.NewReplacer(
+		"${__interval_ms}", strconv.FormatInt(interval.Milliseconds(), 10),
+		"$__interval_ms", strconv.FormatInt(interval.Milliseconds(), 10),
+		"${__interval}", formatInterval(interval),
+		"$__interval", formatInterval(interval),
+	)
+	query.URL = replacer.Replace(query.URL)
+	query.UQL = replacer.Replace(query.UQL)
+	query.GROQ = replacer.Replace(query.GROQ)
+	query.Data = replacer.Replace(query.Data)
+	query.URLOptions.Body = replacer.Replace(query.URLOptions.Body)
+	query.URLOptions.BodyGraphQLQuery = replacer.Replace(query.URLOptions.BodyGraphQLQuery)
+	for idx, p := range query.URLOptions.Params {
+		query.URLOptions.Params[idx].Value = replacer.Replace(p.Value)
+	}
+	for idx, cc := range query.ComputedColumns {
+		query.ComputedColumns[idx].Selector = replacer.Replace(cc.Selector)
+	}
+	query.FilterExpression = replacer.Replace(query.FilterExpression)
+	query.ResampleInterval = replacer.Replace(query.ResampleInterval)
+	return query
+}
+
+// formatInterval formats the duration the same way as grafana frontend does. ex: 500ms, 15s, 1m, 2h, 1d
+func formatInterval(interval time.Duration) string {
+	ms := interval.Milliseconds()
+	units := []struct {
+		suffix string
+		ms     int64
+	}{{"y", 365 * 24 * 3600 * 1000}, {"w", 7 * 24 * 3600 * 1000}, {"d", 24 * 3600 * 1000}, {"h", 3600 * 1000}, {"m", 60 * 1000}, {"s", 1000}}
+	for _, unit := range units {
+		if ms >= unit.ms && ms%unit.ms == 0 {
+			return fmt.Sprintf("%d%s", ms/unit.ms, unit.suffix)
+		}
+	}
+	if ms >= 1000 {
+		return fmt.Sprintf("%ds", ms/1000)
+	}
+	return fmt.Sprintf("%dms", ms)
+}
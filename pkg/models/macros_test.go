@@ -187,3 +187,23 @@ func TestApplyMacros(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyIntervalVariables(t *testing.T) {
+	query := models.Query{
+		URL:        "https://foo.com?interval=${__interval}&step=$__interval_ms",
+		URLOptions: models.URLOptions{Params: []models.URLOptionKeyValuePair{{Key: "step", Value: "$__interval"}}},
+	}
+	got := models.ApplyIntervalVariables(query, 90*time.Second)
+	require.Equal(t, "https://foo.com?interval=90s&step=90000", got.URL)
+	require.Equal(t, "90s", got.URLOptions.Params[0].Value)
+	require.Equal(t, "1m", models.ApplyIntervalVariables(models.Query{ResampleInterval: "$__interval"}, time.Minute).ResampleInterval)
+	require.Equal(t, "2h", models.ApplyIntervalVariables(models.Query{URL: "${__interval}"}, 2*time.Hour).URL)
+	require.Equal(t, "500ms", models.ApplyIntervalVariables(models.Query{URL: "${__interval}"}, 500*time.Millisecond).URL)
+	require.Equal(t, "${__interval}", models.ApplyIntervalVariables(models.Query{URL: "${__interval}"}, 0).URL)
+}
+
+func TestInterPolateGlobalVariables(t *testing.T) {
+	got, err := models.InterPolateMacros("${__range} ${__range_s} ${__range_ms} ${__org.id} ${__org}", backend.TimeRange{From: time.UnixMilli(0), To: time.UnixMilli(6 * 3600 * 1000)}, backend.PluginContext{OrgID: 2})
+	require.Nil(t, err)
+	require.Equal(t, "6h 21600 21600000 2 2", got)
+}
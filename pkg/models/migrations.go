@@ -0,0 +1,162 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+const (
+	// LatestSettingsSchemaVersion is the version of the datasource jsonData written by this version of the plugin
+	LatestSettingsSchemaVersion = 1
+	// LatestQuerySchemaVersion is the version of the query model written by this version of the plugin
+	LatestQuerySchemaVersion = 1
+)
+
+// settingsMigration upgrades the jsonData from the previous version to the Version
+type settingsMigration struct {
+	Version     int
+	Description string
+	Migrate     func(jsonData map[string]any, config backend.DataSourceInstanceSettings)
+}
+
+// queryMigration upgrades the query json from the previous version to the Version. Migrate reports whether the query is changed
+type queryMigration struct {
+	Version     int
+	Description string
+	Migrate     func(query map[string]any) bool
+}
+
+// settingsMigrations are applied in order. New migrations are appended with the next version and the LatestSettingsSchemaVersion is bumped
+var settingsMigrations = []settingsMigration{
+	{
+		Version:     1,
+		Description: "auth_method is derived from the legacy basic auth and oauthPassThru flags",
+		Migrate: func(jsonData map[string]any, config backend.DataSourceInstanceSettings) {
+			if method, _ := jsonData["auth_method"].(string); method != "" {
+				return
+			}
+			switch {
+			case jsonData["oauthPassThru"] == true:
+				jsonData["auth_method"] = AuthenticationMethodForwardOauth
+			case config.BasicAuthEnabled:
+				jsonData["auth_method"] = AuthenticationMethodBasic
+			}
+		},
+	},
+}
+
+// queryMigrations are applied in order. New migrations are appended with the next version and the LatestQuerySchemaVersion is bumped
+var queryMigrations = []queryMigration{
+	{
+		Version:     1,
+		Description: "body type of the POST queries is set explicitly. graphql queries use the body_graphql_query instead of the data",
+		Migrate: func(query map[string]any) bool {
+			urlOptions, ok := query["url_options"].(map[string]any)
+			if !ok || query["source"] != "url" || urlOptions["method"] != "POST" {
+				return false
+			}
+			changed := false
+			if bodyType, _ := urlOptions["body_type"].(string); bodyType == "" {
+				changed = true
+				urlOptions["body_type"] = "raw"
+				if query["type"] == string(QueryTypeGraphQL) {
+					urlOptions["body_type"] = "graphql"
+					urlOptions["body_content_type"] = "application/json"
+					if graphqlQuery, _ := urlOptions["body_graphql_query"].(string); graphqlQuery == "" {
+						urlOptions["body_graphql_query"] = urlOptions["data"]
+					}
+					urlOptions["data"] = ""
+				}
+			}
+			if contentType, _ := urlOptions["body_content_type"].(string); contentType == "" {
+				urlOptions["body_content_type"] = "text/plain"
+				changed = true
+			}
+			return changed
+		},
+	},
+}
+
+// MigrateSettingsJSON upgrades the jsonData of the datasource to the LatestSettingsSchemaVersion.
+// Provisioned datasources without the schemaVersion are treated as version 0
+func MigrateSettingsJSON(config backend.DataSourceInstanceSettings) (json.RawMessage, error) {
+	if len(config.JSONData) == 0 {
+		return config.JSONData, nil
+	}
+	jsonData := map[string]any{}
+	if err := json.Unmarshal(config.JSONData, &jsonData); err != nil {
+		return nil, err
+	}
+	if jsonData == nil {
+		return config.JSONData, nil
+	}
+	version, err := getSchemaVersion(jsonData, "schemaVersion")
+	if err != nil {
+		return nil, err
+	}
+	if version > LatestSettingsSchemaVersion {
+		return nil, fmt.Errorf("datasource settings schema version %d is newer than the supported version %d. update the plugin", version, LatestSettingsSchemaVersion)
+	}
+	if version == LatestSettingsSchemaVersion {
+		return config.JSONData, nil
+	}
+	for _, m := range settingsMigrations {
+		if m.Version > version {
+			m.Migrate(jsonData, config)
+		}
+	}
+	jsonData["schemaVersion"] = LatestSettingsSchemaVersion
+	return json.Marshal(jsonData)
+}
+
+// MigrateQueryJSON upgrades the query json to the LatestQuerySchemaVersion. Queries without the schema_version are treated as version 0
+func MigrateQueryJSON(input json.RawMessage) (json.RawMessage, error) {
+	output, _, err := migrateQueryJSON(input)
+	return output, err
+}
+
+// migrateQueryJSON upgrades the query json and returns the logs of the migrations which changed the query
+func migrateQueryJSON(input json.RawMessage) (json.RawMessage, []string, error) {
+	query := map[string]any{}
+	if err := json.Unmarshal(input, &query); err != nil {
+		return nil, nil, err
+	}
+	if query == nil {
+		return input, nil, nil
+	}
+	version, err := getSchemaVersion(query, "schema_version")
+	if err != nil {
+		return nil, nil, err
+	}
+	if version > LatestQuerySchemaVersion {
+		return nil, nil, fmt.Errorf("query schema version %d is newer than the supported version %d. update the plugin", version, LatestQuerySchemaVersion)
+	}
+	if version == LatestQuerySchemaVersion {
+		return input, nil, nil
+	}
+	var logs []string
+	for _, m := range queryMigrations {
+		if m.Version > version && m.Migrate(query) {
+			logs = append(logs, fmt.Sprintf("version %d: %s", m.Version, m.Description))
+		}
+	}
+	query["schema_version"] = LatestQuerySchemaVersion
+	output, err := json.Marshal(query)
+	return output, logs, err
+}
+
+func getSchemaVersion(doc map[string]any, key string) (int, error) {
+	switch v := doc[key].(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		if v < 0 || v != float64(int(v)) {
+			return 0, fmt.Errorf("invalid %s %v", key, v)
+		}
+		return int(v), nil
+	default:
+		return 0, fmt.Errorf("invalid %s %v", key, v)
+	}
+}
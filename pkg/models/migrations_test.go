@@ -0,0 +1,133 @@
+package models_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestMigrateSettingsJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  backend.DataSourceInstanceSettings
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "empty json data",
+			config: backend.DataSourceInstanceSettings{},
+			want:   "",
+		},
+		{
+			name:   "legacy basic auth",
+			config: backend.DataSourceInstanceSettings{BasicAuthEnabled: true, JSONData: []byte(`{ "timeoutInSeconds" : 10 }`)},
+			want:   `{ "auth_method" : "basicAuth", "schemaVersion" : 1, "timeoutInSeconds" : 10 }`,
+		},
+		{
+			name:   "legacy forward oauth",
+			config: backend.DataSourceInstanceSettings{BasicAuthEnabled: true, JSONData: []byte(`{ "oauthPassThru" : true }`)},
+			want:   `{ "auth_method" : "oauthPassThru", "oauthPassThru" : true, "schemaVersion" : 1 }`,
+		},
+		{
+			name:   "auth method is not overridden",
+			config: backend.DataSourceInstanceSettings{BasicAuthEnabled: true, JSONData: []byte(`{ "auth_method" : "bearerToken" }`)},
+			want:   `{ "auth_method" : "bearerToken", "schemaVersion" : 1 }`,
+		},
+		{
+			name:   "latest version is not migrated",
+			config: backend.DataSourceInstanceSettings{BasicAuthEnabled: true, JSONData: []byte(`{ "schemaVersion" : 1 }`)},
+			want:   `{ "schemaVersion" : 1 }`,
+		},
+		{
+			name:    "newer version",
+			config:  backend.DataSourceInstanceSettings{JSONData: []byte(`{ "schemaVersion" : 99 }`)},
+			wantErr: "datasource settings schema version 99 is newer than the supported version 1. update the plugin",
+		},
+		{
+			name:    "invalid version",
+			config:  backend.DataSourceInstanceSettings{JSONData: []byte(`{ "schemaVersion" : "1" }`)},
+			wantErr: "invalid schemaVersion 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := models.MigrateSettingsJSON(tt.config)
+			if tt.wantErr != "" {
+				require.NotNil(t, err)
+				require.Equal(t, tt.wantErr, err.Error())
+				return
+			}
+			require.Nil(t, err)
+			if tt.want == "" {
+				require.Empty(t, got)
+				return
+			}
+			require.JSONEq(t, tt.want, string(got))
+		})
+	}
+	t.Run("should load the migrated settings", func(t *testing.T) {
+		settings, err := models.LoadSettings(backend.DataSourceInstanceSettings{JSONData: []byte(`{ "oauthPassThru" : true }`)})
+		require.Nil(t, err)
+		require.Equal(t, models.AuthenticationMethodForwardOauth, settings.AuthenticationMethod)
+		_, err = models.LoadSettings(backend.DataSourceInstanceSettings{JSONData: []byte(`{ "schemaVersion" : 2 }`)})
+		require.NotNil(t, err)
+	})
+}
+
+func TestMigrateQueryJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr string
+	}{
+		{
+			name:  "legacy graphql query",
+			input: `{ "type" : "graphql", "source" : "url", "url_options" : { "method" : "POST", "data" : "{ users { name } }" } }`,
+			want:  `{ "type" : "graphql", "source" : "url", "schema_version" : 1, "url_options" : { "method" : "POST", "data" : "", "body_type" : "graphql", "body_content_type" : "application/json", "body_graphql_query" : "{ users { name } }" } }`,
+		},
+		{
+			name:  "legacy post query",
+			input: `{ "type" : "json", "source" : "url", "url_options" : { "method" : "POST", "data" : "{}" } }`,
+			want:  `{ "type" : "json", "source" : "url", "schema_version" : 1, "url_options" : { "method" : "POST", "data" : "{}", "body_type" : "raw", "body_content_type" : "text/plain" } }`,
+		},
+		{
+			name:  "get query",
+			input: `{ "type" : "json", "source" : "url", "url_options" : { "method" : "GET" } }`,
+			want:  `{ "type" : "json", "source" : "url", "schema_version" : 1, "url_options" : { "method" : "GET" } }`,
+		},
+		{
+			name:  "latest version is not migrated",
+			input: `{ "type" : "json", "source" : "url", "schema_version" : 1, "url_options" : { "method" : "POST" } }`,
+			want:  `{ "type" : "json", "source" : "url", "schema_version" : 1, "url_options" : { "method" : "POST" } }`,
+		},
+		{
+			name:    "newer version",
+			input:   `{ "schema_version" : 5 }`,
+			wantErr: "query schema version 5 is newer than the supported version 1. update the plugin",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := models.MigrateQueryJSON([]byte(tt.input))
+			if tt.wantErr != "" {
+				require.NotNil(t, err)
+				require.Equal(t, tt.wantErr, err.Error())
+				return
+			}
+			require.Nil(t, err)
+			require.JSONEq(t, tt.want, string(got))
+		})
+	}
+	t.Run("should load the migrations of the query", func(t *testing.T) {
+		query, err := models.LoadQuery(context.Background(), backend.DataQuery{JSON: []byte(`{ "type" : "json", "source" : "url", "url_options" : { "method" : "POST", "data" : "{}" } }`)}, backend.PluginContext{})
+		require.Nil(t, err)
+		require.Equal(t, []string{"version 1: body type of the POST queries is set explicitly. graphql queries use the body_graphql_query instead of the data"}, query.Migrations)
+		query, err = models.LoadQuery(context.Background(), backend.DataQuery{JSON: []byte(`{ "type" : "json", "source" : "url", "url_options" : { "method" : "GET" } }`)}, backend.PluginContext{})
+		require.Nil(t, err)
+		require.Empty(t, query.Migrations)
+	})
+}
@@ -0,0 +1,20 @@
+package models
+
+// MQTTMode is how the messages of the topics are collected
+type MQTTMode string
+
+const (
+	// MQTTModeWindow collects the messages published during the window
+	MQTTModeWindow MQTTMode = "window"
+	// MQTTModeRetained returns the retained messages of the topics, without waiting for the new publishes
+	MQTTModeRetained MQTTMode = "retained"
+)
+
+// MQTTOptions are the broker and the topics of the mqtt source. Username and password of the basic auth settings are used for the broker
+type MQTTOptions struct {
+	Broker string   `json:"broker,omitempty"` // ex: mqtt://broker:1883, mqtts://broker:8883, wss://broker/mqtt. defaults to the datasource url
+	Topics []string `json:"topics"`           // topic filters. + and # wildcards are supported
+	Mode   MQTTMode `json:"mode,omitempty"`   // 'window' (default) | 'retained'
+	Window string   `json:"window,omitempty"` // how long the messages are collected. ex: 5s. defaults to 2s
+	QoS    byte     `json:"qos,omitempty"`    // 0 (default) | 1 | 2
+}
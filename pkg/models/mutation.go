@@ -0,0 +1,8 @@
+package models
+
+// MutationOptions are the options of the mutation source. Mutation queries send the request of the query with the method,
+// using the url, headers and body of the query, and return the parsed response. The request is sent only by the POST /mutations
+// resource call. Panel queries of the mutation source only validate it, so the dashboard loads and refreshes never send it
+type MutationOptions struct {
+	Method string `json:"method,omitempty"` // 'POST' (default) | 'PUT' | 'PATCH' | 'DELETE'
+}
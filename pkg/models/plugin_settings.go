@@ -0,0 +1,103 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PluginSettings are the settings shared by all the datasources of the plugin. They are set by the grafana server admins in the
+// [plugin.yesoreyeram-infinity-datasource] section of the grafana config, which grafana passes to the plugin as the GF_PLUGIN_*
+// environment variables. The host paths and the options of the cache shared by the datasources are plugin settings, as the
+// datasource settings can be edited by the org admins.
+// ex: export_directory = /var/lib/grafana/infinity-exports
+type PluginSettings struct {
+	// ExportDirectory is the directory of the file exports. File exports are disabled when empty
+	ExportDirectory string
+	// KubeconfigDirectory is the directory of the kubeconfig files selected by the datasources. Datasources can only use
+	// the default kubeconfig of the plugin process when empty
+	KubeconfigDirectory string
+	// CacheDir is the directory of the persistent cache. Cache is kept in memory when empty
+	CacheDir string
+	// CacheEncryptionKey enables the encryption at rest of the persistent cache. Key must be 16, 24 or 32 characters long
+	CacheEncryptionKey string
+	// CachePreviousEncryptionKey is the key the cache was encrypted with, before rotating to CacheEncryptionKey
+	CachePreviousEncryptionKey string
+	CacheMaxBytes              int64
+	CacheMaxEntries            int
+	CacheGCInterval            time.Duration
+	CacheGCDiscardRatio        float64
+	invalid                    []string
+}
+
+// LoadPluginSettings reads the plugin settings from the environment variables passed by grafana.
+// Invalid values are left unset and reported by Validate
+func LoadPluginSettings() PluginSettings {
+	s := PluginSettings{
+		ExportDirectory:            getPluginSettingPath("export_directory"),
+		KubeconfigDirectory:        getPluginSettingPath("kubeconfig_directory"),
+		CacheDir:                   getPluginSettingPath("cache_dir"),
+		CacheEncryptionKey:         getPluginSetting("cache_encryption_key"),
+		CachePreviousEncryptionKey: getPluginSetting("cache_previous_encryption_key"),
+	}
+	if v := getPluginSetting("cache_max_bytes"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			s.CacheMaxBytes = n
+		} else {
+			s.invalid = append(s.invalid, "cache_max_bytes")
+		}
+	}
+	if v := getPluginSetting("cache_max_entries"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			s.CacheMaxEntries = n
+		} else {
+			s.invalid = append(s.invalid, "cache_max_entries")
+		}
+	}
+	if v := getPluginSetting("cache_gc_interval"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			s.CacheGCInterval = d
+		} else {
+			s.invalid = append(s.invalid, "cache_gc_interval")
+		}
+	}
+	if v := getPluginSetting("cache_gc_discard_ratio"); v != "" {
+		if r, err := strconv.ParseFloat(v, 64); err == nil {
+			s.CacheGCDiscardRatio = r
+		} else {
+			s.invalid = append(s.invalid, "cache_gc_discard_ratio")
+		}
+	}
+	return s
+}
+
+// Validate reports the invalid plugin settings
+func (s PluginSettings) Validate() error {
+	if len(s.invalid) > 0 {
+		return fmt.Errorf("invalid plugin settings %s", strings.Join(s.invalid, ", "))
+	}
+	for _, key := range []string{s.CacheEncryptionKey, s.CachePreviousEncryptionKey} {
+		if l := len(key); l != 0 && l != 16 && l != 24 && l != 32 {
+			return errors.New("cache encryption key should be 16, 24 or 32 characters long")
+		}
+	}
+	return nil
+}
+
+func getPluginSetting(key string) string {
+	return strings.TrimSpace(os.Getenv("GF_PLUGIN_" + strings.ToUpper(key)))
+}
+
+// getPluginSettingPath returns the cleaned absolute path of the setting. Relative paths are ignored,
+// as they would depend on the working directory of the plugin process
+func getPluginSettingPath(key string) string {
+	path := getPluginSetting(key)
+	if path == "" || !filepath.IsAbs(path) {
+		return ""
+	}
+	return filepath.Clean(path)
+}
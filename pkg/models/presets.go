@@ -0,0 +1,103 @@
+package models
+
+import "strings"
+
+// SourcePreset configures the pagination and the root of the well known APIs, so only the url and the credentials are required
+type SourcePreset string
+
+const (
+	// SourcePresetJira pages through the jira search api (/rest/api/2/search) with startAt/maxResults
+	SourcePresetJira SourcePreset = "jira"
+	// SourcePresetServiceNow pages through the servicenow table api (/api/now/table/<table>) with sysparm_offset/sysparm_limit
+	SourcePresetServiceNow SourcePreset = "servicenow"
+	// SourcePresetGitHub pages through the github rest api with the Link header and per_page
+	SourcePresetGitHub SourcePreset = "github"
+	// SourcePresetGitLab pages through the gitlab rest api with the Link header and per_page
+	SourcePresetGitLab SourcePreset = "gitlab"
+	// SourcePresetKubernetes lists the kubernetes resources (/api/v1/pods, /apis/<group>/<version>/<resource>) with the limit and continue token
+	SourcePresetKubernetes SourcePreset = "kubernetes"
+	// SourcePresetDocker converts the containers (/containers/json), images (/images/json) and stats (/containers/<id>/stats) of the docker or podman engine api
+	SourcePresetDocker SourcePreset = "docker"
+)
+
+// applySourcePreset fills the parser, root selector and the pagination of the preset. Fields set in the query are retained
+func applySourcePreset(query Query) Query {
+	var rootSelector, sizeField, offsetField string
+	var size int
+	switch query.SourcePreset {
+	case SourcePresetGitHub, SourcePresetGitLab:
+		query.Type = QueryTypeJSON
+		query.Parser = InfinityParserBackend
+		if query.PageMode == "" {
+			query.PageMode = PaginationModeLink
+		}
+		if query.PageMode == PaginationModeLink && query.PageParamSizeFieldName == "" {
+			query.PageParamSizeFieldName = "per_page"
+			if query.PageParamSizeFieldVal == 0 {
+				query.PageParamSizeFieldVal = 100
+			}
+		}
+		return query
+	case SourcePresetKubernetes:
+		query.Type = QueryTypeJSON
+		query.Parser = InfinityParserBackend
+		if query.RootSelector == "" {
+			query.RootSelector = "items"
+		}
+		if query.PageMode == "" {
+			query.PageMode = PaginationModeCursor
+		}
+		if query.PageMode == PaginationModeCursor && query.PageParamCursorFieldName == "" {
+			query.PageParamCursorFieldName = "continue"
+			query.PageParamCursorFieldExtractionPath = "metadata.continue"
+			if !hasParam(query.URLOptions.Params, "limit") {
+				query.URLOptions.Params = append(query.URLOptions.Params, URLOptionKeyValuePair{Key: "limit", Value: "500"})
+			}
+		}
+		return query
+	case SourcePresetDocker:
+		query.Type = QueryTypeJSON
+		query.Parser = InfinityParserBackend
+		// stats are streamed every second unless stream=false
+		if strings.HasSuffix(strings.Split(query.URL, "?")[0], "/stats") && !hasParam(query.URLOptions.Params, "stream") && !strings.Contains(query.URL, "stream=") {
+			query.URLOptions.Params = append(query.URLOptions.Params, URLOptionKeyValuePair{Key: "stream", Value: "false"})
+		}
+		return query
+	case SourcePresetJira:
+		rootSelector, sizeField, offsetField, size = "issues", "maxResults", "startAt", 100
+	case SourcePresetServiceNow:
+		rootSelector, sizeField, offsetField, size = "result", "sysparm_limit", "sysparm_offset", 1000
+	default:
+		return query
+	}
+	query.Type = QueryTypeJSON
+	query.Parser = InfinityParserBackend
+	if query.RootSelector == "" {
+		query.RootSelector = rootSelector
+	}
+	if query.PageMode == "" {
+		query.PageMode = PaginationModeOffset
+	}
+	if query.PageMode != PaginationModeOffset {
+		return query
+	}
+	if query.PageParamSizeFieldName == "" {
+		query.PageParamSizeFieldName = sizeField
+	}
+	if query.PageParamSizeFieldVal == 0 {
+		query.PageParamSizeFieldVal = size
+	}
+	if query.PageParamOffsetFieldName == "" {
+		query.PageParamOffsetFieldName = offsetField
+	}
+	return query
+}
+
+func hasParam(params []URLOptionKeyValuePair, key string) bool {
+	for _, p := range params {
+		if p.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,20 @@
+package models
+
+// ProbeMode is how the targets of the probe source are checked
+type ProbeMode string
+
+const (
+	// ProbeModeTCP connects to the host:port of the target
+	ProbeModeTCP ProbeMode = "tcp"
+	// ProbeModeTLS connects to the host:port of the target and completes the tls handshake
+	ProbeModeTLS ProbeMode = "tls"
+	// ProbeModeICMP sends the icmp echo requests to the host of the target
+	ProbeModeICMP ProbeMode = "icmp"
+)
+
+// ProbeOptions are the targets of the probe source. Failed probes are returned as the rows with the success column set to false
+type ProbeOptions struct {
+	Mode    ProbeMode `json:"mode,omitempty"`  // 'tcp' (default) | 'tls' | 'icmp'
+	Targets []string  `json:"targets"`         // host:port for tcp and tls, host for icmp. ex: db.example.com:5432
+	Count   int       `json:"count,omitempty"` // number of attempts per target. defaults to 1
+}
@@ -23,6 +23,9 @@ const (
 	QueryTypeGROQ            QueryType = "groq"
 	QueryTypeGSheets         QueryType = "google-sheets"
 	QueryTypeTransformations QueryType = "transformations"
+	QueryTypeSQL             QueryType = "sql"
+	QueryTypeLogs            QueryType = "logs"
+	QueryTypeArrow           QueryType = "arrow"
 )
 
 type InfinityParser string
@@ -43,6 +46,34 @@ const (
 	PaginationModePage   PaginationMode = "page"
 	PaginationModeCursor PaginationMode = "cursor"
 	PaginationModeList   PaginationMode = "list"
+	PaginationModeLink   PaginationMode = "link" // follows the rel="next" url of the Link response header
+)
+
+type PaginationErrorMode string
+
+const (
+	PaginationErrorModeFailFast   PaginationErrorMode = "fail-fast"
+	PaginationErrorModeBestEffort PaginationErrorMode = "best-effort"
+)
+
+// CacheMode selects what is cached for the queries with the cache key (cacheq header)
+type CacheMode string
+
+const (
+	// CacheModeRaw caches the response body, which is parsed on every cache hit
+	CacheModeRaw CacheMode = "raw"
+	// CacheModeFrame caches the parsed frame in the arrow format, so the cache hits skip the parsing
+	CacheModeFrame CacheMode = "frame"
+)
+
+// ResultsAPI selects the adapter for the asynchronous results APIs of the data warehouses
+type ResultsAPI string
+
+const (
+	// ResultsAPIBigQuery polls the jobs.query / jobs.insert response with jobs.getQueryResults and pages through the rows
+	ResultsAPIBigQuery ResultsAPI = "bigquery"
+	// ResultsAPIDatabricks polls the SQL statement execution API and pages through the inline result chunks
+	ResultsAPIDatabricks ResultsAPI = "databricks"
 )
 
 type PaginationParamType string
@@ -55,6 +86,61 @@ const (
 	PaginationParamTypeReplace  PaginationParamType = "replace"
 )
 
+type DownsampleMode string
+
+const (
+	DownsampleModeNone    DownsampleMode = "none"
+	DownsampleModeLTTB    DownsampleMode = "lttb"
+	DownsampleModeAverage DownsampleMode = "average"
+)
+
+type ColumnFunctionType string
+
+const (
+	ColumnFunctionRate          ColumnFunctionType = "rate"
+	ColumnFunctionDelta         ColumnFunctionType = "delta"
+	ColumnFunctionCumulativeSum ColumnFunctionType = "cumulative_sum"
+	ColumnFunctionMovingAverage ColumnFunctionType = "moving_average"
+	ColumnFunctionEWMA          ColumnFunctionType = "ewma"
+	ColumnFunctionOutlier       ColumnFunctionType = "outlier"
+)
+
+type ResampleFill string
+
+const (
+	ResampleFillNull     ResampleFill = "null"
+	ResampleFillPrevious ResampleFill = "previous"
+	ResampleFillZero     ResampleFill = "zero"
+	ResampleFillLinear   ResampleFill = "linear"
+)
+
+// ColumnFunction computes the rate, delta, cumulative sum, the smoothed values or the outlier flags of the numeric column over the time ordered rows
+type ColumnFunction struct {
+	Field     string             `json:"field"`
+	Function  ColumnFunctionType `json:"function"`
+	Alias     string             `json:"alias,omitempty"`
+	Window    int                `json:"window,omitempty"`    // number of points used by the moving average and the outlier detection. also used to derive the ewma alpha when alpha is not set
+	Alpha     float64            `json:"alpha,omitempty"`     // smoothing factor of ewma between 0 and 1
+	Method    string             `json:"method,omitempty"`    // outlier detection method. 'mad' (default) | 'sigma'
+	Threshold float64            `json:"threshold,omitempty"` // number of MADs or standard deviations from the window median/mean to flag the row as outlier
+}
+
+type PreParseStepType string
+
+const (
+	PreParseRegexReplace PreParseStepType = "regex_replace"
+	PreParseJSONUnwrap   PreParseStepType = "json_unwrap"
+	PreParseBase64Decode PreParseStepType = "base64_decode"
+)
+
+// PreParseStep transforms the response body before parsing. Steps are applied in the order
+type PreParseStep struct {
+	Type        PreParseStepType `json:"type"`
+	Pattern     string           `json:"pattern,omitempty"`     // regular expression of regex_replace
+	Replacement string           `json:"replacement,omitempty"` // replacement of regex_replace. $1 refers the capture groups
+	Selector    string           `json:"selector,omitempty"`    // dot separated path of the string field for json_unwrap and base64_decode. * matches all the array items. empty means the whole body
+}
+
 type Transformation string
 
 const (
@@ -63,6 +149,9 @@ const (
 	FilterExpressionTransformation Transformation = "filterExpression"
 	SummarizeTransformation        Transformation = "summarize"
 	ComputedColumnTransformation   Transformation = "computedColumn"
+	SparklineTransformation        Transformation = "sparkline"
+	IPEnrichmentTransformation     Transformation = "ipEnrichment"
+	HeatmapTransformation          Transformation = "heatmap"
 )
 
 type TransformationItem struct {
@@ -83,13 +172,34 @@ type TransformationItem struct {
 		Expression string `json:"expression,omitempty"`
 		Alias      string `json:"alias,omitempty"`
 	} `json:"computedColumn,omitempty"`
+	Sparkline struct {
+		Field     string `json:"field,omitempty"`
+		Delimiter string `json:"delimiter,omitempty"`
+		Alias     string `json:"alias,omitempty"`
+	} `json:"sparkline,omitempty"`
+	IPEnrichment struct {
+		Field      string    `json:"field,omitempty"`
+		ReverseDNS bool      `json:"reverseDNS,omitempty"`
+		CIDRTags   []CIDRTag `json:"cidrTags,omitempty"`
+	} `json:"ipEnrichment,omitempty"`
+	Heatmap struct {
+		TimeField   string `json:"timeField,omitempty"`
+		BucketField string `json:"bucketField,omitempty"`
+		CountField  string `json:"countField,omitempty"`
+		Cumulative  bool   `json:"cumulative,omitempty"`
+	} `json:"heatmap,omitempty"`
+}
+
+type CIDRTag struct {
+	CIDR  string `json:"cidr"`
+	Label string `json:"label"`
 }
 
 type Query struct {
 	RefID                              string                 `json:"refId"`
 	Type                               QueryType              `json:"type"`   // 'json' | 'json-backend' | 'csv' | 'tsv' | 'xml' | 'graphql' | 'html' | 'uql' | 'groq' | 'series' | 'global' | 'google-sheets'
-	Format                             string                 `json:"format"` // 'table' | 'timeseries' | 'logs' | 'dataframe' | 'as-is' | 'node-graph-nodes' | 'node-graph-edges'
-	Source                             string                 `json:"source"` // 'url' | 'inline' | 'azure-blob' | 'reference' | 'random-walk' | 'expression'
+	Format                             string                 `json:"format"` // 'table' | 'timeseries' | 'numeric' | 'logs' | 'trace' | 'dataframe' | 'as-is' | 'node-graph-nodes' | 'node-graph-edges' | 'node-graph'
+	Source                             string                 `json:"source"` // 'url' | 'inline' | 'azure-blob' | 'reference' | 'random-walk' | 'expression' | 'snmp' | 'mqtt' | 'redis' | 'database' | 'ldap' | 'dns' | 'probe' | 'certificate' | 'rdap' | 'crawl' | 'webhook'
 	RefName                            string                 `json:"referenceName,omitempty"`
 	URL                                string                 `json:"url"`
 	URLOptions                         URLOptions             `json:"url_options"`
@@ -135,7 +245,90 @@ type Query struct {
 	PageParamListFieldName             string                 `json:"pagination_param_list_field_name,omitempty"`
 	PageParamListFieldType             PaginationParamType    `json:"pagination_param_list_field_type,omitempty"`
 	PageParamListFieldValue            string                 `json:"pagination_param_list_value,omitempty"`
+	PageErrorMode                      PaginationErrorMode    `json:"pagination_error_mode,omitempty"` // 'fail-fast' | 'best-effort'
 	Transformations                    []TransformationItem   `json:"transformations,omitempty"`
+	CoalesceWindowSeconds              int                    `json:"coalesce_window_seconds,omitempty"`
+	IncrementalWatermarkField          string                 `json:"incremental_watermark_field,omitempty"`
+	IncrementalParamName               string                 `json:"incremental_param_name,omitempty"`
+	IncrementalParamType               PaginationParamType    `json:"incremental_param_type,omitempty"`
+	IncrementalInitialValue            string                 `json:"incremental_initial_value,omitempty"`
+	IncrementalKey                     string                 `json:"incremental_key,omitempty"`
+	DownsampleMode                     DownsampleMode         `json:"downsample_mode,omitempty"`
+	DownsamplePoints                   int                    `json:"downsample_points,omitempty"`
+	ColumnFunctions                    []ColumnFunction       `json:"column_functions,omitempty"`
+	ResampleInterval                   string                 `json:"resample_interval,omitempty"` // ex: 1m, 1h, $__interval
+	ResampleFill                       ResampleFill           `json:"resample_fill,omitempty"`
+	MaterializeAs                      string                 `json:"materialize_as,omitempty"`
+	MaterializeTTLSeconds              int64                  `json:"materialize_ttl_seconds,omitempty"`
+	SQLQuery                           string                 `json:"sql_query,omitempty"`
+	SQLDatasets                        []string               `json:"sql_datasets,omitempty"`
+	SQLConnection                      string                 `json:"sql_connection,omitempty"`        // name of the sql connection of the datasource. sql_query is run against it in the database source
+	Charset                            string                 `json:"charset,omitempty"`               // 'auto' | any charset name. ex: 'iso-8859-1', 'shift_jis', 'utf-16'
+	ResponseContentType                string                 `json:"response_content_type,omitempty"` // overrides the content type returned by the server
+	LogPattern                         string                 `json:"log_pattern,omitempty"`           // grok pattern or regular expression with named groups
+	TraceOptions                       *TraceOptions          `json:"trace_options,omitempty"`
+	NodeGraphOptions                   *NodeGraphOptions      `json:"node_graph_options,omitempty"`
+	ForecastOptions                    *ForecastOptions       `json:"forecast_options,omitempty"`
+	HeaderProfile                      string                 `json:"header_profile,omitempty"` // name of the header profile. empty selects the profile by host. 'none' disables the profiles
+	PreParse                           []PreParseStep         `json:"pre_parse,omitempty"`
+	SOAPOptions                        *SOAPOptions           `json:"soap_options,omitempty"`
+	DryRun                             bool                   `json:"dry_run,omitempty"`        // returns only the columns and the row count of the results
+	SampleFixture                      string                 `json:"sample_fixture,omitempty"` // name of the sample response stored in the datasource
+	UseSample                          bool                   `json:"use_sample,omitempty"`     // executes the query against the sample response instead of the live API
+	CacheMode                          CacheMode              `json:"cache_mode,omitempty"`     // 'raw' (default) | 'frame'
+	ResultsAPI                         ResultsAPI             `json:"results_api,omitempty"`    // 'bigquery' | 'databricks'
+	ElasticsearchOptions               *ElasticsearchOptions  `json:"elasticsearch_options,omitempty"`
+	SourcePreset                       SourcePreset           `json:"source_preset,omitempty"` // 'jira' | 'servicenow'
+	SNMPOptions                        *SNMPOptions           `json:"snmp_options,omitempty"`
+	MQTTOptions                        *MQTTOptions           `json:"mqtt_options,omitempty"`
+	RedisOptions                       *RedisOptions          `json:"redis_options,omitempty"`
+	LDAPOptions                        *LDAPOptions           `json:"ldap_options,omitempty"`
+	DNSOptions                         *DNSOptions            `json:"dns_options,omitempty"`
+	ProbeOptions                       *ProbeOptions          `json:"probe_options,omitempty"`
+	CertificateOptions                 *CertificateOptions    `json:"certificate_options,omitempty"`
+	RDAPOptions                        *RDAPOptions           `json:"rdap_options,omitempty"`
+	CrawlOptions                       *CrawlOptions          `json:"crawl_options,omitempty"`
+	WebhookOptions                     *WebhookOptions        `json:"webhook_options,omitempty"`
+	MutationOptions                    *MutationOptions       `json:"mutation_options,omitempty"`
+	Assertions                         []Assertion            `json:"assertions,omitempty"` // url queries with the assertions return the pass/fail frame of the assertions
+	Chain                              []ChainStep            `json:"chain,omitempty"`      // requests sent before the url query, whose extracted values replace the {{name}} placeholders of the query
+	CookieJar                          *CookieJarOptions      `json:"cookie_jar,omitempty"`
+	Migrations                         []string               `json:"-"` // logs of the migrations applied while loading the query
+}
+
+// TraceOptions maps the columns of the results into the trace fields. Columns are looked up by the common names when not specified
+type TraceOptions struct {
+	TraceID       string `json:"trace_id,omitempty"`
+	SpanID        string `json:"span_id,omitempty"`
+	ParentSpanID  string `json:"parent_span_id,omitempty"`
+	OperationName string `json:"operation_name,omitempty"`
+	ServiceName   string `json:"service_name,omitempty"`
+	StartTime     string `json:"start_time,omitempty"`
+	Duration      string `json:"duration,omitempty"`
+	DurationUnit  string `json:"duration_unit,omitempty"` // 'ms' | 'us' | 'ns' | 's'
+}
+
+// SOAPOptions are used when the body type is soap. The body is wrapped in the SOAP envelope unless it is already an envelope
+type SOAPOptions struct {
+	Version     string `json:"version,omitempty"`      // '1.1' (default) | '1.2'
+	Action      string `json:"action,omitempty"`       // SOAPAction of the operation
+	ResultXPath string `json:"result_xpath,omitempty"` // xpath of the result element. ex: //GetWeatherResult
+}
+
+// NodeGraphOptions are the selectors of the nodes and edges arrays, used by the node-graph format
+type NodeGraphOptions struct {
+	NodesSelector string `json:"nodes_selector,omitempty"`
+	EdgesSelector string `json:"edges_selector,omitempty"`
+}
+
+// ForecastOptions configures the Holt-Winters forecast of the numeric field. Forecast is returned as a separate frame
+type ForecastOptions struct {
+	Field       string  `json:"field"`
+	Horizon     int     `json:"horizon,omitempty"`     // number of future points to predict. defaults to 10
+	Seasonality int     `json:"seasonality,omitempty"` // number of points in a season. 0 disables the seasonal component
+	Alpha       float64 `json:"alpha,omitempty"`       // level smoothing factor
+	Beta        float64 `json:"beta,omitempty"`        // trend smoothing factor
+	Gamma       float64 `json:"gamma,omitempty"`       // seasonal smoothing factor
 }
 
 type URLOptionKeyValuePair struct {
@@ -153,6 +346,7 @@ type URLOptions struct {
 	BodyForm             []URLOptionKeyValuePair `json:"body_form"`
 	BodyGraphQLQuery     string                  `json:"body_graphql_query"`
 	BodyGraphQLVariables string                  `json:"body_graphql_variables"`
+	BodyFormFiles        []string                `json:"body_form_files,omitempty"` // names of the form files configured in the datasource, attached to the form-data body
 }
 
 type InfinityCSVOptions struct {
@@ -162,6 +356,10 @@ type InfinityCSVOptions struct {
 	RelaxColumnCount   bool   `json:"relax_column_count"`
 	Columns            string `json:"columns"`
 	Comment            string `json:"comment"`
+	Quote              string `json:"quote,omitempty"`
+	SkipHeaderLines    int    `json:"skip_header_lines,omitempty"`
+	SkipFooterLines    int    `json:"skip_footer_lines,omitempty"`
+	FixedWidths        string `json:"fixed_widths,omitempty"`
 }
 
 type InfinityJSONOptions struct {
@@ -170,10 +368,18 @@ type InfinityJSONOptions struct {
 }
 
 type InfinityColumn struct {
-	Selector        string `json:"selector"`
-	Text            string `json:"text"`
-	Type            string `json:"type"` // "string" | "number" | "timestamp" | "timestamp_epoch" | "timestamp_epoch_s" | "boolean"
-	TimeStampFormat string `json:"timestampFormat"`
+	Selector        string                 `json:"selector"`
+	Text            string                 `json:"text"`
+	Type            string                 `json:"type"` // "string" | "number" | "timestamp" | "timestamp_epoch" | "timestamp_epoch_s" | "boolean" | "duration" | "latitude" | "longitude" | "geohash" | "latlon"
+	TimeStampFormat string                 `json:"timestampFormat"`
+	ParseFormatted  bool                   `json:"parseFormattedNumber,omitempty"`
+	Unit            string                 `json:"unit,omitempty"`
+	ValueMappings   []InfinityValueMapping `json:"valueMappings,omitempty"`
+}
+
+type InfinityValueMapping struct {
+	Value string `json:"value"`
+	Text  string `json:"text"`
 }
 
 type InfinityFilter struct {
@@ -213,6 +419,9 @@ func ApplyDefaultsToQuery(ctx context.Context, query Query) Query {
 	if query.Type == QueryTypeHTML && query.Source == "url" && query.URL == "" {
 		query.URL = "https://raw.githubusercontent.com/yesoreyeram/grafana-infinity-datasource/main/testdata/users.html"
 	}
+	if query.URLOptions.BodyType == "soap" {
+		query.URLOptions.Method = "POST"
+	}
 	if query.Source == "url" && strings.ToUpper(query.URLOptions.Method) == "POST" {
 		if query.URLOptions.BodyType == "" {
 			query.URLOptions.BodyType = "raw"
@@ -243,6 +452,21 @@ func ApplyDefaultsToQuery(ctx context.Context, query Query) Query {
 	if query.Type == QueryTypeJSON && query.Parser == InfinityParserGROQ && query.GROQ == "" {
 		query.GROQ = "*"
 	}
+	if query.Type == QueryTypeArrow {
+		query.Parser = InfinityParserBackend
+	}
+	if query.Source == "url" {
+		query = applySourcePreset(query)
+	}
+	if query.ResultsAPI != "" || query.ElasticsearchOptions != nil {
+		query.Parser = InfinityParserBackend
+	}
+	if query.Type == QueryTypeLogs {
+		query.Parser = InfinityParserBackend
+		if query.Format == "" {
+			query.Format = "logs"
+		}
+	}
 	if query.Columns == nil {
 		query.Columns = []InfinityColumn{}
 	}
@@ -257,13 +481,14 @@ func ApplyDefaultsToQuery(ctx context.Context, query Query) Query {
 			if query.PageMaxPages >= 5 {
 				query.PageMaxPages = 5
 			}
-			if query.PageParamSizeFieldName == "" {
+			// next links of the link pagination already carry the page size. so the size parameter is optional
+			if query.PageParamSizeFieldName == "" && query.PageMode != PaginationModeLink {
 				query.PageParamSizeFieldName = "limit"
 			}
 			if query.PageParamSizeFieldType == "" {
 				query.PageParamSizeFieldType = PaginationParamTypeQuery
 			}
-			if query.PageParamSizeFieldVal == 0 {
+			if query.PageParamSizeFieldVal == 0 && query.PageParamSizeFieldName != "" {
 				query.PageParamSizeFieldVal = 1000
 			}
 		}
@@ -298,6 +523,17 @@ func ApplyDefaultsToQuery(ctx context.Context, query Query) Query {
 			}
 		}
 	}
+	if query.IncrementalWatermarkField != "" {
+		if query.IncrementalParamType == "" {
+			query.IncrementalParamType = PaginationParamTypeReplace
+		}
+		if query.IncrementalParamName == "" && query.IncrementalParamType == PaginationParamTypeReplace {
+			query.IncrementalParamName = "${__since}"
+		}
+		if query.IncrementalParamName == "" {
+			query.IncrementalParamName = "since"
+		}
+	}
 	for i, t := range query.Transformations {
 		if t.Type == "" {
 			query.Transformations[i].Type = NoOpTransformation
@@ -306,15 +542,93 @@ func ApplyDefaultsToQuery(ctx context.Context, query Query) Query {
 	return query
 }
 
+// ApplyQueryDefaults applies the query defaults of the datasource to the empty fields of the query.
+// ApplyDefaultsToQuery needs to be applied afterwards, so the pagination defaults are filled for the default pagination mode
+func ApplyQueryDefaults(query Query, defaults QueryDefaults) Query {
+	switch query.Type {
+	case QueryTypeJSON, QueryTypeGraphQL, QueryTypeXML, QueryTypeHTML:
+		if query.RootSelector == "" {
+			query.RootSelector = defaults.RootSelector
+		}
+	}
+	if query.Source != "url" {
+		return query
+	}
+	for _, header := range defaults.Headers {
+		if !hasHeader(query.URLOptions.Headers, header.Key) {
+			query.URLOptions.Headers = append(query.URLOptions.Headers, header)
+		}
+	}
+	if defaults.CacheTTL != "" && hasHeader(query.URLOptions.Headers, "cacheq") && !hasHeader(query.URLOptions.Headers, "cachettl") {
+		query.URLOptions.Headers = append(query.URLOptions.Headers, URLOptionKeyValuePair{Key: "cachettl", Value: defaults.CacheTTL})
+	}
+	if query.PageMode == "" && defaults.PaginationMode != "" {
+		query.PageMode = defaults.PaginationMode
+		if query.PageMaxPages == 0 {
+			query.PageMaxPages = defaults.PaginationMaxPages
+		}
+		if query.PageParamSizeFieldName == "" {
+			query.PageParamSizeFieldName = defaults.PaginationSizeFieldName
+		}
+		if query.PageParamSizeFieldVal == 0 {
+			query.PageParamSizeFieldVal = defaults.PaginationSizeValue
+		}
+	}
+	return query
+}
+
+func hasHeader(headers []URLOptionKeyValuePair, key string) bool {
+	for _, h := range headers {
+		if strings.EqualFold(h.Key, key) {
+			return true
+		}
+	}
+	return false
+}
+
 func LoadQuery(ctx context.Context, backendQuery backend.DataQuery, pluginContext backend.PluginContext) (Query, error) {
 	var query Query
-	err := json.Unmarshal(backendQuery.JSON, &query)
+	queryJSON, migrations, err := migrateQueryJSON(backendQuery.JSON)
+	if err != nil {
+		return query, fmt.Errorf("error while migrating the query json. %s", err.Error())
+	}
+	if err := ValidateQueryJSON(queryJSON); err != nil {
+		return query, fmt.Errorf("invalid query json. %w", err)
+	}
+	err = json.Unmarshal(queryJSON, &query)
 	if err != nil {
 		return query, fmt.Errorf("error while parsing the query json. %s", err.Error())
 	}
+	query.Migrations = migrations
 	query = ApplyDefaultsToQuery(ctx, query)
+	if query.DownsampleMode != "" && query.DownsampleMode != DownsampleModeNone && query.DownsamplePoints <= 0 {
+		query.DownsamplePoints = int(backendQuery.MaxDataPoints)
+	}
 	if query.PageMode == PaginationModeList && strings.TrimSpace(query.PageParamListFieldName) == "" {
 		return query, errors.New("pagination_param_list_field_name cannot be empty")
 	}
-	return ApplyMacros(ctx, query, backendQuery.TimeRange, pluginContext)
+	query = ApplyIntervalVariables(query, backendQuery.Interval)
+	query, err = ApplyMacros(ctx, query, backendQuery.TimeRange, pluginContext)
+	if err != nil {
+		return query, err
+	}
+	return ApplyElasticsearchOptions(query, backendQuery.TimeRange, pluginContext)
+}
+
+// ApplyHeadlessDefaultsToQuery prepares the query for the requests made without the grafana frontend such as alerting,
+// recorded queries and server side expressions. Results of those requests are never parsed in the browser,
+// so the simple parser is replaced with the backend parser and the frontend only parsers are rejected
+func ApplyHeadlessDefaultsToQuery(ctx context.Context, query Query) (Query, error) {
+	switch query.Type {
+	case QueryTypeUQL, QueryTypeGROQ:
+		return query, fmt.Errorf("%s queries are parsed in the browser and can't be used in alerting, recorded queries or expressions. use json query type with backend parser instead", query.Type)
+	case QueryTypeJSON, QueryTypeCSV, QueryTypeTSV, QueryTypeXML, QueryTypeHTML, QueryTypeGraphQL:
+		switch query.Parser {
+		case "", InfinityParserSimple:
+			query.Parser = InfinityParserBackend
+		case InfinityParserUQL, InfinityParserGROQ:
+			return query, fmt.Errorf("%s parser runs in the browser and can't be used in alerting, recorded queries or expressions. use backend parser instead", query.Parser)
+		}
+	}
+	return ApplyDefaultsToQuery(ctx, query), nil
 }
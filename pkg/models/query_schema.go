@@ -0,0 +1,285 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// QueryFieldError is the validation error of a single field of the query json. Field is the path of the field such as url_options.headers[0].key
+type QueryFieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e QueryFieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// QueryValidationError is the list of the field errors of the query json
+type QueryValidationError []QueryFieldError
+
+func (e QueryValidationError) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldError := range e {
+		messages[i] = fieldError.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+type schemaField struct {
+	Name string
+	Type reflect.Type
+}
+
+var querySchema = sync.OnceValue(func() map[string]any {
+	schema := typeSchema(reflect.TypeOf(Query{}), map[reflect.Type]bool{})
+	// schema_version is read by the migrations before the query is parsed
+	schema["properties"].(map[string]any)["schema_version"] = map[string]any{"type": "integer", "maximum": LatestQuerySchemaVersion}
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "Infinity query"
+	return schema
+})
+
+// QuerySchema returns the json schema of the query model. Unknown fields are allowed, so the queries saved by the newer
+// versions of the frontend are still valid
+func QuerySchema() map[string]any {
+	return querySchema()
+}
+
+// ValidateQueryJSON validates the query json against the query model. Unknown fields are tolerated unless they only differ
+// from a known field by the case, underscores or hyphens, such as rootSelector instead of root_selector, which would be ignored otherwise.
+// Returns QueryValidationError with the errors of all the fields
+func ValidateQueryJSON(input json.RawMessage) error {
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	decoder.UseNumber()
+	var query any
+	if err := decoder.Decode(&query); err != nil {
+		return err
+	}
+	errs := validateValue(query, reflect.TypeOf(Query{}), "", QueryValidationError{})
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateValue(value any, t reflect.Type, path string, errs QueryValidationError) QueryValidationError {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if value == nil {
+		return errs
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return append(errs, QueryFieldError{Field: fieldPath(path), Message: fmt.Sprintf("expected object, got %s", jsonTypeOf(value))})
+		}
+		fields := structFields(t)
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			field, ok := lookupField(fields, key)
+			if ok {
+				errs = validateValue(object[key], field.Type, joinPath(path, key), errs)
+				continue
+			}
+			if suggestion, ok := lookupSimilarField(fields, key); ok {
+				errs = append(errs, QueryFieldError{Field: joinPath(path, key), Message: fmt.Sprintf("unknown field. did you mean %s?", suggestion)})
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return validateKind(value, "string", path, errs)
+		}
+		items, ok := value.([]any)
+		if !ok {
+			return append(errs, QueryFieldError{Field: fieldPath(path), Message: fmt.Sprintf("expected array, got %s", jsonTypeOf(value))})
+		}
+		for i, item := range items {
+			errs = validateValue(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case reflect.Map:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return append(errs, QueryFieldError{Field: fieldPath(path), Message: fmt.Sprintf("expected object, got %s", jsonTypeOf(value))})
+		}
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			errs = validateValue(object[key], t.Elem(), joinPath(path, key), errs)
+		}
+	case reflect.String:
+		return validateKind(value, "string", path, errs)
+	case reflect.Bool:
+		return validateKind(value, "boolean", path, errs)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return validateKind(value, "integer", path, errs)
+	case reflect.Float32, reflect.Float64:
+		return validateKind(value, "number", path, errs)
+	}
+	return errs
+}
+
+func validateKind(value any, kind string, path string, errs QueryValidationError) QueryValidationError {
+	got := jsonTypeOf(value)
+	if got == kind || (kind == "number" && got == "integer") {
+		return errs
+	}
+	return append(errs, QueryFieldError{Field: fieldPath(path), Message: fmt.Sprintf("expected %s, got %s", kind, got)})
+}
+
+func jsonTypeOf(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// lookupField finds the field the same way as encoding/json, which prefers the exact match over the case insensitive match
+func lookupField(fields []schemaField, key string) (schemaField, bool) {
+	for _, field := range fields {
+		if field.Name == key {
+			return field, true
+		}
+	}
+	for _, field := range fields {
+		if strings.EqualFold(field.Name, key) {
+			return field, true
+		}
+	}
+	return schemaField{}, false
+}
+
+func lookupSimilarField(fields []schemaField, key string) (string, bool) {
+	normalized := normalizeFieldName(key)
+	for _, field := range fields {
+		if normalizeFieldName(field.Name) == normalized {
+			return field.Name, true
+		}
+	}
+	return "", false
+}
+
+func normalizeFieldName(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(name))
+}
+
+// structFields returns the json fields of the struct including the fields of the embedded structs
+func structFields(t reflect.Type) []schemaField {
+	fields := []schemaField{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				fields = append(fields, structFields(embedded)...)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, schemaField{Name: name, Type: field.Type})
+	}
+	return fields
+}
+
+func typeSchema(t reflect.Type, visiting map[reflect.Type]bool) map[string]any {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t, nullable = t.Elem(), true
+	}
+	withType := func(schema map[string]any, kind string) map[string]any {
+		if nullable {
+			schema["type"] = []string{kind, "null"}
+			return schema
+		}
+		schema["type"] = kind
+		return schema
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if visiting[t] {
+			return map[string]any{}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		properties := map[string]any{}
+		for _, field := range structFields(t) {
+			properties[field.Name] = typeSchema(field.Type, visiting)
+		}
+		return withType(map[string]any{"properties": properties, "additionalProperties": true}, "object")
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return withType(map[string]any{}, "string")
+		}
+		nullable = nullable || t.Kind() == reflect.Slice
+		return withType(map[string]any{"items": typeSchema(t.Elem(), visiting)}, "array")
+	case reflect.Map:
+		nullable = true
+		return withType(map[string]any{"additionalProperties": typeSchema(t.Elem(), visiting)}, "object")
+	case reflect.String:
+		return withType(map[string]any{}, "string")
+	case reflect.Bool:
+		return withType(map[string]any{}, "boolean")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return withType(map[string]any{}, "integer")
+	case reflect.Float32, reflect.Float64:
+		return withType(map[string]any{}, "number")
+	default:
+		return map[string]any{}
+	}
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func fieldPath(path string) string {
+	if path == "" {
+		return "query"
+	}
+	return path
+}
@@ -0,0 +1,98 @@
+package models_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+)
+
+func TestValidateQueryJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{
+			name:  "valid query",
+			input: `{ "refId" : "A", "type" : "json", "root_selector" : "users", "columns" : [{ "selector" : "name", "type" : "string" }], "seriesCount" : 2 }`,
+		},
+		{
+			name:  "unknown fields from the newer frontend and the grafana fields",
+			input: `{ "refId" : "A", "datasource" : { "uid" : "foo" }, "hide" : false, "intervalMs" : 1000, "some_new_option" : { "enabled" : true } }`,
+		},
+		{
+			name:  "case insensitive field name",
+			input: `{ "RefID" : "A" }`,
+		},
+		{
+			name:  "null values",
+			input: `{ "columns" : null, "url_options" : null, "trace_options" : null }`,
+		},
+		{
+			name:    "field name typo",
+			input:   `{ "refId" : "A", "rootSelector" : "users" }`,
+			wantErr: "rootSelector: unknown field. did you mean root_selector?",
+		},
+		{
+			name:    "nested field name typo",
+			input:   `{ "url_options" : { "method" : "POST", "bodyType" : "raw", "headers" : [{ "key" : "foo", "Value" : "bar" }] } }`,
+			wantErr: "url_options.bodyType: unknown field. did you mean body_type?",
+		},
+		{
+			name:    "wrong types",
+			input:   `{ "seriesCount" : "2", "columns" : [{ "selector" : "name" }, { "selector" : 1 }], "csv_options" : { "skip_empty_lines" : "true" }, "downsample_points" : 1.5 }`,
+			wantErr: "columns[1].selector: expected string, got integer; csv_options.skip_empty_lines: expected boolean, got string; downsample_points: expected integer, got number; seriesCount: expected integer, got string",
+		},
+		{
+			name:    "wrong type of the query",
+			input:   `[]`,
+			wantErr: "query: expected object, got array",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := models.ValidateQueryJSON(json.RawMessage(tt.input))
+			if tt.wantErr == "" {
+				require.Nil(t, err)
+				return
+			}
+			require.NotNil(t, err)
+			require.Equal(t, tt.wantErr, err.Error())
+			var validationErr models.QueryValidationError
+			require.True(t, errors.As(err, &validationErr))
+		})
+	}
+	t.Run("should reject the invalid query while loading", func(t *testing.T) {
+		_, err := models.LoadQuery(context.Background(), backend.DataQuery{JSON: []byte(`{ "refId" : "A", "rootSelector" : "users" }`)}, backend.PluginContext{})
+		require.NotNil(t, err)
+		require.Equal(t, "invalid query json. rootSelector: unknown field. did you mean root_selector?", err.Error())
+	})
+}
+
+func TestQuerySchema(t *testing.T) {
+	b, err := json.Marshal(models.QuerySchema())
+	require.Nil(t, err)
+	var schema struct {
+		Type                 string `json:"type"`
+		AdditionalProperties bool   `json:"additionalProperties"`
+		Properties           map[string]struct {
+			Type       any            `json:"type"`
+			Properties map[string]any `json:"properties"`
+			Items      map[string]any `json:"items"`
+		} `json:"properties"`
+	}
+	require.Nil(t, json.Unmarshal(b, &schema))
+	require.Equal(t, "object", schema.Type)
+	require.True(t, schema.AdditionalProperties)
+	require.Equal(t, "string", schema.Properties["root_selector"].Type)
+	require.Equal(t, "integer", schema.Properties["seriesCount"].Type)
+	require.Equal(t, []any{"array", "null"}, schema.Properties["columns"].Type)
+	require.Contains(t, schema.Properties["columns"].Items["properties"], "selector")
+	require.Contains(t, schema.Properties["url_options"].Properties, "body_type")
+	require.Equal(t, []any{"object", "null"}, schema.Properties["trace_options"].Type)
+}
@@ -133,3 +133,40 @@ func TestLoadQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyQueryDefaults(t *testing.T) {
+	defaults := models.QueryDefaults{
+		RootSelector:            "data",
+		Headers:                 []models.URLOptionKeyValuePair{{Key: "Accept", Value: "application/json"}, {Key: "X-Org", Value: "1"}},
+		PaginationMode:          models.PaginationModeOffset,
+		PaginationMaxPages:      3,
+		PaginationSizeFieldName: "size",
+		PaginationSizeValue:     100,
+		CacheTTL:                "5m",
+	}
+	t.Run("should apply the defaults to the empty fields", func(t *testing.T) {
+		query := models.Query{Type: models.QueryTypeJSON, Source: "url", URLOptions: models.URLOptions{Headers: []models.URLOptionKeyValuePair{{Key: "x-org", Value: "2"}, {Key: "cacheq", Value: "users"}}}}
+		got := models.ApplyQueryDefaults(query, defaults)
+		require.Equal(t, "data", got.RootSelector)
+		require.Equal(t, []models.URLOptionKeyValuePair{{Key: "x-org", Value: "2"}, {Key: "cacheq", Value: "users"}, {Key: "Accept", Value: "application/json"}, {Key: "cachettl", Value: "5m"}}, got.URLOptions.Headers)
+		require.Equal(t, models.PaginationModeOffset, got.PageMode)
+		require.Equal(t, 3, got.PageMaxPages)
+		require.Equal(t, "size", got.PageParamSizeFieldName)
+		require.Equal(t, 100, got.PageParamSizeFieldVal)
+	})
+	t.Run("should not override the query fields", func(t *testing.T) {
+		query := models.Query{Type: models.QueryTypeJSON, Source: "url", RootSelector: "items", PageMode: models.PaginationModeNone}
+		got := models.ApplyQueryDefaults(query, defaults)
+		require.Equal(t, "items", got.RootSelector)
+		require.Equal(t, models.PaginationModeNone, got.PageMode)
+		require.Equal(t, 0, got.PageMaxPages)
+	})
+	t.Run("should apply only the root selector to the inline queries", func(t *testing.T) {
+		got := models.ApplyQueryDefaults(models.Query{Type: models.QueryTypeCSV, Source: "inline"}, defaults)
+		require.Equal(t, "", got.RootSelector)
+		require.Nil(t, got.URLOptions.Headers)
+		got = models.ApplyQueryDefaults(models.Query{Type: models.QueryTypeJSON, Source: "inline"}, defaults)
+		require.Equal(t, "data", got.RootSelector)
+		require.Equal(t, models.PaginationMode(""), got.PageMode)
+	})
+}
@@ -0,0 +1,7 @@
+package models
+
+// RDAPOptions are the domains of the rdap source
+type RDAPOptions struct {
+	Domains []string `json:"domains"`          // ex: example.com
+	Server  string   `json:"server,omitempty"` // base url of the rdap service. defaults to https://rdap.org, which redirects to the registry of the domain
+}
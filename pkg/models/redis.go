@@ -0,0 +1,7 @@
+package models
+
+// RedisOptions is the read only command of the redis source, sent to the redis instance of the datasource settings
+type RedisOptions struct {
+	Command string   `json:"command"`        // ex: GET, MGET, HGETALL, LRANGE, ZRANGE, SCAN, INFO
+	Args    []string `json:"args,omitempty"` // arguments of the command. SCAN arguments exclude the cursor. ex: MATCH, orders:*
+}
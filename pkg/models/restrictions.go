@@ -0,0 +1,35 @@
+package models
+
+import "fmt"
+
+// QueryRestrictions lock down the dangerous options of the queries per datasource. Restrictions are enforced by the backend
+// for the users below the ExemptRole, whatever the dashboard json contains. Requests without the user, such as alerting,
+// are always restricted. Sources reaching the plugin host and the mutations are denied unless allowed explicitly
+type QueryRestrictions struct {
+	AllowLocalHost     bool   `json:"allowLocalHost,omitempty"`     // sources reaching the plugin host, such as the docker socket, crawl, database, the file exports and the network sources targeting the loopback or link-local hosts
+	AllowMutations     bool   `json:"allowMutations,omitempty"`     // mutation (write-back) queries
+	RequireHTTPS       bool   `json:"requireHttps,omitempty"`       // urls of the query and the chain steps should use https
+	BlockCustomHeaders bool   `json:"blockCustomHeaders,omitempty"` // headers of the query and the chain steps. headers of the datasource are still sent
+	ExemptRole         string `json:"exemptRole,omitempty"`         // 'Viewer' | 'Editor' | 'Admin'. users with the role or a higher role are not restricted. defaults to Admin
+}
+
+// DefaultQueryRestrictionsExemptRole is the role exempted from the query restrictions when the exempt role is not configured
+const DefaultQueryRestrictionsExemptRole = "Admin"
+
+var roleRanks = map[string]int{"Viewer": 1, "Editor": 2, "Admin": 3}
+
+// AppliesTo reports whether the restrictions apply to the user with the grafana org role
+func (r QueryRestrictions) AppliesTo(role string) bool {
+	exempt, ok := roleRanks[r.ExemptRole]
+	if !ok {
+		exempt = roleRanks[DefaultQueryRestrictionsExemptRole]
+	}
+	return roleRanks[role] < exempt
+}
+
+func (r QueryRestrictions) validate() error {
+	if _, ok := roleRanks[r.ExemptRole]; r.ExemptRole != "" && !ok {
+		return fmt.Errorf("invalid exempt role %s of the query restrictions. role should be Viewer, Editor or Admin", r.ExemptRole)
+	}
+	return nil
+}
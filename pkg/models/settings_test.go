@@ -3,6 +3,7 @@ package models_test
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/stretchr/testify/assert"
@@ -232,6 +233,40 @@ func TestAllSettingsAgainstFrontEnd(t *testing.T) {
 	}, gotSettings)
 }
 
+func TestLoadSettingsHeaderProfiles(t *testing.T) {
+	gotSettings, err := models.LoadSettings(backend.DataSourceInstanceSettings{
+		JSONData: []byte(`{ "headerProfiles" : [
+			{ "name" : "github", "hosts" : ["api.github.com"], "headers" : ["Authorization"] },
+			{ "name" : "internal", "hosts" : ["*.example.com"], "headers" : ["X-Api-Key", "X-Org"] }
+		]}`),
+		DecryptedSecureJSONData: map[string]string{
+			"headerProfile1Value1": "token foo",
+			"headerProfile2Value1": "key",
+			"headerProfile2Value2": "org",
+		},
+	})
+	require.Nil(t, err)
+	require.Equal(t, []models.HeaderProfile{
+		{Name: "github", Hosts: []string{"api.github.com"}, HeaderNames: []string{"Authorization"}, Headers: map[string]string{"Authorization": "token foo"}},
+		{Name: "internal", Hosts: []string{"*.example.com"}, HeaderNames: []string{"X-Api-Key", "X-Org"}, Headers: map[string]string{"X-Api-Key": "key", "X-Org": "org"}},
+	}, gotSettings.HeaderProfiles)
+}
+
+func TestLoadSettingsQueryDefaults(t *testing.T) {
+	gotSettings, err := models.LoadSettings(backend.DataSourceInstanceSettings{
+		JSONData: []byte(`{ "queryDefaults" : { "rootSelector" : "data", "headers" : [{ "key" : "Accept", "value" : "application/json" }], "paginationMode" : "page", "cacheTTL" : "5m" } }`),
+	})
+	require.Nil(t, err)
+	require.Equal(t, models.QueryDefaults{
+		RootSelector:   "data",
+		Headers:        []models.URLOptionKeyValuePair{{Key: "Accept", Value: "application/json"}},
+		PaginationMode: models.PaginationModePage,
+		CacheTTL:       "5m",
+	}, gotSettings.QueryDefaults)
+	gotSettings.QueryDefaults.CacheTTL = "5 minutes"
+	require.Equal(t, "invalid default cache ttl 5 minutes", gotSettings.Validate().Error())
+}
+
 func Test_getSecrets(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -320,6 +355,14 @@ func TestInfinitySettings_Validate(t *testing.T) {
 			settings: models.InfinitySettings{AuthenticationMethod: models.AuthenticationMethodNone, CustomHeaders: map[string]string{"A": "B"}},
 			wantErr:  errors.New("configure allowed hosts in the authentication section"),
 		},
+		{
+			settings: models.InfinitySettings{AuthenticationMethod: models.AuthenticationMethodNone, HeaderProfiles: []models.HeaderProfile{{Name: "github", Headers: map[string]string{"Authorization": "token"}}}},
+			wantErr:  errors.New("configure allowed hosts in the authentication section"),
+		},
+		{
+			settings: models.InfinitySettings{AuthenticationMethod: models.AuthenticationMethodNone, HeaderProfiles: []models.HeaderProfile{{Name: "github"}, {Name: "github"}}},
+			wantErr:  errors.New("duplicate header profile github"),
+		},
 		{
 			settings: models.InfinitySettings{AuthenticationMethod: models.AuthenticationMethodNone, CustomHeaders: map[string]string{"A": "B", "Accept": ""}},
 			wantErr:  errors.New("configure allowed hosts in the authentication section"),
@@ -381,3 +424,95 @@ func TestInfinitySettings_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestGetSettingsHash(t *testing.T) {
+	config := backend.DataSourceInstanceSettings{
+		ID:                      1,
+		URL:                     "https://foo.com",
+		Updated:                 time.Unix(1, 0),
+		JSONData:                []byte(`{ "auth_method" : "bearerToken", "timeoutInSeconds" : 10 }`),
+		DecryptedSecureJSONData: map[string]string{"bearerToken": "token1"},
+	}
+	hash := models.GetSettingsHash(config)
+	t.Run("should not change when saved without changes", func(t *testing.T) {
+		updated := config
+		updated.Updated = time.Unix(2, 0)
+		updated.JSONData = []byte(`{"timeoutInSeconds":10,"auth_method":"bearerToken"}`)
+		require.Equal(t, hash, models.GetSettingsHash(updated))
+	})
+	t.Run("should change when the settings or secrets change", func(t *testing.T) {
+		updated := config
+		updated.JSONData = []byte(`{ "auth_method" : "bearerToken", "timeoutInSeconds" : 20 }`)
+		require.NotEqual(t, hash, models.GetSettingsHash(updated))
+		updated = config
+		updated.DecryptedSecureJSONData = map[string]string{"bearerToken": "token2"}
+		require.NotEqual(t, hash, models.GetSettingsHash(updated))
+	})
+}
+
+func TestLoadSettingsFeatureFlags(t *testing.T) {
+	settings, err := models.LoadSettings(backend.DataSourceInstanceSettings{})
+	require.Nil(t, err)
+	require.True(t, settings.IsFeatureEnabled(models.FeatureFlagScheduler))
+	require.False(t, settings.IsFeatureEnabled(models.FeatureFlagOpenAPI))
+	settings, err = models.LoadSettings(backend.DataSourceInstanceSettings{JSONData: []byte(`{ "featureFlags" : { "scheduler" : false, "openApi" : true } }`)})
+	require.Nil(t, err)
+	require.False(t, settings.IsFeatureEnabled(models.FeatureFlagScheduler))
+	require.True(t, settings.IsFeatureEnabled(models.FeatureFlagOpenAPI))
+	require.Nil(t, settings.Validate())
+	settings, err = models.LoadSettings(backend.DataSourceInstanceSettings{JSONData: []byte(`{ "featureFlags" : { "schedular" : false, "scheduler" : false } }`)})
+	require.Nil(t, err)
+	require.Nil(t, settings.Validate())
+	require.Equal(t, map[string]bool{"scheduler": false}, settings.FeatureFlags)
+}
+
+func TestQueryRestrictions(t *testing.T) {
+	settings, err := models.LoadSettings(backend.DataSourceInstanceSettings{JSONData: []byte(`{ "queryRestrictions" : { "requireHttps" : true, "exemptRole" : "Editor" } }`)})
+	require.Nil(t, err)
+	require.Equal(t, models.QueryRestrictions{RequireHTTPS: true, ExemptRole: "Editor"}, settings.QueryRestrictions)
+	require.True(t, settings.QueryRestrictions.AppliesTo("Viewer"))
+	require.True(t, settings.QueryRestrictions.AppliesTo(""))
+	require.False(t, settings.QueryRestrictions.AppliesTo("Editor"))
+	require.False(t, settings.QueryRestrictions.AppliesTo("Admin"))
+	require.True(t, models.QueryRestrictions{}.AppliesTo("Editor"))
+	require.False(t, models.QueryRestrictions{}.AppliesTo("Admin"))
+	require.Nil(t, settings.Validate())
+	settings.QueryRestrictions.ExemptRole = "editor"
+	require.Equal(t, "invalid exempt role editor of the query restrictions. role should be Viewer, Editor or Admin", settings.Validate().Error())
+}
+
+func TestLoadPluginSettings(t *testing.T) {
+	t.Setenv("GF_PLUGIN_EXPORT_DIRECTORY", "/var/lib/grafana/exports/")
+	require.Equal(t, models.PluginSettings{ExportDirectory: "/var/lib/grafana/exports"}, models.LoadPluginSettings())
+	settings, err := models.LoadSettings(backend.DataSourceInstanceSettings{JSONData: []byte(`{ "exportDirectory" : "/etc" }`)})
+	require.Nil(t, err)
+	require.Equal(t, "/var/lib/grafana/exports", settings.ExportDirectory)
+	t.Setenv("GF_PLUGIN_EXPORT_DIRECTORY", "exports")
+	require.Equal(t, models.PluginSettings{}, models.LoadPluginSettings())
+	t.Setenv("GF_PLUGIN_KUBECONFIG_DIRECTORY", "/etc/grafana/kube")
+	settings, err = models.LoadSettings(backend.DataSourceInstanceSettings{JSONData: []byte(`{ "auth_method" : "kubernetes", "kubernetes" : { "kubeconfigPath" : "prod.yaml", "KubeconfigDirectory" : "/" } }`)})
+	require.Nil(t, err)
+	require.Equal(t, "/etc/grafana/kube", settings.KubernetesSettings.KubeconfigDirectory)
+	require.Equal(t, "prod.yaml", settings.KubernetesSettings.KubeconfigPath)
+	t.Run("should read the cache options", func(t *testing.T) {
+		t.Setenv("GF_PLUGIN_CACHE_DIR", "/var/lib/grafana/infinity-cache")
+		t.Setenv("GF_PLUGIN_CACHE_ENCRYPTION_KEY", "0123456789abcdef")
+		t.Setenv("GF_PLUGIN_CACHE_MAX_BYTES", "1048576")
+		t.Setenv("GF_PLUGIN_CACHE_MAX_ENTRIES", "1000")
+		t.Setenv("GF_PLUGIN_CACHE_GC_INTERVAL", "10m")
+		t.Setenv("GF_PLUGIN_CACHE_GC_DISCARD_RATIO", "0.5")
+		s := models.LoadPluginSettings()
+		require.Nil(t, s.Validate())
+		require.Equal(t, "/var/lib/grafana/infinity-cache", s.CacheDir)
+		require.Equal(t, "0123456789abcdef", s.CacheEncryptionKey)
+		require.Equal(t, int64(1048576), s.CacheMaxBytes)
+		require.Equal(t, 1000, s.CacheMaxEntries)
+		require.Equal(t, 10*time.Minute, s.CacheGCInterval)
+		require.Equal(t, 0.5, s.CacheGCDiscardRatio)
+		t.Setenv("GF_PLUGIN_CACHE_MAX_BYTES", "1MB")
+		require.Equal(t, "invalid plugin settings cache_max_bytes", models.LoadPluginSettings().Validate().Error())
+		t.Setenv("GF_PLUGIN_CACHE_MAX_BYTES", "")
+		t.Setenv("GF_PLUGIN_CACHE_ENCRYPTION_KEY", "short")
+		require.Equal(t, "cache encryption key should be 16, 24 or 32 characters long", models.LoadPluginSettings().Validate().Error())
+	})
+}
@@ -0,0 +1,29 @@
+package models
+
+// SNMPOperation is the request sent to the snmp agent
+type SNMPOperation string
+
+const (
+	SNMPOperationGet  SNMPOperation = "get"
+	SNMPOperationWalk SNMPOperation = "walk"
+)
+
+// SNMPOptions are the target and the oids of the snmp source. the community and the v3 credentials are in the datasource settings
+type SNMPOptions struct {
+	Target    string        `json:"target"`              // host or host:port of the agent. port defaults to 161
+	Operation SNMPOperation `json:"operation,omitempty"` // 'get' (default) | 'walk'
+	OIDs      []string      `json:"oids"`                // ex: .1.3.6.1.2.1.1.5.0 for get, .1.3.6.1.2.1.2.2 to walk the interfaces table
+}
+
+// SNMPSettings are the protocol version and the credentials of the snmp agents.
+// community and the passphrases are read from the secure settings
+type SNMPSettings struct {
+	Version        string `json:"version,omitempty"`       // '2c' (default) | '1' | '3'
+	Username       string `json:"username,omitempty"`      // v3 security name
+	SecurityLevel  string `json:"securityLevel,omitempty"` // v3 'noAuthNoPriv' | 'authNoPriv' (default) | 'authPriv'
+	AuthProtocol   string `json:"authProtocol,omitempty"`  // v3 'MD5' | 'SHA' (default) | 'SHA224' | 'SHA256' | 'SHA384' | 'SHA512'
+	PrivProtocol   string `json:"privProtocol,omitempty"`  // v3 'DES' | 'AES' (default) | 'AES192' | 'AES256'
+	Community      string `json:"-"`
+	AuthPassphrase string `json:"-"`
+	PrivPassphrase string `json:"-"`
+}
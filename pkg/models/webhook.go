@@ -0,0 +1,17 @@
+package models
+
+// WebhookMode is which of the payloads pushed to the webhook channel are returned
+type WebhookMode string
+
+const (
+	// WebhookModeLatest returns the latest payload, parsed the same way as the response of the url source
+	WebhookModeLatest WebhookMode = "latest"
+	// WebhookModeAll returns the rows of all the payloads kept by the channel, oldest first
+	WebhookModeAll WebhookMode = "all"
+)
+
+// WebhookOptions are the webhook channel and the mode of the webhook source
+type WebhookOptions struct {
+	Channel string      `json:"channel"`
+	Mode    WebhookMode `json:"mode,omitempty"` // 'latest' (default) | 'all'
+}
@@ -1,15 +1,20 @@
 package pluginhost
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/resource/httpadapter"
 	"github.com/graphql-go/graphql"
 	"github.com/graphql-go/handler"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
 	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
 )
 
@@ -18,7 +23,33 @@ func (host *PluginHost) getRouter() *mux.Router {
 	router.Handle("/graphql", host.getGraphQLHandler()) // NOT IN USE YET
 	router.HandleFunc("/reference-data", host.withDatasourceHandlerFunc(GetReferenceDataHandler)).Methods("GET")
 	router.HandleFunc("/open-api", host.withDatasourceHandlerFunc(GetOpenAPIHandler)).Methods("GET") // NOT IN USE YET
+	router.HandleFunc("/open-api/operations", host.withDatasourceHandlerFunc(withFeatureFlag(models.FeatureFlagOpenAPI, GetOpenAPIOperationsHandler))).Methods("GET")
+	router.HandleFunc("/api-operations", host.withDatasourceHandlerFunc(GetAPIOperationsHandler)).Methods("GET")
+	router.HandleFunc("/import/postman", host.withDatasourceHandlerFunc(ImportPostmanCollectionHandler)).Methods("POST")
+	router.HandleFunc("/import/curl", host.withDatasourceHandlerFunc(ImportCurlCommandHandler)).Methods("POST")
+	router.HandleFunc("/export/curl", host.withDatasourceHandlerFunc(ExportCurlCommandHandler)).Methods("POST")
+	router.HandleFunc("/query-schema", host.withDatasourceHandlerFunc(GetQuerySchemaHandler)).Methods("GET")
+	router.HandleFunc("/lint-query", host.withDatasourceHandlerFunc(LintQueryHandler)).Methods("POST")
+	router.HandleFunc("/mutations", host.withDatasourceHandlerFunc(RunMutationHandler)).Methods("POST")
 	router.HandleFunc("/ping", host.withDatasourceHandlerFunc(GetPingHandler)).Methods("GET")
+	router.HandleFunc("/scheduled-queries", host.withDatasourceHandlerFunc(withFeatureFlag(models.FeatureFlagScheduler, GetScheduledQueriesHandler))).Methods("GET")
+	router.HandleFunc("/scheduled-queries", withAdminRole(host.withDatasourceHandlerFunc(withFeatureFlag(models.FeatureFlagScheduler, RegisterScheduledQueryHandler)))).Methods("POST")
+	router.HandleFunc("/scheduled-queries/{id}", withAdminRole(host.withDatasourceHandlerFunc(withFeatureFlag(models.FeatureFlagScheduler, RemoveScheduledQueryHandler)))).Methods("DELETE")
+	router.HandleFunc("/scheduled-exports", host.withDatasourceHandlerFunc(withFeatureFlag(models.FeatureFlagScheduler, GetScheduledExportsHandler))).Methods("GET")
+	router.HandleFunc("/scheduled-exports", withAdminRole(host.withDatasourceHandlerFunc(withFeatureFlag(models.FeatureFlagScheduler, RegisterScheduledExportHandler)))).Methods("POST")
+	router.HandleFunc("/scheduled-exports/{id}/run", withAdminRole(host.withDatasourceHandlerFunc(withFeatureFlag(models.FeatureFlagScheduler, RunScheduledExportHandler)))).Methods("POST")
+	router.HandleFunc("/scheduled-exports/{id}", withAdminRole(host.withDatasourceHandlerFunc(withFeatureFlag(models.FeatureFlagScheduler, RemoveScheduledExportHandler)))).Methods("DELETE")
+	router.HandleFunc("/cache", withAdminRole(host.withDatasourceHandlerFunc(PurgeCacheHandler))).Methods("DELETE")
+	router.HandleFunc("/cache/gc", withAdminRole(host.withDatasourceHandlerFunc(GetCacheGCHandler))).Methods("GET")
+	router.HandleFunc("/cache/backup", withAdminRole(host.withDatasourceHandlerFunc(BackupCacheHandler))).Methods("GET")
+	router.HandleFunc("/cache/restore", withAdminRole(host.withDatasourceHandlerFunc(RestoreCacheHandler))).Methods("POST")
+	router.HandleFunc("/datasets", host.withDatasourceHandlerFunc(GetDatasetsHandler)).Methods("GET")
+	router.HandleFunc("/datasets/{name}/refresh", withAdminRole(host.withDatasourceHandlerFunc(RefreshDatasetHandler))).Methods("POST")
+	router.HandleFunc("/datasets/{name}", withAdminRole(host.withDatasourceHandlerFunc(DeleteDatasetHandler))).Methods("DELETE")
+	router.HandleFunc("/fixtures", host.withDatasourceHandlerFunc(GetFixturesHandler)).Methods("GET")
+	router.HandleFunc("/fixtures/{name}", withAdminRole(host.withDatasourceHandlerFunc(SaveFixtureHandler))).Methods("PUT")
+	router.HandleFunc("/fixtures/{name}", withAdminRole(host.withDatasourceHandlerFunc(DeleteFixtureHandler))).Methods("DELETE")
+	router.HandleFunc("/webhooks/{channel}", host.withDatasourceHandlerFunc(ReceiveWebhookHandler)).Methods("POST")
 	router.NotFoundHandler = http.HandlerFunc(host.withDatasourceHandlerFunc(defaultHandler))
 	return router
 }
@@ -35,6 +66,30 @@ func (host *PluginHost) withDatasourceHandlerFunc(getHandler func(d *instanceSet
 	}
 }
 
+// withAdminRole allows only the Grafana admins to call the handler
+func withAdminRole(h http.HandlerFunc) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		pCtx := httpadapter.PluginConfigFromContext(r.Context())
+		if pCtx.User == nil || pCtx.User.Role != "Admin" {
+			http.Error(rw, "only admins are allowed to perform this operation", http.StatusForbidden)
+			return
+		}
+		h(rw, r)
+	}
+}
+
+// withFeatureFlag rejects the requests when the feature is not enabled in the datasource
+func withFeatureFlag(flag models.FeatureFlag, getHandler func(d *instanceSettings) http.HandlerFunc) func(d *instanceSettings) http.HandlerFunc {
+	return func(client *instanceSettings) http.HandlerFunc {
+		if !client.client.Settings.IsFeatureEnabled(flag) {
+			return func(rw http.ResponseWriter, r *http.Request) {
+				http.Error(rw, fmt.Sprintf("%s feature is not enabled. enable it in the featureFlags of the datasource settings", flag), http.StatusForbidden)
+			}
+		}
+		return getHandler(client)
+	}
+}
+
 func (host *PluginHost) getGraphQLHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		client, err := getInstanceFromRequest(r.Context(), host.im, r)
@@ -130,6 +185,147 @@ func GetOpenAPIHandler(client *instanceSettings) http.HandlerFunc {
 	}
 }
 
+// GetOpenAPIOperationsHandler returns the operations of the OpenAPI document configured in the datasource settings
+func GetOpenAPIOperationsHandler(client *instanceSettings) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		operations, err := infinity.GetOpenAPIOperations(r.Context(), *client.client)
+		if err != nil {
+			statusCode := http.StatusBadGateway
+			if infinity.GetErrorKind(err) == infinity.ErrorKindUser {
+				statusCode = http.StatusBadRequest
+			}
+			http.Error(rw, err.Error(), statusCode)
+			return
+		}
+		writeJSON(rw, http.StatusOK, operations)
+	}
+}
+
+// GetAPIOperationsHandler returns the operations of the WSDL or OpenAPI document of the url query parameter
+func GetAPIOperationsHandler(client *instanceSettings) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		operations, err := infinity.GetAPIOperations(r.Context(), *client.client, r.URL.Query().Get("url"))
+		if err != nil {
+			statusCode := http.StatusBadGateway
+			if infinity.GetErrorKind(err) == infinity.ErrorKindUser {
+				statusCode = http.StatusBadRequest
+			}
+			http.Error(rw, err.Error(), statusCode)
+			return
+		}
+		writeJSON(rw, http.StatusOK, operations)
+	}
+}
+
+// maxImportBytes limits the size of the imported collections
+const maxImportBytes = 20 * 1024 * 1024
+
+// ImportPostmanCollectionHandler converts the requests of the Postman collection in the request body into the infinity queries.
+// Requests can be selected using the request query parameter
+func ImportPostmanCollectionHandler(client *instanceSettings) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(rw, r.Body, maxImportBytes))
+		if err != nil {
+			http.Error(rw, fmt.Sprintf("error reading the collection. %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		queries, err := infinity.ImportPostmanCollection(body, r.URL.Query()["request"])
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(rw, http.StatusOK, queries)
+	}
+}
+
+// ImportCurlCommandHandler converts the curl command in the request body into the infinity query
+func ImportCurlCommandHandler(client *instanceSettings) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(rw, r.Body, maxImportBytes))
+		if err != nil {
+			http.Error(rw, fmt.Sprintf("error reading the command. %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		query, err := infinity.ParseCurlCommand(string(body))
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(rw, http.StatusOK, query)
+	}
+}
+
+// ExportCurlCommandHandler returns the curl command of the query in the request body. Secrets are masked in the command
+func ExportCurlCommandHandler(client *instanceSettings) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		var query models.Query
+		if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, maxImportBytes)).Decode(&query); err != nil {
+			http.Error(rw, fmt.Sprintf("invalid query. %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		query = models.ApplyDefaultsToQuery(r.Context(), query)
+		command, err := infinity.GetCurlCommand(r.Context(), client.client.Settings, query)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(rw, http.StatusOK, map[string]string{"command": command})
+	}
+}
+
+// LintQueryHandler statically checks the query in the request body and returns the problems found
+func LintQueryHandler(client *instanceSettings) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		var query models.Query
+		if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, maxImportBytes)).Decode(&query); err != nil {
+			http.Error(rw, fmt.Sprintf("invalid query. %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		writeJSON(rw, http.StatusOK, infinity.LintQuery(client.client.Settings, query))
+	}
+}
+
+// RunMutationHandler sends the mutation query in the request body. This is the explicit action of the user,
+// as the mutations in the panel queries are only validated and never sent on the dashboard load or refresh
+func RunMutationHandler(client *instanceSettings) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		var query models.Query
+		if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, maxImportBytes)).Decode(&query); err != nil {
+			http.Error(rw, fmt.Sprintf("invalid query. %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		query = models.ApplyDefaultsToQuery(r.Context(), query)
+		if query.Source != "mutation" {
+			http.Error(rw, "invalid query. source should be mutation", http.StatusBadRequest)
+			return
+		}
+		if err := infinity.CheckQueryRestrictions(r.Context(), client.client.Settings, query, httpadapter.PluginConfigFromContext(r.Context()).User); err != nil {
+			http.Error(rw, err.Error(), http.StatusForbidden)
+			return
+		}
+		requestHeaders := map[string]string{}
+		for k := range r.Header {
+			requestHeaders[k] = r.Header.Get(k)
+		}
+		frame, err := infinity.GetFrameForMutation(infinity.WithMutationAction(r.Context()), query, *client.client, requestHeaders)
+		if err != nil {
+			status := http.StatusBadGateway
+			if infinity.GetErrorKind(err) == infinity.ErrorKindUser {
+				status = http.StatusBadRequest
+			}
+			http.Error(rw, err.Error(), status)
+			return
+		}
+		writeJSON(rw, http.StatusOK, frame)
+	}
+}
+
+func GetQuerySchemaHandler(client *instanceSettings) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		writeJSON(rw, http.StatusOK, models.QuerySchema())
+	}
+}
+
 func GetReferenceDataHandler(client *instanceSettings) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		referenceKeys := []string{}
@@ -152,6 +348,300 @@ func GetPingHandler(client *instanceSettings) http.HandlerFunc {
 	}
 }
 
+func GetScheduledQueriesHandler(client *instanceSettings) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		writeJSON(rw, http.StatusOK, client.scheduler.List())
+	}
+}
+
+func RegisterScheduledQueryHandler(client *instanceSettings) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		var item infinity.ScheduledQuery
+		if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+			http.Error(rw, fmt.Sprintf("invalid scheduled query. %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		if err := infinity.CheckQueryRestrictions(r.Context(), client.client.Settings, item.Query, httpadapter.PluginConfigFromContext(r.Context()).User); err != nil {
+			http.Error(rw, err.Error(), http.StatusForbidden)
+			return
+		}
+		item, err := client.scheduler.Register(item)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(rw, http.StatusOK, item)
+	}
+}
+
+func RemoveScheduledQueryHandler(client *instanceSettings) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if err := client.scheduler.Remove(mux.Vars(r)["id"]); err != nil {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func GetScheduledExportsHandler(client *instanceSettings) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		writeJSON(rw, http.StatusOK, client.scheduler.ListExports())
+	}
+}
+
+func RegisterScheduledExportHandler(client *instanceSettings) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		var item infinity.ScheduledExport
+		if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+			http.Error(rw, fmt.Sprintf("invalid scheduled export. %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		user := httpadapter.PluginConfigFromContext(r.Context()).User
+		if err := infinity.CheckExportRestrictions(r.Context(), client.client.Settings, item, user); err != nil {
+			http.Error(rw, err.Error(), http.StatusForbidden)
+			return
+		}
+		item.Role = ""
+		if user != nil {
+			item.Role = user.Role
+		}
+		item, err := client.scheduler.RegisterExport(item)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(rw, http.StatusOK, item)
+	}
+}
+
+func RunScheduledExportHandler(client *instanceSettings) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		item, err := client.scheduler.RunExport(mux.Vars(r)["id"])
+		if err != nil {
+			status := http.StatusBadGateway
+			if errors.Is(err, infinity.ErrScheduledExportNotFound) {
+				status = http.StatusNotFound
+			}
+			http.Error(rw, err.Error(), status)
+			return
+		}
+		writeJSON(rw, http.StatusOK, item)
+	}
+}
+
+func RemoveScheduledExportHandler(client *instanceSettings) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if err := client.scheduler.RemoveExport(mux.Vars(r)["id"]); err != nil {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func PurgeCacheHandler(client *instanceSettings) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		count, err := client.client.PurgeCache(r.Context())
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(rw, http.StatusOK, map[string]int{"deleted": count})
+	}
+}
+
+// cacheGCResponse is the garbage collection of the cache shared by the datasources
+type cacheGCResponse struct {
+	Interval     string               `json:"interval"`
+	DiscardRatio float64              `json:"discardRatio"`
+	Stats        infinity.SettGCStats `json:"stats"`
+}
+
+// GetCacheGCHandler returns the options of the garbage collection of the cache and the stats of the last run
+func GetCacheGCHandler(client *instanceSettings) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		gc := infinity.GetBadgerGC()
+		if gc == nil {
+			http.Error(rw, "cache is not open", http.StatusServiceUnavailable)
+			return
+		}
+		options := gc.Options()
+		writeJSON(rw, http.StatusOK, cacheGCResponse{Interval: options.Interval.String(), DiscardRatio: options.DiscardRatio, Stats: gc.Stats()})
+	}
+}
+
+func GetDatasetsHandler(client *instanceSettings) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		datasets, err := infinity.ListDatasets(r.Context(), *client.client)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(rw, http.StatusOK, datasets)
+	}
+}
+
+// RefreshDatasetHandler re-runs the query of the dataset, which materializes the fresh result
+func RefreshDatasetHandler(client *instanceSettings) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		dataset, err := infinity.GetDataset(r.Context(), *client.client, mux.Vars(r)["name"])
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		pCtx := httpadapter.PluginConfigFromContext(r.Context())
+		res := QueryDataQuery(r.Context(), dataset.Query, *client.client, map[string]string{}, pCtx)
+		if res.Error != nil {
+			http.Error(rw, res.Error.Error(), http.StatusInternalServerError)
+			return
+		}
+		if dataset, err = infinity.GetDataset(r.Context(), *client.client, dataset.Name); err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		dataset.Frame = nil
+		writeJSON(rw, http.StatusOK, dataset)
+	}
+}
+
+func DeleteDatasetHandler(client *instanceSettings) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if err := infinity.DeleteDataset(r.Context(), *client.client, mux.Vars(r)["name"]); err != nil {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func GetFixturesHandler(client *instanceSettings) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		fixtures, err := infinity.ListFixtures(r.Context(), *client.client)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(rw, http.StatusOK, fixtures)
+	}
+}
+
+// SaveFixtureHandler stores the request body as the sample response. The content type of the request is stored along with the fixture
+func SaveFixtureHandler(client *instanceSettings) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(rw, r.Body, maxImportBytes))
+		if err != nil {
+			http.Error(rw, fmt.Sprintf("error reading the fixture. %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		fixture, err := infinity.SaveFixture(r.Context(), *client.client, mux.Vars(r)["name"], r.Header.Get("Content-Type"), body)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fixture.Body = nil
+		writeJSON(rw, http.StatusOK, fixture)
+	}
+}
+
+func DeleteFixtureHandler(client *instanceSettings) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if err := infinity.DeleteFixture(r.Context(), *client.client, mux.Vars(r)["name"]); err != nil {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ReceiveWebhookHandler stores the request body as the payload of the webhook channel. The token of the channel is sent
+// in the X-Webhook-Token header or the token query parameter
+func ReceiveWebhookHandler(client *instanceSettings) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(rw, r.Body, maxImportBytes))
+		if err != nil {
+			http.Error(rw, fmt.Sprintf("error reading the payload. %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		token := r.Header.Get("X-Webhook-Token")
+		if token == "" {
+			token = r.URL.Query().Get("token")
+		}
+		payload, err := infinity.ReceiveWebhookPayload(r.Context(), *client.client, mux.Vars(r)["channel"], token, r.Header.Get("Content-Type"), body)
+		switch {
+		case errors.Is(err, infinity.ErrWebhookChannelNotFound):
+			http.Error(rw, err.Error(), http.StatusNotFound)
+		case errors.Is(err, infinity.ErrWebhookUnauthorized):
+			http.Error(rw, err.Error(), http.StatusUnauthorized)
+		case err != nil:
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+		default:
+			writeJSON(rw, http.StatusAccepted, payload)
+		}
+	}
+}
+
+// cacheDB returns the cache shared by all the datasource instances
+// cacheOfDatasource returns the cache namespace of the datasource. The cache is shared by the datasources of all the orgs,
+// so the resource calls never access the cache without the namespace of the datasource
+func cacheOfDatasource(rw http.ResponseWriter, client *instanceSettings) (*infinity.Sett, bool) {
+	if client.client.CacheNamespace() == "" {
+		http.Error(rw, "the cache of the datasource without id is not available", http.StatusBadRequest)
+		return nil, false
+	}
+	return client.client.Cache(), true
+}
+
+func BackupCacheHandler(client *instanceSettings) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		since, err := strconv.ParseUint(r.URL.Query().Get("since"), 10, 64)
+		if err != nil && r.URL.Query().Get("since") != "" {
+			http.Error(rw, fmt.Sprintf("invalid since version. %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		cache, ok := cacheOfDatasource(rw, client)
+		if !ok {
+			return
+		}
+		var b bytes.Buffer
+		version, err := cache.WithContext(r.Context()).Backup(&b, since)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/octet-stream")
+		rw.Header().Set("Content-Disposition", "attachment; filename=infinity-cache.bak")
+		rw.Header().Set("X-Infinity-Cache-Version", strconv.FormatUint(version, 10))
+		rw.WriteHeader(http.StatusOK)
+		rw.Write(b.Bytes()) //nolint
+	}
+}
+
+func RestoreCacheHandler(client *instanceSettings) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		cache, ok := cacheOfDatasource(rw, client)
+		if !ok {
+			return
+		}
+		if err := cache.WithContext(r.Context()).Load(r.Body); err != nil {
+			http.Error(rw, fmt.Sprintf("error restoring the cache. %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func writeJSON(rw http.ResponseWriter, statusCode int, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(statusCode)
+	rw.Write(b) //nolint
+}
+
 func defaultHandler(client *instanceSettings) http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
 		http.Error(rw, "not a known resource call", http.StatusInternalServerError)
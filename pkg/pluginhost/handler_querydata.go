@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
@@ -26,17 +29,24 @@ func (ds *PluginHost) QueryData(ctx context.Context, req *backend.QueryDataReque
 		logger.Error("error getting infinity instance", "error", err.Error())
 		return response, fmt.Errorf("error getting infinity instance. %w", err)
 	}
+	// queries run concurrently up to the limit of the instance. transformations and sql queries use the responses
+	// of the previous queries, so they wait for the previous queries to complete
+	var wg sync.WaitGroup
+	var mu sync.Mutex
 	for _, q := range req.Queries {
 		res := backend.DataResponse{}
 		query, err := models.LoadQuery(ctx, q, req.PluginContext)
 		if err != nil {
 			span.RecordError(err)
 			logger.Error("error un-marshaling the query", "error", err.Error())
-			res.Error = fmt.Errorf("error un-marshaling the query. %w", err)
-			response.Responses[q.RefID] = res
+			res.Error = infinity.UserError(fmt.Errorf("error un-marshaling the query. %w", err))
+			mu.Lock()
+			response.Responses[q.RefID] = infinity.ClassifyResponse(res)
+			mu.Unlock()
 			continue
 		}
 		if query.Type == models.QueryTypeTransformations {
+			wg.Wait()
 			response1, err := infinity.ApplyTransformations(query, response)
 			if err != nil {
 				logger.Error("error applying infinity query transformation", "error", err.Error())
@@ -47,9 +57,28 @@ func (ds *PluginHost) QueryData(ctx context.Context, req *backend.QueryDataReque
 			response = response1
 			continue
 		}
-		res = QueryDataQuery(ctx, query, *client.client, req.Headers, req.PluginContext)
-		response.Responses[q.RefID] = res
+		if query.Type == models.QueryTypeSQL {
+			wg.Wait()
+			if err := infinity.CheckQueryRestrictions(ctx, client.client.Settings, query, req.PluginContext.User); err != nil {
+				span.RecordError(err)
+				response.Responses[q.RefID] = infinity.ClassifyResponse(backend.DataResponse{Error: err})
+				continue
+			}
+			response.Responses[q.RefID] = infinity.ApplySQL(ctx, query, response, *client.client)
+			continue
+		}
+		wg.Add(1)
+		go func(refID string, query models.Query) {
+			defer wg.Done()
+			res := client.runQuery(ctx, func() backend.DataResponse {
+				return QueryDataQuery(ctx, query, *client.client, req.Headers, req.PluginContext)
+			})
+			mu.Lock()
+			response.Responses[refID] = res
+			mu.Unlock()
+		}(q.RefID, query)
 	}
+	wg.Wait()
 	return response, nil
 }
 
@@ -62,8 +91,8 @@ func QueryData(ctx context.Context, backendQuery backend.DataQuery, infClient in
 		span.RecordError(err)
 		span.SetStatus(500, err.Error())
 		logger.Error("error un-marshaling the query", "error", err.Error())
-		response.Error = fmt.Errorf("error un-marshaling the query. %w", err)
-		return response
+		response.Error = infinity.UserError(fmt.Errorf("error un-marshaling the query. %w", err))
+		return infinity.ClassifyResponse(response)
 	}
 	return QueryDataQuery(ctx, query, infClient, requestHeaders, pluginContext)
 }
@@ -77,6 +106,27 @@ func QueryDataQuery(ctx context.Context, query models.Query, infClient infinity.
 		attribute.String("url", string(query.URL)),
 	))
 	defer span.End()
+	defer func() {
+		response = infinity.ClassifyResponse(response)
+	}()
+	ctx = infinity.WithTimings(ctx)
+	query = models.ApplyDefaultsToQuery(ctx, models.ApplyQueryDefaults(query, infClient.Settings.QueryDefaults))
+	ctx, cancel := infinity.WithQueryDeadline(ctx, infClient.Settings, query)
+	defer cancel()
+	if infinity.IsHeadlessRequest(requestHeaders) {
+		q, err := models.ApplyHeadlessDefaultsToQuery(ctx, query)
+		if err != nil {
+			span.RecordError(err)
+			response.Error = infinity.UserError(err)
+			return response
+		}
+		query = q
+	}
+	if err := infinity.CheckQueryRestrictions(ctx, infClient.Settings, query, pluginContext.User); err != nil {
+		span.RecordError(err)
+		response.Error = err
+		return response
+	}
 	args := []interface{}{}
 	args = append(args, "type", query.Type)
 	args = append(args, "source", query.Source)
@@ -99,7 +149,7 @@ func QueryDataQuery(ctx context.Context, query models.Query, infClient infinity.
 			sheetRange = sheetName + "!" + sheetRange
 		}
 		if sheetId == "" {
-			response.Error = errors.New("invalid or empty sheet ID")
+			response.Error = infinity.UserError(errors.New("invalid or empty sheet ID"))
 			return response
 		}
 		query.URL = fmt.Sprintf("https://sheets.googleapis.com/v4/spreadsheets/%s?includeGridData=true&ranges=%s", sheetId, sheetRange)
@@ -119,11 +169,11 @@ func QueryDataQuery(ctx context.Context, query models.Query, infClient infinity.
 		switch query.Source {
 		case "url", "azure-blob":
 			if infClient.Settings.AuthenticationMethod != models.AuthenticationMethodAzureBlob && infClient.Settings.AuthenticationMethod != models.AuthenticationMethodNone && len(infClient.Settings.AllowedHosts) < 1 {
-				response.Error = errors.New("datasource is missing allowed hosts/URLs. Configure it in the datasource settings page for enhanced security")
+				response.Error = infinity.UserError(errors.New("datasource is missing allowed hosts/URLs. Configure it in the datasource settings page for enhanced security"))
 				return response
 			}
 			if infClient.Settings.HaveSecureHeaders() && len(infClient.Settings.AllowedHosts) < 1 {
-				response.Error = errors.New("datasource is missing allowed hosts/URLs. Configure it in the datasource settings page for enhanced security")
+				response.Error = infinity.UserError(errors.New("datasource is missing allowed hosts/URLs. Configure it in the datasource settings page for enhanced security"))
 				return response
 			}
 			frame, err := infinity.GetFrameForURLSources(ctx, query, infClient, requestHeaders)
@@ -143,6 +193,9 @@ func QueryDataQuery(ctx context.Context, query models.Query, infClient infinity.
 					Text: "Datasource is missing allowed hosts/URLs. Configure it in the datasource settings page for enhanced security.",
 				})
 			}
+			if frame != nil && query.UseSample {
+				frame.AppendNotices(data.Notice{Severity: data.NoticeSeverityInfo, Text: fmt.Sprintf("Results are from the sample fixture %s, not from the live API.", query.SampleFixture)})
+			}
 			if frame != nil {
 				frame, _ = infinity.WrapMetaForRemoteQuery(ctx, frame, nil, query)
 				response.Frames = append(response.Frames, frame)
@@ -155,14 +208,145 @@ func QueryDataQuery(ctx context.Context, query models.Query, infClient infinity.
 				span.SetStatus(500, err.Error())
 				frame, _ := infinity.WrapMetaForInlineQuery(ctx, frame, err, query)
 				response.Frames = append(response.Frames, frame)
-				response.Error = fmt.Errorf("error getting data frame from inline data. %w", err)
+				response.Error = infinity.UserError(fmt.Errorf("error getting data frame from inline data. %w", err))
 				return response
 			}
 			if frame != nil {
 				frame, _ := infinity.WrapMetaForInlineQuery(ctx, frame, nil, query)
 				response.Frames = append(response.Frames, frame)
 			}
+		case "snmp":
+			frame, err := infinity.GetFrameForSNMP(ctx, query, infClient)
+			if err != nil {
+				logger.Error("error while performing the infinity snmp query", "msg", err.Error())
+				span.RecordError(err)
+				span.SetStatus(500, err.Error())
+				response.Error = fmt.Errorf("error getting data frame from snmp. %w", err)
+				return response
+			}
+			response.Frames = append(response.Frames, frame)
+		case "mqtt":
+			frame, err := infinity.GetFrameForMQTT(ctx, query, infClient)
+			if err != nil {
+				logger.Error("error while performing the infinity mqtt query", "msg", err.Error())
+				span.RecordError(err)
+				span.SetStatus(500, err.Error())
+				response.Error = fmt.Errorf("error getting data frame from mqtt. %w", err)
+				return response
+			}
+			response.Frames = append(response.Frames, frame)
+		case "redis":
+			frame, err := infinity.GetFrameForRedis(ctx, query, infClient)
+			if err != nil {
+				logger.Error("error while performing the infinity redis query", "msg", err.Error())
+				span.RecordError(err)
+				span.SetStatus(500, err.Error())
+				response.Error = fmt.Errorf("error getting data frame from redis. %w", err)
+				return response
+			}
+			response.Frames = append(response.Frames, frame)
+		case "database":
+			frame, err := infinity.GetFrameForDatabase(ctx, query, infClient)
+			if err != nil {
+				logger.Error("error while performing the infinity database query", "msg", err.Error())
+				span.RecordError(err)
+				span.SetStatus(500, err.Error())
+				response.Error = fmt.Errorf("error getting data frame from database. %w", err)
+				return response
+			}
+			response.Frames = append(response.Frames, frame)
+		case "ldap":
+			frame, err := infinity.GetFrameForLDAP(ctx, query, infClient)
+			if err != nil {
+				logger.Error("error while performing the infinity ldap query", "msg", err.Error())
+				span.RecordError(err)
+				span.SetStatus(500, err.Error())
+				response.Error = fmt.Errorf("error getting data frame from ldap. %w", err)
+				return response
+			}
+			response.Frames = append(response.Frames, frame)
+		case "dns":
+			frame, err := infinity.GetFrameForDNS(ctx, query, infClient)
+			if err != nil {
+				logger.Error("error while performing the infinity dns query", "msg", err.Error())
+				span.RecordError(err)
+				span.SetStatus(500, err.Error())
+				response.Error = fmt.Errorf("error getting data frame from dns. %w", err)
+				return response
+			}
+			response.Frames = append(response.Frames, frame)
+		case "probe":
+			frame, err := infinity.GetFrameForProbe(ctx, query, infClient)
+			if err != nil {
+				logger.Error("error while performing the infinity probe query", "msg", err.Error())
+				span.RecordError(err)
+				span.SetStatus(500, err.Error())
+				response.Error = fmt.Errorf("error getting data frame from probe. %w", err)
+				return response
+			}
+			response.Frames = append(response.Frames, frame)
+		case "certificate":
+			frame, err := infinity.GetFrameForCertificate(ctx, query, infClient)
+			if err != nil {
+				logger.Error("error while performing the infinity certificate query", "msg", err.Error())
+				span.RecordError(err)
+				span.SetStatus(500, err.Error())
+				response.Error = fmt.Errorf("error getting data frame from certificate. %w", err)
+				return response
+			}
+			response.Frames = append(response.Frames, frame)
+		case "rdap":
+			frame, err := infinity.GetFrameForRDAP(ctx, query, infClient)
+			if err != nil {
+				logger.Error("error while performing the infinity rdap query", "msg", err.Error())
+				span.RecordError(err)
+				span.SetStatus(500, err.Error())
+				response.Error = fmt.Errorf("error getting data frame from rdap. %w", err)
+				return response
+			}
+			response.Frames = append(response.Frames, frame)
+		case "crawl":
+			frame, err := infinity.GetFrameForCrawl(ctx, query, infClient)
+			if err != nil {
+				logger.Error("error while performing the infinity crawl query", "msg", err.Error())
+				span.RecordError(err)
+				span.SetStatus(500, err.Error())
+				response.Error = fmt.Errorf("error getting data frame from crawl. %w", err)
+				return response
+			}
+			response.Frames = append(response.Frames, frame)
+		case "webhook":
+			frame, err := infinity.GetFrameForWebhook(ctx, query, infClient)
+			if err != nil {
+				logger.Error("error while performing the infinity webhook query", "msg", err.Error())
+				span.RecordError(err)
+				span.SetStatus(500, err.Error())
+				response.Error = fmt.Errorf("error getting data frame from webhook. %w", err)
+				return response
+			}
+			response.Frames = append(response.Frames, frame)
+		case "mutation":
+			frame, err := infinity.GetFrameForMutation(ctx, query, infClient, requestHeaders)
+			if err != nil {
+				logger.Error("error while performing the infinity mutation query", "msg", err.Error())
+				span.RecordError(err)
+				span.SetStatus(500, err.Error())
+				response.Error = fmt.Errorf("error getting data frame from mutation. %w", err)
+				return response
+			}
+			response.Frames = append(response.Frames, frame)
 		default:
+			if _, ok := infinity.GetDatasetName(query); ok {
+				frame, err := infinity.GetFrameForDataset(ctx, query, infClient)
+				if err != nil {
+					span.RecordError(err)
+					span.SetStatus(500, err.Error())
+					response.Error = infinity.UserError(fmt.Errorf("error getting data frame from dataset. %w", err))
+					return response
+				}
+				response.Frames = append(response.Frames, frame)
+				break
+			}
 			frame := infinity.GetDummyFrame(query)
 			if frame != nil {
 				response.Frames = append(response.Frames, frame)
@@ -170,5 +354,63 @@ func QueryDataQuery(ctx context.Context, query models.Query, infClient infinity.
 		}
 	}
 	//endregion
+	if query.Format == "node-graph" && response.Error == nil && len(response.Frames) > 0 {
+		frames, err := infinity.ToNodeGraphFrames(ctx, response.Frames[0], query)
+		if err != nil {
+			span.RecordError(err)
+			response.Error = infinity.UserError(err)
+			return response
+		}
+		response.Frames = frames
+	}
+	if response.Error == nil {
+		for i, frame := range response.Frames {
+			frame, err := infinity.ShapeFrameForFormat(ctx, frame, query, infinity.IsHeadlessRequest(requestHeaders))
+			if err != nil {
+				span.RecordError(err)
+				response.Error = infinity.UserError(fmt.Errorf("error shaping the results as %s. %w", query.Format, err))
+				return response
+			}
+			response.Frames[i] = frame
+		}
+	}
+	if query.DryRun && response.Error == nil {
+		for i, frame := range response.Frames {
+			response.Frames[i] = infinity.ToSchemaFrame(ctx, frame)
+		}
+	}
+	if query.ForecastOptions != nil && !query.DryRun && response.Error == nil && len(response.Frames) > 0 {
+		frame, err := infinity.GetForecastFrame(ctx, response.Frames[0], *query.ForecastOptions)
+		if err != nil {
+			span.RecordError(err)
+			response.Error = infinity.UserError(fmt.Errorf("error forecasting. %w", err))
+			return response
+		}
+		response.Frames = append(response.Frames, frame)
+	}
+	if query.MaterializeAs != "" && !query.DryRun && response.Error == nil && len(response.Frames) > 0 {
+		ttl := time.Duration(query.MaterializeTTLSeconds) * time.Second
+		err := infinity.CheckMaterializeRestrictions(infClient.Settings, pluginContext.User)
+		if err == nil {
+			err = infinity.SaveDataset(ctx, infClient, query.MaterializeAs, query, response.Frames[0], ttl)
+		}
+		if err != nil {
+			logger.Error("error materializing the query as dataset", "dataset", query.MaterializeAs, "error", err.Error())
+			response.Frames[0].AppendNotices(data.Notice{Severity: data.NoticeSeverityWarning, Text: fmt.Sprintf("Unable to materialize the result as dataset %s. %s", query.MaterializeAs, err.Error())})
+		}
+	}
+	if len(query.Migrations) > 0 && len(response.Frames) > 0 {
+		logger.Debug("query is migrated", "schema_version", models.LatestQuerySchemaVersion, "migrations", query.Migrations)
+		response.Frames[0].AppendNotices(data.Notice{
+			Severity: data.NoticeSeverityInfo,
+			Text:     fmt.Sprintf("Query is migrated to the schema version %d. %s", models.LatestQuerySchemaVersion, strings.Join(query.Migrations, "; ")),
+		})
+	}
+	for i, frame := range response.Frames {
+		response.Frames[i] = infinity.ApplyFrameBudget(ctx, frame, infClient.Settings.MaxFrameCells)
+		if !infClient.IsMock {
+			infinity.ApplyTimingsToFrame(ctx, response.Frames[i])
+		}
+	}
 	return response
 }
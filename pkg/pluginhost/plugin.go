@@ -2,12 +2,16 @@ package pluginhost
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"sync"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/datasource"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/instancemgmt"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/resource/httpadapter"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
 	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
 )
@@ -18,7 +22,9 @@ type PluginHost struct {
 
 func NewDatasource() datasource.ServeOpts {
 	host := &PluginHost{
-		im: datasource.NewInstanceManager(newDataSourceInstance),
+		im: instancemgmt.New(&instanceProvider{InstanceProvider: datasource.NewInstanceProvider(func(ctx context.Context, setting backend.DataSourceInstanceSettings) (instancemgmt.Instance, error) {
+			return newDataSourceInstance(ctx, 0, setting)
+		})}),
 	}
 	return datasource.ServeOpts{
 		QueryDataHandler:    host,
@@ -28,23 +34,162 @@ func NewDatasource() datasource.ServeOpts {
 }
 
 type instanceSettings struct {
-	client *infinity.Client
+	client    *infinity.Client
+	scheduler *infinity.Scheduler
+	// queries limits the queries of the instance running concurrently across the requests
+	queries chan struct{}
 }
 
-func (is *instanceSettings) Dispose() {}
+const defaultMaxConcurrentQueries = 10
 
-func newDataSourceInstance(ctx context.Context, setting backend.DataSourceInstanceSettings) (instancemgmt.Instance, error) {
+// runQuery runs the query once the concurrency limit of the instance allows it
+func (is *instanceSettings) runQuery(ctx context.Context, run func() backend.DataResponse) backend.DataResponse {
+	if is.queries == nil {
+		return run()
+	}
+	select {
+	case is.queries <- struct{}{}:
+		defer func() { <-is.queries }()
+		return run()
+	case <-ctx.Done():
+		return infinity.ClassifyResponse(backend.DataResponse{Error: fmt.Errorf("query cancelled while waiting for the other queries to complete. %w", ctx.Err())})
+	}
+}
+
+// Dispose stops the background jobs of the instance and releases its connections. The cache shared by the instances
+// is closed when the last instance is disposed, which releases the lock of the persistent cache directory
+func (is *instanceSettings) Dispose() {
+	if is.scheduler != nil {
+		is.scheduler.Stop()
+	}
+	if is.client != nil {
+		if err := is.client.Close(); err != nil {
+			backend.Logger.Warn("error closing the datasource client", "error", err.Error())
+		}
+	}
+	releaseCache()
+}
+
+var (
+	activeInstancesMu sync.Mutex
+	activeInstances   int
+)
+
+// acquireCache opens the cache shared by the instances, or reopens it when its options changed. The instance replacing an updated
+// instance is created before the replaced instance is disposed, so the cache never closes in between and the changed options are
+// applied here. The cache is opened with the lock held, so the cache being closed by the disposer of the last instance is never
+// handed to the new instance
+func acquireCache(options infinity.SettOptions) {
+	activeInstancesMu.Lock()
+	defer activeInstancesMu.Unlock()
+	activeInstances++
+	infinity.BadgerInitWithOptions(options)
+}
+
+// getCacheOptions returns the options of the cache shared by the instances. They are plugin settings, so all the instances
+// open the cache with the same options. Invalid plugin settings fall back to the in-memory cache
+func getCacheOptions(pluginSettings models.PluginSettings) infinity.SettOptions {
+	if err := pluginSettings.Validate(); err != nil {
+		backend.Logger.Error("invalid cache plugin settings. falling back to in-memory cache", "error", err.Error())
+		return infinity.SettOptions{}
+	}
+	return infinity.SettOptions{
+		Dir:                   pluginSettings.CacheDir,
+		EncryptionKey:         []byte(pluginSettings.CacheEncryptionKey),
+		PreviousEncryptionKey: []byte(pluginSettings.CachePreviousEncryptionKey),
+		Limits:                infinity.SettLimits{MaxBytes: pluginSettings.CacheMaxBytes, MaxEntries: pluginSettings.CacheMaxEntries},
+		GC:                    infinity.SettGCOptions{Interval: pluginSettings.CacheGCInterval, DiscardRatio: pluginSettings.CacheGCDiscardRatio},
+	}
+}
+
+func releaseCache() {
+	activeInstancesMu.Lock()
+	defer activeInstancesMu.Unlock()
+	activeInstances--
+	if activeInstances > 0 {
+		return
+	}
+	activeInstances = 0
+	if err := infinity.CloseBadgerDB(); err != nil {
+		backend.Logger.Error("error closing the cache", "error", err.Error())
+	}
+}
+
+// Shutdown closes the cache when the plugin process exits
+func Shutdown() {
+	activeInstancesMu.Lock()
+	defer activeInstancesMu.Unlock()
+	activeInstances = 0
+	if err := infinity.CloseBadgerDB(); err != nil {
+		backend.Logger.Error("error closing the cache", "error", err.Error())
+	}
+}
+
+// instanceProvider passes the org of the datasource to the instances, which isolate their cache per org
+type instanceProvider struct {
+	instancemgmt.InstanceProvider
+}
+
+func (ip *instanceProvider) NewInstance(ctx context.Context, pluginContext backend.PluginContext) (instancemgmt.Instance, error) {
+	if pluginContext.DataSourceInstanceSettings == nil {
+		return nil, errors.New("data source instance settings cannot be nil")
+	}
+	return newDataSourceInstance(ctx, pluginContext.OrgID, *pluginContext.DataSourceInstanceSettings)
+}
+
+// NeedsUpdate rebuilds the instance only when the effective settings change. Saving the datasource without changes
+// updates the Updated timestamp, which would otherwise rebuild the http client, token caches and the scheduler of the instance
+func (ip *instanceProvider) NeedsUpdate(ctx context.Context, pluginContext backend.PluginContext, cachedInstance instancemgmt.CachedInstance) bool {
+	cached := cachedInstance.PluginContext
+	if pluginContext.DataSourceInstanceSettings == nil || cached.DataSourceInstanceSettings == nil {
+		return true
+	}
+	if pluginContext.OrgID != cached.OrgID || !cached.GrafanaConfig.Equal(pluginContext.GrafanaConfig) {
+		return true
+	}
+	return models.GetSettingsHash(*pluginContext.DataSourceInstanceSettings) != models.GetSettingsHash(*cached.DataSourceInstanceSettings)
+}
+
+func newDataSourceInstance(ctx context.Context, orgID int64, setting backend.DataSourceInstanceSettings) (instancemgmt.Instance, error) {
 	settings, err := models.LoadSettings(setting)
 	if err != nil {
 		return nil, err
 	}
+	settings.OrgID = orgID
+	acquireCache(getCacheOptions(models.LoadPluginSettings()))
 	client, err := infinity.NewClient(ctx, settings)
 	if err != nil {
+		releaseCache()
 		return nil, err
 	}
-	return &instanceSettings{
-		client: client,
-	}, nil
+	maxConcurrentQueries := settings.MaxConcurrentQueries
+	if maxConcurrentQueries <= 0 {
+		maxConcurrentQueries = defaultMaxConcurrentQueries
+	}
+	is := &instanceSettings{
+		client:  client,
+		queries: make(chan struct{}, maxConcurrentQueries),
+	}
+	if settings.IsFeatureEnabled(models.FeatureFlagScheduler) {
+		is.scheduler = infinity.NewScheduler(client)
+		// exports run the queries through the same pipeline as the panels, so any source can be exported.
+		// queries run with the role of the user registering the export, so the query restrictions apply at every run
+		is.scheduler.SetQueryRunner(func(ctx context.Context, query models.Query, role string) (*data.Frame, error) {
+			pluginContext := backend.PluginContext{OrgID: orgID, DataSourceInstanceSettings: &setting, User: &backend.User{Role: role}}
+			res := QueryDataQuery(ctx, query, *client, map[string]string{}, pluginContext)
+			if res.Error != nil {
+				return nil, res.Error
+			}
+			if len(res.Frames) == 0 {
+				return nil, nil
+			}
+			return res.Frames[0], nil
+		})
+		if err := is.scheduler.RestoreExports(); err != nil {
+			backend.Logger.Warn("error restoring the scheduled exports", "error", err.Error())
+		}
+	}
+	return is, nil
 }
 
 func getInstance(ctx context.Context, im instancemgmt.InstanceManager, pCtx backend.PluginContext) (*instanceSettings, error) {
@@ -7,8 +7,12 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
@@ -422,6 +426,7 @@ func TestResponseFormats(t *testing.T) {
 		t.Run("should parse the computed columns", func(t *testing.T) {
 			client, err := infinity.NewClient(context.TODO(), models.InfinitySettings{URL: ""})
 			require.Nil(t, err)
+			client.IsMock = true
 			res := pluginhost.QueryData(context.Background(), backend.DataQuery{
 				JSON: []byte(`{
 					"type": "json",
@@ -440,6 +445,7 @@ func TestResponseFormats(t *testing.T) {
 		t.Run("should filter computed columns", func(t *testing.T) {
 			client, err := infinity.NewClient(context.TODO(), models.InfinitySettings{URL: ""})
 			require.Nil(t, err)
+			client.IsMock = true
 			res := pluginhost.QueryData(context.Background(), backend.DataQuery{
 				JSON: []byte(`{
 					"type": "json",
@@ -456,6 +462,29 @@ func TestResponseFormats(t *testing.T) {
 			require.Nil(t, res.Error)
 			experimental.CheckGoldenJSONResponse(t, "golden", "backend-filter-computed-columns", &res, UPDATE_GOLDEN_DATA)
 		})
+		t.Run("should return only the schema for the dry run queries", func(t *testing.T) {
+			client, err := infinity.NewClient(context.TODO(), models.InfinitySettings{URL: ""})
+			require.Nil(t, err)
+			client.IsMock = true
+			res := pluginhost.QueryData(context.Background(), backend.DataQuery{
+				JSON: []byte(`{
+					"type": "json",
+					"data":  "[{ \"name\": \"foo\", \"age\": 12 }, { \"name\": \"bar\", \"age\": 24 }]",
+					"source": "inline",
+					"parser": "backend",
+					"dry_run": true,
+					"materialize_as": "users"
+				}`),
+			}, *client, map[string]string{}, backend.PluginContext{})
+			require.NotNil(t, res)
+			require.Nil(t, res.Error)
+			require.Equal(t, 1, len(res.Frames))
+			require.Equal(t, 0, res.Frames[0].Rows())
+			require.Equal(t, []string{"age", "name"}, []string{res.Frames[0].Fields[0].Name, res.Frames[0].Fields[1].Name})
+			require.Equal(t, data.FieldTypeNullableFloat64, res.Frames[0].Fields[0].Type())
+			require.Equal(t, "Row count", res.Frames[0].Meta.Stats[0].DisplayName)
+			require.Equal(t, float64(2), res.Frames[0].Meta.Stats[0].Value)
+		})
 	})
 	t.Run("JSON SQLite", func(t *testing.T) {
 		t.Run("should parse the response and send results", func(t *testing.T) {
@@ -1316,3 +1345,470 @@ func TestQuery(t *testing.T) {
 		})
 	})
 }
+
+func TestHeadlessQueries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{ "interval": %q, "hosts": [{ "host": "a", "cpu": 10, "time": "2023-01-01T00:00:00Z" }, { "host": "b", "cpu": 20, "time": "2023-01-01T00:00:00Z" }] }`, r.URL.Query().Get("interval"))
+	}))
+	defer server.Close()
+	client, err := infinity.NewClient(context.TODO(), models.InfinitySettings{URL: server.URL})
+	require.Nil(t, err)
+	headers := map[string]string{"FromExpression": "true"}
+	t.Run("simple parser queries are parsed in the backend", func(t *testing.T) {
+		res := pluginhost.QueryData(context.Background(), backend.DataQuery{
+			Interval: time.Minute,
+			JSON: []byte(fmt.Sprintf(`{
+				"type": "json",
+				"source": "url",
+				"url": "%s?interval=${__interval}",
+				"parser": "simple",
+				"root_selector": "hosts",
+				"format": "numeric",
+				"columns": [
+					{ "selector": "host", "text": "host", "type": "string" },
+					{ "selector": "cpu", "text": "cpu", "type": "number" }
+				]
+			}`, server.URL)),
+		}, *client, headers, backend.PluginContext{})
+		require.Nil(t, res.Error)
+		require.Equal(t, 1, len(res.Frames))
+		require.Equal(t, data.FrameTypeNumericWide, res.Frames[0].Meta.Type)
+		require.Equal(t, 2, len(res.Frames[0].Fields))
+		require.Equal(t, data.Labels{"host": "b"}, res.Frames[0].Fields[1].Labels)
+		require.Contains(t, res.Frames[0].Meta.ExecutedQueryString, server.URL+"?interval=1m")
+	})
+	t.Run("time series are converted to wide frames", func(t *testing.T) {
+		res := pluginhost.QueryData(context.Background(), backend.DataQuery{
+			JSON: []byte(fmt.Sprintf(`{
+				"type": "json",
+				"source": "url",
+				"url": "%s",
+				"root_selector": "hosts",
+				"format": "timeseries",
+				"columns": [
+					{ "selector": "time", "text": "time", "type": "timestamp" },
+					{ "selector": "host", "text": "host", "type": "string" },
+					{ "selector": "cpu", "text": "cpu", "type": "number" }
+				]
+			}`, server.URL)),
+		}, *client, headers, backend.PluginContext{})
+		require.Nil(t, res.Error)
+		require.Equal(t, data.FrameTypeTimeSeriesWide, res.Frames[0].Meta.Type)
+		require.Equal(t, 3, len(res.Frames[0].Fields))
+	})
+	t.Run("frontend parsers are rejected", func(t *testing.T) {
+		res := pluginhost.QueryData(context.Background(), backend.DataQuery{
+			JSON: []byte(fmt.Sprintf(`{ "type": "json", "source": "url", "url": "%s", "parser": "uql", "uql": "parse-json" }`, server.URL)),
+		}, *client, headers, backend.PluginContext{})
+		require.NotNil(t, res.Error)
+		require.Contains(t, res.Error.Error(), "use backend parser")
+	})
+}
+
+func TestQueryTimings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `[{ "name": "foo" }]`)
+	}))
+	defer server.Close()
+	client, err := infinity.NewClient(context.TODO(), models.InfinitySettings{URL: server.URL})
+	require.Nil(t, err)
+	res := pluginhost.QueryData(context.Background(), backend.DataQuery{
+		JSON: []byte(fmt.Sprintf(`{ "type": "json", "source": "url", "url": "%s", "parser": "backend" }`, server.URL)),
+	}, *client, map[string]string{}, backend.PluginContext{})
+	require.Nil(t, res.Error)
+	timings := res.Frames[0].Meta.Custom.(*infinity.CustomMeta).Timings
+	require.NotNil(t, timings)
+	require.Greater(t, timings.Connect, time.Duration(0))
+	require.Greater(t, timings.TTFB, time.Duration(0))
+	require.Greater(t, timings.Parse, time.Duration(0))
+	require.Greater(t, timings.Total, timings.TTFB)
+	require.Nil(t, infinity.GetTimings(context.Background()))
+}
+
+func TestErrorSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	client, err := infinity.NewClient(context.TODO(), models.InfinitySettings{URL: server.URL, AllowedHosts: []string{server.URL}})
+	require.Nil(t, err)
+	t.Run("downstream errors", func(t *testing.T) {
+		res := pluginhost.QueryData(context.Background(), backend.DataQuery{
+			JSON: []byte(fmt.Sprintf(`{ "type": "json", "source": "url", "url": "%s", "parser": "backend" }`, server.URL)),
+		}, *client, map[string]string{}, backend.PluginContext{})
+		require.NotNil(t, res.Error)
+		require.Equal(t, backend.ErrorSourceDownstream, res.ErrorSource)
+		require.Equal(t, backend.Status(http.StatusServiceUnavailable), res.Status)
+	})
+	t.Run("user errors", func(t *testing.T) {
+		res := pluginhost.QueryData(context.Background(), backend.DataQuery{
+			JSON: []byte(`{ "type": "google-sheets", "spreadsheet": "" }`),
+		}, *client, map[string]string{}, backend.PluginContext{})
+		require.NotNil(t, res.Error)
+		require.Equal(t, infinity.ErrorKindUser, infinity.GetErrorKind(res.Error))
+		require.Equal(t, backend.ErrorSourceDownstream, res.ErrorSource)
+		require.Equal(t, backend.StatusBadRequest, res.Status)
+		res = pluginhost.QueryData(context.Background(), backend.DataQuery{JSON: []byte(`{`)}, *client, map[string]string{}, backend.PluginContext{})
+		require.Equal(t, backend.ErrorSourceDownstream, res.ErrorSource)
+	})
+}
+
+func TestPaginationErrorMode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintf(w, `[{ "page": %q }]`, r.URL.Query().Get("page"))
+	}))
+	defer server.Close()
+	client, err := infinity.NewClient(context.TODO(), models.InfinitySettings{URL: server.URL})
+	require.Nil(t, err)
+	query := func(errorMode string) backend.DataQuery {
+		return backend.DataQuery{JSON: []byte(fmt.Sprintf(`{
+			"type": "json",
+			"source": "url",
+			"url": "%s",
+			"parser": "backend",
+			"pagination_mode": "page",
+			"pagination_max_pages": 3,
+			"pagination_error_mode": %q
+		}`, server.URL, errorMode))}
+	}
+	t.Run("fail fast", func(t *testing.T) {
+		res := pluginhost.QueryData(context.Background(), query(""), *client, map[string]string{}, backend.PluginContext{})
+		require.NotNil(t, res.Error)
+	})
+	t.Run("best effort", func(t *testing.T) {
+		res := pluginhost.QueryData(context.Background(), query("best-effort"), *client, map[string]string{}, backend.PluginContext{})
+		require.Nil(t, res.Error)
+		require.Equal(t, 2, res.Frames[0].Rows())
+		require.Equal(t, 1, len(res.Frames[0].Meta.Notices))
+		require.Equal(t, data.NoticeSeverityWarning, res.Frames[0].Meta.Notices[0].Severity)
+		require.Contains(t, res.Frames[0].Meta.Notices[0].Text, "1 of 3 pages failed")
+	})
+}
+
+func TestNodeGraphFormat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{ "graph": { "nodes": [{ "ID": "a", "title": "A" }, { "ID": "b", "title": "B" }], "links": [{ "from": "a", "to": "b" }] } }`)
+	}))
+	defer server.Close()
+	client, err := infinity.NewClient(context.TODO(), models.InfinitySettings{URL: server.URL})
+	require.Nil(t, err)
+	t.Run("nodes and edges frames are returned from the single response", func(t *testing.T) {
+		res := pluginhost.QueryData(context.Background(), backend.DataQuery{
+			JSON: []byte(fmt.Sprintf(`{
+				"type": "json",
+				"source": "url",
+				"url": "%s",
+				"parser": "backend",
+				"format": "node-graph",
+				"node_graph_options": { "nodes_selector": "graph.nodes", "edges_selector": "graph.links" }
+			}`, server.URL)),
+		}, *client, map[string]string{}, backend.PluginContext{})
+		require.Nil(t, res.Error)
+		require.Equal(t, 2, len(res.Frames))
+		require.Equal(t, "nodes", res.Frames[0].Name)
+		require.Equal(t, 2, res.Frames[0].Rows())
+		_, idx := res.Frames[0].FieldByName("id")
+		require.NotEqual(t, -1, idx)
+		require.Equal(t, "edges", res.Frames[1].Name)
+		id, _ := res.Frames[1].FieldByName("id")
+		require.Equal(t, "a-b", id.At(0))
+		_, idx = res.Frames[1].FieldByName("source")
+		require.NotEqual(t, -1, idx)
+		require.Equal(t, data.VisTypeNodeGraph, string(res.Frames[1].Meta.PreferredVisualization))
+	})
+	t.Run("nodes selector is required", func(t *testing.T) {
+		res := pluginhost.QueryData(context.Background(), backend.DataQuery{
+			JSON: []byte(fmt.Sprintf(`{ "type": "json", "source": "url", "url": "%s", "parser": "backend", "format": "node-graph" }`, server.URL)),
+		}, *client, map[string]string{}, backend.PluginContext{})
+		require.ErrorContains(t, res.Error, "node graph format requires the nodes selector")
+		require.Equal(t, backend.ErrorSourceDownstream, res.ErrorSource)
+	})
+}
+
+func TestConcurrentQueries(t *testing.T) {
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(100 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		fmt.Fprintf(w, `[{ "id" : "%s" }]`, r.URL.Query().Get("id"))
+	}))
+	defer server.Close()
+	queries := []backend.DataQuery{}
+	for _, refID := range []string{"A", "B", "C", "D"} {
+		queries = append(queries, backend.DataQuery{RefID: refID, JSON: []byte(fmt.Sprintf(`{ "type": "json", "source": "url", "parser": "backend", "url": "%s?id=%s" }`, server.URL, refID))})
+	}
+	ds := pluginhost.NewDatasource()
+	res, err := ds.QueryDataHandler.QueryData(context.Background(), &backend.QueryDataRequest{
+		PluginContext: backend.PluginContext{DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{ID: 918, JSONData: []byte(`{ "maxConcurrentQueries" : 2 }`)}},
+		Queries:       queries,
+	})
+	require.Nil(t, err)
+	require.Equal(t, 2, maxRunning)
+	require.Equal(t, 4, len(res.Responses))
+	for _, refID := range []string{"A", "B", "C", "D"} {
+		require.Nil(t, res.Responses[refID].Error)
+		id, _ := res.Responses[refID].Frames[0].FieldByName("id")
+		require.Equal(t, refID, *(id.At(0).(*string)))
+	}
+}
+
+func TestQueryMigrations(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "text/plain", r.Header.Get("Content-Type"))
+		fmt.Fprintf(w, `[{ "name" : "foo" }]`)
+	}))
+	defer server.Close()
+	client, err := infinity.NewClient(context.TODO(), models.InfinitySettings{URL: server.URL})
+	require.Nil(t, err)
+	t.Run("should notice the migrations of the legacy query", func(t *testing.T) {
+		res := pluginhost.QueryData(context.Background(), backend.DataQuery{
+			JSON: []byte(fmt.Sprintf(`{ "type": "json", "source": "url", "parser": "backend", "url": "%s", "url_options": { "method": "POST", "data": "{}" } }`, server.URL)),
+		}, *client, map[string]string{}, backend.PluginContext{})
+		require.Nil(t, res.Error)
+		require.Len(t, res.Frames[0].Meta.Notices, 1)
+		require.Equal(t, data.NoticeSeverityInfo, res.Frames[0].Meta.Notices[0].Severity)
+		require.Contains(t, res.Frames[0].Meta.Notices[0].Text, "Query is migrated to the schema version 1. version 1: body type of the POST queries is set explicitly")
+	})
+	t.Run("should not notice the latest query", func(t *testing.T) {
+		res := pluginhost.QueryData(context.Background(), backend.DataQuery{
+			JSON: []byte(fmt.Sprintf(`{ "type": "json", "source": "url", "parser": "backend", "url": "%s", "schema_version": 1, "url_options": { "method": "POST", "data": "{}", "body_type": "raw", "body_content_type": "text/plain" } }`, server.URL)),
+		}, *client, map[string]string{}, backend.PluginContext{})
+		require.Nil(t, res.Error)
+		require.Empty(t, res.Frames[0].Meta.Notices)
+	})
+}
+
+type resourceResponseSender func(res *backend.CallResourceResponse)
+
+func (s resourceResponseSender) Send(res *backend.CallResourceResponse) error {
+	s(res)
+	return nil
+}
+
+func TestFeatureFlags(t *testing.T) {
+	ds := pluginhost.NewDatasource()
+	callResource := func(id int64, jsonData string, path string) *backend.CallResourceResponse {
+		var res *backend.CallResourceResponse
+		err := ds.CallResourceHandler.CallResource(context.Background(), &backend.CallResourceRequest{
+			PluginContext: backend.PluginContext{DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{ID: id, JSONData: []byte(jsonData)}},
+			Method:        http.MethodGet,
+			Path:          path,
+			URL:           path,
+		}, resourceResponseSender(func(r *backend.CallResourceResponse) { res = r }))
+		require.Nil(t, err)
+		require.NotNil(t, res)
+		return res
+	}
+	t.Run("should allow the features enabled by default", func(t *testing.T) {
+		res := callResource(952, `{}`, "scheduled-queries")
+		require.Equal(t, http.StatusOK, res.Status)
+	})
+	t.Run("should not gate the open api spec", func(t *testing.T) {
+		res := callResource(952, `{}`, "open-api")
+		require.NotEqual(t, http.StatusForbidden, res.Status)
+		res = callResource(952, `{}`, "open-api/operations")
+		require.Equal(t, http.StatusForbidden, res.Status)
+	})
+	t.Run("should reject the disabled features", func(t *testing.T) {
+		res := callResource(953, `{ "featureFlags" : { "scheduler" : false } }`, "scheduled-queries")
+		require.Equal(t, http.StatusForbidden, res.Status)
+		require.Contains(t, string(res.Body), "scheduler feature is not enabled")
+		res = callResource(953, `{ "featureFlags" : { "scheduler" : false } }`, "open-api/operations")
+		require.Equal(t, http.StatusForbidden, res.Status)
+		require.Contains(t, string(res.Body), "openApi feature is not enabled")
+	})
+}
+
+func TestResourceRoles(t *testing.T) {
+	ds := pluginhost.NewDatasource()
+	callResource := func(role string, method string, path string) *backend.CallResourceResponse {
+		var res *backend.CallResourceResponse
+		err := ds.CallResourceHandler.CallResource(context.Background(), &backend.CallResourceRequest{
+			PluginContext: backend.PluginContext{User: &backend.User{Role: role}, DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{ID: 861, JSONData: []byte(`{}`)}},
+			Method:        method,
+			Path:          path,
+			URL:           path,
+			Body:          []byte(`{}`),
+		}, resourceResponseSender(func(r *backend.CallResourceResponse) { res = r }))
+		require.Nil(t, err)
+		require.NotNil(t, res)
+		return res
+	}
+	for _, route := range []struct{ method, path string }{
+		{http.MethodPost, "scheduled-queries"},
+		{http.MethodDelete, "scheduled-queries/foo"},
+		{http.MethodDelete, "cache"},
+		{http.MethodGet, "cache/gc"},
+		{http.MethodPut, "fixtures/foo"},
+		{http.MethodDelete, "fixtures/foo"},
+		{http.MethodPost, "datasets/foo/refresh"},
+		{http.MethodDelete, "datasets/foo"},
+	} {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			res := callResource("Editor", route.method, route.path)
+			require.Equal(t, http.StatusForbidden, res.Status)
+			require.Contains(t, string(res.Body), "only admins are allowed to perform this operation")
+			res = callResource("Admin", route.method, route.path)
+			require.NotEqual(t, http.StatusForbidden, res.Status)
+		})
+	}
+	t.Run("should allow the viewers to list the scheduled queries", func(t *testing.T) {
+		res := callResource("Viewer", http.MethodGet, "scheduled-queries")
+		require.Equal(t, http.StatusOK, res.Status)
+	})
+	t.Run("should allow the viewers to list the fixtures", func(t *testing.T) {
+		res := callResource("Viewer", http.MethodGet, "fixtures")
+		require.Equal(t, http.StatusOK, res.Status)
+	})
+}
+
+func TestQueryRestrictions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `[{ "name" : "foo" }]`)
+	}))
+	defer server.Close()
+	client, err := infinity.NewClient(context.TODO(), models.InfinitySettings{QueryRestrictions: models.QueryRestrictions{RequireHTTPS: true, BlockCustomHeaders: true, ExemptRole: "Editor"}})
+	require.Nil(t, err)
+	queryJSON := []byte(fmt.Sprintf(`{ "type": "json", "source": "url", "parser": "backend", "url": "%s", "url_options": { "headers": [{ "key": "X-Foo", "value": "bar" }] } }`, server.URL))
+	t.Run("should reject the restricted options for the viewers", func(t *testing.T) {
+		res := pluginhost.QueryData(context.Background(), backend.DataQuery{JSON: queryJSON}, *client, map[string]string{}, backend.PluginContext{User: &backend.User{Role: "Viewer"}})
+		require.NotNil(t, res.Error)
+		require.Equal(t, "custom headers are not allowed by the query restrictions of the datasource", res.Error.Error())
+		require.Equal(t, infinity.ErrorKindUser, infinity.GetErrorKind(res.Error))
+	})
+	t.Run("should allow the restricted options for the exempt role", func(t *testing.T) {
+		res := pluginhost.QueryData(context.Background(), backend.DataQuery{JSON: queryJSON}, *client, map[string]string{}, backend.PluginContext{User: &backend.User{Role: "Editor"}})
+		require.Nil(t, res.Error)
+		require.Equal(t, 1, res.Frames[0].Rows())
+	})
+	t.Run("should deny the mutations for the non admins by default", func(t *testing.T) {
+		client, err := infinity.NewClient(context.TODO(), models.InfinitySettings{})
+		require.Nil(t, err)
+		mutationJSON := []byte(fmt.Sprintf(`{ "type": "json", "source": "mutation", "parser": "backend", "url": "%s" }`, server.URL))
+		res := pluginhost.QueryData(context.Background(), backend.DataQuery{JSON: mutationJSON}, *client, map[string]string{}, backend.PluginContext{User: &backend.User{Role: "Editor"}})
+		require.NotNil(t, res.Error)
+		require.Equal(t, "mutation queries are not allowed by the query restrictions of the datasource", res.Error.Error())
+	})
+	t.Run("should materialize the datasets only for the exempt role", func(t *testing.T) {
+		client, err := infinity.NewClient(context.TODO(), models.InfinitySettings{UID: "materialize-restrictions"})
+		require.Nil(t, err)
+		materializeJSON := []byte(`{ "type": "json", "source": "inline", "parser": "backend", "data": "[{ \"name\": \"foo\" }]", "materialize_as": "users" }`)
+		res := pluginhost.QueryData(context.Background(), backend.DataQuery{JSON: materializeJSON}, *client, map[string]string{}, backend.PluginContext{User: &backend.User{Role: "Editor"}})
+		require.Nil(t, res.Error)
+		require.Equal(t, 1, res.Frames[0].Rows())
+		require.Contains(t, res.Frames[0].Meta.Notices[0].Text, "materialized datasets are not allowed by the query restrictions of the datasource")
+		_, err = infinity.GetDataset(context.Background(), *client, "users")
+		require.NotNil(t, err)
+		res = pluginhost.QueryData(context.Background(), backend.DataQuery{JSON: materializeJSON}, *client, map[string]string{}, backend.PluginContext{User: &backend.User{Role: "Admin"}})
+		require.Nil(t, res.Error)
+		dataset, err := infinity.GetDataset(context.Background(), *client, "users")
+		require.Nil(t, err)
+		require.Equal(t, "users", dataset.Name)
+	})
+}
+
+func TestMutations(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintf(w, `{ "status" : "acknowledged" }`)
+	}))
+	defer server.Close()
+	jsonData := fmt.Sprintf(`{ "allowMutations": true, "mutationHosts": ["%s"] }`, server.URL)
+	queryJSON := fmt.Sprintf(`{ "type": "json", "source": "mutation", "parser": "backend", "url": "%s/incidents/1" }`, server.URL)
+	pluginContext := backend.PluginContext{User: &backend.User{Role: "Admin"}, DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{ID: 941, JSONData: []byte(jsonData)}}
+	ds := pluginhost.NewDatasource()
+	t.Run("should not send the mutations on the panel refresh", func(t *testing.T) {
+		res, err := ds.QueryData(context.Background(), &backend.QueryDataRequest{
+			PluginContext: pluginContext,
+			Headers:       map[string]string{"X-Query-Refresh": "true"},
+			Queries:       []backend.DataQuery{{RefID: "A", JSON: []byte(queryJSON)}},
+		})
+		require.Nil(t, err)
+		require.Nil(t, res.Responses["A"].Error)
+		require.Contains(t, res.Responses["A"].Frames[0].Meta.Notices[0].Text, "is not sent by the panel queries")
+		require.Equal(t, 0, requests)
+	})
+	t.Run("should send the mutation on the explicit action", func(t *testing.T) {
+		var res *backend.CallResourceResponse
+		err := ds.CallResourceHandler.CallResource(context.Background(), &backend.CallResourceRequest{
+			PluginContext: pluginContext,
+			Method:        http.MethodPost,
+			Path:          "mutations",
+			URL:           "mutations",
+			Body:          []byte(queryJSON),
+		}, resourceResponseSender(func(r *backend.CallResourceResponse) { res = r }))
+		require.Nil(t, err)
+		require.Equal(t, http.StatusOK, res.Status)
+		require.Contains(t, string(res.Body), "acknowledged")
+		require.Equal(t, 1, requests)
+	})
+	t.Run("should reject the other sources", func(t *testing.T) {
+		var res *backend.CallResourceResponse
+		err := ds.CallResourceHandler.CallResource(context.Background(), &backend.CallResourceRequest{
+			PluginContext: pluginContext,
+			Method:        http.MethodPost,
+			Path:          "mutations",
+			URL:           "mutations",
+			Body:          []byte(fmt.Sprintf(`{ "type": "json", "source": "url", "url": "%s" }`, server.URL)),
+		}, resourceResponseSender(func(r *backend.CallResourceResponse) { res = r }))
+		require.Nil(t, err)
+		require.Equal(t, http.StatusBadRequest, res.Status)
+		require.Equal(t, 1, requests)
+	})
+}
+
+func TestCacheOptionsChange(t *testing.T) {
+	dirA, dirB := t.TempDir(), t.TempDir()
+	t.Cleanup(pluginhost.Shutdown)
+	ds := pluginhost.NewDatasource()
+	query := func(jsonData string) {
+		res, err := ds.QueryDataHandler.QueryData(context.Background(), &backend.QueryDataRequest{
+			PluginContext: backend.PluginContext{DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{ID: 917, UID: "cache-options", JSONData: []byte(jsonData)}},
+			Queries:       []backend.DataQuery{{RefID: "A", JSON: []byte(`{ "type": "json", "source": "inline", "data": "[]" }`)}},
+		})
+		require.Nil(t, err)
+		require.Nil(t, res.Responses["A"].Error)
+	}
+	t.Setenv("GF_PLUGIN_CACHE_DIR", dirA)
+	query(`{ "timeoutInSeconds" : 10 }`)
+	_, err := os.Stat(filepath.Join(dirA, "MANIFEST"))
+	require.Nil(t, err)
+	// the updated settings create the new instance before the replaced instance is disposed
+	t.Setenv("GF_PLUGIN_CACHE_DIR", dirB)
+	t.Setenv("GF_PLUGIN_CACHE_GC_INTERVAL", "1m")
+	query(`{ "timeoutInSeconds" : 20 }`)
+	_, err = os.Stat(filepath.Join(dirB, "MANIFEST"))
+	require.Nil(t, err)
+	// the garbage collection is restarted along with the cache
+	var res *backend.CallResourceResponse
+	err = ds.CallResourceHandler.CallResource(context.Background(), &backend.CallResourceRequest{
+		PluginContext: backend.PluginContext{User: &backend.User{Role: "Admin"}, DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{ID: 917, UID: "cache-options", JSONData: []byte(`{ "timeoutInSeconds" : 20 }`)}},
+		Method:        http.MethodGet,
+		Path:          "cache/gc",
+		URL:           "cache/gc",
+	}, resourceResponseSender(func(r *backend.CallResourceResponse) { res = r }))
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, res.Status)
+	require.Contains(t, string(res.Body), `"interval":"1m0s"`)
+	require.Nil(t, infinity.GetBadgerDB().Set("foo", "bar"))
+	// the instance manager disposes the replaced instance after 5 seconds, which must not close the cache of the new instance
+	time.Sleep(6 * time.Second)
+	got, err := infinity.GetBadgerDB().Get("foo")
+	require.Nil(t, err)
+	require.Equal(t, "bar", got)
+}
@@ -0,0 +1,108 @@
+package testsuite_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/infinity"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/models"
+	"github.com/yesoreyeram/grafana-infinity-datasource/pkg/pluginhost"
+)
+
+var updateSnapshots = flag.Bool("update-snapshots", false, "rewrite the expected frames of the snapshot tests")
+
+// TestSnapshots runs the query of each directory of snapshots against the stored upstream response and compares the frames with
+// the expected Arrow-encoded frames. Each directory has
+//
+//   - query.json: the query model
+//   - response.*: the upstream response, such as response.json or response.csv
+//   - frame-N.arrow: the expected frames
+//
+// Add a directory with the query and the response, then run go test ./pkg/testsuite -run TestSnapshots -update-snapshots
+// to write the expected frames. Review the frames before committing them
+func TestSnapshots(t *testing.T) {
+	dirs, err := filepath.Glob(filepath.Join("snapshots", "*"))
+	require.Nil(t, err)
+	require.NotEmpty(t, dirs)
+	for _, dir := range dirs {
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			queryJSON, err := os.ReadFile(filepath.Join(dir, "query.json"))
+			require.Nil(t, err)
+			responses, err := filepath.Glob(filepath.Join(dir, "response.*"))
+			require.Nil(t, err)
+			require.Len(t, responses, 1, "snapshot should have a single upstream response")
+			client, err := infinity.NewClient(context.Background(), models.InfinitySettings{})
+			require.Nil(t, err)
+			client.HttpClient.Transport = &InfinityMocker{FileName: responses[0]}
+			client.IsMock = true
+			res := pluginhost.QueryData(context.Background(), backend.DataQuery{RefID: "A", JSON: queryJSON}, *client, map[string]string{}, backend.PluginContext{})
+			require.Nil(t, res.Error)
+			if *updateSnapshots || UPDATE_GOLDEN_DATA {
+				writeSnapshotFrames(t, dir, res.Frames)
+			}
+			expected, err := filepath.Glob(filepath.Join(dir, "frame-*.arrow"))
+			require.Nil(t, err)
+			require.Len(t, res.Frames, len(expected), "number of frames doesn't match. run with -update-snapshots to rewrite the expected frames")
+			for i, frame := range res.Frames {
+				want, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("frame-%d.arrow", i)))
+				require.Nil(t, err)
+				got, err := frame.MarshalArrow()
+				require.Nil(t, err)
+				if bytes.Equal(want, got) {
+					continue
+				}
+				wantFrame, err := data.UnmarshalArrowFrame(want)
+				require.Nil(t, err)
+				// the tables and the meta are compared first for the readable diff
+				require.Equal(t, frameTable(t, wantFrame), frameTable(t, frame), "frame %d doesn't match", i)
+				require.Equal(t, frameMeta(t, wantFrame), frameMeta(t, frame), "meta of frame %d doesn't match", i)
+				require.Equal(t, want, got, "arrow encoding of frame %d doesn't match", i)
+			}
+		})
+	}
+}
+
+func writeSnapshotFrames(t *testing.T, dir string, frames data.Frames) {
+	t.Helper()
+	stale, err := filepath.Glob(filepath.Join(dir, "frame-*.arrow"))
+	require.Nil(t, err)
+	for _, file := range stale {
+		require.Nil(t, os.Remove(file))
+	}
+	for i, frame := range frames {
+		b, err := frame.MarshalArrow()
+		require.Nil(t, err)
+		require.Nil(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("frame-%d.arrow", i)), b, 0o600))
+	}
+}
+
+func frameTable(t *testing.T, frame *data.Frame) string {
+	t.Helper()
+	table, err := frame.StringTable(-1, -1)
+	require.Nil(t, err)
+	return table
+}
+
+func frameMeta(t *testing.T, frame *data.Frame) string {
+	t.Helper()
+	if frame.Meta == nil {
+		return ""
+	}
+	// custom meta is decoded as the map, so the meta is normalized through the map for the comparison
+	b, err := json.Marshal(frame.Meta)
+	require.Nil(t, err)
+	var meta any
+	require.Nil(t, json.Unmarshal(b, &meta))
+	b, err = json.MarshalIndent(meta, "", "  ")
+	require.Nil(t, err)
+	return string(b)
+}
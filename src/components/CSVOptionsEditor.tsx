@@ -23,7 +23,7 @@ export const CSVOptionsEditor = (props: { query: InfinityQuery; onChange: (value
         <div style={{ paddingBlockStart: '4px' }}>
           {query.type === 'csv' && (
             <div className="gf-form">
-              <InlineFormLabel width={LABEL_WIDTH} tooltip="Defaults to comma. If your file is TSV then use '\t'">
+              <InlineFormLabel width={LABEL_WIDTH} tooltip="Defaults to comma. If your file is TSV then use '\t'. Use 'auto' to detect comma, semicolon, tab or pipe delimiter automatically">
                 Delimiter
               </InlineFormLabel>
               <Input width={4} value={query.csv_options?.delimiter} placeholder="," onChange={(e) => onCSVOptionsChange('delimiter', e.currentTarget.value)}></Input>
@@ -51,6 +51,14 @@ export const CSVOptionsEditor = (props: { query: InfinityQuery; onChange: (value
               <Checkbox value={query.csv_options?.relax_column_count} onChange={(e) => onCSVOptionsChange('relax_column_count', e.currentTarget.checked)}></Checkbox>
             </div>
           </div>
+          {query.type === 'csv' && query.parser === 'backend' && (
+            <div className="gf-form">
+              <InlineFormLabel width={LABEL_WIDTH} tooltip="Comma separated column widths for the fixed width files. ex: 10,5,8. Headers are read from the first line unless specified below">
+                Fixed widths
+              </InlineFormLabel>
+              <Input width={30} value={query.csv_options?.fixed_widths} placeholder="10,5,8" onChange={(e) => onCSVOptionsChange('fixed_widths', e.currentTarget.value)}></Input>
+            </div>
+          )}
           <div className="gf-form">
             <InlineFormLabel width={LABEL_WIDTH}>Headers</InlineFormLabel>
             <Input width={30} value={query.csv_options?.columns} placeholder="Comma separated headers" onChange={(e) => onCSVOptionsChange('columns', e.currentTarget.value)}></Input>
@@ -59,6 +67,38 @@ export const CSVOptionsEditor = (props: { query: InfinityQuery; onChange: (value
             <InlineFormLabel width={LABEL_WIDTH}>Comment</InlineFormLabel>
             <Input width={4} value={query.csv_options?.comment} placeholder="#" onChange={(e) => onCSVOptionsChange('comment', e.currentTarget.value)}></Input>
           </div>
+          <div className="gf-form">
+            <InlineFormLabel width={LABEL_WIDTH} tooltip="Character used to quote the values. Defaults to double quote">
+              Quote
+            </InlineFormLabel>
+            <Input width={4} value={query.csv_options?.quote} placeholder={'"'} onChange={(e) => onCSVOptionsChange('quote', e.currentTarget.value)}></Input>
+          </div>
+          <div className="gf-form">
+            <InlineFormLabel width={LABEL_WIDTH} tooltip="Number of lines to skip before the headers">
+              Skip header lines
+            </InlineFormLabel>
+            <Input
+              width={8}
+              type="number"
+              min={0}
+              value={query.csv_options?.skip_header_lines}
+              placeholder="0"
+              onChange={(e) => onCSVOptionsChange('skip_header_lines', e.currentTarget.valueAsNumber || undefined)}
+            ></Input>
+          </div>
+          <div className="gf-form">
+            <InlineFormLabel width={LABEL_WIDTH} tooltip="Number of lines to skip at the end of the file">
+              Skip footer lines
+            </InlineFormLabel>
+            <Input
+              width={8}
+              type="number"
+              min={0}
+              value={query.csv_options?.skip_footer_lines}
+              placeholder="0"
+              onChange={(e) => onCSVOptionsChange('skip_footer_lines', e.currentTarget.valueAsNumber || undefined)}
+            ></Input>
+          </div>
         </div>
       </EditorField>
     </>
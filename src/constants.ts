@@ -33,8 +33,10 @@ export const INFINITY_RESULT_FORMATS: Array<SelectableValue<InfinityQueryFormat>
   { label: 'Logs', value: 'logs' },
   { label: 'Traces', value: 'trace' },
   { label: 'Time Series', value: 'timeseries' },
+  { label: 'Numeric', value: 'numeric' },
   { label: 'Nodes - Node Graph', value: 'node-graph-nodes' },
   { label: 'Edges - Node Graph', value: 'node-graph-edges' },
+  { label: 'Nodes & Edges - Node Graph', value: 'node-graph' },
   { label: 'As Is', value: 'as-is' },
 ];
 export const INFINITY_SOURCES: ScrapQuerySources[] = [
@@ -188,7 +188,7 @@ export class Datasource extends DataSourceWithBackend<InfinityQuery, InfinityOpt
                 } else if (responseCodeFromServer && responseCodeFromServer > 300) {
                   frame.meta.notices = [{ severity: 'warning', text: `Response Code From Server : ${responseCodeFromServer}` }];
                 }
-                if (target.format === 'node-graph-edges' || target.format === 'node-graph-nodes') {
+                if (target.format === 'node-graph-edges' || target.format === 'node-graph-nodes' || target.format === 'node-graph') {
                   frame.meta.preferredVisualisationType = 'nodeGraph';
                 }
                 return frame;
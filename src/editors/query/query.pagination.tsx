@@ -4,7 +4,7 @@ import { SelectableValue } from '@grafana/data';
 import { EditorField } from './../../components/extended/EditorField';
 import { EditorRow } from './../../components/extended/EditorRow';
 import { Stack } from './../../components/extended/Stack';
-import type { InfinityQuery, PaginationParamType, PaginationType } from './../../types';
+import type { InfinityQuery, PaginationErrorMode, PaginationParamType, PaginationType } from './../../types';
 
 const paginationTypes: Array<SelectableValue<PaginationType>> = [
   { value: 'none', label: 'None' },
@@ -12,6 +12,7 @@ const paginationTypes: Array<SelectableValue<PaginationType>> = [
   { value: 'page', label: 'Page number' },
   { value: 'cursor', label: 'Cursor' },
   { value: 'list', label: 'List of values' },
+  { value: 'link', label: 'Link header' },
 ];
 
 const paginationParamTypes: Array<SelectableValue<PaginationParamType>> = [
@@ -22,6 +23,11 @@ const paginationParamTypes: Array<SelectableValue<PaginationParamType>> = [
   { value: 'replace', label: 'Replace URL' },
 ];
 
+const paginationErrorModes: Array<SelectableValue<PaginationErrorMode>> = [
+  { value: 'fail-fast', label: 'Fail fast', description: 'Fail the query when any of the page fails' },
+  { value: 'best-effort', label: 'Best effort', description: 'Return the rows of the successful pages with a warning' },
+];
+
 type PaginationEditorProps = {
   query: InfinityQuery;
   onChange: (query: InfinityQuery) => void;
@@ -50,8 +56,18 @@ export const PaginationEditor = (props: PaginationEditorProps) => {
               />
             </EditorField>
           )}
+          {query.pagination_mode && query.pagination_mode !== 'none' && (
+            <EditorField label="On page failure" tooltip={'Fail the whole query or return the partial results when some of the pages fail. Cursor pagination stops at the failed page.'}>
+              <Select<PaginationErrorMode>
+                width={30}
+                value={query.pagination_error_mode || 'fail-fast'}
+                options={paginationErrorModes}
+                onChange={(e) => onChange({ ...query, pagination_error_mode: e.value || 'fail-fast' })}
+              />
+            </EditorField>
+          )}
         </Stack>
-        {(query.pagination_mode === 'offset' || query.pagination_mode === 'page' || query.pagination_mode === 'cursor') && (
+        {(query.pagination_mode === 'offset' || query.pagination_mode === 'page' || query.pagination_mode === 'cursor' || query.pagination_mode === 'link') && (
           <>
             <Stack gap={1} wrap={false} direction="column">
               <EditorField label="Size field">
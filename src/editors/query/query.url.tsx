@@ -161,6 +161,7 @@ const Body = ({ query, onChange, onRunQuery }: { query: InfinityQuery; onChange:
                 { value: 'form-data', label: 'Form Data' },
                 { value: 'x-www-form-urlencoded', label: 'x-www-form-urlencoded' },
                 { value: 'graphql', label: 'GraphQL' },
+                { value: 'soap', label: 'SOAP' },
               ]}
               onChange={(e) => onURLOptionsChange('body_type', e || 'raw')}
             ></RadioButtonGroup>
@@ -228,6 +229,53 @@ const Body = ({ query, onChange, onRunQuery }: { query: InfinityQuery; onChange:
                 </EditorField>
               </>
             )}
+            {query.url_options?.body_type === 'soap' && isDataQuery(query) && (
+              <>
+                <br />
+                <br />
+                <Stack gap={1}>
+                  <EditorField label="SOAP version">
+                    <RadioButtonGroup<'1.1' | '1.2'>
+                      value={query.soap_options?.version || '1.1'}
+                      options={[
+                        { value: '1.1', label: '1.1' },
+                        { value: '1.2', label: '1.2' },
+                      ]}
+                      onChange={(e) => onChange({ ...query, soap_options: { ...query.soap_options, version: e } })}
+                    />
+                  </EditorField>
+                  <EditorField label="SOAP action">
+                    <Input
+                      width={40}
+                      value={query.soap_options?.action || ''}
+                      onChange={(e) => onChange({ ...query, soap_options: { ...query.soap_options, action: e.currentTarget.value } })}
+                      onBlur={onRunQuery}
+                    />
+                  </EditorField>
+                  <EditorField label="Result XPath" tooltip="xpath of the result element. ex: //GetWeatherResult">
+                    <Input
+                      width={30}
+                      value={query.soap_options?.result_xpath || ''}
+                      placeholder="//OperationResult"
+                      onChange={(e) => onChange({ ...query, soap_options: { ...query.soap_options, result_xpath: e.currentTarget.value } })}
+                      onBlur={onRunQuery}
+                    />
+                  </EditorField>
+                </Stack>
+                <EditorField label="SOAP body" tooltip="Operation element of the SOAP body. The body is wrapped in the SOAP envelope unless it is already an envelope">
+                  <CodeEditor
+                    language="xml"
+                    height={'200px'}
+                    value={query.url_options?.data || ''}
+                    onSave={(e) => onURLOptionsChange('data', e)}
+                    onBlur={(e) => {
+                      onURLOptionsChange('data', e);
+                      onRunQuery();
+                    }}
+                  />
+                </EditorField>
+              </>
+            )}
             {(query.url_options?.body_type === 'raw' || !query.url_options?.body_type) && (
               <div style={{ marginRight: '10px' }}>
                 <br />
@@ -1,4 +1,4 @@
-import type { InfinityQuery } from './query.types';
+import type { InfinityQuery, PaginationType, QueryParam } from './query.types';
 import type { DataSourceInstanceSettings, DataSourceJsonData } from '@grafana/data';
 
 //#region Config
@@ -7,7 +7,19 @@ export interface GlobalInfinityQuery {
   id: string;
   query: InfinityQuery;
 }
-export type AuthType = 'none' | 'basicAuth' | 'apiKey' | 'bearerToken' | 'oauthPassThru' | 'digestAuth' | 'aws' | 'azureBlob' | 'oauth2';
+export type KubernetesAuthProps = {
+  credentialsType?: 'in-cluster' | 'kubeconfig';
+  kubeconfigPath?: string;
+  context?: string;
+};
+export type SNMPProps = {
+  version?: '1' | '2c' | '3';
+  username?: string;
+  securityLevel?: 'noAuthNoPriv' | 'authNoPriv' | 'authPriv';
+  authProtocol?: 'MD5' | 'SHA' | 'SHA224' | 'SHA256' | 'SHA384' | 'SHA512';
+  privProtocol?: 'DES' | 'AES' | 'AES192' | 'AES256';
+};
+export type AuthType = 'none' | 'basicAuth' | 'apiKey' | 'bearerToken' | 'oauthPassThru' | 'digestAuth' | 'aws' | 'azureBlob' | 'oauth2' | 'kubernetes';
 export type OAuth2Type = 'client_credentials' | 'jwt' | 'others';
 export type APIKeyType = 'header' | 'query';
 export type OAuth2Props = {
@@ -27,12 +39,47 @@ export type AWSAuthProps = {
 };
 export type InfinityReferenceData = { name: string; data: string };
 export type ProxyType = 'none' | 'env' | 'url';
+export type InfinityFeatureFlag = 'scheduler' | 'openApi';
+export type InfinityQueryRestrictions = {
+  allowLocalHost?: boolean;
+  allowMutations?: boolean;
+  requireHttps?: boolean;
+  blockCustomHeaders?: boolean;
+  exemptRole?: 'Viewer' | 'Editor' | 'Admin';
+};
+export type InfinityFormFile = { name: string; fileName?: string; contentType?: string };
+export type InfinityHeaderProfile = { name: string; hosts?: string[]; headers?: string[] };
+export type InfinityQueryDefaults = {
+  rootSelector?: string;
+  headers?: QueryParam[];
+  paginationMode?: PaginationType;
+  paginationMaxPages?: number;
+  paginationSizeFieldName?: string;
+  paginationSizeValue?: number;
+  cacheTTL?: string;
+};
+export type SQLConnectionProps = {
+  name: string;
+  driver: 'postgres' | 'mysql';
+};
+export type WebhookChannelProps = {
+  name: string;
+  maxPayloads?: number;
+};
 export interface InfinityOptions extends DataSourceJsonData {
   auth_method?: AuthType;
   apiKeyKey?: string;
   apiKeyType?: APIKeyType;
   oauth2?: OAuth2Props;
   aws?: AWSAuthProps;
+  kubernetes?: KubernetesAuthProps;
+  snmp?: SNMPProps;
+  redisUrl?: string;
+  sqlConnections?: SQLConnectionProps[];
+  webhookChannels?: WebhookChannelProps[];
+  allowMutations?: boolean;
+  mutationMethods?: Array<'POST' | 'PUT' | 'PATCH' | 'DELETE'>;
+  mutationHosts?: string[];
   tlsSkipVerify?: boolean;
   tlsAuth?: boolean;
   serverName?: string;
@@ -52,6 +99,19 @@ export interface InfinityOptions extends DataSourceJsonData {
   customHealthCheckUrl?: string;
   azureBlobAccountUrl?: string;
   azureBlobAccountName?: string;
+  maxFrameCells?: number;
+  cacheBackend?: 'badger' | 'redis' | 'memcached';
+  cacheBackendUrl?: string;
+  cacheMemoryEntries?: number;
+  featureFlags?: Partial<Record<InfinityFeatureFlag, boolean>>;
+  queryRestrictions?: InfinityQueryRestrictions;
+  maxRedirects?: number;
+  maxConcurrentQueries?: number;
+  blockPrivateRedirects?: boolean;
+  headerProfiles?: InfinityHeaderProfile[];
+  formFiles?: InfinityFormFile[];
+  queryDefaults?: InfinityQueryDefaults;
+  schemaVersion?: number;
 }
 
 export interface InfinitySecureOptions {
@@ -66,6 +126,11 @@ export interface InfinitySecureOptions {
   oauth2ClientSecret?: string;
   oauth2JWTPrivateKey?: string;
   azureBlobAccountKey?: string;
+  cacheBackendPassword?: string;
+  redisPassword?: string;
+  snmpCommunity?: string;
+  snmpAuthPassphrase?: string;
+  snmpPrivPassphrase?: string;
 }
 export interface SecureField {
   id: string;
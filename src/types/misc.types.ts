@@ -28,3 +28,39 @@ export type HealthCheckResult = {
 export type queryResult = timeSeriesResult | tableResult;
 export type EditorMode = 'standard' | 'global' | 'variable';
 //#endregion
+
+//#region API operations
+export type APIParameter = {
+  name: string;
+  in?: 'query' | 'path' | 'header' | 'body' | 'element';
+  type?: string;
+  required?: boolean;
+  description?: string;
+  default?: string;
+};
+export type APIQuery = {
+  url: string;
+  method: string;
+  params?: Array<{ key: string; value: string }>;
+  headers?: Array<{ key: string; value: string }>;
+};
+export type APIOperation = {
+  name: string;
+  description?: string;
+  method?: string;
+  path?: string;
+  action?: string;
+  endpoint?: string;
+  parameters?: APIParameter[];
+  example?: unknown;
+  query?: APIQuery;
+};
+//#endregion
+
+//#region Query lint
+export type LintWarning = {
+  field: string;
+  severity: 'error' | 'warning';
+  message: string;
+};
+//#endregion
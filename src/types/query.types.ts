@@ -2,11 +2,11 @@ import { FilterOperator } from './../constants';
 import type { DataQuery, SelectableValue } from '@grafana/data';
 
 //#region Query
-export type InfinityQueryType = 'json' | 'csv' | 'tsv' | 'xml' | 'graphql' | 'html' | 'series' | 'global' | 'uql' | 'groq' | 'google-sheets' | 'transformations';
-export type InfinityQuerySources = 'url' | 'inline' | 'azure-blob' | 'reference' | 'random-walk' | 'expression';
-export type InfinityColumnFormat = 'string' | 'number' | 'timestamp' | 'timestamp_epoch' | 'timestamp_epoch_s' | 'boolean';
-export type InfinityQueryFormat = 'table' | 'timeseries' | 'logs' | 'trace' | 'node-graph-nodes' | 'node-graph-edges' | 'dataframe' | 'as-is';
-export type QueryBodyType = 'none' | 'form-data' | 'x-www-form-urlencoded' | 'raw' | 'graphql';
+export type InfinityQueryType = 'json' | 'csv' | 'tsv' | 'xml' | 'graphql' | 'html' | 'series' | 'global' | 'uql' | 'groq' | 'google-sheets' | 'transformations' | 'sql' | 'logs' | 'arrow';
+export type InfinityQuerySources = 'url' | 'inline' | 'azure-blob' | 'reference' | 'random-walk' | 'expression' | 'snmp' | 'mqtt' | 'redis' | 'database' | 'ldap' | 'dns' | 'probe' | 'certificate' | 'rdap' | 'crawl' | 'webhook' | 'mutation' | `dataset:${string}`;
+export type InfinityColumnFormat = 'string' | 'number' | 'timestamp' | 'timestamp_epoch' | 'timestamp_epoch_s' | 'boolean' | 'duration' | 'latitude' | 'longitude' | 'geohash' | 'latlon';
+export type InfinityQueryFormat = 'table' | 'timeseries' | 'numeric' | 'logs' | 'trace' | 'node-graph-nodes' | 'node-graph-edges' | 'node-graph' | 'dataframe' | 'as-is';
+export type QueryBodyType = 'none' | 'form-data' | 'x-www-form-urlencoded' | 'raw' | 'graphql' | 'soap';
 export type QueryBodyContentType = 'text/plain' | 'application/json' | 'application/xml' | 'text/html' | 'application/javascript';
 export type InfinityQueryBase<T extends InfinityQueryType> = { type: T } & DataQuery;
 export type InfinityQueryWithSource<S extends InfinityQuerySources> = { source: S } & DataQuery;
@@ -21,6 +21,7 @@ export type InfinityURLOptions = {
   body_form?: InfinityKV[];
   body_graphql_query?: string;
   body_graphql_variables?: string;
+  body_form_files?: string[];
 };
 export type InfinityQueryWithReferenceSource<T extends InfinityQueryType> = {
   referenceName: string;
@@ -29,6 +30,12 @@ export type InfinityQueryWithReferenceSource<T extends InfinityQueryType> = {
 export type InfinityQueryWithURLSource<T extends InfinityQueryType> = {
   url: string;
   url_options: InfinityURLOptions;
+  coalesce_window_seconds?: number;
+  incremental_watermark_field?: string;
+  incremental_param_name?: string;
+  incremental_param_type?: PaginationParamType;
+  incremental_initial_value?: string;
+  incremental_key?: string;
 } & InfinityQueryWithSource<'url'> &
   InfinityQueryBase<T>;
 export type InfinityQueryWithAzureBlobSource<T extends InfinityQueryType> = {
@@ -42,18 +49,185 @@ export type InfinityQueryWithInlineSource<T extends InfinityQueryType> = {
   InfinityQueryBase<T>;
 export type InfinityQueryWithRandomWalkSource = {} & InfinityQueryWithSource<'random-walk'>;
 export type InfinityQueryWithExpressionSource = {} & InfinityQueryWithSource<'expression'>;
+export type TraceDurationUnit = 'ms' | 'us' | 'ns' | 's';
+export type InfinityTraceOptions = {
+  trace_id?: string;
+  span_id?: string;
+  parent_span_id?: string;
+  operation_name?: string;
+  service_name?: string;
+  start_time?: string;
+  duration?: string;
+  duration_unit?: TraceDurationUnit;
+};
+export type InfinityNodeGraphOptions = {
+  nodes_selector?: string;
+  edges_selector?: string;
+};
+export type InfinityForecastOptions = {
+  field: string;
+  horizon?: number;
+  seasonality?: number;
+  alpha?: number;
+  beta?: number;
+  gamma?: number;
+};
+export type InfinityPreParseStep = {
+  type: 'regex_replace' | 'json_unwrap' | 'base64_decode';
+  pattern?: string;
+  replacement?: string;
+  selector?: string;
+};
+export type InfinitySOAPOptions = {
+  version?: '1.1' | '1.2';
+  action?: string;
+  result_xpath?: string;
+};
+export type InfinityElasticsearchOptions = {
+  query?: string;
+  time_field?: string;
+  size?: number;
+  sort_field?: string;
+  sort_order?: 'asc' | 'desc';
+};
+export type InfinitySNMPOptions = {
+  target: string;
+  operation?: 'get' | 'walk';
+  oids: string[];
+};
+export type InfinityMQTTOptions = {
+  broker?: string;
+  topics: string[];
+  mode?: 'window' | 'retained';
+  window?: string;
+  qos?: 0 | 1 | 2;
+};
+export type InfinityRedisOptions = {
+  command: string;
+  args?: string[];
+};
+export type InfinityLDAPOptions = {
+  server?: string;
+  base_dn: string;
+  filter?: string;
+  attributes?: string[];
+  scope?: 'base' | 'one' | 'sub';
+  size_limit?: number;
+};
+export type InfinityDNSOptions = {
+  names: string[];
+  record_types?: Array<'A' | 'AAAA' | 'TXT' | 'MX' | 'SRV' | 'CNAME' | 'NS'>;
+  resolver?: string;
+};
+export type InfinityProbeOptions = {
+  mode?: 'tcp' | 'tls' | 'icmp';
+  targets: string[];
+  count?: number;
+};
+export type InfinityCertificateOptions = {
+  targets: string[];
+  chain?: boolean;
+};
+export type InfinityRDAPOptions = {
+  domains: string[];
+  server?: string;
+};
+export type InfinityCrawlOptions = {
+  mode?: 'links' | 'sitemap';
+  depth?: number;
+  max_pages?: number;
+  delay?: string;
+  ignore_robots?: boolean;
+};
+export type InfinityWebhookOptions = {
+  channel: string;
+  mode?: 'latest' | 'all';
+};
+export type InfinityMutationOptions = {
+  method?: 'POST' | 'PUT' | 'PATCH' | 'DELETE';
+};
+export type InfinityAssertion = {
+  type: 'status_code' | 'body' | 'latency';
+  path?: string;
+  value: string;
+};
+export type InfinityChainStep = {
+  name?: string;
+  url: string;
+  url_options: InfinityURLOptions;
+  extract?: Array<{ name: string; from?: 'body' | 'header' | 'cookie' | 'meta'; path: string }>;
+  skip_with_session?: boolean;
+};
+export type InfinityCookieJar = {
+  name?: string;
+  ttl?: string;
+};
 export type InfinityQueryWithDataSource<T extends InfinityQueryType> = {
   root_selector: string;
   columns: InfinityColumn[];
   filters?: InfinityFilter[];
   format: InfinityQueryFormat;
+  materialize_as?: string;
+  materialize_ttl_seconds?: number;
+  charset?: string;
+  response_content_type?: string;
+  trace_options?: InfinityTraceOptions;
+  node_graph_options?: InfinityNodeGraphOptions;
+  forecast_options?: InfinityForecastOptions;
+  header_profile?: string;
+  pre_parse?: InfinityPreParseStep[];
+  soap_options?: InfinitySOAPOptions;
+  dry_run?: boolean;
+  sample_fixture?: string;
+  use_sample?: boolean;
+  schema_version?: number;
+  cache_mode?: InfinityCacheMode;
+  results_api?: InfinityResultsAPI;
+  elasticsearch_options?: InfinityElasticsearchOptions;
+  source_preset?: InfinitySourcePreset;
+  snmp_options?: InfinitySNMPOptions;
+  mqtt_options?: InfinityMQTTOptions;
+  redis_options?: InfinityRedisOptions;
+  ldap_options?: InfinityLDAPOptions;
+  dns_options?: InfinityDNSOptions;
+  probe_options?: InfinityProbeOptions;
+  certificate_options?: InfinityCertificateOptions;
+  rdap_options?: InfinityRDAPOptions;
+  crawl_options?: InfinityCrawlOptions;
+  webhook_options?: InfinityWebhookOptions;
+  mutation_options?: InfinityMutationOptions;
+  assertions?: InfinityAssertion[];
+  chain?: InfinityChainStep[];
+  cookie_jar?: InfinityCookieJar;
 } & (InfinityQueryWithURLSource<T> | InfinityQueryWithInlineSource<T> | InfinityQueryWithReferenceSource<T> | InfinityQueryWithAzureBlobSource<T>) &
   InfinityQueryBase<T>;
 export type InfinityJSONQueryOptions = {
   root_is_not_array?: boolean;
   columnar?: boolean;
 };
-export type BackendParserOptions = { filterExpression?: string; summarizeExpression?: string; summarizeBy?: string; computed_columns?: InfinityColumn[] };
+export type DownsampleMode = 'none' | 'lttb' | 'average';
+export type ColumnFunctionType = 'rate' | 'delta' | 'cumulative_sum' | 'moving_average' | 'ewma' | 'outlier';
+export type ColumnFunction = {
+  field: string;
+  function: ColumnFunctionType;
+  alias?: string;
+  window?: number;
+  alpha?: number;
+  method?: 'mad' | 'sigma';
+  threshold?: number;
+};
+export type ResampleFill = 'null' | 'previous' | 'zero' | 'linear';
+export type BackendParserOptions = {
+  filterExpression?: string;
+  summarizeExpression?: string;
+  summarizeBy?: string;
+  computed_columns?: InfinityColumn[];
+  downsample_mode?: DownsampleMode;
+  downsample_points?: number;
+  column_functions?: ColumnFunction[];
+  resample_interval?: string;
+  resample_fill?: ResampleFill;
+};
 export type InfinityJSONQuery = (
   | { parser?: 'simple'; json_options?: InfinityJSONQueryOptions }
   | ({ parser: 'backend' } & BackendParserOptions)
@@ -69,6 +243,10 @@ export type InfinityCSVQueryOptions = {
   relax_column_count?: boolean;
   columns?: string;
   comment?: string;
+  quote?: string;
+  skip_header_lines?: number;
+  skip_footer_lines?: number;
+  fixed_widths?: string;
 };
 export type InfinityCSVQuery = (
   | { parser?: 'simple'; csv_options?: InfinityCSVQueryOptions }
@@ -104,9 +282,13 @@ export type InfinityUQLQuery = { uql: string; format: InfinityQueryFormat } & In
 export type InfinityGROQQuerySource = InfinityQueryWithURLSource<'groq'> | InfinityQueryWithInlineSource<'groq'>;
 export type InfinityGROQQuery = { groq: string; format: InfinityQueryFormat } & InfinityGROQQuerySource & InfinityQueryBase<'groq'>;
 export type InfinityGSheetsQuery = { spreadsheet: string; sheetName?: string; range: string; columns: InfinityColumn[] } & InfinityQueryBase<'google-sheets'>;
-export type PaginationType = 'none' | 'offset' | 'page' | 'cursor' | 'list';
+export type InfinityCacheMode = 'raw' | 'frame';
+export type InfinityResultsAPI = 'bigquery' | 'databricks';
+export type InfinitySourcePreset = 'jira' | 'servicenow' | 'github' | 'gitlab' | 'kubernetes' | 'docker';
+export type PaginationType = 'none' | 'offset' | 'page' | 'cursor' | 'list' | 'link';
 export type PaginationParamType = 'query' | 'header' | 'body_data' | 'body_json' | 'replace';
-export type PaginationBase<T extends PaginationType> = { pagination_mode?: T; pagination_max_pages?: number };
+export type PaginationErrorMode = 'fail-fast' | 'best-effort';
+export type PaginationBase<T extends PaginationType> = { pagination_mode?: T; pagination_max_pages?: number; pagination_error_mode?: PaginationErrorMode };
 export type PaginationNone = {} & PaginationBase<'none'>;
 export type PaginationOffset = {
   pagination_param_size_field_name?: string;
@@ -137,8 +319,13 @@ export type PaginationList = {
   pagination_param_list_field_type?: PaginationParamType;
   pagination_param_list_value?: string;
 } & PaginationBase<'list'>;
-export type Pagination = PaginationNone | PaginationOffset | PaginationPage | PaginationCursor | PaginationList;
-export type Transformation = 'limit' | 'filterExpression' | 'summarize' | 'computedColumn';
+export type PaginationLink = {
+  pagination_param_size_field_name?: string;
+  pagination_param_size_field_type?: PaginationParamType;
+  pagination_param_size_value?: number;
+} & PaginationBase<'link'>;
+export type Pagination = PaginationNone | PaginationOffset | PaginationPage | PaginationCursor | PaginationList | PaginationLink;
+export type Transformation = 'limit' | 'filterExpression' | 'summarize' | 'computedColumn' | 'sparkline' | 'ipEnrichment' | 'heatmap';
 export type TransformationItem = {
   type: Transformation;
   disabled?: boolean;
@@ -157,11 +344,41 @@ export type TransformationItem = {
     by?: string;
     alias?: string;
   };
+  sparkline?: {
+    field: string;
+    delimiter?: string;
+    alias?: string;
+  };
+  ipEnrichment?: {
+    field: string;
+    reverseDNS?: boolean;
+    cidrTags?: Array<{ cidr: string; label: string }>;
+  };
+  heatmap?: {
+    timeField: string;
+    bucketField: string;
+    countField: string;
+    cumulative?: boolean;
+  };
 };
 export type TransformationsQuery = {
   transformations: TransformationItem[];
 } & InfinityQueryBase<'transformations'>;
-export type InfinityQuery = (InfinityLegacyQuery | InfinityUQLQuery | InfinityGROQQuery | InfinityGSheetsQuery | TransformationsQuery) & Pagination;
+export type SQLQuery = {
+  sql_query: string;
+  sql_datasets?: string[];
+  sql_connection?: string;
+} & InfinityQueryBase<'sql'>;
+export type LogsQuery = {
+  parser?: 'backend';
+  log_pattern?: string;
+} & BackendParserOptions &
+  InfinityQueryWithDataSource<'logs'>;
+export type ArrowQuery = {
+  parser?: 'backend';
+} & BackendParserOptions &
+  InfinityQueryWithDataSource<'arrow'>;
+export type InfinityQuery = (InfinityLegacyQuery | InfinityUQLQuery | InfinityGROQQuery | InfinityGSheetsQuery | TransformationsQuery | SQLQuery | LogsQuery | ArrowQuery) & Pagination;
 //#endregion
 
 //#region Misc
@@ -173,6 +390,13 @@ export interface InfinityColumn {
   text: string;
   type: InfinityColumnFormat;
   timestampFormat?: string;
+  parseFormattedNumber?: boolean;
+  unit?: string;
+  valueMappings?: InfinityValueMapping[];
+}
+export interface InfinityValueMapping {
+  value: string;
+  text: string;
 }
 export interface DataOverride {
   values: string[];
@@ -189,3 +413,10 @@ export type QueryParam = { key: string; value: string };
 export type QueryHeaders = { key: string; value: string };
 
 //#endregion
+export type ImportedQuery = {
+  name: string;
+  folder?: string;
+  query: InfinityQuery;
+  authHint?: string;
+  warnings?: string[];
+};